@@ -2,39 +2,29 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 
-	"github.com/google/uuid"
 	"gorm.io/gorm"
 
 	"paytabs/internal/config"
 	"paytabs/internal/db"
 	"paytabs/internal/model"
 	"paytabs/internal/repository"
+	"paytabs/internal/service"
 )
 
-const accountsAPIURL = "https://gist.githubusercontent.com/paytabscom/b590d72ae115226e288a9c8a15ba2888/raw/ac0d615060b02e755c94116e4e5a5af530bc4bb1/accounts.json"
-
-// SeedAccountData represents the structure from the external API.
-type SeedAccountData struct {
-	ID      string `json:"id"`
-	Active  bool   `json:"active"`
-	Name    string `json:"name"`
-	Balance string `json:"balance"`
-}
-
 func main() {
 	log.Println("Starting seed script...")
 
 	// Load configuration
-	cfg := config.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
 
 	// Connect to database
-	gormDB, err := db.NewMySQL(cfg.MySQLDSN)
+	gormDB, err := db.NewMySQL(cfg.MySQLDSN, cfg.LockWaitTimeout)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -42,61 +32,23 @@ func main() {
 
 	// Drop all tables to start fresh (in reverse dependency order)
 	log.Println("Dropping existing tables...")
-	tables := []interface{}{
-		&model.Transfer{},
-		&model.PaymentLog{},
-		&model.Payment{},
-		&model.Card{},
-		&model.Account{},
-	}
-	for _, table := range tables {
-		if err := gormDB.Migrator().DropTable(table); err != nil {
-			log.Printf("Warning: Failed to drop table (may not exist): %v", err)
-		}
-	}
+	db.DropAll(gormDB)
 	log.Println("Tables dropped")
 
 	// Run migrations to create fresh schema
 	log.Println("Running migrations...")
-	if err := gormDB.AutoMigrate(
-		&model.Account{},
-		&model.Card{},
-		&model.Payment{},
-		&model.PaymentLog{},
-		&model.Transfer{},
-	); err != nil {
+	if _, err := db.Migrate(gormDB, db.Migrations); err != nil {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
 	log.Println("Database migrations completed")
 
 	// Fetch accounts from API
-	log.Printf("Fetching accounts from: %s", accountsAPIURL)
-	accounts, err := fetchAccountsFromAPI(accountsAPIURL)
+	log.Printf("Fetching accounts from: %s", cfg.SeedAccountsURL)
+	modelAccounts, skipped, err := service.FetchSeedAccounts(context.Background(), cfg.SeedAccountsURL)
 	if err != nil {
 		log.Fatalf("Failed to fetch accounts: %v", err)
 	}
-	log.Printf("Fetched %d accounts from API", len(accounts))
-
-	// Convert to model.Account
-	modelAccounts := make([]model.Account, 0, len(accounts))
-	skipped := 0
-	for _, item := range accounts {
-		accountID, err := uuid.Parse(item.ID)
-		if err != nil {
-			log.Printf("Skipping account with invalid UUID: %s", item.ID)
-			skipped++
-			continue
-		}
-
-		account := model.Account{
-			ID:         accountID,
-			Name:       item.Name,
-			Email:      fmt.Sprintf("account-%s@example.com", accountID.String()),
-			Active:     item.Active,
-			IsMerchant: false,
-		}
-		modelAccounts = append(modelAccounts, account)
-	}
+	log.Printf("Fetched %d accounts from API", len(modelAccounts))
 
 	if skipped > 0 {
 		log.Printf("Skipped %d invalid accounts", skipped)
@@ -118,31 +70,6 @@ func main() {
 	log.Printf("  - Total accounts processed: %d", seeded+updated)
 }
 
-// fetchAccountsFromAPI fetches account data from the external API.
-func fetchAccountsFromAPI(url string) ([]SeedAccountData, error) {
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch from API: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned status code: %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	var accounts []SeedAccountData
-	if err := json.Unmarshal(body, &accounts); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON: %w", err)
-	}
-
-	return accounts, nil
-}
-
 // seedAccounts seeds accounts into the database, creating new ones or updating existing ones.
 func seedAccounts(ctx context.Context, repo repository.AccountRepository, accounts []model.Account) (seeded int, updated int, err error) {
 	for _, account := range accounts {
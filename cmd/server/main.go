@@ -1,9 +1,9 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
-	"os"
 
 	_ "paytabs/docs" // swagger docs
 
@@ -12,13 +12,16 @@ import (
 
 	"paytabs/internal/auth"
 	"paytabs/internal/cache"
+	"paytabs/internal/clock"
 	"paytabs/internal/config"
+	"paytabs/internal/currency"
 	"paytabs/internal/db"
 	"paytabs/internal/handler"
-	"paytabs/internal/model"
+	"paytabs/internal/notify"
 	"paytabs/internal/repository"
 	"paytabs/internal/router"
 	"paytabs/internal/service"
+	"paytabs/internal/tracing"
 )
 
 // @title Payment Processor API
@@ -32,43 +35,58 @@ import (
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token.
 func main() {
-	cfg := config.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("config: %v", err)
+	}
+
+	currencies, err := currency.NewRegistry(cfg.SupportedCurrencies, cfg.DefaultCurrency)
+	if err != nil {
+		log.Fatalf("currency config: %v", err)
+	}
+	if _, err := currency.ParseRoundingMode(cfg.RoundingMode); err != nil {
+		log.Fatalf("rounding config: %v", err)
+	}
+	if _, err := router.NewIPExtractor(cfg.TrustedProxies); err != nil {
+		log.Fatalf("TRUSTED_PROXIES: %v", err)
+	}
+
+	// Tracing is installed before the database and cache clients so their spans are exported
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TracingEndpoint, cfg.TracingServiceName, cfg.TracingSampleRatio)
+	if err != nil {
+		log.Fatalf("tracing init: %v", err)
+	}
+	defer shutdownTracing(context.Background())
 
 	e := echo.New()
 	e.Use(middleware.RequestID())
 
-	gormDB, err := db.NewMySQL(cfg.MySQLDSN)
+	gormDB, err := db.NewMySQL(cfg.MySQLDSN, cfg.LockWaitTimeout)
 	if err != nil {
 		log.Fatalf("database init: %v", err)
 	}
 
-	// Drop tables if RESET_DB environment variable is set
-	if os.Getenv("RESET_DB") == "true" {
-		log.Println("RESET_DB=true detected, dropping all tables...")
-		tables := []interface{}{
-			&model.Transfer{},
-			&model.PaymentLog{},
-			&model.Payment{},
-			&model.Card{},
-			&model.Account{},
-		}
-		for _, table := range tables {
-			if err := gormDB.Migrator().DropTable(table); err != nil {
-				log.Printf("Warning: Failed to drop table (may not exist): %v", err)
-			}
+	// Dev-only shortcuts: RESET_DB drops every table and DB_AUTO_MIGRATE syncs the schema to
+	// the models without recording a version. Production relies on versioned migrations only.
+	if cfg.ResetDB {
+		if !cfg.DBAutoMigrate {
+			log.Fatalf("RESET_DB=true is only allowed together with DB_AUTO_MIGRATE=true (development)")
 		}
+		log.Println("RESET_DB=true detected, dropping all tables...")
+		db.DropAll(gormDB)
 		log.Println("Tables dropped")
 	}
 
-	// Run migrations for all models
-	if err := gormDB.AutoMigrate(
-		&model.Account{},
-		&model.Card{},
-		&model.Payment{},
-		&model.PaymentLog{},
-		&model.Transfer{},
-	); err != nil {
-		log.Fatalf("auto-migrate: %v", err)
+	applied, err := db.Migrate(gormDB, db.Migrations)
+	if err != nil {
+		log.Fatalf("migrate: %v", err)
+	}
+	log.Printf("Database migrations up to date (%d applied)", applied)
+
+	if cfg.DBAutoMigrate {
+		if err := gormDB.AutoMigrate(db.Models()...); err != nil {
+			log.Fatalf("auto-migrate: %v", err)
+		}
 	}
 
 	cacheClient := cache.New(cfg.RedisAddr, cfg.RedisPass, cfg.RedisDB)
@@ -79,33 +97,135 @@ func main() {
 	paymentRepo := repository.NewPaymentRepository(gormDB)
 	paymentLogRepo := repository.NewPaymentLogRepository(gormDB)
 	transferRepo := repository.NewTransferRepository(gormDB)
+	payoutRepo := repository.NewPayoutRepository(gormDB)
+	webhookRepo := repository.NewWebhookRepository(gormDB)
+	statsRepo := repository.NewStatsRepository(gormDB)
+	txManager := repository.NewTxManager(gormDB)
 
 	// Initialize auth components
-	jwtService := auth.NewJWTService(cfg.JWTSecret)
-	tokenStore := auth.NewTokenStore(cacheClient)
+	var jwtService *auth.JWTService
+	switch cfg.JWTAlgorithm {
+	case config.JWTAlgorithmHS256:
+		jwtService = auth.NewJWTService(cfg.JWTSecret, cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
+	case config.JWTAlgorithmRS256:
+		privateKey, err := auth.LoadRSAKeyPair(cfg.JWTPrivateKeyFile, cfg.JWTPublicKeyFile)
+		if err != nil {
+			log.Fatalf("jwt keys: %v", err)
+		}
+		jwtService = auth.NewRS256JWTService(privateKey, cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
+	default:
+		log.Fatalf("JWT_ALGORITHM must be %s or %s, got %q", config.JWTAlgorithmHS256, config.JWTAlgorithmRS256, cfg.JWTAlgorithm)
+	}
+	tokenStore := auth.NewTokenStore(cacheClient, cfg.MaxSessionsPerUser)
+
+	// Webhook secrets are encrypted at rest; without a key the feature stays off
+	var webhookSecretBox *auth.SecretBox
+	if cfg.WebhookSecretKey != "" {
+		webhookSecretBox, err = auth.NewSecretBox(cfg.WebhookSecretKey)
+		if err != nil {
+			log.Fatalf("webhook secret key: %v", err)
+		}
+	}
+
+	// Merchant payment emails are delivered off the request path when SMTP is configured
+	var paymentNotifier *service.PaymentNotifier
+	if cfg.SMTPHost != "" {
+		paymentNotifier = service.NewPaymentNotifier(notify.NewSMTPNotifier(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom))
+		go service.SuperviseWorker(context.Background(), "payment_notifications", cfg.WorkerRestartDelay, paymentNotifier.Run)
+	}
+
+	// Balance alerts go out by email when SMTP is configured and to merchants' webhook endpoints
+	webhookClient := notify.NewWebhookClient(cfg.WebhookTimeout, cfg.WebhookMaxResponseBytes, cfg.WebhookAllowPrivateTargets)
+	webhookService := service.NewWebhookService(accountRepo, webhookRepo, webhookSecretBox, webhookClient, cacheClient, cfg.WebhookSecretRevealsPerHour, cfg.WebhookTestsPerHour)
+	var alertEmail notify.EmailNotifier
+	if cfg.SMTPHost != "" {
+		alertEmail = notify.NewSMTPNotifier(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom)
+	}
+	balanceAlerter := service.NewBalanceAlerter(alertEmail, webhookService, cacheClient, cfg.BalanceAlertDebounce)
+	go service.SuperviseWorker(context.Background(), "balance_alerts", cfg.WorkerRestartDelay, balanceAlerter.Run)
+
+	// New accounts must verify their email before signing in, and forgotten passwords can be
+	// reset, once SMTP can deliver the tokens
+	var emailVerifier *auth.EmailVerifier
+	var passwordResetter *auth.PasswordResetter
+	if cfg.SMTPHost != "" {
+		authEmail := notify.NewSMTPNotifier(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom)
+		emailVerifier = auth.NewEmailVerifier(cacheClient, authEmail, cfg.EmailVerificationTTL)
+		passwordResetter = auth.NewPasswordResetter(cacheClient, authEmail, cfg.PasswordResetTTL)
+	}
 
 	// Initialize services
-	authService := service.NewAuthService(accountRepo, jwtService, tokenStore)
+	loginLimiter := auth.NewLoginLimiter(cacheClient, cfg.LoginMaxAttempts, cfg.LoginMaxAttemptsPerIP, cfg.LoginLockoutWindow)
+	authService := service.NewAuthService(accountRepo, jwtService, tokenStore, loginLimiter, emailVerifier, passwordResetter)
 	accountService := service.NewAccountService(accountRepo, cardRepo, cacheClient)
-	paymentService := service.NewPaymentService(accountRepo, cardRepo, paymentRepo, paymentLogRepo, cacheClient)
-	transferService := service.NewTransferService(cardRepo, transferRepo, cacheClient)
+	paymentService := service.NewPaymentService(accountRepo, cardRepo, paymentRepo, paymentLogRepo, payoutRepo, txManager, paymentNotifier, balanceAlerter, cacheClient, cfg)
+	transferService := service.NewTransferService(cardRepo, transferRepo, cacheClient, cfg)
+	payoutService := service.NewPayoutService(accountRepo, payoutRepo, paymentRepo, txManager, clock.New(), cfg)
+	cardService := service.NewCardService(cardRepo, accountRepo, txManager, balanceAlerter, cacheClient, cfg)
+	adminStatsService := service.NewAdminStatsService(statsRepo, clock.New(), cfg.DefaultCurrency,
+		service.QueueGauge{Name: "payment_logs", Depth: paymentService.LogQueueDepth, Dropped: paymentService.DroppedLogs},
+		service.QueueGauge{Name: "payment_notifications", Depth: paymentNotifier.QueueDepth},
+		service.QueueGauge{Name: "balance_alerts", Depth: balanceAlerter.QueueDepth},
+	)
+
+	// Fresh deployments can seed accounts on startup; a populated table is left alone unless forced
+	if cfg.AutoSeed {
+		result, err := service.AutoSeedAccounts(context.Background(), accountRepo, accountService, cfg.SeedAccountsURL, cfg.AutoSeedForce)
+		switch {
+		case err != nil:
+			log.Printf("Auto-seed failed: %v", err)
+		case !result.Ran:
+			log.Printf("Auto-seed skipped: accounts table has %d rows (set AUTO_SEED_FORCE=true to seed anyway)", result.ExistingAccounts)
+		default:
+			log.Printf("Auto-seed completed: %d accounts seeded, %d invalid entries skipped", result.Seeded, result.Skipped)
+		}
+	}
+
+	// Archive completed payments past the retention window in the background
+	if cfg.PaymentRetention > 0 {
+		archiver := service.NewPaymentArchiver(paymentRepo, clock.New(), cfg.PaymentRetention)
+		go service.SuperviseWorker(context.Background(), "payment_archiver", cfg.WorkerRestartDelay, func(ctx context.Context) {
+			archiver.Run(ctx, cfg.PaymentArchiveInterval)
+		})
+	}
 
 	// Initialize handlers
 	authHandler := handler.NewAuthHandler(authService)
-	accountHandler := handler.NewAccountHandler(accountService)
+	accountHandler := handler.NewAccountHandler(accountService, service.NewAccountSnapshotService(accountRepo, cardRepo, paymentRepo, transferRepo, txManager, clock.New()))
 	paymentHandler := handler.NewPaymentHandler(paymentService)
 	transferHandler := handler.NewTransferHandler(transferService)
-	seedHandler := handler.NewSeedHandler(accountService)
+	cardHandler := handler.NewCardHandler(cardService)
+	seedHandler := handler.NewSeedHandler(accountService, cfg.SeedAccountsURL)
+	currencyHandler := handler.NewCurrencyHandler(currencies)
+	merchantSettingsService := service.NewMerchantSettingsService(accountRepo, cacheClient, cfg)
+	merchantHandler := handler.NewMerchantHandler(payoutService, paymentService, merchantSettingsService)
+	webhookHandler := handler.NewWebhookHandler(webhookService)
+	adminHandler := handler.NewAdminHandler(service.NewKillSwitchService(cacheClient, cfg), adminStatsService, service.NewAccountHoldService(cacheClient, clock.New(), cfg), merchantSettingsService)
+
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		log.Fatalf("database handle: %v", err)
+	}
+	healthHandler := handler.NewHealthHandler(sqlDB, cacheClient, cfg.ReadinessTimeout)
 
 	// Register routes
 	router.Register(
 		e,
 		cfg,
+		jwtService,
+		tokenStore,
+		cacheClient,
 		authHandler,
 		accountHandler,
 		paymentHandler,
 		transferHandler,
+		cardHandler,
 		seedHandler,
+		currencyHandler,
+		merchantHandler,
+		webhookHandler,
+		adminHandler,
+		healthHandler,
 	)
 
 	// Log swagger full path
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"paytabs/internal/cache"
+	"paytabs/internal/notify"
+)
+
+// emailVerificationKeyPrefix keys the account ID a verification token confirms, e.g.
+// email_verification:<token>.
+const emailVerificationKeyPrefix = "email_verification:"
+
+// EmailVerifier issues single-use tokens that confirm an account's email address. Each token
+// is kept in redis for ttl and mailed to the address it confirms. A nil *EmailVerifier means
+// verification is disabled.
+type EmailVerifier struct {
+	tokens   oneTimeTokens
+	notifier notify.EmailNotifier
+}
+
+// NewEmailVerifier creates a verifier whose tokens are sent through notifier and expire
+// after ttl.
+func NewEmailVerifier(cache *cache.Client, notifier notify.EmailNotifier, ttl time.Duration) *EmailVerifier {
+	return &EmailVerifier{
+		tokens:   oneTimeTokens{cache: cache, prefix: emailVerificationKeyPrefix, ttl: ttl},
+		notifier: notifier,
+	}
+}
+
+// Issue stores a new token for accountID and mails it to email. A token that could not be
+// stored is not sent, so every token a user receives can be redeemed.
+func (v *EmailVerifier) Issue(ctx context.Context, accountID uuid.UUID, email string) error {
+	token, err := v.tokens.issue(ctx, accountID)
+	if err != nil {
+		return fmt.Errorf("issue verification token: %w", err)
+	}
+
+	body := fmt.Sprintf("Confirm your email address with this verification token:\n\n%s\n\nIt expires in %s.", token, v.tokens.ttl)
+	return v.notifier.Send(ctx, email, "Verify your email address", body)
+}
+
+// Consume redeems token and returns the account it confirms. ok is false when the token is
+// unknown, expired or already redeemed.
+func (v *EmailVerifier) Consume(ctx context.Context, token string) (accountID uuid.UUID, ok bool) {
+	return v.tokens.consume(ctx, token)
+}
@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"paytabs/internal/cache"
+)
+
+// lastEmail records the last email sent through it.
+type lastEmail struct {
+	to, body string
+}
+
+func (e *lastEmail) Send(_ context.Context, to, _, body string) error {
+	e.to, e.body = to, body
+	return nil
+}
+
+var mailedTokenPattern = regexp.MustCompile(`[0-9a-f]{64}`)
+
+func TestEmailVerifier_IssueAndConsume(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	email := &lastEmail{}
+	v := NewEmailVerifier(cache.New(mr.Addr(), "", 0), email, time.Hour)
+	accountID := uuid.New()
+
+	require.NoError(t, v.Issue(ctx, accountID, "alice@example.com"))
+	assert.Equal(t, "alice@example.com", email.to)
+	token := mailedTokenPattern.FindString(email.body)
+	require.NotEmpty(t, token, "the email carries the token")
+	assert.Equal(t, time.Hour, mr.TTL(emailVerificationKeyPrefix+token))
+
+	got, ok := v.Consume(ctx, token)
+	assert.True(t, ok)
+	assert.Equal(t, accountID, got)
+
+	// Tokens are single use
+	_, ok = v.Consume(ctx, token)
+	assert.False(t, ok)
+}
+
+func TestEmailVerifier_ExpiredOrUnknownToken(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	email := &lastEmail{}
+	v := NewEmailVerifier(cache.New(mr.Addr(), "", 0), email, time.Hour)
+
+	require.NoError(t, v.Issue(ctx, uuid.New(), "alice@example.com"))
+	mr.FastForward(time.Hour + time.Second)
+
+	_, ok := v.Consume(ctx, mailedTokenPattern.FindString(email.body))
+	assert.False(t, ok)
+	_, ok = v.Consume(ctx, "not-a-token")
+	assert.False(t, ok)
+}
+
+func TestEmailVerifier_UnavailableCacheSendsNothing(t *testing.T) {
+	email := &lastEmail{}
+	v := NewEmailVerifier(nil, email, time.Hour)
+
+	err := v.Issue(context.Background(), uuid.New(), "alice@example.com")
+
+	assert.ErrorIs(t, err, cache.ErrUnavailable)
+	assert.Empty(t, email.to)
+}
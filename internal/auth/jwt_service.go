@@ -1,83 +1,180 @@
 package auth
 
 import (
+	"crypto/rsa"
 	"errors"
+	"fmt"
+	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
+
+	"paytabs/internal/clock"
 )
 
 const (
-	// AccessTokenExpiry is the duration for which access tokens are valid.
+	// AccessTokenExpiry is how long access tokens are valid when no lifetime is configured.
 	AccessTokenExpiry = 15 * time.Minute
-	// RefreshTokenExpiry is the duration for which refresh tokens are valid.
+	// RefreshTokenExpiry is how long refresh tokens are valid when no lifetime is configured.
 	RefreshTokenExpiry = 7 * 24 * time.Hour
+
+	// TokenTypeAccess marks tokens that authenticate API requests.
+	TokenTypeAccess = "access"
+	// TokenTypeRefresh marks tokens that are only exchanged for new tokens.
+	TokenTypeRefresh = "refresh"
 )
 
-// Claims represents JWT claims.
+// Claims represents JWT claims. AccountID is the full account UUID and identifies the caller;
+// UserID is a lossy number derived from it, kept for clients that still read it. TokenType
+// keeps the two kinds of token apart, as both are signed with the same key.
 type Claims struct {
-	UserID uint   `json:"user_id"`
-	Email  string `json:"email"`
+	UserID    uint   `json:"user_id"`
+	AccountID string `json:"account_id"`
+	Email     string `json:"email"`
+	TokenType string `json:"token_type"`
 	jwt.RegisteredClaims
 }
 
-// JWTService handles JWT token generation and validation.
+// JWTService handles JWT token generation and validation. Tokens are signed with HS256 and
+// a shared secret, or with RS256 so that other services can verify them with only the
+// public key. Tokens signed with any other algorithm are rejected.
 type JWTService struct {
-	secret []byte
+	method     jwt.SigningMethod
+	signKey    interface{}
+	verifyKey  interface{}
+	clock      clock.Clock
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+// NewJWTService creates a new JWT service with the given secret whose access and refresh
+// tokens are valid for accessTTL and refreshTTL. A non-positive lifetime falls back to
+// AccessTokenExpiry or RefreshTokenExpiry.
+func NewJWTService(secret string, accessTTL, refreshTTL time.Duration) *JWTService {
+	return NewJWTServiceWithClock(secret, accessTTL, refreshTTL, clock.New())
+}
+
+// NewJWTServiceWithClock creates a JWT service that reads the current time from clk
+// when issuing and validating tokens.
+func NewJWTServiceWithClock(secret string, accessTTL, refreshTTL time.Duration, clk clock.Clock) *JWTService {
+	return newJWTService(jwt.SigningMethodHS256, []byte(secret), []byte(secret), accessTTL, refreshTTL, clk)
 }
 
-// NewJWTService creates a new JWT service with the given secret.
-func NewJWTService(secret string) *JWTService {
+// NewRS256JWTService creates a JWT service that signs tokens with privateKey and verifies
+// them with its public key. Lifetimes are as for NewJWTService.
+func NewRS256JWTService(privateKey *rsa.PrivateKey, accessTTL, refreshTTL time.Duration) *JWTService {
+	return NewRS256JWTServiceWithClock(privateKey, accessTTL, refreshTTL, clock.New())
+}
+
+// NewRS256JWTServiceWithClock creates an RS256 JWT service that reads the current time
+// from clk when issuing and validating tokens.
+func NewRS256JWTServiceWithClock(privateKey *rsa.PrivateKey, accessTTL, refreshTTL time.Duration, clk clock.Clock) *JWTService {
+	return newJWTService(jwt.SigningMethodRS256, privateKey, &privateKey.PublicKey, accessTTL, refreshTTL, clk)
+}
+
+func newJWTService(method jwt.SigningMethod, signKey, verifyKey interface{}, accessTTL, refreshTTL time.Duration, clk clock.Clock) *JWTService {
+	if accessTTL <= 0 {
+		accessTTL = AccessTokenExpiry
+	}
+	if refreshTTL <= 0 {
+		refreshTTL = RefreshTokenExpiry
+	}
 	return &JWTService{
-		secret: []byte(secret),
+		method:     method,
+		signKey:    signKey,
+		verifyKey:  verifyKey,
+		clock:      clk,
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+	}
+}
+
+// LoadRSAKeyPair reads a PEM-encoded RSA private key and checks that the PEM-encoded
+// public key, the one handed to services that verify tokens, belongs to it.
+func LoadRSAKeyPair(privateKeyPath, publicKeyPath string) (*rsa.PrivateKey, error) {
+	privatePEM, err := os.ReadFile(privateKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("read private key: %w", err)
+	}
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(privatePEM)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+
+	publicPEM, err := os.ReadFile(publicKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("read public key: %w", err)
+	}
+	publicKey, err := jwt.ParseRSAPublicKeyFromPEM(publicPEM)
+	if err != nil {
+		return nil, fmt.Errorf("parse public key: %w", err)
+	}
+	if !privateKey.PublicKey.Equal(publicKey) {
+		return nil, errors.New("public key does not match the private key")
 	}
+	return privateKey, nil
 }
 
-// GenerateAccessToken generates a new access token for the user.
-func (s *JWTService) GenerateAccessToken(userID uint, email string) (string, error) {
+// RefreshTokenTTL is how long the refresh tokens this service issues are valid.
+func (s *JWTService) RefreshTokenTTL() time.Duration {
+	return s.refreshTTL
+}
+
+// GenerateAccessToken generates a new access token for the user. Its token ID (JTI) lets
+// the token be blacklisted on logout before it expires.
+func (s *JWTService) GenerateAccessToken(userID uint, accountID, email string) (string, error) {
+	now := s.clock.Now()
 	claims := &Claims{
-		UserID: userID,
-		Email:  email,
+		UserID:    userID,
+		AccountID: accountID,
+		Email:     email,
+		TokenType: TokenTypeAccess,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenExpiry)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			ID:        generateTokenID(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.accessTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(s.secret)
+	token := jwt.NewWithClaims(s.method, claims)
+	return token.SignedString(s.signKey)
 }
 
 // GenerateRefreshToken generates a new refresh token for the user.
 // The refresh token ID is returned separately for storage in Redis.
-func (s *JWTService) GenerateRefreshToken(userID uint, email string) (tokenID string, token string, err error) {
+func (s *JWTService) GenerateRefreshToken(userID uint, accountID, email string) (tokenID string, token string, err error) {
 	tokenID = generateTokenID()
+	now := s.clock.Now()
 	claims := &Claims{
-		UserID: userID,
-		Email:  email,
+		UserID:    userID,
+		AccountID: accountID,
+		Email:     email,
+		TokenType: TokenTypeRefresh,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ID:        tokenID,
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(RefreshTokenExpiry)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.refreshTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 		},
 	}
 
-	tokenObj := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	token, err = tokenObj.SignedString(s.secret)
+	tokenObj := jwt.NewWithClaims(s.method, claims)
+	token, err = tokenObj.SignedString(s.signKey)
 	return tokenID, token, err
 }
 
-// ValidateToken validates a JWT token and returns the claims.
+// ValidateToken validates a JWT token and returns the claims. The token must be signed
+// with the service's algorithm, so an HS256 token is never checked against an RS256
+// service's public key as if it were a secret.
 func (s *JWTService) ValidateToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		if token.Method.Alg() != s.method.Alg() {
 			return nil, errors.New("unexpected signing method")
 		}
-		return s.secret, nil
-	})
+		return s.verifyKey, nil
+	}, jwt.WithoutClaimsValidation())
 
 	if err != nil {
 		return nil, err
@@ -88,12 +185,60 @@ func (s *JWTService) ValidateToken(tokenString string) (*Claims, error) {
 		return nil, errors.New("invalid token")
 	}
 
+	// Time-based claims are checked against the service clock rather than jwt.TimeFunc
+	now := s.clock.Now()
+	if !claims.VerifyExpiresAt(now, true) {
+		return nil, errors.New("token is expired")
+	}
+	if !claims.VerifyIssuedAt(now, false) {
+		return nil, errors.New("token used before issued")
+	}
+	if !claims.VerifyNotBefore(now, false) {
+		return nil, errors.New("token is not valid yet")
+	}
+
 	return claims, nil
 }
 
+// ValidateAccessToken validates tokenString like ValidateToken and rejects anything but an
+// access token, so a refresh token cannot authenticate API requests.
+func (s *JWTService) ValidateAccessToken(tokenString string) (*Claims, error) {
+	return s.validateTokenType(tokenString, TokenTypeAccess)
+}
+
+// ValidateRefreshToken validates tokenString like ValidateToken and rejects anything but a
+// refresh token.
+func (s *JWTService) ValidateRefreshToken(tokenString string) (*Claims, error) {
+	return s.validateTokenType(tokenString, TokenTypeRefresh)
+}
+
+func (s *JWTService) validateTokenType(tokenString, tokenType string) (*Claims, error) {
+	claims, err := s.ValidateToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenType != tokenType {
+		return nil, errors.New("unexpected token type")
+	}
+	return claims, nil
+}
+
+// RemainingLifetime is how long the token with the given claims stays valid, zero once it
+// has expired or when it carries no expiry.
+func (s *JWTService) RemainingLifetime(claims *Claims) time.Duration {
+	if claims.ExpiresAt == nil {
+		return 0
+	}
+	remaining := claims.ExpiresAt.Time.Sub(s.clock.Now())
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
 // ExtractTokenID extracts the token ID (JTI) from a refresh token.
 func (s *JWTService) ExtractTokenID(tokenString string) (string, error) {
-	claims, err := s.ValidateToken(tokenString)
+	claims, err := s.ValidateRefreshToken(tokenString)
 	if err != nil {
 		return "", err
 	}
@@ -103,7 +248,7 @@ func (s *JWTService) ExtractTokenID(tokenString string) (string, error) {
 	return claims.ID, nil
 }
 
-// generateTokenID generates a unique token ID for refresh tokens.
+// generateTokenID generates a unique token ID for access and refresh tokens.
 func generateTokenID() string {
 	return uuid.New().String()
 }
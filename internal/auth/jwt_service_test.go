@@ -0,0 +1,238 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"paytabs/internal/clock"
+)
+
+func TestJWTService_AccessTokenExpiry(t *testing.T) {
+	issuedAt := time.Date(2026, time.January, 1, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewFixed(issuedAt)
+	service := NewJWTServiceWithClock("test-secret", AccessTokenExpiry, RefreshTokenExpiry, clk)
+
+	token, err := service.GenerateAccessToken(42, "c56a4180-65aa-42ec-a945-5fd21dec0538", "test@example.com")
+	require.NoError(t, err)
+
+	// One second before expiry the token is still accepted
+	clk.Set(issuedAt.Add(AccessTokenExpiry - time.Second))
+	claims, err := service.ValidateToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, uint(42), claims.UserID)
+	assert.Equal(t, "test@example.com", claims.Email)
+
+	// One second after expiry the token is rejected
+	clk.Set(issuedAt.Add(AccessTokenExpiry + time.Second))
+	_, err = service.ValidateToken(token)
+	assert.Error(t, err)
+}
+
+func TestJWTService_ConfiguredLifetimes(t *testing.T) {
+	issuedAt := time.Date(2026, time.January, 1, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewFixed(issuedAt)
+	service := NewJWTServiceWithClock("test-secret", 5*time.Minute, time.Hour, clk)
+
+	access, err := service.GenerateAccessToken(42, "c56a4180-65aa-42ec-a945-5fd21dec0538", "test@example.com")
+	require.NoError(t, err)
+	_, refresh, err := service.GenerateRefreshToken(42, "c56a4180-65aa-42ec-a945-5fd21dec0538", "test@example.com")
+	require.NoError(t, err)
+
+	accessClaims, err := service.ValidateToken(access)
+	require.NoError(t, err)
+	refreshClaims, err := service.ValidateToken(refresh)
+	require.NoError(t, err)
+	assert.Equal(t, issuedAt.Add(5*time.Minute), accessClaims.ExpiresAt.Time.UTC())
+	assert.Equal(t, issuedAt.Add(time.Hour), refreshClaims.ExpiresAt.Time.UTC())
+	assert.Equal(t, time.Hour, service.RefreshTokenTTL())
+}
+
+func TestJWTService_TokenTypes(t *testing.T) {
+	service := NewJWTService("test-secret", AccessTokenExpiry, RefreshTokenExpiry)
+	access, err := service.GenerateAccessToken(42, "c56a4180-65aa-42ec-a945-5fd21dec0538", "test@example.com")
+	require.NoError(t, err)
+	_, refresh, err := service.GenerateRefreshToken(42, "c56a4180-65aa-42ec-a945-5fd21dec0538", "test@example.com")
+	require.NoError(t, err)
+
+	claims, err := service.ValidateAccessToken(access)
+	require.NoError(t, err)
+	assert.Equal(t, TokenTypeAccess, claims.TokenType)
+	claims, err = service.ValidateRefreshToken(refresh)
+	require.NoError(t, err)
+	assert.Equal(t, TokenTypeRefresh, claims.TokenType)
+
+	// Both are signed with the same key, so only the type claim tells them apart
+	_, err = service.ValidateAccessToken(refresh)
+	assert.Error(t, err)
+	_, err = service.ValidateRefreshToken(access)
+	assert.Error(t, err)
+	_, err = service.ExtractTokenID(access)
+	assert.Error(t, err)
+}
+
+func TestJWTService_NonPositiveLifetimesUseDefaults(t *testing.T) {
+	service := NewJWTService("test-secret", 0, -time.Hour)
+
+	assert.Equal(t, AccessTokenExpiry, service.accessTTL)
+	assert.Equal(t, RefreshTokenExpiry, service.RefreshTokenTTL())
+}
+
+func TestJWTService_AccountIDRoundTrips(t *testing.T) {
+	service := NewJWTService("test-secret", AccessTokenExpiry, RefreshTokenExpiry)
+	// The UUIDs share their first four bytes, so the UserID derived at login cannot tell them apart
+	accountIDs := []uuid.UUID{
+		uuid.MustParse("c56a4180-65aa-42ec-a945-5fd21dec0538"),
+		uuid.MustParse("c56a4180-0000-4000-8000-000000000001"),
+	}
+
+	for _, accountID := range accountIDs {
+		access, err := service.GenerateAccessToken(42, accountID.String(), "test@example.com")
+		require.NoError(t, err)
+		_, refresh, err := service.GenerateRefreshToken(42, accountID.String(), "test@example.com")
+		require.NoError(t, err)
+
+		for _, token := range []string{access, refresh} {
+			claims, err := service.ValidateToken(token)
+			require.NoError(t, err)
+			parsed, err := uuid.Parse(claims.AccountID)
+			require.NoError(t, err)
+			assert.Equal(t, accountID, parsed)
+			assert.Equal(t, uint(42), claims.UserID)
+		}
+	}
+}
+
+func TestJWTService_RejectsTokenBeforeIssue(t *testing.T) {
+	issuedAt := time.Date(2026, time.January, 1, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewFixed(issuedAt)
+	service := NewJWTServiceWithClock("test-secret", AccessTokenExpiry, RefreshTokenExpiry, clk)
+
+	_, token, err := service.GenerateRefreshToken(42, "c56a4180-65aa-42ec-a945-5fd21dec0538", "test@example.com")
+	require.NoError(t, err)
+
+	clk.Set(issuedAt.Add(-time.Minute))
+	_, err = service.ValidateToken(token)
+	assert.Error(t, err)
+}
+
+func TestJWTService_AccessTokenIDAndRemainingLifetime(t *testing.T) {
+	issuedAt := time.Date(2026, time.January, 1, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewFixed(issuedAt)
+	service := NewJWTServiceWithClock("test-secret", AccessTokenExpiry, RefreshTokenExpiry, clk)
+
+	first, err := service.GenerateAccessToken(42, "c56a4180-65aa-42ec-a945-5fd21dec0538", "test@example.com")
+	require.NoError(t, err)
+	second, err := service.GenerateAccessToken(42, "c56a4180-65aa-42ec-a945-5fd21dec0538", "test@example.com")
+	require.NoError(t, err)
+
+	firstClaims, err := service.ValidateToken(first)
+	require.NoError(t, err)
+	secondClaims, err := service.ValidateToken(second)
+	require.NoError(t, err)
+	assert.NotEmpty(t, firstClaims.ID, "access tokens carry a token ID so they can be blacklisted")
+	assert.NotEqual(t, firstClaims.ID, secondClaims.ID)
+
+	assert.Equal(t, AccessTokenExpiry, service.RemainingLifetime(firstClaims))
+	clk.Set(issuedAt.Add(10 * time.Minute))
+	assert.Equal(t, AccessTokenExpiry-10*time.Minute, service.RemainingLifetime(firstClaims))
+	clk.Set(issuedAt.Add(AccessTokenExpiry + time.Minute))
+	assert.Zero(t, service.RemainingLifetime(firstClaims))
+	assert.Zero(t, service.RemainingLifetime(&Claims{}))
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key
+}
+
+func TestJWTService_RS256(t *testing.T) {
+	key := newRSAKey(t)
+	service := NewRS256JWTService(key, AccessTokenExpiry, RefreshTokenExpiry)
+
+	access, err := service.GenerateAccessToken(42, "c56a4180-65aa-42ec-a945-5fd21dec0538", "test@example.com")
+	require.NoError(t, err)
+	claims, err := service.ValidateToken(access)
+	require.NoError(t, err)
+	assert.Equal(t, "c56a4180-65aa-42ec-a945-5fd21dec0538", claims.AccountID)
+
+	tokenID, refresh, err := service.GenerateRefreshToken(42, "c56a4180-65aa-42ec-a945-5fd21dec0538", "test@example.com")
+	require.NoError(t, err)
+	extracted, err := service.ExtractTokenID(refresh)
+	require.NoError(t, err)
+	assert.Equal(t, tokenID, extracted)
+
+	// Another service verifies the token with the public key alone
+	parsed, err := jwt.ParseWithClaims(access, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		return &key.PublicKey, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "RS256", parsed.Method.Alg())
+}
+
+func TestJWTService_RS256RejectsOtherAlgorithms(t *testing.T) {
+	key := newRSAKey(t)
+	rs256 := NewRS256JWTService(key, AccessTokenExpiry, RefreshTokenExpiry)
+	hs256 := NewJWTService("test-secret", AccessTokenExpiry, RefreshTokenExpiry)
+
+	hsToken, err := hs256.GenerateAccessToken(42, "c56a4180-65aa-42ec-a945-5fd21dec0538", "test@example.com")
+	require.NoError(t, err)
+	_, err = rs256.ValidateToken(hsToken)
+	assert.Error(t, err, "an HS256 token is rejected when RS256 is configured")
+
+	// The public key is not secret, so an HS256 token signed with it must not pass either
+	publicDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{
+		AccountID:        "c56a4180-65aa-42ec-a945-5fd21dec0538",
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+	}).SignedString(publicPEM)
+	require.NoError(t, err)
+	_, err = rs256.ValidateToken(forged)
+	assert.Error(t, err)
+
+	rsToken, err := rs256.GenerateAccessToken(42, "c56a4180-65aa-42ec-a945-5fd21dec0538", "test@example.com")
+	require.NoError(t, err)
+	_, err = hs256.ValidateToken(rsToken)
+	assert.Error(t, err, "an RS256 token is rejected when HS256 is configured")
+}
+
+func TestLoadRSAKeyPair(t *testing.T) {
+	dir := t.TempDir()
+	writeKey := func(name string, block *pem.Block) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(block), 0o600))
+		return path
+	}
+	publicKeyFile := func(name string, key *rsa.PrivateKey) string {
+		der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		require.NoError(t, err)
+		return writeKey(name, &pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	}
+
+	key := newRSAKey(t)
+	privatePath := writeKey("private.pem", &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	loaded, err := LoadRSAKeyPair(privatePath, publicKeyFile("public.pem", key))
+	require.NoError(t, err)
+	assert.True(t, key.Equal(loaded))
+
+	_, err = LoadRSAKeyPair(privatePath, publicKeyFile("other.pem", newRSAKey(t)))
+	assert.Error(t, err, "a public key from another pair is rejected")
+
+	_, err = LoadRSAKeyPair(filepath.Join(dir, "missing.pem"), publicKeyFile("public.pem", key))
+	assert.Error(t, err)
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"paytabs/internal/cache"
+)
+
+const (
+	loginFailEmailKeyPrefix = "login_fail:email:"
+	loginFailIPKeyPrefix    = "login_fail:ip:"
+)
+
+// LoginLimiter throttles failed logins on two dimensions: per target email, so rotating
+// IPs against one account does not help, and per client IP, so one client cannot spray
+// many accounts. Failures are counted in windows that start at the first failure and
+// expire on their own, so a lock set by an attacker lifts after at most one window.
+// Failed attempts made while blocked are not counted and do not extend the lock. A nil
+// *LoginLimiter, or an unavailable cache, never blocks.
+type LoginLimiter struct {
+	cache       *cache.Client
+	maxPerEmail int
+	maxPerIP    int
+	window      time.Duration
+}
+
+// NewLoginLimiter creates a limiter allowing maxPerEmail failures per email and maxPerIP
+// failures per IP within window. A limit of zero or less disables that dimension.
+func NewLoginLimiter(cache *cache.Client, maxPerEmail, maxPerIP int, window time.Duration) *LoginLimiter {
+	return &LoginLimiter{
+		cache:       cache,
+		maxPerEmail: maxPerEmail,
+		maxPerIP:    maxPerIP,
+		window:      window,
+	}
+}
+
+// Blocked reports whether a login for email from ip should be refused without checking
+// the password.
+func (l *LoginLimiter) Blocked(ctx context.Context, email, ip string) bool {
+	if l == nil {
+		return false
+	}
+	if l.maxPerEmail > 0 {
+		if n, ok := l.cache.GetInt(ctx, loginFailEmailKeyPrefix+email); ok && n >= int64(l.maxPerEmail) {
+			return true
+		}
+	}
+	if l.maxPerIP > 0 && ip != "" {
+		if n, ok := l.cache.GetInt(ctx, loginFailIPKeyPrefix+ip); ok && n >= int64(l.maxPerIP) {
+			return true
+		}
+	}
+	return false
+}
+
+// RecordFailure counts a failed login against both the email and the IP.
+func (l *LoginLimiter) RecordFailure(ctx context.Context, email, ip string) {
+	if l == nil {
+		return
+	}
+	if l.maxPerEmail > 0 {
+		l.cache.IncrBy(ctx, loginFailEmailKeyPrefix+email, 1, l.window)
+	}
+	if l.maxPerIP > 0 && ip != "" {
+		l.cache.IncrBy(ctx, loginFailIPKeyPrefix+ip, 1, l.window)
+	}
+}
+
+// Reset clears the email's failure count after a successful login. The IP count is kept
+// so a client spraying many accounts is not reset by one correct guess.
+func (l *LoginLimiter) Reset(ctx context.Context, email string) {
+	if l == nil {
+		return
+	}
+	_ = l.cache.Delete(ctx, loginFailEmailKeyPrefix+email)
+}
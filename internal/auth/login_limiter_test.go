@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+
+	"paytabs/internal/cache"
+)
+
+func newTestLoginLimiter(t *testing.T, maxPerEmail, maxPerIP int) (*LoginLimiter, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	return NewLoginLimiter(cache.New(mr.Addr(), "", 0), maxPerEmail, maxPerIP, 15*time.Minute), mr
+}
+
+func TestLoginLimiter_PerEmailAcrossIPs(t *testing.T) {
+	ctx := context.Background()
+	l, _ := newTestLoginLimiter(t, 3, 100)
+
+	// An attacker rotating IPs still trips the email limit
+	for i := 0; i < 3; i++ {
+		assert.False(t, l.Blocked(ctx, "victim@example.com", fmt.Sprintf("10.0.0.%d", i)))
+		l.RecordFailure(ctx, "victim@example.com", fmt.Sprintf("10.0.0.%d", i))
+	}
+
+	assert.True(t, l.Blocked(ctx, "victim@example.com", "10.0.0.99"))
+	assert.False(t, l.Blocked(ctx, "other@example.com", "10.0.0.99"))
+}
+
+func TestLoginLimiter_PerIPAcrossEmails(t *testing.T) {
+	ctx := context.Background()
+	l, _ := newTestLoginLimiter(t, 100, 3)
+
+	// One client spraying many accounts trips the IP limit
+	for i := 0; i < 3; i++ {
+		l.RecordFailure(ctx, fmt.Sprintf("user%d@example.com", i), "10.0.0.1")
+	}
+
+	assert.True(t, l.Blocked(ctx, "fresh@example.com", "10.0.0.1"))
+	assert.False(t, l.Blocked(ctx, "fresh@example.com", "10.0.0.2"))
+}
+
+func TestLoginLimiter_WindowExpiresAndResetClearsEmail(t *testing.T) {
+	ctx := context.Background()
+	l, mr := newTestLoginLimiter(t, 2, 100)
+
+	l.RecordFailure(ctx, "user@example.com", "10.0.0.1")
+	l.RecordFailure(ctx, "user@example.com", "10.0.0.1")
+	assert.True(t, l.Blocked(ctx, "user@example.com", "10.0.0.1"))
+
+	// The lock lifts on its own once the window passes
+	mr.FastForward(16 * time.Minute)
+	assert.False(t, l.Blocked(ctx, "user@example.com", "10.0.0.1"))
+
+	l.RecordFailure(ctx, "user@example.com", "10.0.0.1")
+	l.Reset(ctx, "user@example.com")
+	l.RecordFailure(ctx, "user@example.com", "10.0.0.1")
+	assert.False(t, l.Blocked(ctx, "user@example.com", "10.0.0.1"))
+}
+
+func TestLoginLimiter_NilNeverBlocks(t *testing.T) {
+	var l *LoginLimiter
+	l.RecordFailure(context.Background(), "user@example.com", "10.0.0.1")
+	assert.False(t, l.Blocked(context.Background(), "user@example.com", "10.0.0.1"))
+}
@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"paytabs/internal/cache"
+)
+
+// oneTimeTokens keeps random single-use tokens in redis under prefix for ttl, each naming
+// the account it was issued for.
+type oneTimeTokens struct {
+	cache  *cache.Client
+	prefix string
+	ttl    time.Duration
+}
+
+// issue stores a new token for accountID. Redis errors are reported, so a token is only
+// handed out once it can be redeemed.
+func (t oneTimeTokens) issue(ctx context.Context, accountID uuid.UUID) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("generate token: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+
+	if err := t.cache.Put(ctx, t.prefix+token, []byte(accountID.String()), t.ttl); err != nil {
+		return "", fmt.Errorf("store token: %w", err)
+	}
+	return token, nil
+}
+
+// consume atomically redeems token, so of concurrent attempts only one succeeds. ok is false
+// when the token is unknown, expired or already redeemed.
+func (t oneTimeTokens) consume(ctx context.Context, token string) (accountID uuid.UUID, ok bool) {
+	data, _ := t.cache.Take(ctx, t.prefix+token)
+	if data == nil {
+		return uuid.Nil, false
+	}
+	accountID, err := uuid.ParseBytes(data)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return accountID, true
+}
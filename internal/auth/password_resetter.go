@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"paytabs/internal/cache"
+	"paytabs/internal/notify"
+)
+
+// passwordResetKeyPrefix keys the account ID a password reset token is for, e.g.
+// password_reset:<token>.
+const passwordResetKeyPrefix = "password_reset:"
+
+// PasswordResetter issues single-use tokens that let the owner of an account's email set a
+// new password. Each token is kept in redis for ttl and mailed to the account. A nil
+// *PasswordResetter means password resets are disabled.
+type PasswordResetter struct {
+	tokens   oneTimeTokens
+	notifier notify.EmailNotifier
+}
+
+// NewPasswordResetter creates a resetter whose tokens are sent through notifier and expire
+// after ttl.
+func NewPasswordResetter(cache *cache.Client, notifier notify.EmailNotifier, ttl time.Duration) *PasswordResetter {
+	return &PasswordResetter{
+		tokens:   oneTimeTokens{cache: cache, prefix: passwordResetKeyPrefix, ttl: ttl},
+		notifier: notifier,
+	}
+}
+
+// Issue stores a new reset token for accountID and mails it to email.
+func (r *PasswordResetter) Issue(ctx context.Context, accountID uuid.UUID, email string) error {
+	token, err := r.tokens.issue(ctx, accountID)
+	if err != nil {
+		return fmt.Errorf("issue password reset token: %w", err)
+	}
+
+	body := fmt.Sprintf("A password reset was requested for your account. Set a new password with this token:\n\n%s\n\nIt expires in %s. If you did not ask for this, ignore this email.", token, r.tokens.ttl)
+	return r.notifier.Send(ctx, email, "Reset your password", body)
+}
+
+// Consume redeems token and returns the account whose password it may reset. ok is false
+// when the token is unknown, expired or already redeemed.
+func (r *PasswordResetter) Consume(ctx context.Context, token string) (accountID uuid.UUID, ok bool) {
+	return r.tokens.consume(ctx, token)
+}
@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"paytabs/internal/cache"
+)
+
+func TestPasswordResetter_IssueAndConsume(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	email := &lastEmail{}
+	r := NewPasswordResetter(cache.New(mr.Addr(), "", 0), email, 15*time.Minute)
+	accountID := uuid.New()
+
+	require.NoError(t, r.Issue(ctx, accountID, "alice@example.com"))
+	assert.Equal(t, "alice@example.com", email.to)
+	token := mailedTokenPattern.FindString(email.body)
+	require.NotEmpty(t, token)
+	assert.Equal(t, 15*time.Minute, mr.TTL(passwordResetKeyPrefix+token))
+
+	// Reset tokens do not verify emails
+	_, ok := NewEmailVerifier(cache.New(mr.Addr(), "", 0), email, time.Hour).Consume(ctx, token)
+	assert.False(t, ok)
+
+	got, ok := r.Consume(ctx, token)
+	assert.True(t, ok)
+	assert.Equal(t, accountID, got)
+	_, ok = r.Consume(ctx, token)
+	assert.False(t, ok, "tokens are single use")
+}
@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretBox_RoundTrip(t *testing.T) {
+	box, err := NewSecretBox("test-key")
+	require.NoError(t, err)
+
+	sealed, err := box.Seal("whsec_abc", []byte("account-1"))
+	require.NoError(t, err)
+	assert.NotContains(t, sealed, "whsec_abc")
+
+	opened, err := box.Open(sealed, []byte("account-1"))
+	require.NoError(t, err)
+	assert.Equal(t, "whsec_abc", opened)
+
+	// Sealing twice yields different ciphertexts thanks to the random nonce
+	again, err := box.Seal("whsec_abc", []byte("account-1"))
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again)
+}
+
+func TestSecretBox_RejectsWrongKeyOrOwner(t *testing.T) {
+	box, _ := NewSecretBox("test-key")
+	sealed, err := box.Seal("whsec_abc", []byte("account-1"))
+	require.NoError(t, err)
+
+	_, err = box.Open(sealed, []byte("account-2"))
+	assert.Equal(t, ErrMalformedSealedValue, err)
+
+	other, _ := NewSecretBox("other-key")
+	_, err = other.Open(sealed, []byte("account-1"))
+	assert.Equal(t, ErrMalformedSealedValue, err)
+
+	_, err = box.Open("not base64!", []byte("account-1"))
+	assert.Equal(t, ErrMalformedSealedValue, err)
+
+	_, err = NewSecretBox("")
+	assert.Error(t, err)
+}
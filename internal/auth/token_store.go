@@ -3,44 +3,60 @@ package auth
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"time"
 
 	"paytabs/internal/cache"
+	"paytabs/internal/clock"
 )
 
 const (
 	refreshTokenKeyPrefix = "refresh_token:"
 	accessTokenKeyPrefix  = "blacklist:access_token:"
+	// userSessionsKeyPrefix keys a sorted set of each account's refresh token IDs, scored by
+	// creation time, e.g. user_sessions:<accountID>.
+	userSessionsKeyPrefix = "user_sessions:"
 )
 
 // TokenStoreInterface defines the interface for token storage operations.
 type TokenStoreInterface interface {
-	StoreRefreshToken(ctx context.Context, tokenID string, userID uint, email string, ttl time.Duration) error
+	StoreRefreshToken(ctx context.Context, tokenID string, userID uint, accountID, email string, ttl time.Duration) error
 	GetRefreshToken(ctx context.Context, tokenID string) (userID uint, email string, err error)
 	DeleteRefreshToken(ctx context.Context, tokenID string) error
+	RevokeAllForUser(ctx context.Context, accountID string) error
+	ConsumeRefreshToken(ctx context.Context, tokenID string) (userID uint, email string, err error)
 	BlacklistAccessToken(ctx context.Context, tokenID string, ttl time.Duration) error
 	IsAccessTokenBlacklisted(ctx context.Context, tokenID string) (bool, error)
 }
 
-// TokenStore handles storage and retrieval of tokens in Redis.
+// TokenStore handles storage and retrieval of tokens in Redis. Each account's refresh tokens
+// are also indexed by creation time so the oldest can be evicted once it holds maxSessions.
 type TokenStore struct {
-	cache *cache.Client
+	cache       *cache.Client
+	clock       clock.Clock
+	maxSessions int
 }
 
 // Ensure TokenStore implements TokenStoreInterface
 var _ TokenStoreInterface = (*TokenStore)(nil)
 
-// NewTokenStore creates a new token store.
-func NewTokenStore(cache *cache.Client) *TokenStore {
-	return &TokenStore{cache: cache}
+// NewTokenStore creates a new token store keeping at most maxSessions refresh tokens per
+// user. Zero or less leaves the number unbounded.
+func NewTokenStore(cache *cache.Client, maxSessions int) *TokenStore {
+	return &TokenStore{cache: cache, clock: clock.New(), maxSessions: maxSessions}
 }
 
-// StoreRefreshToken stores a refresh token in Redis with TTL.
-func (s *TokenStore) StoreRefreshToken(ctx context.Context, tokenID string, userID uint, email string, ttl time.Duration) error {
+// StoreRefreshToken stores a refresh token in Redis with TTL. When the account then holds more
+// than maxSessions refresh tokens, the oldest are deleted.
+func (s *TokenStore) StoreRefreshToken(ctx context.Context, tokenID string, userID uint, accountID, email string, ttl time.Duration) error {
+	createdAt := s.clock.Now().UTC()
 	data := map[string]interface{}{
-		"user_id": userID,
-		"email":   email,
+		"user_id":    userID,
+		"account_id": accountID,
+		"email":      email,
+		"created_at": createdAt.Format(time.RFC3339Nano),
 	}
 	payload, err := json.Marshal(data)
 	if err != nil {
@@ -48,7 +64,21 @@ func (s *TokenStore) StoreRefreshToken(ctx context.Context, tokenID string, user
 	}
 
 	key := refreshTokenKeyPrefix + tokenID
-	return s.cache.Set(ctx, key, payload, ttl)
+	if err := s.cache.Set(ctx, key, payload, ttl); err != nil {
+		return err
+	}
+
+	// Keyed by the full account ID rather than userID, which is derived from part of it and
+	// may be shared by two accounts. Tokens that expired on their own stay in the set until
+	// they are the oldest and are trimmed.
+	evicted := s.cache.ZAddCapped(ctx, userSessionsKeyPrefix+accountID, float64(createdAt.UnixNano()), tokenID, s.maxSessions, ttl)
+	for _, oldID := range evicted {
+		_ = s.cache.Delete(ctx, refreshTokenKeyPrefix+oldID)
+	}
+	if len(evicted) > 0 {
+		log.Printf("token store: account %s exceeded %d sessions, evicted %d oldest refresh token(s)", accountID, s.maxSessions, len(evicted))
+	}
+	return nil
 }
 
 // GetRefreshToken retrieves refresh token data from Redis.
@@ -58,47 +88,110 @@ func (s *TokenStore) GetRefreshToken(ctx context.Context, tokenID string) (userI
 	if err != nil || data == nil {
 		return 0, "", fmt.Errorf("refresh token not found")
 	}
+	token, err := decodeRefreshToken(data)
+	if err != nil {
+		return 0, "", err
+	}
+	return token.userID, token.email, nil
+}
+
+// ConsumeRefreshToken retrieves a refresh token's data and deletes the token in one step,
+// so a token presented twice, even concurrently, is accepted only once.
+func (s *TokenStore) ConsumeRefreshToken(ctx context.Context, tokenID string) (userID uint, email string, err error) {
+	data, err := s.cache.Take(ctx, refreshTokenKeyPrefix+tokenID)
+	if err != nil || data == nil {
+		return 0, "", fmt.Errorf("refresh token not found")
+	}
+	token, err := decodeRefreshToken(data)
+	if err != nil {
+		return 0, "", err
+	}
+	_ = s.cache.ZRem(ctx, userSessionsKeyPrefix+token.accountID, tokenID)
+	return token.userID, token.email, nil
+}
 
+// refreshTokenData is what is stored for a refresh token.
+type refreshTokenData struct {
+	userID    uint
+	accountID string
+	email     string
+}
+
+// decodeRefreshToken parses the data stored for a refresh token.
+func decodeRefreshToken(data []byte) (*refreshTokenData, error) {
 	var tokenData map[string]interface{}
 	if err := json.Unmarshal(data, &tokenData); err != nil {
-		return 0, "", fmt.Errorf("unmarshal token data: %w", err)
+		return nil, fmt.Errorf("unmarshal token data: %w", err)
 	}
 
-	// Extract user_id and email
+	// Extract user_id, account_id, and email
 	uid, ok := tokenData["user_id"].(float64)
 	if !ok {
-		return 0, "", fmt.Errorf("invalid user_id in token data")
+		return nil, fmt.Errorf("invalid user_id in token data")
 	}
-	userID = uint(uid)
 
-	email, ok = tokenData["email"].(string)
+	accountID, ok := tokenData["account_id"].(string)
 	if !ok {
-		return 0, "", fmt.Errorf("invalid email in token data")
+		return nil, fmt.Errorf("invalid account_id in token data")
 	}
 
-	return userID, email, nil
+	email, ok := tokenData["email"].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid email in token data")
+	}
+
+	return &refreshTokenData{userID: uint(uid), accountID: accountID, email: email}, nil
 }
 
-// DeleteRefreshToken removes a refresh token from Redis.
+// DeleteRefreshToken removes a refresh token from Redis and from its account's sessions.
 func (s *TokenStore) DeleteRefreshToken(ctx context.Context, tokenID string) error {
 	key := refreshTokenKeyPrefix + tokenID
+	if data, _ := s.cache.Get(ctx, key); data != nil {
+		if token, err := decodeRefreshToken(data); err == nil {
+			_ = s.cache.ZRem(ctx, userSessionsKeyPrefix+token.accountID, tokenID)
+		}
+	}
 	return s.cache.Delete(ctx, key)
 }
 
-// BlacklistAccessToken adds an access token to the blacklist until it expires.
+// RevokeAllForUser removes every refresh token of the account, ending all of its sessions.
+// Every token is deleted even when some deletes fail, and only deleted tokens leave the
+// account's index, so a token that could not be deleted is still revoked by a later call.
+// The errors of the failed deletes are returned together.
+func (s *TokenStore) RevokeAllForUser(ctx context.Context, accountID string) error {
+	key := userSessionsKeyPrefix + accountID
+	tokenIDs, err := s.cache.ZMembers(ctx, key)
+	if err != nil {
+		return fmt.Errorf("list sessions: %w", err)
+	}
+
+	var errs []error
+	for _, tokenID := range tokenIDs {
+		if err := s.cache.Remove(ctx, refreshTokenKeyPrefix+tokenID); err != nil {
+			errs = append(errs, fmt.Errorf("delete refresh token %s: %w", tokenID, err))
+			continue
+		}
+		_ = s.cache.ZRem(ctx, key, tokenID)
+	}
+	return errors.Join(errs...)
+}
+
+// BlacklistAccessToken adds an access token to the blacklist until it expires. A failed
+// write is returned, so a revocation is never reported done when it did not land.
 func (s *TokenStore) BlacklistAccessToken(ctx context.Context, tokenID string, ttl time.Duration) error {
 	key := accessTokenKeyPrefix + tokenID
 	// Store a simple marker
-	return s.cache.Set(ctx, key, []byte("1"), ttl)
+	return s.cache.Put(ctx, key, []byte("1"), ttl)
 }
 
-// IsAccessTokenBlacklisted checks if an access token is blacklisted.
+// IsAccessTokenBlacklisted checks if an access token is blacklisted. It returns an error
+// when Redis cannot be read rather than reporting the token live.
 func (s *TokenStore) IsAccessTokenBlacklisted(ctx context.Context, tokenID string) (bool, error) {
 	key := accessTokenKeyPrefix + tokenID
-	data, err := s.cache.Get(ctx, key)
+	_, found, err := s.cache.Lookup(ctx, key)
 	if err != nil {
-		return false, nil // Not blacklisted if error (fail safe)
+		return false, err
 	}
-	return data != nil, nil
+	return found, nil
 }
 
@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"paytabs/internal/cache"
+	"paytabs/internal/clock"
+)
+
+func newTestTokenStore(t *testing.T, maxSessions int) (*TokenStore, *clock.FixedClock, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	clk := clock.NewFixed(time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC))
+	s := NewTokenStore(cache.New(mr.Addr(), "", 0), maxSessions)
+	s.clock = clk
+	return s, clk, mr
+}
+
+func TestTokenStore_EvictsOldestSession(t *testing.T) {
+	ctx := context.Background()
+	s, clk, mr := newTestTokenStore(t, 3)
+
+	for i := 1; i <= 4; i++ {
+		require.NoError(t, s.StoreRefreshToken(ctx, fmt.Sprintf("token-%d", i), 7, "account-7", "user@example.com", RefreshTokenExpiry))
+		clk.Advance(time.Minute)
+	}
+
+	_, _, err := s.GetRefreshToken(ctx, "token-1")
+	assert.Error(t, err, "the fourth login evicts the oldest session")
+	for i := 2; i <= 4; i++ {
+		userID, email, err := s.GetRefreshToken(ctx, fmt.Sprintf("token-%d", i))
+		require.NoError(t, err)
+		assert.Equal(t, uint(7), userID)
+		assert.Equal(t, "user@example.com", email)
+	}
+
+	members, err := mr.ZMembers(userSessionsKeyPrefix + "account-7")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"token-2", "token-3", "token-4"}, members)
+}
+
+func TestTokenStore_SessionsArePerUser(t *testing.T) {
+	ctx := context.Background()
+	s, clk, _ := newTestTokenStore(t, 1)
+
+	require.NoError(t, s.StoreRefreshToken(ctx, "a-1", 1, "account-a", "a@example.com", RefreshTokenExpiry))
+	clk.Advance(time.Minute)
+	require.NoError(t, s.StoreRefreshToken(ctx, "b-1", 2, "account-b", "b@example.com", RefreshTokenExpiry))
+
+	_, _, err := s.GetRefreshToken(ctx, "a-1")
+	assert.NoError(t, err)
+	_, _, err = s.GetRefreshToken(ctx, "b-1")
+	assert.NoError(t, err)
+}
+
+func TestTokenStore_LogoutFreesSession(t *testing.T) {
+	ctx := context.Background()
+	s, clk, mr := newTestTokenStore(t, 2)
+
+	require.NoError(t, s.StoreRefreshToken(ctx, "token-1", 7, "account-7", "user@example.com", RefreshTokenExpiry))
+	clk.Advance(time.Minute)
+	require.NoError(t, s.StoreRefreshToken(ctx, "token-2", 7, "account-7", "user@example.com", RefreshTokenExpiry))
+	require.NoError(t, s.DeleteRefreshToken(ctx, "token-2"))
+	clk.Advance(time.Minute)
+	require.NoError(t, s.StoreRefreshToken(ctx, "token-3", 7, "account-7", "user@example.com", RefreshTokenExpiry))
+
+	_, _, err := s.GetRefreshToken(ctx, "token-1")
+	assert.NoError(t, err, "a logged-out session no longer counts toward the cap")
+	members, err := mr.ZMembers(userSessionsKeyPrefix + "account-7")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"token-1", "token-3"}, members)
+}
+
+func TestTokenStore_Unbounded(t *testing.T) {
+	ctx := context.Background()
+	s, clk, _ := newTestTokenStore(t, 0)
+
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, s.StoreRefreshToken(ctx, fmt.Sprintf("token-%d", i), 7, "account-7", "user@example.com", RefreshTokenExpiry))
+		clk.Advance(time.Minute)
+	}
+	_, _, err := s.GetRefreshToken(ctx, "token-1")
+	assert.NoError(t, err)
+}
+
+func TestTokenStore_ConsumeRefreshToken(t *testing.T) {
+	ctx := context.Background()
+	s, _, mr := newTestTokenStore(t, 2)
+
+	require.NoError(t, s.StoreRefreshToken(ctx, "token-1", 7, "account-7", "user@example.com", RefreshTokenExpiry))
+
+	userID, email, err := s.ConsumeRefreshToken(ctx, "token-1")
+	require.NoError(t, err)
+	assert.Equal(t, uint(7), userID)
+	assert.Equal(t, "user@example.com", email)
+
+	// The token is gone, along with its session
+	_, _, err = s.ConsumeRefreshToken(ctx, "token-1")
+	assert.Error(t, err)
+	_, _, err = s.GetRefreshToken(ctx, "token-1")
+	assert.Error(t, err)
+	assert.False(t, mr.Exists(userSessionsKeyPrefix+"account-7"))
+}
+
+func TestTokenStore_RevokeAllForUser(t *testing.T) {
+	ctx := context.Background()
+	s, clk, mr := newTestTokenStore(t, 0)
+
+	require.NoError(t, s.StoreRefreshToken(ctx, "token-1", 7, "account-7", "user@example.com", RefreshTokenExpiry))
+	clk.Advance(time.Minute)
+	require.NoError(t, s.StoreRefreshToken(ctx, "token-2", 7, "account-7", "user@example.com", RefreshTokenExpiry))
+	require.NoError(t, s.StoreRefreshToken(ctx, "token-3", 8, "account-8", "other@example.com", RefreshTokenExpiry))
+
+	require.NoError(t, s.RevokeAllForUser(ctx, "account-7"))
+
+	for _, tokenID := range []string{"token-1", "token-2"} {
+		_, _, err := s.GetRefreshToken(ctx, tokenID)
+		assert.Error(t, err, tokenID)
+	}
+	assert.False(t, mr.Exists(userSessionsKeyPrefix+"account-7"))
+	_, _, err := s.GetRefreshToken(ctx, "token-3")
+	assert.NoError(t, err, "other accounts keep their sessions")
+}
+
+// failingDelRedis proxies to the redis at addr but answers any DEL of key with an error, so a
+// test can see what happens when only some deletes fail.
+func failingDelRedis(t *testing.T, addr, key string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for {
+			client, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			server, err := net.Dial("tcp", addr)
+			if err != nil {
+				_ = client.Close()
+				return
+			}
+			go func() { _, _ = io.Copy(client, server) }()
+			go func() {
+				buf := make([]byte, 4096)
+				for {
+					n, err := client.Read(buf)
+					if err != nil {
+						_ = server.Close()
+						return
+					}
+					// go-redis waits for each reply, so answering here cannot reorder replies
+					cmd := bytes.ToLower(buf[:n])
+					if bytes.Contains(cmd, []byte("\r\ndel\r\n")) && bytes.Contains(cmd, []byte(key)) {
+						_, _ = client.Write([]byte("-ERR injected failure\r\n"))
+						continue
+					}
+					if _, err := server.Write(buf[:n]); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestTokenStore_RevokeAllForUser_KeepsTokensItCouldNotDelete(t *testing.T) {
+	ctx := context.Background()
+	s, clk, mr := newTestTokenStore(t, 0)
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, s.StoreRefreshToken(ctx, fmt.Sprintf("token-%d", i), 7, "account-7", "user@example.com", RefreshTokenExpiry))
+		clk.Advance(time.Minute)
+	}
+	flaky := NewTokenStore(cache.New(failingDelRedis(t, mr.Addr(), refreshTokenKeyPrefix+"token-2"), "", 0), 0)
+
+	err := flaky.RevokeAllForUser(ctx, "account-7")
+
+	assert.ErrorContains(t, err, "token-2")
+	for _, tokenID := range []string{"token-1", "token-3"} {
+		_, _, err := s.GetRefreshToken(ctx, tokenID)
+		assert.Error(t, err, "%s is deleted despite the earlier failure", tokenID)
+	}
+	_, _, err = s.GetRefreshToken(ctx, "token-2")
+	assert.NoError(t, err)
+	members, err := mr.ZMembers(userSessionsKeyPrefix + "account-7")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"token-2"}, members, "only deleted tokens leave the index")
+
+	// A later call finds the token that survived
+	require.NoError(t, s.RevokeAllForUser(ctx, "account-7"))
+	_, _, err = s.GetRefreshToken(ctx, "token-2")
+	assert.Error(t, err)
+	assert.False(t, mr.Exists(userSessionsKeyPrefix+"account-7"))
+}
+
+func TestTokenStore_RevokeAllForUser_RedisDown(t *testing.T) {
+	ctx := context.Background()
+	s, _, mr := newTestTokenStore(t, 0)
+	require.NoError(t, s.StoreRefreshToken(ctx, "token-1", 7, "account-7", "user@example.com", RefreshTokenExpiry))
+	mr.SetError("LOADING")
+
+	assert.Error(t, s.RevokeAllForUser(ctx, "account-7"), "sessions that could not be listed are not reported revoked")
+
+	mr.SetError("")
+	_, _, err := s.GetRefreshToken(ctx, "token-1")
+	assert.NoError(t, err)
+}
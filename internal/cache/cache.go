@@ -2,24 +2,33 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+
+	"paytabs/internal/tracing"
 )
 
+// ErrUnavailable is returned by the strict methods (Lookup, Put, Claim, Remove, ZMembers) when
+// redis is not configured.
+var ErrUnavailable = errors.New("redis is not configured")
+
 // Client wraps redis.Client but fails safe by swallowing connectivity errors.
 type Client struct {
 	client *redis.Client
 }
 
-// New creates a new Redis client.
+// New creates a new Redis client whose commands are traced.
 func New(addr, password string, db int) *Client {
 	opts := &redis.Options{
 		Addr:     addr,
 		Password: password,
 		DB:       db,
 	}
-	return &Client{client: redis.NewClient(opts)}
+	client := redis.NewClient(opts)
+	client.AddHook(tracingHook{tracer: tracing.Tracer()})
+	return &Client{client: client}
 }
 
 // Get returns value or nil if missing or redis unavailable.
@@ -50,6 +59,39 @@ func (c *Client) Set(ctx context.Context, key string, value []byte, ttl time.Dur
 	return nil
 }
 
+// Lookup returns the value at key and whether it exists. Unlike Get it reports redis
+// errors, for callers that must tell a missing key from an unavailable server.
+func (c *Client) Lookup(ctx context.Context, key string) ([]byte, bool, error) {
+	if c == nil || c.client == nil {
+		return nil, false, ErrUnavailable
+	}
+	res, err := c.client.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return res, true, nil
+}
+
+// Put stores value like Set but reports redis errors, for writes the caller must know
+// landed. A zero ttl keeps the key until it is overwritten or deleted.
+func (c *Client) Put(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if c == nil || c.client == nil {
+		return ErrUnavailable
+	}
+	return c.client.Set(ctx, key, value, ttl).Err()
+}
+
+// Ping reports whether redis answers a PING, for readiness checks.
+func (c *Client) Ping(ctx context.Context) error {
+	if c == nil || c.client == nil {
+		return ErrUnavailable
+	}
+	return c.client.Ping(ctx).Err()
+}
+
 // Delete removes a key, ignoring redis errors.
 func (c *Client) Delete(ctx context.Context, key string) error {
 	if c == nil || c.client == nil {
@@ -60,3 +102,140 @@ func (c *Client) Delete(ctx context.Context, key string) error {
 	}
 	return nil
 }
+
+// Remove deletes a key. Unlike Delete it reports redis errors, for callers that must know
+// the key is gone.
+func (c *Client) Remove(ctx context.Context, key string) error {
+	if c == nil || c.client == nil {
+		return ErrUnavailable
+	}
+	return c.client.Del(ctx, key).Err()
+}
+
+// Take atomically returns the value at key and deletes it, so of several concurrent
+// callers only one receives it. It returns nil if the key is missing or redis is
+// unavailable.
+func (c *Client) Take(ctx context.Context, key string) ([]byte, error) {
+	if c == nil || c.client == nil {
+		return nil, nil
+	}
+	res, err := c.client.GetDel(ctx, key).Bytes()
+	if err != nil {
+		// fail safe: behave like cache miss, including redis.Nil
+		return nil, nil
+	}
+	return res, nil
+}
+
+// SetNX stores value only if key does not exist yet and reports whether it was stored.
+// When redis is unavailable it reports true so callers proceed as if they hold the key.
+func (c *Client) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	if c == nil || c.client == nil {
+		return true, nil
+	}
+	ok, err := c.client.SetNX(ctx, key, value, ttl).Result()
+	if err != nil {
+		// fail safe: behave as if the key was acquired
+		return true, nil
+	}
+	return ok, nil
+}
+
+// Claim stores value only if key does not exist yet and reports whether it was stored. Unlike
+// SetNX it reports redis errors, for callers that must not proceed without the key.
+func (c *Client) Claim(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	if c == nil || c.client == nil {
+		return false, ErrUnavailable
+	}
+	return c.client.SetNX(ctx, key, value, ttl).Result()
+}
+
+// incrByScript increments a counter and sets its expiry only when the key has none yet,
+// so the TTL is fixed by the first increment and later increments do not extend it.
+var incrByScript = redis.NewScript(`
+local v = redis.call('INCRBY', KEYS[1], ARGV[1])
+if redis.call('PTTL', KEYS[1]) == -1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return v
+`)
+
+// IncrBy atomically adds n to the integer counter at key and returns the new value. The
+// first increment sets the key's TTL to ttlOnFirst. ok is false when redis is unavailable.
+func (c *Client) IncrBy(ctx context.Context, key string, n int64, ttlOnFirst time.Duration) (value int64, ok bool) {
+	if c == nil || c.client == nil {
+		return 0, false
+	}
+	value, err := incrByScript.Run(ctx, c.client, []string{key}, n, ttlOnFirst.Milliseconds()).Int64()
+	if err != nil {
+		// fail safe: callers fall back to their source of truth
+		return 0, false
+	}
+	return value, true
+}
+
+// GetInt returns the integer counter at key. ok is false when the key is missing, not an
+// integer, or redis is unavailable.
+func (c *Client) GetInt(ctx context.Context, key string) (value int64, ok bool) {
+	if c == nil || c.client == nil {
+		return 0, false
+	}
+	value, err := c.client.Get(ctx, key).Int64()
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
+
+// zAddCappedScript adds a member to a sorted set, refreshes the set's TTL, and trims it to
+// its ARGV[4] highest-scored members, returning the members it removed.
+var zAddCappedScript = redis.NewScript(`
+redis.call('ZADD', KEYS[1], ARGV[1], ARGV[2])
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+local max = tonumber(ARGV[4])
+if max <= 0 then
+	return {}
+end
+local excess = redis.call('ZCARD', KEYS[1]) - max
+if excess <= 0 then
+	return {}
+end
+local evicted = redis.call('ZRANGE', KEYS[1], 0, excess - 1)
+redis.call('ZREMRANGEBYRANK', KEYS[1], 0, excess - 1)
+return evicted
+`)
+
+// ZAddCapped atomically adds member with score to the sorted set at key, sets the set's TTL
+// to ttl, and, when max is positive, removes the lowest-scored members beyond max. It
+// returns the removed members; none are reported when redis is unavailable.
+func (c *Client) ZAddCapped(ctx context.Context, key string, score float64, member string, max int, ttl time.Duration) []string {
+	if c == nil || c.client == nil {
+		return nil
+	}
+	evicted, err := zAddCappedScript.Run(ctx, c.client, []string{key}, score, member, ttl.Milliseconds(), max).StringSlice()
+	if err != nil {
+		// fail safe: nothing is evicted
+		return nil
+	}
+	return evicted
+}
+
+// ZMembers returns every member of the sorted set at key, lowest score first. Unlike the
+// other sorted set methods it reports redis errors.
+func (c *Client) ZMembers(ctx context.Context, key string) ([]string, error) {
+	if c == nil || c.client == nil {
+		return nil, ErrUnavailable
+	}
+	return c.client.ZRange(ctx, key, 0, -1).Result()
+}
+
+// ZRem removes member from the sorted set at key, ignoring redis errors.
+func (c *Client) ZRem(ctx context.Context, key, member string) error {
+	if c == nil || c.client == nil {
+		return nil
+	}
+	if err := c.client.ZRem(ctx, key, member).Err(); err != nil {
+		return nil
+	}
+	return nil
+}
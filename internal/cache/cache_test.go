@@ -0,0 +1,214 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T) (*Client, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	return New(mr.Addr(), "", 0), mr
+}
+
+func TestClient_IncrBy(t *testing.T) {
+	ctx := context.Background()
+	c, mr := newTestClient(t)
+
+	value, ok := c.IncrBy(ctx, "counter", 150, time.Hour)
+	require.True(t, ok)
+	assert.Equal(t, int64(150), value)
+	assert.Equal(t, time.Hour, mr.TTL("counter"))
+
+	// Later increments add up and keep the TTL set by the first one
+	mr.FastForward(10 * time.Minute)
+	value, ok = c.IncrBy(ctx, "counter", 50, 24*time.Hour)
+	require.True(t, ok)
+	assert.Equal(t, int64(200), value)
+	assert.Equal(t, 50*time.Minute, mr.TTL("counter"))
+
+	got, ok := c.GetInt(ctx, "counter")
+	require.True(t, ok)
+	assert.Equal(t, int64(200), got)
+}
+
+func TestClient_GetInt_Missing(t *testing.T) {
+	c, _ := newTestClient(t)
+
+	_, ok := c.GetInt(context.Background(), "missing")
+	assert.False(t, ok)
+}
+
+func TestClient_Counters_FailSafe(t *testing.T) {
+	ctx := context.Background()
+
+	var nilClient *Client
+	_, ok := nilClient.IncrBy(ctx, "counter", 1, time.Hour)
+	assert.False(t, ok)
+	_, ok = nilClient.GetInt(ctx, "counter")
+	assert.False(t, ok)
+
+	c, mr := newTestClient(t)
+	mr.Close()
+	// Bound go-redis's reconnect retries so the test stays fast
+	ctx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	_, ok = c.IncrBy(ctx, "counter", 1, time.Hour)
+	assert.False(t, ok)
+	_, ok = c.GetInt(ctx, "counter")
+	assert.False(t, ok)
+}
+
+func TestClient_LookupAndPut(t *testing.T) {
+	ctx := context.Background()
+	c, mr := newTestClient(t)
+
+	_, found, err := c.Lookup(ctx, "flag")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	require.NoError(t, c.Put(ctx, "flag", []byte("0"), 0))
+	value, found, err := c.Lookup(ctx, "flag")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("0"), value)
+	assert.Zero(t, mr.TTL("flag"), "a zero ttl does not expire")
+}
+
+func TestClient_LookupAndPut_ReportUnavailable(t *testing.T) {
+	ctx := context.Background()
+
+	var nilClient *Client
+	_, _, err := nilClient.Lookup(ctx, "flag")
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.ErrorIs(t, nilClient.Put(ctx, "flag", []byte("1"), 0), ErrUnavailable)
+
+	c, mr := newTestClient(t)
+	mr.Close()
+	ctx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	_, _, err = c.Lookup(ctx, "flag")
+	assert.Error(t, err)
+	assert.Error(t, c.Put(ctx, "flag", []byte("1"), 0))
+}
+
+func TestClient_Ping(t *testing.T) {
+	ctx := context.Background()
+	c, mr := newTestClient(t)
+	assert.NoError(t, c.Ping(ctx))
+
+	mr.Close()
+	ctx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	assert.Error(t, c.Ping(ctx))
+
+	var nilClient *Client
+	assert.ErrorIs(t, nilClient.Ping(ctx), ErrUnavailable)
+}
+
+func TestClient_Take(t *testing.T) {
+	ctx := context.Background()
+	c, mr := newTestClient(t)
+
+	require.NoError(t, c.Set(ctx, "token", []byte("payload"), time.Hour))
+	value, err := c.Take(ctx, "token")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("payload"), value)
+	assert.False(t, mr.Exists("token"))
+
+	// A second taker gets nothing
+	value, err = c.Take(ctx, "token")
+	require.NoError(t, err)
+	assert.Nil(t, value)
+
+	var nilClient *Client
+	value, err = nilClient.Take(ctx, "token")
+	require.NoError(t, err)
+	assert.Nil(t, value)
+}
+
+func TestClient_Claim(t *testing.T) {
+	ctx := context.Background()
+	c, mr := newTestClient(t)
+
+	claimed, err := c.Claim(ctx, "lock", []byte("1"), time.Minute)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	assert.Equal(t, time.Minute, mr.TTL("lock"))
+
+	claimed, err = c.Claim(ctx, "lock", []byte("1"), time.Minute)
+	require.NoError(t, err)
+	assert.False(t, claimed, "the key is already held")
+
+	// Unlike SetNX, an unavailable redis is reported rather than treated as claimed
+	mr.Close()
+	_, err = c.Claim(ctx, "other", []byte("1"), time.Minute)
+	assert.Error(t, err)
+	var nilClient *Client
+	_, err = nilClient.Claim(ctx, "other", []byte("1"), time.Minute)
+	assert.ErrorIs(t, err, ErrUnavailable)
+}
+
+func TestClient_ZAddCapped(t *testing.T) {
+	ctx := context.Background()
+	c, mr := newTestClient(t)
+
+	assert.Empty(t, c.ZAddCapped(ctx, "set", 2, "b", 2, time.Hour))
+	assert.Empty(t, c.ZAddCapped(ctx, "set", 1, "a", 2, time.Hour))
+	assert.Equal(t, []string{"a"}, c.ZAddCapped(ctx, "set", 3, "c", 2, time.Hour), "the lowest score goes first")
+
+	members, err := mr.ZMembers("set")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b", "c"}, members)
+	assert.Equal(t, time.Hour, mr.TTL("set"))
+
+	require.NoError(t, c.ZRem(ctx, "set", "b"))
+	members, err = mr.ZMembers("set")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c"}, members)
+
+	assert.Empty(t, c.ZAddCapped(ctx, "set", 4, "d", 0, time.Hour), "a zero max never trims")
+}
+
+func TestClient_ZMembers(t *testing.T) {
+	ctx := context.Background()
+	c, mr := newTestClient(t)
+	c.ZAddCapped(ctx, "set", 2, "b", 0, time.Hour)
+	c.ZAddCapped(ctx, "set", 1, "a", 0, time.Hour)
+
+	members, err := c.ZMembers(ctx, "set")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, members)
+	assert.True(t, mr.Exists("set"), "the set is only read")
+
+	members, err = c.ZMembers(ctx, "missing")
+	require.NoError(t, err)
+	assert.Empty(t, members)
+
+	mr.Close()
+	_, err = c.ZMembers(ctx, "set")
+	assert.Error(t, err)
+	var nilClient *Client
+	_, err = nilClient.ZMembers(ctx, "set")
+	assert.ErrorIs(t, err, ErrUnavailable)
+}
+
+func TestClient_Remove(t *testing.T) {
+	ctx := context.Background()
+	c, mr := newTestClient(t)
+	require.NoError(t, mr.Set("key", "value"))
+
+	require.NoError(t, c.Remove(ctx, "key"))
+	assert.False(t, mr.Exists("key"))
+	require.NoError(t, c.Remove(ctx, "key"), "a missing key is not an error")
+
+	mr.Close()
+	assert.Error(t, c.Remove(ctx, "key"))
+	var nilClient *Client
+	assert.ErrorIs(t, nilClient.Remove(ctx, "key"), ErrUnavailable)
+}
@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"context"
+	"net"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracingHook wraps every redis command and pipeline in a client span, a child of the span
+// in the caller's context. Only command names are recorded, not keys or values. A missing
+// key is not an error.
+type tracingHook struct {
+	tracer trace.Tracer
+}
+
+func (h tracingHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (h tracingHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		ctx, span := h.start(ctx, "redis."+cmd.Name(), attribute.String("db.operation", cmd.Name()))
+		defer span.End()
+		err := next(ctx, cmd)
+		h.end(span, err)
+		return err
+	}
+}
+
+func (h tracingHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		ctx, span := h.start(ctx, "redis.pipeline", attribute.Int("db.redis.pipeline_length", len(cmds)))
+		defer span.End()
+		err := next(ctx, cmds)
+		h.end(span, err)
+		return err
+	}
+}
+
+func (h tracingHook) start(ctx context.Context, name string, attr attribute.KeyValue) (context.Context, trace.Span) {
+	return h.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.system", "redis"), attr),
+	)
+}
+
+func (h tracingHook) end(span trace.Span, err error) {
+	if err != nil && err != redis.Nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracingHook(t *testing.T) {
+	mr := miniredis.RunT(t)
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	client.AddHook(tracingHook{tracer: provider.Tracer("test")})
+	c := &Client{client: client}
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+	require.NoError(t, c.Set(ctx, "key", []byte("value"), time.Minute))
+	_, found, err := c.Lookup(ctx, "missing")
+	require.NoError(t, err)
+	assert.False(t, found)
+	parent.End()
+
+	// The first command also traces the connection handshake before it
+	spans := recorder.Ended()
+	require.GreaterOrEqual(t, len(spans), 3)
+	spans = spans[len(spans)-3:]
+	assert.Equal(t, "redis.set", spans[0].Name())
+	assert.Equal(t, "redis.get", spans[1].Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.Equal(t, codes.Unset, spans[1].Status().Code, "a missing key is not an error")
+}
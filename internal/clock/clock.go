@@ -0,0 +1,45 @@
+package clock
+
+import "time"
+
+// Clock provides the current time so time-dependent logic can be tested deterministically.
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+// New returns a Clock backed by time.Now.
+func New() Clock {
+	return realClock{}
+}
+
+// Now returns the current local time.
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// FixedClock is a Clock that always returns the same instant. Intended for tests.
+type FixedClock struct {
+	t time.Time
+}
+
+// NewFixed creates a clock frozen at t.
+func NewFixed(t time.Time) *FixedClock {
+	return &FixedClock{t: t}
+}
+
+// Now returns the frozen instant.
+func (c *FixedClock) Now() time.Time {
+	return c.t
+}
+
+// Set moves the frozen instant to t.
+func (c *FixedClock) Set(t time.Time) {
+	c.t = t
+}
+
+// Advance moves the frozen instant forward by d.
+func (c *FixedClock) Advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
@@ -1,8 +1,33 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Payment log overflow policies, applied when the async payment log channel is full.
+const (
+	// LogOverflowSync writes the log synchronously on the request path (durable, slower).
+	LogOverflowSync = "sync"
+	// LogOverflowDrop drops the log and counts it (fast, lossy).
+	LogOverflowDrop = "drop"
+	// LogOverflowBlock waits up to PaymentLogOverflowTimeout for channel space, then drops.
+	LogOverflowBlock = "block"
+)
+
+// JWT signing algorithms.
+const (
+	// JWTAlgorithmHS256 signs and verifies tokens with the shared JWTSecret.
+	JWTAlgorithmHS256 = "HS256"
+	// JWTAlgorithmRS256 signs tokens with an RSA private key; anyone with the public key can
+	// verify them.
+	JWTAlgorithmRS256 = "RS256"
 )
 
 // Config holds application level configuration loaded from environment variables.
@@ -14,19 +39,273 @@ type Config struct {
 	RedisPass   string
 	JWTSecret   string
 	SwaggerHost string
+	// JWTAlgorithm is JWTAlgorithmHS256 or JWTAlgorithmRS256.
+	JWTAlgorithm string
+	// JWTPrivateKeyFile and JWTPublicKeyFile are PEM files of the RSA key pair used with
+	// RS256.
+	JWTPrivateKeyFile string
+	JWTPublicKeyFile  string
+	// AccessTokenTTL is how long issued access tokens are valid.
+	AccessTokenTTL time.Duration
+	// RefreshTokenTTL is how long issued refresh tokens are valid, and how long they are kept
+	// in redis.
+	RefreshTokenTTL time.Duration
+	// LockWaitTimeout bounds how long a statement waits for a row lock before failing with
+	// errors.ErrLockTimeout. It is sent to MySQL in whole seconds; zero keeps the server's
+	// innodb_lock_wait_timeout.
+	LockWaitTimeout time.Duration
+	// TrustedProxies are the CIDR ranges of reverse proxies whose X-Forwarded-For header is
+	// believed when resolving the client IP. Empty means the connection's address is used as is.
+	TrustedProxies []string
+	// ReadinessTimeout bounds each dependency ping of the readiness probe.
+	ReadinessTimeout time.Duration
+	// DBAutoMigrate syncs the schema to the models with AutoMigrate after versioned
+	// migrations run. Development only: the changes it makes are not recorded.
+	DBAutoMigrate bool
+	// ResetDB drops every table on startup. Only honoured together with DBAutoMigrate.
+	ResetDB bool
+	// SeedAccountsURL is the external JSON source used to seed accounts.
+	SeedAccountsURL string
+	// AutoSeed seeds accounts from SeedAccountsURL on startup when the accounts table is empty.
+	AutoSeed bool
+	// AutoSeedForce makes AutoSeed run even when accounts already exist.
+	AutoSeedForce bool
+	// SupportedCurrencies is the allow-list of ISO 4217 codes the server accepts.
+	SupportedCurrencies []string
+	// DefaultCurrency is used when a request or record does not name a currency. It must be supported.
+	DefaultCurrency string
+	// RequireActiveCardOwner rejects transfers when either card's owning account is inactive.
+	RequireActiveCardOwner bool
+	// MaxTransferAmount caps a single card-to-card transfer. Zero means unlimited.
+	MaxTransferAmount decimal.Decimal
+	// MaxPaymentAmount caps a single card payment. Zero means unlimited. A merchant's own
+	// MaxPaymentAmount can only lower it.
+	MaxPaymentAmount decimal.Decimal
+	// MaxDailyCardSpend caps what one card can spend per UTC day across payments, outgoing
+	// transfers, and withdrawals. Zero means unlimited.
+	MaxDailyCardSpend decimal.Decimal
+	// MaxCardsPerAccount caps how many cards one account may hold. Zero means unlimited.
+	MaxCardsPerAccount int
+	// CardMaxExpiryYears is how many years ahead a new card's expiry may be. Zero disables the bound.
+	CardMaxExpiryYears int
+	// EmailAvailabilityEnabled exposes GET /api/auth/email-available. It lets anyone probe
+	// which emails are registered, so it is off by default.
+	EmailAvailabilityEnabled bool
+	// PaymentLogOverflowPolicy is one of LogOverflowSync (default), LogOverflowDrop, or LogOverflowBlock.
+	PaymentLogOverflowPolicy string
+	// PaymentLogOverflowTimeout bounds how long LogOverflowBlock waits for channel space.
+	PaymentLogOverflowTimeout time.Duration
+	// WorkerRestartDelay is how long a background worker that panicked waits before restarting.
+	WorkerRestartDelay time.Duration
+	// PaymentFeePercent is the processing fee as a percentage of the payment amount (2.9 means 2.9%).
+	PaymentFeePercent decimal.Decimal
+	// PaymentFeeFixed is a flat processing fee added to every payment.
+	PaymentFeeFixed decimal.Decimal
+	// RoundingMode is how computed amounts such as fees are rounded to the currency's minor
+	// unit: half_even (default), half_up, down, or up.
+	RoundingMode string
+	// PayoutReserveFixed is a flat amount a merchant must keep after a payout.
+	PayoutReserveFixed decimal.Decimal
+	// PayoutReservePercent is the share (2.5 means 2.5%) of the merchant's accepted payment
+	// volume over PayoutReserveWindow to keep after a payout. The larger of the two applies.
+	PayoutReservePercent decimal.Decimal
+	// PayoutReserveWindow is how far back payment volume counts towards the percentage reserve.
+	PayoutReserveWindow time.Duration
+	// SMTPHost is the relay used for notification emails. Empty disables email notifications.
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	// SMTPFrom is the sender address on notification emails.
+	SMTPFrom string
+	// LoginMaxAttempts is how many failed logins one email may have per LoginLockoutWindow
+	// before further attempts are refused. Zero disables the per-email limit.
+	LoginMaxAttempts int
+	// LoginMaxAttemptsPerIP is the same limit keyed by client IP. Zero disables it.
+	LoginMaxAttemptsPerIP int
+	// LoginLockoutWindow is how long failures are counted; a lock lifts when it expires.
+	LoginLockoutWindow time.Duration
+	// MaxSessionsPerUser caps the refresh tokens one user may hold; logging in beyond it evicts
+	// the oldest. Zero disables the cap.
+	MaxSessionsPerUser int
+	// EmailVerificationTTL is how long the token mailed to a new account stays redeemable.
+	// Verification is only required when SMTPHost is set.
+	EmailVerificationTTL time.Duration
+	// PasswordResetTTL is how long a mailed password reset token stays redeemable. Password
+	// resets are only available when SMTPHost is set.
+	PasswordResetTTL time.Duration
+	// ReceiptSigningKey signs payment receipts with HMAC-SHA256. Empty disables receipts.
+	ReceiptSigningKey string
+	// WebhookSecretKey encrypts merchants' webhook secrets at rest. Empty disables webhook secrets.
+	WebhookSecretKey string
+	// WebhookSecretRevealsPerHour caps how often one merchant may regenerate and reveal its
+	// webhook secret. Zero disables the limit.
+	WebhookSecretRevealsPerHour int
+	// WebhookTestsPerHour caps how many test events one merchant may send to its webhook
+	// endpoint. Zero disables the limit.
+	WebhookTestsPerHour int
+	// BalanceAlertDebounce is how long after a balance alert the merchant gets no other alert
+	// of the same kind, so a balance hovering at a threshold alerts once.
+	BalanceAlertDebounce time.Duration
+	// WebhookTimeout bounds a whole outbound webhook call, from dial to the last body byte read.
+	WebhookTimeout time.Duration
+	// WebhookMaxResponseBytes caps how much of a webhook response body is read.
+	WebhookMaxResponseBytes int64
+	// WebhookAllowPrivateTargets permits webhook URLs that resolve to loopback, private or
+	// link-local addresses. Local development only.
+	WebhookAllowPrivateTargets bool
+	// KillSwitchFailClosed refuses payments and transfers when their kill switch flags cannot
+	// be read from redis. By default they proceed (fail open).
+	KillSwitchFailClosed bool
+	// AccountHoldDuration is how long an account hold lasts when none is given.
+	AccountHoldDuration time.Duration
+	// CardHoldMaxDuration is the longest a card hold may last, and how long it lasts when
+	// none is given.
+	CardHoldMaxDuration time.Duration
+	// MaintenanceMode answers 503 for every mutating /api request except the admin and
+	// sign-in endpoints. The system:maintenance_mode redis flag switches it on at runtime too.
+	MaintenanceMode bool
+	// MaintenanceRetryAfter is the Retry-After sent with maintenance responses.
+	MaintenanceRetryAfter time.Duration
+	// AdminToken authenticates the operator endpoints under /api/admin via the X-Admin-Token
+	// header. Empty disables those endpoints.
+	AdminToken string
+	// CompressionEnabled gzips GET responses under /api for clients that accept it.
+	CompressionEnabled bool
+	// CompressionMinLength is the smallest response, in bytes, that is compressed.
+	CompressionMinLength int
+	// MaxPageSize caps the limit query param of every listing endpoint.
+	MaxPageSize int
+	// RejectOversizedPages answers 400 for a limit above MaxPageSize instead of clamping it.
+	RejectOversizedPages bool
+	// IdempotencyTTL is how long responses stored under an Idempotency-Key are replayed.
+	IdempotencyTTL time.Duration
+	// PaymentRetention is how long completed payments stay hot before being archived. Zero disables archival.
+	PaymentRetention time.Duration
+	// PaymentArchiveInterval is how often the archival job runs.
+	PaymentArchiveInterval time.Duration
+	// TracingEndpoint is the OTLP/HTTP collector URL spans are exported to, e.g.
+	// "http://otel-collector:4318". Empty disables tracing.
+	TracingEndpoint string
+	// TracingServiceName is the service.name spans are reported under.
+	TracingServiceName string
+	// TracingSampleRatio is the fraction of new traces recorded, from 0 to 1. Requests that
+	// arrive with trace context follow the caller's decision.
+	TracingSampleRatio float64
 }
 
-// Load builds Config from environment with sensible defaults.
-func Load() *Config {
-	return &Config{
+// Load builds Config from environment with sensible defaults. Money settings that do not
+// parse as decimals are reported as an error rather than defaulted, since falling back to
+// zero would silently lift the limit they set.
+func Load() (*Config, error) {
+	var errs []error
+	decimalEnv := func(key string, def decimal.Decimal) decimal.Decimal {
+		v, err := getEnvDecimal(key, def)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		return v
+	}
+
+	cfg := &Config{
 		ServerPort:  getEnv("SERVER_PORT", "8080"),
-		MySQLDSN:    getEnv("MYSQL_DSN", "user:password@tcp(localhost:3306)/app?charset=utf8mb4&parseTime=True&loc=Local"),
+		MySQLDSN:    getEnv("MYSQL_DSN", "user:password@tcp(localhost:3306)/app?charset=utf8mb4&parseTime=True&loc=UTC"),
 		RedisAddr:   getEnv("REDIS_ADDR", "localhost:6379"),
 		RedisDB:     getEnvInt("REDIS_DB", 0),
 		RedisPass:   os.Getenv("REDIS_PASSWORD"),
 		JWTSecret:   getEnv("JWT_SECRET", "change-me"),
 		SwaggerHost: os.Getenv("SWAGGER_HOST"),
+
+		JWTAlgorithm:      strings.ToUpper(getEnv("JWT_ALGORITHM", JWTAlgorithmHS256)),
+		JWTPrivateKeyFile: os.Getenv("JWT_PRIVATE_KEY_FILE"),
+		JWTPublicKeyFile:  os.Getenv("JWT_PUBLIC_KEY_FILE"),
+
+		AccessTokenTTL:  getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: getEnvDuration("REFRESH_TOKEN_TTL", 7*24*time.Hour),
+
+		LockWaitTimeout:  getEnvDuration("LOCK_WAIT_TIMEOUT", 5*time.Second),
+		TrustedProxies:   getEnvList("TRUSTED_PROXIES", nil),
+		ReadinessTimeout: getEnvDuration("READINESS_TIMEOUT", 2*time.Second),
+
+		DBAutoMigrate: getEnvBool("DB_AUTO_MIGRATE", false),
+		ResetDB:       getEnvBool("RESET_DB", false),
+
+		SeedAccountsURL: getEnv("SEED_ACCOUNTS_URL", "https://gist.githubusercontent.com/paytabscom/b590d72ae115226e288a9c8a15ba2888/raw/ac0d615060b02e755c94116e4e5a5af530bc4bb1/accounts.json"),
+		AutoSeed:        getEnvBool("AUTO_SEED", false),
+		AutoSeedForce:   getEnvBool("AUTO_SEED_FORCE", false),
+
+		SupportedCurrencies: getEnvList("SUPPORTED_CURRENCIES", []string{"USD"}),
+		DefaultCurrency:     getEnv("DEFAULT_CURRENCY", "USD"),
+
+		RequireActiveCardOwner:   getEnvBool("REQUIRE_ACTIVE_CARD_OWNER", true),
+		MaxTransferAmount:        decimalEnv("MAX_TRANSFER_AMOUNT", decimal.Zero),
+		MaxPaymentAmount:         decimalEnv("MAX_PAYMENT_AMOUNT", decimal.Zero),
+		MaxDailyCardSpend:        decimalEnv("MAX_DAILY_CARD_SPEND", decimal.Zero),
+		MaxCardsPerAccount:       getEnvInt("MAX_CARDS_PER_ACCOUNT", 10),
+		CardMaxExpiryYears:       getEnvInt("CARD_MAX_EXPIRY_YEARS", 10),
+		EmailAvailabilityEnabled: getEnvBool("EMAIL_AVAILABILITY_ENABLED", false),
+
+		PaymentLogOverflowPolicy:  getEnv("PAYMENT_LOG_OVERFLOW_POLICY", LogOverflowSync),
+		PaymentLogOverflowTimeout: getEnvDuration("PAYMENT_LOG_OVERFLOW_TIMEOUT", 50*time.Millisecond),
+		WorkerRestartDelay:        getEnvDuration("WORKER_RESTART_DELAY", time.Second),
+
+		PaymentFeePercent: decimalEnv("PAYMENT_FEE_PERCENT", decimal.Zero),
+		PaymentFeeFixed:   decimalEnv("PAYMENT_FEE_FIXED", decimal.Zero),
+		RoundingMode:      getEnv("ROUNDING_MODE", "half_even"),
+
+		PayoutReserveFixed:   decimalEnv("PAYOUT_RESERVE_FIXED", decimal.Zero),
+		PayoutReservePercent: decimalEnv("PAYOUT_RESERVE_PERCENT", decimal.Zero),
+		PayoutReserveWindow:  getEnvDuration("PAYOUT_RESERVE_WINDOW", 30*24*time.Hour),
+
+		SMTPHost:     os.Getenv("SMTP_HOST"),
+		SMTPPort:     getEnvInt("SMTP_PORT", 587),
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+		SMTPFrom:     getEnv("SMTP_FROM", "no-reply@localhost"),
+
+		LoginMaxAttempts:      getEnvInt("LOGIN_MAX_ATTEMPTS", 5),
+		LoginMaxAttemptsPerIP: getEnvInt("LOGIN_MAX_ATTEMPTS_PER_IP", 20),
+		LoginLockoutWindow:    getEnvDuration("LOGIN_LOCKOUT_WINDOW", 15*time.Minute),
+		MaxSessionsPerUser:    getEnvInt("MAX_SESSIONS_PER_USER", 10),
+		EmailVerificationTTL:  getEnvDuration("EMAIL_VERIFICATION_TTL", 24*time.Hour),
+		PasswordResetTTL:      getEnvDuration("PASSWORD_RESET_TTL", 15*time.Minute),
+
+		ReceiptSigningKey:           os.Getenv("RECEIPT_SIGNING_KEY"),
+		WebhookSecretKey:            os.Getenv("WEBHOOK_SECRET_KEY"),
+		WebhookSecretRevealsPerHour: getEnvInt("WEBHOOK_SECRET_REVEALS_PER_HOUR", 3),
+		WebhookTestsPerHour:         getEnvInt("WEBHOOK_TESTS_PER_HOUR", 10),
+		BalanceAlertDebounce:        getEnvDuration("BALANCE_ALERT_DEBOUNCE", time.Hour),
+		WebhookTimeout:              getEnvDuration("WEBHOOK_TIMEOUT", 5*time.Second),
+		WebhookMaxResponseBytes:     int64(getEnvInt("WEBHOOK_MAX_RESPONSE_BYTES", 64*1024)),
+		WebhookAllowPrivateTargets:  getEnvBool("WEBHOOK_ALLOW_PRIVATE_TARGETS", false),
+
+		KillSwitchFailClosed: getEnvBool("KILL_SWITCH_FAIL_CLOSED", false),
+		AccountHoldDuration:  getEnvDuration("ACCOUNT_HOLD_DURATION", 24*time.Hour),
+		CardHoldMaxDuration:  getEnvDuration("CARD_HOLD_MAX_DURATION", 7*24*time.Hour),
+		AdminToken:           os.Getenv("ADMIN_TOKEN"),
+
+		MaintenanceMode:       getEnvBool("MAINTENANCE_MODE", false),
+		MaintenanceRetryAfter: getEnvDuration("MAINTENANCE_RETRY_AFTER", 5*time.Minute),
+
+		IdempotencyTTL: getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+
+		CompressionEnabled:   getEnvBool("COMPRESSION_ENABLED", true),
+		CompressionMinLength: getEnvInt("COMPRESSION_MIN_LENGTH", 1024),
+
+		MaxPageSize:          getEnvInt("MAX_PAGE_SIZE", 100),
+		RejectOversizedPages: getEnvBool("REJECT_OVERSIZED_PAGES", false),
+
+		PaymentRetention:       getEnvDuration("PAYMENT_RETENTION", 0),
+		PaymentArchiveInterval: getEnvDuration("PAYMENT_ARCHIVE_INTERVAL", time.Hour),
+
+		TracingEndpoint:    os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"),
+		TracingServiceName: getEnv("OTEL_SERVICE_NAME", "payment-processor"),
+		TracingSampleRatio: getEnvFloat("TRACING_SAMPLE_RATIO", 1),
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
 	}
+	return cfg, nil
 }
 
 func getEnv(key, def string) string {
@@ -44,3 +323,61 @@ func getEnvInt(key string, def int) int {
 	}
 	return def
 }
+
+func getEnvBool(key string, def bool) bool {
+	if v := os.Getenv(key); v != "" {
+		if parsed, err := strconv.ParseBool(v); err == nil {
+			return parsed
+		}
+	}
+	return def
+}
+
+func getEnvDuration(key string, def time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if parsed, err := time.ParseDuration(v); err == nil {
+			return parsed
+		}
+	}
+	return def
+}
+
+func getEnvFloat(key string, def float64) float64 {
+	if v := os.Getenv(key); v != "" {
+		if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+			return parsed
+		}
+	}
+	return def
+}
+
+// getEnvDecimal reads a decimal, returning an error naming key if it is set but malformed.
+func getEnvDecimal(key string, def decimal.Decimal) (decimal.Decimal, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	parsed, err := decimal.NewFromString(strings.TrimSpace(v))
+	if err != nil {
+		return def, fmt.Errorf("%s: %q is not a decimal number", key, v)
+	}
+	return parsed, nil
+}
+
+// getEnvList reads a comma-separated list, dropping empty items.
+func getEnvList(key string, def []string) []string {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	if len(out) == 0 {
+		return def
+	}
+	return out
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_TokenTTLs(t *testing.T) {
+	tests := []struct {
+		name        string
+		access      string
+		refresh     string
+		wantAccess  time.Duration
+		wantRefresh time.Duration
+	}{
+		{name: "unset", wantAccess: 15 * time.Minute, wantRefresh: 7 * 24 * time.Hour},
+		{name: "valid", access: "5m", refresh: "36h", wantAccess: 5 * time.Minute, wantRefresh: 36 * time.Hour},
+		{name: "malformed", access: "fifteen minutes", refresh: "7d", wantAccess: 15 * time.Minute, wantRefresh: 7 * 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ACCESS_TOKEN_TTL", tt.access)
+			t.Setenv("REFRESH_TOKEN_TTL", tt.refresh)
+
+			cfg, err := Load()
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantAccess, cfg.AccessTokenTTL)
+			assert.Equal(t, tt.wantRefresh, cfg.RefreshTokenTTL)
+		})
+	}
+}
+
+func TestLoad_JWTAlgorithm(t *testing.T) {
+	t.Setenv("JWT_ALGORITHM", "")
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, JWTAlgorithmHS256, cfg.JWTAlgorithm)
+
+	t.Setenv("JWT_ALGORITHM", "rs256")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, JWTAlgorithmRS256, cfg.JWTAlgorithm)
+}
+
+func TestLoad_DecimalLimits(t *testing.T) {
+	t.Run("unset is unlimited", func(t *testing.T) {
+		t.Setenv("MAX_TRANSFER_AMOUNT", "")
+
+		cfg, err := Load()
+		require.NoError(t, err)
+		assert.True(t, cfg.MaxTransferAmount.IsZero())
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		t.Setenv("MAX_TRANSFER_AMOUNT", "5000.00")
+
+		cfg, err := Load()
+		require.NoError(t, err)
+		assert.Equal(t, "5000.00", cfg.MaxTransferAmount.StringFixed(2))
+	})
+
+	t.Run("malformed fails instead of defaulting to unlimited", func(t *testing.T) {
+		t.Setenv("MAX_TRANSFER_AMOUNT", "5,000")
+		t.Setenv("PAYMENT_FEE_FIXED", "0.30 USD")
+
+		cfg, err := Load()
+		assert.Nil(t, cfg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "MAX_TRANSFER_AMOUNT")
+		assert.Contains(t, err.Error(), "PAYMENT_FEE_FIXED")
+	})
+}
@@ -0,0 +1,89 @@
+package currency
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Currency describes an ISO 4217 currency and the number of digits in its minor unit.
+type Currency struct {
+	Code  string `json:"code"`
+	Name  string `json:"name"`
+	Scale int32  `json:"scale"`
+}
+
+// known lists the ISO 4217 currencies the server can be configured to accept.
+var known = map[string]Currency{
+	"AED": {Code: "AED", Name: "UAE Dirham", Scale: 2},
+	"BHD": {Code: "BHD", Name: "Bahraini Dinar", Scale: 3},
+	"EGP": {Code: "EGP", Name: "Egyptian Pound", Scale: 2},
+	"EUR": {Code: "EUR", Name: "Euro", Scale: 2},
+	"GBP": {Code: "GBP", Name: "Pound Sterling", Scale: 2},
+	"JOD": {Code: "JOD", Name: "Jordanian Dinar", Scale: 3},
+	"JPY": {Code: "JPY", Name: "Yen", Scale: 0},
+	"KWD": {Code: "KWD", Name: "Kuwaiti Dinar", Scale: 3},
+	"OMR": {Code: "OMR", Name: "Rial Omani", Scale: 3},
+	"QAR": {Code: "QAR", Name: "Qatari Rial", Scale: 2},
+	"SAR": {Code: "SAR", Name: "Saudi Riyal", Scale: 2},
+	"USD": {Code: "USD", Name: "US Dollar", Scale: 2},
+}
+
+// Lookup returns the metadata for a known currency code.
+func Lookup(code string) (Currency, bool) {
+	c, ok := known[normalize(code)]
+	return c, ok
+}
+
+// Registry is the allow-list of currencies the server accepts, with one default.
+type Registry struct {
+	currencies []Currency
+	byCode     map[string]Currency
+	defaultCur Currency
+}
+
+// NewRegistry builds a registry from configured codes. Every code must be a known ISO 4217
+// currency and the default must be one of them.
+func NewRegistry(codes []string, defaultCode string) (*Registry, error) {
+	r := &Registry{byCode: make(map[string]Currency, len(codes))}
+	for _, code := range codes {
+		c, ok := Lookup(code)
+		if !ok {
+			return nil, fmt.Errorf("unsupported currency %q", code)
+		}
+		if _, dup := r.byCode[c.Code]; dup {
+			continue
+		}
+		r.byCode[c.Code] = c
+		r.currencies = append(r.currencies, c)
+	}
+
+	def, ok := r.byCode[normalize(defaultCode)]
+	if !ok {
+		return nil, fmt.Errorf("default currency %q is not in the supported list", defaultCode)
+	}
+	r.defaultCur = def
+
+	return r, nil
+}
+
+// Supported returns the allowed currencies in configured order.
+func (r *Registry) Supported() []Currency {
+	out := make([]Currency, len(r.currencies))
+	copy(out, r.currencies)
+	return out
+}
+
+// Get returns an allowed currency by code.
+func (r *Registry) Get(code string) (Currency, bool) {
+	c, ok := r.byCode[normalize(code)]
+	return c, ok
+}
+
+// Default returns the default currency.
+func (r *Registry) Default() Currency {
+	return r.defaultCur
+}
+
+func normalize(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
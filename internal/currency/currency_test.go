@@ -0,0 +1,35 @@
+package currency
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRegistry(t *testing.T) {
+	r, err := NewRegistry([]string{"usd", " EUR", "KWD", "USD"}, "usd")
+	require.NoError(t, err)
+
+	assert.Equal(t, []Currency{
+		{Code: "USD", Name: "US Dollar", Scale: 2},
+		{Code: "EUR", Name: "Euro", Scale: 2},
+		{Code: "KWD", Name: "Kuwaiti Dinar", Scale: 3},
+	}, r.Supported())
+	assert.Equal(t, "USD", r.Default().Code)
+
+	c, ok := r.Get("kwd")
+	require.True(t, ok)
+	assert.Equal(t, int32(3), c.Scale)
+
+	_, ok = r.Get("JPY")
+	assert.False(t, ok)
+}
+
+func TestNewRegistry_Invalid(t *testing.T) {
+	_, err := NewRegistry([]string{"USD", "XYZ"}, "USD")
+	assert.Error(t, err)
+
+	_, err = NewRegistry([]string{"USD"}, "EUR")
+	assert.Error(t, err)
+}
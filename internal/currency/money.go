@@ -0,0 +1,165 @@
+package currency
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrCurrencyMismatch is the panic value, wrapped with both codes, of arithmetic between
+// amounts in different currencies. Mixing currencies is a programming error, like indexing
+// out of range, so it panics rather than returning an error every caller would ignore.
+var ErrCurrencyMismatch = errors.New("currency mismatch")
+
+// Money is an amount bound to the ISO 4217 code of its currency. Sums and differences only
+// combine amounts of the same currency, and products are rounded to the currency's minor
+// unit, so computed amounts never mix currencies or carry digits below the minor unit.
+//
+// Money persists as two columns when embedded in a model; the amount uses decimal's own
+// Scanner and Valuer. This field stores fee_amount and fee_currency:
+//
+//	Fee currency.Money `gorm:"embedded;embeddedPrefix:fee_"`
+type Money struct {
+	Amount   decimal.Decimal `json:"amount" gorm:"type:decimal(20,2);not null;default:0"`
+	Currency string          `json:"currency" gorm:"size:3;not null;default:''"`
+}
+
+// New returns amount in cur, unrounded. Round it first if it may have more digits than the
+// currency's minor unit.
+func New(amount decimal.Decimal, cur Currency) Money {
+	return Money{Amount: amount, Currency: cur.Code}
+}
+
+// Zero returns a zero amount in cur.
+func Zero(cur Currency) Money {
+	return New(decimal.Zero, cur)
+}
+
+// Add returns m + o. It panics with ErrCurrencyMismatch if the currencies differ.
+func (m Money) Add(o Money) Money {
+	m.mustMatch(o)
+	return Money{Amount: m.Amount.Add(o.Amount), Currency: m.Currency}
+}
+
+// Sub returns m - o. It panics with ErrCurrencyMismatch if the currencies differ.
+func (m Money) Sub(o Money) Money {
+	m.mustMatch(o)
+	return Money{Amount: m.Amount.Sub(o.Amount), Currency: m.Currency}
+}
+
+// Mul returns m * factor rounded to the currency's minor unit with mode.
+func (m Money) Mul(factor decimal.Decimal, mode RoundingMode) Money {
+	return Money{Amount: m.Amount.Mul(factor), Currency: m.Currency}.Round(mode)
+}
+
+// Round rounds m to the currency's minor unit with mode. Amounts in a currency this package
+// does not know are returned unchanged.
+func (m Money) Round(mode RoundingMode) Money {
+	cur, ok := Lookup(m.Currency)
+	if !ok {
+		return m
+	}
+	return Money{Amount: RoundToCurrency(m.Amount, cur, mode), Currency: m.Currency}
+}
+
+// Max returns the larger of m and o. It panics with ErrCurrencyMismatch if the currencies differ.
+func (m Money) Max(o Money) Money {
+	m.mustMatch(o)
+	if o.Amount.GreaterThan(m.Amount) {
+		return o
+	}
+	return m
+}
+
+// GreaterThan reports whether m > o. It panics with ErrCurrencyMismatch if the currencies differ.
+func (m Money) GreaterThan(o Money) bool {
+	m.mustMatch(o)
+	return m.Amount.GreaterThan(o.Amount)
+}
+
+// Allocate splits m into one part per weight, proportional to the weights, using the
+// largest-remainder method: every part gets its share rounded down to the minor unit, then
+// the minor units left over go one each to the parts that lost the most, earlier parts
+// winning ties. The parts always sum to exactly m, so no cent is lost or invented however
+// the shares round. m must already be at its currency's minor unit.
+//
+// Weights must be non-negative with a positive sum; anything else is a programming error
+// and panics. A zero weight gets a zero part.
+func (m Money) Allocate(weights []decimal.Decimal) []Money {
+	total := decimal.Zero
+	for _, w := range weights {
+		if w.IsNegative() {
+			panic(fmt.Errorf("currency: negative allocation weight %s", w))
+		}
+		total = total.Add(w)
+	}
+	if !total.IsPositive() {
+		panic(errors.New("currency: allocation weights must have a positive sum"))
+	}
+
+	scale := m.scale()
+	amount := m.Amount.Abs()
+	parts := make([]decimal.Decimal, len(weights))
+	remainders := make([]decimal.Decimal, len(weights))
+	allocated := decimal.Zero
+	for i, w := range weights {
+		// QuoRem is exact: amount*w == parts[i]*total + remainders[i]
+		parts[i], remainders[i] = amount.Mul(w).QuoRem(total, scale)
+		allocated = allocated.Add(parts[i])
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]].GreaterThan(remainders[order[b]])
+	})
+	unit := decimal.New(1, -scale)
+	leftover := amount.Sub(allocated).Shift(scale).IntPart()
+	for _, i := range order[:leftover] {
+		parts[i] = parts[i].Add(unit)
+	}
+
+	out := make([]Money, len(parts))
+	for i, p := range parts {
+		if m.Amount.IsNegative() {
+			p = p.Neg()
+		}
+		out[i] = Money{Amount: p, Currency: m.Currency}
+	}
+	return out
+}
+
+// scale is the number of digits in m's minor unit, or the digits m's amount already has
+// when its currency is unknown.
+func (m Money) scale() int32 {
+	if cur, ok := Lookup(m.Currency); ok {
+		return cur.Scale
+	}
+	if exp := m.Amount.Exponent(); exp < 0 {
+		return -exp
+	}
+	return 0
+}
+
+// IsNegative reports whether the amount is below zero.
+func (m Money) IsNegative() bool {
+	return m.Amount.IsNegative()
+}
+
+// String formats the amount at the currency's scale followed by its code, e.g. "12.50 USD".
+func (m Money) String() string {
+	if cur, ok := Lookup(m.Currency); ok {
+		return m.Amount.StringFixed(cur.Scale) + " " + m.Currency
+	}
+	return m.Amount.String() + " " + m.Currency
+}
+
+func (m Money) mustMatch(o Money) {
+	if normalize(m.Currency) != normalize(o.Currency) {
+		panic(fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, o.Currency))
+	}
+}
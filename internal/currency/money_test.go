@@ -0,0 +1,120 @@
+package currency
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/schema"
+)
+
+func TestMoney_Arithmetic(t *testing.T) {
+	usd, _ := Lookup("USD")
+	kwd, _ := Lookup("KWD")
+
+	a := New(decimal.RequireFromString("10.25"), usd)
+	b := New(decimal.RequireFromString("0.75"), usd)
+
+	assert.Equal(t, "11.00 USD", a.Add(b).String())
+	assert.Equal(t, "9.50 USD", a.Sub(b).String())
+	assert.True(t, a.Sub(b).Sub(a).IsNegative())
+	assert.Equal(t, a, a.Max(b))
+	assert.True(t, a.GreaterThan(b))
+
+	// 2.9% of 10.25 is 0.29725: products are quantized to the currency's scale
+	assert.Equal(t, "0.30 USD", a.Mul(decimal.RequireFromString("0.029"), RoundHalfEven).String())
+	assert.Equal(t, "0.29 USD", a.Mul(decimal.RequireFromString("0.029"), RoundDown).String())
+	assert.Equal(t, "0.297 KWD", New(a.Amount, kwd).Mul(decimal.RequireFromString("0.029"), RoundDown).String())
+}
+
+func TestMoney_CurrencyMismatchPanics(t *testing.T) {
+	usd, _ := Lookup("USD")
+	eur, _ := Lookup("EUR")
+	a := New(decimal.NewFromInt(1), usd)
+	b := New(decimal.NewFromInt(1), eur)
+
+	for name, op := range map[string]func(){
+		"add":          func() { a.Add(b) },
+		"sub":          func() { a.Sub(b) },
+		"max":          func() { a.Max(b) },
+		"greater than": func() { a.GreaterThan(b) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				err, ok := recover().(error)
+				require.True(t, ok, "expected a panic with an error")
+				assert.ErrorIs(t, err, ErrCurrencyMismatch)
+			}()
+			op()
+		})
+	}
+}
+
+func TestMoney_Allocate(t *testing.T) {
+	usd, _ := Lookup("USD")
+	jpy, _ := Lookup("JPY")
+	w := func(ws ...string) []decimal.Decimal {
+		out := make([]decimal.Decimal, len(ws))
+		for i, s := range ws {
+			out[i] = decimal.RequireFromString(s)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name     string
+		amount   Money
+		weights  []decimal.Decimal
+		expected []string
+	}{
+		{name: "even thirds", amount: New(decimal.RequireFromString("10.00"), usd), weights: w("1", "1", "1"), expected: []string{"3.34 USD", "3.33 USD", "3.33 USD"}},
+		// Exact shares 0.0333.., 0.0666..: the larger remainder gets the leftover cent
+		{name: "largest remainder wins", amount: New(decimal.RequireFromString("0.10"), usd), weights: w("1", "2"), expected: []string{"0.03 USD", "0.07 USD"}},
+		// Two tied remainders: the earlier part gets the cent
+		{name: "tied remainders", amount: New(decimal.RequireFromString("7.50"), usd), weights: w("50", "25", "25"), expected: []string{"3.75 USD", "1.88 USD", "1.87 USD"}},
+		{name: "zero weight", amount: New(decimal.RequireFromString("1.00"), usd), weights: w("0", "3"), expected: []string{"0.00 USD", "1.00 USD"}},
+		{name: "zero decimal currency", amount: New(decimal.NewFromInt(100), jpy), weights: w("1", "1", "1"), expected: []string{"34 JPY", "33 JPY", "33 JPY"}},
+		{name: "negative amount", amount: New(decimal.RequireFromString("-0.05"), usd), weights: w("1", "1"), expected: []string{"-0.03 USD", "-0.02 USD"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts := tt.amount.Allocate(tt.weights)
+
+			got := make([]string, len(parts))
+			sum := decimal.Zero
+			for i, p := range parts {
+				got[i] = p.String()
+				sum = sum.Add(p.Amount)
+			}
+			assert.Equal(t, tt.expected, got)
+			assert.True(t, sum.Equal(tt.amount.Amount), "parts sum to %s, want %s", sum, tt.amount.Amount)
+		})
+	}
+}
+
+func TestMoney_AllocateInvalidWeightsPanics(t *testing.T) {
+	usd, _ := Lookup("USD")
+	m := New(decimal.NewFromInt(1), usd)
+
+	assert.Panics(t, func() { m.Allocate(nil) })
+	assert.Panics(t, func() { m.Allocate([]decimal.Decimal{decimal.Zero}) })
+	assert.Panics(t, func() { m.Allocate([]decimal.Decimal{decimal.NewFromInt(2), decimal.NewFromInt(-1)}) })
+}
+
+func TestMoney_PersistsAsEmbeddedColumns(t *testing.T) {
+	type row struct {
+		ID  uint
+		Fee Money `gorm:"embedded;embeddedPrefix:fee_"`
+	}
+
+	s, err := schema.Parse(&row{}, &sync.Map{}, schema.NamingStrategy{})
+	require.NoError(t, err)
+
+	amount := s.LookUpField("fee_amount")
+	require.NotNil(t, amount)
+	assert.Equal(t, schema.DataType("decimal(20,2)"), amount.DataType)
+	require.NotNil(t, s.LookUpField("fee_currency"))
+}
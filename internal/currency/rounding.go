@@ -0,0 +1,51 @@
+package currency
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// RoundingMode selects how amounts with more digits than a currency's scale are rounded.
+type RoundingMode string
+
+const (
+	// RoundHalfEven rounds ties to the nearest even digit (banker's rounding). It is the
+	// default because it does not bias totals upwards over many roundings.
+	RoundHalfEven RoundingMode = "half_even"
+	// RoundHalfUp rounds ties away from zero.
+	RoundHalfUp RoundingMode = "half_up"
+	// RoundDown truncates towards zero.
+	RoundDown RoundingMode = "down"
+	// RoundUp rounds away from zero whenever any digit is dropped.
+	RoundUp RoundingMode = "up"
+)
+
+// ParseRoundingMode validates a configured rounding mode. Empty means RoundHalfEven.
+func ParseRoundingMode(s string) (RoundingMode, error) {
+	mode := RoundingMode(strings.ToLower(strings.TrimSpace(s)))
+	switch mode {
+	case "":
+		return RoundHalfEven, nil
+	case RoundHalfEven, RoundHalfUp, RoundDown, RoundUp:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown rounding mode %q", s)
+}
+
+// RoundToCurrency rounds amount to the currency's minor unit using mode. Every computed
+// amount (fees, conversions, any product or quotient) goes through here before it is
+// stored, so the same input always produces the same cents.
+func RoundToCurrency(amount decimal.Decimal, c Currency, mode RoundingMode) decimal.Decimal {
+	switch mode {
+	case RoundHalfUp:
+		return amount.Round(c.Scale)
+	case RoundDown:
+		return amount.RoundDown(c.Scale)
+	case RoundUp:
+		return amount.RoundUp(c.Scale)
+	default:
+		return amount.RoundBank(c.Scale)
+	}
+}
@@ -0,0 +1,62 @@
+package currency
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundToCurrency_Modes(t *testing.T) {
+	usd, _ := Lookup("USD")
+	kwd, _ := Lookup("KWD")
+	jpy, _ := Lookup("JPY")
+
+	tests := []struct {
+		amount   string
+		cur      Currency
+		mode     RoundingMode
+		expected string
+	}{
+		{amount: "1.005", cur: usd, mode: RoundHalfEven, expected: "1.00"},
+		{amount: "1.015", cur: usd, mode: RoundHalfEven, expected: "1.02"},
+		{amount: "1.005", cur: usd, mode: RoundHalfUp, expected: "1.01"},
+		{amount: "1.009", cur: usd, mode: RoundDown, expected: "1.00"},
+		{amount: "1.001", cur: usd, mode: RoundUp, expected: "1.01"},
+		{amount: "-1.005", cur: usd, mode: RoundHalfUp, expected: "-1.01"},
+		{amount: "1.0005", cur: kwd, mode: RoundHalfEven, expected: "1.000"},
+		{amount: "100.5", cur: jpy, mode: RoundHalfEven, expected: "100"},
+	}
+
+	for _, tt := range tests {
+		got := RoundToCurrency(decimal.RequireFromString(tt.amount), tt.cur, tt.mode)
+		assert.Equal(t, tt.expected, got.StringFixed(tt.cur.Scale), "%s %s %s", tt.amount, tt.cur.Code, tt.mode)
+	}
+}
+
+func TestRoundToCurrency_Deterministic(t *testing.T) {
+	usd, _ := Lookup("USD")
+	// 1.005 must round the same way however it was produced
+	inputs := []decimal.Decimal{
+		decimal.RequireFromString("1.005"),
+		decimal.RequireFromString("100.5").Div(decimal.NewFromInt(100)),
+		decimal.RequireFromString("0.335").Mul(decimal.NewFromInt(3)),
+	}
+	for _, in := range inputs {
+		assert.Equal(t, "1.00", RoundToCurrency(in, usd, RoundHalfEven).StringFixed(2))
+	}
+}
+
+func TestParseRoundingMode(t *testing.T) {
+	mode, err := ParseRoundingMode("")
+	require.NoError(t, err)
+	assert.Equal(t, RoundHalfEven, mode)
+
+	mode, err = ParseRoundingMode(" HALF_UP ")
+	require.NoError(t, err)
+	assert.Equal(t, RoundHalfUp, mode)
+
+	_, err = ParseRoundingMode("nearest")
+	assert.Error(t, err)
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrationLockName is the MySQL named lock held while migrating, so instances starting
+// together do not apply the same migration twice.
+const (
+	migrationLockName    = "schema_migrations"
+	migrationLockTimeout = 60 // seconds
+)
+
+// Migration is one ordered schema change. Up runs outside a transaction (MySQL commits
+// DDL implicitly) and must be idempotent, e.g. guard AddColumn with HasColumn, so a run
+// interrupted before its version is recorded can simply be retried.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+}
+
+// schemaMigration records an applied migration.
+type schemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"size:255;not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (schemaMigration) TableName() string { return "schema_migrations" }
+
+// Migrate applies every migration not yet recorded in schema_migrations, in version order,
+// recording each as it completes. It returns how many were applied.
+func Migrate(gdb *gorm.DB, migrations []Migration) (int, error) {
+	if err := validateMigrations(migrations); err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	// Connection pins one pooled connection so the named lock is released on the session that took it
+	err := gdb.Connection(func(conn *gorm.DB) error {
+		var locked int
+		if err := conn.Raw("SELECT GET_LOCK(?, ?)", migrationLockName, migrationLockTimeout).Scan(&locked).Error; err != nil {
+			return fmt.Errorf("acquire migration lock: %w", err)
+		}
+		if locked != 1 {
+			return fmt.Errorf("acquire migration lock: timed out after %ds", migrationLockTimeout)
+		}
+		defer conn.Exec("SELECT RELEASE_LOCK(?)", migrationLockName)
+
+		if err := conn.AutoMigrate(&schemaMigration{}); err != nil {
+			return fmt.Errorf("create schema_migrations: %w", err)
+		}
+
+		var versions []int
+		if err := conn.Model(&schemaMigration{}).Pluck("version", &versions).Error; err != nil {
+			return fmt.Errorf("read applied migrations: %w", err)
+		}
+		done := make(map[int]bool, len(versions))
+		for _, v := range versions {
+			done[v] = true
+		}
+
+		for _, m := range pendingMigrations(migrations, done) {
+			log.Printf("applying migration %d_%s", m.Version, m.Name)
+			if err := m.Up(conn); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+			}
+			if err := conn.Create(&schemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now().UTC()}).Error; err != nil {
+				return fmt.Errorf("record migration %d_%s: %w", m.Version, m.Name, err)
+			}
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// validateMigrations rejects non-positive or duplicate versions and missing Up functions.
+func validateMigrations(migrations []Migration) error {
+	seen := make(map[int]bool, len(migrations))
+	for _, m := range migrations {
+		if m.Version <= 0 {
+			return fmt.Errorf("migration %q: version must be positive", m.Name)
+		}
+		if seen[m.Version] {
+			return fmt.Errorf("migration version %d is defined twice", m.Version)
+		}
+		if m.Up == nil {
+			return fmt.Errorf("migration %d_%s has no Up", m.Version, m.Name)
+		}
+		seen[m.Version] = true
+	}
+	return nil
+}
+
+// pendingMigrations returns the migrations not in applied, sorted by version.
+func pendingMigrations(migrations []Migration, applied map[int]bool) []Migration {
+	var pending []Migration
+	for _, m := range migrations {
+		if !applied[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Version < pending[j].Version })
+	return pending
+}
@@ -0,0 +1,34 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func noop(*gorm.DB) error { return nil }
+
+func TestPendingMigrations_OrdersAndSkipsApplied(t *testing.T) {
+	migrations := []Migration{
+		{Version: 3, Name: "c", Up: noop},
+		{Version: 1, Name: "a", Up: noop},
+		{Version: 2, Name: "b", Up: noop},
+	}
+
+	pending := pendingMigrations(migrations, map[int]bool{2: true})
+
+	if assert.Len(t, pending, 2) {
+		assert.Equal(t, 1, pending[0].Version)
+		assert.Equal(t, 3, pending[1].Version)
+	}
+	assert.Empty(t, pendingMigrations(migrations, map[int]bool{1: true, 2: true, 3: true}))
+}
+
+func TestValidateMigrations(t *testing.T) {
+	assert.NoError(t, validateMigrations(Migrations))
+
+	assert.Error(t, validateMigrations([]Migration{{Version: 1, Up: noop}, {Version: 1, Up: noop}}))
+	assert.Error(t, validateMigrations([]Migration{{Version: 0, Up: noop}}))
+	assert.Error(t, validateMigrations([]Migration{{Version: 1}}))
+}
@@ -0,0 +1,246 @@
+package db
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+
+	"paytabs/internal/model"
+)
+
+// Models returns every persisted model in dependency order (referenced tables first).
+// Drop them in reverse.
+func Models() []interface{} {
+	return []interface{}{
+		&model.Account{},
+		&model.Card{},
+		&model.Payment{},
+		&model.PaymentLog{},
+		&model.Transfer{},
+		&model.LedgerEntry{},
+		&model.Payout{},
+		&model.WebhookSecret{},
+		&model.WebhookSecretAudit{},
+		&model.CardHold{},
+		&model.WebhookEndpoint{},
+	}
+}
+
+// DropAll drops every model table in reverse dependency order plus schema_migrations.
+// Development and seeding only; failures (e.g. a table that does not exist) are logged.
+func DropAll(gdb *gorm.DB) {
+	models := Models()
+	tables := []interface{}{&schemaMigration{}}
+	for i := len(models) - 1; i >= 0; i-- {
+		tables = append(tables, models[i])
+	}
+	for _, table := range tables {
+		if err := gdb.Migrator().DropTable(table); err != nil {
+			log.Printf("Warning: Failed to drop table (may not exist): %v", err)
+		}
+	}
+}
+
+// Migrations is the ordered schema history. Append new entries with the next version;
+// never edit or reorder ones that have shipped.
+var Migrations = []Migration{
+	{
+		// Version 1 brings a database created by the old AutoMigrate-on-start up to the
+		// schema at the time versioning was introduced, and creates it from scratch on an
+		// empty database. It uses the live model structs, so later migrations must check
+		// HasTable/HasColumn before adding what a fresh baseline may already contain.
+		Version: 1,
+		Name:    "baseline",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(Models()...)
+		},
+	},
+	{
+		Version: 2,
+		Name:    "payment_failure_reason_and_retry_link",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			for _, field := range []string{"FailureReason", "RetryOfID"} {
+				if !m.HasColumn(&model.Payment{}, field) {
+					if err := m.AddColumn(&model.Payment{}, field); err != nil {
+						return err
+					}
+				}
+			}
+			if !m.HasIndex(&model.Payment{}, "RetryOfID") {
+				return m.CreateIndex(&model.Payment{}, "RetryOfID")
+			}
+			return nil
+		},
+	},
+	{
+		Version: 3,
+		Name:    "account_and_card_currency",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			for _, table := range []interface{}{&model.Account{}, &model.Card{}} {
+				if !m.HasColumn(table, "Currency") {
+					if err := m.AddColumn(table, "Currency"); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 4,
+		Name:    "account_and_payment_test_mode",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			for _, table := range []interface{}{&model.Account{}, &model.Payment{}} {
+				if !m.HasColumn(table, "TestMode") {
+					if err := m.AddColumn(table, "TestMode"); err != nil {
+						return err
+					}
+				}
+			}
+			if !m.HasIndex(&model.Payment{}, "TestMode") {
+				return m.CreateIndex(&model.Payment{}, "TestMode")
+			}
+			return nil
+		},
+	},
+	{
+		// Version 5 fails while any card balance is negative; correct those rows first.
+		// MySQL before 8.0.16 parses but ignores CHECK, leaving the repository guard alone.
+		Version: 5,
+		Name:    "card_balance_non_negative",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if !m.HasConstraint(&model.Card{}, model.CardBalanceCheck) {
+				return m.CreateConstraint(&model.Card{}, model.CardBalanceCheck)
+			}
+			return nil
+		},
+	},
+	{
+		Version: 6,
+		Name:    "card_holds",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if !m.HasTable(&model.CardHold{}) {
+				return m.CreateTable(&model.CardHold{})
+			}
+			return nil
+		},
+	},
+	{
+		Version: 7,
+		Name:    "webhook_endpoints",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if !m.HasTable(&model.WebhookEndpoint{}) {
+				return m.CreateTable(&model.WebhookEndpoint{})
+			}
+			return nil
+		},
+	},
+	{
+		Version: 8,
+		Name:    "account_max_payment_amount",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if !m.HasColumn(&model.Account{}, "MaxPaymentAmount") {
+				return m.AddColumn(&model.Account{}, "MaxPaymentAmount")
+			}
+			return nil
+		},
+	},
+	{
+		Version: 9,
+		Name:    "account_balance_alert_thresholds",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			for _, field := range []string{"LowBalanceThreshold", "HighBalanceThreshold"} {
+				if !m.HasColumn(&model.Account{}, field) {
+					if err := m.AddColumn(&model.Account{}, field); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 10,
+		Name:    "payment_merchant_reference",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if !m.HasColumn(&model.Payment{}, "MerchantReference") {
+				if err := m.AddColumn(&model.Payment{}, "MerchantReference"); err != nil {
+					return err
+				}
+			}
+			if !m.HasIndex(&model.Payment{}, model.PaymentMerchantReferenceIndex) {
+				return m.CreateIndex(&model.Payment{}, model.PaymentMerchantReferenceIndex)
+			}
+			return nil
+		},
+	},
+	{
+		Version: 11,
+		Name:    "payment_refunds",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if !m.HasColumn(&model.Payment{}, "RefundOfID") {
+				return m.AddColumn(&model.Payment{}, "RefundOfID")
+			}
+			return nil
+		},
+	},
+	{
+		Version: 12,
+		Name:    "payment_refund_idempotency_key",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if !m.HasColumn(&model.Payment{}, "IdempotencyKey") {
+				if err := m.AddColumn(&model.Payment{}, "IdempotencyKey"); err != nil {
+					return err
+				}
+			}
+			if !m.HasIndex(&model.Payment{}, model.PaymentRefundIdempotencyIndex) {
+				return m.CreateIndex(&model.Payment{}, model.PaymentRefundIdempotencyIndex)
+			}
+			return nil
+		},
+	},
+	{
+		// Accounts that predate verification could already log in, so they all count as verified.
+		// The backfill is safe to repeat: this version only runs before any account can register
+		// unverified.
+		Version: 13,
+		Name:    "account_email_verified",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if !m.HasColumn(&model.Account{}, "EmailVerified") {
+				if err := m.AddColumn(&model.Account{}, "EmailVerified"); err != nil {
+					return err
+				}
+			}
+			return tx.Unscoped().Model(&model.Account{}).Where("email_verified = ?", false).UpdateColumn("email_verified", true).Error
+		},
+	},
+	{
+		// Existing payments have no key, which the unique index allows any number of
+		Version: 14,
+		Name:    "payment_charge_idempotency_key",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if !m.HasColumn(&model.Payment{}, "ChargeIdempotencyKey") {
+				if err := m.AddColumn(&model.Payment{}, "ChargeIdempotencyKey"); err != nil {
+					return err
+				}
+			}
+			if !m.HasIndex(&model.Payment{}, model.PaymentChargeIdempotencyIndex) {
+				return m.CreateIndex(&model.Payment{}, model.PaymentChargeIdempotencyIndex)
+			}
+			return nil
+		},
+	},
+}
@@ -2,16 +2,76 @@ package db
 
 import (
 	"fmt"
+	"strconv"
+	"time"
 
+	gomysql "github.com/go-sql-driver/mysql"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+
+	"paytabs/internal/tracing"
 )
 
-// NewMySQL returns a connected GORM DB instance.
-func NewMySQL(dsn string) (*gorm.DB, error) {
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+// NewMySQL returns a connected GORM DB instance whose queries are traced. Timestamps are written and read in UTC
+// whatever the DSN says, so every deployment serializes the same RFC3339 values. A positive
+// lockWaitTimeout bounds how long any statement waits for a row lock.
+func NewMySQL(dsn string, lockWaitTimeout time.Duration) (*gorm.DB, error) {
+	dsn, err := utcDSN(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("parse mysql dsn: %w", err)
+	}
+	dsn, err = lockWaitDSN(dsn, lockWaitTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("parse mysql dsn: %w", err)
+	}
+	db, err := gorm.Open(mysql.Open(dsn), gormConfig())
 	if err != nil {
 		return nil, fmt.Errorf("connect mysql: %w", err)
 	}
+	if err := registerTracing(db, tracing.Tracer()); err != nil {
+		return nil, fmt.Errorf("register tracing: %w", err)
+	}
 	return db, nil
 }
+
+// gormConfig stamps created_at/updated_at in UTC rather than GORM's default local time.
+func gormConfig() *gorm.Config {
+	return &gorm.Config{
+		NowFunc: func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// utcDSN forces the driver to parse DATETIME columns as UTC and the session time zone to
+// UTC, overriding loc= and time_zone= in dsn.
+func utcDSN(dsn string) (string, error) {
+	cfg, err := gomysql.ParseDSN(dsn)
+	if err != nil {
+		return "", err
+	}
+	cfg.ParseTime = true
+	cfg.Loc = time.UTC
+	if cfg.Params == nil {
+		cfg.Params = map[string]string{}
+	}
+	cfg.Params["time_zone"] = "'+00:00'"
+	return cfg.FormatDSN(), nil
+}
+
+// lockWaitDSN sets the session's innodb_lock_wait_timeout to timeout, rounded up to whole
+// seconds as MySQL requires, overriding any value in dsn. A timeout of zero or less leaves
+// dsn, and so the server default, unchanged.
+func lockWaitDSN(dsn string, timeout time.Duration) (string, error) {
+	if timeout <= 0 {
+		return dsn, nil
+	}
+	cfg, err := gomysql.ParseDSN(dsn)
+	if err != nil {
+		return "", err
+	}
+	seconds := int64((timeout + time.Second - 1) / time.Second)
+	if cfg.Params == nil {
+		cfg.Params = map[string]string{}
+	}
+	cfg.Params["innodb_lock_wait_timeout"] = strconv.FormatInt(seconds, 10)
+	return cfg.FormatDSN(), nil
+}
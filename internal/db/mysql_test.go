@@ -0,0 +1,63 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	gomysql "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+
+	"paytabs/internal/model"
+)
+
+func TestUTCDSN_OverridesLocalZone(t *testing.T) {
+	dsn, err := utcDSN("user:password@tcp(localhost:3306)/app?charset=utf8mb4&parseTime=False&loc=Local&time_zone=%27SYSTEM%27")
+	require.NoError(t, err)
+
+	cfg, err := gomysql.ParseDSN(dsn)
+	require.NoError(t, err)
+	assert.True(t, cfg.ParseTime)
+	assert.Equal(t, time.UTC, cfg.Loc)
+	assert.Equal(t, "'+00:00'", cfg.Params["time_zone"])
+	assert.Equal(t, "app", cfg.DBName)
+	assert.Equal(t, "utf8mb4", cfg.Params["charset"])
+}
+
+func TestUTCDSN_RejectsMalformedDSN(t *testing.T) {
+	_, err := utcDSN("not a dsn")
+	assert.Error(t, err)
+}
+
+func TestGormConfig_CreatedRecordIsUTC(t *testing.T) {
+	// Dry run builds the INSERT without a server, but still stamps the timestamps
+	cfg := gormConfig()
+	cfg.DisableAutomaticPing = true
+	cfg.SkipDefaultTransaction = true
+	gdb, err := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       "user:password@tcp(127.0.0.1:1)/app?parseTime=true",
+		SkipInitializeWithVersion: true,
+	}), cfg)
+	require.NoError(t, err)
+
+	account := &model.Account{Name: "Alice", Email: "alice@example.com"}
+	require.NoError(t, gdb.Session(&gorm.Session{DryRun: true}).Create(account).Error)
+
+	assert.False(t, account.CreatedAt.IsZero())
+	assert.Equal(t, time.UTC, account.CreatedAt.Location())
+	assert.Equal(t, time.UTC, account.UpdatedAt.Location())
+}
+
+func TestLockWaitDSN(t *testing.T) {
+	dsn, err := lockWaitDSN("user:password@tcp(localhost:3306)/app?innodb_lock_wait_timeout=50", 2500*time.Millisecond)
+	require.NoError(t, err)
+	cfg, err := gomysql.ParseDSN(dsn)
+	require.NoError(t, err)
+	assert.Equal(t, "3", cfg.Params["innodb_lock_wait_timeout"], "rounded up to whole seconds")
+
+	dsn, err = lockWaitDSN("user:password@tcp(localhost:3306)/app", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "user:password@tcp(localhost:3306)/app", dsn, "zero keeps the server default")
+}
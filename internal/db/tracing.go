@@ -0,0 +1,76 @@
+package db
+
+import (
+	stderrors "errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const tracingSpanKey = "tracing:span"
+
+// registerTracing wraps every GORM operation in a client span, a child of the span in the
+// statement's context. Spans carry the SQL with its placeholders, never the bound values,
+// so no card numbers or balances reach the tracing backend. A missing row is not an error.
+func registerTracing(db *gorm.DB, tracer trace.Tracer) error {
+	cbs := db.Callback()
+	operations := []struct {
+		name          string
+		before, after callbackRegisterer
+	}{
+		{"create", cbs.Create().Before("*"), cbs.Create().After("*")},
+		{"query", cbs.Query().Before("*"), cbs.Query().After("*")},
+		{"update", cbs.Update().Before("*"), cbs.Update().After("*")},
+		{"delete", cbs.Delete().Before("*"), cbs.Delete().After("*")},
+		{"row", cbs.Row().Before("*"), cbs.Row().After("*")},
+		{"raw", cbs.Raw().Before("*"), cbs.Raw().After("*")},
+	}
+	for _, op := range operations {
+		operation := op.name
+		if err := op.before.Register("tracing:before_"+operation, func(tx *gorm.DB) {
+			_, span := tracer.Start(tx.Statement.Context, "db."+operation,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(
+					attribute.String("db.system", "mysql"),
+					attribute.String("db.operation", operation),
+				),
+			)
+			tx.InstanceSet(tracingSpanKey, span)
+		}); err != nil {
+			return err
+		}
+		if err := op.after.Register("tracing:after_"+operation, endSpan); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// callbackRegisterer is a GORM callback positioned in its chain, ready to register.
+type callbackRegisterer interface {
+	Register(name string, fn func(*gorm.DB)) error
+}
+
+func endSpan(tx *gorm.DB) {
+	value, ok := tx.InstanceGet(tracingSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("db.statement", tx.Statement.SQL.String()),
+		attribute.String("db.sql.table", tx.Statement.Table),
+		attribute.Int64("db.rows_affected", tx.RowsAffected),
+	)
+	if tx.Error != nil && !stderrors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(tx.Error)
+		span.SetStatus(codes.Error, tx.Error.Error())
+	}
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+
+	"paytabs/internal/model"
+)
+
+func TestRegisterTracing_SpanPerStatement(t *testing.T) {
+	cfg := gormConfig()
+	cfg.DisableAutomaticPing = true
+	cfg.SkipDefaultTransaction = true
+	gdb, err := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       "user:password@tcp(127.0.0.1:1)/app?parseTime=true",
+		SkipInitializeWithVersion: true,
+	}), cfg)
+	require.NoError(t, err)
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	require.NoError(t, registerTracing(gdb, provider.Tracer("test")))
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+	account := &model.Account{Name: "Alice", Email: "alice@example.com"}
+	require.NoError(t, gdb.WithContext(ctx).Session(&gorm.Session{DryRun: true}).Create(account).Error)
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	span := spans[0]
+	assert.Equal(t, "db.create", span.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	assert.Equal(t, "accounts", attrs["db.sql.table"].AsString())
+	statement := attrs["db.statement"].AsString()
+	assert.Contains(t, statement, "INSERT INTO `accounts`")
+	assert.NotContains(t, statement, "alice@example.com", "bound values stay out of spans")
+}
@@ -0,0 +1,122 @@
+package errors
+
+// Code is the machine-readable code of an error response. Clients match on it, so a code
+// is a contract: add new ones here rather than inlining string literals, and never rename one.
+type Code string
+
+// Generic codes for errors that carry no more specific one, chosen by HTTP status.
+const (
+	CodeBadRequest         Code = "BAD_REQUEST"
+	CodeUnauthorized       Code = "UNAUTHORIZED"
+	CodeForbidden          Code = "FORBIDDEN"
+	CodeNotFound           Code = "NOT_FOUND"
+	CodeMethodNotAllowed   Code = "METHOD_NOT_ALLOWED"
+	CodeConflict           Code = "CONFLICT"
+	CodeRequestTooLarge    Code = "REQUEST_TOO_LARGE"
+	CodeTooManyRequests    Code = "TOO_MANY_REQUESTS"
+	CodeServiceUnavailable Code = "SERVICE_UNAVAILABLE"
+	CodeInternalError      Code = "INTERNAL_ERROR"
+	CodeRequestError       Code = "REQUEST_ERROR"
+	CodeDatabaseError      Code = "DATABASE_ERROR"
+	CodeEndpointRemoved    Code = "ENDPOINT_REMOVED"
+)
+
+// Request validation.
+const (
+	CodeInvalidRequest    Code = "INVALID_REQUEST"
+	CodeValidationError   Code = "VALIDATION_ERROR"
+	CodeInvalidUUID       Code = "INVALID_UUID"
+	CodeInvalidAmount     Code = "INVALID_AMOUNT"
+	CodeInvalidPagination Code = "INVALID_PAGINATION"
+	CodeInvalidDateRange  Code = "INVALID_DATE_RANGE"
+)
+
+// Authentication and accounts.
+const (
+	CodeInvalidCredentials       Code = "INVALID_CREDENTIALS"
+	CodeInvalidToken             Code = "INVALID_TOKEN"
+	CodeInvalidRefreshToken      Code = "INVALID_REFRESH_TOKEN"
+	CodeTooManyAttempts          Code = "TOO_MANY_ATTEMPTS"
+	CodeLoginFailed              Code = "LOGIN_FAILED"
+	CodeLogoutFailed             Code = "LOGOUT_FAILED"
+	CodeLogoutAllFailed          Code = "LOGOUT_ALL_FAILED"
+	CodeRefreshFailed            Code = "REFRESH_FAILED"
+	CodeRegistrationFailed       Code = "REGISTRATION_FAILED"
+	CodeEmailCheckFailed         Code = "EMAIL_CHECK_FAILED"
+	CodeAccountAlreadyExists     Code = "ACCOUNT_ALREADY_EXISTS"
+	CodeAccountNotVerified       Code = "ACCOUNT_NOT_VERIFIED"
+	CodeInvalidVerificationToken Code = "INVALID_VERIFICATION_TOKEN"
+	CodeVerificationFailed       Code = "VERIFICATION_FAILED"
+	CodeVerificationDisabled     Code = "VERIFICATION_DISABLED"
+	CodeInvalidResetToken        Code = "INVALID_RESET_TOKEN"
+	CodePasswordResetDisabled    Code = "PASSWORD_RESET_DISABLED"
+	CodePasswordResetFailed      Code = "PASSWORD_RESET_FAILED"
+	CodePasswordChangeFailed     Code = "PASSWORD_CHANGE_FAILED"
+	CodeAccountNotFound          Code = "ACCOUNT_NOT_FOUND"
+	CodeAccountInactive          Code = "ACCOUNT_INACTIVE"
+	CodeAccountOnHold            Code = "ACCOUNT_ON_HOLD"
+	CodeNotAMerchant             Code = "NOT_A_MERCHANT"
+)
+
+// Cards and balances.
+const (
+	CodeCardNotFound        Code = "CARD_NOT_FOUND"
+	CodeCardInactive        Code = "CARD_INACTIVE"
+	CodeCardLimitExceeded   Code = "CARD_LIMIT_EXCEEDED"
+	CodeCardHoldNotFound    Code = "CARD_HOLD_NOT_FOUND"
+	CodeCardHoldNotActive   Code = "CARD_HOLD_NOT_ACTIVE"
+	CodeInvalidCard         Code = "INVALID_CARD"
+	CodeInsufficientBalance Code = "INSUFFICIENT_BALANCE"
+	CodeBalanceReadError    Code = "BALANCE_READ_ERROR"
+	CodeLockTimeout         Code = "LOCK_TIMEOUT"
+	CodeAmountOutOfRange    Code = "AMOUNT_OUT_OF_RANGE"
+	CodeDailyLimitExceeded  Code = "DAILY_LIMIT_EXCEEDED"
+	CodeUnsupportedCurrency Code = "UNSUPPORTED_CURRENCY"
+	CodeCurrencyMismatch    Code = "CURRENCY_MISMATCH"
+)
+
+// Payments and payouts.
+const (
+	CodePaymentNotFound         Code = "PAYMENT_NOT_FOUND"
+	CodePaymentNotFailed        Code = "PAYMENT_NOT_FAILED"
+	CodePaymentNotRetryable     Code = "PAYMENT_NOT_RETRYABLE"
+	CodePaymentAlreadyRetried   Code = "PAYMENT_ALREADY_RETRIED"
+	CodePaymentNotCancellable   Code = "PAYMENT_NOT_CANCELLABLE"
+	CodePaymentAlreadyCancelled Code = "PAYMENT_ALREADY_CANCELLED"
+	CodePaymentNotRefundable    Code = "PAYMENT_NOT_REFUNDABLE"
+	CodeRefundExceedsOriginal   Code = "REFUND_EXCEEDS_ORIGINAL"
+	CodeDuplicateReference      Code = "DUPLICATE_REFERENCE"
+	CodePayoutExceedsReserve    Code = "PAYOUT_EXCEEDS_RESERVE"
+	CodePayoutNotFound          Code = "PAYOUT_NOT_FOUND"
+	CodeIdempotencyKeyReused    Code = "IDEMPOTENCY_KEY_REUSED"
+	CodeIdempotencyInProgress   Code = "IDEMPOTENCY_IN_PROGRESS"
+	CodeIdempotencyUnavailable  Code = "IDEMPOTENCY_UNAVAILABLE"
+)
+
+// Transfers. Transfer validation reports these as rejection reasons as well as errors.
+const (
+	CodeTransferRejected        Code = "TRANSFER_REJECTED"
+	CodeSameCard                Code = "SAME_CARD"
+	CodeSourceCardNotFound      Code = "SOURCE_CARD_NOT_FOUND"
+	CodeDestinationCardNotFound Code = "DESTINATION_CARD_NOT_FOUND"
+	CodeSourceCardInactive      Code = "SOURCE_CARD_INACTIVE"
+	CodeDestinationCardInactive Code = "DESTINATION_CARD_INACTIVE"
+)
+
+// Operators, webhooks, and upstream services.
+const (
+	CodeServiceDisabled         Code = "SERVICE_DISABLED"
+	CodeMaintenanceMode         Code = "MAINTENANCE_MODE"
+	CodeKillSwitchUnavailable   Code = "KILL_SWITCH_UNAVAILABLE"
+	CodeUnknownOperation        Code = "UNKNOWN_OPERATION"
+	CodeAccountHoldUnavailable  Code = "ACCOUNT_HOLD_UNAVAILABLE"
+	CodeWebhooksDisabled        Code = "WEBHOOKS_DISABLED"
+	CodeWebhookEndpointNotSet   Code = "WEBHOOK_ENDPOINT_NOT_SET"
+	CodeWebhookSecretNotSet     Code = "WEBHOOK_SECRET_NOT_SET"
+	CodeReceiptsDisabled        Code = "RECEIPTS_DISABLED"
+	CodeSeedFailed              Code = "SEED_FAILED"
+	CodeUpstreamUnavailable     Code = "UPSTREAM_UNAVAILABLE"
+	CodeUpstreamBadStatus       Code = "UPSTREAM_BAD_STATUS"
+	CodeUpstreamInvalidResponse Code = "UPSTREAM_INVALID_RESPONSE"
+	CodeUpstreamReadFailed      Code = "UPSTREAM_READ_FAILED"
+)
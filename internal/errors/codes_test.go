@@ -0,0 +1,91 @@
+package errors
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// codeLiteral matches string literals shaped like an error code.
+var codeLiteral = regexp.MustCompile(`^[A-Z][A-Z0-9]*(_[A-Z0-9]+)+$|^[A-Z]{4,}$`)
+
+// codePackages are the packages that build error responses; they must use the Code constants.
+var codePackages = []string{"errors", "handler", "middleware", "router", "service"}
+
+// definedCodes parses codes.go and returns each Code constant's value by name.
+func definedCodes(t *testing.T) map[string]string {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), "codes.go", nil, 0)
+	require.NoError(t, err)
+
+	codes := make(map[string]string)
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			for i, name := range vs.Names {
+				value, err := strconv.Unquote(vs.Values[i].(*ast.BasicLit).Value)
+				require.NoError(t, err)
+				codes[name.Name] = value
+			}
+		}
+	}
+	return codes
+}
+
+func TestCodes_AreUniqueAndWellFormed(t *testing.T) {
+	codes := definedCodes(t)
+	require.NotEmpty(t, codes)
+
+	seen := make(map[string]string, len(codes))
+	for name, value := range codes {
+		assert.Regexp(t, codeLiteral, value, name)
+		if other, dup := seen[value]; dup {
+			t.Errorf("%s and %s share the code %q", name, other, value)
+		}
+		seen[value] = name
+	}
+}
+
+// TestCodes_NoInlineLiterals fails when a package that renders errors spells a code as a string
+// literal instead of referencing a Code constant, so a typo cannot ship an undocumented code.
+func TestCodes_NoInlineLiterals(t *testing.T) {
+	for _, pkg := range codePackages {
+		dir := filepath.Join("..", pkg)
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+
+		for _, entry := range entries {
+			name := entry.Name()
+			if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") || (pkg == "errors" && name == "codes.go") {
+				continue
+			}
+			fset := token.NewFileSet()
+			file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, 0)
+			require.NoError(t, err)
+
+			ast.Inspect(file, func(n ast.Node) bool {
+				lit, ok := n.(*ast.BasicLit)
+				if !ok || lit.Kind != token.STRING {
+					return true
+				}
+				if value, err := strconv.Unquote(lit.Value); err == nil && codeLiteral.MatchString(value) {
+					t.Errorf("%s: error code %q must be a Code constant from internal/errors/codes.go", fset.Position(lit.Pos()), value)
+				}
+				return true
+			})
+		}
+	}
+}
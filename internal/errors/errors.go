@@ -18,19 +18,47 @@ var (
 	ErrAccountInactive = errors.New("account is not active")
 	// ErrInvalidAmount is returned when amount is invalid.
 	ErrInvalidAmount = errors.New("invalid amount")
+	// ErrAmountOutOfRange is returned when amount exceeds a configured limit.
+	ErrAmountOutOfRange = errors.New("amount out of range")
+	// ErrDailyLimitExceeded is returned when a debit would exceed the card's daily spend limit.
+	ErrDailyLimitExceeded = errors.New("daily spend limit exceeded")
+	// ErrNotMerchant is returned when a merchant-only operation is used by a non-merchant account.
+	ErrNotMerchant = errors.New("account is not a merchant")
+	// ErrCardLimitExceeded is returned when creating cards would exceed the per-account card limit.
+	ErrCardLimitExceeded = errors.New("card limit exceeded")
+	// ErrPaymentNotFound is returned when a payment is not found or belongs to another merchant.
+	ErrPaymentNotFound = errors.New("payment not found")
+	// ErrDuplicateReference is returned when the merchant already has a payment with the same
+	// merchant reference.
+	ErrDuplicateReference = errors.New("a payment with this merchant reference already exists")
+	// ErrBalanceRead is returned (wrapped with the entity and id) when a stored balance cannot be scanned.
+	ErrBalanceRead = errors.New("stored balance could not be read")
+	// ErrServiceDisabled is returned when operators have switched off payments or transfers.
+	ErrServiceDisabled = errors.New("service is temporarily disabled")
+	// ErrAccountOnHold is returned when the paying account is temporarily held on suspicion of fraud.
+	ErrAccountOnHold = errors.New("account is on hold")
+	// ErrLockTimeout is returned (wrapped with the entity and id) when a row stayed locked by
+	// another request for longer than LOCK_WAIT_TIMEOUT.
+	ErrLockTimeout = errors.New("the record is busy, please retry")
+	// ErrDuplicateIdempotencyKey is returned when the merchant already has a charge requested
+	// with the same idempotency key.
+	ErrDuplicateIdempotencyKey = errors.New("a payment with this idempotency key already exists")
+	// ErrCurrencyMismatch is returned when money would move between balances held in different
+	// currencies. There is no conversion, so such operations are refused.
+	ErrCurrencyMismatch = errors.New("source and destination hold different currencies")
 )
 
 // ErrorResponse represents a standardized error response.
 type ErrorResponse struct {
 	Error string `json:"error"`
-	Code  string `json:"code"`
+	Code  Code   `json:"code"`
 }
 
 // HTTPError represents an HTTP error with status code.
 type HTTPError struct {
 	StatusCode int
 	Message    string
-	Code       string
+	Code       Code
 }
 
 func (e *HTTPError) Error() string {
@@ -38,7 +66,7 @@ func (e *HTTPError) Error() string {
 }
 
 // NewHTTPError creates a new HTTP error.
-func NewHTTPError(statusCode int, message, code string) *HTTPError {
+func NewHTTPError(statusCode int, message string, code Code) *HTTPError {
 	return &HTTPError{
 		StatusCode: statusCode,
 		Message:    message,
@@ -56,20 +84,48 @@ func (e *HTTPError) ToErrorResponse() ErrorResponse {
 
 // MapErrorToHTTP maps domain errors to HTTP errors.
 func MapErrorToHTTP(err error) *HTTPError {
+	// Balance read failures arrive wrapped with the entity that failed
+	if errors.Is(err, ErrBalanceRead) {
+		return NewHTTPError(http.StatusInternalServerError, ErrBalanceRead.Error(), CodeBalanceReadError)
+	}
+	// 503 rather than 409 so the idempotency middleware does not store it and a retry with
+	// the same key runs again
+	if errors.Is(err, ErrLockTimeout) {
+		return NewHTTPError(http.StatusServiceUnavailable, ErrLockTimeout.Error(), CodeLockTimeout)
+	}
+
 	switch err {
 	case ErrAccountNotFound:
-		return NewHTTPError(http.StatusNotFound, err.Error(), "ACCOUNT_NOT_FOUND")
+		return NewHTTPError(http.StatusNotFound, err.Error(), CodeAccountNotFound)
 	case ErrCardNotFound:
-		return NewHTTPError(http.StatusNotFound, err.Error(), "CARD_NOT_FOUND")
+		return NewHTTPError(http.StatusNotFound, err.Error(), CodeCardNotFound)
 	case ErrInsufficientBalance:
-		return NewHTTPError(http.StatusBadRequest, err.Error(), "INSUFFICIENT_BALANCE")
+		return NewHTTPError(http.StatusBadRequest, err.Error(), CodeInsufficientBalance)
 	case ErrInvalidCard:
-		return NewHTTPError(http.StatusBadRequest, err.Error(), "INVALID_CARD")
+		return NewHTTPError(http.StatusBadRequest, err.Error(), CodeInvalidCard)
 	case ErrAccountInactive:
-		return NewHTTPError(http.StatusBadRequest, err.Error(), "ACCOUNT_INACTIVE")
+		return NewHTTPError(http.StatusBadRequest, err.Error(), CodeAccountInactive)
 	case ErrInvalidAmount:
-		return NewHTTPError(http.StatusBadRequest, err.Error(), "INVALID_AMOUNT")
+		return NewHTTPError(http.StatusBadRequest, err.Error(), CodeInvalidAmount)
+	case ErrAmountOutOfRange:
+		return NewHTTPError(http.StatusBadRequest, err.Error(), CodeAmountOutOfRange)
+	case ErrDailyLimitExceeded:
+		return NewHTTPError(http.StatusBadRequest, err.Error(), CodeDailyLimitExceeded)
+	case ErrNotMerchant:
+		return NewHTTPError(http.StatusForbidden, err.Error(), CodeNotAMerchant)
+	case ErrCardLimitExceeded:
+		return NewHTTPError(http.StatusConflict, err.Error(), CodeCardLimitExceeded)
+	case ErrPaymentNotFound:
+		return NewHTTPError(http.StatusNotFound, err.Error(), CodePaymentNotFound)
+	case ErrDuplicateReference:
+		return NewHTTPError(http.StatusConflict, err.Error(), CodeDuplicateReference)
+	case ErrServiceDisabled:
+		return NewHTTPError(http.StatusServiceUnavailable, err.Error(), CodeServiceDisabled)
+	case ErrAccountOnHold:
+		return NewHTTPError(http.StatusLocked, err.Error(), CodeAccountOnHold)
+	case ErrCurrencyMismatch:
+		return NewHTTPError(http.StatusUnprocessableEntity, err.Error(), CodeCurrencyMismatch)
 	default:
-		return NewHTTPError(http.StatusInternalServerError, "internal server error", "INTERNAL_ERROR")
+		return NewHTTPError(http.StatusInternalServerError, "internal server error", CodeInternalError)
 	}
 }
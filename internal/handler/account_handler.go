@@ -1,23 +1,30 @@
 package handler
 
 import (
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 
 	"paytabs/internal/errors"
+	"paytabs/internal/model"
+	"paytabs/internal/repository"
 	"paytabs/internal/service"
 )
 
 // AccountHandler handles account endpoints.
 type AccountHandler struct {
-	accountService service.AccountService
+	accountService  service.AccountService
+	snapshotService service.AccountSnapshotService
 }
 
 // NewAccountHandler creates a new account handler.
-func NewAccountHandler(accountService service.AccountService) *AccountHandler {
-	return &AccountHandler{accountService: accountService}
+func NewAccountHandler(accountService service.AccountService, snapshotService service.AccountSnapshotService) *AccountHandler {
+	return &AccountHandler{accountService: accountService, snapshotService: snapshotService}
 }
 
 // BalanceResponse represents an account balance response.
@@ -26,6 +33,63 @@ type BalanceResponse struct {
 	Balance   string    `json:"balance"`
 }
 
+// GetAccount godoc
+// @Summary Get an account
+// @Description Only the account itself may read it.
+// @Tags accounts
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Account ID"
+// @Success 200 {object} model.Account
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /accounts/{id} [get]
+func (h *AccountHandler) GetAccount(c echo.Context) error {
+	accountID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := requireAccountOwner(c, accountID); err != nil {
+		return err
+	}
+
+	return h.account(c, accountID)
+}
+
+// GetMe godoc
+// @Summary Get the authenticated account
+// @Description Same as GET /accounts/{id} for the account the token was issued to. 404 once that account is deleted.
+// @Tags accounts
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} model.Account
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /me [get]
+func (h *AccountHandler) GetMe(c echo.Context) error {
+	accountID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	return h.account(c, accountID)
+}
+
+func (h *AccountHandler) account(c echo.Context, accountID uuid.UUID) error {
+	account, err := h.accountService.GetAccount(c.Request().Context(), accountID)
+	if err != nil {
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+
+	return c.JSON(http.StatusOK, account)
+}
+
 // GetBalance godoc
 // @Summary Get account balance
 // @Tags accounts
@@ -39,15 +103,35 @@ type BalanceResponse struct {
 // @Failure 500 {object} errors.ErrorResponse
 // @Router /accounts/{id}/balance [get]
 func (h *AccountHandler) GetBalance(c echo.Context) error {
-	accountIDStr := c.Param("id")
-	accountID, err := uuid.Parse(accountIDStr)
+	accountID, err := parseUUIDParam(c, "id")
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
-			Error: "invalid account ID",
-			Code:  "INVALID_UUID",
-		})
+		return err
+	}
+
+	return h.balance(c, accountID)
+}
+
+// GetMyBalance godoc
+// @Summary Get the authenticated account's balance
+// @Description Same as GET /accounts/{id}/balance for the caller's own account.
+// @Tags accounts
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} BalanceResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /me/balance [get]
+func (h *AccountHandler) GetMyBalance(c echo.Context) error {
+	accountID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
 	}
 
+	return h.balance(c, accountID)
+}
+
+func (h *AccountHandler) balance(c echo.Context, accountID uuid.UUID) error {
 	balance, err := h.accountService.GetBalance(c.Request().Context(), accountID)
 	if err != nil {
 		httpErr := errors.MapErrorToHTTP(err)
@@ -59,3 +143,390 @@ func (h *AccountHandler) GetBalance(c echo.Context) error {
 		Balance:   balance.String(),
 	})
 }
+
+// BatchBalanceRequest names the accounts whose balances to fetch, at most 100 per request.
+type BatchBalanceRequest struct {
+	AccountIDs []string `json:"account_ids" validate:"required,min=1,max=100"`
+}
+
+// Batch balance result statuses.
+const (
+	BalanceFound    = "found"
+	BalanceNotFound = "not_found"
+)
+
+// BatchBalanceItem is one account's balance, or a not_found marker.
+type BatchBalanceItem struct {
+	AccountID uuid.UUID `json:"account_id"`
+	Status    string    `json:"status"`
+	Balance   string    `json:"balance,omitempty"`
+}
+
+// BatchBalanceResponse lists balances in the order the accounts were requested, once each.
+type BatchBalanceResponse struct {
+	Balances []BatchBalanceItem `json:"balances"`
+}
+
+// GetBalances godoc
+// @Summary Get the balances of several accounts
+// @Description Callers may only name their own account. Operators use POST /admin/accounts/balances for any account.
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body BatchBalanceRequest true "Account IDs (1 to 100)"
+// @Success 200 {object} BatchBalanceResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /accounts/balances [post]
+func (h *AccountHandler) GetBalances(c echo.Context) error {
+	ids, err := bindBatchBalanceRequest(c)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := requireAccountOwner(c, id); err != nil {
+			return err
+		}
+	}
+	return h.balances(c, ids)
+}
+
+// AdminGetBalances godoc
+// @Summary Get the balances of any accounts
+// @Description Operator variant of POST /accounts/balances, authenticated with the admin token.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string true "Operator token (ADMIN_TOKEN)"
+// @Param request body BatchBalanceRequest true "Account IDs (1 to 100)"
+// @Success 200 {object} BatchBalanceResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /admin/accounts/balances [post]
+func (h *AccountHandler) AdminGetBalances(c echo.Context) error {
+	ids, err := bindBatchBalanceRequest(c)
+	if err != nil {
+		return err
+	}
+	return h.balances(c, ids)
+}
+
+// bindBatchBalanceRequest reads and validates the account IDs of a batch balance request.
+func bindBatchBalanceRequest(c echo.Context) ([]uuid.UUID, error) {
+	var req BatchBalanceRequest
+	if err := c.Bind(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: "invalid request body",
+			Code:  errors.CodeInvalidRequest,
+		})
+	}
+	if err := c.Validate(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: err.Error(),
+			Code:  errors.CodeValidationError,
+		})
+	}
+
+	ids := make([]uuid.UUID, 0, len(req.AccountIDs))
+	for _, value := range req.AccountIDs {
+		id, err := parseUUIDBody(value, "account_ids")
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (h *AccountHandler) balances(c echo.Context, ids []uuid.UUID) error {
+	results, err := h.accountService.GetBalances(c.Request().Context(), ids)
+	if err != nil {
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+
+	items := make([]BatchBalanceItem, 0, len(results))
+	for _, result := range results {
+		item := BatchBalanceItem{AccountID: result.AccountID, Status: BalanceNotFound}
+		if result.Found {
+			item.Status = BalanceFound
+			item.Balance = result.Balance.String()
+		}
+		items = append(items, item)
+	}
+	return c.JSON(http.StatusOK, BatchBalanceResponse{Balances: items})
+}
+
+// WalletCardResponse represents a card entry in the wallet response.
+type WalletCardResponse struct {
+	ID         uuid.UUID `json:"id"`
+	CardNumber string    `json:"card_number"`
+	CardExpiry string    `json:"card_expiry"`
+	Balance    string    `json:"balance"`
+}
+
+// WalletResponse represents the authenticated account's profile, balance, and cards.
+type WalletResponse struct {
+	Account *model.Account       `json:"account"`
+	Balance string               `json:"balance"`
+	Cards   []WalletCardResponse `json:"cards"`
+}
+
+// GetWallet godoc
+// @Summary Get the authenticated account's profile, balance, and active cards
+// @Tags accounts
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} WalletResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /me/wallet [get]
+func (h *AccountHandler) GetWallet(c echo.Context) error {
+	accountID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	wallet, err := h.accountService.GetWallet(c.Request().Context(), accountID)
+	if err != nil {
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+
+	cards := make([]WalletCardResponse, 0, len(wallet.Cards))
+	for _, card := range wallet.Cards {
+		cards = append(cards, WalletCardResponse{
+			ID:         card.ID,
+			CardNumber: card.CardNumber,
+			CardExpiry: card.CardExpiry,
+			Balance:    card.Balance.String(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, WalletResponse{
+		Account: wallet.Account,
+		Balance: wallet.Balance.String(),
+		Cards:   cards,
+	})
+}
+
+// SnapshotCardResponse is a card in an account snapshot.
+type SnapshotCardResponse struct {
+	ID         uuid.UUID `json:"id"`
+	CardNumber string    `json:"card_number"` // Masked
+	Balance    string    `json:"balance"`
+	Active     bool      `json:"active"`
+}
+
+// AccountSnapshotResponse is an account's balances and activity read in one database snapshot.
+type AccountSnapshotResponse struct {
+	AccountID      uuid.UUID              `json:"account_id"`
+	AsOf           time.Time              `json:"as_of"`
+	AccountBalance string                 `json:"account_balance"`
+	CardBalance    string                 `json:"card_balance"` // Active cards only
+	Cards          []SnapshotCardResponse `json:"cards"`
+	Payments       []PaymentListItem      `json:"payments"`
+	Transfers      []TransferListItem     `json:"transfers"`
+}
+
+// GetSnapshot godoc
+// @Summary Export a consistent snapshot of an account's balances and activity
+// @Description Returns the account's balances, cards, every payment (archived ones included) and every transfer, oldest first, all read in one REPEATABLE READ transaction so they reconcile exactly. Only the account's owner may export it.
+// @Tags accounts
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Account ID"
+// @Success 200 {object} AccountSnapshotResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /accounts/{id}/snapshot [get]
+func (h *AccountHandler) GetSnapshot(c echo.Context) error {
+	accountID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+	if err := requireAccountOwner(c, accountID); err != nil {
+		return err
+	}
+	return h.snapshot(c, accountID)
+}
+
+// AdminGetSnapshot godoc
+// @Summary Export a consistent snapshot of any account's balances and activity
+// @Description Operator variant of GET /accounts/{id}/snapshot for any account, authenticated with the admin token.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Token header string true "Operator token (ADMIN_TOKEN)"
+// @Param id path string true "Account ID"
+// @Success 200 {object} AccountSnapshotResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /admin/accounts/{id}/snapshot [get]
+func (h *AccountHandler) AdminGetSnapshot(c echo.Context) error {
+	accountID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+	return h.snapshot(c, accountID)
+}
+
+func (h *AccountHandler) snapshot(c echo.Context, accountID uuid.UUID) error {
+	snapshot, err := h.snapshotService.Snapshot(c.Request().Context(), accountID)
+	if err != nil {
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+
+	cards := make([]SnapshotCardResponse, 0, len(snapshot.Cards))
+	for _, card := range snapshot.Cards {
+		cards = append(cards, SnapshotCardResponse{
+			ID:         card.ID,
+			CardNumber: card.CardNumber,
+			Balance:    card.Balance.String(),
+			Active:     card.Active,
+		})
+	}
+	payments := make([]PaymentListItem, 0, len(snapshot.Payments))
+	for i := range snapshot.Payments {
+		payments = append(payments, PaymentListItem{
+			PaymentResponse:   newPaymentResponse(&snapshot.Payments[i]),
+			MerchantAccountID: snapshot.Payments[i].MerchantAccountID.String(),
+			CardID:            snapshot.Payments[i].CardID.String(),
+			CreatedAt:         snapshot.Payments[i].CreatedAt,
+		})
+	}
+
+	return c.JSON(http.StatusOK, AccountSnapshotResponse{
+		AccountID:      snapshot.AccountID,
+		AsOf:           snapshot.AsOf,
+		AccountBalance: snapshot.AccountBalance.String(),
+		CardBalance:    snapshot.CardBalance.String(),
+		Cards:          cards,
+		Payments:       payments,
+		Transfers:      newTransferListItems(snapshot.Transfers),
+	})
+}
+
+// maxAccountSearchLength bounds the q search term of the admin account listing.
+const maxAccountSearchLength = 100
+
+// AdminAccountResponse is an account in the operator listing. The password hash is never
+// part of it.
+type AdminAccountResponse struct {
+	ID         uuid.UUID `json:"id"`
+	Name       string    `json:"name"`
+	Email      string    `json:"email"`
+	IsMerchant bool      `json:"is_merchant"`
+	Active     bool      `json:"active"`
+	Balance    string    `json:"balance"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// AccountListResponse is a page of accounts.
+type AccountListResponse struct {
+	Accounts []AdminAccountResponse `json:"accounts"`
+	Total    int64                  `json:"total"`
+	Limit    int                    `json:"limit"`
+	Offset   int                    `json:"offset"`
+}
+
+// AdminListAccounts godoc
+// @Summary List accounts
+// @Description For support and operations. Newest first unless sorted otherwise. Every call is logged with its filters and the caller's IP.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Token header string true "Operator token (ADMIN_TOKEN)"
+// @Param is_merchant query bool false "Only merchants (true) or non-merchants (false)"
+// @Param active query bool false "Only active (true) or inactive (false) accounts"
+// @Param q query string false "Case-insensitive substring of the name or email (max 100 characters)"
+// @Param sort query string false "created_at (default) or balance"
+// @Param order query string false "desc (default) or asc"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param offset query int false "Number of accounts to skip"
+// @Success 200 {object} AccountListResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /admin/accounts [get]
+func (h *AccountHandler) AdminListAccounts(c echo.Context) error {
+	invalid := func(message string) error {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: message,
+			Code:  errors.CodeValidationError,
+		})
+	}
+
+	var filter repository.AccountFilter
+	if v := c.QueryParam("is_merchant"); v != "" {
+		isMerchant, err := strconv.ParseBool(v)
+		if err != nil {
+			return invalid("is_merchant must be true or false")
+		}
+		filter.IsMerchant = &isMerchant
+	}
+	if v := c.QueryParam("active"); v != "" {
+		active, err := strconv.ParseBool(v)
+		if err != nil {
+			return invalid("active must be true or false")
+		}
+		filter.Active = &active
+	}
+	filter.Query = strings.TrimSpace(c.QueryParam("q"))
+	if len(filter.Query) > maxAccountSearchLength {
+		return invalid("q must be at most 100 characters")
+	}
+
+	var sort repository.AccountSort
+	switch v := c.QueryParam("sort"); v {
+	case "", string(repository.AccountSortCreatedAt):
+		sort.Field = repository.AccountSortCreatedAt
+	case string(repository.AccountSortBalance):
+		sort.Field = repository.AccountSortBalance
+	default:
+		return invalid("sort must be created_at or balance")
+	}
+	switch c.QueryParam("order") {
+	case "", "desc":
+	case "asc":
+		sort.Ascending = true
+	default:
+		return invalid("order must be asc or desc")
+	}
+
+	limit, offset, err := parsePagination(c)
+	if err != nil {
+		return err
+	}
+
+	accounts, total, err := h.accountService.ListAccounts(c.Request().Context(), filter, sort, limit, offset)
+	if err != nil {
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+	log.Printf("admin: accounts listed (is_merchant=%q active=%q q=%q sort=%s order=%q limit=%d offset=%d) by %s",
+		c.QueryParam("is_merchant"), c.QueryParam("active"), filter.Query, sort.Field, c.QueryParam("order"), limit, offset, c.RealIP())
+
+	items := make([]AdminAccountResponse, 0, len(accounts))
+	for _, account := range accounts {
+		items = append(items, AdminAccountResponse{
+			ID:         account.ID,
+			Name:       account.Name,
+			Email:      account.Email,
+			IsMerchant: account.IsMerchant,
+			Active:     account.Active,
+			Balance:    account.Balance.StringFixed(2),
+			CreatedAt:  account.CreatedAt,
+		})
+	}
+	return c.JSON(http.StatusOK, AccountListResponse{Accounts: items, Total: total, Limit: limit, Offset: offset})
+}
@@ -0,0 +1,291 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"paytabs/internal/errors"
+	"paytabs/internal/model"
+	"paytabs/internal/repository"
+	"paytabs/internal/service"
+)
+
+func TestAccountHandler_GetAccount(t *testing.T) {
+	account := &model.Account{ID: uuid.New(), Name: "Alice", Email: "alice@example.com", PasswordHash: "secret-hash"}
+
+	svc := new(MockAccountService)
+	svc.On("GetAccount", mock.Anything, account.ID).Return(account, nil)
+
+	c, rec := newTestContext(http.MethodGet, "/api/accounts/"+account.ID.String(), nil, account.ID.String())
+	c.SetParamNames("id")
+	c.SetParamValues(account.ID.String())
+	require.NoError(t, NewAccountHandler(svc, nil).GetAccount(c))
+
+	var resp model.Account
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, account.ID, resp.ID)
+	assert.NotContains(t, rec.Body.String(), "secret-hash")
+}
+
+func TestAccountHandler_GetAccount_OtherAccount(t *testing.T) {
+	accountID := uuid.New()
+	svc := new(MockAccountService)
+
+	c, _ := newTestContext(http.MethodGet, "/api/accounts/"+accountID.String(), nil, uuid.NewString())
+	c.SetParamNames("id")
+	c.SetParamValues(accountID.String())
+	err := NewAccountHandler(svc, nil).GetAccount(c)
+
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusForbidden, httpErr.Code)
+	svc.AssertNotCalled(t, "GetAccount", mock.Anything, mock.Anything)
+}
+
+func TestAccountHandler_GetMe(t *testing.T) {
+	account := &model.Account{ID: uuid.New(), Name: "Alice", Email: "alice@example.com", PasswordHash: "secret-hash"}
+
+	svc := new(MockAccountService)
+	svc.On("GetAccount", mock.Anything, account.ID).Return(account, nil)
+
+	c, rec := newTestContext(http.MethodGet, "/api/me", nil, account.ID.String())
+	require.NoError(t, NewAccountHandler(svc, nil).GetMe(c))
+
+	var resp model.Account
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, account.ID, resp.ID)
+	assert.Equal(t, "alice@example.com", resp.Email)
+	assert.NotContains(t, rec.Body.String(), "secret-hash")
+}
+
+func TestAccountHandler_GetMe_DeletedAccount(t *testing.T) {
+	accountID := uuid.New()
+	svc := new(MockAccountService)
+	svc.On("GetAccount", mock.Anything, accountID).Return(nil, errors.ErrAccountNotFound)
+
+	c, _ := newTestContext(http.MethodGet, "/api/me", nil, accountID.String())
+	err := NewAccountHandler(svc, nil).GetMe(c)
+
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.Code)
+	assert.Equal(t, errors.CodeAccountNotFound, httpErr.Message.(errors.ErrorResponse).Code)
+}
+
+func TestAccountHandler_GetMyBalance_MatchesGetBalance(t *testing.T) {
+	accountID := uuid.New()
+	svc := new(MockAccountService)
+	svc.On("GetBalance", mock.Anything, accountID).Return(decimal.NewFromFloat(42.5), nil)
+	h := NewAccountHandler(svc, nil)
+
+	byID, byIDRec := newTestContext(http.MethodGet, "/api/accounts/"+accountID.String()+"/balance", nil, accountID.String())
+	byID.SetParamNames("id")
+	byID.SetParamValues(accountID.String())
+	require.NoError(t, h.GetBalance(byID))
+
+	me, meRec := newTestContext(http.MethodGet, "/api/me/balance", nil, accountID.String())
+	require.NoError(t, h.GetMyBalance(me))
+
+	assert.Equal(t, http.StatusOK, meRec.Code)
+	assert.JSONEq(t, byIDRec.Body.String(), meRec.Body.String())
+}
+
+func TestAccountHandler_GetMyBalance_RequiresClaims(t *testing.T) {
+	svc := new(MockAccountService)
+
+	c, _ := newTestContext(http.MethodGet, "/api/me/balance", nil, "")
+	err := NewAccountHandler(svc, nil).GetMyBalance(c)
+
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusUnauthorized, httpErr.Code)
+	svc.AssertNotCalled(t, "GetBalance", mock.Anything, mock.Anything)
+}
+
+func TestAccountHandler_GetBalances(t *testing.T) {
+	ownID := uuid.New()
+	otherID := uuid.New()
+	body := func(ids ...uuid.UUID) *strings.Reader {
+		quoted := make([]string, 0, len(ids))
+		for _, id := range ids {
+			quoted = append(quoted, `"`+id.String()+`"`)
+		}
+		return strings.NewReader(`{"account_ids":[` + strings.Join(quoted, ",") + `]}`)
+	}
+
+	svc := new(MockAccountService)
+	svc.On("GetBalances", mock.Anything, []uuid.UUID{ownID}).Return([]service.AccountBalance{
+		{AccountID: ownID, Found: true, Balance: decimal.RequireFromString("25.5")},
+	}, nil)
+	svc.On("GetBalances", mock.Anything, []uuid.UUID{ownID, otherID}).Return([]service.AccountBalance{
+		{AccountID: ownID, Found: true, Balance: decimal.RequireFromString("25.5")},
+		{AccountID: otherID},
+	}, nil)
+	h := NewAccountHandler(svc, nil)
+
+	c, rec := newTestContext(http.MethodPost, "/api/accounts/balances", body(ownID), ownID.String())
+	require.NoError(t, h.GetBalances(c))
+	var resp BatchBalanceResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, []BatchBalanceItem{{AccountID: ownID, Status: BalanceFound, Balance: "25.5"}}, resp.Balances)
+
+	// Naming someone else's account is refused outright
+	c, _ = newTestContext(http.MethodPost, "/api/accounts/balances", body(ownID, otherID), ownID.String())
+	err := h.GetBalances(c)
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusForbidden, httpErr.Code)
+
+	// Operators may name any account; unknown ones are marked not_found
+	c, rec = newTestContext(http.MethodPost, "/api/admin/accounts/balances", body(ownID, otherID), "")
+	require.NoError(t, h.AdminGetBalances(c))
+	resp = BatchBalanceResponse{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, []BatchBalanceItem{
+		{AccountID: ownID, Status: BalanceFound, Balance: "25.5"},
+		{AccountID: otherID, Status: BalanceNotFound},
+	}, resp.Balances)
+}
+
+func TestAccountHandler_GetBalances_Validation(t *testing.T) {
+	tooMany := make([]string, 101)
+	for i := range tooMany {
+		tooMany[i] = `"` + uuid.NewString() + `"`
+	}
+	tests := []struct {
+		name string
+		body string
+		code errors.Code
+	}{
+		{name: "empty", body: `{"account_ids":[]}`, code: errors.CodeValidationError},
+		{name: "over the cap", body: fmt.Sprintf(`{"account_ids":[%s]}`, strings.Join(tooMany, ",")), code: errors.CodeValidationError},
+		{name: "not a uuid", body: `{"account_ids":["nope"]}`, code: errors.CodeInvalidUUID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := new(MockAccountService)
+			c, _ := newTestContext(http.MethodPost, "/api/admin/accounts/balances", strings.NewReader(tt.body), "")
+			err := NewAccountHandler(svc, nil).AdminGetBalances(c)
+
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+			assert.Equal(t, tt.code, httpErr.Message.(errors.ErrorResponse).Code)
+			svc.AssertNotCalled(t, "GetBalances", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestAccountHandler_GetSnapshot(t *testing.T) {
+	accountID := uuid.New()
+	asOf := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	snapshots := new(MockAccountSnapshotService)
+	snapshots.On("Snapshot", mock.Anything, accountID).Return(&service.AccountSnapshot{
+		AccountID:      accountID,
+		AsOf:           asOf,
+		AccountBalance: decimal.NewFromInt(100),
+		CardBalance:    decimal.NewFromInt(30),
+		Cards:          []model.Card{{ID: uuid.New(), CardNumber: "****1111", Balance: decimal.NewFromInt(30), Active: true}},
+		Payments:       []model.Payment{{ID: uuid.New(), Amount: decimal.NewFromInt(10), Status: model.PaymentStatusAccepted}},
+	}, nil)
+
+	c, rec := newTestContext(http.MethodGet, "/api/accounts/"+accountID.String()+"/snapshot", nil, accountID.String())
+	c.SetParamNames("id")
+	c.SetParamValues(accountID.String())
+	require.NoError(t, NewAccountHandler(nil, snapshots).GetSnapshot(c))
+
+	var resp AccountSnapshotResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, accountID, resp.AccountID)
+	assert.True(t, asOf.Equal(resp.AsOf))
+	assert.Equal(t, "100", resp.AccountBalance)
+	assert.Equal(t, "30", resp.CardBalance)
+	require.Len(t, resp.Cards, 1)
+	assert.Equal(t, "****1111", resp.Cards[0].CardNumber)
+	assert.Len(t, resp.Payments, 1)
+	assert.NotNil(t, resp.Transfers, "empty activity is an empty list, not null")
+	assert.Contains(t, rec.Body.String(), `"transfers":[]`)
+
+	// Another account's snapshot is forbidden
+	c, _ = newTestContext(http.MethodGet, "/api/accounts/"+accountID.String()+"/snapshot", nil, uuid.NewString())
+	c.SetParamNames("id")
+	c.SetParamValues(accountID.String())
+	err := NewAccountHandler(nil, snapshots).GetSnapshot(c)
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusForbidden, httpErr.Code)
+	snapshots.AssertNumberOfCalls(t, "Snapshot", 1)
+}
+
+func TestAccountHandler_AdminListAccounts(t *testing.T) {
+	yes := true
+	svc := new(MockAccountService)
+	svc.On("ListAccounts", mock.Anything,
+		repository.AccountFilter{IsMerchant: &yes, Active: &yes, Query: "shop"},
+		repository.AccountSort{Field: repository.AccountSortBalance, Ascending: true}, 5, 10,
+	).Return([]model.Account{
+		{ID: uuid.New(), Name: "Shop", Email: "shop@example.com", IsMerchant: true, Active: true, Balance: decimal.NewFromInt(12), PasswordHash: "secret-hash"},
+	}, int64(11), nil)
+
+	c, rec := newTestContext(http.MethodGet, "/api/admin/accounts?is_merchant=true&active=true&q=+shop+&sort=balance&order=asc&limit=5&offset=10", nil, "")
+	require.NoError(t, NewAccountHandler(svc, nil).AdminListAccounts(c))
+
+	assert.NotContains(t, rec.Body.String(), "secret-hash")
+	assert.NotContains(t, rec.Body.String(), "password")
+	var resp AccountListResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, int64(11), resp.Total)
+	assert.Equal(t, 5, resp.Limit)
+	assert.Equal(t, 10, resp.Offset)
+	require.Len(t, resp.Accounts, 1)
+	assert.Equal(t, "shop@example.com", resp.Accounts[0].Email)
+	assert.Equal(t, "12.00", resp.Accounts[0].Balance)
+	assert.True(t, resp.Accounts[0].IsMerchant)
+}
+
+func TestAccountHandler_AdminListAccounts_Defaults(t *testing.T) {
+	svc := new(MockAccountService)
+	svc.On("ListAccounts", mock.Anything, repository.AccountFilter{},
+		repository.AccountSort{Field: repository.AccountSortCreatedAt}, 20, 0,
+	).Return([]model.Account{}, int64(0), nil)
+
+	c, rec := newTestContext(http.MethodGet, "/api/admin/accounts", nil, "")
+	require.NoError(t, NewAccountHandler(svc, nil).AdminListAccounts(c))
+
+	assert.JSONEq(t, `{"accounts":[],"total":0,"limit":20,"offset":0}`, rec.Body.String())
+}
+
+func TestAccountHandler_AdminListAccounts_Rejections(t *testing.T) {
+	queries := []string{
+		"is_merchant=maybe",
+		"active=1x",
+		"q=" + strings.Repeat("a", 101),
+		"sort=password_hash",
+		"order=up",
+		"limit=0",
+	}
+	for _, query := range queries {
+		svc := new(MockAccountService)
+		c, _ := newTestContext(http.MethodGet, "/api/admin/accounts?"+query, nil, "")
+		err := NewAccountHandler(svc, nil).AdminListAccounts(c)
+
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr, query)
+		assert.Equal(t, http.StatusBadRequest, httpErr.Code, query)
+		svc.AssertNotCalled(t, "ListAccounts", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	}
+}
@@ -0,0 +1,474 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/shopspring/decimal"
+
+	"paytabs/internal/errors"
+	"paytabs/internal/service"
+)
+
+// AdminHandler handles operator endpoints.
+type AdminHandler struct {
+	killSwitch service.KillSwitchService
+	stats      service.AdminStatsService
+	holds      service.AccountHoldService
+	settings   service.MerchantSettingsService
+}
+
+// NewAdminHandler creates a new admin handler.
+func NewAdminHandler(killSwitch service.KillSwitchService, stats service.AdminStatsService, holds service.AccountHoldService, settings service.MerchantSettingsService) *AdminHandler {
+	return &AdminHandler{killSwitch: killSwitch, stats: stats, holds: holds, settings: settings}
+}
+
+// KillSwitchStatus is whether one class of money movement is switched on.
+type KillSwitchStatus struct {
+	Operation string `json:"operation"`
+	Enabled   bool   `json:"enabled"`
+}
+
+// KillSwitchListResponse lists every kill switch.
+type KillSwitchListResponse struct {
+	Switches []KillSwitchStatus `json:"switches"`
+}
+
+// SetKillSwitchRequest turns an operation on or off.
+type SetKillSwitchRequest struct {
+	Enabled *bool `json:"enabled" validate:"required"`
+}
+
+// QueueDepthResponse is how full one in-memory work queue is.
+type QueueDepthResponse struct {
+	Name     string `json:"name"`
+	Length   int    `json:"length"`
+	Capacity int    `json:"capacity"`
+	Dropped  int64  `json:"dropped"`
+}
+
+// WorkerPanicResponse is how many times one background worker panicked and was restarted.
+type WorkerPanicResponse struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// PlatformStatsResponse is the operational snapshot returned by GET /admin/stats.
+type PlatformStatsResponse struct {
+	Accounts        int64                 `json:"accounts"`
+	ActiveMerchants int64                 `json:"active_merchants"`
+	Cards           int64                 `json:"cards"`
+	TotalBalance    string                `json:"total_balance"`
+	CardBalance     string                `json:"card_balance"`
+	AccountBalance  string                `json:"account_balance"`
+	PaymentsToday   int64                 `json:"payments_today"`
+	TransfersToday  int64                 `json:"transfers_today"`
+	Since           time.Time             `json:"since"`
+	Queues          []QueueDepthResponse  `json:"queues"`
+	WorkerPanics    []WorkerPanicResponse `json:"worker_panics"`
+}
+
+// GetStats godoc
+// @Summary Live operational figures
+// @Description Account, merchant, and card counts, platform balances, today's payments and transfers (since midnight UTC), the depth of this instance's in-memory queues, and how often its background workers panicked.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Token header string true "Operator token (ADMIN_TOKEN)"
+// @Success 200 {object} PlatformStatsResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 429 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /admin/stats [get]
+func (h *AdminHandler) GetStats(c echo.Context) error {
+	stats, err := h.stats.Stats(c.Request().Context())
+	if err != nil {
+		return errors.MapErrorToHTTP(err)
+	}
+
+	queues := make([]QueueDepthResponse, 0, len(stats.Queues))
+	for _, q := range stats.Queues {
+		queues = append(queues, QueueDepthResponse{Name: q.Name, Length: q.Length, Capacity: q.Capacity, Dropped: q.Dropped})
+	}
+	panics := make([]WorkerPanicResponse, 0, len(stats.WorkerPanics))
+	for _, p := range stats.WorkerPanics {
+		panics = append(panics, WorkerPanicResponse{Name: p.Name, Count: p.Count})
+	}
+
+	return c.JSON(http.StatusOK, PlatformStatsResponse{
+		Accounts:        stats.Accounts,
+		ActiveMerchants: stats.ActiveMerchants,
+		Cards:           stats.Cards,
+		TotalBalance:    stats.TotalBalance.StringFixed(2),
+		CardBalance:     stats.CardBalance.StringFixed(2),
+		AccountBalance:  stats.AccountBalance.StringFixed(2),
+		PaymentsToday:   stats.PaymentsSince,
+		TransfersToday:  stats.TransfersSince,
+		Since:           stats.Since,
+		Queues:          queues,
+		WorkerPanics:    panics,
+	})
+}
+
+// CurrencyFloatResponse is the money the platform holds in one currency.
+type CurrencyFloatResponse struct {
+	Currency       string `json:"currency"`
+	CardBalance    string `json:"card_balance"`
+	AccountBalance string `json:"account_balance"`
+	PendingPayouts string `json:"pending_payouts"`
+	NetFloat       string `json:"net_float"`
+}
+
+// PlatformFloatResponse is the platform's float, returned by GET /admin/float.
+type PlatformFloatResponse struct {
+	AsOf       time.Time               `json:"as_of"`
+	Currencies []CurrencyFloatResponse `json:"currencies"`
+}
+
+// GetFloat godoc
+// @Summary Total money held, per currency
+// @Description For reconciling against the bank at the daily close. Per currency: the sum of card balances, the sum of account balances, pending payouts, and the net float (card plus account balances less pending payouts). The sums are read in one consistent database snapshot.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Token header string true "Operator token (ADMIN_TOKEN)"
+// @Success 200 {object} PlatformFloatResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 429 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /admin/float [get]
+func (h *AdminHandler) GetFloat(c echo.Context) error {
+	float, err := h.stats.Float(c.Request().Context())
+	if err != nil {
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+	log.Printf("admin: float read by %s", c.RealIP())
+
+	currencies := make([]CurrencyFloatResponse, 0, len(float.Currencies))
+	for _, f := range float.Currencies {
+		currencies = append(currencies, CurrencyFloatResponse{
+			Currency:       f.Currency,
+			CardBalance:    f.CardBalance.StringFixed(2),
+			AccountBalance: f.AccountBalance.StringFixed(2),
+			PendingPayouts: f.PendingPayouts.StringFixed(2),
+			NetFloat:       f.Net.StringFixed(2),
+		})
+	}
+	return c.JSON(http.StatusOK, PlatformFloatResponse{AsOf: float.AsOf, Currencies: currencies})
+}
+
+// ListKillSwitches godoc
+// @Summary List the payment and transfer kill switches
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Token header string true "Operator token (ADMIN_TOKEN)"
+// @Success 200 {object} KillSwitchListResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 503 {object} errors.ErrorResponse
+// @Router /admin/kill-switches [get]
+func (h *AdminHandler) ListKillSwitches(c echo.Context) error {
+	switches := make([]KillSwitchStatus, 0, len(service.MoneyOperations))
+	for _, op := range service.MoneyOperations {
+		enabled, err := h.killSwitch.Enabled(c.Request().Context(), op)
+		if err != nil {
+			return killSwitchUnavailable()
+		}
+		switches = append(switches, KillSwitchStatus{Operation: string(op), Enabled: enabled})
+	}
+	return c.JSON(http.StatusOK, KillSwitchListResponse{Switches: switches})
+}
+
+// SetKillSwitch godoc
+// @Summary Switch payments or transfers on or off
+// @Description Takes effect immediately on every instance. While an operation is off its endpoints return 503 SERVICE_DISABLED.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string true "Operator token (ADMIN_TOKEN)"
+// @Param operation path string true "payments or transfers"
+// @Param request body SetKillSwitchRequest true "New state"
+// @Success 200 {object} KillSwitchStatus
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 503 {object} errors.ErrorResponse
+// @Router /admin/kill-switches/{operation} [put]
+func (h *AdminHandler) SetKillSwitch(c echo.Context) error {
+	op, ok := service.ParseMoneyOperation(c.Param("operation"))
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, errors.ErrorResponse{
+			Error: "unknown operation",
+			Code:  errors.CodeUnknownOperation,
+		})
+	}
+
+	var req SetKillSwitchRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: "invalid request body",
+			Code:  errors.CodeInvalidRequest,
+		})
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: err.Error(),
+			Code:  errors.CodeValidationError,
+		})
+	}
+
+	if err := h.killSwitch.SetEnabled(c.Request().Context(), op, *req.Enabled); err != nil {
+		return killSwitchUnavailable()
+	}
+	log.Printf("kill switch: %s enabled=%t by %s", op, *req.Enabled, c.RealIP())
+
+	return c.JSON(http.StatusOK, KillSwitchStatus{Operation: string(op), Enabled: *req.Enabled})
+}
+
+func killSwitchUnavailable() error {
+	return echo.NewHTTPError(http.StatusServiceUnavailable, errors.ErrorResponse{
+		Error: "kill switch store is unavailable",
+		Code:  errors.CodeKillSwitchUnavailable,
+	})
+}
+
+// PlaceAccountHoldRequest holds an account on suspicion of fraud.
+type PlaceAccountHoldRequest struct {
+	Reason   string `json:"reason" validate:"required,max=255"`
+	Duration string `json:"duration,omitempty"` // Go duration, e.g. "2h"; defaults to ACCOUNT_HOLD_DURATION
+}
+
+// AccountHoldResponse is an account's current hold.
+type AccountHoldResponse struct {
+	AccountID string    `json:"account_id"`
+	Reason    string    `json:"reason"`
+	PlacedAt  time.Time `json:"placed_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func newAccountHoldResponse(hold *service.AccountHold) AccountHoldResponse {
+	return AccountHoldResponse{
+		AccountID: hold.AccountID.String(),
+		Reason:    hold.Reason,
+		PlacedAt:  hold.PlacedAt,
+		ExpiresAt: hold.ExpiresAt,
+	}
+}
+
+// GetAccountHold godoc
+// @Summary Get an account's hold
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Token header string true "Operator token (ADMIN_TOKEN)"
+// @Param id path string true "Account ID"
+// @Success 200 {object} AccountHoldResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 503 {object} errors.ErrorResponse
+// @Router /admin/accounts/{id}/hold [get]
+func (h *AdminHandler) GetAccountHold(c echo.Context) error {
+	accountID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	hold, err := h.holds.Get(c.Request().Context(), accountID)
+	if err != nil {
+		return accountHoldUnavailable()
+	}
+	if hold == nil {
+		return echo.NewHTTPError(http.StatusNotFound, errors.ErrorResponse{
+			Error: "account is not on hold",
+			Code:  errors.CodeNotFound,
+		})
+	}
+	return c.JSON(http.StatusOK, newAccountHoldResponse(hold))
+}
+
+// PlaceAccountHold godoc
+// @Summary Hold an account
+// @Description Blocks payments from and transfers out of the account's cards until the hold expires or is lifted. Replaces any current hold.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string true "Operator token (ADMIN_TOKEN)"
+// @Param id path string true "Account ID"
+// @Param request body PlaceAccountHoldRequest true "Hold reason and duration"
+// @Success 200 {object} AccountHoldResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 503 {object} errors.ErrorResponse
+// @Router /admin/accounts/{id}/hold [put]
+func (h *AdminHandler) PlaceAccountHold(c echo.Context) error {
+	accountID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var req PlaceAccountHoldRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: "invalid request body",
+			Code:  errors.CodeInvalidRequest,
+		})
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: err.Error(),
+			Code:  errors.CodeValidationError,
+		})
+	}
+
+	var duration time.Duration
+	if req.Duration != "" {
+		duration, err = time.ParseDuration(req.Duration)
+		if err != nil || duration <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+				Error: "duration must be a positive Go duration, e.g. 2h",
+				Code:  errors.CodeValidationError,
+			})
+		}
+	}
+
+	hold, err := h.holds.Place(c.Request().Context(), accountID, req.Reason, duration)
+	if err != nil {
+		return accountHoldUnavailable()
+	}
+	return c.JSON(http.StatusOK, newAccountHoldResponse(hold))
+}
+
+// LiftAccountHold godoc
+// @Summary Lift an account's hold
+// @Description Succeeds whether or not the account is on hold.
+// @Tags admin
+// @Param X-Admin-Token header string true "Operator token (ADMIN_TOKEN)"
+// @Param id path string true "Account ID"
+// @Success 204
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 503 {object} errors.ErrorResponse
+// @Router /admin/accounts/{id}/hold [delete]
+func (h *AdminHandler) LiftAccountHold(c echo.Context) error {
+	accountID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := h.holds.Lift(c.Request().Context(), accountID); err != nil {
+		return accountHoldUnavailable()
+	}
+	log.Printf("account hold: %s lifted by %s", accountID, c.RealIP())
+	return c.NoContent(http.StatusNoContent)
+}
+
+func accountHoldUnavailable() error {
+	return echo.NewHTTPError(http.StatusServiceUnavailable, errors.ErrorResponse{
+		Error: "account hold store is unavailable",
+		Code:  errors.CodeAccountHoldUnavailable,
+	})
+}
+
+// MerchantSettingsResponse is a merchant's operator-controlled settings. Amounts of "0.00" mean
+// no limit; effective_max_payment_amount is the ceiling payments are checked against.
+type MerchantSettingsResponse struct {
+	AccountID                 string `json:"account_id"`
+	MaxPaymentAmount          string `json:"max_payment_amount"`
+	GlobalMaxPaymentAmount    string `json:"global_max_payment_amount"`
+	EffectiveMaxPaymentAmount string `json:"effective_max_payment_amount"`
+}
+
+// UpdateMerchantSettingsRequest changes a merchant's settings.
+type UpdateMerchantSettingsRequest struct {
+	MaxPaymentAmount string `json:"max_payment_amount" validate:"required"` // "0" falls back to MAX_PAYMENT_AMOUNT
+}
+
+func newMerchantSettingsResponse(settings *service.MerchantSettings) MerchantSettingsResponse {
+	return MerchantSettingsResponse{
+		AccountID:                 settings.AccountID.String(),
+		MaxPaymentAmount:          settings.MaxPaymentAmount.StringFixed(2),
+		GlobalMaxPaymentAmount:    settings.GlobalMaxPaymentAmount.StringFixed(2),
+		EffectiveMaxPaymentAmount: settings.EffectiveMaxPaymentAmount.StringFixed(2),
+	}
+}
+
+// GetMerchantSettings godoc
+// @Summary Get a merchant's settings
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Token header string true "Operator token (ADMIN_TOKEN)"
+// @Param id path string true "Merchant account ID"
+// @Success 200 {object} MerchantSettingsResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /admin/merchants/{id}/settings [get]
+func (h *AdminHandler) GetMerchantSettings(c echo.Context) error {
+	merchantID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	settings, err := h.settings.Get(c.Request().Context(), merchantID)
+	if err != nil {
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+	return c.JSON(http.StatusOK, newMerchantSettingsResponse(settings))
+}
+
+// UpdateMerchantSettings godoc
+// @Summary Change a merchant's settings
+// @Description Sets the merchant's max single-payment amount. Payments are checked against the lower of it and MAX_PAYMENT_AMOUNT; 0 means only MAX_PAYMENT_AMOUNT applies.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string true "Operator token (ADMIN_TOKEN)"
+// @Param id path string true "Merchant account ID"
+// @Param request body UpdateMerchantSettingsRequest true "New settings"
+// @Success 200 {object} MerchantSettingsResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /admin/merchants/{id}/settings [put]
+func (h *AdminHandler) UpdateMerchantSettings(c echo.Context) error {
+	merchantID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var req UpdateMerchantSettingsRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: "invalid request body",
+			Code:  errors.CodeInvalidRequest,
+		})
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: err.Error(),
+			Code:  errors.CodeValidationError,
+		})
+	}
+
+	amount, err := decimal.NewFromString(req.MaxPaymentAmount)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: "invalid amount",
+			Code:  errors.CodeInvalidAmount,
+		})
+	}
+
+	settings, err := h.settings.SetMaxPaymentAmount(c.Request().Context(), merchantID, amount)
+	if err != nil {
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+	log.Printf("merchant settings: %s max_payment_amount=%s by %s", merchantID, amount.StringFixed(2), c.RealIP())
+
+	return c.JSON(http.StatusOK, newMerchantSettingsResponse(settings))
+}
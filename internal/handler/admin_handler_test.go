@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"paytabs/internal/repository"
+	"paytabs/internal/service"
+)
+
+func TestAdminHandler_ListKillSwitches(t *testing.T) {
+	svc := new(MockKillSwitchService)
+	svc.On("Enabled", mock.Anything, service.OperationPayments).Return(false, nil)
+	svc.On("Enabled", mock.Anything, service.OperationTransfers).Return(true, nil)
+
+	c, rec := newTestContext(http.MethodGet, "/api/admin/kill-switches", nil, "")
+	require.NoError(t, NewAdminHandler(svc, nil, nil, nil).ListKillSwitches(c))
+
+	var resp KillSwitchListResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, []KillSwitchStatus{
+		{Operation: "payments", Enabled: false},
+		{Operation: "transfers", Enabled: true},
+	}, resp.Switches)
+}
+
+func TestAdminHandler_SetKillSwitch(t *testing.T) {
+	svc := new(MockKillSwitchService)
+	svc.On("SetEnabled", mock.Anything, service.OperationTransfers, false).Return(nil)
+
+	c, rec := newTestContext(http.MethodPut, "/api/admin/kill-switches/transfers", strings.NewReader(`{"enabled":false}`), "")
+	c.SetParamNames("operation")
+	c.SetParamValues("transfers")
+	require.NoError(t, NewAdminHandler(svc, nil, nil, nil).SetKillSwitch(c))
+
+	var resp KillSwitchStatus
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, KillSwitchStatus{Operation: "transfers", Enabled: false}, resp)
+	svc.AssertExpectations(t)
+}
+
+func TestAdminHandler_SetKillSwitch_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		operation  string
+		body       string
+		storeErr   error
+		wantStatus int
+	}{
+		{name: "unknown operation", operation: "refunds", body: `{"enabled":false}`, wantStatus: http.StatusNotFound},
+		{name: "missing enabled", operation: "payments", body: `{}`, wantStatus: http.StatusBadRequest},
+		{name: "redis unavailable", operation: "payments", body: `{"enabled":false}`, storeErr: stderrors.New("connection refused"), wantStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := new(MockKillSwitchService)
+			svc.On("SetEnabled", mock.Anything, mock.Anything, mock.Anything).Return(tt.storeErr).Maybe()
+
+			c, _ := newTestContext(http.MethodPut, "/api/admin/kill-switches/"+tt.operation, strings.NewReader(tt.body), "")
+			c.SetParamNames("operation")
+			c.SetParamValues(tt.operation)
+			err := NewAdminHandler(svc, nil, nil, nil).SetKillSwitch(c)
+
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, tt.wantStatus, httpErr.Code)
+		})
+	}
+}
+
+func TestAdminHandler_GetStats(t *testing.T) {
+	since := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+	stats := new(MockAdminStatsService)
+	stats.On("Stats", mock.Anything).Return(&service.PlatformStats{
+		PlatformTotals: repository.PlatformTotals{
+			Accounts:        12,
+			ActiveMerchants: 3,
+			Cards:           20,
+			CardBalance:     decimal.RequireFromString("1500.25"),
+			AccountBalance:  decimal.RequireFromString("499.75"),
+			PaymentsSince:   7,
+			TransfersSince:  4,
+		},
+		TotalBalance: decimal.RequireFromString("2000"),
+		Since:        since,
+		Queues:       []service.QueueDepth{{Name: "payment_logs", Length: 2, Capacity: 100, Dropped: 5}},
+		WorkerPanics: []service.WorkerPanic{{Name: "balance_alerts", Count: 1}},
+	}, nil)
+
+	c, rec := newTestContext(http.MethodGet, "/api/admin/stats", nil, "")
+	require.NoError(t, NewAdminHandler(nil, stats, nil, nil).GetStats(c))
+
+	var resp PlatformStatsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, PlatformStatsResponse{
+		Accounts:        12,
+		ActiveMerchants: 3,
+		Cards:           20,
+		TotalBalance:    "2000.00",
+		CardBalance:     "1500.25",
+		AccountBalance:  "499.75",
+		PaymentsToday:   7,
+		TransfersToday:  4,
+		Since:           since,
+		Queues:          []QueueDepthResponse{{Name: "payment_logs", Length: 2, Capacity: 100, Dropped: 5}},
+		WorkerPanics:    []WorkerPanicResponse{{Name: "balance_alerts", Count: 1}},
+	}, resp)
+}
+
+func TestAdminHandler_GetFloat(t *testing.T) {
+	asOf := time.Date(2024, 3, 15, 23, 59, 0, 0, time.UTC)
+	stats := new(MockAdminStatsService)
+	stats.On("Float", mock.Anything).Return(&service.PlatformFloat{
+		AsOf: asOf,
+		Currencies: []service.FloatBalance{{
+			CurrencyFloat: repository.CurrencyFloat{
+				Currency:       "USD",
+				CardBalance:    decimal.RequireFromString("1010.5"),
+				AccountBalance: decimal.RequireFromString("305.25"),
+				PendingPayouts: decimal.RequireFromString("100.75"),
+			},
+			Net: decimal.RequireFromString("1215"),
+		}},
+	}, nil)
+
+	c, rec := newTestContext(http.MethodGet, "/api/admin/float", nil, "")
+	require.NoError(t, NewAdminHandler(nil, stats, nil, nil).GetFloat(c))
+
+	var resp PlatformFloatResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, PlatformFloatResponse{
+		AsOf: asOf,
+		Currencies: []CurrencyFloatResponse{{
+			Currency:       "USD",
+			CardBalance:    "1010.50",
+			AccountBalance: "305.25",
+			PendingPayouts: "100.75",
+			NetFloat:       "1215.00",
+		}},
+	}, resp)
+}
+
+func TestAdminHandler_PlaceAccountHold(t *testing.T) {
+	accountID := uuid.New()
+	placed := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		body       string
+		duration   time.Duration
+		wantStatus int
+	}{
+		{name: "default duration", body: `{"reason":"velocity"}`, wantStatus: http.StatusOK},
+		{name: "explicit duration", body: `{"reason":"velocity","duration":"2h"}`, duration: 2 * time.Hour, wantStatus: http.StatusOK},
+		{name: "missing reason", body: `{"duration":"2h"}`, wantStatus: http.StatusBadRequest},
+		{name: "bad duration", body: `{"reason":"velocity","duration":"-1h"}`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			holds := new(MockAccountHoldService)
+			holds.On("Place", mock.Anything, accountID, "velocity", tt.duration).Return(&service.AccountHold{
+				AccountID: accountID, Reason: "velocity", PlacedAt: placed, ExpiresAt: placed.Add(time.Hour),
+			}, nil).Maybe()
+
+			c, rec := newTestContext(http.MethodPut, "/api/admin/accounts/"+accountID.String()+"/hold", strings.NewReader(tt.body), "")
+			c.SetParamNames("id")
+			c.SetParamValues(accountID.String())
+			err := NewAdminHandler(nil, nil, holds, nil).PlaceAccountHold(c)
+
+			if tt.wantStatus != http.StatusOK {
+				var httpErr *echo.HTTPError
+				require.ErrorAs(t, err, &httpErr)
+				assert.Equal(t, tt.wantStatus, httpErr.Code)
+				holds.AssertNotCalled(t, "Place", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			var resp AccountHoldResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, accountID.String(), resp.AccountID)
+			assert.Equal(t, placed.Add(time.Hour), resp.ExpiresAt)
+			holds.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAdminHandler_LiftAccountHold(t *testing.T) {
+	accountID := uuid.New()
+	holds := new(MockAccountHoldService)
+	holds.On("Lift", mock.Anything, accountID).Return(nil)
+
+	c, rec := newTestContext(http.MethodDelete, "/api/admin/accounts/"+accountID.String()+"/hold", nil, "")
+	c.SetParamNames("id")
+	c.SetParamValues(accountID.String())
+	require.NoError(t, NewAdminHandler(nil, nil, holds, nil).LiftAccountHold(c))
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	holds.AssertExpectations(t)
+}
+
+func TestAdminHandler_UpdateMerchantSettings(t *testing.T) {
+	merchantID := uuid.New()
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "sets ceiling", body: `{"max_payment_amount":"250"}`, wantStatus: http.StatusOK},
+		{name: "missing amount", body: `{}`, wantStatus: http.StatusBadRequest},
+		{name: "not a number", body: `{"max_payment_amount":"lots"}`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := new(MockMerchantSettingsService)
+			settings.On("SetMaxPaymentAmount", mock.Anything, merchantID, decimal.RequireFromString("250")).Return(&service.MerchantSettings{
+				AccountID:                 merchantID,
+				MaxPaymentAmount:          decimal.RequireFromString("250"),
+				GlobalMaxPaymentAmount:    decimal.RequireFromString("1000"),
+				EffectiveMaxPaymentAmount: decimal.RequireFromString("250"),
+			}, nil).Maybe()
+
+			c, rec := newTestContext(http.MethodPut, "/api/admin/merchants/"+merchantID.String()+"/settings", strings.NewReader(tt.body), "")
+			c.SetParamNames("id")
+			c.SetParamValues(merchantID.String())
+			err := NewAdminHandler(nil, nil, nil, settings).UpdateMerchantSettings(c)
+
+			if tt.wantStatus != http.StatusOK {
+				var httpErr *echo.HTTPError
+				require.ErrorAs(t, err, &httpErr)
+				assert.Equal(t, tt.wantStatus, httpErr.Code)
+				settings.AssertNotCalled(t, "SetMaxPaymentAmount", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			var resp MerchantSettingsResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, MerchantSettingsResponse{
+				AccountID:                 merchantID.String(),
+				MaxPaymentAmount:          "250.00",
+				GlobalMaxPaymentAmount:    "1000.00",
+				EffectiveMaxPaymentAmount: "250.00",
+			}, resp)
+		})
+	}
+}
@@ -2,11 +2,13 @@ package handler
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/labstack/echo/v4"
 	"gorm.io/gorm"
 
 	"paytabs/internal/errors"
+	"paytabs/internal/model"
 	"paytabs/internal/service"
 )
 
@@ -44,6 +46,44 @@ type LogoutRequest struct {
 	RefreshToken string `json:"refresh_token" validate:"required"`
 }
 
+// VerifyEmailRequest represents an email verification request.
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// ResendVerificationRequest asks for a new email verification token.
+type ResendVerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ForgotPasswordRequest represents a password reset request.
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResetPasswordRequest sets a new password with a mailed reset token.
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=6"`
+}
+
+// ChangePasswordRequest replaces the authenticated account's password.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=6"`
+}
+
+// EmailAvailabilityRequest represents an email availability query.
+type EmailAvailabilityRequest struct {
+	Email string `query:"email" validate:"required,email"`
+}
+
+// EmailAvailabilityResponse represents whether an email can be used to register.
+type EmailAvailabilityResponse struct {
+	Email     string `json:"email"`
+	Available bool   `json:"available"`
+}
+
 // AuthResponse represents an authentication response.
 type AuthResponse struct {
 	AccessToken  string      `json:"access_token"`
@@ -58,6 +98,7 @@ type AuthResponse struct {
 // @Produce json
 // @Param request body RegisterRequest true "Registration data"
 // @Success 201 {object} map[string]interface{}
+// @Header 201 {string} Location "/api/accounts/{id}"
 // @Failure 400 {object} errors.ErrorResponse
 // @Failure 409 {object} errors.ErrorResponse
 // @Failure 500 {object} errors.ErrorResponse
@@ -67,6 +108,7 @@ func (h *AuthHandler) Register(c echo.Context) error {
 	if err := c.Bind(&req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
 	}
+	req.Email = model.NormalizeEmail(req.Email)
 
 	if err := c.Validate(&req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
@@ -77,17 +119,22 @@ func (h *AuthHandler) Register(c echo.Context) error {
 		if err == service.ErrUserAlreadyExists {
 			return echo.NewHTTPError(http.StatusConflict, errors.ErrorResponse{
 				Error: err.Error(),
-				Code:  "ACCOUNT_ALREADY_EXISTS",
+				Code:  errors.CodeAccountAlreadyExists,
 			})
 		}
 		return echo.NewHTTPError(http.StatusInternalServerError, errors.ErrorResponse{
 			Error: "failed to register account",
-			Code:  "REGISTRATION_FAILED",
+			Code:  errors.CodeRegistrationFailed,
 		})
 	}
 
+	message := "account registered successfully"
+	if !account.EmailVerified {
+		message = "account registered, verify your email to log in"
+	}
+	setLocation(c, "/api/accounts/"+account.ID.String())
 	return c.JSON(http.StatusCreated, map[string]interface{}{
-		"message": "account registered successfully",
+		"message": message,
 		"account": account,
 	})
 }
@@ -101,6 +148,8 @@ func (h *AuthHandler) Register(c echo.Context) error {
 // @Success 200 {object} AuthResponse
 // @Failure 400 {object} errors.ErrorResponse
 // @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 429 {object} errors.ErrorResponse
 // @Failure 500 {object} errors.ErrorResponse
 // @Router /auth/login [post]
 func (h *AuthHandler) Login(c echo.Context) error {
@@ -108,22 +157,35 @@ func (h *AuthHandler) Login(c echo.Context) error {
 	if err := c.Bind(&req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
 	}
+	req.Email = model.NormalizeEmail(req.Email)
 
 	if err := c.Validate(&req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
-	accessToken, refreshToken, account, err := h.authService.Login(c.Request().Context(), req.Email, req.Password)
+	accessToken, refreshToken, account, err := h.authService.Login(c.Request().Context(), req.Email, req.Password, c.RealIP())
 	if err != nil {
 		if err == service.ErrInvalidCredentials {
 			return echo.NewHTTPError(http.StatusUnauthorized, errors.ErrorResponse{
 				Error: err.Error(),
-				Code:  "INVALID_CREDENTIALS",
+				Code:  errors.CodeInvalidCredentials,
+			})
+		}
+		if err == service.ErrTooManyAttempts {
+			return echo.NewHTTPError(http.StatusTooManyRequests, errors.ErrorResponse{
+				Error: err.Error(),
+				Code:  errors.CodeTooManyAttempts,
+			})
+		}
+		if err == service.ErrAccountNotVerified {
+			return echo.NewHTTPError(http.StatusForbidden, errors.ErrorResponse{
+				Error: err.Error(),
+				Code:  errors.CodeAccountNotVerified,
 			})
 		}
 		return echo.NewHTTPError(http.StatusInternalServerError, errors.ErrorResponse{
 			Error: "failed to login",
-			Code:  "LOGIN_FAILED",
+			Code:  errors.CodeLoginFailed,
 		})
 	}
 
@@ -136,6 +198,7 @@ func (h *AuthHandler) Login(c echo.Context) error {
 
 // Refresh godoc
 // @Summary Refresh access token
+// @Description Returns a new access token and a new refresh token. The presented refresh token is consumed and rejected if used again.
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -155,22 +218,23 @@ func (h *AuthHandler) Refresh(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
-	accessToken, err := h.authService.RefreshToken(c.Request().Context(), req.RefreshToken)
+	accessToken, refreshToken, err := h.authService.RefreshToken(c.Request().Context(), req.RefreshToken)
 	if err != nil {
 		if err == service.ErrInvalidRefreshToken {
 			return echo.NewHTTPError(http.StatusUnauthorized, errors.ErrorResponse{
 				Error: err.Error(),
-				Code:  "INVALID_REFRESH_TOKEN",
+				Code:  errors.CodeInvalidRefreshToken,
 			})
 		}
 		return echo.NewHTTPError(http.StatusInternalServerError, errors.ErrorResponse{
 			Error: "failed to refresh token",
-			Code:  "REFRESH_FAILED",
+			Code:  errors.CodeRefreshFailed,
 		})
 	}
 
 	return c.JSON(http.StatusOK, AuthResponse{
-		AccessToken: accessToken,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
 	})
 }
 
@@ -180,6 +244,7 @@ func (h *AuthHandler) Refresh(c echo.Context) error {
 // @Accept json
 // @Produce json
 // @Param request body LogoutRequest true "Refresh token"
+// @Param Authorization header string false "Bearer access token to revoke along with the refresh token"
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} errors.ErrorResponse
 // @Failure 401 {object} errors.ErrorResponse
@@ -195,16 +260,18 @@ func (h *AuthHandler) Logout(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
-	if err := h.authService.Logout(c.Request().Context(), req.RefreshToken); err != nil {
+	// The access token is optional; when sent it is revoked too instead of staying valid until it expires
+	accessToken := strings.TrimPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+	if err := h.authService.Logout(c.Request().Context(), req.RefreshToken, accessToken); err != nil {
 		if err == service.ErrInvalidRefreshToken {
 			return echo.NewHTTPError(http.StatusUnauthorized, errors.ErrorResponse{
 				Error: err.Error(),
-				Code:  "INVALID_REFRESH_TOKEN",
+				Code:  errors.CodeInvalidRefreshToken,
 			})
 		}
 		return echo.NewHTTPError(http.StatusInternalServerError, errors.ErrorResponse{
 			Error: "failed to logout",
-			Code:  "LOGOUT_FAILED",
+			Code:  errors.CodeLogoutFailed,
 		})
 	}
 
@@ -213,17 +280,336 @@ func (h *AuthHandler) Logout(c echo.Context) error {
 	})
 }
 
+// LogoutAll godoc
+// @Summary Log out every session
+// @Description Revokes all refresh tokens of the authenticated account and the access token used for this request. Access tokens held by other sessions keep working until they expire.
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /auth/logout-all [post]
+func (h *AuthHandler) LogoutAll(c echo.Context) error {
+	accountID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	accessToken := strings.TrimPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+	if err := h.authService.LogoutAll(c.Request().Context(), accountID, accessToken); err != nil {
+		if err == errors.ErrAccountNotFound {
+			httpErr := errors.MapErrorToHTTP(err)
+			return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, errors.ErrorResponse{
+			Error: "failed to log out all sessions",
+			Code:  errors.CodeLogoutAllFailed,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "all sessions logged out",
+	})
+}
+
+// VerifyEmail godoc
+// @Summary Verify a new account's email
+// @Description Redeems the token mailed on registration and marks the account's email verified, so it can log in. Each token works once.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body VerifyEmailRequest true "Verification token"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /auth/verify-email [post]
+func (h *AuthHandler) VerifyEmail(c echo.Context) error {
+	var req VerifyEmailRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: "invalid request body",
+			Code:  errors.CodeInvalidRequest,
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: err.Error(),
+			Code:  errors.CodeValidationError,
+		})
+	}
+
+	if err := h.authService.VerifyEmail(c.Request().Context(), req.Token); err != nil {
+		if err == service.ErrInvalidVerificationToken {
+			return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+				Error: err.Error(),
+				Code:  errors.CodeInvalidVerificationToken,
+			})
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, errors.ErrorResponse{
+			Error: "failed to verify email",
+			Code:  errors.CodeVerificationFailed,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "email verified",
+	})
+}
+
+// ResendVerification godoc
+// @Summary Resend the email verification token
+// @Description Emails a new verification token to the account registered with the email, if it is not verified yet. Answers 200 whether or not such an account exists, so it cannot be used to find registered emails.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body ResendVerificationRequest true "Account email"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Failure 503 {object} errors.ErrorResponse
+// @Router /auth/resend-verification [post]
+func (h *AuthHandler) ResendVerification(c echo.Context) error {
+	var req ResendVerificationRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: "invalid request body",
+			Code:  errors.CodeInvalidRequest,
+		})
+	}
+	req.Email = model.NormalizeEmail(req.Email)
+
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: err.Error(),
+			Code:  errors.CodeValidationError,
+		})
+	}
+
+	if err := h.authService.ResendVerification(c.Request().Context(), req.Email); err != nil {
+		if err == service.ErrEmailVerificationDisabled {
+			return echo.NewHTTPError(http.StatusServiceUnavailable, errors.ErrorResponse{
+				Error: err.Error(),
+				Code:  errors.CodeVerificationDisabled,
+			})
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, errors.ErrorResponse{
+			Error: "failed to resend verification email",
+			Code:  errors.CodeVerificationFailed,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "if an unverified account is registered with this email, a verification token has been sent to it",
+	})
+}
+
+// ForgotPassword godoc
+// @Summary Request a password reset
+// @Description Emails a single-use reset token to the account registered with the email. Answers 200 whether or not such an account exists, so it cannot be used to find registered emails.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body ForgotPasswordRequest true "Account email"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Failure 503 {object} errors.ErrorResponse
+// @Router /auth/forgot-password [post]
+func (h *AuthHandler) ForgotPassword(c echo.Context) error {
+	var req ForgotPasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: "invalid request body",
+			Code:  errors.CodeInvalidRequest,
+		})
+	}
+	req.Email = model.NormalizeEmail(req.Email)
+
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: err.Error(),
+			Code:  errors.CodeValidationError,
+		})
+	}
+
+	if err := h.authService.ForgotPassword(c.Request().Context(), req.Email); err != nil {
+		if err == service.ErrPasswordResetDisabled {
+			return echo.NewHTTPError(http.StatusServiceUnavailable, errors.ErrorResponse{
+				Error: err.Error(),
+				Code:  errors.CodePasswordResetDisabled,
+			})
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, errors.ErrorResponse{
+			Error: "failed to request password reset",
+			Code:  errors.CodePasswordResetFailed,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "if an account is registered with this email, a password reset token has been sent to it",
+	})
+}
+
+// ResetPassword godoc
+// @Summary Reset a password
+// @Description Redeems a token from forgot-password and sets the account's new password. Each token works once.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /auth/reset-password [post]
+func (h *AuthHandler) ResetPassword(c echo.Context) error {
+	var req ResetPasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: "invalid request body",
+			Code:  errors.CodeInvalidRequest,
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: err.Error(),
+			Code:  errors.CodeValidationError,
+		})
+	}
+
+	if err := h.authService.ResetPassword(c.Request().Context(), req.Token, req.Password); err != nil {
+		if err == service.ErrInvalidResetToken {
+			return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+				Error: err.Error(),
+				Code:  errors.CodeInvalidResetToken,
+			})
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, errors.ErrorResponse{
+			Error: "failed to reset password",
+			Code:  errors.CodePasswordResetFailed,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "password updated",
+	})
+}
+
+// ChangePassword godoc
+// @Summary Change the authenticated account's password
+// @Description Requires the current password. On success every refresh token of the account is revoked, so other sessions must log in again.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /auth/change-password [post]
+func (h *AuthHandler) ChangePassword(c echo.Context) error {
+	accountID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	var req ChangePasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: "invalid request body",
+			Code:  errors.CodeInvalidRequest,
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: err.Error(),
+			Code:  errors.CodeValidationError,
+		})
+	}
+
+	if err := h.authService.ChangePassword(c.Request().Context(), accountID, req.CurrentPassword, req.NewPassword); err != nil {
+		// 400 rather than Login's 401: the access token is fine, only the password is wrong
+		if err == service.ErrInvalidCredentials {
+			return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+				Error: "current password is incorrect",
+				Code:  errors.CodeInvalidCredentials,
+			})
+		}
+		if err == errors.ErrAccountNotFound {
+			httpErr := errors.MapErrorToHTTP(err)
+			return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, errors.ErrorResponse{
+			Error: "failed to change password",
+			Code:  errors.CodePasswordChangeFailed,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "password changed",
+	})
+}
+
+// EmailAvailable godoc
+// @Summary Check whether an email is available for registration
+// @Description Disabled unless EMAIL_AVAILABILITY_ENABLED=true. Answers reveal which emails are registered,
+// @Description so the endpoint is aggressively rate limited per client IP.
+// @Tags auth
+// @Produce json
+// @Param email query string true "Email address"
+// @Success 200 {object} EmailAvailabilityResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 429 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /auth/email-available [get]
+func (h *AuthHandler) EmailAvailable(c echo.Context) error {
+	var req EmailAvailabilityRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: "invalid request",
+			Code:  errors.CodeInvalidRequest,
+		})
+	}
+	req.Email = model.NormalizeEmail(req.Email)
+
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: err.Error(),
+			Code:  errors.CodeValidationError,
+		})
+	}
+
+	available, err := h.authService.IsEmailAvailable(c.Request().Context(), req.Email)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, errors.ErrorResponse{
+			Error: "failed to check email availability",
+			Code:  errors.CodeEmailCheckFailed,
+		})
+	}
+
+	return c.JSON(http.StatusOK, EmailAvailabilityResponse{
+		Email:     req.Email,
+		Available: available,
+	})
+}
+
 // Helper function to handle GORM errors
 func handleDBError(err error) *echo.HTTPError {
 	if err == gorm.ErrRecordNotFound {
 		return echo.NewHTTPError(http.StatusNotFound, errors.ErrorResponse{
 			Error: "record not found",
-			Code:  "NOT_FOUND",
+			Code:  errors.CodeNotFound,
 		})
 	}
 	return echo.NewHTTPError(http.StatusInternalServerError, errors.ErrorResponse{
 		Error: "database error",
-		Code:  "DATABASE_ERROR",
+		Code:  errors.CodeDatabaseError,
 	})
 }
 
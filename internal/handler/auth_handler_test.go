@@ -0,0 +1,329 @@
+package handler
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"paytabs/internal/errors"
+	"paytabs/internal/model"
+	"paytabs/internal/service"
+)
+
+func TestAuthHandler_Register_DuplicateMixedCaseEmail(t *testing.T) {
+	svc := new(MockAuthService)
+	svc.On("Register", mock.Anything, "existing@example.com", "password123", "Existing User", false).
+		Return(nil, service.ErrUserAlreadyExists)
+
+	body := `{"email":" Existing@Example.com ","password":"password123","name":"Existing User"}`
+	c, _ := newTestContext(http.MethodPost, "/api/auth/register", strings.NewReader(body), "")
+
+	err := NewAuthHandler(svc).Register(c)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusConflict, httpErr.Code)
+	assert.Equal(t, errors.CodeAccountAlreadyExists, httpErr.Message.(errors.ErrorResponse).Code)
+	svc.AssertExpectations(t)
+}
+
+func TestAuthHandler_Login_MixedCaseEmail(t *testing.T) {
+	svc := new(MockAuthService)
+	svc.On("Login", mock.Anything, "test@example.com", "password123", mock.Anything).
+		Return("access", "refresh", &model.Account{Email: "test@example.com"}, nil)
+
+	body := `{"email":"Test@Example.com ","password":"password123"}`
+	c, rec := newTestContext(http.MethodPost, "/api/auth/login", strings.NewReader(body), "")
+
+	require.NoError(t, NewAuthHandler(svc).Login(c))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	svc.AssertExpectations(t)
+}
+
+func TestAuthHandler_Register_SetsLocation(t *testing.T) {
+	account := &model.Account{ID: uuid.New(), Email: "new@example.com"}
+	svc := new(MockAuthService)
+	svc.On("Register", mock.Anything, "new@example.com", "password123", "New User", false).Return(account, nil)
+
+	body := `{"email":"new@example.com","password":"password123","name":"New User"}`
+	c, rec := newTestContext(http.MethodPost, "/api/auth/register", strings.NewReader(body), "")
+
+	require.NoError(t, NewAuthHandler(svc).Register(c))
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "/api/accounts/"+account.ID.String(), rec.Header().Get(echo.HeaderLocation))
+}
+
+func TestAuthHandler_Logout_RevokesBearerToken(t *testing.T) {
+	svc := new(MockAuthService)
+	svc.On("Logout", mock.Anything, "refresh", "access").Return(nil)
+
+	c, rec := newTestContext(http.MethodPost, "/api/auth/logout", strings.NewReader(`{"refresh_token":"refresh"}`), "")
+	c.Request().Header.Set(echo.HeaderAuthorization, "Bearer access")
+
+	require.NoError(t, NewAuthHandler(svc).Logout(c))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	svc.AssertExpectations(t)
+}
+
+func TestAuthHandler_Login_TooManyAttempts(t *testing.T) {
+	svc := new(MockAuthService)
+	svc.On("Login", mock.Anything, "test@example.com", "password123", mock.Anything).
+		Return("", "", nil, service.ErrTooManyAttempts)
+
+	body := `{"email":"test@example.com","password":"password123"}`
+	c, _ := newTestContext(http.MethodPost, "/api/auth/login", strings.NewReader(body), "")
+
+	err := NewAuthHandler(svc).Login(c)
+
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusTooManyRequests, httpErr.Code)
+	assert.Equal(t, errors.CodeTooManyAttempts, httpErr.Message.(errors.ErrorResponse).Code)
+}
+
+func TestAuthHandler_Login_NotVerified(t *testing.T) {
+	svc := new(MockAuthService)
+	svc.On("Login", mock.Anything, "new@example.com", "password123", mock.Anything).
+		Return("", "", nil, service.ErrAccountNotVerified)
+
+	body := `{"email":"new@example.com","password":"password123"}`
+	c, _ := newTestContext(http.MethodPost, "/api/auth/login", strings.NewReader(body), "")
+
+	err := NewAuthHandler(svc).Login(c)
+
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusForbidden, httpErr.Code)
+	assert.Equal(t, errors.CodeAccountNotVerified, httpErr.Message.(errors.ErrorResponse).Code)
+}
+
+func TestAuthHandler_VerifyEmail(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		serviceErr error
+		wantStatus int
+		wantCode   errors.Code
+	}{
+		{name: "verified", body: `{"token":"abc"}`, wantStatus: http.StatusOK},
+		{name: "invalid token", body: `{"token":"abc"}`, serviceErr: service.ErrInvalidVerificationToken, wantStatus: http.StatusBadRequest, wantCode: errors.CodeInvalidVerificationToken},
+		{name: "missing token", body: `{}`, wantStatus: http.StatusBadRequest, wantCode: errors.CodeValidationError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := new(MockAuthService)
+			svc.On("VerifyEmail", mock.Anything, "abc").Return(tt.serviceErr)
+
+			c, rec := newTestContext(http.MethodPost, "/api/auth/verify-email", strings.NewReader(tt.body), "")
+			err := NewAuthHandler(svc).VerifyEmail(c)
+
+			if tt.wantCode == "" {
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantStatus, rec.Code)
+				return
+			}
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, tt.wantStatus, httpErr.Code)
+			assert.Equal(t, tt.wantCode, httpErr.Message.(errors.ErrorResponse).Code)
+		})
+	}
+}
+
+func TestAuthHandler_ResendVerification(t *testing.T) {
+	tests := []struct {
+		name       string
+		serviceErr error
+		wantStatus int
+		wantCode   errors.Code
+	}{
+		{name: "sent or unknown email alike", wantStatus: http.StatusOK},
+		{name: "disabled", serviceErr: service.ErrEmailVerificationDisabled, wantStatus: http.StatusServiceUnavailable, wantCode: errors.CodeVerificationDisabled},
+		{name: "lookup failed", serviceErr: stderrors.New("db down"), wantStatus: http.StatusInternalServerError, wantCode: errors.CodeVerificationFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := new(MockAuthService)
+			svc.On("ResendVerification", mock.Anything, "alice@example.com").Return(tt.serviceErr)
+
+			c, rec := newTestContext(http.MethodPost, "/api/auth/resend-verification", strings.NewReader(`{"email":" Alice@Example.com"}`), "")
+			err := NewAuthHandler(svc).ResendVerification(c)
+
+			if tt.wantCode == "" {
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantStatus, rec.Code)
+				return
+			}
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, tt.wantStatus, httpErr.Code)
+			assert.Equal(t, tt.wantCode, httpErr.Message.(errors.ErrorResponse).Code)
+		})
+	}
+}
+
+func TestAuthHandler_ForgotPassword(t *testing.T) {
+	tests := []struct {
+		name       string
+		serviceErr error
+		wantStatus int
+		wantCode   errors.Code
+	}{
+		{name: "sent or unknown email alike", wantStatus: http.StatusOK},
+		{name: "disabled", serviceErr: service.ErrPasswordResetDisabled, wantStatus: http.StatusServiceUnavailable, wantCode: errors.CodePasswordResetDisabled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := new(MockAuthService)
+			svc.On("ForgotPassword", mock.Anything, "alice@example.com").Return(tt.serviceErr)
+
+			c, rec := newTestContext(http.MethodPost, "/api/auth/forgot-password", strings.NewReader(`{"email":" Alice@Example.com"}`), "")
+			err := NewAuthHandler(svc).ForgotPassword(c)
+
+			if tt.wantCode == "" {
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantStatus, rec.Code)
+				return
+			}
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, tt.wantStatus, httpErr.Code)
+			assert.Equal(t, tt.wantCode, httpErr.Message.(errors.ErrorResponse).Code)
+		})
+	}
+}
+
+func TestAuthHandler_ResetPassword(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		serviceErr error
+		wantStatus int
+		wantCode   errors.Code
+	}{
+		{name: "reset", body: `{"token":"abc","password":"new-password"}`, wantStatus: http.StatusOK},
+		{name: "invalid token", body: `{"token":"abc","password":"new-password"}`, serviceErr: service.ErrInvalidResetToken, wantStatus: http.StatusBadRequest, wantCode: errors.CodeInvalidResetToken},
+		{name: "short password", body: `{"token":"abc","password":"short"}`, wantStatus: http.StatusBadRequest, wantCode: errors.CodeValidationError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := new(MockAuthService)
+			svc.On("ResetPassword", mock.Anything, "abc", "new-password").Return(tt.serviceErr)
+
+			c, rec := newTestContext(http.MethodPost, "/api/auth/reset-password", strings.NewReader(tt.body), "")
+			err := NewAuthHandler(svc).ResetPassword(c)
+
+			if tt.wantCode == "" {
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantStatus, rec.Code)
+				return
+			}
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, tt.wantStatus, httpErr.Code)
+			assert.Equal(t, tt.wantCode, httpErr.Message.(errors.ErrorResponse).Code)
+		})
+	}
+}
+
+func TestAuthHandler_LogoutAll(t *testing.T) {
+	tests := []struct {
+		name       string
+		serviceErr error
+		wantStatus int
+		wantCode   errors.Code
+	}{
+		{name: "logged out", wantStatus: http.StatusOK},
+		{name: "deleted account", serviceErr: errors.ErrAccountNotFound, wantStatus: http.StatusNotFound, wantCode: errors.CodeAccountNotFound},
+		{name: "store failure", serviceErr: assert.AnError, wantStatus: http.StatusInternalServerError, wantCode: errors.CodeLogoutAllFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accountID := uuid.New()
+			svc := new(MockAuthService)
+			svc.On("LogoutAll", mock.Anything, accountID, "access-token").Return(tt.serviceErr)
+
+			c, rec := newTestContext(http.MethodPost, "/api/auth/logout-all", nil, accountID.String())
+			c.Request().Header.Set(echo.HeaderAuthorization, "Bearer access-token")
+			err := NewAuthHandler(svc).LogoutAll(c)
+
+			if tt.wantCode == "" {
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantStatus, rec.Code)
+				svc.AssertExpectations(t)
+				return
+			}
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, tt.wantStatus, httpErr.Code)
+			assert.Equal(t, tt.wantCode, httpErr.Message.(errors.ErrorResponse).Code)
+		})
+	}
+}
+
+func TestAuthHandler_ChangePassword(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		serviceErr error
+		wantStatus int
+		wantCode   errors.Code
+	}{
+		{name: "changed", body: `{"current_password":"old-password","new_password":"new-password"}`, wantStatus: http.StatusOK},
+		{name: "wrong current password", body: `{"current_password":"old-password","new_password":"new-password"}`, serviceErr: service.ErrInvalidCredentials, wantStatus: http.StatusBadRequest, wantCode: errors.CodeInvalidCredentials},
+		{name: "deleted account", body: `{"current_password":"old-password","new_password":"new-password"}`, serviceErr: errors.ErrAccountNotFound, wantStatus: http.StatusNotFound, wantCode: errors.CodeAccountNotFound},
+		{name: "short new password", body: `{"current_password":"old-password","new_password":"short"}`, wantStatus: http.StatusBadRequest, wantCode: errors.CodeValidationError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accountID := uuid.New()
+			svc := new(MockAuthService)
+			svc.On("ChangePassword", mock.Anything, accountID, "old-password", "new-password").Return(tt.serviceErr)
+
+			c, rec := newTestContext(http.MethodPost, "/api/auth/change-password", strings.NewReader(tt.body), accountID.String())
+			err := NewAuthHandler(svc).ChangePassword(c)
+
+			if tt.wantCode == "" {
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantStatus, rec.Code)
+				return
+			}
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, tt.wantStatus, httpErr.Code)
+			assert.Equal(t, tt.wantCode, httpErr.Message.(errors.ErrorResponse).Code)
+		})
+	}
+}
+
+func TestAuthHandler_Refresh_ReturnsRotatedRefreshToken(t *testing.T) {
+	svc := new(MockAuthService)
+	svc.On("RefreshToken", mock.Anything, "old-refresh").Return("new-access", "new-refresh", nil)
+
+	c, rec := newTestContext(http.MethodPost, "/api/auth/refresh", strings.NewReader(`{"refresh_token":"old-refresh"}`), "")
+
+	require.NoError(t, NewAuthHandler(svc).Refresh(c))
+
+	var resp AuthResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "new-access", resp.AccessToken)
+	assert.Equal(t, "new-refresh", resp.RefreshToken)
+}
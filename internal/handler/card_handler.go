@@ -0,0 +1,906 @@
+package handler
+
+import (
+	stderrors "errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/shopspring/decimal"
+
+	"paytabs/internal/errors"
+	"paytabs/internal/model"
+	"paytabs/internal/repository"
+	"paytabs/internal/service"
+)
+
+// defaultHistoryWindow is the balance history range used when "from" is omitted.
+const defaultHistoryWindow = 30 * 24 * time.Hour
+
+// CardHandler handles card endpoints.
+type CardHandler struct {
+	cardService service.CardService
+}
+
+// NewCardHandler creates a new card handler.
+func NewCardHandler(cardService service.CardService) *CardHandler {
+	return &CardHandler{cardService: cardService}
+}
+
+// BalanceHistoryPoint represents a single card balance change.
+type BalanceHistoryPoint struct {
+	Timestamp    time.Time          `json:"timestamp"`
+	Delta        string             `json:"delta"`
+	BalanceAfter string             `json:"balance_after"`
+	Reason       model.LedgerReason `json:"reason"`
+	ReferenceID  string             `json:"reference_id"`
+}
+
+// BalanceHistoryResponse represents a page of a card's balance history.
+type BalanceHistoryResponse struct {
+	CardID string                `json:"card_id"`
+	From   time.Time             `json:"from"`
+	To     time.Time             `json:"to"`
+	Points []BalanceHistoryPoint `json:"points"`
+	Limit  int                   `json:"limit"`
+	Offset int                   `json:"offset"`
+}
+
+// GetBalanceHistory godoc
+// @Summary Get a card's balance history
+// @Description Returns balance changes from the card ledger within [from, to], oldest first.
+// @Tags cards
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Card ID"
+// @Param from query string false "Range start, RFC3339 (default 30 days before to)"
+// @Param to query string false "Range end, RFC3339 (default now)"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param offset query int false "Number of points to skip"
+// @Success 200 {object} BalanceHistoryResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /cards/{id}/balance-history [get]
+func (h *CardHandler) GetBalanceHistory(c echo.Context) error {
+	cardID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	from, to, err := parseTimeRange(c, defaultHistoryWindow)
+	if err != nil {
+		return err
+	}
+
+	limit, offset, err := parsePagination(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	card, err := h.cardService.GetCard(ctx, cardID)
+	if err != nil {
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+
+	if err := requireAccountOwner(c, card.AccountID); err != nil {
+		return err
+	}
+
+	entries, err := h.cardService.GetBalanceHistory(ctx, cardID, from, to, limit, offset)
+	if err != nil {
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+
+	points := make([]BalanceHistoryPoint, 0, len(entries))
+	for _, entry := range entries {
+		points = append(points, BalanceHistoryPoint{
+			Timestamp:    entry.CreatedAt,
+			Delta:        entry.Delta.StringFixed(2),
+			BalanceAfter: entry.BalanceAfter.StringFixed(2),
+			Reason:       entry.Reason,
+			ReferenceID:  entry.ReferenceID.String(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, BalanceHistoryResponse{
+		CardID: cardID.String(),
+		From:   from,
+		To:     to,
+		Points: points,
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+// UpdateCardRequest represents a partial card update. Omitted fields are left unchanged;
+// pointers tell an omitted field apart from false or an empty string.
+type UpdateCardRequest struct {
+	Active     *bool   `json:"active"`
+	CardExpiry *string `json:"card_expiry"`
+}
+
+// CardResponse represents a card.
+type CardResponse struct {
+	ID         string `json:"id"`
+	AccountID  string `json:"account_id"`
+	CardNumber string `json:"card_number"` // Masked
+	CardExpiry string `json:"card_expiry"`
+	Currency   string `json:"currency,omitempty"`
+	Balance    string `json:"balance"`
+	Active     bool   `json:"active"`
+}
+
+// UpdateCard godoc
+// @Summary Update a card
+// @Description Changes only the fields present in the body: active (freeze or unfreeze) and card_expiry (MM/YY, validated like on creation).
+// @Tags cards
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Card ID"
+// @Param request body UpdateCardRequest true "Fields to change"
+// @Success 200 {object} CardResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /cards/{id} [patch]
+func (h *CardHandler) UpdateCard(c echo.Context) error {
+	cardID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var req UpdateCardRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: "invalid request body",
+			Code:  errors.CodeInvalidRequest,
+		})
+	}
+	if req.Active == nil && req.CardExpiry == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: "provide at least one of active or card_expiry",
+			Code:  errors.CodeValidationError,
+		})
+	}
+
+	ctx := c.Request().Context()
+	card, err := h.cardService.GetCard(ctx, cardID)
+	if err != nil {
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+
+	if err := requireAccountOwner(c, card.AccountID); err != nil {
+		return err
+	}
+
+	card, err = h.cardService.UpdateCard(ctx, cardID, service.CardUpdate{
+		Active:     req.Active,
+		CardExpiry: req.CardExpiry,
+	})
+	if err != nil {
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+
+	return c.JSON(http.StatusOK, newCardResponse(card))
+}
+
+func newCardResponse(card *model.Card) CardResponse {
+	return CardResponse{
+		ID:         card.ID.String(),
+		AccountID:  card.AccountID.String(),
+		CardNumber: card.CardNumber,
+		CardExpiry: card.CardExpiry,
+		Currency:   card.Currency,
+		Balance:    card.Balance.StringFixed(2),
+		Active:     card.Active,
+	}
+}
+
+// WithdrawRequest represents a card withdrawal request.
+type WithdrawRequest struct {
+	Amount string `json:"amount" validate:"required"`
+}
+
+// WithdrawResponse represents the result of moving funds from a card to its account.
+type WithdrawResponse struct {
+	WithdrawalID   string `json:"withdrawal_id"`
+	CardID         string `json:"card_id"`
+	AccountID      string `json:"account_id"`
+	Amount         string `json:"amount"`
+	CardBalance    string `json:"card_balance"`
+	AccountBalance string `json:"account_balance"`
+}
+
+// Withdraw godoc
+// @Summary Move funds from a card to the owning account
+// @Description Debits the card and credits the owning account's balance in one transaction, recording a withdrawal ledger entry on the card.
+// @Tags cards
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Card ID"
+// @Param Idempotency-Key header string false "Replays the stored response for a repeated key"
+// @Param request body WithdrawRequest true "Amount to withdraw"
+// @Success 200 {object} WithdrawResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 409 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /cards/{id}/withdraw [post]
+func (h *CardHandler) Withdraw(c echo.Context) error {
+	cardID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var req WithdrawRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: "invalid request body",
+			Code:  errors.CodeInvalidRequest,
+		})
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: err.Error(),
+			Code:  errors.CodeValidationError,
+		})
+	}
+
+	amount, err := decimal.NewFromString(req.Amount)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: "invalid amount",
+			Code:  errors.CodeInvalidAmount,
+		})
+	}
+
+	ctx := c.Request().Context()
+	card, err := h.cardService.GetCard(ctx, cardID)
+	if err != nil {
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+
+	if err := requireAccountOwner(c, card.AccountID); err != nil {
+		return err
+	}
+
+	withdrawal, err := h.cardService.Withdraw(ctx, cardID, amount)
+	if err != nil {
+		if stderrors.Is(err, service.ErrCardInactive) {
+			return echo.NewHTTPError(http.StatusConflict, errors.ErrorResponse{
+				Error: err.Error(),
+				Code:  errors.CodeCardInactive,
+			})
+		}
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+
+	return c.JSON(http.StatusOK, WithdrawResponse{
+		WithdrawalID:   withdrawal.ID.String(),
+		CardID:         withdrawal.CardID.String(),
+		AccountID:      withdrawal.AccountID.String(),
+		Amount:         withdrawal.Amount.StringFixed(2),
+		CardBalance:    withdrawal.CardBalance.StringFixed(2),
+		AccountBalance: withdrawal.AccountBalance.StringFixed(2),
+	})
+}
+
+// CardDefinition describes one card in a bulk create request.
+type CardDefinition struct {
+	CardNumber string `json:"card_number"`
+	CardExpiry string `json:"card_expiry"`
+	CVV        string `json:"cvv"`
+	Currency   string `json:"currency,omitempty"` // Defaults to the account's currency
+}
+
+// CreateCardRequest represents a card creation request.
+type CreateCardRequest struct {
+	CardNumber string `json:"card_number" validate:"required"`
+	CardExpiry string `json:"card_expiry" validate:"required"` // MM/YY
+	CVV        string `json:"cvv" validate:"required"`
+	Currency   string `json:"currency,omitempty"` // Defaults to the account's currency
+}
+
+// CreateCard godoc
+// @Summary Create a card for the authenticated account
+// @Description Validates the card number (Luhn), expiry (MM/YY, not past), and CVV. Only the masked number is stored; the CVV is never persisted or returned.
+// @Tags cards
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateCardRequest true "Card to create"
+// @Success 201 {object} CardResponse
+// @Header 201 {string} Location "/api/cards/{id}"
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 409 {object} errors.ErrorResponse
+// @Failure 422 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /cards [post]
+func (h *CardHandler) CreateCard(c echo.Context) error {
+	accountID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	var req CreateCardRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: "invalid request body",
+			Code:  errors.CodeInvalidRequest,
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: err.Error(),
+			Code:  errors.CodeValidationError,
+		})
+	}
+
+	card, err := h.cardService.CreateCard(c.Request().Context(), accountID, service.NewCard{
+		CardNumber: req.CardNumber,
+		CardExpiry: req.CardExpiry,
+		CVV:        req.CVV,
+		Currency:   req.Currency,
+	})
+	if err != nil {
+		if stderrors.Is(err, service.ErrUnsupportedCurrency) {
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, errors.ErrorResponse{
+				Error: err.Error(),
+				Code:  errors.CodeUnsupportedCurrency,
+			})
+		}
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+
+	setLocation(c, "/api/cards/"+card.ID.String())
+	return c.JSON(http.StatusCreated, newCardResponse(card))
+}
+
+// BulkCreateCardsRequest represents a bulk card creation request.
+type BulkCreateCardsRequest struct {
+	Cards []CardDefinition `json:"cards" validate:"required,min=1,max=50"`
+}
+
+// Bulk card result statuses.
+const (
+	BulkCardCreated    = "created"
+	BulkCardInvalid    = "invalid"
+	BulkCardNotCreated = "not_created"
+)
+
+// BulkCardResult reports the outcome for one card in a bulk request, by position.
+type BulkCardResult struct {
+	Index      int    `json:"index"`
+	Status     string `json:"status"`
+	CardID     string `json:"card_id,omitempty"`
+	CardNumber string `json:"card_number,omitempty"` // Masked
+	CardExpiry string `json:"card_expiry,omitempty"`
+	Currency   string `json:"currency,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// BulkCreateCardsResponse represents the result of a bulk card creation.
+type BulkCreateCardsResponse struct {
+	Created int              `json:"created"`
+	Results []BulkCardResult `json:"results"`
+}
+
+// CreateCardsBulk godoc
+// @Summary Create several cards for the authenticated account
+// @Description Validates every card and creates them all in one transaction. If any card is
+// @Description invalid nothing is created and the per-item results explain which ones failed.
+// @Description Cards without a currency inherit the account's currency (or the server default).
+// @Tags cards
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body BulkCreateCardsRequest true "Cards to create"
+// @Success 201 {object} BulkCreateCardsResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 409 {object} errors.ErrorResponse
+// @Failure 422 {object} BulkCreateCardsResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /cards/bulk [post]
+func (h *CardHandler) CreateCardsBulk(c echo.Context) error {
+	accountID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	var req BulkCreateCardsRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: "invalid request body",
+			Code:  errors.CodeInvalidRequest,
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: err.Error(),
+			Code:  errors.CodeValidationError,
+		})
+	}
+
+	cards := make([]service.NewCard, 0, len(req.Cards))
+	for _, def := range req.Cards {
+		cards = append(cards, service.NewCard{
+			CardNumber: def.CardNumber,
+			CardExpiry: def.CardExpiry,
+			CVV:        def.CVV,
+			Currency:   def.Currency,
+		})
+	}
+
+	created, err := h.cardService.CreateCards(c.Request().Context(), accountID, cards)
+	if err != nil {
+		var invalid *service.BulkCardValidationError
+		if stderrors.As(err, &invalid) {
+			results := make([]BulkCardResult, len(req.Cards))
+			for i := range results {
+				results[i] = BulkCardResult{Index: i, Status: BulkCardNotCreated}
+			}
+			for _, item := range invalid.Items {
+				results[item.Index].Status = BulkCardInvalid
+				results[item.Index].Error = item.Err.Error()
+			}
+			return c.JSON(http.StatusUnprocessableEntity, BulkCreateCardsResponse{Created: 0, Results: results})
+		}
+		if stderrors.Is(err, service.ErrUnsupportedCurrency) {
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, errors.ErrorResponse{
+				Error: "the account's currency is not supported; name a currency for each card",
+				Code:  errors.CodeUnsupportedCurrency,
+			})
+		}
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+
+	results := make([]BulkCardResult, 0, len(created))
+	for i, card := range created {
+		results = append(results, BulkCardResult{
+			Index:      i,
+			Status:     BulkCardCreated,
+			CardID:     card.ID.String(),
+			CardNumber: card.CardNumber,
+			CardExpiry: card.CardExpiry,
+			Currency:   card.Currency,
+		})
+	}
+
+	return c.JSON(http.StatusCreated, BulkCreateCardsResponse{Created: len(created), Results: results})
+}
+
+// CardAvailableBalanceResponse is a card's balance split into held and spendable funds.
+type CardAvailableBalanceResponse struct {
+	CardID    string `json:"card_id"`
+	Balance   string `json:"balance"`
+	Held      string `json:"held"`
+	Available string `json:"available"`
+}
+
+// GetAvailableBalance godoc
+// @Summary Get a card's available balance
+// @Description Returns the card's balance, the total of its active holds, and the difference that payments and withdrawals may use.
+// @Tags cards
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Card ID"
+// @Success 200 {object} CardAvailableBalanceResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /cards/{id}/available-balance [get]
+func (h *CardHandler) GetAvailableBalance(c echo.Context) error {
+	cardID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	balance, err := h.cardService.GetAvailableBalance(c.Request().Context(), cardID)
+	if err != nil {
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+
+	if err := requireAccountOwner(c, balance.Card.AccountID); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, CardAvailableBalanceResponse{
+		CardID:    cardID.String(),
+		Balance:   balance.Balance.StringFixed(2),
+		Held:      balance.Held.StringFixed(2),
+		Available: balance.Available.StringFixed(2),
+	})
+}
+
+// PlaceCardHoldRequest reserves funds on a card.
+type PlaceCardHoldRequest struct {
+	Amount    string `json:"amount" validate:"required"`
+	Duration  string `json:"duration,omitempty"` // Go duration, e.g. "72h"; defaults to CARD_HOLD_MAX_DURATION
+	Reference string `json:"reference,omitempty" validate:"max=64"`
+}
+
+// CardHoldResponse represents a card hold.
+type CardHoldResponse struct {
+	ID                string               `json:"id"`
+	CardID            string               `json:"card_id"`
+	PlacedByAccountID string               `json:"placed_by_account_id"`
+	Amount            string               `json:"amount"`
+	Reference         string               `json:"reference,omitempty"`
+	Status            model.CardHoldStatus `json:"status"`
+	ExpiresAt         time.Time            `json:"expires_at"`
+	ReleasedAt        *time.Time           `json:"released_at,omitempty"`
+	CreatedAt         time.Time            `json:"created_at"`
+}
+
+func newCardHoldResponse(hold *model.CardHold) CardHoldResponse {
+	return CardHoldResponse{
+		ID:                hold.ID.String(),
+		CardID:            hold.CardID.String(),
+		PlacedByAccountID: hold.PlacedByAccountID.String(),
+		Amount:            hold.Amount.StringFixed(2),
+		Reference:         hold.Reference,
+		Status:            hold.StatusAt(time.Now()),
+		ExpiresAt:         hold.ExpiresAt,
+		ReleasedAt:        hold.ReleasedAt,
+		CreatedAt:         hold.CreatedAt,
+	}
+}
+
+// PlaceHold godoc
+// @Summary Hold funds on a card
+// @Description Reserves an amount of the card's balance without moving money, e.g. to pre-authorise a rental.
+// @Description Held funds cannot be spent by payments or withdrawals until the hold is released or expires.
+// @Description The card's owner may hold funds on it, as may an active merchant the card has paid before.
+// @Tags cards
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Card ID"
+// @Param Idempotency-Key header string false "Replays the stored response for a repeated key"
+// @Param request body PlaceCardHoldRequest true "Amount, duration and reference"
+// @Success 201 {object} CardHoldResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 409 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /cards/{id}/holds [post]
+func (h *CardHandler) PlaceHold(c echo.Context) error {
+	callerID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	cardID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var req PlaceCardHoldRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: "invalid request body",
+			Code:  errors.CodeInvalidRequest,
+		})
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: err.Error(),
+			Code:  errors.CodeValidationError,
+		})
+	}
+
+	amount, err := decimal.NewFromString(req.Amount)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: "invalid amount",
+			Code:  errors.CodeInvalidAmount,
+		})
+	}
+
+	var duration time.Duration
+	if req.Duration != "" {
+		duration, err = time.ParseDuration(req.Duration)
+		if err != nil || duration <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+				Error: "duration must be a positive Go duration, e.g. 72h",
+				Code:  errors.CodeValidationError,
+			})
+		}
+	}
+
+	hold, err := h.cardService.PlaceHold(c.Request().Context(), cardID, callerID, service.NewCardHold{
+		Amount:    amount,
+		Duration:  duration,
+		Reference: req.Reference,
+	})
+	if err != nil {
+		return cardHoldError(err)
+	}
+
+	setLocation(c, "/api/cards/"+cardID.String()+"/holds/"+hold.ID.String())
+	return c.JSON(http.StatusCreated, newCardHoldResponse(hold))
+}
+
+// GetHold godoc
+// @Summary Get a card hold
+// @Description Visible to the card's owner and to the account that placed the hold.
+// @Tags cards
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Card ID"
+// @Param hold_id path string true "Hold ID"
+// @Success 200 {object} CardHoldResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /cards/{id}/holds/{hold_id} [get]
+func (h *CardHandler) GetHold(c echo.Context) error {
+	callerID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	cardID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+	holdID, err := parseUUIDParam(c, "hold_id")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	hold, err := h.cardService.GetHold(ctx, cardID, holdID)
+	if err != nil {
+		return cardHoldError(err)
+	}
+
+	if hold.PlacedByAccountID != callerID {
+		card, err := h.cardService.GetCard(ctx, cardID)
+		if err != nil {
+			httpErr := errors.MapErrorToHTTP(err)
+			return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+		}
+		if err := requireAccountOwner(c, card.AccountID); err != nil {
+			return err
+		}
+	}
+
+	return c.JSON(http.StatusOK, newCardHoldResponse(hold))
+}
+
+// ReleaseHold godoc
+// @Summary Release a card hold
+// @Description Returns the held funds to the card's available balance. Only the account that placed the hold may release it.
+// @Tags cards
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Card ID"
+// @Param hold_id path string true "Hold ID"
+// @Success 200 {object} CardHoldResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 409 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /cards/{id}/holds/{hold_id}/release [post]
+func (h *CardHandler) ReleaseHold(c echo.Context) error {
+	cardID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+	holdID, err := parseUUIDParam(c, "hold_id")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	hold, err := h.cardService.GetHold(ctx, cardID, holdID)
+	if err != nil {
+		return cardHoldError(err)
+	}
+	if err := requireAccountOwner(c, hold.PlacedByAccountID); err != nil {
+		return err
+	}
+
+	hold, err = h.cardService.ReleaseHold(ctx, cardID, holdID)
+	if err != nil {
+		return cardHoldError(err)
+	}
+	return c.JSON(http.StatusOK, newCardHoldResponse(hold))
+}
+
+// cardHoldError maps the errors of the card hold endpoints to responses.
+func cardHoldError(err error) error {
+	switch {
+	case stderrors.Is(err, service.ErrCardHoldNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, errors.ErrorResponse{
+			Error: err.Error(),
+			Code:  errors.CodeCardHoldNotFound,
+		})
+	case stderrors.Is(err, service.ErrCardHoldNotActive):
+		return echo.NewHTTPError(http.StatusConflict, errors.ErrorResponse{
+			Error: err.Error(),
+			Code:  errors.CodeCardHoldNotActive,
+		})
+	case stderrors.Is(err, service.ErrCardHoldNotCustomer):
+		return echo.NewHTTPError(http.StatusForbidden, errors.ErrorResponse{
+			Error: err.Error(),
+			Code:  errors.CodeForbidden,
+		})
+	case stderrors.Is(err, service.ErrCardHoldTooLong):
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: err.Error(),
+			Code:  errors.CodeValidationError,
+		})
+	case stderrors.Is(err, service.ErrCardInactive):
+		return echo.NewHTTPError(http.StatusConflict, errors.ErrorResponse{
+			Error: err.Error(),
+			Code:  errors.CodeCardInactive,
+		})
+	}
+	httpErr := errors.MapErrorToHTTP(err)
+	return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+}
+
+// parseTimeRange reads RFC3339 "from" and "to" query params. "to" defaults to now and
+// "from" defaults to window before "to".
+func parseTimeRange(c echo.Context, window time.Duration) (from, to time.Time, err error) {
+	invalid := func(msg string) error {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: msg,
+			Code:  errors.CodeInvalidDateRange,
+		})
+	}
+
+	to = time.Now().UTC()
+	if v := c.QueryParam("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			return time.Time{}, time.Time{}, invalid("to must be an RFC3339 timestamp")
+		}
+	}
+
+	from = to.Add(-window)
+	if v := c.QueryParam("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			return time.Time{}, time.Time{}, invalid("from must be an RFC3339 timestamp")
+		}
+	}
+
+	if from.After(to) {
+		return time.Time{}, time.Time{}, invalid("from must not be after to")
+	}
+
+	return from, to, nil
+}
+
+// AccountCardsResponse lists an account's cards.
+type AccountCardsResponse struct {
+	Cards []CardResponse `json:"cards"`
+}
+
+// ListMyCards godoc
+// @Summary List the authenticated account's cards
+// @Description Active and inactive cards with their balances. Card numbers are masked; CVVs are never returned.
+// @Tags cards
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} AccountCardsResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /cards [get]
+func (h *CardHandler) ListMyCards(c echo.Context) error {
+	accountID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	cards, err := h.cardService.ListAccountCards(c.Request().Context(), accountID)
+	if err != nil {
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+
+	items := make([]CardResponse, 0, len(cards))
+	for i := range cards {
+		items = append(items, newCardResponse(&cards[i]))
+	}
+	return c.JSON(http.StatusOK, AccountCardsResponse{Cards: items})
+}
+
+// CardListResponse is a page of cards across accounts.
+type CardListResponse struct {
+	Cards  []CardResponse `json:"cards"`
+	Total  int64          `json:"total"`
+	Limit  int            `json:"limit"`
+	Offset int            `json:"offset"`
+}
+
+// AdminListCards godoc
+// @Summary List cards across all accounts
+// @Description For fraud investigations. Newest first, with masked card numbers. Every call is logged with its filters and the caller's IP.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Token header string true "Operator token (ADMIN_TOKEN)"
+// @Param account_id query string false "Only cards of this account"
+// @Param active query bool false "Only active (true) or inactive (false) cards"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param offset query int false "Number of cards to skip"
+// @Success 200 {object} CardListResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /admin/cards [get]
+func (h *CardHandler) AdminListCards(c echo.Context) error {
+	var filter repository.CardFilter
+	if v := c.QueryParam("account_id"); v != "" {
+		accountID, err := parseUUIDBody(v, "account_id")
+		if err != nil {
+			return err
+		}
+		filter.AccountID = &accountID
+	}
+	if v := c.QueryParam("active"); v != "" {
+		active, err := strconv.ParseBool(v)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+				Error: "active must be true or false",
+				Code:  errors.CodeValidationError,
+			})
+		}
+		filter.Active = &active
+	}
+
+	limit, offset, err := parsePagination(c)
+	if err != nil {
+		return err
+	}
+
+	cards, total, err := h.cardService.ListCards(c.Request().Context(), filter, limit, offset)
+	if err != nil {
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+	log.Printf("admin: cards listed (account_id=%q active=%q limit=%d offset=%d) by %s",
+		c.QueryParam("account_id"), c.QueryParam("active"), limit, offset, c.RealIP())
+
+	items := make([]CardResponse, 0, len(cards))
+	for i := range cards {
+		items = append(items, newCardResponse(&cards[i]))
+	}
+	return c.JSON(http.StatusOK, CardListResponse{Cards: items, Total: total, Limit: limit, Offset: offset})
+}
@@ -0,0 +1,643 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"paytabs/internal/errors"
+	"paytabs/internal/model"
+	"paytabs/internal/repository"
+	"paytabs/internal/service"
+)
+
+func TestCardHandler_GetBalanceHistory(t *testing.T) {
+	ownerID := uuid.New()
+	cardID := uuid.New()
+	paymentID := uuid.New()
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	svc := new(MockCardService)
+	svc.On("GetCard", mock.Anything, cardID).Return(&model.Card{ID: cardID, AccountID: ownerID}, nil)
+	svc.On("GetBalanceHistory", mock.Anything, cardID, from, to, defaultPageLimit, 0).Return([]model.LedgerEntry{
+		{
+			CardID:       cardID,
+			Delta:        decimal.RequireFromString("-12.5"),
+			BalanceAfter: decimal.RequireFromString("87.5"),
+			Reason:       model.LedgerReasonPayment,
+			ReferenceID:  paymentID,
+			CreatedAt:    from.Add(time.Hour),
+		},
+	}, nil)
+
+	c, rec := newTestContext(http.MethodGet, "/api/cards/"+cardID.String()+"/balance-history?from=2025-01-01T00:00:00Z&to=2025-02-01T00:00:00Z", nil, ownerID.String())
+	c.SetParamNames("id")
+	c.SetParamValues(cardID.String())
+
+	require.NoError(t, NewCardHandler(svc).GetBalanceHistory(c))
+
+	var resp BalanceHistoryResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	require.Len(t, resp.Points, 1)
+	assert.Equal(t, "-12.50", resp.Points[0].Delta)
+	assert.Equal(t, "87.50", resp.Points[0].BalanceAfter)
+	assert.Equal(t, model.LedgerReasonPayment, resp.Points[0].Reason)
+	assert.Equal(t, paymentID.String(), resp.Points[0].ReferenceID)
+	svc.AssertExpectations(t)
+}
+
+func TestCardHandler_GetBalanceHistory_Rejections(t *testing.T) {
+	ownerID := uuid.New()
+	cardID := uuid.New()
+
+	tests := []struct {
+		name           string
+		query          string
+		caller         uuid.UUID
+		expectedStatus int
+		expectedCode   errors.Code
+	}{
+		{
+			name:           "other account's card",
+			caller:         uuid.New(),
+			expectedStatus: http.StatusForbidden,
+			expectedCode:   errors.CodeForbidden,
+		},
+		{
+			name:           "from after to",
+			query:          "?from=2025-03-01T00:00:00Z&to=2025-02-01T00:00:00Z",
+			caller:         ownerID,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   errors.CodeInvalidDateRange,
+		},
+		{
+			name:           "malformed timestamp",
+			query:          "?from=yesterday",
+			caller:         ownerID,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   errors.CodeInvalidDateRange,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := new(MockCardService)
+			svc.On("GetCard", mock.Anything, cardID).Return(&model.Card{ID: cardID, AccountID: ownerID}, nil).Maybe()
+
+			c, _ := newTestContext(http.MethodGet, "/api/cards/"+cardID.String()+"/balance-history"+tt.query, nil, tt.caller.String())
+			c.SetParamNames("id")
+			c.SetParamValues(cardID.String())
+
+			err := NewCardHandler(svc).GetBalanceHistory(c)
+
+			httpErr, ok := err.(*echo.HTTPError)
+			require.True(t, ok)
+			assert.Equal(t, tt.expectedStatus, httpErr.Code)
+			assert.Equal(t, tt.expectedCode, httpErr.Message.(errors.ErrorResponse).Code)
+			svc.AssertNotCalled(t, "GetBalanceHistory", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestCardHandler_CreateCard(t *testing.T) {
+	ownerID := uuid.New()
+
+	t.Run("created", func(t *testing.T) {
+		cardID := uuid.New()
+		svc := new(MockCardService)
+		svc.On("CreateCard", mock.Anything, ownerID, service.NewCard{CardNumber: "4111111111111111", CardExpiry: "06/30", CVV: "123"}).
+			Return(&model.Card{ID: cardID, AccountID: ownerID, CardNumber: "****1111", CardExpiry: "06/30", Currency: "USD", Active: true}, nil)
+
+		body := `{"card_number":"4111111111111111","card_expiry":"06/30","cvv":"123"}`
+		c, rec := newTestContext(http.MethodPost, "/api/cards", strings.NewReader(body), ownerID.String())
+		require.NoError(t, NewCardHandler(svc).CreateCard(c))
+
+		var resp CardResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, "/api/cards/"+cardID.String(), rec.Header().Get(echo.HeaderLocation))
+		assert.Equal(t, cardID.String(), resp.ID)
+		assert.Equal(t, ownerID.String(), resp.AccountID)
+		assert.Equal(t, "****1111", resp.CardNumber)
+		assert.Equal(t, "0.00", resp.Balance)
+		assert.NotContains(t, rec.Body.String(), "cvv")
+		assert.NotContains(t, rec.Body.String(), "4111111111111111")
+	})
+
+	t.Run("invalid card", func(t *testing.T) {
+		svc := new(MockCardService)
+		svc.On("CreateCard", mock.Anything, ownerID, mock.Anything).Return(nil, errors.ErrInvalidCard)
+
+		body := `{"card_number":"4111111111111112","card_expiry":"06/30","cvv":"123"}`
+		c, _ := newTestContext(http.MethodPost, "/api/cards", strings.NewReader(body), ownerID.String())
+		err := NewCardHandler(svc).CreateCard(c)
+
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+		assert.Equal(t, errors.CodeInvalidCard, httpErr.Message.(errors.ErrorResponse).Code)
+	})
+
+	t.Run("missing fields", func(t *testing.T) {
+		svc := new(MockCardService)
+
+		c, _ := newTestContext(http.MethodPost, "/api/cards", strings.NewReader(`{"card_number":"4111111111111111"}`), ownerID.String())
+		err := NewCardHandler(svc).CreateCard(c)
+
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+		assert.Equal(t, errors.CodeValidationError, httpErr.Message.(errors.ErrorResponse).Code)
+		svc.AssertNotCalled(t, "CreateCard", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestCardHandler_CreateCardsBulk(t *testing.T) {
+	ownerID := uuid.New()
+	body := `{"cards":[{"card_number":"4111111111111111","card_expiry":"06/30","cvv":"123"},{"card_number":"5555555555554444","card_expiry":"06/30","cvv":"123"}]}`
+
+	t.Run("created", func(t *testing.T) {
+		cardID := uuid.New()
+		svc := new(MockCardService)
+		svc.On("CreateCards", mock.Anything, ownerID, mock.Anything).Return([]model.Card{
+			{ID: cardID, AccountID: ownerID, CardNumber: "****1111", CardExpiry: "06/30"},
+			{ID: uuid.New(), AccountID: ownerID, CardNumber: "****4444", CardExpiry: "06/30"},
+		}, nil)
+
+		c, rec := newTestContext(http.MethodPost, "/api/cards/bulk", strings.NewReader(body), ownerID.String())
+		require.NoError(t, NewCardHandler(svc).CreateCardsBulk(c))
+
+		var resp BulkCreateCardsResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, 2, resp.Created)
+		assert.Equal(t, cardID.String(), resp.Results[0].CardID)
+		assert.Equal(t, "****1111", resp.Results[0].CardNumber)
+		assert.NotContains(t, rec.Body.String(), "cvv")
+	})
+
+	t.Run("per-item validation failures", func(t *testing.T) {
+		svc := new(MockCardService)
+		svc.On("CreateCards", mock.Anything, ownerID, mock.Anything).Return(nil, &service.BulkCardValidationError{
+			Items: []service.CardItemError{{Index: 1, Err: errors.ErrInvalidCard}},
+		})
+
+		c, rec := newTestContext(http.MethodPost, "/api/cards/bulk", strings.NewReader(body), ownerID.String())
+		require.NoError(t, NewCardHandler(svc).CreateCardsBulk(c))
+
+		var resp BulkCreateCardsResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Equal(t, 0, resp.Created)
+		require.Len(t, resp.Results, 2)
+		assert.Equal(t, BulkCardNotCreated, resp.Results[0].Status)
+		assert.Equal(t, BulkCardInvalid, resp.Results[1].Status)
+		assert.Equal(t, errors.ErrInvalidCard.Error(), resp.Results[1].Error)
+	})
+
+	t.Run("card limit", func(t *testing.T) {
+		svc := new(MockCardService)
+		svc.On("CreateCards", mock.Anything, ownerID, mock.Anything).Return(nil, errors.ErrCardLimitExceeded)
+
+		c, _ := newTestContext(http.MethodPost, "/api/cards/bulk", strings.NewReader(body), ownerID.String())
+		err := NewCardHandler(svc).CreateCardsBulk(c)
+
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, http.StatusConflict, httpErr.Code)
+	})
+
+	t.Run("inherited currency not supported", func(t *testing.T) {
+		svc := new(MockCardService)
+		svc.On("CreateCards", mock.Anything, ownerID, mock.Anything).Return(nil, service.ErrUnsupportedCurrency)
+
+		c, _ := newTestContext(http.MethodPost, "/api/cards/bulk", strings.NewReader(body), ownerID.String())
+		err := NewCardHandler(svc).CreateCardsBulk(c)
+
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, http.StatusUnprocessableEntity, httpErr.Code)
+		assert.Equal(t, errors.CodeUnsupportedCurrency, httpErr.Message.(errors.ErrorResponse).Code)
+	})
+
+	t.Run("empty batch", func(t *testing.T) {
+		c, _ := newTestContext(http.MethodPost, "/api/cards/bulk", strings.NewReader(`{"cards":[]}`), ownerID.String())
+		err := NewCardHandler(new(MockCardService)).CreateCardsBulk(c)
+
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+	})
+}
+
+func TestCardHandler_UpdateCard(t *testing.T) {
+	ownerID := uuid.New()
+	cardID := uuid.New()
+	falseVal := false
+	expiry := "06/30"
+
+	tests := []struct {
+		name   string
+		body   string
+		update service.CardUpdate
+	}{
+		{name: "only active", body: `{"active":false}`, update: service.CardUpdate{Active: &falseVal}},
+		{name: "only expiry", body: `{"card_expiry":"06/30"}`, update: service.CardUpdate{CardExpiry: &expiry}},
+		{name: "both", body: `{"active":false,"card_expiry":"06/30"}`, update: service.CardUpdate{Active: &falseVal, CardExpiry: &expiry}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := new(MockCardService)
+			svc.On("GetCard", mock.Anything, cardID).Return(&model.Card{ID: cardID, AccountID: ownerID}, nil)
+			svc.On("UpdateCard", mock.Anything, cardID, tt.update).Return(&model.Card{
+				ID: cardID, AccountID: ownerID, CardNumber: "****1111", CardExpiry: "06/30", Balance: decimal.NewFromInt(5),
+			}, nil)
+
+			c, rec := newTestContext(http.MethodPatch, "/api/cards/"+cardID.String(), strings.NewReader(tt.body), ownerID.String())
+			c.SetParamNames("id")
+			c.SetParamValues(cardID.String())
+			require.NoError(t, NewCardHandler(svc).UpdateCard(c))
+
+			var resp CardResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, cardID.String(), resp.ID)
+			assert.Equal(t, "5.00", resp.Balance)
+			svc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCardHandler_UpdateCard_Rejections(t *testing.T) {
+	ownerID := uuid.New()
+	cardID := uuid.New()
+
+	tests := []struct {
+		name         string
+		body         string
+		caller       uuid.UUID
+		expectedCode int
+	}{
+		{name: "empty body", body: `{}`, caller: ownerID, expectedCode: http.StatusBadRequest},
+		{name: "explicit nulls", body: `{"active":null}`, caller: ownerID, expectedCode: http.StatusBadRequest},
+		{name: "not the owner", body: `{"active":false}`, caller: uuid.New(), expectedCode: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := new(MockCardService)
+			svc.On("GetCard", mock.Anything, cardID).Return(&model.Card{ID: cardID, AccountID: ownerID}, nil).Maybe()
+
+			c, _ := newTestContext(http.MethodPatch, "/api/cards/"+cardID.String(), strings.NewReader(tt.body), tt.caller.String())
+			c.SetParamNames("id")
+			c.SetParamValues(cardID.String())
+			err := NewCardHandler(svc).UpdateCard(c)
+
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, tt.expectedCode, httpErr.Code)
+			svc.AssertNotCalled(t, "UpdateCard", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestCardHandler_Withdraw(t *testing.T) {
+	ownerID := uuid.New()
+	cardID := uuid.New()
+
+	newRequest := func(body string, caller uuid.UUID) (echo.Context, *httptest.ResponseRecorder) {
+		c, rec := newTestContext(http.MethodPost, "/api/cards/"+cardID.String()+"/withdraw", strings.NewReader(body), caller.String())
+		c.SetParamNames("id")
+		c.SetParamValues(cardID.String())
+		return c, rec
+	}
+
+	t.Run("withdrawn", func(t *testing.T) {
+		svc := new(MockCardService)
+		svc.On("GetCard", mock.Anything, cardID).Return(&model.Card{ID: cardID, AccountID: ownerID}, nil)
+		svc.On("Withdraw", mock.Anything, cardID, mock.MatchedBy(func(d decimal.Decimal) bool {
+			return d.Equal(decimal.RequireFromString("25.50"))
+		})).Return(&service.CardWithdrawal{
+			ID:             uuid.New(),
+			CardID:         cardID,
+			AccountID:      ownerID,
+			Amount:         decimal.RequireFromString("25.50"),
+			CardBalance:    decimal.Zero,
+			AccountBalance: decimal.RequireFromString("125.50"),
+		}, nil)
+
+		c, rec := newRequest(`{"amount":"25.50"}`, ownerID)
+		require.NoError(t, NewCardHandler(svc).Withdraw(c))
+
+		var resp WithdrawResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "0.00", resp.CardBalance)
+		assert.Equal(t, "125.50", resp.AccountBalance)
+	})
+
+	tests := []struct {
+		name         string
+		body         string
+		caller       uuid.UUID
+		serviceErr   error
+		expectedCode int
+		expectedErr  errors.Code
+	}{
+		{name: "insufficient balance", body: `{"amount":"99"}`, caller: ownerID, serviceErr: errors.ErrInsufficientBalance, expectedCode: http.StatusBadRequest, expectedErr: errors.CodeInsufficientBalance},
+		{name: "inactive card", body: `{"amount":"1"}`, caller: ownerID, serviceErr: service.ErrCardInactive, expectedCode: http.StatusConflict, expectedErr: errors.CodeCardInactive},
+		{name: "not the owner", body: `{"amount":"1"}`, caller: uuid.New(), expectedCode: http.StatusForbidden},
+		{name: "bad amount", body: `{"amount":"lots"}`, caller: ownerID, expectedCode: http.StatusBadRequest, expectedErr: errors.CodeInvalidAmount},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := new(MockCardService)
+			svc.On("GetCard", mock.Anything, cardID).Return(&model.Card{ID: cardID, AccountID: ownerID}, nil).Maybe()
+			if tt.serviceErr != nil {
+				svc.On("Withdraw", mock.Anything, cardID, mock.Anything).Return(nil, tt.serviceErr)
+			}
+
+			c, _ := newRequest(tt.body, tt.caller)
+			err := NewCardHandler(svc).Withdraw(c)
+
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, tt.expectedCode, httpErr.Code)
+			if tt.expectedErr != "" {
+				assert.Equal(t, tt.expectedErr, httpErr.Message.(errors.ErrorResponse).Code)
+			}
+			if tt.serviceErr == nil {
+				svc.AssertNotCalled(t, "Withdraw", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestCardHandler_GetAvailableBalance(t *testing.T) {
+	ownerID := uuid.New()
+	cardID := uuid.New()
+	svc := new(MockCardService)
+	svc.On("GetAvailableBalance", mock.Anything, cardID).Return(&service.CardAvailableBalance{
+		Card:      &model.Card{ID: cardID, AccountID: ownerID},
+		Balance:   decimal.RequireFromString("100"),
+		Held:      decimal.RequireFromString("30.25"),
+		Available: decimal.RequireFromString("69.75"),
+	}, nil)
+
+	for _, tt := range []struct {
+		name         string
+		caller       uuid.UUID
+		expectedCode int
+	}{
+		{name: "owner", caller: ownerID, expectedCode: http.StatusOK},
+		{name: "not the owner", caller: uuid.New(), expectedCode: http.StatusForbidden},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rec := newTestContext(http.MethodGet, "/api/cards/"+cardID.String()+"/available-balance", nil, tt.caller.String())
+			c.SetParamNames("id")
+			c.SetParamValues(cardID.String())
+			err := NewCardHandler(svc).GetAvailableBalance(c)
+
+			if tt.expectedCode != http.StatusOK {
+				var httpErr *echo.HTTPError
+				require.ErrorAs(t, err, &httpErr)
+				assert.Equal(t, tt.expectedCode, httpErr.Code)
+				return
+			}
+			require.NoError(t, err)
+			var resp CardAvailableBalanceResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, CardAvailableBalanceResponse{CardID: cardID.String(), Balance: "100.00", Held: "30.25", Available: "69.75"}, resp)
+		})
+	}
+}
+
+func TestCardHandler_PlaceHold(t *testing.T) {
+	callerID := uuid.New()
+	cardID := uuid.New()
+
+	newRequest := func(body string) (echo.Context, *httptest.ResponseRecorder) {
+		c, rec := newTestContext(http.MethodPost, "/api/cards/"+cardID.String()+"/holds", strings.NewReader(body), callerID.String())
+		c.SetParamNames("id")
+		c.SetParamValues(cardID.String())
+		return c, rec
+	}
+
+	t.Run("placed", func(t *testing.T) {
+		hold := &model.CardHold{
+			ID:                uuid.New(),
+			CardID:            cardID,
+			PlacedByAccountID: callerID,
+			Amount:            decimal.RequireFromString("40"),
+			Reference:         "rental-42",
+			Status:            model.CardHoldStatusActive,
+			ExpiresAt:         time.Now().Add(72 * time.Hour),
+		}
+		svc := new(MockCardService)
+		svc.On("PlaceHold", mock.Anything, cardID, callerID, mock.MatchedBy(func(h service.NewCardHold) bool {
+			return h.Amount.Equal(decimal.RequireFromString("40")) && h.Duration == 72*time.Hour && h.Reference == "rental-42"
+		})).Return(hold, nil)
+
+		c, rec := newRequest(`{"amount":"40","duration":"72h","reference":"rental-42"}`)
+		require.NoError(t, NewCardHandler(svc).PlaceHold(c))
+
+		var resp CardHoldResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, "/api/cards/"+cardID.String()+"/holds/"+hold.ID.String(), rec.Header().Get(echo.HeaderLocation))
+		assert.Equal(t, "40.00", resp.Amount)
+		assert.Equal(t, model.CardHoldStatusActive, resp.Status)
+	})
+
+	tests := []struct {
+		name         string
+		body         string
+		serviceErr   error
+		expectedCode int
+		expectedErr  errors.Code
+	}{
+		{name: "insufficient available balance", body: `{"amount":"40"}`, serviceErr: errors.ErrInsufficientBalance, expectedCode: http.StatusBadRequest, expectedErr: errors.CodeInsufficientBalance},
+		{name: "not the owner or a merchant", body: `{"amount":"40"}`, serviceErr: errors.ErrNotMerchant, expectedCode: http.StatusForbidden, expectedErr: errors.CodeNotAMerchant},
+		{name: "merchant the card never paid", body: `{"amount":"40"}`, serviceErr: service.ErrCardHoldNotCustomer, expectedCode: http.StatusForbidden, expectedErr: errors.CodeForbidden},
+		{name: "too long", body: `{"amount":"40","duration":"999h"}`, serviceErr: service.ErrCardHoldTooLong, expectedCode: http.StatusBadRequest, expectedErr: errors.CodeValidationError},
+		{name: "bad duration", body: `{"amount":"40","duration":"soon"}`, expectedCode: http.StatusBadRequest, expectedErr: errors.CodeValidationError},
+		{name: "bad amount", body: `{"amount":"lots"}`, expectedCode: http.StatusBadRequest, expectedErr: errors.CodeInvalidAmount},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := new(MockCardService)
+			if tt.serviceErr != nil {
+				svc.On("PlaceHold", mock.Anything, cardID, callerID, mock.Anything).Return(nil, tt.serviceErr)
+			}
+
+			c, _ := newRequest(tt.body)
+			err := NewCardHandler(svc).PlaceHold(c)
+
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, tt.expectedCode, httpErr.Code)
+			assert.Equal(t, tt.expectedErr, httpErr.Message.(errors.ErrorResponse).Code)
+			if tt.serviceErr == nil {
+				svc.AssertNotCalled(t, "PlaceHold", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestCardHandler_HoldAccess(t *testing.T) {
+	ownerID := uuid.New()
+	merchantID := uuid.New()
+	cardID := uuid.New()
+	hold := &model.CardHold{
+		ID:                uuid.New(),
+		CardID:            cardID,
+		PlacedByAccountID: merchantID,
+		Amount:            decimal.RequireFromString("40"),
+		Status:            model.CardHoldStatusActive,
+		ExpiresAt:         time.Now().Add(time.Hour),
+	}
+
+	newRequest := func(method, path string, caller uuid.UUID) echo.Context {
+		c, _ := newTestContext(method, path, nil, caller.String())
+		c.SetParamNames("id", "hold_id")
+		c.SetParamValues(cardID.String(), hold.ID.String())
+		return c
+	}
+	newService := func() *MockCardService {
+		svc := new(MockCardService)
+		svc.On("GetHold", mock.Anything, cardID, hold.ID).Return(hold, nil)
+		svc.On("GetCard", mock.Anything, cardID).Return(&model.Card{ID: cardID, AccountID: ownerID}, nil).Maybe()
+		svc.On("ReleaseHold", mock.Anything, cardID, hold.ID).Return(hold, nil).Maybe()
+		return svc
+	}
+	holdPath := "/api/cards/" + cardID.String() + "/holds/" + hold.ID.String()
+
+	for _, tt := range []struct {
+		name         string
+		caller       uuid.UUID
+		expectedCode int
+	}{
+		{name: "placer", caller: merchantID, expectedCode: http.StatusOK},
+		{name: "card owner", caller: ownerID, expectedCode: http.StatusOK},
+		{name: "anyone else", caller: uuid.New(), expectedCode: http.StatusForbidden},
+	} {
+		t.Run("get by "+tt.name, func(t *testing.T) {
+			err := NewCardHandler(newService()).GetHold(newRequest(http.MethodGet, holdPath, tt.caller))
+			if tt.expectedCode == http.StatusOK {
+				assert.NoError(t, err)
+				return
+			}
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, tt.expectedCode, httpErr.Code)
+		})
+	}
+
+	for _, tt := range []struct {
+		name         string
+		caller       uuid.UUID
+		expectedCode int
+	}{
+		{name: "placer", caller: merchantID, expectedCode: http.StatusOK},
+		{name: "card owner", caller: ownerID, expectedCode: http.StatusForbidden},
+	} {
+		t.Run("release by "+tt.name, func(t *testing.T) {
+			svc := newService()
+			err := NewCardHandler(svc).ReleaseHold(newRequest(http.MethodPost, holdPath+"/release", tt.caller))
+			if tt.expectedCode == http.StatusOK {
+				assert.NoError(t, err)
+				svc.AssertCalled(t, "ReleaseHold", mock.Anything, cardID, hold.ID)
+				return
+			}
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, tt.expectedCode, httpErr.Code)
+			svc.AssertNotCalled(t, "ReleaseHold", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+
+	t.Run("release of an inactive hold", func(t *testing.T) {
+		svc := new(MockCardService)
+		svc.On("GetHold", mock.Anything, cardID, hold.ID).Return(hold, nil)
+		svc.On("ReleaseHold", mock.Anything, cardID, hold.ID).Return(nil, service.ErrCardHoldNotActive)
+
+		err := NewCardHandler(svc).ReleaseHold(newRequest(http.MethodPost, holdPath+"/release", merchantID))
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, http.StatusConflict, httpErr.Code)
+		assert.Equal(t, errors.CodeCardHoldNotActive, httpErr.Message.(errors.ErrorResponse).Code)
+	})
+}
+
+func TestCardHandler_ListMyCards(t *testing.T) {
+	accountID := uuid.New()
+	active, frozen := uuid.New(), uuid.New()
+	svc := new(MockCardService)
+	svc.On("ListAccountCards", mock.Anything, accountID).Return([]model.Card{
+		{ID: active, AccountID: accountID, CardNumber: "****1111", CardExpiry: "12/30", Balance: decimal.RequireFromString("42.5"), Active: true},
+		{ID: frozen, AccountID: accountID, CardNumber: "****0004", CardExpiry: "01/29", Balance: decimal.Zero},
+	}, nil)
+
+	c, rec := newTestContext(http.MethodGet, "/api/cards", nil, accountID.String())
+	require.NoError(t, NewCardHandler(svc).ListMyCards(c))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var resp AccountCardsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Cards, 2)
+	assert.Equal(t, active.String(), resp.Cards[0].ID)
+	assert.Equal(t, "****1111", resp.Cards[0].CardNumber)
+	assert.Equal(t, "42.50", resp.Cards[0].Balance)
+	assert.True(t, resp.Cards[0].Active)
+	assert.Equal(t, frozen.String(), resp.Cards[1].ID)
+	assert.False(t, resp.Cards[1].Active)
+	assert.NotContains(t, rec.Body.String(), "cvv")
+}
+
+func TestCardHandler_AdminListCards(t *testing.T) {
+	accountID := uuid.New()
+	inactive := false
+	svc := new(MockCardService)
+	svc.On("ListCards", mock.Anything, repository.CardFilter{AccountID: &accountID, Active: &inactive}, 5, 10).Return([]model.Card{
+		{ID: uuid.New(), AccountID: accountID, CardNumber: "****1111", Balance: decimal.NewFromInt(7)},
+	}, int64(11), nil)
+
+	c, rec := newTestContext(http.MethodGet, "/api/admin/cards?account_id="+accountID.String()+"&active=false&limit=5&offset=10", nil, "")
+	require.NoError(t, NewCardHandler(svc).AdminListCards(c))
+
+	var resp CardListResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, int64(11), resp.Total)
+	assert.Equal(t, 5, resp.Limit)
+	assert.Equal(t, 10, resp.Offset)
+	require.Len(t, resp.Cards, 1)
+	assert.Equal(t, "****1111", resp.Cards[0].CardNumber)
+	assert.Equal(t, "7.00", resp.Cards[0].Balance)
+	assert.False(t, resp.Cards[0].Active)
+}
+
+func TestCardHandler_AdminListCards_Rejections(t *testing.T) {
+	for _, query := range []string{"account_id=nope", "active=maybe", "limit=0"} {
+		svc := new(MockCardService)
+		c, _ := newTestContext(http.MethodGet, "/api/admin/cards?"+query, nil, "")
+		err := NewCardHandler(svc).AdminListCards(c)
+
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr, query)
+		assert.Equal(t, http.StatusBadRequest, httpErr.Code, query)
+		svc.AssertNotCalled(t, "ListCards", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	}
+}
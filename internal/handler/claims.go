@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"paytabs/internal/auth"
+	"paytabs/internal/errors"
+)
+
+// accountIDFromContext returns the authenticated account ID from the JWT claims
+// stored by the auth middleware.
+func accountIDFromContext(c echo.Context) (uuid.UUID, error) {
+	claims, ok := c.Get("user").(*auth.Claims)
+	if !ok {
+		return uuid.Nil, echo.NewHTTPError(http.StatusUnauthorized, errors.ErrorResponse{
+			Error: "invalid token",
+			Code:  errors.CodeInvalidToken,
+		})
+	}
+
+	accountID, err := uuid.Parse(claims.AccountID)
+	if err != nil {
+		return uuid.Nil, echo.NewHTTPError(http.StatusUnauthorized, errors.ErrorResponse{
+			Error: "token does not identify an account",
+			Code:  errors.CodeInvalidToken,
+		})
+	}
+
+	return accountID, nil
+}
+
+// requireAccountOwner ensures the authenticated account is the one identified by accountID.
+func requireAccountOwner(c echo.Context, accountID uuid.UUID) error {
+	callerID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
+	}
+	if callerID != accountID {
+		return echo.NewHTTPError(http.StatusForbidden, errors.ErrorResponse{
+			Error: "access to this account is not allowed",
+			Code:  errors.CodeForbidden,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"paytabs/internal/currency"
+)
+
+// currencyCacheControl lets clients and proxies reuse the currency list; it only changes on redeploy.
+const currencyCacheControl = "public, max-age=3600"
+
+// CurrencyHandler handles currency endpoints.
+type CurrencyHandler struct {
+	resp CurrencyListResponse
+}
+
+// NewCurrencyHandler creates a new currency handler. The response is built once since the
+// allow-list is fixed for the life of the process.
+func NewCurrencyHandler(registry *currency.Registry) *CurrencyHandler {
+	return &CurrencyHandler{resp: CurrencyListResponse{
+		Default:    registry.Default().Code,
+		Currencies: registry.Supported(),
+	}}
+}
+
+// CurrencyListResponse lists the supported currencies.
+type CurrencyListResponse struct {
+	Default    string              `json:"default"`
+	Currencies []currency.Currency `json:"currencies"`
+}
+
+// ListCurrencies godoc
+// @Summary List supported currencies
+// @Description Returns the currency allow-list with each currency's code, name, and minor-unit scale.
+// @Tags currencies
+// @Produce json
+// @Success 200 {object} CurrencyListResponse
+// @Router /currencies [get]
+func (h *CurrencyHandler) ListCurrencies(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", currencyCacheControl)
+	return c.JSON(http.StatusOK, h.resp)
+}
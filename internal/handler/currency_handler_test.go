@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"paytabs/internal/config"
+	"paytabs/internal/currency"
+)
+
+func TestCurrencyHandler_ListCurrencies_IncludesDefault(t *testing.T) {
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	registry, err := currency.NewRegistry(cfg.SupportedCurrencies, cfg.DefaultCurrency)
+	require.NoError(t, err)
+
+	c, rec := newTestContext(http.MethodGet, "/api/currencies", nil, "")
+	require.NoError(t, NewCurrencyHandler(registry).ListCurrencies(c))
+
+	var resp CurrencyListResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, cfg.DefaultCurrency, resp.Default)
+	assert.Contains(t, resp.Currencies, registry.Default())
+	assert.Equal(t, currencyCacheControl, rec.Header().Get("Cache-Control"))
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"paytabs/internal/cache"
+)
+
+// Readiness check outcomes.
+const (
+	ReadinessOK          = "ok"
+	ReadinessUnavailable = "unavailable"
+)
+
+// HealthHandler handles the readiness probe.
+type HealthHandler struct {
+	db      *sql.DB
+	cache   *cache.Client
+	timeout time.Duration
+}
+
+// NewHealthHandler creates a health handler that pings db and cacheClient, each for at most timeout.
+func NewHealthHandler(db *sql.DB, cacheClient *cache.Client, timeout time.Duration) *HealthHandler {
+	return &HealthHandler{db: db, cache: cacheClient, timeout: timeout}
+}
+
+// ReadinessResponse reports whether the service can serve traffic, with the outcome of each
+// dependency check. Failures are only named here; their errors are logged, not exposed.
+type ReadinessResponse struct {
+	Status string            `json:"status"` // ok or unavailable
+	Checks map[string]string `json:"checks"` // Dependency to ok or unavailable
+}
+
+// Ready godoc
+// @Summary Readiness probe
+// @Description Pings MySQL and Redis. 503 names the dependency that failed, so an orchestrator stops routing traffic to this instance until it recovers.
+// @Tags health
+// @Produce json
+// @Success 200 {object} ReadinessResponse
+// @Failure 503 {object} ReadinessResponse
+// @Router /readyz [get]
+func (h *HealthHandler) Ready(c echo.Context) error {
+	ctx := c.Request().Context()
+	resp := ReadinessResponse{
+		Status: ReadinessOK,
+		Checks: map[string]string{
+			"mysql": h.check(ctx, "mysql", h.db.PingContext),
+			"redis": h.check(ctx, "redis", h.cache.Ping),
+		},
+	}
+
+	status := http.StatusOK
+	for _, outcome := range resp.Checks {
+		if outcome != ReadinessOK {
+			resp.Status = ReadinessUnavailable
+			status = http.StatusServiceUnavailable
+		}
+	}
+	return c.JSON(status, resp)
+}
+
+// check runs ping with the handler's timeout and reports its outcome.
+func (h *HealthHandler) check(ctx context.Context, name string, ping func(context.Context) error) string {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	if err := ping(ctx); err != nil {
+		log.Printf("readiness: %s unavailable: %v", name, err)
+		return ReadinessUnavailable
+	}
+	return ReadinessOK
+}
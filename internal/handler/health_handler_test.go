@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	stderrors "errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"paytabs/internal/cache"
+)
+
+// pingDB is a database whose pings run ping; nothing else is supported.
+func pingDB(t *testing.T, ping func(ctx context.Context) error) *sql.DB {
+	t.Helper()
+	db := sql.OpenDB(pingConnector{ping: ping})
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+type pingConnector struct {
+	ping func(ctx context.Context) error
+}
+
+func (c pingConnector) Connect(context.Context) (driver.Conn, error) { return pingConn(c), nil }
+func (c pingConnector) Driver() driver.Driver                        { return nil }
+
+type pingConn struct {
+	ping func(ctx context.Context) error
+}
+
+func (c pingConn) Ping(ctx context.Context) error { return c.ping(ctx) }
+func (c pingConn) Prepare(string) (driver.Stmt, error) {
+	return nil, stderrors.New("not supported")
+}
+func (c pingConn) Close() error              { return nil }
+func (c pingConn) Begin() (driver.Tx, error) { return nil, stderrors.New("not supported") }
+
+func healthyPing(context.Context) error { return nil }
+
+func readiness(t *testing.T, h *HealthHandler) (int, ReadinessResponse) {
+	t.Helper()
+	c, rec := newTestContext(http.MethodGet, "/readyz", nil, "")
+	require.NoError(t, h.Ready(c))
+
+	var resp ReadinessResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return rec.Code, resp
+}
+
+func TestHealthHandler_Ready(t *testing.T) {
+	mr := miniredis.RunT(t)
+	h := NewHealthHandler(pingDB(t, healthyPing), cache.New(mr.Addr(), "", 0), time.Second)
+
+	code, resp := readiness(t, h)
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, ReadinessResponse{
+		Status: ReadinessOK,
+		Checks: map[string]string{"mysql": ReadinessOK, "redis": ReadinessOK},
+	}, resp)
+}
+
+func TestHealthHandler_Ready_ClosedDatabase(t *testing.T) {
+	mr := miniredis.RunT(t)
+	db := pingDB(t, healthyPing)
+	require.NoError(t, db.Close())
+	h := NewHealthHandler(db, cache.New(mr.Addr(), "", 0), time.Second)
+
+	code, resp := readiness(t, h)
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, ReadinessUnavailable, resp.Status)
+	assert.Equal(t, ReadinessUnavailable, resp.Checks["mysql"])
+	assert.Equal(t, ReadinessOK, resp.Checks["redis"])
+}
+
+func TestHealthHandler_Ready_ClosedRedis(t *testing.T) {
+	mr := miniredis.RunT(t)
+	cacheClient := cache.New(mr.Addr(), "", 0)
+	mr.Close()
+	h := NewHealthHandler(pingDB(t, healthyPing), cacheClient, 200*time.Millisecond)
+
+	code, resp := readiness(t, h)
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, ReadinessOK, resp.Checks["mysql"])
+	assert.Equal(t, ReadinessUnavailable, resp.Checks["redis"])
+}
+
+func TestHealthHandler_Ready_SlowDatabaseTimesOut(t *testing.T) {
+	mr := miniredis.RunT(t)
+	hang := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	h := NewHealthHandler(pingDB(t, hang), cache.New(mr.Addr(), "", 0), 50*time.Millisecond)
+
+	start := time.Now()
+	code, resp := readiness(t, h)
+
+	assert.Less(t, time.Since(start), time.Second, "bounded by the timeout")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, ReadinessUnavailable, resp.Checks["mysql"])
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"io"
+	"net/http/httptest"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+
+	"paytabs/internal/auth"
+)
+
+// testValidator mirrors the router's validator for handler tests.
+type testValidator struct {
+	validator *validator.Validate
+}
+
+func (v *testValidator) Validate(i interface{}) error {
+	return v.validator.Struct(i)
+}
+
+// newTestContext builds an Echo context for a request, optionally authenticated as accountID.
+func newTestContext(method, target string, body io.Reader, accountID string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	e.Validator = &testValidator{validator: validator.New()}
+
+	req := httptest.NewRequest(method, target, body)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if accountID != "" {
+		c.Set("user", &auth.Claims{AccountID: accountID})
+	}
+	return c, rec
+}
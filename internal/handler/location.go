@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// forwardedPrefixHeader carries the path prefix a reverse proxy stripped before
+// forwarding, e.g. /payments when the API is served at https://host/payments/api/....
+const forwardedPrefixHeader = "X-Forwarded-Prefix"
+
+// setLocation sets the Location header to the API path of a created resource, e.g.
+// /api/payments/<id>. When a proxy reports a stripped prefix it is prepended so the URL
+// resolves for the client. Prefixes that are not plain absolute paths are ignored.
+func setLocation(c echo.Context, path string) {
+	prefix := strings.TrimRight(c.Request().Header.Get(forwardedPrefixHeader), "/")
+	if !strings.HasPrefix(prefix, "/") || strings.HasPrefix(prefix, "//") || strings.ContainsAny(prefix, ":?#\\") {
+		prefix = ""
+	}
+	c.Response().Header().Set(echo.HeaderLocation, prefix+path)
+}
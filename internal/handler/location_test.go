@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetLocation(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		want   string
+	}{
+		{name: "no proxy", want: "/api/payments/42"},
+		{name: "proxy prefix", prefix: "/pay", want: "/pay/api/payments/42"},
+		{name: "trailing slash", prefix: "/pay/", want: "/pay/api/payments/42"},
+		{name: "absolute URL ignored", prefix: "https://evil.example", want: "/api/payments/42"},
+		{name: "protocol-relative ignored", prefix: "//evil.example", want: "/api/payments/42"},
+		{name: "relative path ignored", prefix: "pay", want: "/api/payments/42"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rec := newTestContext(http.MethodPost, "/api/payments/card", nil, "")
+			if tt.prefix != "" {
+				c.Request().Header.Set(forwardedPrefixHeader, tt.prefix)
+			}
+
+			setLocation(c, "/api/payments/42")
+
+			assert.Equal(t, tt.want, rec.Header().Get(echo.HeaderLocation))
+		})
+	}
+}
@@ -0,0 +1,492 @@
+package handler
+
+import (
+	stderrors "errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/shopspring/decimal"
+
+	"paytabs/internal/errors"
+	"paytabs/internal/model"
+	"paytabs/internal/service"
+)
+
+// MerchantHandler handles merchant endpoints.
+type MerchantHandler struct {
+	payoutService  service.PayoutService
+	paymentService service.PaymentService
+	settings       service.MerchantSettingsService
+}
+
+// NewMerchantHandler creates a new merchant handler.
+func NewMerchantHandler(payoutService service.PayoutService, paymentService service.PaymentService, settings service.MerchantSettingsService) *MerchantHandler {
+	return &MerchantHandler{payoutService: payoutService, paymentService: paymentService, settings: settings}
+}
+
+// MerchantBalanceResponse represents a merchant's total, held, and available balance.
+type MerchantBalanceResponse struct {
+	AccountID string `json:"account_id"`
+	Total     string `json:"total"`
+	Held      string `json:"held"`
+	Available string `json:"available"`
+}
+
+// GetMyBalance godoc
+// @Summary Get the authenticated merchant's available vs held balance
+// @Description Held is the sum of pending payouts; available is total minus held.
+// @Tags merchants
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} MerchantBalanceResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /merchants/me/balance [get]
+func (h *MerchantHandler) GetMyBalance(c echo.Context) error {
+	accountID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	balance, err := h.payoutService.GetMerchantBalance(c.Request().Context(), accountID)
+	if err != nil {
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+
+	return c.JSON(http.StatusOK, MerchantBalanceResponse{
+		AccountID: accountID.String(),
+		Total:     balance.Total.StringFixed(2),
+		Held:      balance.Held.StringFixed(2),
+		Available: balance.Available.StringFixed(2),
+	})
+}
+
+// PayoutRequest represents a payout request.
+type PayoutRequest struct {
+	Amount string `json:"amount" validate:"required"`
+}
+
+// PayoutResponse represents a payout.
+type PayoutResponse struct {
+	ID        string    `json:"id"`
+	Amount    string    `json:"amount"`
+	Status    string    `json:"status"`
+	Reference string    `json:"reference,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func newPayoutResponse(payout *model.Payout) PayoutResponse {
+	return PayoutResponse{
+		ID:        payout.ID.String(),
+		Amount:    payout.Amount.StringFixed(2),
+		Status:    string(payout.Status),
+		Reference: payout.Reference,
+		CreatedAt: payout.CreatedAt,
+		UpdatedAt: payout.UpdatedAt,
+	}
+}
+
+// PayoutReserveErrorResponse is returned when a payout would breach the merchant's reserve.
+type PayoutReserveErrorResponse struct {
+	Error      string      `json:"error"`
+	Code       errors.Code `json:"code"`
+	Reserve    string      `json:"reserve"`
+	MaxPayable string      `json:"max_payable"`
+}
+
+// RequestPayout godoc
+// @Summary Request a payout of the authenticated merchant's balance
+// @Description The available balance left after the payout must cover the reserve (the larger of PAYOUT_RESERVE_FIXED and PAYOUT_RESERVE_PERCENT of recent payment volume).
+// @Tags merchants
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body PayoutRequest true "Payout amount"
+// @Success 201 {object} PayoutResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 422 {object} PayoutReserveErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /merchants/me/payouts [post]
+func (h *MerchantHandler) RequestPayout(c echo.Context) error {
+	accountID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	var req PayoutRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: "invalid request body",
+			Code:  errors.CodeInvalidRequest,
+		})
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: err.Error(),
+			Code:  errors.CodeValidationError,
+		})
+	}
+
+	amount, err := decimal.NewFromString(req.Amount)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: "invalid amount",
+			Code:  errors.CodeInvalidAmount,
+		})
+	}
+
+	payout, err := h.payoutService.RequestPayout(c.Request().Context(), accountID, amount)
+	if err != nil {
+		var reserveErr *service.PayoutReserveError
+		if stderrors.As(err, &reserveErr) {
+			return c.JSON(http.StatusUnprocessableEntity, PayoutReserveErrorResponse{
+				Error:      reserveErr.Error(),
+				Code:       errors.CodePayoutExceedsReserve,
+				Reserve:    reserveErr.Reserve.StringFixed(2),
+				MaxPayable: reserveErr.MaxPayable.StringFixed(2),
+			})
+		}
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+
+	setLocation(c, "/api/payouts/"+payout.ID.String())
+	return c.JSON(http.StatusCreated, newPayoutResponse(payout))
+}
+
+// PayoutListResponse is a page of the merchant's payout history.
+type PayoutListResponse struct {
+	Payouts      []PayoutResponse `json:"payouts"`
+	Total        int64            `json:"total"`
+	TotalPaidOut string           `json:"total_paid_out"`
+	Limit        int              `json:"limit"`
+	Offset       int              `json:"offset"`
+}
+
+// ListPayouts godoc
+// @Summary List the authenticated merchant's payouts
+// @Description Newest first. total_paid_out is the sum of every paid payout, regardless of the page or status filter.
+// @Tags merchants
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Only payouts in this status (pending, paid, failed)"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param offset query int false "Number of payouts to skip"
+// @Success 200 {object} PayoutListResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /payouts [get]
+func (h *MerchantHandler) ListPayouts(c echo.Context) error {
+	accountID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	status := model.PayoutStatus(c.QueryParam("status"))
+	switch status {
+	case "", model.PayoutStatusPending, model.PayoutStatusPaid, model.PayoutStatusFailed:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: "status must be one of pending, paid, failed",
+			Code:  errors.CodeValidationError,
+		})
+	}
+
+	limit, offset, err := parsePagination(c)
+	if err != nil {
+		return err
+	}
+
+	page, err := h.payoutService.ListPayouts(c.Request().Context(), accountID, status, limit, offset)
+	if err != nil {
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+
+	items := make([]PayoutResponse, 0, len(page.Payouts))
+	for i := range page.Payouts {
+		items = append(items, newPayoutResponse(&page.Payouts[i]))
+	}
+
+	return c.JSON(http.StatusOK, PayoutListResponse{
+		Payouts:      items,
+		Total:        page.Total,
+		TotalPaidOut: page.TotalPaidOut.StringFixed(2),
+		Limit:        limit,
+		Offset:       offset,
+	})
+}
+
+// GetPayout godoc
+// @Summary Get one of the authenticated merchant's payouts
+// @Tags merchants
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Payout ID"
+// @Success 200 {object} PayoutResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /payouts/{id} [get]
+func (h *MerchantHandler) GetPayout(c echo.Context) error {
+	accountID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	payoutID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	payout, err := h.payoutService.GetPayout(c.Request().Context(), accountID, payoutID)
+	if err != nil {
+		if stderrors.Is(err, service.ErrPayoutNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, errors.ErrorResponse{
+				Error: err.Error(),
+				Code:  errors.CodePayoutNotFound,
+			})
+		}
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+
+	return c.JSON(http.StatusOK, newPayoutResponse(payout))
+}
+
+// MerchantCustomerItem is a customer of the merchant. Only minimal details are exposed.
+type MerchantCustomerItem struct {
+	Name          string    `json:"name"`
+	TotalSpent    string    `json:"total_spent"`
+	PaymentCount  int64     `json:"payment_count"`
+	LastPaymentAt time.Time `json:"last_payment_at"`
+}
+
+// MerchantCustomerListResponse is a page of the merchant's customers.
+type MerchantCustomerListResponse struct {
+	Customers []MerchantCustomerItem `json:"customers"`
+	Total     int64                  `json:"total"`
+	Limit     int                    `json:"limit"`
+	Offset    int                    `json:"offset"`
+}
+
+// ListCustomers godoc
+// @Summary List the accounts that have paid the authenticated merchant
+// @Description Derived from accepted payments: the owners of the cards used, with total spent, payment count, and last payment date, biggest spenders first.
+// @Tags merchants
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param offset query int false "Number of customers to skip"
+// @Success 200 {object} MerchantCustomerListResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /merchants/me/customers [get]
+func (h *MerchantHandler) ListCustomers(c echo.Context) error {
+	accountID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	limit, offset, err := parsePagination(c)
+	if err != nil {
+		return err
+	}
+
+	customers, total, err := h.paymentService.ListMerchantCustomers(c.Request().Context(), accountID, limit, offset)
+	if err != nil {
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+
+	items := make([]MerchantCustomerItem, 0, len(customers))
+	for _, customer := range customers {
+		items = append(items, MerchantCustomerItem{
+			Name:          customer.Name,
+			TotalSpent:    customer.TotalSpent.StringFixed(2),
+			PaymentCount:  customer.PaymentCount,
+			LastPaymentAt: customer.LastPaymentAt,
+		})
+	}
+
+	return c.JSON(http.StatusOK, MerchantCustomerListResponse{
+		Customers: items,
+		Total:     total,
+		Limit:     limit,
+		Offset:    offset,
+	})
+}
+
+// PendingStatusTotal is the in-flight volume in one payment status.
+type PendingStatusTotal struct {
+	Status string `json:"status"`
+	Count  int64  `json:"count"`
+	Total  string `json:"total"`
+}
+
+// PendingPaymentsResponse is the merchant's in-flight payment volume.
+type PendingPaymentsResponse struct {
+	Count    int64                `json:"count"`
+	Total    string               `json:"total"`
+	ByStatus []PendingStatusTotal `json:"by_status"`
+}
+
+// GetPending godoc
+// @Summary Get the authenticated merchant's in-flight payments
+// @Description Count and total amount of payments that have not settled yet, overall and per status. Archived payments are excluded.
+// @Tags merchants
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} PendingPaymentsResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /merchants/me/pending [get]
+func (h *MerchantHandler) GetPending(c echo.Context) error {
+	accountID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	summary, err := h.paymentService.GetPendingSummary(c.Request().Context(), accountID)
+	if err != nil {
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+
+	byStatus := make([]PendingStatusTotal, 0, len(summary.ByStatus))
+	for _, t := range summary.ByStatus {
+		byStatus = append(byStatus, PendingStatusTotal{
+			Status: string(t.Status),
+			Count:  t.Count,
+			Total:  t.Total.StringFixed(2),
+		})
+	}
+
+	return c.JSON(http.StatusOK, PendingPaymentsResponse{
+		Count:    summary.Count,
+		Total:    summary.Total.StringFixed(2),
+		ByStatus: byStatus,
+	})
+}
+
+// BalanceAlertsResponse is the authenticated merchant's balance alert thresholds; "0.00"
+// means the alert is off.
+type BalanceAlertsResponse struct {
+	LowThreshold  string `json:"low_threshold"`
+	HighThreshold string `json:"high_threshold"`
+}
+
+// UpdateBalanceAlertsRequest sets the balance alert thresholds; "0" turns an alert off.
+type UpdateBalanceAlertsRequest struct {
+	LowThreshold  string `json:"low_threshold" validate:"required"`
+	HighThreshold string `json:"high_threshold" validate:"required"`
+}
+
+func newBalanceAlertsResponse(settings *service.MerchantSettings) BalanceAlertsResponse {
+	return BalanceAlertsResponse{
+		LowThreshold:  settings.LowBalanceThreshold.StringFixed(2),
+		HighThreshold: settings.HighBalanceThreshold.StringFixed(2),
+	}
+}
+
+// GetBalanceAlerts godoc
+// @Summary Get the authenticated merchant's balance alert thresholds
+// @Tags merchants
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} BalanceAlertsResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /merchants/me/balance-alerts [get]
+func (h *MerchantHandler) GetBalanceAlerts(c echo.Context) error {
+	accountID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	settings, err := h.settings.Get(c.Request().Context(), accountID)
+	if err != nil {
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+
+	return c.JSON(http.StatusOK, newBalanceAlertsResponse(settings))
+}
+
+// UpdateBalanceAlerts godoc
+// @Summary Set the authenticated merchant's balance alert thresholds
+// @Description When a payment or withdrawal takes the balance below low_threshold, or up to high_threshold or over it, the merchant is sent a balance.below_threshold or balance.above_threshold webhook and email. Each alert is sent at most once per BALANCE_ALERT_DEBOUNCE. "0" turns an alert off; when both are set low must be below high.
+// @Tags merchants
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body UpdateBalanceAlertsRequest true "Thresholds"
+// @Success 200 {object} BalanceAlertsResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /merchants/me/balance-alerts [put]
+func (h *MerchantHandler) UpdateBalanceAlerts(c echo.Context) error {
+	accountID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	var req UpdateBalanceAlertsRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: "invalid request body",
+			Code:  errors.CodeInvalidRequest,
+		})
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: err.Error(),
+			Code:  errors.CodeValidationError,
+		})
+	}
+
+	low, err := decimal.NewFromString(req.LowThreshold)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: "invalid low_threshold",
+			Code:  errors.CodeInvalidAmount,
+		})
+	}
+	high, err := decimal.NewFromString(req.HighThreshold)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: "invalid high_threshold",
+			Code:  errors.CodeInvalidAmount,
+		})
+	}
+
+	settings, err := h.settings.SetBalanceAlerts(c.Request().Context(), accountID, low, high)
+	if err != nil {
+		if err == service.ErrInvalidBalanceThresholds {
+			return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+				Error: err.Error(),
+				Code:  errors.CodeValidationError,
+			})
+		}
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+
+	return c.JSON(http.StatusOK, newBalanceAlertsResponse(settings))
+}
@@ -0,0 +1,271 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"paytabs/internal/errors"
+	"paytabs/internal/model"
+	"paytabs/internal/repository"
+	"paytabs/internal/service"
+)
+
+func TestMerchantHandler_GetMyBalance(t *testing.T) {
+	merchantID := uuid.New()
+
+	svc := new(MockPayoutService)
+	svc.On("GetMerchantBalance", mock.Anything, merchantID).Return(&service.MerchantBalance{
+		Total:     decimal.RequireFromString("250"),
+		Held:      decimal.RequireFromString("100.5"),
+		Available: decimal.RequireFromString("149.5"),
+	}, nil)
+
+	c, rec := newTestContext(http.MethodGet, "/api/merchants/me/balance", nil, merchantID.String())
+	require.NoError(t, NewMerchantHandler(svc, nil, nil).GetMyBalance(c))
+
+	var resp MerchantBalanceResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, MerchantBalanceResponse{
+		AccountID: merchantID.String(),
+		Total:     "250.00",
+		Held:      "100.50",
+		Available: "149.50",
+	}, resp)
+}
+
+func TestMerchantHandler_GetMyBalance_NotMerchant(t *testing.T) {
+	accountID := uuid.New()
+
+	svc := new(MockPayoutService)
+	svc.On("GetMerchantBalance", mock.Anything, accountID).Return(nil, errors.ErrNotMerchant)
+
+	c, _ := newTestContext(http.MethodGet, "/api/merchants/me/balance", nil, accountID.String())
+	err := NewMerchantHandler(svc, nil, nil).GetMyBalance(c)
+
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusForbidden, httpErr.Code)
+}
+
+func TestMerchantHandler_RequestPayout(t *testing.T) {
+	merchantID := uuid.New()
+
+	svc := new(MockPayoutService)
+	svc.On("RequestPayout", mock.Anything, merchantID, mock.MatchedBy(func(d decimal.Decimal) bool {
+		return d.Equal(decimal.RequireFromString("120.50"))
+	})).Return(&model.Payout{ID: uuid.New(), Amount: decimal.RequireFromString("120.5"), Status: model.PayoutStatusPending}, nil)
+
+	c, rec := newTestContext(http.MethodPost, "/api/merchants/me/payouts", strings.NewReader(`{"amount":"120.50"}`), merchantID.String())
+	require.NoError(t, NewMerchantHandler(svc, nil, nil).RequestPayout(c))
+
+	var resp PayoutResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "120.50", resp.Amount)
+	assert.Equal(t, "pending", resp.Status)
+	assert.Equal(t, "/api/payouts/"+resp.ID, rec.Header().Get(echo.HeaderLocation))
+}
+
+func TestMerchantHandler_RequestPayout_ExceedsReserve(t *testing.T) {
+	merchantID := uuid.New()
+
+	svc := new(MockPayoutService)
+	svc.On("RequestPayout", mock.Anything, merchantID, mock.Anything).Return(nil, &service.PayoutReserveError{
+		Reserve:    decimal.RequireFromString("50"),
+		MaxPayable: decimal.RequireFromString("849.5"),
+	})
+
+	c, rec := newTestContext(http.MethodPost, "/api/merchants/me/payouts", strings.NewReader(`{"amount":"900.00"}`), merchantID.String())
+	require.NoError(t, NewMerchantHandler(svc, nil, nil).RequestPayout(c))
+
+	var resp PayoutReserveErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal(t, errors.CodePayoutExceedsReserve, resp.Code)
+	assert.Equal(t, "849.50", resp.MaxPayable)
+	assert.Contains(t, resp.Error, "maximum payable is 849.50")
+}
+
+func TestMerchantHandler_ListCustomers(t *testing.T) {
+	merchantID := uuid.New()
+	last := time.Date(2024, 6, 1, 9, 30, 0, 0, time.UTC)
+
+	svc := new(MockPaymentService)
+	svc.On("ListMerchantCustomers", mock.Anything, merchantID, 2, 4).Return([]repository.MerchantCustomer{
+		{AccountID: uuid.New(), Name: "Alice", TotalSpent: decimal.RequireFromString("310.5"), PaymentCount: 3, LastPaymentAt: last},
+	}, int64(5), nil)
+
+	c, rec := newTestContext(http.MethodGet, "/api/merchants/me/customers?limit=2&offset=4", nil, merchantID.String())
+	require.NoError(t, NewMerchantHandler(nil, svc, nil).ListCustomers(c))
+
+	var resp MerchantCustomerListResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, int64(5), resp.Total)
+	assert.Equal(t, 2, resp.Limit)
+	assert.Equal(t, 4, resp.Offset)
+	require.Len(t, resp.Customers, 1)
+	assert.Equal(t, "Alice", resp.Customers[0].Name)
+	assert.Equal(t, "310.50", resp.Customers[0].TotalSpent)
+	assert.Equal(t, int64(3), resp.Customers[0].PaymentCount)
+	assert.True(t, resp.Customers[0].LastPaymentAt.Equal(last))
+	assert.NotContains(t, rec.Body.String(), "account_id", "customer account ids are not exposed")
+}
+
+func TestMerchantHandler_GetPending(t *testing.T) {
+	merchantID := uuid.New()
+
+	svc := new(MockPaymentService)
+	svc.On("GetPendingSummary", mock.Anything, merchantID).Return(&service.PendingPaymentsSummary{
+		Count: 2,
+		Total: decimal.RequireFromString("40.5"),
+		ByStatus: []repository.PaymentStatusTotal{
+			{Status: model.PaymentStatusPending, Count: 2, Total: decimal.RequireFromString("40.5")},
+		},
+	}, nil)
+
+	c, rec := newTestContext(http.MethodGet, "/api/merchants/me/pending", nil, merchantID.String())
+	require.NoError(t, NewMerchantHandler(nil, svc, nil).GetPending(c))
+
+	var resp PendingPaymentsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, int64(2), resp.Count)
+	assert.Equal(t, "40.50", resp.Total)
+	require.Len(t, resp.ByStatus, 1)
+	assert.Equal(t, "pending", resp.ByStatus[0].Status)
+	assert.Equal(t, "40.50", resp.ByStatus[0].Total)
+}
+
+func TestMerchantHandler_GetPending_NotMerchant(t *testing.T) {
+	accountID := uuid.New()
+
+	svc := new(MockPaymentService)
+	svc.On("GetPendingSummary", mock.Anything, accountID).Return(nil, errors.ErrNotMerchant)
+
+	c, _ := newTestContext(http.MethodGet, "/api/merchants/me/pending", nil, accountID.String())
+	err := NewMerchantHandler(nil, svc, nil).GetPending(c)
+
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusForbidden, httpErr.Code)
+}
+
+func TestMerchantHandler_ListPayouts(t *testing.T) {
+	merchantID := uuid.New()
+	paidAt := time.Date(2024, 6, 2, 9, 0, 0, 0, time.UTC)
+
+	svc := new(MockPayoutService)
+	svc.On("ListPayouts", mock.Anything, merchantID, model.PayoutStatusPaid, 20, 0).Return(&service.PayoutPage{
+		Payouts: []model.Payout{{
+			ID:        uuid.New(),
+			Amount:    decimal.RequireFromString("120.5"),
+			Status:    model.PayoutStatusPaid,
+			Reference: "BANK-123",
+			CreatedAt: paidAt.Add(-time.Hour),
+			UpdatedAt: paidAt,
+		}},
+		Total:        1,
+		TotalPaidOut: decimal.RequireFromString("320.5"),
+	}, nil)
+
+	c, rec := newTestContext(http.MethodGet, "/api/payouts?status=paid", nil, merchantID.String())
+	require.NoError(t, NewMerchantHandler(svc, nil, nil).ListPayouts(c))
+
+	var resp PayoutListResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, int64(1), resp.Total)
+	assert.Equal(t, "320.50", resp.TotalPaidOut)
+	require.Len(t, resp.Payouts, 1)
+	assert.Equal(t, "120.50", resp.Payouts[0].Amount)
+	assert.Equal(t, "BANK-123", resp.Payouts[0].Reference)
+	assert.Equal(t, paidAt, resp.Payouts[0].UpdatedAt)
+}
+
+func TestMerchantHandler_ListPayouts_InvalidStatus(t *testing.T) {
+	svc := new(MockPayoutService)
+
+	c, _ := newTestContext(http.MethodGet, "/api/payouts?status=settled", nil, uuid.New().String())
+	err := NewMerchantHandler(svc, nil, nil).ListPayouts(c)
+
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+	assert.Equal(t, errors.CodeValidationError, httpErr.Message.(errors.ErrorResponse).Code)
+	svc.AssertNotCalled(t, "ListPayouts", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestMerchantHandler_GetPayout_NotFound(t *testing.T) {
+	merchantID := uuid.New()
+	payoutID := uuid.New()
+
+	svc := new(MockPayoutService)
+	svc.On("GetPayout", mock.Anything, merchantID, payoutID).Return(nil, service.ErrPayoutNotFound)
+
+	c, _ := newTestContext(http.MethodGet, "/api/payouts/"+payoutID.String(), nil, merchantID.String())
+	c.SetParamNames("id")
+	c.SetParamValues(payoutID.String())
+	err := NewMerchantHandler(svc, nil, nil).GetPayout(c)
+
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.Code)
+	assert.Equal(t, errors.CodePayoutNotFound, httpErr.Message.(errors.ErrorResponse).Code)
+}
+
+func TestMerchantHandler_UpdateBalanceAlerts(t *testing.T) {
+	merchantID := uuid.New()
+
+	tests := []struct {
+		name       string
+		body       string
+		serviceErr error
+		wantStatus int
+	}{
+		{name: "set", body: `{"low_threshold":"100","high_threshold":"5000.5"}`, wantStatus: http.StatusOK},
+		{name: "missing high", body: `{"low_threshold":"100"}`, wantStatus: http.StatusBadRequest},
+		{name: "not a number", body: `{"low_threshold":"lots","high_threshold":"5000"}`, wantStatus: http.StatusBadRequest},
+		{name: "rejected by the service", body: `{"low_threshold":"100","high_threshold":"5000.5"}`, serviceErr: service.ErrInvalidBalanceThresholds, wantStatus: http.StatusBadRequest},
+		{name: "not a merchant", body: `{"low_threshold":"100","high_threshold":"5000.5"}`, serviceErr: errors.ErrNotMerchant, wantStatus: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := new(MockMerchantSettingsService)
+			if tt.serviceErr != nil {
+				settings.On("SetBalanceAlerts", mock.Anything, merchantID, decimal.RequireFromString("100"), decimal.RequireFromString("5000.5")).Return(nil, tt.serviceErr)
+			} else {
+				settings.On("SetBalanceAlerts", mock.Anything, merchantID, decimal.RequireFromString("100"), decimal.RequireFromString("5000.5")).Return(&service.MerchantSettings{
+					AccountID:            merchantID,
+					LowBalanceThreshold:  decimal.RequireFromString("100"),
+					HighBalanceThreshold: decimal.RequireFromString("5000.5"),
+				}, nil).Maybe()
+			}
+
+			c, rec := newTestContext(http.MethodPut, "/api/merchants/me/balance-alerts", strings.NewReader(tt.body), merchantID.String())
+			err := NewMerchantHandler(nil, nil, settings).UpdateBalanceAlerts(c)
+
+			if tt.wantStatus != http.StatusOK {
+				var httpErr *echo.HTTPError
+				require.ErrorAs(t, err, &httpErr)
+				assert.Equal(t, tt.wantStatus, httpErr.Code)
+				return
+			}
+			require.NoError(t, err)
+			var resp BalanceAlertsResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, BalanceAlertsResponse{LowThreshold: "100.00", HighThreshold: "5000.50"}, resp)
+		})
+	}
+}
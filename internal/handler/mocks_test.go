@@ -0,0 +1,587 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+
+	"paytabs/internal/model"
+	"paytabs/internal/repository"
+	"paytabs/internal/service"
+)
+
+// MockPaymentService is a mock implementation of PaymentService.
+type MockPaymentService struct {
+	mock.Mock
+}
+
+func (m *MockPaymentService) ProcessCardPayment(ctx context.Context, merchantAccountID uuid.UUID, cardID uuid.UUID, amount decimal.Decimal, reference, idempotencyKey string) (*model.Payment, error) {
+	args := m.Called(ctx, merchantAccountID, cardID, amount, reference, idempotencyKey)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Payment), args.Error(1)
+}
+
+func (m *MockPaymentService) ListMerchantCustomers(ctx context.Context, merchantAccountID uuid.UUID, limit, offset int) ([]repository.MerchantCustomer, int64, error) {
+	args := m.Called(ctx, merchantAccountID, limit, offset)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]repository.MerchantCustomer), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockPaymentService) GetPendingSummary(ctx context.Context, merchantAccountID uuid.UUID) (*service.PendingPaymentsSummary, error) {
+	args := m.Called(ctx, merchantAccountID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.PendingPaymentsSummary), args.Error(1)
+}
+
+func (m *MockPaymentService) RetryPayment(ctx context.Context, merchantAccountID uuid.UUID, paymentID uuid.UUID) (*model.Payment, error) {
+	args := m.Called(ctx, merchantAccountID, paymentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Payment), args.Error(1)
+}
+
+func (m *MockPaymentService) ListAccountPayments(ctx context.Context, accountID uuid.UUID, includeArchived bool, limit, offset int) ([]model.Payment, int64, error) {
+	args := m.Called(ctx, accountID, includeArchived, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(int64), args.Error(2)
+	}
+	return args.Get(0).([]model.Payment), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockPaymentService) ListMerchantPayments(ctx context.Context, merchantAccountID uuid.UUID, filter repository.PaymentFilter, limit, offset int) ([]model.Payment, int64, error) {
+	args := m.Called(ctx, merchantAccountID, filter, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(int64), args.Error(2)
+	}
+	return args.Get(0).([]model.Payment), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockPaymentService) ListCardPayments(ctx context.Context, cardID uuid.UUID, limit, offset int) (*service.CardPaymentPage, error) {
+	args := m.Called(ctx, cardID, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.CardPaymentPage), args.Error(1)
+}
+
+func (m *MockPaymentService) LogQueueDepth() (int, int) {
+	args := m.Called()
+	return args.Int(0), args.Int(1)
+}
+
+func (m *MockPaymentService) DroppedLogs() int64 {
+	args := m.Called()
+	return args.Get(0).(int64)
+}
+
+func (m *MockPaymentService) CancelPayment(ctx context.Context, merchantAccountID uuid.UUID, paymentID uuid.UUID) (*model.Payment, error) {
+	args := m.Called(ctx, merchantAccountID, paymentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Payment), args.Error(1)
+}
+
+func (m *MockPaymentService) RefundPayment(ctx context.Context, merchantAccountID uuid.UUID, paymentID uuid.UUID, amount decimal.Decimal, idempotencyKey string) (*model.Payment, error) {
+	args := m.Called(ctx, merchantAccountID, paymentID, amount, idempotencyKey)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Payment), args.Error(1)
+}
+
+func (m *MockPaymentService) GetPayment(ctx context.Context, merchantAccountID uuid.UUID, paymentID uuid.UUID) (*model.Payment, error) {
+	args := m.Called(ctx, merchantAccountID, paymentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Payment), args.Error(1)
+}
+
+func (m *MockPaymentService) GetPaymentTimeline(ctx context.Context, merchantAccountID uuid.UUID, paymentID uuid.UUID) (*service.PaymentTimeline, error) {
+	args := m.Called(ctx, merchantAccountID, paymentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.PaymentTimeline), args.Error(1)
+}
+
+func (m *MockPaymentService) GetPaymentReceipt(ctx context.Context, merchantAccountID uuid.UUID, paymentID uuid.UUID) (*service.PaymentReceipt, error) {
+	args := m.Called(ctx, merchantAccountID, paymentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.PaymentReceipt), args.Error(1)
+}
+
+func (m *MockPaymentService) GetPaymentBreakdown(ctx context.Context, merchantAccountID uuid.UUID, paymentID uuid.UUID) (*service.PaymentBreakdown, error) {
+	args := m.Called(ctx, merchantAccountID, paymentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.PaymentBreakdown), args.Error(1)
+}
+
+// MockAccountService is a mock implementation of AccountService.
+type MockAccountService struct {
+	mock.Mock
+}
+
+func (m *MockAccountService) GetAccount(ctx context.Context, id uuid.UUID) (*model.Account, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Account), args.Error(1)
+}
+
+func (m *MockAccountService) GetBalance(ctx context.Context, id uuid.UUID) (decimal.Decimal, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(decimal.Decimal), args.Error(1)
+}
+
+func (m *MockAccountService) GetBalances(ctx context.Context, ids []uuid.UUID) ([]service.AccountBalance, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]service.AccountBalance), args.Error(1)
+}
+
+func (m *MockAccountService) GetWallet(ctx context.Context, id uuid.UUID) (*service.Wallet, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.Wallet), args.Error(1)
+}
+
+func (m *MockAccountService) ListAccounts(ctx context.Context, filter repository.AccountFilter, sort repository.AccountSort, limit, offset int) ([]model.Account, int64, error) {
+	args := m.Called(ctx, filter, sort, limit, offset)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]model.Account), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockAccountService) SeedAccounts(ctx context.Context, accounts []model.Account) (int, error) {
+	args := m.Called(ctx, accounts)
+	return args.Int(0), args.Error(1)
+}
+
+// MockAccountSnapshotService is a mock implementation of AccountSnapshotService.
+type MockAccountSnapshotService struct {
+	mock.Mock
+}
+
+func (m *MockAccountSnapshotService) Snapshot(ctx context.Context, accountID uuid.UUID) (*service.AccountSnapshot, error) {
+	args := m.Called(ctx, accountID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.AccountSnapshot), args.Error(1)
+}
+
+// MockCardService is a mock implementation of CardService.
+type MockCardService struct {
+	mock.Mock
+}
+
+func (m *MockCardService) GetBalance(ctx context.Context, cardID uuid.UUID) (decimal.Decimal, error) {
+	args := m.Called(ctx, cardID)
+	return args.Get(0).(decimal.Decimal), args.Error(1)
+}
+
+func (m *MockCardService) GetAccountTotalBalance(ctx context.Context, accountID uuid.UUID) (decimal.Decimal, error) {
+	args := m.Called(ctx, accountID)
+	return args.Get(0).(decimal.Decimal), args.Error(1)
+}
+
+func (m *MockCardService) ListCards(ctx context.Context, filter repository.CardFilter, limit, offset int) ([]model.Card, int64, error) {
+	args := m.Called(ctx, filter, limit, offset)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]model.Card), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockCardService) CreateCard(ctx context.Context, accountID uuid.UUID, card service.NewCard) (*model.Card, error) {
+	args := m.Called(ctx, accountID, card)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Card), args.Error(1)
+}
+
+func (m *MockCardService) ListAccountCards(ctx context.Context, accountID uuid.UUID) ([]model.Card, error) {
+	args := m.Called(ctx, accountID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Card), args.Error(1)
+}
+
+func (m *MockCardService) GetCard(ctx context.Context, cardID uuid.UUID) (*model.Card, error) {
+	args := m.Called(ctx, cardID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Card), args.Error(1)
+}
+
+func (m *MockCardService) GetBalanceHistory(ctx context.Context, cardID uuid.UUID, from, to time.Time, limit, offset int) ([]model.LedgerEntry, error) {
+	args := m.Called(ctx, cardID, from, to, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.LedgerEntry), args.Error(1)
+}
+
+func (m *MockCardService) UpdateCard(ctx context.Context, cardID uuid.UUID, update service.CardUpdate) (*model.Card, error) {
+	args := m.Called(ctx, cardID, update)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Card), args.Error(1)
+}
+
+func (m *MockCardService) Withdraw(ctx context.Context, cardID uuid.UUID, amount decimal.Decimal) (*service.CardWithdrawal, error) {
+	args := m.Called(ctx, cardID, amount)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.CardWithdrawal), args.Error(1)
+}
+
+func (m *MockCardService) CreateCards(ctx context.Context, accountID uuid.UUID, cards []service.NewCard) ([]model.Card, error) {
+	args := m.Called(ctx, accountID, cards)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Card), args.Error(1)
+}
+
+func (m *MockCardService) GetAvailableBalance(ctx context.Context, cardID uuid.UUID) (*service.CardAvailableBalance, error) {
+	args := m.Called(ctx, cardID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.CardAvailableBalance), args.Error(1)
+}
+
+func (m *MockCardService) PlaceHold(ctx context.Context, cardID uuid.UUID, placedBy uuid.UUID, hold service.NewCardHold) (*model.CardHold, error) {
+	args := m.Called(ctx, cardID, placedBy, hold)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.CardHold), args.Error(1)
+}
+
+func (m *MockCardService) GetHold(ctx context.Context, cardID uuid.UUID, holdID uuid.UUID) (*model.CardHold, error) {
+	args := m.Called(ctx, cardID, holdID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.CardHold), args.Error(1)
+}
+
+func (m *MockCardService) ReleaseHold(ctx context.Context, cardID uuid.UUID, holdID uuid.UUID) (*model.CardHold, error) {
+	args := m.Called(ctx, cardID, holdID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.CardHold), args.Error(1)
+}
+
+// MockAuthService is a mock implementation of AuthService.
+type MockAuthService struct {
+	mock.Mock
+}
+
+func (m *MockAuthService) Register(ctx context.Context, email, password, name string, isMerchant bool) (*model.Account, error) {
+	args := m.Called(ctx, email, password, name, isMerchant)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Account), args.Error(1)
+}
+
+func (m *MockAuthService) Login(ctx context.Context, email, password, clientIP string) (string, string, *model.Account, error) {
+	args := m.Called(ctx, email, password, clientIP)
+	if args.Get(2) == nil {
+		return args.String(0), args.String(1), nil, args.Error(3)
+	}
+	return args.String(0), args.String(1), args.Get(2).(*model.Account), args.Error(3)
+}
+
+func (m *MockAuthService) RefreshToken(ctx context.Context, refreshToken string) (string, string, error) {
+	args := m.Called(ctx, refreshToken)
+	return args.String(0), args.String(1), args.Error(2)
+}
+
+func (m *MockAuthService) Logout(ctx context.Context, refreshToken, accessToken string) error {
+	args := m.Called(ctx, refreshToken, accessToken)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) IsEmailAvailable(ctx context.Context, email string) (bool, error) {
+	args := m.Called(ctx, email)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAuthService) VerifyEmail(ctx context.Context, token string) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) ResendVerification(ctx context.Context, email string) error {
+	args := m.Called(ctx, email)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) ForgotPassword(ctx context.Context, email string) error {
+	args := m.Called(ctx, email)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	args := m.Called(ctx, token, newPassword)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) LogoutAll(ctx context.Context, accountID uuid.UUID, accessToken string) error {
+	args := m.Called(ctx, accountID, accessToken)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) ChangePassword(ctx context.Context, accountID uuid.UUID, currentPassword, newPassword string) error {
+	args := m.Called(ctx, accountID, currentPassword, newPassword)
+	return args.Error(0)
+}
+
+// MockTransferService is a mock implementation of TransferService.
+type MockTransferService struct {
+	mock.Mock
+}
+
+func (m *MockTransferService) ProcessTransfer(ctx context.Context, accountID, sourceCardID, destinationCardID uuid.UUID, amount decimal.Decimal) (*model.Transfer, error) {
+	args := m.Called(ctx, accountID, sourceCardID, destinationCardID, amount)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Transfer), args.Error(1)
+}
+
+func (m *MockTransferService) ValidateTransfer(ctx context.Context, accountID, sourceCardID, destinationCardID uuid.UUID, amount decimal.Decimal) (*service.TransferCheck, error) {
+	args := m.Called(ctx, accountID, sourceCardID, destinationCardID, amount)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.TransferCheck), args.Error(1)
+}
+
+func (m *MockTransferService) ListAccountTransfers(ctx context.Context, accountID uuid.UUID, limit, offset int) ([]repository.AccountTransfer, int64, error) {
+	args := m.Called(ctx, accountID, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(int64), args.Error(2)
+	}
+	return args.Get(0).([]repository.AccountTransfer), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockTransferService) ListCardTransfers(ctx context.Context, cardID uuid.UUID, status model.TransferStatus, limit, offset int) (*service.CardTransferPage, error) {
+	args := m.Called(ctx, cardID, status, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.CardTransferPage), args.Error(1)
+}
+
+func (m *MockTransferService) GetCardTransferStats(ctx context.Context, cardID uuid.UUID, from, to time.Time) (*service.CardTransferStats, error) {
+	args := m.Called(ctx, cardID, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.CardTransferStats), args.Error(1)
+}
+
+// MockPayoutService is a mock implementation of PayoutService.
+type MockPayoutService struct {
+	mock.Mock
+}
+
+func (m *MockPayoutService) GetMerchantBalance(ctx context.Context, merchantAccountID uuid.UUID) (*service.MerchantBalance, error) {
+	args := m.Called(ctx, merchantAccountID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.MerchantBalance), args.Error(1)
+}
+
+func (m *MockPayoutService) RequestPayout(ctx context.Context, merchantAccountID uuid.UUID, amount decimal.Decimal) (*model.Payout, error) {
+	args := m.Called(ctx, merchantAccountID, amount)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Payout), args.Error(1)
+}
+
+func (m *MockPayoutService) ListPayouts(ctx context.Context, merchantAccountID uuid.UUID, status model.PayoutStatus, limit, offset int) (*service.PayoutPage, error) {
+	args := m.Called(ctx, merchantAccountID, status, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.PayoutPage), args.Error(1)
+}
+
+func (m *MockPayoutService) GetPayout(ctx context.Context, merchantAccountID uuid.UUID, payoutID uuid.UUID) (*model.Payout, error) {
+	args := m.Called(ctx, merchantAccountID, payoutID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Payout), args.Error(1)
+}
+
+// MockWebhookService is a mock implementation of WebhookService.
+type MockWebhookService struct {
+	mock.Mock
+}
+
+func (m *MockWebhookService) RegenerateSecret(ctx context.Context, accountID uuid.UUID, clientIP string) (string, error) {
+	args := m.Called(ctx, accountID, clientIP)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockWebhookService) SigningSecret(ctx context.Context, accountID uuid.UUID) (string, error) {
+	args := m.Called(ctx, accountID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockWebhookService) SetEndpoint(ctx context.Context, accountID uuid.UUID, url string) (*model.WebhookEndpoint, error) {
+	args := m.Called(ctx, accountID, url)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.WebhookEndpoint), args.Error(1)
+}
+
+func (m *MockWebhookService) GetEndpoint(ctx context.Context, accountID uuid.UUID) (*model.WebhookEndpoint, error) {
+	args := m.Called(ctx, accountID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.WebhookEndpoint), args.Error(1)
+}
+
+func (m *MockWebhookService) SendEvent(ctx context.Context, accountID uuid.UUID, eventType string, data interface{}) error {
+	args := m.Called(ctx, accountID, eventType, data)
+	return args.Error(0)
+}
+
+func (m *MockWebhookService) SendTestEvent(ctx context.Context, accountID uuid.UUID) (*service.WebhookTestResult, error) {
+	args := m.Called(ctx, accountID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.WebhookTestResult), args.Error(1)
+}
+
+// MockKillSwitchService is a mock implementation of KillSwitchService.
+type MockKillSwitchService struct {
+	mock.Mock
+}
+
+func (m *MockKillSwitchService) Enabled(ctx context.Context, op service.MoneyOperation) (bool, error) {
+	args := m.Called(ctx, op)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockKillSwitchService) SetEnabled(ctx context.Context, op service.MoneyOperation, enabled bool) error {
+	args := m.Called(ctx, op, enabled)
+	return args.Error(0)
+}
+
+// MockAdminStatsService is a mock implementation of AdminStatsService.
+type MockAdminStatsService struct {
+	mock.Mock
+}
+
+func (m *MockAdminStatsService) Stats(ctx context.Context) (*service.PlatformStats, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.PlatformStats), args.Error(1)
+}
+
+func (m *MockAdminStatsService) Float(ctx context.Context) (*service.PlatformFloat, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.PlatformFloat), args.Error(1)
+}
+
+// MockAccountHoldService is a mock implementation of AccountHoldService.
+type MockAccountHoldService struct {
+	mock.Mock
+}
+
+func (m *MockAccountHoldService) Place(ctx context.Context, accountID uuid.UUID, reason string, duration time.Duration) (*service.AccountHold, error) {
+	args := m.Called(ctx, accountID, reason, duration)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.AccountHold), args.Error(1)
+}
+
+func (m *MockAccountHoldService) Get(ctx context.Context, accountID uuid.UUID) (*service.AccountHold, error) {
+	args := m.Called(ctx, accountID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.AccountHold), args.Error(1)
+}
+
+func (m *MockAccountHoldService) Lift(ctx context.Context, accountID uuid.UUID) error {
+	args := m.Called(ctx, accountID)
+	return args.Error(0)
+}
+
+// MockMerchantSettingsService is a mock implementation of MerchantSettingsService.
+type MockMerchantSettingsService struct {
+	mock.Mock
+}
+
+func (m *MockMerchantSettingsService) Get(ctx context.Context, merchantID uuid.UUID) (*service.MerchantSettings, error) {
+	args := m.Called(ctx, merchantID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.MerchantSettings), args.Error(1)
+}
+
+func (m *MockMerchantSettingsService) SetMaxPaymentAmount(ctx context.Context, merchantID uuid.UUID, amount decimal.Decimal) (*service.MerchantSettings, error) {
+	args := m.Called(ctx, merchantID, amount)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.MerchantSettings), args.Error(1)
+}
+
+func (m *MockMerchantSettingsService) SetBalanceAlerts(ctx context.Context, merchantID uuid.UUID, low, high decimal.Decimal) (*service.MerchantSettings, error) {
+	args := m.Called(ctx, merchantID, low, high)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.MerchantSettings), args.Error(1)
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"paytabs/internal/errors"
+)
+
+const (
+	defaultPageLimit = 20
+	// defaultMaxPageLimit caps page sizes when no PageSizeLimits are installed.
+	defaultMaxPageLimit = 100
+)
+
+// pageSizeLimitsKey is the context key WithPageSizeLimits stores the limits under.
+const pageSizeLimitsKey = "page_size_limits"
+
+// PageSizeLimits bounds the limit query param every listing endpoint accepts.
+type PageSizeLimits struct {
+	// Max is the largest page size served. Zero or less means defaultMaxPageLimit.
+	Max int
+	// RejectOversized answers 400 for a larger limit instead of clamping it to Max.
+	RejectOversized bool
+}
+
+// WithPageSizeLimits makes parsePagination enforce limits for the routes it wraps.
+func WithPageSizeLimits(limits PageSizeLimits) echo.MiddlewareFunc {
+	if limits.Max <= 0 {
+		limits.Max = defaultMaxPageLimit
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set(pageSizeLimitsKey, limits)
+			return next(c)
+		}
+	}
+}
+
+// parsePagination reads limit and offset query params, applying defaults and bounds. A
+// limit above the installed maximum is clamped, or rejected if the limits say so.
+func parsePagination(c echo.Context) (limit, offset int, err error) {
+	limits, ok := c.Get(pageSizeLimitsKey).(PageSizeLimits)
+	if !ok {
+		limits = PageSizeLimits{Max: defaultMaxPageLimit}
+	}
+
+	limit = defaultPageLimit
+	if v := c.QueryParam("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			return 0, 0, echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+				Error: "limit must be a positive integer",
+				Code:  errors.CodeInvalidPagination,
+			})
+		}
+	}
+	if limit > limits.Max {
+		if limits.RejectOversized {
+			return 0, 0, echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+				Error: fmt.Sprintf("limit must be at most %d", limits.Max),
+				Code:  errors.CodeInvalidPagination,
+			})
+		}
+		limit = limits.Max
+	}
+
+	if v := c.QueryParam("offset"); v != "" {
+		offset, err = strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return 0, 0, echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+				Error: "offset must be a non-negative integer",
+				Code:  errors.CodeInvalidPagination,
+			})
+		}
+	}
+
+	return limit, offset, nil
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// paginate runs parsePagination for target behind WithPageSizeLimits(limits).
+func paginate(t *testing.T, target string, limits *PageSizeLimits) (limit, offset int, err error) {
+	t.Helper()
+	c, _ := newTestContext(http.MethodGet, target, nil, "")
+	if limits == nil {
+		return parsePagination(c)
+	}
+	err = WithPageSizeLimits(*limits)(func(c echo.Context) error {
+		var perr error
+		limit, offset, perr = parsePagination(c)
+		return perr
+	})(c)
+	return limit, offset, err
+}
+
+func TestParsePagination_ClampsHugeLimit(t *testing.T) {
+	limit, offset, err := paginate(t, "/items?limit=100000&offset=3", &PageSizeLimits{})
+	require.NoError(t, err)
+	assert.Equal(t, defaultMaxPageLimit, limit)
+	assert.Equal(t, 3, offset)
+
+	limit, _, err = paginate(t, "/items?limit=100000", nil)
+	require.NoError(t, err)
+	assert.Equal(t, defaultMaxPageLimit, limit, "the default cap applies without the middleware")
+
+	limit, _, err = paginate(t, "/items?limit=100000", &PageSizeLimits{Max: 50})
+	require.NoError(t, err)
+	assert.Equal(t, 50, limit)
+}
+
+func TestParsePagination_RejectsOversizedLimit(t *testing.T) {
+	_, _, err := paginate(t, "/items?limit=100000", &PageSizeLimits{Max: 50, RejectOversized: true})
+
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+
+	limit, _, err := paginate(t, "/items?limit=50", &PageSizeLimits{Max: 50, RejectOversized: true})
+	require.NoError(t, err)
+	assert.Equal(t, 50, limit)
+}
+
+func TestParsePagination_DefaultsAndInvalid(t *testing.T) {
+	limit, offset, err := paginate(t, "/items", &PageSizeLimits{Max: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 10, limit, "the default page size never exceeds the max")
+	assert.Equal(t, 0, offset)
+
+	for _, target := range []string{"/items?limit=0", "/items?limit=abc", "/items?offset=-1"} {
+		_, _, err := paginate(t, target, nil)
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr, target)
+		assert.Equal(t, http.StatusBadRequest, httpErr.Code, target)
+	}
+}
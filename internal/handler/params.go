@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"paytabs/internal/errors"
+)
+
+// parseUUIDParam parses the named path parameter as a UUID. On failure it returns the
+// same 400 INVALID_UUID error as parseUUIDBody, naming the parameter.
+func parseUUIDParam(c echo.Context, name string) (uuid.UUID, error) {
+	return parseUUIDBody(c.Param(name), name)
+}
+
+// parseUUIDBody parses value, taken from the request body field named field, as a UUID.
+func parseUUIDBody(value, field string) (uuid.UUID, error) {
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: fmt.Sprintf("invalid %s: must be a UUID", field),
+			Code:  errors.CodeInvalidUUID,
+		})
+	}
+	return id, nil
+}
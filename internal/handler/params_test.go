@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"paytabs/internal/errors"
+)
+
+func TestInvalidUUIDs_ReturnTheSameError(t *testing.T) {
+	callerID := uuid.NewString()
+	validID := uuid.NewString()
+
+	tests := []struct {
+		name      string
+		method    string
+		body      string
+		pathParam bool
+		field     string
+		call      func(c echo.Context) error
+	}{
+		{name: "account balance", method: http.MethodGet, pathParam: true, field: "id", call: NewAccountHandler(nil, nil).GetBalance},
+		{name: "card balance history", method: http.MethodGet, pathParam: true, field: "id", call: NewCardHandler(nil).GetBalanceHistory},
+		{name: "payment timeline", method: http.MethodGet, pathParam: true, field: "id", call: NewPaymentHandler(nil).GetPaymentTimeline},
+		{name: "payment retry", method: http.MethodPost, pathParam: true, field: "id", call: NewPaymentHandler(nil).RetryPayment},
+		{name: "payment refund", method: http.MethodPost, pathParam: true, field: "id", call: NewPaymentHandler(nil).RefundPayment},
+		{name: "account transfers", method: http.MethodGet, pathParam: true, field: "id", call: NewTransferHandler(nil).ListAccountTransfers},
+		{
+			name: "payment merchant", method: http.MethodPost, field: "merchant_account_id",
+			body: `{"merchant_account_id":"not-a-uuid","card_id":"` + validID + `","amount":"1.00"}`,
+			call: NewPaymentHandler(nil).ProcessCardPayment,
+		},
+		{
+			name: "payment card", method: http.MethodPost, field: "card_id",
+			body: `{"merchant_account_id":"` + validID + `","card_id":"not-a-uuid","amount":"1.00"}`,
+			call: NewPaymentHandler(nil).ProcessCardPayment,
+		},
+		{
+			name: "transfer source", method: http.MethodPost, field: "source_card_id",
+			body: `{"source_card_id":"not-a-uuid","destination_card_id":"` + validID + `","amount":"1.00"}`,
+			call: NewTransferHandler(nil).ProcessTransfer,
+		},
+		{
+			name: "transfer destination", method: http.MethodPost, field: "destination_card_id",
+			body: `{"source_card_id":"` + validID + `","destination_card_id":"not-a-uuid","amount":"1.00"}`,
+			call: NewTransferHandler(nil).ValidateTransfer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			c, _ := newTestContext(tt.method, "/api/test", body, callerID)
+			if tt.pathParam {
+				c.SetParamNames(tt.field)
+				c.SetParamValues("not-a-uuid")
+			}
+
+			err := tt.call(c)
+
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+			assert.Equal(t, errors.ErrorResponse{
+				Error: "invalid " + tt.field + ": must be a UUID",
+				Code:  errors.CodeInvalidUUID,
+			}, httpErr.Message)
+		})
+	}
+}
@@ -1,13 +1,22 @@
 package handler
 
 import (
+	"bytes"
+	stderrors "errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/shopspring/decimal"
 
 	"paytabs/internal/errors"
+	"paytabs/internal/middleware"
+	"paytabs/internal/model"
+	"paytabs/internal/repository"
 	"paytabs/internal/service"
 )
 
@@ -21,65 +30,115 @@ func NewPaymentHandler(paymentService service.PaymentService) *PaymentHandler {
 	return &PaymentHandler{paymentService: paymentService}
 }
 
-// CardPaymentRequest represents a card payment request.
+// CardPaymentRequest represents a card payment request. The payment is always credited to
+// the authenticated merchant.
 type CardPaymentRequest struct {
-	MerchantAccountID string `json:"merchant_account_id" validate:"required,uuid"`
-	CardID            string `json:"card_id" validate:"required,uuid"`
+	// MerchantAccountID is optional; when given it must be the authenticated account.
+	MerchantAccountID string `json:"merchant_account_id,omitempty"`
+	CardID            string `json:"card_id" validate:"required"`
 	Amount            string `json:"amount" validate:"required"`
+	// MerchantReference is the merchant's own reference, e.g. an order number. A merchant can
+	// have only one payment per reference.
+	MerchantReference string `json:"merchant_reference,omitempty" validate:"max=64"`
 }
 
 // PaymentResponse represents a payment response.
 type PaymentResponse struct {
-	PaymentID string `json:"payment_id"`
-	Status    string `json:"status"`
-	Message   string `json:"message"`
+	PaymentID   string `json:"payment_id"`
+	Status      string `json:"status"`
+	Message     string `json:"message"`
+	Amount      string `json:"amount"`
+	FeeAmount   string `json:"fee_amount"`
+	GrossAmount string `json:"gross_amount"` // Charged to the card
+	NetAmount   string `json:"net_amount"`   // Credited to the merchant
+	RetryOfID   string `json:"retry_of_id,omitempty"`
+	RefundOfID  string `json:"refund_of_id,omitempty"`
+	TestMode    bool   `json:"test_mode,omitempty"` // No money moved
+
+	MerchantReference string `json:"merchant_reference,omitempty"`
+}
+
+// newPaymentResponse builds the client-facing view of a payment.
+func newPaymentResponse(payment *model.Payment) PaymentResponse {
+	resp := PaymentResponse{
+		PaymentID:   payment.ID.String(),
+		Status:      string(payment.Status),
+		Message:     paymentStatusMessage(payment.Status),
+		Amount:      payment.Amount.StringFixed(2),
+		FeeAmount:   payment.FeeAmount.StringFixed(2),
+		GrossAmount: payment.GrossAmount.StringFixed(2),
+		NetAmount:   payment.NetAmount.StringFixed(2),
+		TestMode:    payment.TestMode,
+	}
+	if payment.RetryOfID != nil {
+		resp.RetryOfID = payment.RetryOfID.String()
+	}
+	if payment.RefundOfID != nil {
+		resp.RefundOfID = payment.RefundOfID.String()
+	}
+	if payment.MerchantReference != nil {
+		resp.MerchantReference = *payment.MerchantReference
+	}
+	return resp
 }
 
 // ProcessCardPayment godoc
 // @Summary Process a card payment
+// @Description Charges the card and credits the authenticated merchant. A merchant_account_id naming any other account is refused with 403.
 // @Tags payments
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param request body CardPaymentRequest true "Payment data"
-// @Success 200 {object} PaymentResponse
+// @Param Idempotency-Key header string false "Mapped to the payment it creates; repeating it returns that payment"
+// @Success 201 {object} PaymentResponse
+// @Header 201 {string} Location "/api/payments/{id}"
 // @Failure 400 {object} errors.ErrorResponse
 // @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
 // @Failure 404 {object} errors.ErrorResponse
+// @Failure 409 {object} errors.ErrorResponse
+// @Failure 423 {object} errors.ErrorResponse
 // @Failure 500 {object} errors.ErrorResponse
+// @Failure 503 {object} errors.ErrorResponse
 // @Router /payments/card [post]
 func (h *PaymentHandler) ProcessCardPayment(c echo.Context) error {
 	var req CardPaymentRequest
 	if err := c.Bind(&req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
 			Error: "invalid request body",
-			Code:  "INVALID_REQUEST",
+			Code:  errors.CodeInvalidRequest,
 		})
 	}
 
 	if err := c.Validate(&req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
 			Error: err.Error(),
-			Code:  "VALIDATION_ERROR",
+			Code:  errors.CodeValidationError,
 		})
 	}
 
-	// Parse merchant account ID
-	merchantAccountID, err := uuid.Parse(req.MerchantAccountID)
+	cardID, err := parseUUIDBody(req.CardID, "card_id")
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
-			Error: "invalid merchant_account_id",
-			Code:  "INVALID_UUID",
-		})
+		return err
 	}
 
-	// Parse card ID
-	cardID, err := uuid.Parse(req.CardID)
+	// Payments are credited to the caller; naming another merchant must not attribute them elsewhere
+	merchantAccountID, err := accountIDFromContext(c)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
-			Error: "invalid card_id",
-			Code:  "INVALID_UUID",
-		})
+		return err
+	}
+	if req.MerchantAccountID != "" {
+		named, err := parseUUIDBody(req.MerchantAccountID, "merchant_account_id")
+		if err != nil {
+			return err
+		}
+		if named != merchantAccountID {
+			return echo.NewHTTPError(http.StatusForbidden, errors.ErrorResponse{
+				Error: "payments can only be credited to the authenticated merchant account",
+				Code:  errors.CodeForbidden,
+			})
+		}
 	}
 
 	// Parse amount
@@ -87,16 +146,25 @@ func (h *PaymentHandler) ProcessCardPayment(c echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
 			Error: "invalid amount",
-			Code:  "INVALID_AMOUNT",
+			Code:  errors.CodeInvalidAmount,
 		})
 	}
 
+	// The service stores the key on the payment it creates, so a retry returns that payment
+	// rather than charging the card again
+	idempotencyKey, err := idempotencyKeyFromHeader(c)
+	if err != nil {
+		return err
+	}
+
 	// Process payment
 	payment, err := h.paymentService.ProcessCardPayment(
 		c.Request().Context(),
 		merchantAccountID,
 		cardID,
 		amount,
+		strings.TrimSpace(req.MerchantReference),
+		idempotencyKey,
 	)
 
 	if err != nil {
@@ -104,16 +172,839 @@ func (h *PaymentHandler) ProcessCardPayment(c echo.Context) error {
 		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
 	}
 
-	status := "accepted"
-	message := "Payment processed successfully"
-	if payment.Status == "failed" {
-		status = "failed"
-		message = "Payment processing failed"
+	setLocation(c, "/api/payments/"+payment.ID.String())
+	return c.JSON(http.StatusCreated, newPaymentResponse(payment))
+}
+
+// RetryPayment godoc
+// @Summary Retry a failed payment
+// @Description Re-runs a payment of the authenticated merchant that failed for a transient reason, as a new payment linked to it by retry_of_id. Business rejections such as insufficient balance cannot be retried.
+// @Tags payments
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Failed payment ID"
+// @Param Idempotency-Key header string false "Replays the stored response for a repeated key"
+// @Success 201 {object} PaymentResponse
+// @Header 201 {string} Location "/api/payments/{id}"
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 409 {object} errors.ErrorResponse
+// @Failure 422 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Failure 503 {object} errors.ErrorResponse
+// @Router /payments/{id}/retry [post]
+func (h *PaymentHandler) RetryPayment(c echo.Context) error {
+	paymentID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	merchantAccountID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	payment, err := h.paymentService.RetryPayment(c.Request().Context(), merchantAccountID, paymentID)
+	if err != nil {
+		var notRetryable *service.PaymentNotRetryableError
+		switch {
+		case stderrors.As(err, &notRetryable):
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, errors.ErrorResponse{
+				Error: err.Error(),
+				Code:  errors.CodePaymentNotRetryable,
+			})
+		case err == service.ErrPaymentNotFailed:
+			return echo.NewHTTPError(http.StatusConflict, errors.ErrorResponse{
+				Error: err.Error(),
+				Code:  errors.CodePaymentNotFailed,
+			})
+		case err == service.ErrPaymentAlreadyRetried:
+			return echo.NewHTTPError(http.StatusConflict, errors.ErrorResponse{
+				Error: err.Error(),
+				Code:  errors.CodePaymentAlreadyRetried,
+			})
+		}
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+
+	setLocation(c, "/api/payments/"+payment.ID.String())
+	return c.JSON(http.StatusCreated, newPaymentResponse(payment))
+}
+
+// RefundPaymentRequest is a refund of an accepted payment.
+type RefundPaymentRequest struct {
+	// Amount is the part of the payment to refund; omitted, whatever is left is refunded.
+	Amount string `json:"amount,omitempty"`
+}
+
+// RefundPayment godoc
+// @Summary Refund an accepted payment
+// @Description Refunds an accepted payment of the authenticated merchant, in full or in part, as a new payment in status refunded linked to it by refund_of_id. The card is credited and the merchant debited the refunded amount; processing fees are not returned. Refunds together cannot exceed the payment amount.
+// @Tags payments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Payment ID"
+// @Param request body RefundPaymentRequest false "Amount to refund; omitted for a full refund"
+// @Param Idempotency-Key header string false "Stored with the refund; repeating it for the same payment returns the existing refund"
+// @Success 201 {object} PaymentDetailResponse
+// @Header 201 {string} Location "/api/payments/{id}"
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 409 {object} errors.ErrorResponse
+// @Failure 422 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Failure 503 {object} errors.ErrorResponse
+// @Router /payments/{id}/refund [post]
+func (h *PaymentHandler) RefundPayment(c echo.Context) error {
+	paymentID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	merchantAccountID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	var req RefundPaymentRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: "invalid request body",
+			Code:  errors.CodeInvalidRequest,
+		})
+	}
+
+	// Zero asks the service for a full refund, so an explicit amount must be positive
+	amount := decimal.Zero
+	if req.Amount != "" {
+		amount, err = decimal.NewFromString(req.Amount)
+		if err != nil || !amount.IsPositive() {
+			return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+				Error: "invalid amount",
+				Code:  errors.CodeInvalidAmount,
+			})
+		}
+	}
+
+	// The key is stored with the refund, so retries are deduplicated even once the cached
+	// response of the idempotency middleware has expired
+	idempotencyKey, err := idempotencyKeyFromHeader(c)
+	if err != nil {
+		return err
+	}
+
+	refund, err := h.paymentService.RefundPayment(c.Request().Context(), merchantAccountID, paymentID, amount, idempotencyKey)
+	if err != nil {
+		switch {
+		case err == service.ErrRefundKeyReused:
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, errors.ErrorResponse{
+				Error: err.Error(),
+				Code:  errors.CodeIdempotencyKeyReused,
+			})
+		case err == service.ErrPaymentNotRefundable:
+			return echo.NewHTTPError(http.StatusConflict, errors.ErrorResponse{
+				Error: err.Error(),
+				Code:  errors.CodePaymentNotRefundable,
+			})
+		case err == service.ErrRefundExceedsOriginal:
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, errors.ErrorResponse{
+				Error: err.Error(),
+				Code:  errors.CodeRefundExceedsOriginal,
+			})
+		}
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+
+	setLocation(c, "/api/payments/"+refund.ID.String())
+	return c.JSON(http.StatusCreated, newPaymentDetailResponse(refund))
+}
+
+// CancelPayment godoc
+// @Summary Cancel a pending payment
+// @Description Voids a pending payment of the authenticated merchant before it settles. Accepted, failed, and already-cancelled payments cannot be cancelled.
+// @Tags payments
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Payment ID"
+// @Success 200 {object} PaymentDetailResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 409 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /payments/{id}/cancel [post]
+func (h *PaymentHandler) CancelPayment(c echo.Context) error {
+	paymentID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	merchantAccountID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	payment, err := h.paymentService.CancelPayment(c.Request().Context(), merchantAccountID, paymentID)
+	if err != nil {
+		switch {
+		case err == service.ErrPaymentNotCancellable:
+			return echo.NewHTTPError(http.StatusConflict, errors.ErrorResponse{
+				Error: err.Error(),
+				Code:  errors.CodePaymentNotCancellable,
+			})
+		case err == service.ErrPaymentAlreadyCancelled:
+			return echo.NewHTTPError(http.StatusConflict, errors.ErrorResponse{
+				Error: err.Error(),
+				Code:  errors.CodePaymentAlreadyCancelled,
+			})
+		}
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+
+	return c.JSON(http.StatusOK, newPaymentDetailResponse(payment))
+}
+
+// GetPayment godoc
+// @Summary Get a payment
+// @Description A payment owned by the authenticated merchant; other callers get 404.
+// @Tags payments
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Payment ID"
+// @Success 200 {object} PaymentDetailResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /payments/{id} [get]
+func (h *PaymentHandler) GetPayment(c echo.Context) error {
+	paymentID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	merchantAccountID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	payment, err := h.paymentService.GetPayment(c.Request().Context(), merchantAccountID, paymentID)
+	if err != nil {
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+
+	return c.JSON(http.StatusOK, newPaymentDetailResponse(payment))
+}
+
+// PaymentListItem represents a payment in a listing.
+type PaymentListItem struct {
+	PaymentResponse
+	MerchantAccountID string    `json:"merchant_account_id"`
+	CardID            string    `json:"card_id"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// PaymentDetailResponse represents a single payment with its parties and timestamps.
+type PaymentDetailResponse struct {
+	PaymentListItem
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// newPaymentDetailResponse builds the client-facing view of a single looked-up payment.
+func newPaymentDetailResponse(payment *model.Payment) PaymentDetailResponse {
+	return PaymentDetailResponse{
+		PaymentListItem: PaymentListItem{
+			PaymentResponse:   newPaymentResponse(payment),
+			MerchantAccountID: payment.MerchantAccountID.String(),
+			CardID:            payment.CardID.String(),
+			CreatedAt:         payment.CreatedAt,
+		},
+		UpdatedAt: payment.UpdatedAt,
+	}
+}
+
+// PaymentListResponse represents a page of payments.
+type PaymentListResponse struct {
+	Payments []PaymentListItem `json:"payments"`
+	Total    int64             `json:"total"`
+	Limit    int               `json:"limit"`
+	Offset   int               `json:"offset"`
+}
+
+// ListAccountPayments godoc
+// @Summary List an account's payments
+// @Description Payments the account received as a merchant or made with one of its cards, newest first. Archived payments are left out unless include_archived is true.
+// @Tags payments
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Account ID"
+// @Param include_archived query bool false "Also list archived payments"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param offset query int false "Number of payments to skip"
+// @Success 200 {object} PaymentListResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /accounts/{id}/payments [get]
+func (h *PaymentHandler) ListAccountPayments(c echo.Context) error {
+	accountID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := requireAccountOwner(c, accountID); err != nil {
+		return err
+	}
+
+	return h.listPayments(c, accountID)
+}
+
+// ListMyPayments godoc
+// @Summary List the authenticated account's payments
+// @Description Same as GET /accounts/{id}/payments for the caller's own account.
+// @Tags payments
+// @Produce json
+// @Security BearerAuth
+// @Param include_archived query bool false "Also list archived payments"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param offset query int false "Number of payments to skip"
+// @Success 200 {object} PaymentListResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /me/payments [get]
+func (h *PaymentHandler) ListMyPayments(c echo.Context) error {
+	accountID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	return h.listPayments(c, accountID)
+}
+
+func (h *PaymentHandler) listPayments(c echo.Context, accountID uuid.UUID) error {
+	includeArchived, err := parseIncludeArchived(c)
+	if err != nil {
+		return err
+	}
+
+	limit, offset, err := parsePagination(c)
+	if err != nil {
+		return err
+	}
+
+	payments, total, err := h.paymentService.ListAccountPayments(c.Request().Context(), accountID, includeArchived, limit, offset)
+	if err != nil {
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
 	}
 
-	return c.JSON(http.StatusOK, PaymentResponse{
-		PaymentID: payment.ID.String(),
-		Status:    status,
-		Message:   message,
+	return c.JSON(http.StatusOK, PaymentListResponse{
+		Payments: newPaymentListItems(payments),
+		Total:    total,
+		Limit:    limit,
+		Offset:   offset,
 	})
 }
+
+// newPaymentListItems builds the listing view of payments.
+func newPaymentListItems(payments []model.Payment) []PaymentListItem {
+	items := make([]PaymentListItem, 0, len(payments))
+	for i := range payments {
+		items = append(items, PaymentListItem{
+			PaymentResponse:   newPaymentResponse(&payments[i]),
+			MerchantAccountID: payments[i].MerchantAccountID.String(),
+			CardID:            payments[i].CardID.String(),
+			CreatedAt:         payments[i].CreatedAt,
+		})
+	}
+	return items
+}
+
+// ListMerchantPayments godoc
+// @Summary List the authenticated merchant's payments
+// @Description Payments the merchant received, newest first, optionally only those in one status or created within [from, to]. Archived payments are left out unless include_archived is true.
+// @Tags payments
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Only payments in this status (pending, accepted, failed, cancelled, refunded)"
+// @Param from query string false "Only payments created at or after this RFC3339 time"
+// @Param to query string false "Only payments created at or before this RFC3339 time"
+// @Param include_archived query bool false "Also list archived payments"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param offset query int false "Number of payments to skip"
+// @Success 200 {object} PaymentListResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /payments [get]
+func (h *PaymentHandler) ListMerchantPayments(c echo.Context) error {
+	merchantAccountID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	filter, err := parsePaymentFilter(c)
+	if err != nil {
+		return err
+	}
+
+	limit, offset, err := parsePagination(c)
+	if err != nil {
+		return err
+	}
+
+	payments, total, err := h.paymentService.ListMerchantPayments(c.Request().Context(), merchantAccountID, filter, limit, offset)
+	if err != nil {
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+
+	return c.JSON(http.StatusOK, PaymentListResponse{
+		Payments: newPaymentListItems(payments),
+		Total:    total,
+		Limit:    limit,
+		Offset:   offset,
+	})
+}
+
+// parsePaymentFilter reads the optional status, from, to and include_archived query parameters.
+func parsePaymentFilter(c echo.Context) (repository.PaymentFilter, error) {
+	var filter repository.PaymentFilter
+
+	includeArchived, err := parseIncludeArchived(c)
+	if err != nil {
+		return filter, err
+	}
+	filter.IncludeArchived = includeArchived
+
+	if v := c.QueryParam("status"); v != "" {
+		status := model.PaymentStatus(v)
+		switch status {
+		case model.PaymentStatusPending, model.PaymentStatusAccepted, model.PaymentStatusFailed,
+			model.PaymentStatusCancelled, model.PaymentStatusRefunded:
+		default:
+			return filter, echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+				Error: "status must be one of pending, accepted, failed, cancelled, refunded",
+				Code:  errors.CodeValidationError,
+			})
+		}
+		filter.Status = &status
+	}
+
+	invalid := func(msg string) error {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: msg,
+			Code:  errors.CodeInvalidDateRange,
+		})
+	}
+	if v := c.QueryParam("from"); v != "" {
+		if filter.From, err = time.Parse(time.RFC3339, v); err != nil {
+			return filter, invalid("from must be an RFC3339 timestamp")
+		}
+	}
+	if v := c.QueryParam("to"); v != "" {
+		if filter.To, err = time.Parse(time.RFC3339, v); err != nil {
+			return filter, invalid("to must be an RFC3339 timestamp")
+		}
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.From.After(filter.To) {
+		return filter, invalid("from must not be after to")
+	}
+
+	return filter, nil
+}
+
+// parseIncludeArchived reads the optional include_archived query parameter, false by default.
+func parseIncludeArchived(c echo.Context) (bool, error) {
+	v := c.QueryParam("include_archived")
+	if v == "" {
+		return false, nil
+	}
+	include, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: "include_archived must be true or false",
+			Code:  errors.CodeValidationError,
+		})
+	}
+	return include, nil
+}
+
+// CardPaymentItem is a payment in a card's payment history.
+type CardPaymentItem struct {
+	PaymentID         string    `json:"payment_id"`
+	MerchantAccountID string    `json:"merchant_account_id"`
+	MerchantName      string    `json:"merchant_name"`
+	Amount            string    `json:"amount"`
+	GrossAmount       string    `json:"gross_amount"` // Charged to the card
+	Status            string    `json:"status"`
+	FailureReason     string    `json:"failure_reason,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// CardPaymentListResponse represents a page of a card's payments.
+type CardPaymentListResponse struct {
+	Payments []CardPaymentItem `json:"payments"`
+	Total    int64             `json:"total"`
+	Limit    int               `json:"limit"`
+	Offset   int               `json:"offset"`
+}
+
+// ListCardPayments godoc
+// @Summary List a card's payments
+// @Description Accepted and failed payments made with the card, newest first, for the card's owner. Archived and test-mode payments are left out.
+// @Tags payments
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Card ID"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param offset query int false "Number of payments to skip"
+// @Success 200 {object} CardPaymentListResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /cards/{id}/payments [get]
+func (h *PaymentHandler) ListCardPayments(c echo.Context) error {
+	cardID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	limit, offset, err := parsePagination(c)
+	if err != nil {
+		return err
+	}
+
+	page, err := h.paymentService.ListCardPayments(c.Request().Context(), cardID, limit, offset)
+	if err != nil {
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+
+	if err := requireAccountOwner(c, page.Card.AccountID); err != nil {
+		return err
+	}
+
+	items := make([]CardPaymentItem, 0, len(page.Payments))
+	for _, payment := range page.Payments {
+		items = append(items, CardPaymentItem{
+			PaymentID:         payment.ID.String(),
+			MerchantAccountID: payment.MerchantAccountID.String(),
+			MerchantName:      payment.MerchantName,
+			Amount:            payment.Amount.StringFixed(2),
+			GrossAmount:       payment.GrossAmount.StringFixed(2),
+			Status:            string(payment.Status),
+			FailureReason:     string(payment.FailureReason),
+			CreatedAt:         payment.CreatedAt,
+		})
+	}
+
+	return c.JSON(http.StatusOK, CardPaymentListResponse{
+		Payments: items,
+		Total:    page.Total,
+		Limit:    limit,
+		Offset:   offset,
+	})
+}
+
+// PaymentTimelineEvent is one status transition of a payment.
+type PaymentTimelineEvent struct {
+	Status    string    `json:"status"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// PaymentTimelineResponse lists a payment's status transitions, oldest first.
+type PaymentTimelineResponse struct {
+	PaymentID string                 `json:"payment_id"`
+	Status    string                 `json:"status"`
+	Events    []PaymentTimelineEvent `json:"events"`
+}
+
+// GetPaymentTimeline godoc
+// @Summary Get a payment's status timeline
+// @Description Ordered status transitions (pending, then accepted or failed with the reason) for a payment owned by the authenticated merchant.
+// @Tags payments
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Payment ID"
+// @Success 200 {object} PaymentTimelineResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /payments/{id}/timeline [get]
+func (h *PaymentHandler) GetPaymentTimeline(c echo.Context) error {
+	paymentID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	merchantAccountID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	timeline, err := h.paymentService.GetPaymentTimeline(c.Request().Context(), merchantAccountID, paymentID)
+	if err != nil {
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+
+	events := make([]PaymentTimelineEvent, 0, len(timeline.Events))
+	for _, event := range timeline.Events {
+		message := event.Message
+		if message == "" {
+			message = paymentStatusMessage(event.Status)
+		}
+		events = append(events, PaymentTimelineEvent{
+			Status:    string(event.Status),
+			Message:   message,
+			Timestamp: event.At,
+		})
+	}
+
+	return c.JSON(http.StatusOK, PaymentTimelineResponse{
+		PaymentID: timeline.Payment.ID.String(),
+		Status:    string(timeline.Payment.Status),
+		Events:    events,
+	})
+}
+
+// PaymentLineItemResponse is one component of a payment breakdown.
+type PaymentLineItemResponse struct {
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Amount      string `json:"amount"`
+}
+
+// PaymentBreakdownResponse itemizes a payment: the gross charged to the card, less
+// fee_amount, equals the net credited to the merchant.
+type PaymentBreakdownResponse struct {
+	PaymentID   string                    `json:"payment_id"`
+	Status      string                    `json:"status"`
+	Amount      string                    `json:"amount"`
+	FeePaidBy   string                    `json:"fee_paid_by"`
+	GrossAmount string                    `json:"gross_amount"`
+	FeeAmount   string                    `json:"fee_amount"`
+	NetAmount   string                    `json:"net_amount"`
+	LineItems   []PaymentLineItemResponse `json:"line_items"`
+}
+
+// paymentLineDescriptions label breakdown line items for display.
+var paymentLineDescriptions = map[service.PaymentLineKind]string{
+	service.PaymentLineGross:         "Charged to card",
+	service.PaymentLineProcessingFee: "Processing fee",
+	service.PaymentLineNet:           "Credited to merchant",
+}
+
+// GetPaymentBreakdown godoc
+// @Summary Get the line-item breakdown of a payment
+// @Description The gross charged to the card, the processing fee and the net credited to the merchant, as stored when the payment was processed; fee changes since do not alter them. fee_paid_by is customer when the fee was added to the card charge. A payment owned by another merchant is 404.
+// @Tags payments
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Payment ID"
+// @Success 200 {object} PaymentBreakdownResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /payments/{id}/breakdown [get]
+func (h *PaymentHandler) GetPaymentBreakdown(c echo.Context) error {
+	paymentID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	merchantAccountID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	breakdown, err := h.paymentService.GetPaymentBreakdown(c.Request().Context(), merchantAccountID, paymentID)
+	if err != nil {
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+
+	lines := make([]PaymentLineItemResponse, 0, len(breakdown.Lines))
+	for _, line := range breakdown.Lines {
+		lines = append(lines, PaymentLineItemResponse{
+			Type:        string(line.Kind),
+			Description: paymentLineDescriptions[line.Kind],
+			Amount:      line.Amount.StringFixed(2),
+		})
+	}
+
+	payment := breakdown.Payment
+	return c.JSON(http.StatusOK, PaymentBreakdownResponse{
+		PaymentID:   payment.ID.String(),
+		Status:      string(payment.Status),
+		Amount:      payment.Amount.StringFixed(2),
+		FeePaidBy:   breakdown.FeePaidBy,
+		GrossAmount: payment.GrossAmount.StringFixed(2),
+		FeeAmount:   payment.FeeAmount.StringFixed(2),
+		NetAmount:   payment.NetAmount.StringFixed(2),
+		LineItems:   lines,
+	})
+}
+
+// PaymentReceiptResponse is a signed payment receipt. signature is the hex HMAC-SHA256 of
+// the canonical payload described in the README under RECEIPT_SIGNING_KEY.
+type PaymentReceiptResponse struct {
+	PaymentID          string    `json:"payment_id"`
+	MerchantAccountID  string    `json:"merchant_account_id"`
+	MerchantName       string    `json:"merchant_name"`
+	CardNumber         string    `json:"card_number"`
+	Currency           string    `json:"currency"`
+	Amount             string    `json:"amount"`
+	GrossAmount        string    `json:"gross_amount"`
+	FeeAmount          string    `json:"fee_amount"`
+	NetAmount          string    `json:"net_amount"`
+	Status             string    `json:"status"`
+	TestMode           bool      `json:"test_mode"`
+	CreatedAt          time.Time `json:"created_at"`
+	Signature          string    `json:"signature"`
+	SignatureAlgorithm string    `json:"signature_algorithm"`
+}
+
+// GetPaymentReceipt godoc
+// @Summary Get a signed payment receipt
+// @Description A payment owned by the authenticated merchant, with an HMAC-SHA256 signature customers can have verified. See /payments/{id}/receipt.pdf for a printable copy.
+// @Tags payments
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Payment ID"
+// @Success 200 {object} PaymentReceiptResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Failure 503 {object} errors.ErrorResponse
+// @Router /payments/{id}/receipt [get]
+func (h *PaymentHandler) GetPaymentReceipt(c echo.Context) error {
+	receipt, err := h.paymentReceipt(c)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, PaymentReceiptResponse{
+		PaymentID:          receipt.PaymentID.String(),
+		MerchantAccountID:  receipt.MerchantAccountID.String(),
+		MerchantName:       receipt.MerchantName,
+		CardNumber:         receipt.CardNumber,
+		Currency:           receipt.Currency,
+		Amount:             receipt.Amount.StringFixed(2),
+		GrossAmount:        receipt.GrossAmount.StringFixed(2),
+		FeeAmount:          receipt.FeeAmount.StringFixed(2),
+		NetAmount:          receipt.NetAmount.StringFixed(2),
+		Status:             string(receipt.Status),
+		TestMode:           receipt.TestMode,
+		CreatedAt:          receipt.CreatedAt,
+		Signature:          receipt.Signature,
+		SignatureAlgorithm: service.ReceiptSignatureAlgorithm,
+	})
+}
+
+// GetPaymentReceiptPDF godoc
+// @Summary Download a signed payment receipt as PDF
+// @Description The receipt of GET /payments/{id}/receipt rendered as a PDF attachment; the signature is printed on it and sent in X-Receipt-Signature.
+// @Tags payments
+// @Produce application/pdf
+// @Security BearerAuth
+// @Param id path string true "Payment ID"
+// @Success 200 {file} file
+// @Header 200 {string} X-Receipt-Signature "Hex HMAC-SHA256 of the receipt"
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Failure 503 {object} errors.ErrorResponse
+// @Router /payments/{id}/receipt.pdf [get]
+func (h *PaymentHandler) GetPaymentReceiptPDF(c echo.Context) error {
+	receipt, err := h.paymentReceipt(c)
+	if err != nil {
+		return err
+	}
+
+	// Render fully before writing so a rendering error can still be reported as JSON
+	var buf bytes.Buffer
+	if err := writeReceiptPDF(&buf, receipt); err != nil {
+		return err
+	}
+
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="receipt-%s.pdf"`, receipt.PaymentID))
+	header.Set("X-Receipt-Signature", receipt.Signature)
+	return c.Stream(http.StatusOK, "application/pdf", &buf)
+}
+
+// paymentReceipt loads the signed receipt of the payment named by the id param for the
+// authenticated merchant.
+func (h *PaymentHandler) paymentReceipt(c echo.Context) (*service.PaymentReceipt, error) {
+	paymentID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	merchantAccountID, err := accountIDFromContext(c)
+	if err != nil {
+		return nil, err
+	}
+
+	receipt, err := h.paymentService.GetPaymentReceipt(c.Request().Context(), merchantAccountID, paymentID)
+	if err != nil {
+		if err == service.ErrReceiptsDisabled {
+			return nil, echo.NewHTTPError(http.StatusServiceUnavailable, errors.ErrorResponse{
+				Error: err.Error(),
+				Code:  errors.CodeReceiptsDisabled,
+			})
+		}
+		httpErr := errors.MapErrorToHTTP(err)
+		return nil, echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+	return receipt, nil
+}
+
+// idempotencyKeyFromHeader returns the request's Idempotency-Key, empty when it has none.
+func idempotencyKeyFromHeader(c echo.Context) (string, error) {
+	key := c.Request().Header.Get(middleware.IdempotencyKeyHeader)
+	if len(key) > model.MaxIdempotencyKeyLength {
+		return "", echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: fmt.Sprintf("%s must be at most %d characters", middleware.IdempotencyKeyHeader, model.MaxIdempotencyKeyLength),
+			Code:  errors.CodeValidationError,
+		})
+	}
+	return key, nil
+}
+
+// paymentStatusMessage returns the client-facing message for a payment status.
+func paymentStatusMessage(status model.PaymentStatus) string {
+	switch status {
+	case model.PaymentStatusAccepted:
+		return "Payment processed successfully"
+	case model.PaymentStatusPending:
+		return "Payment is pending"
+	case model.PaymentStatusFailed:
+		return "Payment processing failed"
+	case model.PaymentStatusCancelled:
+		return "Payment was cancelled"
+	case model.PaymentStatusRefunded:
+		return "Payment refunded successfully"
+	default:
+		return "Payment status: " + string(status)
+	}
+}
@@ -0,0 +1,764 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"paytabs/internal/errors"
+	"paytabs/internal/model"
+	"paytabs/internal/repository"
+	"paytabs/internal/service"
+)
+
+func TestPaymentHandler_ProcessCardPayment_Status(t *testing.T) {
+	tests := []struct {
+		name           string
+		paymentStatus  model.PaymentStatus
+		expectedStatus string
+	}{
+		{name: "accepted payment", paymentStatus: model.PaymentStatusAccepted, expectedStatus: "accepted"},
+		{name: "pending payment is not reported as accepted", paymentStatus: model.PaymentStatusPending, expectedStatus: "pending"},
+		{name: "failed payment", paymentStatus: model.PaymentStatusFailed, expectedStatus: "failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merchantID := uuid.New()
+			cardID := uuid.New()
+			payment := &model.Payment{ID: uuid.New(), Status: tt.paymentStatus}
+
+			svc := new(MockPaymentService)
+			svc.On("ProcessCardPayment", mock.Anything, merchantID, cardID, mock.Anything, "", "").Return(payment, nil)
+
+			body := `{"merchant_account_id":"` + merchantID.String() + `","card_id":"` + cardID.String() + `","amount":"10.00"}`
+			c, rec := newTestContext(http.MethodPost, "/api/payments/card", strings.NewReader(body), merchantID.String())
+
+			h := NewPaymentHandler(svc)
+			require.NoError(t, h.ProcessCardPayment(c))
+
+			var resp PaymentResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, http.StatusCreated, rec.Code)
+			assert.Equal(t, "/api/payments/"+payment.ID.String(), rec.Header().Get(echo.HeaderLocation))
+			assert.Equal(t, tt.expectedStatus, resp.Status)
+			assert.Equal(t, payment.ID.String(), resp.PaymentID)
+		})
+	}
+}
+
+func TestPaymentHandler_ProcessCardPayment_MerchantReference(t *testing.T) {
+	merchantID := uuid.New()
+	cardID := uuid.New()
+	reference := "order-1001"
+	body := `{"merchant_account_id":"` + merchantID.String() + `","card_id":"` + cardID.String() + `","amount":"10.00","merchant_reference":" order-1001 "}`
+
+	t.Run("stored and returned", func(t *testing.T) {
+		svc := new(MockPaymentService)
+		svc.On("ProcessCardPayment", mock.Anything, merchantID, cardID, mock.Anything, reference, "").
+			Return(&model.Payment{ID: uuid.New(), Status: model.PaymentStatusAccepted, MerchantReference: &reference}, nil)
+
+		c, rec := newTestContext(http.MethodPost, "/api/payments/card", strings.NewReader(body), merchantID.String())
+		require.NoError(t, NewPaymentHandler(svc).ProcessCardPayment(c))
+
+		var resp PaymentResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, reference, resp.MerchantReference)
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		svc := new(MockPaymentService)
+		svc.On("ProcessCardPayment", mock.Anything, merchantID, cardID, mock.Anything, reference, "").
+			Return(nil, errors.ErrDuplicateReference)
+
+		c, _ := newTestContext(http.MethodPost, "/api/payments/card", strings.NewReader(body), merchantID.String())
+		err := NewPaymentHandler(svc).ProcessCardPayment(c)
+
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, http.StatusConflict, httpErr.Code)
+		assert.Equal(t, errors.CodeDuplicateReference, httpErr.Message.(errors.ErrorResponse).Code)
+	})
+}
+
+func TestPaymentHandler_ProcessCardPayment_CreditsCaller(t *testing.T) {
+	merchantID := uuid.New()
+	cardID := uuid.New()
+	svc := new(MockPaymentService)
+	svc.On("ProcessCardPayment", mock.Anything, merchantID, cardID, mock.Anything, "", "").
+		Return(&model.Payment{ID: uuid.New(), Status: model.PaymentStatusAccepted}, nil)
+
+	body := `{"card_id":"` + cardID.String() + `","amount":"10.00"}`
+	c, rec := newTestContext(http.MethodPost, "/api/payments/card", strings.NewReader(body), merchantID.String())
+	require.NoError(t, NewPaymentHandler(svc).ProcessCardPayment(c))
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	svc.AssertExpectations(t)
+}
+
+func TestPaymentHandler_ProcessCardPayment_ForgedMerchant(t *testing.T) {
+	svc := new(MockPaymentService)
+
+	body := `{"merchant_account_id":"` + uuid.NewString() + `","card_id":"` + uuid.NewString() + `","amount":"10.00"}`
+	c, _ := newTestContext(http.MethodPost, "/api/payments/card", strings.NewReader(body), uuid.NewString())
+	err := NewPaymentHandler(svc).ProcessCardPayment(c)
+
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusForbidden, httpErr.Code)
+	assert.Equal(t, errors.CodeForbidden, httpErr.Message.(errors.ErrorResponse).Code)
+	svc.AssertNotCalled(t, "ProcessCardPayment", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPaymentHandler_ProcessCardPayment_IdempotencyKey(t *testing.T) {
+	merchantID := uuid.New()
+	cardID := uuid.New()
+	body := `{"card_id":"` + cardID.String() + `","amount":"10.00"}`
+
+	t.Run("passed to the service", func(t *testing.T) {
+		svc := new(MockPaymentService)
+		svc.On("ProcessCardPayment", mock.Anything, merchantID, cardID, mock.Anything, "", "order-7-attempt").
+			Return(&model.Payment{ID: uuid.New(), Status: model.PaymentStatusAccepted}, nil)
+
+		c, rec := newTestContext(http.MethodPost, "/api/payments/card", strings.NewReader(body), merchantID.String())
+		c.Request().Header.Set("Idempotency-Key", "order-7-attempt")
+		require.NoError(t, NewPaymentHandler(svc).ProcessCardPayment(c))
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("too long", func(t *testing.T) {
+		svc := new(MockPaymentService)
+
+		c, _ := newTestContext(http.MethodPost, "/api/payments/card", strings.NewReader(body), merchantID.String())
+		c.Request().Header.Set("Idempotency-Key", strings.Repeat("k", model.MaxIdempotencyKeyLength+1))
+		err := NewPaymentHandler(svc).ProcessCardPayment(c)
+
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+		assert.Equal(t, errors.CodeValidationError, httpErr.Message.(errors.ErrorResponse).Code)
+		svc.AssertNotCalled(t, "ProcessCardPayment", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestPaymentHandler_GetPaymentTimeline(t *testing.T) {
+	merchantID := uuid.New()
+	paymentID := uuid.New()
+	created := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	svc := new(MockPaymentService)
+	svc.On("GetPaymentTimeline", mock.Anything, merchantID, paymentID).Return(&service.PaymentTimeline{
+		Payment: &model.Payment{ID: paymentID, Status: model.PaymentStatusFailed},
+		Events: []service.PaymentEvent{
+			{Status: model.PaymentStatusPending, Message: "payment created", At: created},
+			{Status: model.PaymentStatusFailed, Message: "insufficient balance", At: created.Add(time.Second)},
+		},
+	}, nil)
+
+	c, rec := newTestContext(http.MethodGet, "/api/payments/"+paymentID.String()+"/timeline", nil, merchantID.String())
+	c.SetParamNames("id")
+	c.SetParamValues(paymentID.String())
+	require.NoError(t, NewPaymentHandler(svc).GetPaymentTimeline(c))
+
+	var resp PaymentTimelineResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "failed", resp.Status)
+	require.Len(t, resp.Events, 2)
+	assert.Equal(t, "pending", resp.Events[0].Status)
+	assert.Equal(t, "failed", resp.Events[1].Status)
+	assert.Equal(t, "insufficient balance", resp.Events[1].Message)
+	assert.True(t, resp.Events[1].Timestamp.Equal(created.Add(time.Second)))
+}
+
+func TestPaymentHandler_GetPaymentTimeline_NotFound(t *testing.T) {
+	merchantID := uuid.New()
+	paymentID := uuid.New()
+
+	svc := new(MockPaymentService)
+	svc.On("GetPaymentTimeline", mock.Anything, merchantID, paymentID).Return(nil, errors.ErrPaymentNotFound)
+
+	c, _ := newTestContext(http.MethodGet, "/api/payments/"+paymentID.String()+"/timeline", nil, merchantID.String())
+	c.SetParamNames("id")
+	c.SetParamValues(paymentID.String())
+	err := NewPaymentHandler(svc).GetPaymentTimeline(c)
+
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.Code)
+}
+
+func TestPaymentHandler_GetPaymentBreakdown(t *testing.T) {
+	merchantID := uuid.New()
+	payment := &model.Payment{
+		ID:          uuid.New(),
+		Status:      model.PaymentStatusAccepted,
+		Amount:      decimal.RequireFromString("100"),
+		GrossAmount: decimal.RequireFromString("100"),
+		FeeAmount:   decimal.RequireFromString("3.2"),
+		NetAmount:   decimal.RequireFromString("96.8"),
+	}
+
+	svc := new(MockPaymentService)
+	svc.On("GetPaymentBreakdown", mock.Anything, merchantID, payment.ID).Return(service.NewPaymentBreakdown(payment), nil)
+
+	c, rec := newTestContext(http.MethodGet, "/api/payments/"+payment.ID.String()+"/breakdown", nil, merchantID.String())
+	c.SetParamNames("id")
+	c.SetParamValues(payment.ID.String())
+	require.NoError(t, NewPaymentHandler(svc).GetPaymentBreakdown(c))
+
+	var resp PaymentBreakdownResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, PaymentBreakdownResponse{
+		PaymentID:   payment.ID.String(),
+		Status:      "accepted",
+		Amount:      "100.00",
+		FeePaidBy:   "merchant",
+		GrossAmount: "100.00",
+		FeeAmount:   "3.20",
+		NetAmount:   "96.80",
+		LineItems: []PaymentLineItemResponse{
+			{Type: "gross", Description: "Charged to card", Amount: "100.00"},
+			{Type: "processing_fee", Description: "Processing fee", Amount: "3.20"},
+			{Type: "net", Description: "Credited to merchant", Amount: "96.80"},
+		},
+	}, resp)
+}
+
+func TestPaymentHandler_RetryPayment(t *testing.T) {
+	merchantID := uuid.New()
+	failedID := uuid.New()
+	retryID := uuid.New()
+
+	svc := new(MockPaymentService)
+	svc.On("RetryPayment", mock.Anything, merchantID, failedID).Return(&model.Payment{
+		ID:          retryID,
+		Status:      model.PaymentStatusAccepted,
+		Amount:      decimal.RequireFromString("25"),
+		GrossAmount: decimal.RequireFromString("25"),
+		NetAmount:   decimal.RequireFromString("25"),
+		RetryOfID:   &failedID,
+	}, nil)
+
+	c, rec := newTestContext(http.MethodPost, "/api/payments/"+failedID.String()+"/retry", nil, merchantID.String())
+	c.SetParamNames("id")
+	c.SetParamValues(failedID.String())
+	require.NoError(t, NewPaymentHandler(svc).RetryPayment(c))
+
+	var resp PaymentResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "/api/payments/"+retryID.String(), rec.Header().Get(echo.HeaderLocation))
+	assert.Equal(t, retryID.String(), resp.PaymentID)
+	assert.Equal(t, failedID.String(), resp.RetryOfID)
+	assert.Equal(t, "accepted", resp.Status)
+}
+
+func TestPaymentHandler_RetryPayment_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   errors.Code
+	}{
+		{"business failure", &service.PaymentNotRetryableError{Reason: model.PaymentFailureInsufficientBalance}, http.StatusUnprocessableEntity, errors.CodePaymentNotRetryable},
+		{"not failed", service.ErrPaymentNotFailed, http.StatusConflict, errors.CodePaymentNotFailed},
+		{"already retried", service.ErrPaymentAlreadyRetried, http.StatusConflict, errors.CodePaymentAlreadyRetried},
+		{"not found", errors.ErrPaymentNotFound, http.StatusNotFound, errors.CodePaymentNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merchantID := uuid.New()
+			paymentID := uuid.New()
+
+			svc := new(MockPaymentService)
+			svc.On("RetryPayment", mock.Anything, merchantID, paymentID).Return(nil, tt.err)
+
+			c, _ := newTestContext(http.MethodPost, "/api/payments/"+paymentID.String()+"/retry", nil, merchantID.String())
+			c.SetParamNames("id")
+			c.SetParamValues(paymentID.String())
+			err := NewPaymentHandler(svc).RetryPayment(c)
+
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, tt.wantStatus, httpErr.Code)
+			assert.Equal(t, tt.wantCode, httpErr.Message.(errors.ErrorResponse).Code)
+		})
+	}
+}
+
+func TestPaymentHandler_GetPayment(t *testing.T) {
+	merchantID := uuid.New()
+	paymentID := uuid.New()
+	cardID := uuid.New()
+	createdAt := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	svc := new(MockPaymentService)
+	svc.On("GetPayment", mock.Anything, merchantID, paymentID).Return(&model.Payment{
+		ID:                paymentID,
+		MerchantAccountID: merchantID,
+		CardID:            cardID,
+		Status:            model.PaymentStatusAccepted,
+		Amount:            decimal.RequireFromString("12.5"),
+		CreatedAt:         createdAt,
+		UpdatedAt:         createdAt.Add(time.Second),
+	}, nil)
+
+	c, rec := newTestContext(http.MethodGet, "/api/payments/"+paymentID.String(), nil, merchantID.String())
+	c.SetParamNames("id")
+	c.SetParamValues(paymentID.String())
+	require.NoError(t, NewPaymentHandler(svc).GetPayment(c))
+
+	var resp PaymentDetailResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, paymentID.String(), resp.PaymentID)
+	assert.Equal(t, "accepted", resp.Status)
+	assert.Equal(t, "12.50", resp.Amount)
+	assert.Equal(t, merchantID.String(), resp.MerchantAccountID)
+	assert.Equal(t, cardID.String(), resp.CardID)
+	assert.True(t, createdAt.Equal(resp.CreatedAt))
+	assert.True(t, createdAt.Add(time.Second).Equal(resp.UpdatedAt))
+}
+
+func TestPaymentHandler_GetPayment_NotFound(t *testing.T) {
+	merchantID := uuid.New()
+	paymentID := uuid.New()
+
+	svc := new(MockPaymentService)
+	svc.On("GetPayment", mock.Anything, merchantID, paymentID).Return(nil, errors.ErrPaymentNotFound)
+
+	c, _ := newTestContext(http.MethodGet, "/api/payments/"+paymentID.String(), nil, merchantID.String())
+	c.SetParamNames("id")
+	c.SetParamValues(paymentID.String())
+	err := NewPaymentHandler(svc).GetPayment(c)
+
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.Code)
+	assert.Equal(t, errors.CodePaymentNotFound, httpErr.Message.(errors.ErrorResponse).Code)
+}
+
+func TestPaymentHandler_ListMyPayments_MatchesListAccountPayments(t *testing.T) {
+	accountID := uuid.New()
+	payments := []model.Payment{
+		{
+			ID:                uuid.New(),
+			MerchantAccountID: accountID,
+			CardID:            uuid.New(),
+			Amount:            decimal.NewFromInt(25),
+			Status:            model.PaymentStatusAccepted,
+			CreatedAt:         time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+	}
+	svc := new(MockPaymentService)
+	svc.On("ListAccountPayments", mock.Anything, accountID, false, 10, 0).Return(payments, int64(1), nil)
+	h := NewPaymentHandler(svc)
+
+	byID, byIDRec := newTestContext(http.MethodGet, "/api/accounts/"+accountID.String()+"/payments?limit=10", nil, accountID.String())
+	byID.SetParamNames("id")
+	byID.SetParamValues(accountID.String())
+	require.NoError(t, h.ListAccountPayments(byID))
+
+	me, meRec := newTestContext(http.MethodGet, "/api/me/payments?limit=10", nil, accountID.String())
+	require.NoError(t, h.ListMyPayments(me))
+
+	assert.Equal(t, http.StatusOK, meRec.Code)
+	assert.JSONEq(t, byIDRec.Body.String(), meRec.Body.String())
+
+	var resp PaymentListResponse
+	require.NoError(t, json.Unmarshal(meRec.Body.Bytes(), &resp))
+	require.Len(t, resp.Payments, 1)
+	assert.Equal(t, payments[0].ID.String(), resp.Payments[0].PaymentID)
+	assert.Equal(t, "25.00", resp.Payments[0].Amount)
+	assert.Equal(t, int64(1), resp.Total)
+}
+
+func TestPaymentHandler_ListMerchantPayments(t *testing.T) {
+	accepted := model.PaymentStatusAccepted
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 3, 31, 23, 59, 59, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantFilter repository.PaymentFilter
+	}{
+		{name: "no filter", query: ""},
+		{name: "status", query: "?status=accepted", wantFilter: repository.PaymentFilter{Status: &accepted}},
+		{name: "date range", query: "?from=2026-03-01T00:00:00Z&to=2026-03-31T23:59:59Z", wantFilter: repository.PaymentFilter{From: from, To: to}},
+		{name: "open-ended range", query: "?from=2026-03-01T00:00:00Z", wantFilter: repository.PaymentFilter{From: from}},
+		{name: "archived included", query: "?include_archived=true", wantFilter: repository.PaymentFilter{IncludeArchived: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merchantID := uuid.New()
+			svc := new(MockPaymentService)
+			svc.On("ListMerchantPayments", mock.Anything, merchantID, tt.wantFilter, 20, 0).Return([]model.Payment{
+				{ID: uuid.New(), MerchantAccountID: merchantID, CardID: uuid.New(), Status: model.PaymentStatusAccepted, Amount: decimal.RequireFromString("12.50")},
+			}, int64(1), nil)
+
+			c, rec := newTestContext(http.MethodGet, "/api/payments"+tt.query, nil, merchantID.String())
+			require.NoError(t, NewPaymentHandler(svc).ListMerchantPayments(c))
+
+			var resp PaymentListResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, int64(1), resp.Total)
+			require.Len(t, resp.Payments, 1)
+			assert.Equal(t, "12.50", resp.Payments[0].Amount)
+			assert.Equal(t, merchantID.String(), resp.Payments[0].MerchantAccountID)
+			svc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPaymentHandler_ListMerchantPayments_InvalidFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		wantCode errors.Code
+	}{
+		{"unknown status", "?status=settled", errors.CodeValidationError},
+		{"malformed from", "?from=2026-03-01", errors.CodeInvalidDateRange},
+		{"malformed to", "?to=yesterday", errors.CodeInvalidDateRange},
+		{"from after to", "?from=2026-04-01T00:00:00Z&to=2026-03-01T00:00:00Z", errors.CodeInvalidDateRange},
+		{"malformed include_archived", "?include_archived=yes", errors.CodeValidationError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := new(MockPaymentService)
+			c, _ := newTestContext(http.MethodGet, "/api/payments"+tt.query, nil, uuid.NewString())
+			err := NewPaymentHandler(svc).ListMerchantPayments(c)
+
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+			assert.Equal(t, tt.wantCode, httpErr.Message.(errors.ErrorResponse).Code)
+			svc.AssertNotCalled(t, "ListMerchantPayments", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestPaymentHandler_ListMerchantPayments_NotMerchant(t *testing.T) {
+	merchantID := uuid.New()
+	svc := new(MockPaymentService)
+	svc.On("ListMerchantPayments", mock.Anything, merchantID, repository.PaymentFilter{}, 20, 0).Return(nil, int64(0), errors.ErrNotMerchant)
+
+	c, _ := newTestContext(http.MethodGet, "/api/payments", nil, merchantID.String())
+	err := NewPaymentHandler(svc).ListMerchantPayments(c)
+
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusForbidden, httpErr.Code)
+	assert.Equal(t, errors.CodeNotAMerchant, httpErr.Message.(errors.ErrorResponse).Code)
+}
+
+func TestPaymentHandler_ListAccountPayments_OtherAccount(t *testing.T) {
+	accountID := uuid.New()
+	svc := new(MockPaymentService)
+
+	c, _ := newTestContext(http.MethodGet, "/api/accounts/"+accountID.String()+"/payments", nil, uuid.NewString())
+	c.SetParamNames("id")
+	c.SetParamValues(accountID.String())
+	err := NewPaymentHandler(svc).ListAccountPayments(c)
+
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusForbidden, httpErr.Code)
+	svc.AssertNotCalled(t, "ListAccountPayments", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPaymentHandler_ListMyPayments_IncludeArchived(t *testing.T) {
+	accountID := uuid.New()
+	svc := new(MockPaymentService)
+	svc.On("ListAccountPayments", mock.Anything, accountID, true, 20, 0).Return([]model.Payment{}, int64(0), nil)
+
+	c, rec := newTestContext(http.MethodGet, "/api/me/payments?include_archived=true", nil, accountID.String())
+	require.NoError(t, NewPaymentHandler(svc).ListMyPayments(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	svc.AssertExpectations(t)
+
+	c, _ = newTestContext(http.MethodGet, "/api/me/payments?include_archived=maybe", nil, accountID.String())
+	err := NewPaymentHandler(svc).ListMyPayments(c)
+
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+	assert.Equal(t, errors.CodeValidationError, httpErr.Message.(errors.ErrorResponse).Code)
+}
+
+func TestPaymentHandler_CancelPayment(t *testing.T) {
+	merchantID := uuid.New()
+	paymentID := uuid.New()
+
+	svc := new(MockPaymentService)
+	svc.On("CancelPayment", mock.Anything, merchantID, paymentID).Return(&model.Payment{
+		ID:     paymentID,
+		Status: model.PaymentStatusCancelled,
+		Amount: decimal.RequireFromString("25"),
+	}, nil)
+
+	c, rec := newTestContext(http.MethodPost, "/api/payments/"+paymentID.String()+"/cancel", nil, merchantID.String())
+	c.SetParamNames("id")
+	c.SetParamValues(paymentID.String())
+	require.NoError(t, NewPaymentHandler(svc).CancelPayment(c))
+
+	var resp PaymentResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "cancelled", resp.Status)
+	assert.Equal(t, "Payment was cancelled", resp.Message)
+}
+
+func TestPaymentHandler_CancelPayment_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   errors.Code
+	}{
+		{"settled", service.ErrPaymentNotCancellable, http.StatusConflict, errors.CodePaymentNotCancellable},
+		{"already cancelled", service.ErrPaymentAlreadyCancelled, http.StatusConflict, errors.CodePaymentAlreadyCancelled},
+		{"not found", errors.ErrPaymentNotFound, http.StatusNotFound, errors.CodePaymentNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merchantID := uuid.New()
+			paymentID := uuid.New()
+
+			svc := new(MockPaymentService)
+			svc.On("CancelPayment", mock.Anything, merchantID, paymentID).Return(nil, tt.err)
+
+			c, _ := newTestContext(http.MethodPost, "/api/payments/"+paymentID.String()+"/cancel", nil, merchantID.String())
+			c.SetParamNames("id")
+			c.SetParamValues(paymentID.String())
+			err := NewPaymentHandler(svc).CancelPayment(c)
+
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, tt.wantStatus, httpErr.Code)
+			assert.Equal(t, tt.wantCode, httpErr.Message.(errors.ErrorResponse).Code)
+		})
+	}
+}
+
+func TestPaymentHandler_RefundPayment(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantAmount string
+	}{
+		{name: "partial", body: `{"amount":"40.00"}`, wantAmount: "40"},
+		{name: "full", body: `{}`, wantAmount: "0"},
+		{name: "full without a body", body: "", wantAmount: "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merchantID := uuid.New()
+			paymentID := uuid.New()
+			refundID := uuid.New()
+
+			svc := new(MockPaymentService)
+			svc.On("RefundPayment", mock.Anything, merchantID, paymentID, mock.MatchedBy(func(d decimal.Decimal) bool {
+				return d.Equal(decimal.RequireFromString(tt.wantAmount))
+			}), "refund-1").Return(&model.Payment{
+				ID:         refundID,
+				Status:     model.PaymentStatusRefunded,
+				Amount:     decimal.RequireFromString("40"),
+				RefundOfID: &paymentID,
+			}, nil)
+
+			c, rec := newTestContext(http.MethodPost, "/api/payments/"+paymentID.String()+"/refund", strings.NewReader(tt.body), merchantID.String())
+			c.Request().Header.Set("Idempotency-Key", "refund-1")
+			c.SetParamNames("id")
+			c.SetParamValues(paymentID.String())
+			require.NoError(t, NewPaymentHandler(svc).RefundPayment(c))
+
+			var resp PaymentDetailResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, http.StatusCreated, rec.Code)
+			assert.Equal(t, "/api/payments/"+refundID.String(), rec.Header().Get(echo.HeaderLocation))
+			assert.Equal(t, "refunded", resp.Status)
+			assert.Equal(t, paymentID.String(), resp.RefundOfID)
+			assert.Equal(t, "40.00", resp.Amount)
+			svc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPaymentHandler_RefundPayment_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		err        error
+		wantStatus int
+		wantCode   errors.Code
+	}{
+		{"malformed amount", `{"amount":"ten"}`, nil, http.StatusBadRequest, errors.CodeInvalidAmount},
+		{"zero amount", `{"amount":"0"}`, nil, http.StatusBadRequest, errors.CodeInvalidAmount},
+		{"not accepted", `{}`, service.ErrPaymentNotRefundable, http.StatusConflict, errors.CodePaymentNotRefundable},
+		{"over refund", `{"amount":"150.00"}`, service.ErrRefundExceedsOriginal, http.StatusUnprocessableEntity, errors.CodeRefundExceedsOriginal},
+		{"merchant balance too low", `{}`, errors.ErrInsufficientBalance, http.StatusBadRequest, errors.CodeInsufficientBalance},
+		{"not found", `{}`, errors.ErrPaymentNotFound, http.StatusNotFound, errors.CodePaymentNotFound},
+		{"idempotency key reused", `{"amount":"20.00"}`, service.ErrRefundKeyReused, http.StatusUnprocessableEntity, errors.CodeIdempotencyKeyReused},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merchantID := uuid.New()
+			paymentID := uuid.New()
+
+			svc := new(MockPaymentService)
+			if tt.err != nil {
+				svc.On("RefundPayment", mock.Anything, merchantID, paymentID, mock.Anything, mock.Anything).Return(nil, tt.err)
+			}
+
+			c, _ := newTestContext(http.MethodPost, "/api/payments/"+paymentID.String()+"/refund", strings.NewReader(tt.body), merchantID.String())
+			c.SetParamNames("id")
+			c.SetParamValues(paymentID.String())
+			err := NewPaymentHandler(svc).RefundPayment(c)
+
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, tt.wantStatus, httpErr.Code)
+			assert.Equal(t, tt.wantCode, httpErr.Message.(errors.ErrorResponse).Code)
+			if tt.err == nil {
+				svc.AssertNotCalled(t, "RefundPayment", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestPaymentHandler_RefundPayment_IdempotencyKeyTooLong(t *testing.T) {
+	merchantID := uuid.New()
+	paymentID := uuid.New()
+	svc := new(MockPaymentService)
+
+	c, _ := newTestContext(http.MethodPost, "/api/payments/"+paymentID.String()+"/refund", strings.NewReader(`{}`), merchantID.String())
+	c.Request().Header.Set("Idempotency-Key", strings.Repeat("k", model.MaxIdempotencyKeyLength+1))
+	c.SetParamNames("id")
+	c.SetParamValues(paymentID.String())
+	err := NewPaymentHandler(svc).RefundPayment(c)
+
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+	assert.Equal(t, errors.CodeValidationError, httpErr.Message.(errors.ErrorResponse).Code)
+	svc.AssertNotCalled(t, "RefundPayment", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPaymentHandler_GetPaymentReceiptPDF(t *testing.T) {
+	merchantID := uuid.New()
+	paymentID := uuid.New()
+
+	svc := new(MockPaymentService)
+	svc.On("GetPaymentReceipt", mock.Anything, merchantID, paymentID).Return(&service.PaymentReceipt{
+		PaymentID:         paymentID,
+		MerchantAccountID: merchantID,
+		MerchantName:      "Café Nord",
+		CardNumber:        "**** **** **** 4242",
+		Currency:          "USD",
+		Amount:            decimal.RequireFromString("100"),
+		GrossAmount:       decimal.RequireFromString("100"),
+		FeeAmount:         decimal.RequireFromString("3.2"),
+		NetAmount:         decimal.RequireFromString("96.8"),
+		Status:            model.PaymentStatusAccepted,
+		CreatedAt:         time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		Signature:         "abc123",
+	}, nil)
+
+	c, rec := newTestContext(http.MethodGet, "/api/payments/"+paymentID.String()+"/receipt.pdf", nil, merchantID.String())
+	c.SetParamNames("id")
+	c.SetParamValues(paymentID.String())
+	require.NoError(t, NewPaymentHandler(svc).GetPaymentReceiptPDF(c))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/pdf", rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, `attachment; filename="receipt-`+paymentID.String()+`.pdf"`, rec.Header().Get(echo.HeaderContentDisposition))
+	assert.Equal(t, "abc123", rec.Header().Get("X-Receipt-Signature"))
+	assert.True(t, strings.HasPrefix(rec.Body.String(), "%PDF-"))
+}
+
+func TestPaymentHandler_GetPaymentReceipt_Disabled(t *testing.T) {
+	merchantID := uuid.New()
+	paymentID := uuid.New()
+
+	svc := new(MockPaymentService)
+	svc.On("GetPaymentReceipt", mock.Anything, merchantID, paymentID).Return(nil, service.ErrReceiptsDisabled)
+
+	c, _ := newTestContext(http.MethodGet, "/api/payments/"+paymentID.String()+"/receipt", nil, merchantID.String())
+	c.SetParamNames("id")
+	c.SetParamValues(paymentID.String())
+	err := NewPaymentHandler(svc).GetPaymentReceipt(c)
+
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusServiceUnavailable, httpErr.Code)
+	assert.Equal(t, errors.CodeReceiptsDisabled, httpErr.Message.(errors.ErrorResponse).Code)
+}
+
+func TestPaymentHandler_ListCardPayments(t *testing.T) {
+	ownerID := uuid.New()
+	card := &model.Card{ID: uuid.New(), AccountID: ownerID}
+	payment := repository.CardPayment{
+		Payment: model.Payment{
+			ID:                uuid.New(),
+			MerchantAccountID: uuid.New(),
+			Amount:            decimal.RequireFromString("40"),
+			GrossAmount:       decimal.RequireFromString("41.2"),
+			Status:            model.PaymentStatusFailed,
+			FailureReason:     model.PaymentFailureInsufficientBalance,
+		},
+		MerchantName: "Corner Shop",
+	}
+
+	svc := new(MockPaymentService)
+	svc.On("ListCardPayments", mock.Anything, card.ID, 20, 0).Return(&service.CardPaymentPage{
+		Card:     card,
+		Payments: []repository.CardPayment{payment},
+		Total:    1,
+	}, nil)
+	h := NewPaymentHandler(svc)
+
+	c, rec := newTestContext(http.MethodGet, "/api/cards/"+card.ID.String()+"/payments", nil, ownerID.String())
+	c.SetParamNames("id")
+	c.SetParamValues(card.ID.String())
+	require.NoError(t, h.ListCardPayments(c))
+
+	var resp CardPaymentListResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, int64(1), resp.Total)
+	require.Len(t, resp.Payments, 1)
+	assert.Equal(t, "Corner Shop", resp.Payments[0].MerchantName)
+	assert.Equal(t, "40.00", resp.Payments[0].Amount)
+	assert.Equal(t, "41.20", resp.Payments[0].GrossAmount)
+	assert.Equal(t, "failed", resp.Payments[0].Status)
+	assert.Equal(t, "insufficient_balance", resp.Payments[0].FailureReason)
+
+	// Another account learns nothing about the card's payments
+	c, _ = newTestContext(http.MethodGet, "/api/cards/"+card.ID.String()+"/payments", nil, uuid.NewString())
+	c.SetParamNames("id")
+	c.SetParamValues(card.ID.String())
+	err := h.ListCardPayments(c)
+
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusForbidden, httpErr.Code)
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"io"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+
+	"paytabs/internal/service"
+)
+
+// writeReceiptPDF renders a one-page A4 receipt to w. The document's dates are the payment's
+// creation time, so the same receipt renders to the same bytes.
+func writeReceiptPDF(w io.Writer, r *service.PaymentReceipt) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetCreationDate(r.CreatedAt)
+	pdf.SetModificationDate(r.CreatedAt)
+	pdf.SetTitle("Payment receipt "+r.PaymentID.String(), true)
+	pdf.SetAuthor(r.MerchantName, true)
+	// The core fonts cover cp1252; this maps UTF-8 merchant names onto it
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.CellFormat(0, 12, "Payment receipt", "", 1, "L", false, 0, "")
+	if r.TestMode {
+		pdf.SetFont("Helvetica", "B", 11)
+		pdf.SetTextColor(200, 0, 0)
+		pdf.CellFormat(0, 7, "TEST MODE - no money was moved", "", 1, "L", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	}
+	pdf.Ln(4)
+
+	rows := [][2]string{
+		{"Merchant", tr(r.MerchantName)},
+		{"Merchant ID", r.MerchantAccountID.String()},
+		{"Payment ID", r.PaymentID.String()},
+		{"Card", r.CardNumber},
+		{"Amount", r.Amount.StringFixed(2) + " " + r.Currency},
+		{"Charged", r.GrossAmount.StringFixed(2) + " " + r.Currency},
+		{"Fee", r.FeeAmount.StringFixed(2) + " " + r.Currency},
+		{"Net to merchant", r.NetAmount.StringFixed(2) + " " + r.Currency},
+		{"Status", string(r.Status)},
+		{"Date", r.CreatedAt.UTC().Format(time.RFC1123)},
+	}
+	for _, row := range rows {
+		pdf.SetFont("Helvetica", "B", 11)
+		pdf.CellFormat(45, 8, row[0], "B", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 11)
+		pdf.CellFormat(0, 8, row[1], "B", 1, "L", false, 0, "")
+	}
+
+	pdf.Ln(8)
+	pdf.SetFont("Helvetica", "B", 9)
+	pdf.CellFormat(0, 6, "Verification ("+service.ReceiptSignatureAlgorithm+")", "", 1, "L", false, 0, "")
+	pdf.SetFont("Courier", "", 9)
+	pdf.CellFormat(0, 6, r.Signature, "", 1, "L", false, 0, "")
+
+	return pdf.Output(w)
+}
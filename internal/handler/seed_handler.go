@@ -9,6 +9,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 
+	"paytabs/internal/errors"
 	"paytabs/internal/model"
 	"paytabs/internal/service"
 )
@@ -16,11 +17,15 @@ import (
 // SeedHandler handles seed data endpoints.
 type SeedHandler struct {
 	accountService service.AccountService
+	sourceURL      string
 }
 
-// NewSeedHandler creates a new seed handler.
-func NewSeedHandler(accountService service.AccountService) *SeedHandler {
-	return &SeedHandler{accountService: accountService}
+// NewSeedHandler creates a new seed handler that fetches accounts from sourceURL.
+func NewSeedHandler(accountService service.AccountService, sourceURL string) *SeedHandler {
+	return &SeedHandler{
+		accountService: accountService,
+		sourceURL:      sourceURL,
+	}
 }
 
 // SeedAccountsRequest represents the structure from the external API.
@@ -42,37 +47,41 @@ type SeedAccountsResponse struct {
 // @Tags seed
 // @Produce json
 // @Success 200 {object} SeedAccountsResponse
-// @Failure 500 {object} map[string]string
+// @Failure 500 {object} errors.ErrorResponse
+// @Failure 502 {object} errors.ErrorResponse
 // @Router /seed/accounts [get]
 func (h *SeedHandler) SeedAccounts(c echo.Context) error {
 	// Fetch accounts from external API
-	url := "https://gist.githubusercontent.com/paytabscom/b590d72ae115226e288a9c8a15ba2888/raw/ac0d615060b02e755c94116e4e5a5af530bc4bb1/accounts.json"
-	resp, err := http.Get(url)
+	resp, err := http.Get(h.sourceURL)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, map[string]string{
-			"error": fmt.Sprintf("failed to fetch accounts: %v", err),
+		return echo.NewHTTPError(http.StatusBadGateway, errors.ErrorResponse{
+			Error: fmt.Sprintf("failed to fetch accounts: %v", err),
+			Code:  errors.CodeUpstreamUnavailable,
 		})
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return echo.NewHTTPError(http.StatusInternalServerError, map[string]string{
-			"error": fmt.Sprintf("external API returned status: %d", resp.StatusCode),
+		return echo.NewHTTPError(http.StatusBadGateway, errors.ErrorResponse{
+			Error: fmt.Sprintf("external API returned status: %d", resp.StatusCode),
+			Code:  errors.CodeUpstreamBadStatus,
 		})
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, map[string]string{
-			"error": fmt.Sprintf("failed to read response: %v", err),
+		return echo.NewHTTPError(http.StatusBadGateway, errors.ErrorResponse{
+			Error: fmt.Sprintf("failed to read response: %v", err),
+			Code:  errors.CodeUpstreamReadFailed,
 		})
 	}
 
 	// Parse JSON response
 	var seedData []SeedAccountsRequest
 	if err := json.Unmarshal(body, &seedData); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, map[string]string{
-			"error": fmt.Sprintf("failed to parse JSON: %v", err),
+		return echo.NewHTTPError(http.StatusBadGateway, errors.ErrorResponse{
+			Error: fmt.Sprintf("failed to parse JSON: %v", err),
+			Code:  errors.CodeUpstreamInvalidResponse,
 		})
 	}
 
@@ -91,6 +100,8 @@ func (h *SeedHandler) SeedAccounts(c echo.Context) error {
 			Email:     fmt.Sprintf("account-%s@example.com", accountID.String()), // Generate email for seeded accounts
 			Active:    item.Active,
 			IsMerchant: false, // Default to non-merchant for seeded accounts
+			// Seeded emails are generated and cannot receive a verification token
+			EmailVerified: true,
 		}
 		accounts = append(accounts, account)
 	}
@@ -98,8 +109,9 @@ func (h *SeedHandler) SeedAccounts(c echo.Context) error {
 	// Seed accounts
 	count, err := h.accountService.SeedAccounts(c.Request().Context(), accounts)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, map[string]string{
-			"error": fmt.Sprintf("failed to seed accounts: %v", err),
+		return echo.NewHTTPError(http.StatusInternalServerError, errors.ErrorResponse{
+			Error: "failed to seed accounts",
+			Code:  errors.CodeSeedFailed,
 		})
 	}
 
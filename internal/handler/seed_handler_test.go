@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"paytabs/internal/errors"
+)
+
+func TestSeedHandler_SeedAccounts(t *testing.T) {
+	validBody := `[{"id":"c56a4180-65aa-42ec-a945-5fd21dec0538","active":true,"name":"Alice","balance":"10.00"}]`
+
+	tests := []struct {
+		name           string
+		upstreamStatus int
+		upstreamBody   string
+		setupMock      func(*MockAccountService)
+		expectedStatus int
+		expectedCode   errors.Code
+	}{
+		{
+			name:           "upstream returns non-200",
+			upstreamStatus: http.StatusServiceUnavailable,
+			upstreamBody:   "unavailable",
+			setupMock:      func(m *MockAccountService) {},
+			expectedStatus: http.StatusBadGateway,
+			expectedCode:   errors.CodeUpstreamBadStatus,
+		},
+		{
+			name:           "upstream returns unparseable JSON",
+			upstreamStatus: http.StatusOK,
+			upstreamBody:   "<html>not json</html>",
+			setupMock:      func(m *MockAccountService) {},
+			expectedStatus: http.StatusBadGateway,
+			expectedCode:   errors.CodeUpstreamInvalidResponse,
+		},
+		{
+			name:           "database failure",
+			upstreamStatus: http.StatusOK,
+			upstreamBody:   validBody,
+			setupMock: func(m *MockAccountService) {
+				m.On("SeedAccounts", mock.Anything, mock.Anything).Return(0, fmt.Errorf("connection refused"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   errors.CodeSeedFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.upstreamStatus)
+				_, _ = w.Write([]byte(tt.upstreamBody))
+			}))
+			defer upstream.Close()
+
+			svc := new(MockAccountService)
+			tt.setupMock(svc)
+
+			c, _ := newTestContext(http.MethodGet, "/api/seed/accounts", nil, "")
+			err := NewSeedHandler(svc, upstream.URL).SeedAccounts(c)
+
+			httpErr, ok := err.(*echo.HTTPError)
+			require.True(t, ok)
+			assert.Equal(t, tt.expectedStatus, httpErr.Code)
+			assert.Equal(t, tt.expectedCode, httpErr.Message.(errors.ErrorResponse).Code)
+			svc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSeedHandler_SeedAccounts_Success(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"id":"c56a4180-65aa-42ec-a945-5fd21dec0538","active":true,"name":"Alice","balance":"10.00"},{"id":"not-a-uuid","active":true,"name":"Bob","balance":"0"}]`))
+	}))
+	defer upstream.Close()
+
+	svc := new(MockAccountService)
+	svc.On("SeedAccounts", mock.Anything, mock.Anything).Return(1, nil)
+
+	c, rec := newTestContext(http.MethodGet, "/api/seed/accounts", nil, "")
+	require.NoError(t, NewSeedHandler(svc, upstream.URL).SeedAccounts(c))
+
+	var resp SeedAccountsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 1, resp.Count)
+}
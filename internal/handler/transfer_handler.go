@@ -1,13 +1,17 @@
 package handler
 
 import (
+	stderrors "errors"
 	"net/http"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/shopspring/decimal"
 
 	"paytabs/internal/errors"
+	"paytabs/internal/model"
+	"paytabs/internal/repository"
 	"paytabs/internal/service"
 )
 
@@ -23,8 +27,8 @@ func NewTransferHandler(transferService service.TransferService) *TransferHandle
 
 // TransferRequest represents a transfer request.
 type TransferRequest struct {
-	SourceCardID      string `json:"source_card_id" validate:"required,uuid"`
-	DestinationCardID string `json:"destination_card_id" validate:"required,uuid"`
+	SourceCardID      string `json:"source_card_id" validate:"required"`
+	DestinationCardID string `json:"destination_card_id" validate:"required"`
 	Amount            string `json:"amount" validate:"required"`
 }
 
@@ -45,78 +49,429 @@ type TransferResponse struct {
 // @Success 200 {object} TransferResponse
 // @Failure 400 {object} errors.ErrorResponse
 // @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
 // @Failure 404 {object} errors.ErrorResponse
+// @Failure 423 {object} errors.ErrorResponse
 // @Failure 500 {object} errors.ErrorResponse
+// @Failure 503 {object} errors.ErrorResponse
 // @Router /transfers [post]
 func (h *TransferHandler) ProcessTransfer(c echo.Context) error {
+	callerID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
+	}
+	sourceCardID, destinationCardID, amount, err := bindTransferRequest(c)
+	if err != nil {
+		return err
+	}
+
+	// Process transfer
+	transfer, err := h.transferService.ProcessTransfer(
+		c.Request().Context(),
+		callerID,
+		sourceCardID,
+		destinationCardID,
+		amount,
+	)
+
+	if err != nil {
+		return transferError(err)
+	}
+
+	status := "completed"
+	message := "Transfer completed successfully"
+	if transfer.Status == "failed" {
+		status = "failed"
+		message = transfer.ErrorMessage
+		if message == "" {
+			message = "Transfer processing failed"
+		}
+	}
+
+	return c.JSON(http.StatusOK, TransferResponse{
+		TransferID: transfer.ID.String(),
+		Status:      status,
+		Message:     message,
+	})
+}
+
+// TransferValidationResponse reports whether a transfer would succeed and its projected effect.
+type TransferValidationResponse struct {
+	Valid                  bool   `json:"valid"`
+	Amount                 string `json:"amount"`
+	Fee                    string `json:"fee"`
+	ProjectedSourceBalance string `json:"projected_source_balance,omitempty"`
+	// ProjectedDestinationBalance is only included when the caller also owns the destination card.
+	ProjectedDestinationBalance string                      `json:"projected_destination_balance,omitempty"`
+	Reasons                     []service.TransferRejection `json:"reasons"`
+}
+
+// ValidateTransfer godoc
+// @Summary Dry-run a transfer
+// @Description Runs the transfer checks and returns projected balances without moving money.
+// @Tags transfers
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body TransferRequest true "Transfer data"
+// @Success 200 {object} TransferValidationResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Failure 503 {object} errors.ErrorResponse
+// @Router /transfers/validate [post]
+func (h *TransferHandler) ValidateTransfer(c echo.Context) error {
+	callerID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
+	}
+	sourceCardID, destinationCardID, amount, err := bindTransferRequest(c)
+	if err != nil {
+		return err
+	}
+
+	// Only the source card owner may dry-run a transfer from it, as only they may send it
+	check, err := h.transferService.ValidateTransfer(c.Request().Context(), callerID, sourceCardID, destinationCardID, amount)
+	if err != nil {
+		return transferError(err)
+	}
+
+	resp := TransferValidationResponse{
+		Valid:   check.OK(),
+		Amount:  amount.StringFixed(2),
+		Fee:     check.Fee.StringFixed(2),
+		Reasons: check.Rejections,
+	}
+	if resp.Reasons == nil {
+		resp.Reasons = []service.TransferRejection{}
+	}
+	if check.SourceCard != nil {
+		resp.ProjectedSourceBalance = check.ProjectedSourceBalance.StringFixed(2)
+	}
+	if check.DestinationCard != nil {
+		// Don't reveal another account's balance
+		if callerID == check.DestinationCard.AccountID {
+			resp.ProjectedDestinationBalance = check.ProjectedDestinationBalance.StringFixed(2)
+		}
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// transferError maps a transfer service error to an HTTP error.
+func transferError(err error) error {
+	if stderrors.Is(err, service.ErrTransferNotCardOwner) {
+		return echo.NewHTTPError(http.StatusForbidden, errors.ErrorResponse{
+			Error: err.Error(),
+			Code:  errors.CodeForbidden,
+		})
+	}
+	httpErr := errors.MapErrorToHTTP(err)
+	return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+}
+
+// bindTransferRequest binds, validates, and parses a TransferRequest body.
+func bindTransferRequest(c echo.Context) (sourceCardID, destinationCardID uuid.UUID, amount decimal.Decimal, err error) {
 	var req TransferRequest
 	if err := c.Bind(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+		return uuid.Nil, uuid.Nil, decimal.Zero, echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
 			Error: "invalid request body",
-			Code:  "INVALID_REQUEST",
+			Code:  errors.CodeInvalidRequest,
 		})
 	}
 
 	if err := c.Validate(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+		return uuid.Nil, uuid.Nil, decimal.Zero, echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
 			Error: err.Error(),
-			Code:  "VALIDATION_ERROR",
+			Code:  errors.CodeValidationError,
 		})
 	}
 
 	// Parse card IDs
-	sourceCardID, err := uuid.Parse(req.SourceCardID)
+	sourceCardID, err = parseUUIDBody(req.SourceCardID, "source_card_id")
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
-			Error: "invalid source_card_id",
-			Code:  "INVALID_UUID",
-		})
+		return uuid.Nil, uuid.Nil, decimal.Zero, err
 	}
 
-	destinationCardID, err := uuid.Parse(req.DestinationCardID)
+	destinationCardID, err = parseUUIDBody(req.DestinationCardID, "destination_card_id")
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
-			Error: "invalid destination_card_id",
-			Code:  "INVALID_UUID",
-		})
+		return uuid.Nil, uuid.Nil, decimal.Zero, err
 	}
 
 	// Parse amount
-	amount, err := decimal.NewFromString(req.Amount)
+	amount, err = decimal.NewFromString(req.Amount)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+		return uuid.Nil, uuid.Nil, decimal.Zero, echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
 			Error: "invalid amount",
-			Code:  "INVALID_AMOUNT",
+			Code:  errors.CodeInvalidAmount,
 		})
 	}
 
-	// Process transfer
-	transfer, err := h.transferService.ProcessTransfer(
-		c.Request().Context(),
-		sourceCardID,
-		destinationCardID,
-		amount,
-	)
+	return sourceCardID, destinationCardID, amount, nil
+}
+
+// TransferListItem represents a transfer in a listing.
+type TransferListItem struct {
+	ID                string                  `json:"id"`
+	SourceCardID      string                  `json:"source_card_id"`
+	DestinationCardID string                  `json:"destination_card_id"`
+	Amount            string                  `json:"amount"`
+	Status            model.TransferStatus    `json:"status"`
+	Direction         model.TransferDirection `json:"direction"`
+	ErrorMessage      string                  `json:"error_message,omitempty"`
+	CreatedAt         time.Time               `json:"created_at"`
+}
+
+// TransferListResponse represents a page of transfers.
+type TransferListResponse struct {
+	Transfers []TransferListItem `json:"transfers"`
+	Total     int64              `json:"total"`
+	Limit     int                `json:"limit"`
+	Offset    int                `json:"offset"`
+}
 
+// ListAccountTransfers godoc
+// @Summary List transfers involving any of an account's cards
+// @Tags transfers
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Account ID"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param offset query int false "Number of transfers to skip"
+// @Success 200 {object} TransferListResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /accounts/{id}/transfers [get]
+func (h *TransferHandler) ListAccountTransfers(c echo.Context) error {
+	accountID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := requireAccountOwner(c, accountID); err != nil {
+		return err
+	}
+
+	return h.listTransfers(c, accountID)
+}
+
+// ListMyTransfers godoc
+// @Summary List transfers involving any of the authenticated account's cards
+// @Description Same as GET /accounts/{id}/transfers for the caller's own account.
+// @Tags transfers
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param offset query int false "Number of transfers to skip"
+// @Success 200 {object} TransferListResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /me/transfers [get]
+func (h *TransferHandler) ListMyTransfers(c echo.Context) error {
+	accountID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	return h.listTransfers(c, accountID)
+}
+
+func (h *TransferHandler) listTransfers(c echo.Context, accountID uuid.UUID) error {
+	limit, offset, err := parsePagination(c)
+	if err != nil {
+		return err
+	}
+
+	transfers, total, err := h.transferService.ListAccountTransfers(c.Request().Context(), accountID, limit, offset)
 	if err != nil {
 		httpErr := errors.MapErrorToHTTP(err)
 		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
 	}
 
-	status := "completed"
-	message := "Transfer completed successfully"
-	if transfer.Status == "failed" {
-		status = "failed"
-		message = transfer.ErrorMessage
-		if message == "" {
-			message = "Transfer processing failed"
-		}
+	return c.JSON(http.StatusOK, TransferListResponse{
+		Transfers: newTransferListItems(transfers),
+		Total:     total,
+		Limit:     limit,
+		Offset:    offset,
+	})
+}
+
+// ListCardTransfers godoc
+// @Summary List a card's transfers
+// @Description Transfers into or out of the card in any status, newest first, with their direction relative to the card.
+// @Tags transfers
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Card ID"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param offset query int false "Number of transfers to skip"
+// @Success 200 {object} TransferListResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /cards/{id}/transfers [get]
+func (h *TransferHandler) ListCardTransfers(c echo.Context) error {
+	return h.listCardTransfers(c, "")
+}
+
+// ListFailedCardTransfers godoc
+// @Summary List a card's failed transfers
+// @Description Failed transfers into or out of the card, newest first, each with the error message it failed with.
+// @Tags transfers
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Card ID"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param offset query int false "Number of transfers to skip"
+// @Success 200 {object} TransferListResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /cards/{id}/transfers/failed [get]
+func (h *TransferHandler) ListFailedCardTransfers(c echo.Context) error {
+	return h.listCardTransfers(c, model.TransferStatusFailed)
+}
+
+// listCardTransfers answers with a page of the card's transfers in status (any when empty),
+// once the caller is confirmed to own the card.
+func (h *TransferHandler) listCardTransfers(c echo.Context, status model.TransferStatus) error {
+	cardID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return err
 	}
 
-	return c.JSON(http.StatusOK, TransferResponse{
-		TransferID: transfer.ID.String(),
-		Status:      status,
-		Message:     message,
+	limit, offset, err := parsePagination(c)
+	if err != nil {
+		return err
+	}
+
+	page, err := h.transferService.ListCardTransfers(c.Request().Context(), cardID, status, limit, offset)
+	if err != nil {
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+
+	if err := requireAccountOwner(c, page.Card.AccountID); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, TransferListResponse{
+		Transfers: newTransferListItems(page.Transfers),
+		Total:     page.Total,
+		Limit:     limit,
+		Offset:    offset,
 	})
 }
 
+// newTransferListItems builds the listing view of transfers.
+func newTransferListItems(transfers []repository.AccountTransfer) []TransferListItem {
+	items := make([]TransferListItem, 0, len(transfers))
+	for _, transfer := range transfers {
+		items = append(items, TransferListItem{
+			ID:                transfer.ID.String(),
+			SourceCardID:      transfer.SourceCardID.String(),
+			DestinationCardID: transfer.DestinationCardID.String(),
+			Amount:            transfer.Amount.String(),
+			Status:            transfer.Status,
+			Direction:         transfer.Direction,
+			ErrorMessage:      transfer.ErrorMessage,
+			CreatedAt:         transfer.CreatedAt,
+		})
+	}
+	return items
+}
+
+// TransferDirectionStatsResponse summarizes a card's completed transfers in one direction.
+type TransferDirectionStatsResponse struct {
+	Count   int64  `json:"count"`
+	Total   string `json:"total"`
+	Average string `json:"average"`
+}
+
+// TransferDayResponse is the day with the most transfers in a period.
+type TransferDayResponse struct {
+	Date  string `json:"date"` // YYYY-MM-DD, UTC
+	Count int64  `json:"count"`
+	Total string `json:"total"`
+}
+
+// CardTransferStatsResponse represents a card's transfer throughput over a period.
+type CardTransferStatsResponse struct {
+	CardID     string                         `json:"card_id"`
+	From       time.Time                      `json:"from"`
+	To         time.Time                      `json:"to"`
+	Inbound    TransferDirectionStatsResponse `json:"inbound"`
+	Outbound   TransferDirectionStatsResponse `json:"outbound"`
+	BusiestDay *TransferDayResponse           `json:"busiest_day,omitempty"`
+}
+
+// GetCardTransferStats godoc
+// @Summary Get a card's transfer throughput
+// @Description Counts, totals, and average sizes of the card's completed inbound and outbound transfers within [from, to], plus the busiest day.
+// @Tags transfers
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Card ID"
+// @Param from query string false "Range start, RFC3339 (default 30 days before to)"
+// @Param to query string false "Range end, RFC3339 (default now)"
+// @Success 200 {object} CardTransferStatsResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /cards/{id}/transfer-stats [get]
+func (h *TransferHandler) GetCardTransferStats(c echo.Context) error {
+	cardID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return err
+	}
+
+	from, to, err := parseTimeRange(c, defaultHistoryWindow)
+	if err != nil {
+		return err
+	}
+
+	stats, err := h.transferService.GetCardTransferStats(c.Request().Context(), cardID, from, to)
+	if err != nil {
+		httpErr := errors.MapErrorToHTTP(err)
+		return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+	}
+
+	if err := requireAccountOwner(c, stats.Card.AccountID); err != nil {
+		return err
+	}
+
+	resp := CardTransferStatsResponse{
+		CardID:   cardID.String(),
+		From:     from,
+		To:       to,
+		Inbound:  newTransferDirectionStatsResponse(stats.Inbound),
+		Outbound: newTransferDirectionStatsResponse(stats.Outbound),
+	}
+	if stats.BusiestDay != nil {
+		resp.BusiestDay = &TransferDayResponse{
+			Date:  stats.BusiestDay.Day.UTC().Format(time.DateOnly),
+			Count: stats.BusiestDay.Count,
+			Total: stats.BusiestDay.Total.StringFixed(2),
+		}
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func newTransferDirectionStatsResponse(stats service.TransferDirectionStats) TransferDirectionStatsResponse {
+	return TransferDirectionStatsResponse{
+		Count:   stats.Count,
+		Total:   stats.Total.StringFixed(2),
+		Average: stats.Average.StringFixed(2),
+	}
+}
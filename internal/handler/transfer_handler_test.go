@@ -0,0 +1,289 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"paytabs/internal/errors"
+	"paytabs/internal/model"
+	"paytabs/internal/repository"
+	"paytabs/internal/service"
+)
+
+func TestTransferHandler_ValidateTransfer(t *testing.T) {
+	ownerID := uuid.New()
+	otherID := uuid.New()
+	sourceID := uuid.New()
+	destID := uuid.New()
+	body := `{"source_card_id":"` + sourceID.String() + `","destination_card_id":"` + destID.String() + `","amount":"40.00"}`
+
+	newCheck := func(destOwner uuid.UUID) *service.TransferCheck {
+		return &service.TransferCheck{
+			SourceCard:                  &model.Card{ID: sourceID, AccountID: ownerID},
+			DestinationCard:             &model.Card{ID: destID, AccountID: destOwner},
+			Fee:                         decimal.Zero,
+			ProjectedSourceBalance:      decimal.RequireFromString("60"),
+			ProjectedDestinationBalance: decimal.RequireFromString("45"),
+		}
+	}
+
+	t.Run("own cards include both projections", func(t *testing.T) {
+		svc := new(MockTransferService)
+		svc.On("ValidateTransfer", mock.Anything, ownerID, sourceID, destID, mock.Anything).Return(newCheck(ownerID), nil)
+
+		c, rec := newTestContext(http.MethodPost, "/api/transfers/validate", strings.NewReader(body), ownerID.String())
+		require.NoError(t, NewTransferHandler(svc).ValidateTransfer(c))
+
+		var resp TransferValidationResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.True(t, resp.Valid)
+		assert.Equal(t, "0.00", resp.Fee)
+		assert.Equal(t, "60.00", resp.ProjectedSourceBalance)
+		assert.Equal(t, "45.00", resp.ProjectedDestinationBalance)
+		assert.Empty(t, resp.Reasons)
+	})
+
+	t.Run("other account's destination balance is hidden", func(t *testing.T) {
+		svc := new(MockTransferService)
+		svc.On("ValidateTransfer", mock.Anything, ownerID, sourceID, destID, mock.Anything).Return(newCheck(otherID), nil)
+
+		c, rec := newTestContext(http.MethodPost, "/api/transfers/validate", strings.NewReader(body), ownerID.String())
+		require.NoError(t, NewTransferHandler(svc).ValidateTransfer(c))
+
+		var resp TransferValidationResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, "60.00", resp.ProjectedSourceBalance)
+		assert.Empty(t, resp.ProjectedDestinationBalance)
+	})
+
+	t.Run("source card owned by someone else", func(t *testing.T) {
+		svc := new(MockTransferService)
+		svc.On("ValidateTransfer", mock.Anything, otherID, sourceID, destID, mock.Anything).Return(nil, service.ErrTransferNotCardOwner)
+
+		c, _ := newTestContext(http.MethodPost, "/api/transfers/validate", strings.NewReader(body), otherID.String())
+		err := NewTransferHandler(svc).ValidateTransfer(c)
+
+		httpErr, ok := err.(*echo.HTTPError)
+		require.True(t, ok)
+		assert.Equal(t, http.StatusForbidden, httpErr.Code)
+		assert.Equal(t, errors.CodeForbidden, httpErr.Message.(errors.ErrorResponse).Code)
+	})
+}
+
+func TestTransferHandler_ProcessTransfer_NotCardOwner(t *testing.T) {
+	callerID := uuid.New()
+	sourceID := uuid.New()
+	destID := uuid.New()
+	body := `{"source_card_id":"` + sourceID.String() + `","destination_card_id":"` + destID.String() + `","amount":"40.00"}`
+	svc := new(MockTransferService)
+	svc.On("ProcessTransfer", mock.Anything, callerID, sourceID, destID, mock.Anything).Return(nil, service.ErrTransferNotCardOwner)
+
+	c, _ := newTestContext(http.MethodPost, "/api/transfers", strings.NewReader(body), callerID.String())
+	err := NewTransferHandler(svc).ProcessTransfer(c)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusForbidden, httpErr.Code)
+	assert.Equal(t, errors.CodeForbidden, httpErr.Message.(errors.ErrorResponse).Code)
+}
+
+func TestTransferHandler_ListMyTransfers_MatchesListAccountTransfers(t *testing.T) {
+	accountID := uuid.New()
+	transfers := []repository.AccountTransfer{
+		{
+			Transfer: model.Transfer{
+				ID:                uuid.New(),
+				SourceCardID:      uuid.New(),
+				DestinationCardID: uuid.New(),
+				Amount:            decimal.NewFromInt(10),
+				Status:            model.TransferStatusCompleted,
+			},
+			Direction: model.TransferDirectionOutbound,
+		},
+	}
+	svc := new(MockTransferService)
+	svc.On("ListAccountTransfers", mock.Anything, accountID, defaultPageLimit, 5).Return(transfers, int64(6), nil)
+	h := NewTransferHandler(svc)
+
+	byID, byIDRec := newTestContext(http.MethodGet, "/api/accounts/"+accountID.String()+"/transfers?offset=5", nil, accountID.String())
+	byID.SetParamNames("id")
+	byID.SetParamValues(accountID.String())
+	require.NoError(t, h.ListAccountTransfers(byID))
+
+	me, meRec := newTestContext(http.MethodGet, "/api/me/transfers?offset=5", nil, accountID.String())
+	require.NoError(t, h.ListMyTransfers(me))
+
+	assert.Equal(t, http.StatusOK, meRec.Code)
+	assert.JSONEq(t, byIDRec.Body.String(), meRec.Body.String())
+	assert.Contains(t, meRec.Body.String(), transfers[0].ID.String())
+}
+
+func TestTransferHandler_GetCardTransferStats(t *testing.T) {
+	ownerID := uuid.New()
+	cardID := uuid.New()
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)
+	target := "/api/cards/" + cardID.String() + "/transfer-stats?from=2026-03-01T00:00:00Z&to=2026-03-31T00:00:00Z"
+
+	svc := new(MockTransferService)
+	svc.On("GetCardTransferStats", mock.Anything, cardID, from, to).Return(&service.CardTransferStats{
+		Card: &model.Card{ID: cardID, AccountID: ownerID},
+		Inbound: service.TransferDirectionStats{
+			Count: 3, Total: decimal.RequireFromString("100"), Average: decimal.RequireFromString("33.333"),
+		},
+		Outbound: service.TransferDirectionStats{Total: decimal.Zero, Average: decimal.Zero},
+		BusiestDay: &repository.TransferDayTotal{
+			Day: time.Date(2026, 3, 14, 0, 0, 0, 0, time.UTC), Count: 2, Total: decimal.RequireFromString("75"),
+		},
+	}, nil)
+
+	t.Run("owner", func(t *testing.T) {
+		c, rec := newTestContext(http.MethodGet, target, nil, ownerID.String())
+		c.SetParamNames("id")
+		c.SetParamValues(cardID.String())
+		require.NoError(t, NewTransferHandler(svc).GetCardTransferStats(c))
+
+		var resp CardTransferStatsResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, TransferDirectionStatsResponse{Count: 3, Total: "100.00", Average: "33.33"}, resp.Inbound)
+		assert.Equal(t, TransferDirectionStatsResponse{Count: 0, Total: "0.00", Average: "0.00"}, resp.Outbound)
+		require.NotNil(t, resp.BusiestDay)
+		assert.Equal(t, TransferDayResponse{Date: "2026-03-14", Count: 2, Total: "75.00"}, *resp.BusiestDay)
+	})
+
+	t.Run("other account", func(t *testing.T) {
+		c, _ := newTestContext(http.MethodGet, target, nil, uuid.NewString())
+		c.SetParamNames("id")
+		c.SetParamValues(cardID.String())
+		err := NewTransferHandler(svc).GetCardTransferStats(c)
+
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, http.StatusForbidden, httpErr.Code)
+	})
+}
+
+func TestTransferHandler_ListCardTransfers(t *testing.T) {
+	ownerID := uuid.New()
+	cardID := uuid.New()
+
+	svc := new(MockTransferService)
+	svc.On("ListCardTransfers", mock.Anything, cardID, model.TransferStatus(""), 10, 20).Return(&service.CardTransferPage{
+		Card: &model.Card{ID: cardID, AccountID: ownerID},
+		Transfers: []repository.AccountTransfer{
+			{
+				Transfer:  model.Transfer{ID: uuid.New(), SourceCardID: uuid.New(), DestinationCardID: cardID, Amount: decimal.RequireFromString("15"), Status: model.TransferStatusCompleted},
+				Direction: model.TransferDirectionInbound,
+			},
+			{
+				Transfer:  model.Transfer{ID: uuid.New(), SourceCardID: cardID, DestinationCardID: uuid.New(), Amount: decimal.RequireFromString("40"), Status: model.TransferStatusFailed},
+				Direction: model.TransferDirectionOutbound,
+			},
+		},
+		Total: 22,
+	}, nil)
+
+	t.Run("owner", func(t *testing.T) {
+		c, rec := newTestContext(http.MethodGet, "/api/cards/"+cardID.String()+"/transfers?limit=10&offset=20", nil, ownerID.String())
+		c.SetParamNames("id")
+		c.SetParamValues(cardID.String())
+		require.NoError(t, NewTransferHandler(svc).ListCardTransfers(c))
+
+		var resp TransferListResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, int64(22), resp.Total)
+		assert.Equal(t, 10, resp.Limit)
+		assert.Equal(t, 20, resp.Offset)
+		require.Len(t, resp.Transfers, 2)
+		assert.Equal(t, model.TransferDirectionInbound, resp.Transfers[0].Direction)
+		assert.Equal(t, model.TransferStatusFailed, resp.Transfers[1].Status)
+	})
+
+	t.Run("other account", func(t *testing.T) {
+		c, _ := newTestContext(http.MethodGet, "/api/cards/"+cardID.String()+"/transfers?limit=10&offset=20", nil, uuid.NewString())
+		c.SetParamNames("id")
+		c.SetParamValues(cardID.String())
+		err := NewTransferHandler(svc).ListCardTransfers(c)
+
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, http.StatusForbidden, httpErr.Code)
+	})
+
+	t.Run("unknown card", func(t *testing.T) {
+		missing := uuid.New()
+		svc.On("ListCardTransfers", mock.Anything, missing, model.TransferStatus(""), 20, 0).Return(nil, errors.ErrCardNotFound)
+
+		c, _ := newTestContext(http.MethodGet, "/api/cards/"+missing.String()+"/transfers", nil, ownerID.String())
+		c.SetParamNames("id")
+		c.SetParamValues(missing.String())
+		err := NewTransferHandler(svc).ListCardTransfers(c)
+
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, http.StatusNotFound, httpErr.Code)
+	})
+}
+
+func TestTransferHandler_ListFailedCardTransfers(t *testing.T) {
+	ownerID := uuid.New()
+	cardID := uuid.New()
+	failedAt := time.Date(2026, 3, 14, 9, 30, 0, 0, time.UTC)
+
+	svc := new(MockTransferService)
+	svc.On("ListCardTransfers", mock.Anything, cardID, model.TransferStatusFailed, 20, 0).Return(&service.CardTransferPage{
+		Card: &model.Card{ID: cardID, AccountID: ownerID},
+		Transfers: []repository.AccountTransfer{{
+			Transfer: model.Transfer{
+				ID:                uuid.New(),
+				SourceCardID:      cardID,
+				DestinationCardID: uuid.New(),
+				Amount:            decimal.RequireFromString("40"),
+				Status:            model.TransferStatusFailed,
+				ErrorMessage:      "insufficient balance",
+				CreatedAt:         failedAt,
+			},
+			Direction: model.TransferDirectionOutbound,
+		}},
+		Total: 1,
+	}, nil)
+
+	t.Run("owner", func(t *testing.T) {
+		c, rec := newTestContext(http.MethodGet, "/api/cards/"+cardID.String()+"/transfers/failed", nil, ownerID.String())
+		c.SetParamNames("id")
+		c.SetParamValues(cardID.String())
+		require.NoError(t, NewTransferHandler(svc).ListFailedCardTransfers(c))
+
+		var resp TransferListResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, int64(1), resp.Total)
+		require.Len(t, resp.Transfers, 1)
+		assert.Equal(t, "insufficient balance", resp.Transfers[0].ErrorMessage)
+		assert.Equal(t, model.TransferDirectionOutbound, resp.Transfers[0].Direction)
+		assert.True(t, resp.Transfers[0].CreatedAt.Equal(failedAt))
+	})
+
+	t.Run("other account", func(t *testing.T) {
+		c, _ := newTestContext(http.MethodGet, "/api/cards/"+cardID.String()+"/transfers/failed", nil, uuid.NewString())
+		c.SetParamNames("id")
+		c.SetParamValues(cardID.String())
+		err := NewTransferHandler(svc).ListFailedCardTransfers(c)
+
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, http.StatusForbidden, httpErr.Code)
+	})
+}
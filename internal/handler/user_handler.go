@@ -1,83 +0,0 @@
-package handler
-
-import (
-	"errors"
-	"net/http"
-	"strconv"
-
-	"github.com/labstack/echo/v4"
-	"gorm.io/gorm"
-
-	"paytabs/internal/model"
-	"paytabs/internal/service"
-)
-
-// UserHandler bundles HTTP handlers.
-type UserHandler struct {
-	svc service.UserService
-}
-
-// NewUserHandler creates a handler layer.
-func NewUserHandler(svc service.UserService) *UserHandler {
-	return &UserHandler{svc: svc}
-}
-
-// CreateUser godoc
-// @Summary Create user
-// @Tags users
-// @Accept json
-// @Produce json
-// @Param user body model.User true "User payload"
-// @Success 201 {object} model.User
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
-// @Router /users [post]
-func (h *UserHandler) CreateUser(c echo.Context) error {
-	var user model.User
-	if err := c.Bind(&user); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
-	}
-	created, err := h.svc.CreateUser(c.Request().Context(), &user)
-	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
-	}
-	return c.JSON(http.StatusCreated, created)
-}
-
-// GetUser godoc
-// @Summary Get user by id
-// @Tags users
-// @Produce json
-// @Param id path int true "User ID"
-// @Success 200 {object} model.User
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Router /users/{id} [get]
-func (h *UserHandler) GetUser(c echo.Context) error {
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid id")
-	}
-	user, err := h.svc.GetUser(c.Request().Context(), uint(id))
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return echo.NewHTTPError(http.StatusNotFound, "user not found")
-		}
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
-	}
-	return c.JSON(http.StatusOK, user)
-}
-
-// ListUsers godoc
-// @Summary List users
-// @Tags users
-// @Produce json
-// @Success 200 {array} model.User
-// @Router /users [get]
-func (h *UserHandler) ListUsers(c echo.Context) error {
-	users, err := h.svc.ListUsers(c.Request().Context())
-	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
-	}
-	return c.JSON(http.StatusOK, users)
-}
@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"paytabs/internal/errors"
+	"paytabs/internal/service"
+)
+
+// WebhookHandler handles webhook configuration endpoints.
+type WebhookHandler struct {
+	webhookService service.WebhookService
+}
+
+// NewWebhookHandler creates a new webhook handler.
+func NewWebhookHandler(webhookService service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{webhookService: webhookService}
+}
+
+// WebhookSecretResponse carries a freshly generated webhook signing secret.
+type WebhookSecretResponse struct {
+	Secret string `json:"secret"`
+}
+
+// WebhookEndpointRequest sets the URL webhook events are sent to.
+type WebhookEndpointRequest struct {
+	URL string `json:"url" validate:"required"`
+}
+
+// WebhookEndpointResponse is the merchant's webhook endpoint.
+type WebhookEndpointResponse struct {
+	URL       string    `json:"url"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WebhookTestResponse reports how the merchant's endpoint answered a test event.
+type WebhookTestResponse struct {
+	EventID    string `json:"event_id"`
+	URL        string `json:"url"`
+	Delivered  bool   `json:"delivered"`
+	StatusCode int    `json:"status_code,omitempty"`
+	LatencyMs  int64  `json:"latency_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// RevealSecret godoc
+// @Summary Regenerate and reveal the merchant's webhook signing secret
+// @Description Generates a new secret, replacing the previous one, and returns it. The secret is stored encrypted and cannot be shown again; call this again to rotate it. Limited per hour and audited.
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} WebhookSecretResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 429 {object} errors.ErrorResponse
+// @Failure 503 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /webhooks/secret/reveal [post]
+func (h *WebhookHandler) RevealSecret(c echo.Context) error {
+	accountID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	secret, err := h.webhookService.RegenerateSecret(c.Request().Context(), accountID, c.RealIP())
+	if err != nil {
+		return webhookError(err)
+	}
+
+	// The secret is only ever shown here, so keep it out of any cache
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return c.JSON(http.StatusOK, WebhookSecretResponse{Secret: secret})
+}
+
+// SetEndpoint godoc
+// @Summary Set the merchant's webhook endpoint
+// @Description Sets the http or https URL webhook events are sent to, replacing any previous one.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body WebhookEndpointRequest true "Endpoint URL"
+// @Success 200 {object} WebhookEndpointResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /webhooks/endpoint [put]
+func (h *WebhookHandler) SetEndpoint(c echo.Context) error {
+	accountID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	var req WebhookEndpointRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: "invalid request body",
+			Code:  errors.CodeInvalidRequest,
+		})
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: err.Error(),
+			Code:  errors.CodeValidationError,
+		})
+	}
+
+	endpoint, err := h.webhookService.SetEndpoint(c.Request().Context(), accountID, req.URL)
+	if err != nil {
+		return webhookError(err)
+	}
+	return c.JSON(http.StatusOK, WebhookEndpointResponse{URL: endpoint.URL, UpdatedAt: endpoint.UpdatedAt})
+}
+
+// GetEndpoint godoc
+// @Summary Get the merchant's webhook endpoint
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} WebhookEndpointResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /webhooks/endpoint [get]
+func (h *WebhookHandler) GetEndpoint(c echo.Context) error {
+	accountID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	endpoint, err := h.webhookService.GetEndpoint(c.Request().Context(), accountID)
+	if err != nil {
+		if err == service.ErrWebhookEndpointNotSet {
+			return echo.NewHTTPError(http.StatusNotFound, errors.ErrorResponse{
+				Error: err.Error(),
+				Code:  errors.CodeWebhookEndpointNotSet,
+			})
+		}
+		return webhookError(err)
+	}
+	return c.JSON(http.StatusOK, WebhookEndpointResponse{URL: endpoint.URL, UpdatedAt: endpoint.UpdatedAt})
+}
+
+// SendTestEvent godoc
+// @Summary Send a signed test event to the merchant's webhook endpoint
+// @Description Delivers one signed webhook.test event through the normal delivery path and reports the endpoint's status code, the latency, and whether it answered 2xx. A failed delivery is reported in the body, not as an error. Limited per hour.
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} WebhookTestResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 409 {object} errors.ErrorResponse
+// @Failure 429 {object} errors.ErrorResponse
+// @Failure 503 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /webhooks/test [post]
+func (h *WebhookHandler) SendTestEvent(c echo.Context) error {
+	accountID, err := accountIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	result, err := h.webhookService.SendTestEvent(c.Request().Context(), accountID)
+	if err != nil {
+		return webhookError(err)
+	}
+	return c.JSON(http.StatusOK, WebhookTestResponse{
+		EventID:    result.EventID,
+		URL:        result.URL,
+		Delivered:  result.Delivered,
+		StatusCode: result.StatusCode,
+		LatencyMs:  result.Latency.Milliseconds(),
+		Error:      result.Error,
+	})
+}
+
+// webhookError maps webhook service errors to HTTP errors.
+func webhookError(err error) error {
+	switch err {
+	case service.ErrTooManySecretReveals, service.ErrTooManyWebhookTests:
+		return echo.NewHTTPError(http.StatusTooManyRequests, errors.ErrorResponse{
+			Error: err.Error(),
+			Code:  errors.CodeTooManyRequests,
+		})
+	case service.ErrWebhookSecretsDisabled:
+		return echo.NewHTTPError(http.StatusServiceUnavailable, errors.ErrorResponse{
+			Error: err.Error(),
+			Code:  errors.CodeWebhooksDisabled,
+		})
+	case service.ErrInvalidWebhookURL:
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: err.Error(),
+			Code:  errors.CodeValidationError,
+		})
+	case service.ErrWebhookEndpointNotSet:
+		return echo.NewHTTPError(http.StatusConflict, errors.ErrorResponse{
+			Error: err.Error(),
+			Code:  errors.CodeWebhookEndpointNotSet,
+		})
+	case service.ErrWebhookSecretNotSet:
+		return echo.NewHTTPError(http.StatusConflict, errors.ErrorResponse{
+			Error: err.Error(),
+			Code:  errors.CodeWebhookSecretNotSet,
+		})
+	}
+	httpErr := errors.MapErrorToHTTP(err)
+	return echo.NewHTTPError(httpErr.StatusCode, httpErr.ToErrorResponse())
+}
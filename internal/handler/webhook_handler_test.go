@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"paytabs/internal/errors"
+	"paytabs/internal/model"
+	"paytabs/internal/service"
+)
+
+func TestWebhookHandler_RevealSecret(t *testing.T) {
+	merchantID := uuid.New()
+
+	svc := new(MockWebhookService)
+	svc.On("RegenerateSecret", mock.Anything, merchantID, mock.Anything).Return("whsec_abc", nil)
+
+	c, rec := newTestContext(http.MethodPost, "/api/webhooks/secret/reveal", nil, merchantID.String())
+	require.NoError(t, NewWebhookHandler(svc).RevealSecret(c))
+
+	var resp WebhookSecretResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "whsec_abc", resp.Secret)
+	assert.Equal(t, "no-store", rec.Header().Get(echo.HeaderCacheControl))
+}
+
+func TestWebhookHandler_RevealSecret_RateLimited(t *testing.T) {
+	merchantID := uuid.New()
+
+	svc := new(MockWebhookService)
+	svc.On("RegenerateSecret", mock.Anything, merchantID, mock.Anything).Return("", service.ErrTooManySecretReveals)
+
+	c, _ := newTestContext(http.MethodPost, "/api/webhooks/secret/reveal", nil, merchantID.String())
+	err := NewWebhookHandler(svc).RevealSecret(c)
+
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusTooManyRequests, httpErr.Code)
+}
+
+func TestWebhookHandler_SetEndpoint(t *testing.T) {
+	merchantID := uuid.New()
+	updated := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	svc := new(MockWebhookService)
+	svc.On("SetEndpoint", mock.Anything, merchantID, "https://shop.example.com/hooks").
+		Return(&model.WebhookEndpoint{AccountID: merchantID, URL: "https://shop.example.com/hooks", UpdatedAt: updated}, nil)
+
+	c, rec := newTestContext(http.MethodPut, "/api/webhooks/endpoint", strings.NewReader(`{"url":"https://shop.example.com/hooks"}`), merchantID.String())
+	require.NoError(t, NewWebhookHandler(svc).SetEndpoint(c))
+
+	var resp WebhookEndpointResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "https://shop.example.com/hooks", resp.URL)
+	assert.Equal(t, updated, resp.UpdatedAt)
+}
+
+func TestWebhookHandler_SetEndpoint_InvalidURL(t *testing.T) {
+	merchantID := uuid.New()
+
+	svc := new(MockWebhookService)
+	svc.On("SetEndpoint", mock.Anything, merchantID, "ftp://shop.example.com").Return(nil, service.ErrInvalidWebhookURL)
+
+	c, _ := newTestContext(http.MethodPut, "/api/webhooks/endpoint", strings.NewReader(`{"url":"ftp://shop.example.com"}`), merchantID.String())
+	err := NewWebhookHandler(svc).SetEndpoint(c)
+
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+	assert.Equal(t, errors.CodeValidationError, httpErr.Message.(errors.ErrorResponse).Code)
+}
+
+func TestWebhookHandler_SendTestEvent(t *testing.T) {
+	merchantID := uuid.New()
+
+	svc := new(MockWebhookService)
+	svc.On("SendTestEvent", mock.Anything, merchantID).Return(&service.WebhookTestResult{
+		EventID:    "evt_1",
+		URL:        "https://shop.example.com/hooks",
+		Delivered:  false,
+		StatusCode: http.StatusUnauthorized,
+		Latency:    120 * time.Millisecond,
+		Error:      "webhook delivery failed with status 401",
+	}, nil)
+
+	c, rec := newTestContext(http.MethodPost, "/api/webhooks/test", nil, merchantID.String())
+	require.NoError(t, NewWebhookHandler(svc).SendTestEvent(c))
+
+	var resp WebhookTestResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, http.StatusOK, rec.Code, "a failed delivery is reported, not an error")
+	assert.Equal(t, WebhookTestResponse{
+		EventID:    "evt_1",
+		URL:        "https://shop.example.com/hooks",
+		StatusCode: http.StatusUnauthorized,
+		LatencyMs:  120,
+		Error:      "webhook delivery failed with status 401",
+	}, resp)
+}
+
+func TestWebhookHandler_SendTestEvent_Errors(t *testing.T) {
+	tests := []struct {
+		err          error
+		expectedCode int
+		expectedErr  errors.Code
+	}{
+		{service.ErrWebhookEndpointNotSet, http.StatusConflict, errors.CodeWebhookEndpointNotSet},
+		{service.ErrWebhookSecretNotSet, http.StatusConflict, errors.CodeWebhookSecretNotSet},
+		{service.ErrTooManyWebhookTests, http.StatusTooManyRequests, errors.CodeTooManyRequests},
+		{service.ErrWebhookSecretsDisabled, http.StatusServiceUnavailable, errors.CodeWebhooksDisabled},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.expectedErr), func(t *testing.T) {
+			merchantID := uuid.New()
+			svc := new(MockWebhookService)
+			svc.On("SendTestEvent", mock.Anything, merchantID).Return(nil, tt.err)
+
+			c, _ := newTestContext(http.MethodPost, "/api/webhooks/test", nil, merchantID.String())
+			err := NewWebhookHandler(svc).SendTestEvent(c)
+
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, tt.expectedCode, httpErr.Code)
+			assert.Equal(t, tt.expectedErr, httpErr.Message.(errors.ErrorResponse).Code)
+		})
+	}
+}
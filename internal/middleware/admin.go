@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"paytabs/internal/errors"
+)
+
+// AdminTokenHeader is the request header carrying the operator token.
+const AdminTokenHeader = "X-Admin-Token"
+
+// AdminToken admits requests whose X-Admin-Token header matches token and rejects the rest
+// with 401. The comparison is constant time.
+func AdminToken(token string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			given := c.Request().Header.Get(AdminTokenHeader)
+			if token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				return echo.NewHTTPError(http.StatusUnauthorized, errors.ErrorResponse{
+					Error: "invalid admin token",
+					Code:  errors.CodeUnauthorized,
+				})
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	echomiddleware "github.com/labstack/echo/v4/middleware"
+)
+
+// Compression gzips the responses of GET requests from clients that accept it, once a
+// response reaches minLength bytes; smaller ones are sent as they are, since gzip would not
+// shrink them. Other methods are never compressed: their responses are small and the
+// idempotency middleware stores and replays them byte for byte.
+func Compression(minLength int) echo.MiddlewareFunc {
+	return echomiddleware.GzipWithConfig(echomiddleware.GzipConfig{
+		Skipper: func(c echo.Context) bool {
+			return c.Request().Method != http.MethodGet
+		},
+		MinLength: minLength,
+	})
+}
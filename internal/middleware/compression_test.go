@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompression(t *testing.T) {
+	items := make([]map[string]string, 500)
+	for i := range items {
+		items[i] = map[string]string{"id": "3f1c2d4e-0000-4000-8000-000000000000", "status": "accepted"}
+	}
+	large := func(c echo.Context) error {
+		return c.JSON(http.StatusOK, map[string]interface{}{"payments": items})
+	}
+	small := func(c echo.Context) error {
+		return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+	}
+
+	serve := func(method string, acceptEncoding string, handler echo.HandlerFunc) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(method, "/api/accounts/me/payments", nil)
+		if acceptEncoding != "" {
+			req.Header.Set(echo.HeaderAcceptEncoding, acceptEncoding)
+		}
+		rec := httptest.NewRecorder()
+		require.NoError(t, Compression(1024)(handler)(e.NewContext(req, rec)))
+		return rec
+	}
+
+	t.Run("large GET response is gzipped", func(t *testing.T) {
+		rec := serve(http.MethodGet, "gzip", large)
+
+		assert.Equal(t, "gzip", rec.Header().Get(echo.HeaderContentEncoding))
+		reader, err := gzip.NewReader(rec.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		var decoded struct {
+			Payments []map[string]string `json:"payments"`
+		}
+		require.NoError(t, json.Unmarshal(body, &decoded))
+		assert.Len(t, decoded.Payments, len(items))
+	})
+
+	t.Run("client without gzip", func(t *testing.T) {
+		rec := serve(http.MethodGet, "", large)
+
+		assert.Empty(t, rec.Header().Get(echo.HeaderContentEncoding))
+		assert.True(t, strings.HasPrefix(rec.Body.String(), `{"payments":`))
+	})
+
+	t.Run("below the minimum length", func(t *testing.T) {
+		rec := serve(http.MethodGet, "gzip", small)
+
+		assert.Empty(t, rec.Header().Get(echo.HeaderContentEncoding))
+		assert.JSONEq(t, `{"status":"ok"}`, rec.Body.String())
+	})
+
+	t.Run("POST is never compressed", func(t *testing.T) {
+		rec := serve(http.MethodPost, "gzip", large)
+
+		assert.Empty(t, rec.Header().Get(echo.HeaderContentEncoding))
+		assert.True(t, strings.HasPrefix(rec.Body.String(), `{"payments":`))
+	})
+}
@@ -0,0 +1,203 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"paytabs/internal/auth"
+	"paytabs/internal/cache"
+	"paytabs/internal/errors"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header carrying the client-supplied key.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotencyReplayedHeader is set on responses served from a stored result.
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+
+	// DefaultIdempotencyTTL is how long stored responses are kept when no TTL is configured.
+	DefaultIdempotencyTTL = 24 * time.Hour
+
+	idempotencyKeyPrefix  = "idempotency:"
+	idempotencyLockPrefix = "idempotency_lock:"
+	idempotencyLockTTL    = 30 * time.Second
+)
+
+// storedResponse is the response recorded for an idempotency key, along with a hash of
+// the request body that produced it.
+type storedResponse struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type"`
+	Location    string `json:"location,omitempty"`
+	Body        []byte `json:"body"`
+	BodyHash    string `json:"body_hash"`
+}
+
+// Idempotency replays the stored response when a request with the same Idempotency-Key,
+// method, and path is repeated by the same account with the same body, and records the response of the first
+// request otherwise. Reusing a key with a different body is rejected with 422 rather
+// than replaying a result for another payload. Requests without the header pass through
+// untouched. Concurrent duplicates of an in-flight request are rejected with 409.
+// Stored responses are kept for ttl, or DefaultIdempotencyTTL if ttl is not positive.
+//
+// The routes it guards move money, so it fails closed: a keyed request is refused with 503
+// when redis cannot be read or the key cannot be locked, rather than run without dedup.
+func Idempotency(cacheClient *cache.Client, ttl time.Duration) echo.MiddlewareFunc {
+	if ttl <= 0 {
+		ttl = DefaultIdempotencyTTL
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				return next(c)
+			}
+
+			body, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+					Error: "invalid request body",
+					Code:  errors.CodeInvalidRequest,
+				})
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+			ctx := c.Request().Context()
+			scope := scopedKey(c, key)
+			storeKey := idempotencyKeyPrefix + scope
+			bodyHash := hashBody(body)
+
+			if replayed, err := replayStored(c, cacheClient, storeKey, bodyHash); replayed || err != nil {
+				return err
+			}
+
+			lockKey := idempotencyLockPrefix + scope
+			acquired, err := cacheClient.Claim(ctx, lockKey, []byte("1"), idempotencyLockTTL)
+			if err != nil {
+				log.Printf("idempotency: lock %s: %v", lockKey, err)
+				return idempotencyUnavailable()
+			}
+			if !acquired {
+				return echo.NewHTTPError(http.StatusConflict, errors.ErrorResponse{
+					Error: "a request with this idempotency key is already in progress",
+					Code:  errors.CodeIdempotencyInProgress,
+				})
+			}
+			defer func() {
+				// Release with a fresh context so a cancelled request still unlocks
+				_ = cacheClient.Delete(context.Background(), lockKey)
+			}()
+
+			// The first request may have stored its response and released the lock between
+			// the read above and Claim; replay it rather than run the handler again
+			if replayed, err := replayStored(c, cacheClient, storeKey, bodyHash); replayed || err != nil {
+				return err
+			}
+
+			recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = recorder
+
+			if err := next(c); err != nil {
+				// Render the error now so the error response is captured as well
+				c.Error(err)
+			}
+
+			status := c.Response().Status
+			if status >= http.StatusInternalServerError {
+				return nil
+			}
+
+			payload, err := json.Marshal(storedResponse{
+				Status:      status,
+				ContentType: c.Response().Header().Get(echo.HeaderContentType),
+				Location:    c.Response().Header().Get(echo.HeaderLocation),
+				Body:        recorder.body.Bytes(),
+				BodyHash:    bodyHash,
+			})
+			if err == nil {
+				_ = cacheClient.Set(ctx, storeKey, payload, ttl)
+			}
+
+			return nil
+		}
+	}
+}
+
+// replayStored writes the response stored at storeKey, if any, and reports whether it did. A
+// stored response for a different body is refused with 422 instead, and a failed redis read
+// with 503.
+func replayStored(c echo.Context, cacheClient *cache.Client, storeKey, bodyHash string) (bool, error) {
+	data, found, err := cacheClient.Lookup(c.Request().Context(), storeKey)
+	if err != nil {
+		log.Printf("idempotency: read %s: %v", storeKey, err)
+		return false, idempotencyUnavailable()
+	}
+	if !found {
+		return false, nil
+	}
+
+	var stored storedResponse
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return false, nil
+	}
+	if stored.BodyHash != bodyHash {
+		return false, echo.NewHTTPError(http.StatusUnprocessableEntity, errors.ErrorResponse{
+			Error: "idempotency key reused with different payload",
+			Code:  errors.CodeIdempotencyKeyReused,
+		})
+	}
+	c.Response().Header().Set(IdempotencyReplayedHeader, "true")
+	if stored.Location != "" {
+		c.Response().Header().Set(echo.HeaderLocation, stored.Location)
+	}
+	return true, c.Blob(stored.Status, stored.ContentType, stored.Body)
+}
+
+// idempotencyUnavailable refuses a keyed request that cannot be deduplicated.
+func idempotencyUnavailable() error {
+	return echo.NewHTTPError(http.StatusServiceUnavailable, errors.ErrorResponse{
+		Error: "idempotency keys cannot be checked right now, try again later",
+		Code:  errors.CodeIdempotencyUnavailable,
+	})
+}
+
+// scopedKey builds the cache key suffix for an idempotency key:
+// <method>:<path>:<account id>:<key>, e.g. POST:/api/transfers:<uuid>:abc. The path is the
+// request path rather than the route pattern, so a key is scoped to the resource as well as
+// the endpoint: reusing it for /payments/card and /transfers, or to withdraw from two
+// different cards, runs each request. Unauthenticated requests have an empty account ID.
+func scopedKey(c echo.Context, key string) string {
+	accountID := ""
+	if claims, ok := c.Get("user").(*auth.Claims); ok {
+		accountID = claims.AccountID
+	}
+
+	return c.Request().Method + ":" + c.Request().URL.Path + ":" + accountID + ":" + key
+}
+
+// hashBody returns the hex SHA-256 of a request body.
+func hashBody(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// responseRecorder tees the response body so it can be stored after the handler runs.
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,271 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"paytabs/internal/auth"
+	"paytabs/internal/cache"
+)
+
+// newIdempotentServer returns an Echo instance with one idempotent route that counts
+// how many times the handler actually runs.
+func newIdempotentServer(t *testing.T, ttl time.Duration) (*echo.Echo, *miniredis.Miniredis, *int) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	calls := 0
+
+	e := echo.New()
+	e.POST("/pay", func(c echo.Context) error {
+		calls++
+		c.Response().Header().Set(echo.HeaderLocation, "/api/payments/"+strconv.Itoa(calls))
+		return c.JSON(http.StatusCreated, map[string]int{"call": calls})
+	}, Idempotency(cache.New(mr.Addr(), "", 0), ttl))
+	return e, mr, &calls
+}
+
+func doRequest(e *echo.Echo, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/pay", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(IdempotencyKeyHeader, key)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIdempotency_SameBodyReplays(t *testing.T) {
+	e, _, calls := newIdempotentServer(t, time.Hour)
+
+	first := doRequest(e, "key-1", `{"amount":"10.00"}`)
+	second := doRequest(e, "key-1", `{"amount":"10.00"}`)
+
+	assert.Equal(t, 1, *calls)
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "/api/payments/1", second.Header().Get(echo.HeaderLocation))
+	assert.Equal(t, "true", second.Header().Get(IdempotencyReplayedHeader))
+}
+
+func TestIdempotency_DifferentBodyConflicts(t *testing.T) {
+	e, _, calls := newIdempotentServer(t, time.Hour)
+
+	doRequest(e, "key-1", `{"amount":"10.00"}`)
+	rec := doRequest(e, "key-1", `{"amount":"99.00"}`)
+
+	assert.Equal(t, 1, *calls)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), "IDEMPOTENCY_KEY_REUSED")
+	assert.Empty(t, rec.Header().Get(IdempotencyReplayedHeader))
+}
+
+// gatedRedis proxies connections to addr, holding back any command that takes a key with NX
+// until release is closed, so a test can order two instances' steps around the lock. held
+// receives once a command is being held back.
+func gatedRedis(t *testing.T, addr string, held chan<- struct{}, release <-chan struct{}) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for {
+			client, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			server, err := net.Dial("tcp", addr)
+			if err != nil {
+				_ = client.Close()
+				return
+			}
+			go func() { _, _ = io.Copy(client, server) }()
+			go func() {
+				buf := make([]byte, 4096)
+				for {
+					n, err := client.Read(buf)
+					if err != nil {
+						_ = server.Close()
+						return
+					}
+					if bytes.Contains(bytes.ToLower(buf[:n]), []byte("\r\nnx\r\n")) {
+						held <- struct{}{}
+						<-release
+					}
+					if _, err := server.Write(buf[:n]); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestIdempotency_DuplicateLockedAfterOriginalCompletesReplays(t *testing.T) {
+	mr := miniredis.RunT(t)
+	held, release := make(chan struct{}, 1), make(chan struct{})
+	var calls int32
+	handler := func(c echo.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return c.JSON(http.StatusCreated, map[string]string{"status": "accepted"})
+	}
+
+	// Two instances share redis; the second one's lock request is held back
+	first := echo.New()
+	first.POST("/pay", handler, Idempotency(cache.New(mr.Addr(), "", 0), time.Hour))
+	second := echo.New()
+	second.POST("/pay", handler, Idempotency(cache.New(gatedRedis(t, mr.Addr(), held, release), "", 0), time.Hour))
+
+	// The duplicate finds no stored response and waits to take the lock...
+	duplicate := make(chan *httptest.ResponseRecorder)
+	go func() { duplicate <- doRequest(second, "key-1", `{"amount":"10.00"}`) }()
+	<-held
+
+	// ...while the original runs, stores its response, and unlocks
+	original := doRequest(first, "key-1", `{"amount":"10.00"}`)
+	require.Equal(t, http.StatusCreated, original.Code)
+	close(release)
+
+	rec := <-duplicate
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls), "the duplicate must not run the handler again")
+	assert.Equal(t, "true", rec.Header().Get(IdempotencyReplayedHeader))
+	assert.Equal(t, original.Body.String(), rec.Body.String())
+}
+
+func TestIdempotency_FailsClosedWithoutRedis(t *testing.T) {
+	mr := miniredis.RunT(t)
+	cacheClient := cache.New(mr.Addr(), "", 0)
+	mr.Close()
+
+	for name, client := range map[string]*cache.Client{"redis down": cacheClient, "no redis client": nil} {
+		t.Run(name, func(t *testing.T) {
+			calls := 0
+			e := echo.New()
+			e.POST("/pay", func(c echo.Context) error {
+				calls++
+				return c.NoContent(http.StatusCreated)
+			}, Idempotency(client, time.Hour))
+
+			rec := doRequest(e, "key-1", `{"amount":"10.00"}`)
+
+			assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+			assert.Contains(t, rec.Body.String(), "IDEMPOTENCY_UNAVAILABLE")
+			assert.Zero(t, calls, "the request is not run without dedup")
+
+			// Requests without a key do not depend on redis
+			rec = doRequest(e, "", `{"amount":"10.00"}`)
+			assert.Equal(t, http.StatusCreated, rec.Code)
+		})
+	}
+}
+
+func TestIdempotency_TTL(t *testing.T) {
+	e, mr, calls := newIdempotentServer(t, time.Minute)
+
+	doRequest(e, "key-1", `{"amount":"10.00"}`)
+	require.Len(t, mr.Keys(), 1)
+	assert.Equal(t, time.Minute, mr.TTL(mr.Keys()[0]))
+
+	// Once the key expires the request is processed again
+	mr.FastForward(2 * time.Minute)
+	doRequest(e, "key-1", `{"amount":"99.00"}`)
+	assert.Equal(t, 2, *calls)
+}
+
+func TestIdempotency_ScopedByPath(t *testing.T) {
+	mr := miniredis.RunT(t)
+	idempotent := Idempotency(cache.New(mr.Addr(), "", 0), time.Hour)
+	calls := map[string]int{}
+	handler := func(c echo.Context) error {
+		calls[c.Request().URL.Path]++
+		return c.JSON(http.StatusCreated, map[string]string{"path": c.Request().URL.Path})
+	}
+
+	e := echo.New()
+	e.POST("/api/payments/card", handler, idempotent)
+	e.POST("/api/transfers", handler, idempotent)
+	e.POST("/api/cards/:id/withdraw", handler, idempotent)
+
+	send := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"amount":"10.00"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(IdempotencyKeyHeader, "shared-key")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// The same key on another endpoint, or another resource of the same endpoint, runs again
+	for _, path := range []string{"/api/payments/card", "/api/transfers", "/api/cards/a/withdraw", "/api/cards/b/withdraw"} {
+		rec := send(path)
+		assert.Equal(t, http.StatusCreated, rec.Code, path)
+		assert.Empty(t, rec.Header().Get(IdempotencyReplayedHeader), path)
+		assert.Contains(t, rec.Body.String(), path)
+	}
+
+	// Each path still replays its own response
+	rec := send("/api/transfers")
+	assert.Equal(t, "true", rec.Header().Get(IdempotencyReplayedHeader))
+	assert.Contains(t, rec.Body.String(), "/api/transfers")
+	assert.Equal(t, map[string]int{
+		"/api/payments/card":    1,
+		"/api/transfers":        1,
+		"/api/cards/a/withdraw": 1,
+		"/api/cards/b/withdraw": 1,
+	}, calls)
+}
+
+func TestIdempotency_ScopedByAccount(t *testing.T) {
+	mr := miniredis.RunT(t)
+	debits := map[string]int{}
+
+	e := echo.New()
+	e.POST("/api/payments/card", func(c echo.Context) error {
+		accountID := c.Get("user").(*auth.Claims).AccountID
+		debits[accountID]++
+		return c.JSON(http.StatusCreated, map[string]string{"merchant": accountID})
+	}, func(next echo.HandlerFunc) echo.HandlerFunc {
+		// Stands in for JWT auth, which runs before the idempotency middleware
+		return func(c echo.Context) error {
+			c.Set("user", &auth.Claims{AccountID: c.Request().Header.Get("X-Test-Account")})
+			return next(c)
+		}
+	}, Idempotency(cache.New(mr.Addr(), "", 0), time.Hour))
+
+	send := func(accountID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/payments/card", strings.NewReader(`{"amount":"10.00"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(IdempotencyKeyHeader, "order-42")
+		req.Header.Set("X-Test-Account", accountID)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// A retried payment replays the original and debits the card once
+	first := send("merchant-a")
+	retry := send("merchant-a")
+	assert.Equal(t, "true", retry.Header().Get(IdempotencyReplayedHeader))
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+
+	// Another merchant using the same key gets its own payment
+	other := send("merchant-b")
+	assert.Equal(t, http.StatusCreated, other.Code)
+	assert.Empty(t, other.Header().Get(IdempotencyReplayedHeader))
+	assert.Contains(t, other.Body.String(), "merchant-b")
+
+	assert.Equal(t, map[string]int{"merchant-a": 1, "merchant-b": 1}, debits)
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"paytabs/internal/cache"
+	"paytabs/internal/errors"
+)
+
+const (
+	// MaintenanceModeKey is the redis key that switches maintenance mode on for every
+	// instance at once; any value that parses as true enables it.
+	MaintenanceModeKey = "system:maintenance_mode"
+
+	// DefaultMaintenanceRetryAfter is the Retry-After sent when none is configured.
+	DefaultMaintenanceRetryAfter = 5 * time.Minute
+)
+
+// maintenanceExemptPaths stay writable during maintenance: operators must still reach the
+// admin endpoints, and clients must be able to sign in to use the read endpoints.
+var maintenanceExemptPaths = []string{
+	"/api/admin/",
+	"/api/auth/login",
+	"/api/auth/refresh",
+	"/api/auth/logout",
+}
+
+// Maintenance answers 503 with a Retry-After header for mutating requests (anything but
+// GET, HEAD and OPTIONS) while maintenance mode is on, so operators can migrate without
+// stopping the server. It is on when enabled is set (MAINTENANCE_MODE) or when the
+// MaintenanceModeKey flag is true in redis. When redis cannot be read only enabled counts,
+// so an outage does not take writes down with it. Reads are never affected.
+func Maintenance(cacheClient *cache.Client, enabled bool, retryAfter time.Duration) echo.MiddlewareFunc {
+	if retryAfter <= 0 {
+		retryAfter = DefaultMaintenanceRetryAfter
+	}
+	retryAfterSeconds := strconv.Itoa(int(retryAfter.Round(time.Second) / time.Second))
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			switch req.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				return next(c)
+			}
+			if maintenanceExempt(req.URL.Path) {
+				return next(c)
+			}
+			if !enabled && !maintenanceFlagSet(req.Context(), cacheClient) {
+				return next(c)
+			}
+
+			c.Response().Header().Set(echo.HeaderRetryAfter, retryAfterSeconds)
+			return echo.NewHTTPError(http.StatusServiceUnavailable, errors.ErrorResponse{
+				Error: "the service is in maintenance; changes are not accepted until it ends",
+				Code:  errors.CodeMaintenanceMode,
+			})
+		}
+	}
+}
+
+// maintenanceFlagSet reports whether the redis maintenance flag is true. A missing key, an
+// unparsable value or an unreachable redis all read as off.
+func maintenanceFlagSet(ctx context.Context, cacheClient *cache.Client) bool {
+	value, found, err := cacheClient.Lookup(ctx, MaintenanceModeKey)
+	if err != nil || !found {
+		return false
+	}
+	on, err := strconv.ParseBool(string(value))
+	return err == nil && on
+}
+
+func maintenanceExempt(path string) bool {
+	for _, prefix := range maintenanceExemptPaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
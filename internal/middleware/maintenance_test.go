@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"paytabs/internal/cache"
+	"paytabs/internal/errors"
+)
+
+// newMaintenanceServer returns an Echo instance whose routes all answer 200 behind the
+// maintenance middleware.
+func newMaintenanceServer(cacheClient *cache.Client, enabled bool) *echo.Echo {
+	e := echo.New()
+	api := e.Group("/api", Maintenance(cacheClient, enabled, 90*time.Second))
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+	api.GET("/payments/:id", ok)
+	api.POST("/payments/card", ok)
+	api.POST("/auth/login", ok)
+	api.POST("/auth/register", ok)
+	api.PUT("/admin/kill-switches/:operation", ok)
+	return e
+}
+
+func serve(e *echo.Echo, method, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	return rec
+}
+
+func TestMaintenance_Enabled(t *testing.T) {
+	e := newMaintenanceServer(nil, true)
+
+	rec := serve(e, http.MethodPost, "/api/payments/card")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "90", rec.Header().Get(echo.HeaderRetryAfter))
+	var body errors.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, errors.CodeMaintenanceMode, body.Code)
+
+	assert.Equal(t, http.StatusServiceUnavailable, serve(e, http.MethodPost, "/api/auth/register").Code)
+	assert.Equal(t, http.StatusOK, serve(e, http.MethodGet, "/api/payments/1").Code, "reads keep working")
+	assert.Equal(t, http.StatusOK, serve(e, http.MethodPost, "/api/auth/login").Code, "sign-in keeps working")
+	assert.Equal(t, http.StatusOK, serve(e, http.MethodPut, "/api/admin/kill-switches/payments").Code, "operators keep working")
+}
+
+func TestMaintenance_RedisFlag(t *testing.T) {
+	mr := miniredis.RunT(t)
+	e := newMaintenanceServer(cache.New(mr.Addr(), "", 0), false)
+
+	assert.Equal(t, http.StatusOK, serve(e, http.MethodPost, "/api/payments/card").Code, "no flag")
+
+	require.NoError(t, mr.Set(MaintenanceModeKey, "true"))
+	assert.Equal(t, http.StatusServiceUnavailable, serve(e, http.MethodPost, "/api/payments/card").Code)
+
+	require.NoError(t, mr.Set(MaintenanceModeKey, "off"))
+	assert.Equal(t, http.StatusOK, serve(e, http.MethodPost, "/api/payments/card").Code)
+
+	// An unreachable redis fails open
+	mr.Close()
+	assert.Equal(t, http.StatusOK, serve(e, http.MethodPost, "/api/payments/card").Code)
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"paytabs/internal/auth"
+	"paytabs/internal/errors"
+)
+
+// RejectRevokedTokens rejects requests whose access token was blacklisted on logout with
+// 401. It runs after JWT authentication, which stores the token's *auth.Claims under the
+// "user" key. Tokens issued without a token ID cannot be blacklisted and pass through. When
+// the blacklist cannot be read the request is refused with 503, as the token may be revoked.
+func RejectRevokedTokens(tokenStore auth.TokenStoreInterface) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims, ok := c.Get("user").(*auth.Claims)
+			if !ok || claims.ID == "" {
+				return next(c)
+			}
+			revoked, err := tokenStore.IsAccessTokenBlacklisted(c.Request().Context(), claims.ID)
+			if err != nil {
+				log.Printf("revoked tokens: check token %s: %v", claims.ID, err)
+				return echo.NewHTTPError(http.StatusServiceUnavailable, errors.ErrorResponse{
+					Error: "token revocation cannot be checked right now, try again later",
+					Code:  errors.CodeServiceUnavailable,
+				})
+			}
+			if revoked {
+				return echo.NewHTTPError(http.StatusUnauthorized, errors.ErrorResponse{
+					Error: "token has been revoked",
+					Code:  errors.CodeInvalidToken,
+				})
+			}
+			return next(c)
+		}
+	}
+}
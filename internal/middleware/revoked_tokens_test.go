@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"paytabs/internal/auth"
+	"paytabs/internal/cache"
+)
+
+func TestRejectRevokedTokens(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := auth.NewTokenStore(cache.New(mr.Addr(), "", 0), 0)
+	require.NoError(t, store.BlacklistAccessToken(context.Background(), "revoked-id", time.Minute))
+
+	tests := []struct {
+		name       string
+		claims     *auth.Claims
+		wantStatus int
+	}{
+		{name: "live token", claims: &auth.Claims{RegisteredClaims: jwt.RegisteredClaims{ID: "live-id"}}, wantStatus: http.StatusOK},
+		{name: "revoked token", claims: &auth.Claims{RegisteredClaims: jwt.RegisteredClaims{ID: "revoked-id"}}, wantStatus: http.StatusUnauthorized},
+		{name: "token without ID", claims: &auth.Claims{}, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/me", nil), httptest.NewRecorder())
+			c.Set("user", tt.claims)
+
+			err := RejectRevokedTokens(store)(func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})(c)
+
+			if tt.wantStatus == http.StatusOK {
+				assert.NoError(t, err)
+				return
+			}
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, tt.wantStatus, httpErr.Code)
+		})
+	}
+
+	// The blacklist entry expires with the token
+	mr.FastForward(2 * time.Minute)
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/api/me", nil), httptest.NewRecorder())
+	c.Set("user", &auth.Claims{RegisteredClaims: jwt.RegisteredClaims{ID: "revoked-id"}})
+	assert.NoError(t, RejectRevokedTokens(store)(func(c echo.Context) error { return nil })(c))
+}
+
+func TestRejectRevokedTokens_FailsClosed(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := auth.NewTokenStore(cache.New(mr.Addr(), "", 0), 0)
+	mr.Close()
+
+	// Neither a revocation nor the check reports success while Redis is down
+	assert.Error(t, store.BlacklistAccessToken(context.Background(), "revoked-id", time.Minute))
+
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/api/me", nil), httptest.NewRecorder())
+	c.Set("user", &auth.Claims{RegisteredClaims: jwt.RegisteredClaims{ID: "live-id"}})
+	err := RejectRevokedTokens(store)(func(c echo.Context) error { return nil })(c)
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusServiceUnavailable, httpErr.Code)
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"paytabs/internal/errors"
+)
+
+// TraceIDHeader carries the trace ID of a traced response, so a slow or failed request
+// can be looked up in the tracing backend.
+const TraceIDHeader = "X-Trace-Id"
+
+// Tracing starts the root span of each request, continuing the caller's trace when the
+// request carries W3C trace context. The span is named after the route template, records
+// the request ID set by the RequestID middleware and the response status, and is marked
+// failed for 5xx responses. Repository and cache spans started with the request's context
+// nest under it. With the no-op tracer it only passes the context through.
+func Tracing(tracer trace.Tracer) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+			ctx, span := tracer.Start(ctx, req.Method+" "+route,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.request.method", req.Method),
+					attribute.String("http.route", route),
+					attribute.String("url.path", req.URL.Path),
+					attribute.String("request.id", c.Response().Header().Get(echo.HeaderXRequestID)),
+				),
+			)
+			defer span.End()
+
+			if sc := span.SpanContext(); sc.IsValid() {
+				c.Response().Header().Set(TraceIDHeader, sc.TraceID().String())
+			}
+			c.SetRequest(req.WithContext(ctx))
+
+			err := next(c)
+
+			status := responseStatus(c, err)
+			span.SetAttributes(attribute.Int("http.response.status_code", status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+				if err != nil {
+					span.RecordError(err)
+				}
+			}
+			return err
+		}
+	}
+}
+
+// responseStatus is the status the request is answered with: the one written, or the one
+// the error handler will write for err.
+func responseStatus(c echo.Context, err error) int {
+	if err == nil {
+		return c.Response().Status
+	}
+	var echoErr *echo.HTTPError
+	if stderrors.As(err, &echoErr) {
+		return echoErr.Code
+	}
+	var appErr *errors.HTTPError
+	if stderrors.As(err, &appErr) {
+		return appErr.StatusCode
+	}
+	return http.StatusInternalServerError
+}
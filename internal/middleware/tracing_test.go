@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newTracingServer returns an Echo instance whose requests are traced into the recorder.
+func newTracingServer(recorder *tracetest.SpanRecorder) *echo.Echo {
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	e := echo.New()
+	e.Use(middleware.RequestID())
+	e.Use(Tracing(provider.Tracer("test")))
+	e.GET("/api/payments/:id", func(c echo.Context) error {
+		// Work done with the request's context joins the request's trace
+		_, child := provider.Tracer("test").Start(c.Request().Context(), "db.query")
+		child.End()
+		return c.NoContent(http.StatusOK)
+	})
+	e.POST("/api/payments/card", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "down")
+	})
+	return e
+}
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracing_RootSpanPerRequest(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	e := newTracingServer(recorder)
+
+	rec := serve(e, http.MethodGet, "/api/payments/42")
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	child, root := spans[0], spans[1]
+	assert.Equal(t, "GET /api/payments/:id", root.Name())
+	assert.Equal(t, trace.SpanKindServer, root.SpanKind())
+	assert.Equal(t, root.SpanContext().SpanID(), child.Parent().SpanID())
+	assert.Equal(t, int64(http.StatusOK), spanAttribute(root, "http.response.status_code").AsInt64())
+	assert.Equal(t, "/api/payments/42", spanAttribute(root, "url.path").AsString())
+
+	// The trace and the request ID point at each other
+	assert.Equal(t, root.SpanContext().TraceID().String(), rec.Header().Get(TraceIDHeader))
+	assert.Equal(t, rec.Header().Get(echo.HeaderXRequestID), spanAttribute(root, "request.id").AsString())
+	assert.Equal(t, codes.Unset, root.Status().Code)
+}
+
+func TestTracing_ServerErrorMarksSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	e := newTracingServer(recorder)
+
+	serve(e, http.MethodPost, "/api/payments/card")
+	serve(e, http.MethodGet, "/api/nowhere")
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, int64(http.StatusServiceUnavailable), spanAttribute(spans[0], "http.response.status_code").AsInt64())
+	assert.Equal(t, codes.Unset, spans[1].Status().Code, "a 404 is the client's error")
+	assert.Equal(t, int64(http.StatusNotFound), spanAttribute(spans[1], "http.response.status_code").AsInt64())
+}
+
+func TestTracing_ContinuesCallerTrace(t *testing.T) {
+	previous := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(previous) })
+
+	recorder := tracetest.NewSpanRecorder()
+	e := newTracingServer(recorder)
+	req := httptest.NewRequest(http.MethodGet, "/api/payments/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	root := spans[1]
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", root.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", root.Parent().SpanID().String())
+}
@@ -1,9 +1,11 @@
 package model
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
@@ -11,9 +13,29 @@ import (
 type Account struct {
 	ID           uuid.UUID      `json:"id" gorm:"type:char(36);primaryKey"`
 	Name         string          `json:"name" gorm:"size:255;not null;index"`
-	Email        string          `json:"email" gorm:"uniqueIndex;size:255;not null"`
+	Email        string          `json:"email" gorm:"uniqueIndex;size:255;not null"` // Stored normalized, see NormalizeEmail
 	PasswordHash string          `json:"-" gorm:"size:255;not null"` // Never expose in JSON
 	IsMerchant   bool            `json:"is_merchant" gorm:"default:false;index"`
+	// Balance holds funds credited to the account itself (e.g. merchant proceeds), separate from card balances.
+	Balance decimal.Decimal `json:"balance" gorm:"type:decimal(20,2);not null;default:0"`
+	// PassFeeToCustomer charges the processing fee to the paying card instead of deducting it from the merchant.
+	PassFeeToCustomer bool `json:"pass_fee_to_customer" gorm:"default:false"`
+	// NotifyOnPayment opts a merchant into an email for every accepted payment.
+	NotifyOnPayment bool `json:"notify_on_payment" gorm:"default:false"`
+	// Currency is the ISO 4217 code new cards inherit when they do not name one. Empty means the server default.
+	Currency string `json:"currency" gorm:"size:3;not null;default:''"`
+	// TestMode makes a merchant's payments simulated: they are validated and decided as usual but never move money.
+	TestMode bool `json:"test_mode" gorm:"default:false"`
+	// MaxPaymentAmount caps a single payment to this merchant, on top of MAX_PAYMENT_AMOUNT; the
+	// lower of the two applies. Zero means only the global limit. Set by operators.
+	MaxPaymentAmount decimal.Decimal `json:"max_payment_amount" gorm:"type:decimal(20,2);not null;default:0"`
+	// LowBalanceThreshold and HighBalanceThreshold alert a merchant when its balance drops below
+	// or reaches them. Zero turns an alert off.
+	LowBalanceThreshold  decimal.Decimal `json:"low_balance_threshold" gorm:"type:decimal(20,2);not null;default:0"`
+	HighBalanceThreshold decimal.Decimal `json:"high_balance_threshold" gorm:"type:decimal(20,2);not null;default:0"`
+	// EmailVerified is set once the owner redeems the token mailed on registration. While email
+	// verification is on, Login refuses accounts without it.
+	EmailVerified bool `json:"email_verified" gorm:"not null;default:false"`
 	Active       bool            `json:"active" gorm:"default:true;index"`
 	CreatedAt    time.Time       `json:"created_at"`
 	UpdatedAt    time.Time       `json:"updated_at"`
@@ -30,3 +52,14 @@ func (a *Account) BeforeCreate(tx *gorm.DB) error {
 	}
 	return nil
 }
+
+// BeforeSave normalizes the email so the unique index compares canonical addresses.
+func (a *Account) BeforeSave(tx *gorm.DB) error {
+	a.Email = NormalizeEmail(a.Email)
+	return nil
+}
+
+// NormalizeEmail returns the canonical form of an email address: trimmed and lowercased.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
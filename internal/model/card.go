@@ -8,13 +8,18 @@ import (
 	"gorm.io/gorm"
 )
 
+// CardBalanceCheck names the CHECK constraint declared on Card.Balance that keeps card
+// balances at or above zero.
+const CardBalanceCheck = "chk_cards_balance_non_negative"
+
 // Card represents a payment card linked to an account.
 type Card struct {
 	ID          uuid.UUID       `json:"id" gorm:"type:char(36);primaryKey"`
 	AccountID   uuid.UUID       `json:"account_id" gorm:"type:char(36);not null;index"`
 	CardNumber  string          `json:"card_number" gorm:"size:19;not null"` // Masked card number
 	CardExpiry  string          `json:"card_expiry" gorm:"size:5;not null"`  // MM/YY format
-	Balance     decimal.Decimal `json:"balance" gorm:"type:decimal(20,2);not null;default:0"`
+	Balance     decimal.Decimal `json:"balance" gorm:"type:decimal(20,2);not null;default:0;check:chk_cards_balance_non_negative,balance >= 0"`
+	Currency    string          `json:"currency" gorm:"size:3;not null;default:''"` // ISO 4217; empty on cards created before currencies were stored
 	Active      bool            `json:"active" gorm:"default:true;index"`
 	CreatedAt   time.Time       `json:"created_at"`
 	UpdatedAt   time.Time       `json:"updated_at"`
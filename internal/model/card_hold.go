@@ -0,0 +1,55 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// CardHoldStatus represents the status of a card hold.
+type CardHoldStatus string
+
+const (
+	CardHoldStatusActive   CardHoldStatus = "active"
+	CardHoldStatusReleased CardHoldStatus = "released"
+	// CardHoldStatusExpired is never stored: an active hold past its expiry reports it.
+	CardHoldStatusExpired CardHoldStatus = "expired"
+)
+
+// CardHold reserves part of a card's balance, e.g. a pre-authorisation for a rental, without
+// moving money. The card's balance is unchanged; what it can spend is its balance less its
+// active, unexpired holds.
+type CardHold struct {
+	ID                uuid.UUID       `json:"id" gorm:"type:char(36);primaryKey"`
+	CardID            uuid.UUID       `json:"card_id" gorm:"type:char(36);not null;index:idx_card_holds_card_status"`
+	PlacedByAccountID uuid.UUID       `json:"placed_by_account_id" gorm:"type:char(36);not null;index"`
+	Amount            decimal.Decimal `json:"amount" gorm:"type:decimal(20,2);not null"`
+	Reference         string          `json:"reference" gorm:"size:64"`
+	Status            CardHoldStatus  `json:"status" gorm:"type:varchar(20);not null;default:'active';index:idx_card_holds_card_status"`
+	ExpiresAt         time.Time       `json:"expires_at" gorm:"not null"`
+	ReleasedAt        *time.Time      `json:"released_at,omitempty"`
+	CreatedAt         time.Time       `json:"created_at"`
+	UpdatedAt         time.Time       `json:"updated_at"`
+
+	// Relations
+	Card            Card    `json:"-" gorm:"foreignKey:CardID"`
+	PlacedByAccount Account `json:"-" gorm:"foreignKey:PlacedByAccountID"`
+}
+
+// StatusAt returns the hold's status at now, reporting an active hold past its expiry as expired.
+func (h *CardHold) StatusAt(now time.Time) CardHoldStatus {
+	if h.Status == CardHoldStatusActive && !now.Before(h.ExpiresAt) {
+		return CardHoldStatusExpired
+	}
+	return h.Status
+}
+
+// BeforeCreate sets UUID before creating the record.
+func (h *CardHold) BeforeCreate(tx *gorm.DB) error {
+	if h.ID == uuid.Nil {
+		h.ID = uuid.New()
+	}
+	return nil
+}
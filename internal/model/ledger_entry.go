@@ -0,0 +1,44 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// LedgerReason describes why a card balance changed.
+type LedgerReason string
+
+const (
+	LedgerReasonPayment     LedgerReason = "payment"
+	LedgerReasonTransferIn  LedgerReason = "transfer_in"
+	LedgerReasonTransferOut LedgerReason = "transfer_out"
+	// LedgerReasonWithdrawal moves card funds to the owning account's balance.
+	LedgerReasonWithdrawal LedgerReason = "withdrawal"
+	// LedgerReasonRefund returns funds of a refunded payment to the card.
+	LedgerReasonRefund LedgerReason = "refund"
+)
+
+// LedgerEntry records a single change to a card balance. Entries are append-only.
+type LedgerEntry struct {
+	ID           uuid.UUID       `json:"id" gorm:"type:char(36);primaryKey"`
+	CardID       uuid.UUID       `json:"card_id" gorm:"type:char(36);not null;index:idx_ledger_card_created,priority:1"`
+	Delta        decimal.Decimal `json:"delta" gorm:"type:decimal(20,2);not null"`
+	BalanceAfter decimal.Decimal `json:"balance_after" gorm:"type:decimal(20,2);not null"`
+	Reason       LedgerReason    `json:"reason" gorm:"type:varchar(30);not null"`
+	ReferenceID  uuid.UUID       `json:"reference_id" gorm:"type:char(36);not null;index"` // Payment or transfer ID
+	CreatedAt    time.Time       `json:"created_at" gorm:"index:idx_ledger_card_created,priority:2"`
+
+	// Relations
+	Card Card `json:"-" gorm:"foreignKey:CardID"`
+}
+
+// BeforeCreate sets UUID before creating the record.
+func (l *LedgerEntry) BeforeCreate(tx *gorm.DB) error {
+	if l.ID == uuid.Nil {
+		l.ID = uuid.New()
+	}
+	return nil
+}
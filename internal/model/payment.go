@@ -15,24 +15,79 @@ const (
 	PaymentStatusPending  PaymentStatus = "pending"
 	PaymentStatusAccepted PaymentStatus = "accepted"
 	PaymentStatusFailed   PaymentStatus = "failed"
+	// PaymentStatusCancelled is a pending payment the merchant voided before it settled.
+	PaymentStatusCancelled PaymentStatus = "cancelled"
+	// PaymentStatusRefunded is a refund of an accepted payment, recorded as a payment of its
+	// own linked to the original by RefundOfID.
+	PaymentStatusRefunded PaymentStatus = "refunded"
 )
 
+// PaymentFailureReason classifies why a payment failed.
+type PaymentFailureReason string
+
+const (
+	// Business rejections: retrying the same payment will fail the same way.
+	PaymentFailureMerchantNotFound    PaymentFailureReason = "merchant_not_found"
+	PaymentFailureMerchantInactive    PaymentFailureReason = "merchant_inactive"
+	PaymentFailureNotMerchant         PaymentFailureReason = "not_merchant"
+	PaymentFailureCardNotFound        PaymentFailureReason = "card_not_found"
+	PaymentFailureCardInactive        PaymentFailureReason = "card_inactive"
+	PaymentFailureAmountBelowFee      PaymentFailureReason = "amount_below_fee"
+	PaymentFailureDailyLimit          PaymentFailureReason = "daily_limit_exceeded"
+	PaymentFailureInsufficientBalance PaymentFailureReason = "insufficient_balance"
+	PaymentFailureAccountOnHold       PaymentFailureReason = "account_on_hold"
+	PaymentFailureAmountOutOfRange    PaymentFailureReason = "amount_out_of_range"
+	PaymentFailureCurrencyMismatch    PaymentFailureReason = "currency_mismatch"
+
+	// PaymentFailureProcessingError is a transient infrastructure failure (database or cache
+	// errors) that may succeed on retry.
+	PaymentFailureProcessingError PaymentFailureReason = "processing_error"
+)
+
+// Transient reports whether a payment that failed for this reason may succeed if retried.
+func (r PaymentFailureReason) Transient() bool {
+	return r == PaymentFailureProcessingError
+}
+
 // Payment represents a card-based payment transaction.
 type Payment struct {
-	ID                uuid.UUID       `json:"id" gorm:"type:char(36);primaryKey"`
-	MerchantAccountID uuid.UUID       `json:"merchant_account_id" gorm:"type:char(36);not null;index"`
-	CardID            uuid.UUID       `json:"card_id" gorm:"type:char(36);not null;index"`
-	Amount            decimal.Decimal `json:"amount" gorm:"type:decimal(20,2);not null"`
-	Status            PaymentStatus   `json:"status" gorm:"type:varchar(20);not null;default:'pending';index"`
-	CreatedAt         time.Time       `json:"created_at"`
-	UpdatedAt         time.Time       `json:"updated_at"`
-	DeletedAt         gorm.DeletedAt  `json:"-" gorm:"index"`
+	ID                   uuid.UUID            `json:"id" gorm:"type:char(36);primaryKey"`
+	MerchantAccountID    uuid.UUID            `json:"merchant_account_id" gorm:"type:char(36);not null;index;uniqueIndex:idx_payments_merchant_reference,priority:1;uniqueIndex:idx_payments_charge_idempotency_key,priority:1"`
+	CardID               uuid.UUID            `json:"card_id" gorm:"type:char(36);not null;index"`
+	Amount               decimal.Decimal      `json:"amount" gorm:"type:decimal(20,2);not null"`
+	GrossAmount          decimal.Decimal      `json:"gross_amount" gorm:"type:decimal(20,2);not null;default:0"` // Charged to the card
+	FeeAmount            decimal.Decimal      `json:"fee_amount" gorm:"type:decimal(20,2);not null;default:0"`
+	NetAmount            decimal.Decimal      `json:"net_amount" gorm:"type:decimal(20,2);not null;default:0"` // Credited to the merchant
+	Status               PaymentStatus        `json:"status" gorm:"type:varchar(20);not null;default:'pending';index"`
+	FailureReason        PaymentFailureReason `json:"failure_reason,omitempty" gorm:"type:varchar(40)"` // Set when Status is failed
+	RetryOfID            *uuid.UUID           `json:"retry_of_id,omitempty" gorm:"type:char(36);index"` // The original failed payment this one retries
+	TestMode             bool                 `json:"test_mode" gorm:"not null;default:false;index"`    // Taken by a test-mode merchant; moved no money
+	ArchivedAt           *time.Time           `json:"archived_at,omitempty" gorm:"index"`
+	RefundOfID           *uuid.UUID           `json:"refund_of_id,omitempty" gorm:"type:char(36);index;uniqueIndex:idx_payments_refund_idempotency_key,priority:1"` // The accepted payment this refund returns
+	IdempotencyKey       *string              `json:"-" gorm:"type:varchar(255);uniqueIndex:idx_payments_refund_idempotency_key,priority:2"`                        // The Idempotency-Key a refund was requested with; unique per refunded payment
+	MerchantReference    *string              `json:"merchant_reference,omitempty" gorm:"type:varchar(64);uniqueIndex:idx_payments_merchant_reference,priority:2"`  // The merchant's own, e.g. an order number; unique per merchant
+	ChargeIdempotencyKey *string              `json:"-" gorm:"type:varchar(255);uniqueIndex:idx_payments_charge_idempotency_key,priority:2"`                        // The Idempotency-Key an accepted charge was requested with; unique per merchant
+	CreatedAt            time.Time            `json:"created_at"`
+	UpdatedAt            time.Time            `json:"updated_at"`
+	DeletedAt            gorm.DeletedAt       `json:"-" gorm:"index"`
 
 	// Relations
 	MerchantAccount Account `json:"-" gorm:"foreignKey:MerchantAccountID"`
 	Card            Card    `json:"-" gorm:"foreignKey:CardID"`
 }
 
+// PaymentMerchantReferenceIndex is the unique index on a merchant's payment references.
+const PaymentMerchantReferenceIndex = "idx_payments_merchant_reference"
+
+// PaymentRefundIdempotencyIndex is the unique index on the idempotency keys of a payment's refunds.
+const PaymentRefundIdempotencyIndex = "idx_payments_refund_idempotency_key"
+
+// PaymentChargeIdempotencyIndex is the unique index on the idempotency keys of a merchant's accepted charges.
+const PaymentChargeIdempotencyIndex = "idx_payments_charge_idempotency_key"
+
+// MaxIdempotencyKeyLength is the longest idempotency key a payment can be stored with.
+const MaxIdempotencyKeyLength = 255
+
 // BeforeCreate sets UUID before creating the record.
 func (p *Payment) BeforeCreate(tx *gorm.DB) error {
 	if p.ID == uuid.Nil {
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// PayoutStatus represents the status of a merchant payout.
+type PayoutStatus string
+
+const (
+	PayoutStatusPending PayoutStatus = "pending"
+	PayoutStatusPaid    PayoutStatus = "paid"
+	PayoutStatusFailed  PayoutStatus = "failed"
+)
+
+// Payout moves funds from a merchant's account balance to their bank. Pending payouts
+// hold merchant funds; the account balance is only debited once the payout is paid.
+type Payout struct {
+	ID                uuid.UUID       `json:"id" gorm:"type:char(36);primaryKey"`
+	MerchantAccountID uuid.UUID       `json:"merchant_account_id" gorm:"type:char(36);not null;index:idx_payouts_merchant_status"`
+	Amount            decimal.Decimal `json:"amount" gorm:"type:decimal(20,2);not null"`
+	Status            PayoutStatus    `json:"status" gorm:"type:varchar(20);not null;default:'pending';index:idx_payouts_merchant_status"`
+	Reference         string          `json:"reference" gorm:"size:64"`
+	CreatedAt         time.Time       `json:"created_at"`
+	UpdatedAt         time.Time       `json:"updated_at"`
+
+	// Relations
+	MerchantAccount Account `json:"-" gorm:"foreignKey:MerchantAccountID"`
+}
+
+// BeforeCreate sets UUID before creating the record.
+func (p *Payout) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
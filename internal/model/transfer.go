@@ -17,6 +17,16 @@ const (
 	TransferStatusFailed    TransferStatus = "failed"
 )
 
+// TransferDirection describes a transfer from the point of view of one account.
+type TransferDirection string
+
+const (
+	TransferDirectionInbound  TransferDirection = "inbound"
+	TransferDirectionOutbound TransferDirection = "outbound"
+	// TransferDirectionInternal is a transfer between two cards of the same account.
+	TransferDirectionInternal TransferDirection = "internal"
+)
+
 // Transfer represents a card-to-card money transfer.
 type Transfer struct {
 	ID                 uuid.UUID       `json:"id" gorm:"type:char(36);primaryKey"`
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// WebhookSecret is the key a merchant uses to verify webhook signatures. Only the
+// encrypted form is stored; the plaintext is returned once, when it is generated.
+type WebhookSecret struct {
+	AccountID  uuid.UUID `json:"account_id" gorm:"type:char(36);primaryKey"`
+	Ciphertext string    `json:"-" gorm:"type:text;not null"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+
+	// Relations
+	Account Account `json:"-" gorm:"foreignKey:AccountID"`
+}
+
+// WebhookEndpoint is the URL a merchant receives webhook events at.
+type WebhookEndpoint struct {
+	AccountID uuid.UUID `json:"account_id" gorm:"type:char(36);primaryKey"`
+	URL       string    `json:"url" gorm:"size:2048;not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Relations
+	Account Account `json:"-" gorm:"foreignKey:AccountID"`
+}
+
+// WebhookSecretAction is what happened in a webhook secret audit record.
+type WebhookSecretAction string
+
+const (
+	// WebhookSecretActionRegenerated records a new secret being generated and revealed.
+	WebhookSecretActionRegenerated WebhookSecretAction = "regenerated"
+	// WebhookSecretActionDenied records a reveal refused by the rate limit.
+	WebhookSecretActionDenied WebhookSecretAction = "denied"
+)
+
+// WebhookSecretAudit records every attempt to reveal a webhook secret.
+type WebhookSecretAudit struct {
+	ID        uuid.UUID           `json:"id" gorm:"type:char(36);primaryKey"`
+	AccountID uuid.UUID           `json:"account_id" gorm:"type:char(36);not null;index"`
+	Action    WebhookSecretAction `json:"action" gorm:"type:varchar(20);not null"`
+	ClientIP  string              `json:"client_ip" gorm:"size:45"`
+	CreatedAt time.Time           `json:"created_at"`
+}
+
+// BeforeCreate sets UUID before creating the record.
+func (a *WebhookSecretAudit) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
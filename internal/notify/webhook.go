@@ -0,0 +1,144 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedDestination is returned when a webhook URL resolves to a private, loopback,
+// link-local or otherwise internal address and private targets are not allowed.
+var ErrBlockedDestination = errors.New("webhook destination is not a public address")
+
+// cgnatRange is the carrier-grade NAT block (RFC 6598), which net.IP.IsPrivate does not cover.
+var cgnatRange = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// WebhookDeliveryError describes a failed delivery. Retryable is true for timeouts, network
+// failures, 429 and 5xx responses; the caller should not retry anything else.
+type WebhookDeliveryError struct {
+	StatusCode int // Zero when no response was received
+	Retryable  bool
+	Err        error
+}
+
+func (e *WebhookDeliveryError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("webhook delivery failed with status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("webhook delivery failed: %v", e.Err)
+}
+
+func (e *WebhookDeliveryError) Unwrap() error {
+	return e.Err
+}
+
+// WebhookResponse is a delivered webhook's response. Body holds at most the client's
+// response size limit; Truncated reports whether more was sent.
+type WebhookResponse struct {
+	StatusCode int
+	Body       []byte
+	Truncated  bool
+}
+
+// WebhookClient posts webhook payloads to merchant-controlled URLs. Every call is bounded by
+// a total timeout, only the first maxResponseBytes of a response are read, redirects are
+// not followed, and, unless allowPrivate is set, connections to non-public addresses are
+// refused. The address check runs on the resolved IP at connect time, so DNS names that
+// point (or later rebind) to internal hosts are caught too.
+type WebhookClient struct {
+	client           *http.Client
+	maxResponseBytes int64
+}
+
+// NewWebhookClient creates a webhook client.
+func NewWebhookClient(timeout time.Duration, maxResponseBytes int64, allowPrivate bool) *WebhookClient {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return ErrBlockedDestination
+			}
+			return nil
+		}
+	}
+
+	return &WebhookClient{
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				// No proxy: the dial check must see the real destination
+				Proxy:                 nil,
+				DialContext:           dialer.DialContext,
+				TLSHandshakeTimeout:   timeout,
+				ResponseHeaderTimeout: timeout,
+				MaxIdleConnsPerHost:   2,
+			},
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		maxResponseBytes: maxResponseBytes,
+	}
+}
+
+// Post sends body as JSON to rawURL with the given extra headers. A 2xx response returns
+// the response and no error; anything else returns a *WebhookDeliveryError (with the
+// response, when there was one).
+func (c *WebhookClient) Post(ctx context.Context, rawURL string, body []byte, headers map[string]string) (*WebhookResponse, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, &WebhookDeliveryError{Err: fmt.Errorf("invalid webhook URL %q", rawURL)}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, &WebhookDeliveryError{Err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		// Blocked destinations will not become public on retry; everything else (timeouts,
+		// refused connections, resets) is transient
+		return nil, &WebhookDeliveryError{Retryable: !errors.Is(err, ErrBlockedDestination), Err: err}
+	}
+	defer resp.Body.Close()
+
+	// Read one byte past the limit to learn whether the body was cut off
+	data, err := io.ReadAll(io.LimitReader(resp.Body, c.maxResponseBytes+1))
+	if err != nil {
+		return nil, &WebhookDeliveryError{StatusCode: resp.StatusCode, Retryable: true, Err: err}
+	}
+	out := &WebhookResponse{StatusCode: resp.StatusCode, Body: data}
+	if int64(len(data)) > c.maxResponseBytes {
+		out.Body = data[:c.maxResponseBytes]
+		out.Truncated = true
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return out, &WebhookDeliveryError{StatusCode: resp.StatusCode, Retryable: retryable}
+	}
+	return out, nil
+}
+
+// isPublicIP reports whether ip is a globally routable unicast address.
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() || cgnatRange.Contains(ip))
+}
@@ -0,0 +1,163 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookClient_SlowResponderTimesOutAsRetryable(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	client := NewWebhookClient(100*time.Millisecond, 1024, true)
+
+	start := time.Now()
+	_, err := client.Post(context.Background(), srv.URL, []byte(`{}`), nil)
+	elapsed := time.Since(start)
+
+	var deliveryErr *WebhookDeliveryError
+	require.ErrorAs(t, err, &deliveryErr)
+	assert.True(t, deliveryErr.Retryable)
+	assert.Zero(t, deliveryErr.StatusCode)
+	assert.Less(t, elapsed, 2*time.Second)
+}
+
+func TestWebhookClient_OversizedResponseIsTruncated(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chunk := []byte(strings.Repeat("x", 4096))
+		// Far more than the client will read; stops once the client hangs up
+		for i := 0; i < 16*1024; i++ {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	client := NewWebhookClient(5*time.Second, 100, true)
+
+	resp, err := client.Post(context.Background(), srv.URL, []byte(`{}`), nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Len(t, resp.Body, 100)
+	assert.True(t, resp.Truncated)
+}
+
+func TestWebhookClient_SmallResponseIsNotTruncated(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "sig", r.Header.Get("X-Signature"))
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	client := NewWebhookClient(5*time.Second, 2, true)
+
+	resp, err := client.Post(context.Background(), srv.URL, []byte(`{}`), map[string]string{"X-Signature": "sig"})
+
+	require.NoError(t, err)
+	assert.Equal(t, []byte("ok"), resp.Body)
+	assert.False(t, resp.Truncated)
+}
+
+func TestWebhookClient_RejectsPrivateDestinations(t *testing.T) {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+
+	client := NewWebhookClient(time.Second, 1024, false)
+
+	_, err := client.Post(context.Background(), srv.URL, []byte(`{}`), nil)
+
+	var deliveryErr *WebhookDeliveryError
+	require.ErrorAs(t, err, &deliveryErr)
+	assert.ErrorIs(t, err, ErrBlockedDestination)
+	assert.False(t, deliveryErr.Retryable)
+	assert.False(t, called)
+}
+
+func TestWebhookClient_RejectsNonHTTPURLs(t *testing.T) {
+	client := NewWebhookClient(time.Second, 1024, false)
+
+	for _, u := range []string{"ftp://example.com/hook", "file:///etc/passwd", "not a url", "http://"} {
+		_, err := client.Post(context.Background(), u, nil, nil)
+
+		var deliveryErr *WebhookDeliveryError
+		require.ErrorAs(t, err, &deliveryErr, u)
+		assert.False(t, deliveryErr.Retryable, u)
+	}
+}
+
+func TestWebhookClient_StatusRetryability(t *testing.T) {
+	tests := []struct {
+		status    int
+		retryable bool
+	}{
+		{http.StatusInternalServerError, true},
+		{http.StatusBadGateway, true},
+		{http.StatusTooManyRequests, true},
+		{http.StatusBadRequest, false},
+		{http.StatusNotFound, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			client := NewWebhookClient(time.Second, 1024, true)
+
+			resp, err := client.Post(context.Background(), srv.URL, []byte(`{}`), nil)
+
+			var deliveryErr *WebhookDeliveryError
+			require.True(t, errors.As(err, &deliveryErr))
+			assert.Equal(t, tt.status, deliveryErr.StatusCode)
+			assert.Equal(t, tt.retryable, deliveryErr.Retryable)
+			require.NotNil(t, resp)
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	tests := map[string]bool{
+		"8.8.8.8":         true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"100.64.0.1":      false,
+		"0.0.0.0":         false,
+		"::1":             false,
+		"fd00::1":         false,
+		"fe80::1":         false,
+		"224.0.0.1":       false,
+	}
+
+	for addr, want := range tests {
+		assert.Equal(t, want, isPublicIP(net.ParseIP(addr)), addr)
+	}
+}
@@ -2,9 +2,13 @@ package repository
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"paytabs/internal/model"
 )
@@ -15,12 +19,41 @@ type AccountRepository interface {
 	Update(ctx context.Context, account *model.Account) error
 	FindByID(ctx context.Context, id uuid.UUID) (*model.Account, error)
 	FindByIDForUpdate(ctx context.Context, id uuid.UUID) (*model.Account, error)
+	FindByIDs(ctx context.Context, ids []uuid.UUID) ([]model.Account, error)
 	FindByEmail(ctx context.Context, email string) (*model.Account, error)
 	ListActive(ctx context.Context) ([]model.Account, error)
+	List(ctx context.Context, filter AccountFilter, sort AccountSort, limit, offset int) ([]model.Account, int64, error)
+	Count(ctx context.Context) (int64, error)
 	FindByIDOrCreate(ctx context.Context, account *model.Account) (*model.Account, error)
 	// Transaction methods
 	WithTransaction(ctx context.Context, fn func(ctx context.Context, repo AccountRepository) error) error
+	FindByIDTx(ctx context.Context, tx interface{}, id uuid.UUID) (*model.Account, error)
 	FindByIDForUpdateTx(ctx context.Context, tx interface{}, id uuid.UUID) (*model.Account, error)
+	CreditBalanceTx(ctx context.Context, tx interface{}, id uuid.UUID, amount decimal.Decimal) error
+	UpdateColumns(ctx context.Context, id uuid.UUID, columns map[string]interface{}) error
+	UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error
+}
+
+// AccountFilter narrows List. Nil fields match every account; a non-empty Query matches
+// accounts whose name or email contains it.
+type AccountFilter struct {
+	IsMerchant *bool
+	Active     *bool
+	Query      string
+}
+
+// AccountSortField is a column List can order by.
+type AccountSortField string
+
+const (
+	AccountSortCreatedAt AccountSortField = "created_at"
+	AccountSortBalance   AccountSortField = "balance"
+)
+
+// AccountSort orders List; the zero value is newest first.
+type AccountSort struct {
+	Field     AccountSortField
+	Ascending bool
 }
 
 type accountRepository struct {
@@ -46,17 +79,31 @@ func (r *accountRepository) Update(ctx context.Context, account *model.Account)
 func (r *accountRepository) FindByID(ctx context.Context, id uuid.UUID) (*model.Account, error) {
 	var account model.Account
 	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&account).Error; err != nil {
-		return nil, err
+		return nil, wrapBalanceScan(err, "account", id.String())
 	}
 	return &account, nil
 }
 
-// FindByIDForUpdate finds an account by ID with row-level lock for update.
+// FindByIDs finds the accounts with the given IDs in one query. IDs with no account are
+// skipped, so the result may be shorter than ids and is in no particular order.
+func (r *accountRepository) FindByIDs(ctx context.Context, ids []uuid.UUID) ([]model.Account, error) {
+	var accounts []model.Account
+	if len(ids) == 0 {
+		return accounts, nil
+	}
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&accounts).Error; err != nil {
+		return nil, wrapBalanceScan(err, "accounts", fmt.Sprintf("%d ids", len(ids)))
+	}
+	return accounts, nil
+}
+
+// FindByIDForUpdate finds an account by ID with row-level lock for update. A lock that is not
+// acquired within the session's innodb_lock_wait_timeout returns errors.ErrLockTimeout.
 func (r *accountRepository) FindByIDForUpdate(ctx context.Context, id uuid.UUID) (*model.Account, error) {
 	var account model.Account
-	if err := r.db.WithContext(ctx).Set("gorm:query_option", "FOR UPDATE").
+	if err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
 		Where("id = ?", id).First(&account).Error; err != nil {
-		return nil, err
+		return nil, wrapLockedRead(err, "account", id.String())
 	}
 	return &account, nil
 }
@@ -64,12 +111,19 @@ func (r *accountRepository) FindByIDForUpdate(ctx context.Context, id uuid.UUID)
 // FindByEmail finds an account by email.
 func (r *accountRepository) FindByEmail(ctx context.Context, email string) (*model.Account, error) {
 	var account model.Account
-	if err := r.db.WithContext(ctx).Where("email = ?", email).First(&account).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("email = ?", model.NormalizeEmail(email)).First(&account).Error; err != nil {
 		return nil, err
 	}
 	return &account, nil
 }
 
+// Count counts every account row, soft-deleted ones included.
+func (r *accountRepository) Count(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Unscoped().Model(&model.Account{}).Count(&count).Error
+	return count, err
+}
+
 // ListActive lists all active accounts.
 func (r *accountRepository) ListActive(ctx context.Context) ([]model.Account, error) {
 	var accounts []model.Account
@@ -105,14 +159,109 @@ func (r *accountRepository) WithTransaction(ctx context.Context, fn func(ctx con
 	})
 }
 
-// FindByIDForUpdateTx finds an account by ID with row-level lock within a transaction.
+// FindByIDTx finds an account by ID within a transaction, without locking it.
+func (r *accountRepository) FindByIDTx(ctx context.Context, tx interface{}, id uuid.UUID) (*model.Account, error) {
+	txDB := tx.(*gorm.DB)
+	var account model.Account
+	if err := txDB.WithContext(ctx).Where("id = ?", id).First(&account).Error; err != nil {
+		return nil, wrapBalanceScan(err, "account", id.String())
+	}
+	return &account, nil
+}
+
+// FindByIDForUpdateTx finds an account by ID with row-level lock within a transaction. A lock that
+// is not acquired within the session's innodb_lock_wait_timeout returns errors.ErrLockTimeout.
 func (r *accountRepository) FindByIDForUpdateTx(ctx context.Context, tx interface{}, id uuid.UUID) (*model.Account, error) {
 	txDB := tx.(*gorm.DB)
 	var account model.Account
-	if err := txDB.WithContext(ctx).Set("gorm:query_option", "FOR UPDATE").
+	if err := txDB.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
 		Where("id = ?", id).First(&account).Error; err != nil {
-		return nil, err
+		return nil, wrapLockedRead(err, "account", id.String())
 	}
 	return &account, nil
 }
 
+
+// CreditBalanceTx adds amount to an account balance within a transaction. The increment
+// happens in SQL so concurrent credits to the same merchant cannot overwrite each other.
+func (r *accountRepository) CreditBalanceTx(ctx context.Context, tx interface{}, id uuid.UUID, amount decimal.Decimal) error {
+	txDB := tx.(*gorm.DB)
+	result := txDB.WithContext(ctx).Model(&model.Account{}).
+		Where("id = ?", id).
+		Update("balance", gorm.Expr("balance + ?", amount))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// UpdateColumns sets only the given columns of an account, leaving the rest of the row
+// (notably the balance) as it is in the database.
+func (r *accountRepository) UpdateColumns(ctx context.Context, id uuid.UUID, columns map[string]interface{}) error {
+	return r.db.WithContext(ctx).Model(&model.Account{}).Where("id = ?", id).Updates(columns).Error
+}
+
+// UpdatePassword replaces the account's password hash.
+func (r *accountRepository) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	return r.db.WithContext(ctx).Model(&model.Account{}).Where("id = ?", id).Update("password_hash", passwordHash).Error
+}
+
+// List lists accounts matching filter in sort order, ties broken by id, along with the total
+// number of matching accounts. Password hashes are not read.
+func (r *accountRepository) List(ctx context.Context, filter AccountFilter, sort AccountSort, limit, offset int) ([]model.Account, int64, error) {
+	query := filterAccounts(r.db.WithContext(ctx).Model(&model.Account{}), filter).Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var accounts []model.Account
+	if err := query.
+		Omit("password_hash").
+		Order(accountOrder(sort)).
+		Limit(limit).
+		Offset(offset).
+		Find(&accounts).Error; err != nil {
+		return nil, 0, wrapBalanceScan(err, "accounts", "list")
+	}
+	return accounts, total, nil
+}
+
+func filterAccounts(query *gorm.DB, filter AccountFilter) *gorm.DB {
+	if filter.IsMerchant != nil {
+		query = query.Where("is_merchant = ?", *filter.IsMerchant)
+	}
+	if filter.Active != nil {
+		query = query.Where("active = ?", *filter.Active)
+	}
+	if filter.Query != "" {
+		pattern := "%" + escapeLike(filter.Query) + "%"
+		query = query.Where("name LIKE ? OR email LIKE ?", pattern, pattern)
+	}
+	return query
+}
+
+// accountOrder is the ORDER BY of sort. Only known columns are used; anything else sorts by
+// created_at.
+func accountOrder(sort AccountSort) string {
+	column := string(AccountSortCreatedAt)
+	if sort.Field == AccountSortBalance {
+		column = string(AccountSortBalance)
+	}
+	direction := "DESC"
+	if sort.Ascending {
+		direction = "ASC"
+	}
+	return column + " " + direction + ", id " + direction
+}
+
+// likeEscaper escapes the LIKE wildcards, and MySQL's escape character, in user input.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"paytabs/internal/model"
+)
+
+// accountListSQL renders the page query List runs for filter and sort.
+func accountListSQL(t *testing.T, filter AccountFilter, sort AccountSort) string {
+	t.Helper()
+	gdb, _ := newHeldLockDB(t)
+	return gdb.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		var accounts []model.Account
+		return filterAccounts(tx.Model(&model.Account{}), filter).Omit("password_hash").Order(accountOrder(sort)).Limit(20).Find(&accounts)
+	})
+}
+
+func TestAccountRepository_ListFilters(t *testing.T) {
+	yes, no := true, false
+
+	tests := []struct {
+		name     string
+		filter   AccountFilter
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "no filter",
+			excludes: []string{"is_merchant =", "active =", "LIKE"},
+		},
+		{
+			name:     "merchants",
+			filter:   AccountFilter{IsMerchant: &yes},
+			contains: []string{"is_merchant = true"},
+			excludes: []string{"active =", "LIKE"},
+		},
+		{
+			name:     "inactive",
+			filter:   AccountFilter{Active: &no},
+			contains: []string{"active = false"},
+			excludes: []string{"is_merchant =", "LIKE"},
+		},
+		{
+			name:     "search matches name or email",
+			filter:   AccountFilter{Query: "alice"},
+			contains: []string{"(name LIKE '%alice%' OR email LIKE '%alice%')"},
+		},
+		{
+			name:     "search wildcards are literal",
+			filter:   AccountFilter{Query: `50%_off\`},
+			contains: []string{`name LIKE '%50\%\_off\\%'`},
+		},
+		{
+			name:     "all filters combine",
+			filter:   AccountFilter{IsMerchant: &no, Active: &yes, Query: "shop"},
+			contains: []string{"is_merchant = false AND active = true AND (name LIKE '%shop%' OR email LIKE '%shop%')"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql := accountListSQL(t, tt.filter, AccountSort{})
+
+			assert.Contains(t, sql, "`accounts`.`deleted_at` IS NULL")
+			assert.NotContains(t, sql, "password_hash")
+			for _, s := range tt.contains {
+				assert.Contains(t, sql, s)
+			}
+			for _, s := range tt.excludes {
+				assert.NotContains(t, sql, s)
+			}
+		})
+	}
+}
+
+func TestAccountRepository_ListSort(t *testing.T) {
+	assert.Contains(t, accountListSQL(t, AccountFilter{}, AccountSort{}), "ORDER BY created_at DESC, id DESC")
+	assert.Contains(t, accountListSQL(t, AccountFilter{}, AccountSort{Field: AccountSortBalance, Ascending: true}), "ORDER BY balance ASC, id ASC")
+	assert.Contains(t, accountListSQL(t, AccountFilter{}, AccountSort{Field: "password_hash"}), "ORDER BY created_at DESC", "unknown columns are never sorted by")
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	stderrors "errors"
+	"fmt"
+	"log"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/shopspring/decimal"
+
+	apperrors "paytabs/internal/errors"
+)
+
+// mysqlCheckViolation is MySQL's ER_CHECK_CONSTRAINT_VIOLATED error number.
+const mysqlCheckViolation = 3819
+
+// guardCardBalance refuses a negative card balance before it reaches the database, so a bug
+// in a mutation path fails the same way on servers that do not enforce CHECK constraints.
+// The refusal is logged and returned as the bare errors.ErrInsufficientBalance, which callers
+// compare by identity. Account balances are not guarded.
+func guardCardBalance(id fmt.Stringer, newBalance interface{}) error {
+	var balance decimal.Decimal
+	switch v := newBalance.(type) {
+	case decimal.Decimal:
+		balance = v
+	case *decimal.Decimal:
+		if v == nil {
+			return nil
+		}
+		balance = *v
+	default:
+		return nil
+	}
+	if balance.IsNegative() {
+		log.Printf("card %s: refused negative balance %s", id, balance.StringFixed(2))
+		return apperrors.ErrInsufficientBalance
+	}
+	return nil
+}
+
+// wrapBalanceCheck turns a violation of the card balance constraint into
+// errors.ErrInsufficientBalance, logging the database's message. Any other error is returned
+// unchanged.
+func wrapBalanceCheck(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if stderrors.As(err, &mysqlErr) && mysqlErr.Number == mysqlCheckViolation {
+		log.Printf("card balance constraint: %s", mysqlErr.Message)
+		return apperrors.ErrInsufficientBalance
+	}
+	return err
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	apperrors "paytabs/internal/errors"
+	"paytabs/internal/model"
+)
+
+func TestCardRepository_UpdateBalance_RefusesNegative(t *testing.T) {
+	// The guard runs before any query, so the repository needs no database
+	repo := &cardRepository{}
+	id := uuid.New()
+
+	err := repo.UpdateBalance(context.Background(), id, decimal.RequireFromString("-0.01"))
+	assert.Equal(t, apperrors.ErrInsufficientBalance, err)
+
+	err = repo.UpdateBalanceTx(context.Background(), &gorm.DB{}, id, decimal.NewFromInt(-5))
+	assert.Equal(t, apperrors.ErrInsufficientBalance, err)
+
+	assert.NoError(t, guardCardBalance(id, decimal.Zero), "zero is a valid card balance")
+}
+
+func TestWrapBalanceCheck(t *testing.T) {
+	violation := &mysql.MySQLError{Number: 3819, Message: "Check constraint 'chk_cards_balance_non_negative' is violated."}
+	assert.Equal(t, apperrors.ErrInsufficientBalance, wrapBalanceCheck(violation))
+
+	duplicate := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}
+	assert.Equal(t, error(duplicate), wrapBalanceCheck(duplicate))
+	assert.NoError(t, wrapBalanceCheck(nil))
+}
+
+func TestCardModel_DeclaresBalanceCheck(t *testing.T) {
+	s, err := schema.Parse(&model.Card{}, &sync.Map{}, schema.NamingStrategy{})
+	require.NoError(t, err)
+
+	check, ok := s.ParseCheckConstraints()[model.CardBalanceCheck]
+	require.True(t, ok)
+	assert.Equal(t, "balance >= 0", check.Constraint)
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	apperrors "paytabs/internal/errors"
+)
+
+// balanceColumns are the decimal columns whose scan failures are reported as balance read errors.
+var balanceColumns = []string{`name "balance"`, `name "balance_after"`}
+
+// wrapBalanceScan turns a failure to scan a stored balance into errors.ErrBalanceRead, logging
+// the entity and id so the corrupt row can be found. Any other error is returned unchanged.
+func wrapBalanceScan(err error, entity, id string) error {
+	if err == nil || !isBalanceScanError(err) {
+		return err
+	}
+	log.Printf("%s %s: stored balance could not be read: %v", entity, id, err)
+	return fmt.Errorf("%s %s: %w", entity, id, apperrors.ErrBalanceRead)
+}
+
+// isBalanceScanError reports whether err is database/sql's "Scan error on column ... name
+// \"balance\"" failure, which is how a corrupt decimal or a driver quirk surfaces.
+func isBalanceScanError(err error) bool {
+	msg := err.Error()
+	if !strings.Contains(msg, "Scan error on column") {
+		return false
+	}
+	for _, column := range balanceColumns {
+		if strings.Contains(msg, column) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package repository
+
+import (
+	stderrors "errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	apperrors "paytabs/internal/errors"
+)
+
+func TestWrapBalanceScan(t *testing.T) {
+	// The shape database/sql produces when a decimal column fails to scan
+	scanErr := fmt.Errorf("sql: Scan error on column index 5, name %q: %w", "balance", stderrors.New("can't convert 1e-x to decimal"))
+
+	err := wrapBalanceScan(scanErr, "account", "42")
+	assert.ErrorIs(t, err, apperrors.ErrBalanceRead)
+	assert.Contains(t, err.Error(), "account 42")
+
+	other := fmt.Errorf("sql: Scan error on column index 1, name %q: bad", "name")
+	assert.Equal(t, other, wrapBalanceScan(other, "account", "42"))
+	assert.Equal(t, gorm.ErrRecordNotFound, wrapBalanceScan(gorm.ErrRecordNotFound, "account", "42"))
+	assert.NoError(t, wrapBalanceScan(nil, "account", "42"))
+}
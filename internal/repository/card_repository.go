@@ -2,9 +2,12 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"paytabs/internal/model"
 )
@@ -13,15 +16,40 @@ import (
 type CardRepository interface {
 	Create(ctx context.Context, card *model.Card) error
 	Update(ctx context.Context, card *model.Card) error
+	UpdateColumns(ctx context.Context, id uuid.UUID, columns map[string]interface{}) error
 	FindByID(ctx context.Context, id uuid.UUID) (*model.Card, error)
 	FindByIDForUpdate(ctx context.Context, id uuid.UUID) (*model.Card, error)
 	FindByAccountID(ctx context.Context, accountID uuid.UUID) ([]model.Card, error)
+	List(ctx context.Context, filter CardFilter, limit, offset int) ([]model.Card, int64, error)
+	SumActiveBalancesByAccount(ctx context.Context, accountIDs []uuid.UUID) (map[uuid.UUID]decimal.Decimal, error)
 	UpdateBalance(ctx context.Context, id uuid.UUID, newBalance interface{}) error
 	FindByCardNumber(ctx context.Context, cardNumber string) (*model.Card, error)
+	FindOwnerAccount(ctx context.Context, accountID uuid.UUID) (*model.Account, error)
+	// Ledger methods
+	AddLedgerEntry(ctx context.Context, entry *model.LedgerEntry) error
+	ListLedgerEntries(ctx context.Context, cardID uuid.UUID, from, to time.Time, limit, offset int) ([]model.LedgerEntry, error)
+	SumLedgerDebitsSince(ctx context.Context, cardID uuid.UUID, since time.Time) (decimal.Decimal, error)
+	// Hold methods
+	FindHoldByID(ctx context.Context, id uuid.UUID) (*model.CardHold, error)
+	SumActiveHolds(ctx context.Context, cardID uuid.UUID, now time.Time) (decimal.Decimal, error)
+	ReleaseHold(ctx context.Context, id uuid.UUID, at time.Time) (bool, error)
+	HasAcceptedPaymentTo(ctx context.Context, cardID, merchantAccountID uuid.UUID) (bool, error)
 	// Transaction methods
 	WithTransaction(ctx context.Context, fn func(ctx context.Context, repo CardRepository) error) error
 	FindByIDForUpdateTx(ctx context.Context, tx interface{}, id uuid.UUID) (*model.Card, error)
 	UpdateBalanceTx(ctx context.Context, tx interface{}, id uuid.UUID, newBalance interface{}) error
+	AddLedgerEntryTx(ctx context.Context, tx interface{}, entry *model.LedgerEntry) error
+	CreateTx(ctx context.Context, tx interface{}, card *model.Card) error
+	FindByAccountIDTx(ctx context.Context, tx interface{}, accountID uuid.UUID) ([]model.Card, error)
+	CountByAccountIDTx(ctx context.Context, tx interface{}, accountID uuid.UUID) (int64, error)
+	CreateHoldTx(ctx context.Context, tx interface{}, hold *model.CardHold) error
+	SumActiveHoldsTx(ctx context.Context, tx interface{}, cardID uuid.UUID, now time.Time) (decimal.Decimal, error)
+}
+
+// CardFilter narrows List. Nil fields match every card.
+type CardFilter struct {
+	AccountID *uuid.UUID
+	Active    *bool
 }
 
 type cardRepository struct {
@@ -43,21 +71,28 @@ func (r *cardRepository) Update(ctx context.Context, card *model.Card) error {
 	return r.db.WithContext(ctx).Save(card).Error
 }
 
+// UpdateColumns sets only the given columns of a card, leaving the rest of the row (notably
+// the balance) as it is in the database.
+func (r *cardRepository) UpdateColumns(ctx context.Context, id uuid.UUID, columns map[string]interface{}) error {
+	return r.db.WithContext(ctx).Model(&model.Card{}).Where("id = ?", id).Updates(columns).Error
+}
+
 // FindByID finds a card by ID.
 func (r *cardRepository) FindByID(ctx context.Context, id uuid.UUID) (*model.Card, error) {
 	var card model.Card
 	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&card).Error; err != nil {
-		return nil, err
+		return nil, wrapBalanceScan(err, "card", id.String())
 	}
 	return &card, nil
 }
 
-// FindByIDForUpdate finds a card by ID with row-level lock for update.
+// FindByIDForUpdate finds a card by ID with row-level lock for update. A lock that is not
+// acquired within the session's innodb_lock_wait_timeout returns errors.ErrLockTimeout.
 func (r *cardRepository) FindByIDForUpdate(ctx context.Context, id uuid.UUID) (*model.Card, error) {
 	var card model.Card
-	if err := r.db.WithContext(ctx).Set("gorm:query_option", "FOR UPDATE").
+	if err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
 		Where("id = ?", id).First(&card).Error; err != nil {
-		return nil, err
+		return nil, wrapLockedRead(err, "card", id.String())
 	}
 	return &card, nil
 }
@@ -66,16 +101,48 @@ func (r *cardRepository) FindByIDForUpdate(ctx context.Context, id uuid.UUID) (*
 func (r *cardRepository) FindByAccountID(ctx context.Context, accountID uuid.UUID) ([]model.Card, error) {
 	var cards []model.Card
 	if err := r.db.WithContext(ctx).Where("account_id = ?", accountID).Find(&cards).Error; err != nil {
-		return nil, err
+		return nil, wrapBalanceScan(err, "cards of account", accountID.String())
 	}
 	return cards, nil
 }
 
-// UpdateBalance updates the balance of a card.
+// List lists cards of every account matching filter, newest first, along with the total
+// number of matching cards.
+func (r *cardRepository) List(ctx context.Context, filter CardFilter, limit, offset int) ([]model.Card, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.Card{})
+	if filter.AccountID != nil {
+		query = query.Where("account_id = ?", *filter.AccountID)
+	}
+	if filter.Active != nil {
+		query = query.Where("active = ?", *filter.Active)
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var cards []model.Card
+	if err := query.
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&cards).Error; err != nil {
+		return nil, 0, wrapBalanceScan(err, "cards", "list")
+	}
+	return cards, total, nil
+}
+
+// UpdateBalance updates the balance of a card. A negative balance is refused with
+// errors.ErrInsufficientBalance.
 func (r *cardRepository) UpdateBalance(ctx context.Context, id uuid.UUID, newBalance interface{}) error {
-	return r.db.WithContext(ctx).Model(&model.Card{}).
+	if err := guardCardBalance(id, newBalance); err != nil {
+		return err
+	}
+	return wrapBalanceCheck(r.db.WithContext(ctx).Model(&model.Card{}).
 		Where("id = ?", id).
-		Update("balance", newBalance).Error
+		Update("balance", newBalance).Error)
 }
 
 // FindByCardNumber finds a card by card number (for payment processing).
@@ -87,23 +154,195 @@ func (r *cardRepository) FindByCardNumber(ctx context.Context, cardNumber string
 	return &card, nil
 }
 
-// FindByIDForUpdateTx finds a card by ID with row-level lock within a transaction.
+// FindOwnerAccount loads a card's owning account with a shared lock, so card transactions
+// can check the owner without a second repository and a concurrent deactivation waits.
+func (r *cardRepository) FindOwnerAccount(ctx context.Context, accountID uuid.UUID) (*model.Account, error) {
+	var account model.Account
+	if err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "SHARE"}).
+		Where("id = ?", accountID).First(&account).Error; err != nil {
+		return nil, wrapBalanceScan(err, "account", accountID.String())
+	}
+	return &account, nil
+}
+
+// AddLedgerEntry appends a balance change to the card ledger. Call it on the
+// transactional repository so the entry commits with the balance update.
+func (r *cardRepository) AddLedgerEntry(ctx context.Context, entry *model.LedgerEntry) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+// ListLedgerEntries lists a card's ledger entries created within [from, to], oldest first.
+func (r *cardRepository) ListLedgerEntries(ctx context.Context, cardID uuid.UUID, from, to time.Time, limit, offset int) ([]model.LedgerEntry, error) {
+	var entries []model.LedgerEntry
+	if err := r.db.WithContext(ctx).
+		Where("card_id = ? AND created_at BETWEEN ? AND ?", cardID, from, to).
+		Order("created_at ASC, id ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&entries).Error; err != nil {
+		return nil, wrapBalanceScan(err, "ledger of card", cardID.String())
+	}
+	return entries, nil
+}
+
+// SumLedgerDebitsSince returns the total debited from a card since the given time.
+func (r *cardRepository) SumLedgerDebitsSince(ctx context.Context, cardID uuid.UUID, since time.Time) (decimal.Decimal, error) {
+	var result struct {
+		Total decimal.Decimal
+	}
+	if err := r.db.WithContext(ctx).Model(&model.LedgerEntry{}).
+		Select("COALESCE(SUM(-delta), 0) AS total").
+		Where("card_id = ? AND delta < 0 AND created_at >= ?", cardID, since).
+		Scan(&result).Error; err != nil {
+		return decimal.Zero, err
+	}
+	return result.Total, nil
+}
+
+// FindHoldByID finds a card hold by ID.
+func (r *cardRepository) FindHoldByID(ctx context.Context, id uuid.UUID) (*model.CardHold, error) {
+	var hold model.CardHold
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&hold).Error; err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// SumActiveHolds totals the card's holds that are active and unexpired at now.
+func (r *cardRepository) SumActiveHolds(ctx context.Context, cardID uuid.UUID, now time.Time) (decimal.Decimal, error) {
+	return sumActiveHolds(r.db.WithContext(ctx), cardID, now)
+}
+
+// ReleaseHold marks an active hold released. It reports false when the hold was not active,
+// so of two concurrent releases only one succeeds.
+func (r *cardRepository) ReleaseHold(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.CardHold{}).
+		Where("id = ? AND status = ?", id, model.CardHoldStatusActive).
+		Updates(map[string]interface{}{"status": model.CardHoldStatusReleased, "released_at": at})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// HasAcceptedPaymentTo reports whether the card has ever paid the merchant outside test mode.
+// Archived payments count; refunds do not, as they are stored with their own status.
+func (r *cardRepository) HasAcceptedPaymentTo(ctx context.Context, cardID, merchantAccountID uuid.UUID) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&model.Payment{}).
+		Where("card_id = ? AND merchant_account_id = ? AND status = ? AND test_mode = ?",
+			cardID, merchantAccountID, model.PaymentStatusAccepted, false).
+		Limit(1).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// SumActiveBalancesByAccount totals the balances of each account's active cards in one
+// grouped query. Accounts without active cards are missing from the result.
+func (r *cardRepository) SumActiveBalancesByAccount(ctx context.Context, accountIDs []uuid.UUID) (map[uuid.UUID]decimal.Decimal, error) {
+	totals := make(map[uuid.UUID]decimal.Decimal, len(accountIDs))
+	if len(accountIDs) == 0 {
+		return totals, nil
+	}
+	var rows []struct {
+		AccountID uuid.UUID
+		Total     decimal.Decimal
+	}
+	if err := r.db.WithContext(ctx).Model(&model.Card{}).
+		Select("account_id, SUM(balance) AS total").
+		Where("account_id IN ? AND active = ?", accountIDs, true).
+		Group("account_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		totals[row.AccountID] = row.Total
+	}
+	return totals, nil
+}
+
+// FindByAccountIDTx finds all cards for an account within a transaction, without locking them.
+func (r *cardRepository) FindByAccountIDTx(ctx context.Context, tx interface{}, accountID uuid.UUID) ([]model.Card, error) {
+	txDB := tx.(*gorm.DB)
+	var cards []model.Card
+	if err := txDB.WithContext(ctx).Where("account_id = ?", accountID).Find(&cards).Error; err != nil {
+		return nil, wrapBalanceScan(err, "cards of account", accountID.String())
+	}
+	return cards, nil
+}
+
+// FindByIDForUpdateTx finds a card by ID with row-level lock within a transaction. A lock that
+// is not acquired within the session's innodb_lock_wait_timeout returns errors.ErrLockTimeout.
 func (r *cardRepository) FindByIDForUpdateTx(ctx context.Context, tx interface{}, id uuid.UUID) (*model.Card, error) {
 	txDB := tx.(*gorm.DB)
 	var card model.Card
-	if err := txDB.WithContext(ctx).Set("gorm:query_option", "FOR UPDATE").
+	if err := txDB.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
 		Where("id = ?", id).First(&card).Error; err != nil {
-		return nil, err
+		return nil, wrapLockedRead(err, "card", id.String())
 	}
 	return &card, nil
 }
 
-// UpdateBalanceTx updates the balance within a transaction.
+// UpdateBalanceTx updates the balance within a transaction. A negative balance is refused
+// with errors.ErrInsufficientBalance.
 func (r *cardRepository) UpdateBalanceTx(ctx context.Context, tx interface{}, id uuid.UUID, newBalance interface{}) error {
+	if err := guardCardBalance(id, newBalance); err != nil {
+		return err
+	}
 	txDB := tx.(*gorm.DB)
-	return txDB.WithContext(ctx).Model(&model.Card{}).
+	return wrapBalanceCheck(txDB.WithContext(ctx).Model(&model.Card{}).
 		Where("id = ?", id).
-		Update("balance", newBalance).Error
+		Update("balance", newBalance).Error)
+}
+
+// AddLedgerEntryTx appends a balance change to the card ledger within a transaction.
+func (r *cardRepository) AddLedgerEntryTx(ctx context.Context, tx interface{}, entry *model.LedgerEntry) error {
+	txDB := tx.(*gorm.DB)
+	return txDB.WithContext(ctx).Create(entry).Error
+}
+
+// CreateTx creates a new card within a transaction.
+func (r *cardRepository) CreateTx(ctx context.Context, tx interface{}, card *model.Card) error {
+	txDB := tx.(*gorm.DB)
+	return txDB.WithContext(ctx).Create(card).Error
+}
+
+// CountByAccountIDTx counts an account's cards within a transaction.
+func (r *cardRepository) CountByAccountIDTx(ctx context.Context, tx interface{}, accountID uuid.UUID) (int64, error) {
+	txDB := tx.(*gorm.DB)
+	var count int64
+	if err := txDB.WithContext(ctx).Model(&model.Card{}).
+		Where("account_id = ?", accountID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// CreateHoldTx creates a card hold within a transaction.
+func (r *cardRepository) CreateHoldTx(ctx context.Context, tx interface{}, hold *model.CardHold) error {
+	txDB := tx.(*gorm.DB)
+	return txDB.WithContext(ctx).Create(hold).Error
+}
+
+// SumActiveHoldsTx totals the card's active, unexpired holds within a transaction. Call it
+// with the card row locked so no hold is placed between the sum and the debit.
+func (r *cardRepository) SumActiveHoldsTx(ctx context.Context, tx interface{}, cardID uuid.UUID, now time.Time) (decimal.Decimal, error) {
+	txDB := tx.(*gorm.DB)
+	return sumActiveHolds(txDB.WithContext(ctx), cardID, now)
+}
+
+func sumActiveHolds(db *gorm.DB, cardID uuid.UUID, now time.Time) (decimal.Decimal, error) {
+	var result struct {
+		Total decimal.Decimal
+	}
+	if err := db.Model(&model.CardHold{}).
+		Select("COALESCE(SUM(amount), 0) AS total").
+		Where("card_id = ? AND status = ? AND expires_at > ?", cardID, model.CardHoldStatusActive, now).
+		Scan(&result).Error; err != nil {
+		return decimal.Zero, err
+	}
+	return result.Total, nil
 }
 
 // WithTransaction executes a function within a database transaction.
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	stderrors "errors"
+	"fmt"
+	"log"
+
+	"github.com/go-sql-driver/mysql"
+
+	apperrors "paytabs/internal/errors"
+)
+
+const (
+	// mysqlLockWaitTimeout is MySQL's ER_LOCK_WAIT_TIMEOUT error number, returned once a
+	// statement has waited innodb_lock_wait_timeout for a row lock.
+	mysqlLockWaitTimeout = 1205
+	// mysqlDeadlock is MySQL's ER_LOCK_DEADLOCK error number, returned to the transaction
+	// chosen as the victim of a deadlock.
+	mysqlDeadlock = 1213
+)
+
+// wrapLockedRead reports the failure of a locking read. A row lock that could not be taken in
+// time, or a deadlock, becomes errors.ErrLockTimeout wrapped with the entity and id; anything
+// else goes through wrapBalanceScan.
+func wrapLockedRead(err error, entity, id string) error {
+	var mysqlErr *mysql.MySQLError
+	if stderrors.As(err, &mysqlErr) && (mysqlErr.Number == mysqlLockWaitTimeout || mysqlErr.Number == mysqlDeadlock) {
+		log.Printf("%s %s: row lock not acquired: %s", entity, id, mysqlErr.Message)
+		return fmt.Errorf("%s %s: %w", entity, id, apperrors.ErrLockTimeout)
+	}
+	return wrapBalanceScan(err, entity, id)
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	gomysql "github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+
+	apperrors "paytabs/internal/errors"
+)
+
+// heldLockDriver is a database/sql driver whose rows are all locked by another session: every
+// locking read fails the way MySQL fails one once innodb_lock_wait_timeout has passed.
+type heldLockDriver struct {
+	mu      sync.Mutex
+	queries []string
+}
+
+func (d *heldLockDriver) Open(string) (driver.Conn, error) { return &heldLockConn{d: d}, nil }
+
+type heldLockConn struct{ d *heldLockDriver }
+
+func (c *heldLockConn) Prepare(string) (driver.Stmt, error) { return nil, errNotSupported }
+func (c *heldLockConn) Close() error                        { return nil }
+func (c *heldLockConn) Begin() (driver.Tx, error)           { return nil, errNotSupported }
+
+var errNotSupported = errors.New("not supported by heldLockDriver")
+
+func (c *heldLockConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.d.mu.Lock()
+	c.d.queries = append(c.d.queries, query)
+	c.d.mu.Unlock()
+	if strings.Contains(query, "FOR UPDATE") {
+		return nil, &gomysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded; try restarting transaction"}
+	}
+	return nil, errNotSupported
+}
+
+func newHeldLockDB(t *testing.T) (*gorm.DB, *heldLockDriver) {
+	t.Helper()
+	d := &heldLockDriver{}
+	gdb, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sql.OpenDB(heldLockConnector{d}),
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{DisableAutomaticPing: true})
+	require.NoError(t, err)
+	return gdb, d
+}
+
+type heldLockConnector struct{ d *heldLockDriver }
+
+func (c heldLockConnector) Connect(context.Context) (driver.Conn, error) { return c.d.Open("") }
+func (c heldLockConnector) Driver() driver.Driver                        { return c.d }
+
+func TestForUpdate_HeldLockReturnsErrLockTimeout(t *testing.T) {
+	gdb, d := newHeldLockDB(t)
+	ctx := context.Background()
+	id := uuid.New()
+
+	cards := NewCardRepository(gdb)
+	_, err := cards.FindByIDForUpdate(ctx, id)
+	assert.ErrorIs(t, err, apperrors.ErrLockTimeout)
+	assert.Contains(t, err.Error(), id.String())
+	_, err = cards.FindByIDForUpdateTx(ctx, gdb, id)
+	assert.ErrorIs(t, err, apperrors.ErrLockTimeout)
+
+	accounts := NewAccountRepository(gdb)
+	_, err = accounts.FindByIDForUpdate(ctx, id)
+	assert.ErrorIs(t, err, apperrors.ErrLockTimeout)
+	_, err = accounts.FindByIDForUpdateTx(ctx, gdb, id)
+	assert.ErrorIs(t, err, apperrors.ErrLockTimeout)
+
+	require.Len(t, d.queries, 4)
+	for _, query := range d.queries {
+		assert.True(t, strings.HasSuffix(query, "FOR UPDATE"), query)
+	}
+}
+
+func TestWrapLockedRead(t *testing.T) {
+	deadlock := &gomysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+	assert.ErrorIs(t, wrapLockedRead(deadlock, "card", "c1"), apperrors.ErrLockTimeout)
+
+	assert.Equal(t, gorm.ErrRecordNotFound, wrapLockedRead(gorm.ErrRecordNotFound, "card", "c1"))
+	assert.NoError(t, wrapLockedRead(nil, "card", "c1"))
+}
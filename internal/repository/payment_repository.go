@@ -2,18 +2,75 @@ package repository
 
 import (
 	"context"
+	stderrors "errors"
+	"strings"
+	"time"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 
+	apperrors "paytabs/internal/errors"
 	"paytabs/internal/model"
 )
 
+// mysqlDuplicateEntry is MySQL's ER_DUP_ENTRY error number.
+const mysqlDuplicateEntry = 1062
+
+// MerchantCustomer is an account whose cards paid a merchant, with its aggregate spend.
+type MerchantCustomer struct {
+	AccountID     uuid.UUID
+	Name          string
+	TotalSpent    decimal.Decimal
+	PaymentCount  int64
+	LastPaymentAt time.Time
+}
+
+// PaymentStatusTotal is the number and summed amount of a merchant's payments in one status.
+type PaymentStatusTotal struct {
+	Status model.PaymentStatus
+	Count  int64
+	Total  decimal.Decimal
+}
+
+// PaymentFilter narrows ListByMerchant. A nil Status matches every status; a zero From or To
+// leaves that end of the creation time range open. Both bounds are inclusive. Archived
+// payments are left out unless IncludeArchived is set.
+type PaymentFilter struct {
+	Status          *model.PaymentStatus
+	From            time.Time
+	To              time.Time
+	IncludeArchived bool
+}
+
+// CardPayment is a payment made with a card, with the name of the merchant it paid.
+type CardPayment struct {
+	model.Payment
+	MerchantName string
+}
+
 // PaymentRepository defines payment persistence operations.
 type PaymentRepository interface {
 	Create(ctx context.Context, payment *model.Payment) error
+	CreateTx(ctx context.Context, tx interface{}, payment *model.Payment) error
 	Update(ctx context.Context, payment *model.Payment) error
+	TransitionStatusTx(ctx context.Context, tx interface{}, id uuid.UUID, from, to model.PaymentStatus) (bool, error)
 	FindByID(ctx context.Context, id uuid.UUID) (*model.Payment, error)
+	ArchiveBefore(ctx context.Context, statuses []model.PaymentStatus, cutoff, archivedAt time.Time) (int64, error)
+	SumAcceptedByMerchantSince(ctx context.Context, merchantAccountID uuid.UUID, since time.Time) (decimal.Decimal, error)
+	ListCustomersByMerchant(ctx context.Context, merchantAccountID uuid.UUID, limit, offset int) ([]MerchantCustomer, int64, error)
+	SumByMerchantAndStatus(ctx context.Context, merchantAccountID uuid.UUID, statuses []model.PaymentStatus) ([]PaymentStatusTotal, error)
+	CountAcceptedRetries(ctx context.Context, originalPaymentID uuid.UUID) (int64, error)
+	SumRefundsTx(ctx context.Context, tx interface{}, originalPaymentID uuid.UUID) (decimal.Decimal, error)
+	FindRefundByIdempotencyKeyTx(ctx context.Context, tx interface{}, originalPaymentID uuid.UUID, key string) (*model.Payment, error)
+	ExistsByMerchantReference(ctx context.Context, merchantAccountID uuid.UUID, reference string) (bool, error)
+	FindByChargeIdempotencyKey(ctx context.Context, merchantAccountID uuid.UUID, key string) (*model.Payment, error)
+	SetChargeIdempotencyKeyTx(ctx context.Context, tx interface{}, id uuid.UUID, key string) error
+	ListByAccount(ctx context.Context, accountID uuid.UUID, includeArchived bool, limit, offset int) ([]model.Payment, int64, error)
+	ListByMerchant(ctx context.Context, merchantAccountID uuid.UUID, filter PaymentFilter, limit, offset int) ([]model.Payment, int64, error)
+	ListAllByAccountTx(ctx context.Context, tx interface{}, accountID uuid.UUID) ([]model.Payment, error)
+	ListByCard(ctx context.Context, cardID uuid.UUID, statuses []model.PaymentStatus, limit, offset int) ([]CardPayment, int64, error)
 }
 
 type paymentRepository struct {
@@ -27,7 +84,13 @@ func NewPaymentRepository(db *gorm.DB) PaymentRepository {
 
 // Create creates a new payment record.
 func (r *paymentRepository) Create(ctx context.Context, payment *model.Payment) error {
-	return r.db.WithContext(ctx).Create(payment).Error
+	return wrapDuplicateChargeKey(wrapDuplicateReference(r.db.WithContext(ctx).Create(payment).Error))
+}
+
+// CreateTx creates a new payment record within tx.
+func (r *paymentRepository) CreateTx(ctx context.Context, tx interface{}, payment *model.Payment) error {
+	txDB := tx.(*gorm.DB)
+	return wrapDuplicateReference(txDB.WithContext(ctx).Create(payment).Error)
 }
 
 // Update updates an existing payment record.
@@ -35,6 +98,20 @@ func (r *paymentRepository) Update(ctx context.Context, payment *model.Payment)
 	return r.db.WithContext(ctx).Save(payment).Error
 }
 
+// TransitionStatusTx moves the payment from one status to another within tx, but only if it is
+// still in from. It reports whether the payment was moved, so concurrent transitions out of
+// the same status cannot both win.
+func (r *paymentRepository) TransitionStatusTx(ctx context.Context, tx interface{}, id uuid.UUID, from, to model.PaymentStatus) (bool, error) {
+	txDB := tx.(*gorm.DB)
+	result := txDB.WithContext(ctx).Model(&model.Payment{}).
+		Where("id = ? AND status = ?", id, from).
+		Update("status", to)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
 // FindByID finds a payment by ID.
 func (r *paymentRepository) FindByID(ctx context.Context, id uuid.UUID) (*model.Payment, error) {
 	var payment model.Payment
@@ -44,10 +121,299 @@ func (r *paymentRepository) FindByID(ctx context.Context, id uuid.UUID) (*model.
 	return &payment, nil
 }
 
+// ArchiveBefore marks payments in the given statuses created before cutoff as archived.
+// Rows keep their IDs and already-archived rows are left untouched, so re-running is safe.
+func (r *paymentRepository) ArchiveBefore(ctx context.Context, statuses []model.PaymentStatus, cutoff, archivedAt time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Model(&model.Payment{}).
+		Where("status IN ? AND created_at < ? AND archived_at IS NULL", statuses, cutoff).
+		Update("archived_at", archivedAt)
+	return result.RowsAffected, result.Error
+}
+
+// SumAcceptedByMerchantSince totals the amounts of the merchant's accepted payments created at
+// or after since, archived ones included. Test-mode payments moved no money and are excluded.
+func (r *paymentRepository) SumAcceptedByMerchantSince(ctx context.Context, merchantAccountID uuid.UUID, since time.Time) (decimal.Decimal, error) {
+	var result struct {
+		Total decimal.Decimal
+	}
+	if err := r.db.WithContext(ctx).Model(&model.Payment{}).
+		Select("COALESCE(SUM(amount), 0) AS total").
+		Where("merchant_account_id = ? AND status = ? AND created_at >= ? AND test_mode = ?", merchantAccountID, model.PaymentStatusAccepted, since, false).
+		Scan(&result).Error; err != nil {
+		return decimal.Zero, err
+	}
+	return result.Total, nil
+}
+
+// CountAcceptedRetries counts accepted payments that retry the given original payment.
+func (r *paymentRepository) CountAcceptedRetries(ctx context.Context, originalPaymentID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.Payment{}).
+		Where("retry_of_id = ? AND status = ?", originalPaymentID, model.PaymentStatusAccepted).
+		Count(&count).Error
+	return count, err
+}
+
+// SumRefundsTx totals the refunds of the given original payment within tx.
+func (r *paymentRepository) SumRefundsTx(ctx context.Context, tx interface{}, originalPaymentID uuid.UUID) (decimal.Decimal, error) {
+	txDB := tx.(*gorm.DB)
+	var result struct {
+		Total decimal.Decimal
+	}
+	if err := txDB.WithContext(ctx).Model(&model.Payment{}).
+		Select("COALESCE(SUM(amount), 0) AS total").
+		Where("refund_of_id = ? AND status = ?", originalPaymentID, model.PaymentStatusRefunded).
+		Scan(&result).Error; err != nil {
+		return decimal.Zero, err
+	}
+	return result.Total, nil
+}
+
+// FindRefundByIdempotencyKeyTx finds the refund of the original payment that was requested
+// with key, within tx. Soft-deleted refunds count, as the unique index covers them too.
+func (r *paymentRepository) FindRefundByIdempotencyKeyTx(ctx context.Context, tx interface{}, originalPaymentID uuid.UUID, key string) (*model.Payment, error) {
+	txDB := tx.(*gorm.DB)
+	var refund model.Payment
+	if err := txDB.WithContext(ctx).Unscoped().
+		Where("refund_of_id = ? AND idempotency_key = ?", originalPaymentID, key).
+		First(&refund).Error; err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+// ExistsByMerchantReference reports whether the merchant has a payment with the reference.
+// Soft-deleted payments count, as the unique index covers them too.
+func (r *paymentRepository) ExistsByMerchantReference(ctx context.Context, merchantAccountID uuid.UUID, reference string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Unscoped().Model(&model.Payment{}).
+		Where("merchant_account_id = ? AND merchant_reference = ?", merchantAccountID, reference).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// FindByChargeIdempotencyKey finds the merchant's accepted charge that was requested with the
+// idempotency key. Soft-deleted payments are included, as the unique index covers them too.
+func (r *paymentRepository) FindByChargeIdempotencyKey(ctx context.Context, merchantAccountID uuid.UUID, key string) (*model.Payment, error) {
+	var payment model.Payment
+	if err := r.db.WithContext(ctx).Unscoped().
+		Where("merchant_account_id = ? AND charge_idempotency_key = ?", merchantAccountID, key).
+		First(&payment).Error; err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// SetChargeIdempotencyKeyTx stores the idempotency key a charge was requested with within tx.
+// It returns errors.ErrDuplicateIdempotencyKey when another charge of the same merchant already
+// holds the key.
+func (r *paymentRepository) SetChargeIdempotencyKeyTx(ctx context.Context, tx interface{}, id uuid.UUID, key string) error {
+	txDB := tx.(*gorm.DB)
+	return wrapDuplicateChargeKey(txDB.WithContext(ctx).Model(&model.Payment{}).
+		Where("id = ?", id).
+		Update("charge_idempotency_key", key).Error)
+}
+
+// wrapDuplicateChargeKey turns a violation of the unique charge idempotency key index, which a
+// concurrent charge with the same key can cause, into errors.ErrDuplicateIdempotencyKey.
+// Any other error is returned unchanged.
+func wrapDuplicateChargeKey(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if stderrors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry &&
+		strings.Contains(mysqlErr.Message, model.PaymentChargeIdempotencyIndex) {
+		return apperrors.ErrDuplicateIdempotencyKey
+	}
+	return err
+}
+
+// wrapDuplicateReference turns a violation of the unique merchant reference index, which a
+// concurrent payment with the same reference can cause, into errors.ErrDuplicateReference.
+// Any other error is returned unchanged.
+func wrapDuplicateReference(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if stderrors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry &&
+		strings.Contains(mysqlErr.Message, model.PaymentMerchantReferenceIndex) {
+		return apperrors.ErrDuplicateReference
+	}
+	return err
+}
+
+// SumByMerchantAndStatus counts and totals the merchant's unarchived, non-test payments in
+// the given statuses with a single grouped query. Statuses with no payments are absent from the result.
+func (r *paymentRepository) SumByMerchantAndStatus(ctx context.Context, merchantAccountID uuid.UUID, statuses []model.PaymentStatus) ([]PaymentStatusTotal, error) {
+	var totals []PaymentStatusTotal
+	err := r.db.WithContext(ctx).Model(&model.Payment{}).
+		Select("status, COUNT(*) AS count, COALESCE(SUM(amount), 0) AS total").
+		Where("merchant_account_id = ? AND status IN ? AND archived_at IS NULL AND test_mode = ?", merchantAccountID, statuses, false).
+		Group("status").
+		Order("status ASC").
+		Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+// ListCustomersByMerchant groups the merchant's accepted payments by the account owning the
+// paying card, biggest spenders first, along with the number of distinct customers. Spend is
+// the gross amount charged; payments recorded before fees were tracked have no gross amount
+// and count their amount instead. Test-mode payments are left out.
+func (r *paymentRepository) ListCustomersByMerchant(ctx context.Context, merchantAccountID uuid.UUID, limit, offset int) ([]MerchantCustomer, int64, error) {
+	query := r.db.WithContext(ctx).Table("payments").
+		Joins("JOIN cards ON cards.id = payments.card_id").
+		Joins("JOIN accounts ON accounts.id = cards.account_id").
+		Where("payments.merchant_account_id = ? AND payments.status = ? AND payments.test_mode = ? AND payments.deleted_at IS NULL",
+			merchantAccountID, model.PaymentStatusAccepted, false).
+		Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Distinct("cards.account_id").Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var customers []MerchantCustomer
+	err := query.
+		Select(`accounts.id AS account_id, accounts.name AS name,
+			SUM(CASE WHEN payments.gross_amount > 0 THEN payments.gross_amount ELSE payments.amount END) AS total_spent,
+			COUNT(*) AS payment_count, MAX(payments.created_at) AS last_payment_at`).
+		Group("accounts.id, accounts.name").
+		Order("total_spent DESC, accounts.id ASC").
+		Limit(limit).
+		Offset(offset).
+		Scan(&customers).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return customers, total, nil
+}
+
+// ListByAccount lists payments the account received as a merchant or made with one of its
+// cards, newest first, along with the total number of matching payments. Archived payments
+// are left out unless includeArchived is set.
+func (r *paymentRepository) ListByAccount(ctx context.Context, accountID uuid.UUID, includeArchived bool, limit, offset int) ([]model.Payment, int64, error) {
+	query := accountPayments(r.db.WithContext(ctx), accountID)
+	if !includeArchived {
+		query = query.Where("archived_at IS NULL")
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var payments []model.Payment
+	err := query.
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&payments).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return payments, total, nil
+}
+
+// ListByMerchant lists the merchant's payments matching filter, newest first, along with the
+// total number of matching payments.
+func (r *paymentRepository) ListByMerchant(ctx context.Context, merchantAccountID uuid.UUID, filter PaymentFilter, limit, offset int) ([]model.Payment, int64, error) {
+	query := merchantPayments(r.db.WithContext(ctx), merchantAccountID, filter).Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var payments []model.Payment
+	err := query.
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&payments).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return payments, total, nil
+}
+
+// merchantPayments scopes a query to the merchant's payments matching filter.
+func merchantPayments(db *gorm.DB, merchantAccountID uuid.UUID, filter PaymentFilter) *gorm.DB {
+	query := db.Model(&model.Payment{}).
+		Where("merchant_account_id = ?", merchantAccountID)
+	if !filter.IncludeArchived {
+		query = query.Where("archived_at IS NULL")
+	}
+	if filter.Status != nil {
+		query = query.Where("status = ?", *filter.Status)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at <= ?", filter.To)
+	}
+	return query
+}
+
+// ListAllByAccountTx lists every payment the account received as a merchant or made with one
+// of its cards, archived ones included, oldest first, within a transaction.
+func (r *paymentRepository) ListAllByAccountTx(ctx context.Context, tx interface{}, accountID uuid.UUID) ([]model.Payment, error) {
+	txDB := tx.(*gorm.DB)
+	var payments []model.Payment
+	if err := accountPayments(txDB.WithContext(ctx), accountID).
+		Order("created_at ASC, id ASC").
+		Find(&payments).Error; err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+// accountPayments selects the payments the account received as a merchant or made with one
+// of its cards.
+func accountPayments(db *gorm.DB, accountID uuid.UUID) *gorm.DB {
+	cardIDs := db.Session(&gorm.Session{NewDB: true}).Table("cards").Select("id").Where("account_id = ?", accountID)
+	return db.Model(&model.Payment{}).
+		Where("merchant_account_id = ? OR card_id IN (?)", accountID, cardIDs)
+}
+
+// ListByCard lists unarchived, live payments made with the card in one of statuses, newest
+// first, along with the total number of matching payments. Test-mode payments never touched
+// the card and are left out.
+func (r *paymentRepository) ListByCard(ctx context.Context, cardID uuid.UUID, statuses []model.PaymentStatus, limit, offset int) ([]CardPayment, int64, error) {
+	query := r.db.WithContext(ctx).Table("payments").
+		Joins("JOIN accounts ON accounts.id = payments.merchant_account_id").
+		Where("payments.card_id = ? AND payments.status IN ? AND payments.test_mode = ?", cardID, statuses, false).
+		Where("payments.archived_at IS NULL AND payments.deleted_at IS NULL").
+		Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var payments []CardPayment
+	err := query.
+		Select("payments.*, accounts.name AS merchant_name").
+		Order("payments.created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Scan(&payments).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return payments, total, nil
+}
+
 // PaymentLogRepository defines payment log persistence operations.
 type PaymentLogRepository interface {
 	Create(ctx context.Context, log *model.PaymentLog) error
 	CreateBatch(ctx context.Context, logs []model.PaymentLog) error
+	ListByPaymentID(ctx context.Context, paymentID uuid.UUID) ([]model.PaymentLog, error)
 }
 
 type paymentLogRepository struct {
@@ -72,3 +438,14 @@ func (r *paymentLogRepository) CreateBatch(ctx context.Context, logs []model.Pay
 	return r.db.WithContext(ctx).CreateInBatches(logs, 100).Error
 }
 
+// ListByPaymentID returns a payment's log entries, oldest first.
+func (r *paymentLogRepository) ListByPaymentID(ctx context.Context, paymentID uuid.UUID) ([]model.PaymentLog, error) {
+	var logs []model.PaymentLog
+	if err := r.db.WithContext(ctx).
+		Where("payment_id = ?", paymentID).
+		Order("created_at ASC").
+		Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
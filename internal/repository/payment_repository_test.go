@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	apperrors "paytabs/internal/errors"
+	"paytabs/internal/model"
+)
+
+func TestWrapDuplicateReference(t *testing.T) {
+	duplicate := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a1-order-1001' for key 'payments.idx_payments_merchant_reference'"}
+	assert.Equal(t, apperrors.ErrDuplicateReference, wrapDuplicateReference(duplicate))
+
+	primary := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a1' for key 'payments.PRIMARY'"}
+	assert.Equal(t, error(primary), wrapDuplicateReference(primary), "only the reference index means a duplicate reference")
+	assert.NoError(t, wrapDuplicateReference(nil))
+}
+
+func TestWrapDuplicateChargeKey(t *testing.T) {
+	duplicate := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a1-order-7-attempt' for key 'payments.idx_payments_charge_idempotency_key'"}
+	assert.Equal(t, apperrors.ErrDuplicateIdempotencyKey, wrapDuplicateChargeKey(duplicate))
+
+	reference := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a1-order-1001' for key 'payments.idx_payments_merchant_reference'"}
+	assert.Equal(t, apperrors.ErrDuplicateReference, wrapDuplicateChargeKey(wrapDuplicateReference(reference)), "a duplicate reference stays one")
+	assert.NoError(t, wrapDuplicateChargeKey(nil))
+}
+
+func TestPaymentModel_DeclaresMerchantReferenceIndex(t *testing.T) {
+	s, err := schema.Parse(&model.Payment{}, &sync.Map{}, schema.NamingStrategy{})
+	require.NoError(t, err)
+
+	for _, index := range s.ParseIndexes() {
+		if index.Name != model.PaymentMerchantReferenceIndex {
+			continue
+		}
+		assert.Equal(t, "UNIQUE", index.Class)
+		require.Len(t, index.Fields, 2)
+		assert.Equal(t, "merchant_account_id", index.Fields[0].DBName, "scoped to the merchant")
+		assert.Equal(t, "merchant_reference", index.Fields[1].DBName)
+		return
+	}
+	t.Fatalf("no %s index", model.PaymentMerchantReferenceIndex)
+}
+
+func TestPaymentModel_DeclaresRefundIdempotencyIndex(t *testing.T) {
+	s, err := schema.Parse(&model.Payment{}, &sync.Map{}, schema.NamingStrategy{})
+	require.NoError(t, err)
+
+	for _, index := range s.ParseIndexes() {
+		if index.Name != model.PaymentRefundIdempotencyIndex {
+			continue
+		}
+		assert.Equal(t, "UNIQUE", index.Class)
+		require.Len(t, index.Fields, 2)
+		assert.Equal(t, "refund_of_id", index.Fields[0].DBName, "scoped to the refunded payment")
+		assert.Equal(t, "idempotency_key", index.Fields[1].DBName)
+		return
+	}
+	t.Fatalf("no %s index", model.PaymentRefundIdempotencyIndex)
+}
+
+func TestPaymentModel_DeclaresChargeIdempotencyIndex(t *testing.T) {
+	s, err := schema.Parse(&model.Payment{}, &sync.Map{}, schema.NamingStrategy{})
+	require.NoError(t, err)
+
+	for _, index := range s.ParseIndexes() {
+		if index.Name != model.PaymentChargeIdempotencyIndex {
+			continue
+		}
+		assert.Equal(t, "UNIQUE", index.Class)
+		require.Len(t, index.Fields, 2)
+		assert.Equal(t, "merchant_account_id", index.Fields[0].DBName, "scoped to the merchant")
+		assert.Equal(t, "charge_idempotency_key", index.Fields[1].DBName)
+		return
+	}
+	t.Fatalf("no %s index", model.PaymentChargeIdempotencyIndex)
+}
+
+// merchantPaymentsSQL renders the page query ListByMerchant runs for filter.
+func merchantPaymentsSQL(t *testing.T, merchantID uuid.UUID, filter PaymentFilter) string {
+	t.Helper()
+	gdb, _ := newHeldLockDB(t)
+	return gdb.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		var payments []model.Payment
+		return merchantPayments(tx, merchantID, filter).Order("created_at DESC").Limit(20).Find(&payments)
+	})
+}
+
+func TestPaymentRepository_ListByMerchantFilters(t *testing.T) {
+	merchantID := uuid.New()
+	accepted := model.PaymentStatusAccepted
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 3, 31, 23, 59, 59, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		filter   PaymentFilter
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "no filter",
+			excludes: []string{"status =", "created_at >=", "created_at <="},
+		},
+		{
+			name:     "status",
+			filter:   PaymentFilter{Status: &accepted},
+			contains: []string{"status = 'accepted'"},
+			excludes: []string{"created_at >=", "created_at <="},
+		},
+		{
+			name:     "from only",
+			filter:   PaymentFilter{From: from},
+			contains: []string{"created_at >= '2026-03-01 00:00:00'"},
+			excludes: []string{"status =", "created_at <="},
+		},
+		{
+			name:     "to only",
+			filter:   PaymentFilter{To: to},
+			contains: []string{"created_at <= '2026-03-31 23:59:59'"},
+			excludes: []string{"status =", "created_at >="},
+		},
+		{
+			name:     "archived included",
+			filter:   PaymentFilter{IncludeArchived: true},
+			excludes: []string{"archived_at", "status =", "created_at >=", "created_at <="},
+		},
+		{
+			name:     "all filters combine",
+			filter:   PaymentFilter{Status: &accepted, From: from, To: to},
+			contains: []string{"status = 'accepted' AND created_at >= '2026-03-01 00:00:00' AND created_at <= '2026-03-31 23:59:59'"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql := merchantPaymentsSQL(t, merchantID, tt.filter)
+
+			assert.Contains(t, sql, "merchant_account_id = '"+merchantID.String()+"'")
+			if !tt.filter.IncludeArchived {
+				assert.Contains(t, sql, "merchant_account_id = '"+merchantID.String()+"' AND archived_at IS NULL")
+			}
+			assert.Contains(t, sql, "ORDER BY created_at DESC LIMIT 20")
+			for _, want := range tt.contains {
+				assert.Contains(t, sql, want)
+			}
+			for _, unwanted := range tt.excludes {
+				assert.NotContains(t, sql, unwanted)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"paytabs/internal/model"
+)
+
+// PayoutRepository defines payout persistence operations.
+type PayoutRepository interface {
+	Create(ctx context.Context, payout *model.Payout) error
+	SumPendingByMerchant(ctx context.Context, merchantAccountID uuid.UUID) (decimal.Decimal, error)
+	SumPaidByMerchant(ctx context.Context, merchantAccountID uuid.UUID) (decimal.Decimal, error)
+	FindByID(ctx context.Context, id uuid.UUID) (*model.Payout, error)
+	ListByMerchant(ctx context.Context, merchantAccountID uuid.UUID, status model.PayoutStatus, limit, offset int) ([]model.Payout, int64, error)
+	// Transaction methods
+	CreateTx(ctx context.Context, tx interface{}, payout *model.Payout) error
+	SumPendingByMerchantTx(ctx context.Context, tx interface{}, merchantAccountID uuid.UUID) (decimal.Decimal, error)
+}
+
+type payoutRepository struct {
+	db *gorm.DB
+}
+
+// NewPayoutRepository creates a new payout repository.
+func NewPayoutRepository(db *gorm.DB) PayoutRepository {
+	return &payoutRepository{db: db}
+}
+
+// Create creates a new payout record.
+func (r *payoutRepository) Create(ctx context.Context, payout *model.Payout) error {
+	return r.db.WithContext(ctx).Create(payout).Error
+}
+
+// SumPendingByMerchant totals the merchant's payouts that have not completed yet.
+func (r *payoutRepository) SumPendingByMerchant(ctx context.Context, merchantAccountID uuid.UUID) (decimal.Decimal, error) {
+	return sumPendingByMerchant(r.db.WithContext(ctx), merchantAccountID)
+}
+
+// SumPaidByMerchant totals the merchant's completed payouts.
+func (r *payoutRepository) SumPaidByMerchant(ctx context.Context, merchantAccountID uuid.UUID) (decimal.Decimal, error) {
+	var result struct {
+		Total decimal.Decimal
+	}
+	if err := r.db.WithContext(ctx).Model(&model.Payout{}).
+		Select("COALESCE(SUM(amount), 0) AS total").
+		Where("merchant_account_id = ? AND status = ?", merchantAccountID, model.PayoutStatusPaid).
+		Scan(&result).Error; err != nil {
+		return decimal.Zero, err
+	}
+	return result.Total, nil
+}
+
+// FindByID finds a payout by ID.
+func (r *payoutRepository) FindByID(ctx context.Context, id uuid.UUID) (*model.Payout, error) {
+	var payout model.Payout
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&payout).Error; err != nil {
+		return nil, err
+	}
+	return &payout, nil
+}
+
+// ListByMerchant lists the merchant's payouts, newest first, along with the total number of
+// matching payouts. An empty status lists payouts in every status.
+func (r *payoutRepository) ListByMerchant(ctx context.Context, merchantAccountID uuid.UUID, status model.PayoutStatus, limit, offset int) ([]model.Payout, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.Payout{}).
+		Where("merchant_account_id = ?", merchantAccountID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var payouts []model.Payout
+	if err := query.
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&payouts).Error; err != nil {
+		return nil, 0, err
+	}
+	return payouts, total, nil
+}
+
+// CreateTx creates a payout within a transaction.
+func (r *payoutRepository) CreateTx(ctx context.Context, tx interface{}, payout *model.Payout) error {
+	txDB := tx.(*gorm.DB)
+	return txDB.WithContext(ctx).Create(payout).Error
+}
+
+// SumPendingByMerchantTx totals the merchant's pending payouts within a transaction.
+func (r *payoutRepository) SumPendingByMerchantTx(ctx context.Context, tx interface{}, merchantAccountID uuid.UUID) (decimal.Decimal, error) {
+	txDB := tx.(*gorm.DB)
+	return sumPendingByMerchant(txDB.WithContext(ctx), merchantAccountID)
+}
+
+func sumPendingByMerchant(db *gorm.DB, merchantAccountID uuid.UUID) (decimal.Decimal, error) {
+	var result struct {
+		Total decimal.Decimal
+	}
+	if err := db.Model(&model.Payout{}).
+		Select("COALESCE(SUM(amount), 0) AS total").
+		Where("merchant_account_id = ? AND status = ?", merchantAccountID, model.PayoutStatusPending).
+		Scan(&result).Error; err != nil {
+		return decimal.Zero, err
+	}
+	return result.Total, nil
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"paytabs/internal/model"
+)
+
+// PlatformTotals are platform-wide counts and sums for operators.
+type PlatformTotals struct {
+	Accounts        int64
+	ActiveMerchants int64
+	Cards           int64
+	CardBalance     decimal.Decimal // Sum of every card balance
+	AccountBalance  decimal.Decimal // Sum of every account balance (merchant proceeds)
+	PaymentsSince   int64
+	TransfersSince  int64
+}
+
+// CurrencyFloat is the money held in one currency, as stored: Currency is empty for cards and
+// accounts created before currencies were stored.
+type CurrencyFloat struct {
+	Currency       string
+	CardBalance    decimal.Decimal // Sum of card balances
+	AccountBalance decimal.Decimal // Sum of account balances (merchant proceeds)
+	PendingPayouts decimal.Decimal // Sum of pending payouts, in the merchant account's currency
+}
+
+// StatsRepository runs platform-wide aggregate queries.
+type StatsRepository interface {
+	PlatformTotals(ctx context.Context, since time.Time) (*PlatformTotals, error)
+	Float(ctx context.Context) ([]CurrencyFloat, error)
+}
+
+type statsRepository struct {
+	db *gorm.DB
+}
+
+// NewStatsRepository creates a new stats repository.
+func NewStatsRepository(db *gorm.DB) StatsRepository {
+	return &statsRepository{db: db}
+}
+
+// PlatformTotals counts accounts, active merchants, and cards, sums card and account
+// balances, and counts payments and transfers created at or after since. Soft-deleted rows
+// are excluded throughout.
+func (r *statsRepository) PlatformTotals(ctx context.Context, since time.Time) (*PlatformTotals, error) {
+	db := r.db.WithContext(ctx)
+	var totals PlatformTotals
+
+	var accounts struct {
+		Count           int64
+		ActiveMerchants int64
+		Balance         decimal.Decimal
+	}
+	if err := db.Model(&model.Account{}).
+		Select("COUNT(*) AS count, COALESCE(SUM(CASE WHEN is_merchant AND active THEN 1 ELSE 0 END), 0) AS active_merchants, COALESCE(SUM(balance), 0) AS balance").
+		Scan(&accounts).Error; err != nil {
+		return nil, err
+	}
+	totals.Accounts = accounts.Count
+	totals.ActiveMerchants = accounts.ActiveMerchants
+	totals.AccountBalance = accounts.Balance
+
+	var cards struct {
+		Count   int64
+		Balance decimal.Decimal
+	}
+	if err := db.Model(&model.Card{}).
+		Select("COUNT(*) AS count, COALESCE(SUM(balance), 0) AS balance").
+		Scan(&cards).Error; err != nil {
+		return nil, err
+	}
+	totals.Cards = cards.Count
+	totals.CardBalance = cards.Balance
+
+	if err := db.Model(&model.Payment{}).Where("created_at >= ?", since).Count(&totals.PaymentsSince).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Model(&model.Transfer{}).Where("created_at >= ?", since).Count(&totals.TransfersSince).Error; err != nil {
+		return nil, err
+	}
+
+	return &totals, nil
+}
+
+// Float sums card balances, account balances, and pending payouts per stored currency, in
+// one read-only REPEATABLE READ transaction so the three sums reconcile with each other even
+// while money moves. Soft-deleted cards and accounts are excluded, and so are the payouts of
+// soft-deleted accounts. Currencies are sorted; a currency appears if any of its sums does.
+func (r *statsRepository) Float(ctx context.Context) ([]CurrencyFloat, error) {
+	type currencySum struct {
+		Currency string
+		Total    decimal.Decimal
+	}
+	var cards, accounts, payouts []currencySum
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Card{}).
+			Select("currency, COALESCE(SUM(balance), 0) AS total").
+			Group("currency").
+			Scan(&cards).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Account{}).
+			Select("currency, COALESCE(SUM(balance), 0) AS total").
+			Group("currency").
+			Scan(&accounts).Error; err != nil {
+			return err
+		}
+		return tx.Table("payouts").
+			Select("accounts.currency AS currency, COALESCE(SUM(payouts.amount), 0) AS total").
+			Joins("JOIN accounts ON accounts.id = payouts.merchant_account_id AND accounts.deleted_at IS NULL").
+			Where("payouts.status = ?", model.PayoutStatusPending).
+			Group("accounts.currency").
+			Scan(&payouts).Error
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+
+	byCurrency := make(map[string]*CurrencyFloat)
+	float := func(currency string) *CurrencyFloat {
+		f, ok := byCurrency[currency]
+		if !ok {
+			f = &CurrencyFloat{Currency: currency}
+			byCurrency[currency] = f
+		}
+		return f
+	}
+	for _, s := range cards {
+		float(s.Currency).CardBalance = s.Total
+	}
+	for _, s := range accounts {
+		float(s.Currency).AccountBalance = s.Total
+	}
+	for _, s := range payouts {
+		float(s.Currency).PendingPayouts = s.Total
+	}
+
+	floats := make([]CurrencyFloat, 0, len(byCurrency))
+	for _, f := range byCurrency {
+		floats = append(floats, *f)
+	}
+	sort.Slice(floats, func(i, j int) bool { return floats[i].Currency < floats[j].Currency })
+	return floats, nil
+}
@@ -2,17 +2,45 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 
 	"paytabs/internal/model"
 )
 
+// AccountTransfer is a transfer annotated with its direction relative to an account, or to
+// a card when listed by card.
+type AccountTransfer struct {
+	model.Transfer
+	Direction model.TransferDirection `json:"direction"`
+}
+
+// TransferDirectionTotal is the number and summed amount of a card's transfers in one direction.
+type TransferDirectionTotal struct {
+	Direction model.TransferDirection
+	Count     int64
+	Total     decimal.Decimal
+}
+
+// TransferDayTotal is the number and summed amount of a card's transfers on one UTC day.
+type TransferDayTotal struct {
+	Day   time.Time
+	Count int64
+	Total decimal.Decimal
+}
+
 // TransferRepository defines transfer persistence operations.
 type TransferRepository interface {
 	Create(ctx context.Context, transfer *model.Transfer) error
 	FindByID(ctx context.Context, id uuid.UUID) (*model.Transfer, error)
+	ListByAccount(ctx context.Context, accountID uuid.UUID, limit, offset int) ([]AccountTransfer, int64, error)
+	ListAllByAccountTx(ctx context.Context, tx interface{}, accountID uuid.UUID) ([]AccountTransfer, error)
+	ListByCard(ctx context.Context, cardID uuid.UUID, status model.TransferStatus, limit, offset int) ([]AccountTransfer, int64, error)
+	SumByCardAndDirection(ctx context.Context, cardID uuid.UUID, from, to time.Time) ([]TransferDirectionTotal, error)
+	BusiestDayByCard(ctx context.Context, cardID uuid.UUID, from, to time.Time) (*TransferDayTotal, error)
 }
 
 type transferRepository struct {
@@ -38,3 +66,146 @@ func (r *transferRepository) FindByID(ctx context.Context, id uuid.UUID) (*model
 	return &transfer, nil
 }
 
+
+// ListByAccount lists transfers where any card of the account is the source or destination,
+// newest first, along with the total number of matching transfers. Card ownership is
+// resolved with a join so the page is built in a single query.
+func (r *transferRepository) ListByAccount(ctx context.Context, accountID uuid.UUID, limit, offset int) ([]AccountTransfer, int64, error) {
+	query := accountTransfers(r.db.WithContext(ctx), accountID).Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var transfers []AccountTransfer
+	err := selectAccountTransferDirection(query, accountID).
+		Order("transfers.created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Scan(&transfers).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return transfers, total, nil
+}
+
+// ListAllByAccountTx lists every transfer where any card of the account is the source or
+// destination, oldest first, within a transaction.
+func (r *transferRepository) ListAllByAccountTx(ctx context.Context, tx interface{}, accountID uuid.UUID) ([]AccountTransfer, error) {
+	txDB := tx.(*gorm.DB)
+	var transfers []AccountTransfer
+	if err := selectAccountTransferDirection(accountTransfers(txDB.WithContext(ctx), accountID), accountID).
+		Order("transfers.created_at ASC, transfers.id ASC").
+		Scan(&transfers).Error; err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
+
+// accountTransfers selects the transfers where any card of the account is the source or
+// destination, joining both cards to resolve ownership.
+func accountTransfers(db *gorm.DB, accountID uuid.UUID) *gorm.DB {
+	return db.Table("transfers").
+		Joins("JOIN cards AS source_cards ON source_cards.id = transfers.source_card_id").
+		Joins("JOIN cards AS destination_cards ON destination_cards.id = transfers.destination_card_id").
+		Where("transfers.deleted_at IS NULL").
+		Where("source_cards.account_id = ? OR destination_cards.account_id = ?", accountID, accountID)
+}
+
+// selectAccountTransferDirection selects the transfer columns of query along with each
+// transfer's direction relative to the account.
+func selectAccountTransferDirection(query *gorm.DB, accountID uuid.UUID) *gorm.DB {
+	return query.Select(`transfers.*, CASE
+			WHEN source_cards.account_id = ? AND destination_cards.account_id = ? THEN ?
+			WHEN source_cards.account_id = ? THEN ?
+			ELSE ? END AS direction`,
+		accountID, accountID, model.TransferDirectionInternal,
+		accountID, model.TransferDirectionOutbound,
+		model.TransferDirectionInbound)
+}
+
+// ListByCard lists transfers into or out of the card, newest first, along with the total
+// number of matching transfers. An empty status matches every status.
+func (r *transferRepository) ListByCard(ctx context.Context, cardID uuid.UUID, status model.TransferStatus, limit, offset int) ([]AccountTransfer, int64, error) {
+	query := transfersOfCard(r.db.WithContext(ctx), cardID, status).Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var transfers []AccountTransfer
+	if err := pageCardTransfers(query, cardID, limit, offset).Scan(&transfers).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return transfers, total, nil
+}
+
+// transfersOfCard scopes a query to the transfers into or out of the card in status, or in
+// any status when it is empty.
+func transfersOfCard(db *gorm.DB, cardID uuid.UUID, status model.TransferStatus) *gorm.DB {
+	query := db.Model(&model.Transfer{}).
+		Where("source_card_id = ? OR destination_card_id = ?", cardID, cardID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	return query
+}
+
+// pageCardTransfers selects one page of a card's transfers, newest first, each with its
+// direction relative to the card.
+func pageCardTransfers(query *gorm.DB, cardID uuid.UUID, limit, offset int) *gorm.DB {
+	return query.
+		Select("transfers.*, CASE WHEN source_card_id = ? THEN ? ELSE ? END AS direction",
+			cardID, model.TransferDirectionOutbound, model.TransferDirectionInbound).
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset)
+}
+
+// cardTransfers scopes a query to the completed transfers into or out of a card created
+// within [from, to]. Failed and pending transfers moved no money and are left out.
+func (r *transferRepository) cardTransfers(ctx context.Context, cardID uuid.UUID, from, to time.Time) *gorm.DB {
+	return r.db.WithContext(ctx).Model(&model.Transfer{}).
+		Where("(source_card_id = ? OR destination_card_id = ?) AND status = ? AND created_at BETWEEN ? AND ?",
+			cardID, cardID, model.TransferStatusCompleted, from, to)
+}
+
+// SumByCardAndDirection counts and totals a card's completed transfers within [from, to],
+// grouped into inbound and outbound with a single query. Directions with no transfers are
+// absent from the result.
+func (r *transferRepository) SumByCardAndDirection(ctx context.Context, cardID uuid.UUID, from, to time.Time) ([]TransferDirectionTotal, error) {
+	var totals []TransferDirectionTotal
+	err := r.cardTransfers(ctx, cardID, from, to).
+		Select("CASE WHEN source_card_id = ? THEN ? ELSE ? END AS direction, COUNT(*) AS count, COALESCE(SUM(amount), 0) AS total",
+			cardID, model.TransferDirectionOutbound, model.TransferDirectionInbound).
+		Group("direction").
+		Order("direction ASC").
+		Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+// BusiestDayByCard returns the UTC day within [from, to] with the most completed transfers
+// into or out of the card, the earliest day winning ties. It returns nil when there are none.
+func (r *transferRepository) BusiestDayByCard(ctx context.Context, cardID uuid.UUID, from, to time.Time) (*TransferDayTotal, error) {
+	var days []TransferDayTotal
+	err := r.cardTransfers(ctx, cardID, from, to).
+		Select("DATE(created_at) AS day, COUNT(*) AS count, COALESCE(SUM(amount), 0) AS total").
+		Group("DATE(created_at)").
+		Order("count DESC, day ASC").
+		Limit(1).
+		Scan(&days).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(days) == 0 {
+		return nil, nil
+	}
+	return &days[0], nil
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"paytabs/internal/model"
+)
+
+// cardTransfersSQL renders the page query ListByCard runs.
+func cardTransfersSQL(t *testing.T, cardID uuid.UUID, status model.TransferStatus, limit, offset int) string {
+	t.Helper()
+	gdb, _ := newHeldLockDB(t)
+	// Scan reports that dry runs are unsupported; the SQL is rendered all the same
+	gdb = gdb.Session(&gorm.Session{Logger: logger.Discard})
+	return gdb.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		var transfers []AccountTransfer
+		return pageCardTransfers(transfersOfCard(tx, cardID, status), cardID, limit, offset).Scan(&transfers)
+	})
+}
+
+func TestTransferRepository_ListByCardQuery(t *testing.T) {
+	cardID := uuid.New()
+	id := "'" + cardID.String() + "'"
+
+	t.Run("any status", func(t *testing.T) {
+		sql := cardTransfersSQL(t, cardID, "", 20, 40)
+
+		assert.Contains(t, sql, "source_card_id = "+id+" OR destination_card_id = "+id, "the card as source or destination")
+		assert.NotContains(t, sql, "status =")
+		assert.Contains(t, sql, "CASE WHEN source_card_id = "+id+" THEN 'outbound' ELSE 'inbound' END AS direction")
+		assert.Contains(t, sql, "ORDER BY created_at DESC LIMIT 20 OFFSET 40")
+	})
+
+	t.Run("one status", func(t *testing.T) {
+		sql := cardTransfersSQL(t, cardID, model.TransferStatusFailed, 20, 0)
+
+		assert.Contains(t, sql, "(source_card_id = "+id+" OR destination_card_id = "+id+") AND status = 'failed'")
+	})
+}
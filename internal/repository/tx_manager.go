@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"gorm.io/gorm"
+)
+
+// TxManager runs work that spans several repositories inside one database transaction.
+// The tx handed to fn is passed to the repositories' *Tx methods.
+type TxManager interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context, tx interface{}) error) error
+	// WithSnapshot runs fn in a read-only REPEATABLE READ transaction, so every read through
+	// tx sees the database as of the first one, whatever commits in between.
+	WithSnapshot(ctx context.Context, fn func(ctx context.Context, tx interface{}) error) error
+}
+
+type txManager struct {
+	db *gorm.DB
+}
+
+// NewTxManager creates a new transaction manager.
+func NewTxManager(db *gorm.DB) TxManager {
+	return &txManager{db: db}
+}
+
+// WithTransaction executes fn within a database transaction, rolling back if it returns an error.
+func (m *txManager) WithTransaction(ctx context.Context, fn func(ctx context.Context, tx interface{}) error) error {
+	return m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(ctx, tx)
+	})
+}
+
+// WithSnapshot executes fn within a read-only REPEATABLE READ transaction. InnoDB fixes the
+// transaction's read view at its first consistent read, not when it begins.
+func (m *txManager) WithSnapshot(ctx context.Context, fn func(ctx context.Context, tx interface{}) error) error {
+	return m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(ctx, tx)
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"paytabs/internal/model"
+)
+
+// WebhookRepository defines webhook secret and endpoint persistence operations.
+type WebhookRepository interface {
+	// SaveSecret inserts or replaces the account's secret and records audit in the same transaction.
+	SaveSecret(ctx context.Context, secret *model.WebhookSecret, audit *model.WebhookSecretAudit) error
+	FindSecret(ctx context.Context, accountID uuid.UUID) (*model.WebhookSecret, error)
+	CreateAudit(ctx context.Context, audit *model.WebhookSecretAudit) error
+	// SaveEndpoint inserts or replaces the account's webhook endpoint.
+	SaveEndpoint(ctx context.Context, endpoint *model.WebhookEndpoint) error
+	FindEndpoint(ctx context.Context, accountID uuid.UUID) (*model.WebhookEndpoint, error)
+}
+
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookRepository creates a new webhook repository.
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+// SaveSecret upserts the secret and writes the audit record atomically.
+func (r *webhookRepository) SaveSecret(ctx context.Context, secret *model.WebhookSecret, audit *model.WebhookSecretAudit) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "account_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"ciphertext", "updated_at"}),
+		}).Create(secret).Error; err != nil {
+			return err
+		}
+		return tx.Create(audit).Error
+	})
+}
+
+// FindSecret finds the account's webhook secret.
+func (r *webhookRepository) FindSecret(ctx context.Context, accountID uuid.UUID) (*model.WebhookSecret, error) {
+	var secret model.WebhookSecret
+	if err := r.db.WithContext(ctx).First(&secret, "account_id = ?", accountID).Error; err != nil {
+		return nil, err
+	}
+	return &secret, nil
+}
+
+// CreateAudit records a webhook secret audit entry.
+func (r *webhookRepository) CreateAudit(ctx context.Context, audit *model.WebhookSecretAudit) error {
+	return r.db.WithContext(ctx).Create(audit).Error
+}
+
+// SaveEndpoint upserts the account's webhook endpoint.
+func (r *webhookRepository) SaveEndpoint(ctx context.Context, endpoint *model.WebhookEndpoint) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "account_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"url", "updated_at"}),
+	}).Create(endpoint).Error
+}
+
+// FindEndpoint finds the account's webhook endpoint.
+func (r *webhookRepository) FindEndpoint(ctx context.Context, accountID uuid.UUID) (*model.WebhookEndpoint, error) {
+	var endpoint model.WebhookEndpoint
+	if err := r.db.WithContext(ctx).First(&endpoint, "account_id = ?", accountID).Error; err != nil {
+		return nil, err
+	}
+	return &endpoint, nil
+}
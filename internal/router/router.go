@@ -1,32 +1,76 @@
 package router
 
 import (
+	"fmt"
+	"log"
+	"net"
 	"net/http"
+	"time"
 
 	"github.com/go-playground/validator/v10"
-	"github.com/golang-jwt/jwt/v4"
 	echojwt "github.com/labstack/echo-jwt/v4"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	echoSwagger "github.com/swaggo/echo-swagger"
+	"golang.org/x/time/rate"
 
+	"paytabs/internal/auth"
+	"paytabs/internal/cache"
 	"paytabs/internal/config"
+	"paytabs/internal/errors"
 	"paytabs/internal/handler"
+	appmiddleware "paytabs/internal/middleware"
+	"paytabs/internal/tracing"
+)
+
+const (
+	// emailAvailabilityInterval and emailAvailabilityBurst allow 5 lookups up front,
+	// then one every 12 seconds, per client IP.
+	emailAvailabilityInterval = 12 * time.Second
+	emailAvailabilityBurst    = 5
+
+	// adminStatsInterval and adminStatsBurst allow 10 stats or float reads up front, then one
+	// every 6 seconds, per client IP and endpoint; each read runs platform-wide aggregates.
+	adminStatsInterval = 6 * time.Second
+	adminStatsBurst    = 10
 )
 
 // Register wires routes and middleware.
 func Register(
 	e *echo.Echo,
 	cfg *config.Config,
+	jwtService *auth.JWTService,
+	tokenStore auth.TokenStoreInterface,
+	cacheClient *cache.Client,
 	authHandler *handler.AuthHandler,
 	accountHandler *handler.AccountHandler,
 	paymentHandler *handler.PaymentHandler,
 	transferHandler *handler.TransferHandler,
+	cardHandler *handler.CardHandler,
 	seedHandler *handler.SeedHandler,
+	currencyHandler *handler.CurrencyHandler,
+	merchantHandler *handler.MerchantHandler,
+	webhookHandler *handler.WebhookHandler,
+	adminHandler *handler.AdminHandler,
+	healthHandler *handler.HealthHandler,
 ) {
+	// Client IPs key the login throttle and appear in audit logs, so X-Forwarded-For is only
+	// believed from configured proxies. main rejects malformed TRUSTED_PROXIES at startup.
+	ipExtractor, err := NewIPExtractor(cfg.TrustedProxies)
+	if err != nil {
+		log.Printf("router: %v; using the connection address as the client IP", err)
+		ipExtractor = echo.ExtractIPDirect()
+	}
+	e.IPExtractor = ipExtractor
+
 	e.Use(middleware.Logger())
+	// Inside the logger and outside Recover, so a panic is recorded on the span as a 500
+	e.Use(appmiddleware.Tracing(tracing.Tracer()))
 	e.Use(middleware.Recover())
 
+	// Render every error (including unmatched routes) as an ErrorResponse
+	e.HTTPErrorHandler = HTTPErrorHandler
+
 	// Add validator
 	e.Validator = &CustomValidator{validator: validator.New()}
 
@@ -34,9 +78,11 @@ func Register(
 		// Swag uses this for server URL in docs when set.
 	}
 
+	// Liveness only: the process is up. Readiness also needs MySQL and Redis to answer
 	e.GET("/healthz", func(c echo.Context) error {
 		return c.String(http.StatusOK, "ok")
 	})
+	e.GET("/readyz", healthHandler.Ready)
 
 	// Swagger documentation
 	e.GET("/api-docs", func(c echo.Context) error {
@@ -44,38 +90,164 @@ func Register(
 	})
 	e.GET("/api-docs/*", echoSwagger.WrapHandler)
 
-	api := e.Group("/api")
+	// Every listing endpoint reads its page size through these limits
+	api := e.Group("/api", handler.WithPageSizeLimits(handler.PageSizeLimits{
+		Max:             cfg.MaxPageSize,
+		RejectOversized: cfg.RejectOversizedPages,
+	}))
+
+	// Only API responses are compressed; the swagger UI serves its own assets as they are
+	if cfg.CompressionEnabled {
+		api.Use(appmiddleware.Compression(cfg.CompressionMinLength))
+	}
+
+	// Maintenance mode refuses writes before authentication, so they fail fast and alike
+	api.Use(appmiddleware.Maintenance(cacheClient, cfg.MaintenanceMode, cfg.MaintenanceRetryAfter))
 
 	// Public routes
 	api.POST("/auth/register", authHandler.Register)
 	api.POST("/auth/login", authHandler.Login)
 	api.POST("/auth/refresh", authHandler.Refresh)
 	api.POST("/auth/logout", authHandler.Logout)
+	api.POST("/auth/verify-email", authHandler.VerifyEmail)
+	api.POST("/auth/resend-verification", authHandler.ResendVerification)
+	api.POST("/auth/forgot-password", authHandler.ForgotPassword)
+	api.POST("/auth/reset-password", authHandler.ResetPassword)
 	api.GET("/seed/accounts", seedHandler.SeedAccounts)
+	api.GET("/currencies", currencyHandler.ListCurrencies)
 
-	// Secured routes (require JWT authentication)
-	secured := api.Group("", echojwt.WithConfig(echojwt.Config{
-		SigningKey:  []byte(cfg.JWTSecret),
-		TokenLookup: "header:" + echo.HeaderAuthorization,
-	}))
+	// Legacy /users routes. Accounts are the only identity in this service: the old
+	// model.User (uint IDs, separate table) and its endpoints were removed rather than
+	// adapted, so these answer 410 for every method and point integrators at
+	// /auth/register and /me instead. Public so clients see why without a token.
+	api.Any("/users", legacyUsersGone)
+	api.Any("/users/*", legacyUsersGone)
 
-	secured.GET("/me", func(c echo.Context) error {
-		token, ok := c.Get("user").(*jwt.Token)
-		if !ok {
-			return echo.NewHTTPError(http.StatusUnauthorized, "invalid token")
+	// Email availability leaks which addresses are registered, so it is opt-in and
+	// limited to a handful of lookups per minute per client IP.
+	if cfg.EmailAvailabilityEnabled {
+		api.GET("/auth/email-available", authHandler.EmailAvailable, middleware.RateLimiterWithConfig(middleware.RateLimiterConfig{
+			Store: middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
+				Rate:      rate.Every(emailAvailabilityInterval),
+				Burst:     emailAvailabilityBurst,
+				ExpiresIn: 10 * time.Minute,
+			}),
+		}))
+	}
+
+	// Operator routes authenticate with ADMIN_TOKEN rather than a user JWT and are only
+	// registered when one is configured.
+	if cfg.AdminToken != "" {
+		admin := api.Group("/admin", appmiddleware.AdminToken(cfg.AdminToken))
+		admin.GET("/kill-switches", adminHandler.ListKillSwitches)
+		admin.PUT("/kill-switches/:operation", adminHandler.SetKillSwitch)
+		admin.GET("/accounts/:id/hold", adminHandler.GetAccountHold)
+		admin.PUT("/accounts/:id/hold", adminHandler.PlaceAccountHold)
+		admin.DELETE("/accounts/:id/hold", adminHandler.LiftAccountHold)
+		admin.GET("/accounts", accountHandler.AdminListAccounts)
+		admin.POST("/accounts/balances", accountHandler.AdminGetBalances)
+		admin.GET("/accounts/:id/snapshot", accountHandler.AdminGetSnapshot)
+		admin.GET("/cards", cardHandler.AdminListCards)
+		admin.GET("/merchants/:id/settings", adminHandler.GetMerchantSettings)
+		admin.PUT("/merchants/:id/settings", adminHandler.UpdateMerchantSettings)
+		aggregateLimit := func() echo.MiddlewareFunc {
+			return middleware.RateLimiterWithConfig(middleware.RateLimiterConfig{
+				Store: middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
+					Rate:      rate.Every(adminStatsInterval),
+					Burst:     adminStatsBurst,
+					ExpiresIn: 10 * time.Minute,
+				}),
+			})
 		}
-		claims, _ := token.Claims.(jwt.MapClaims)
-		return c.JSON(http.StatusOK, echo.Map{"token_claims": claims})
-	})
+		admin.GET("/stats", adminHandler.GetStats, aggregateLimit())
+		admin.GET("/float", adminHandler.GetFloat, aggregateLimit())
+	}
+
+	// Secured routes (require JWT authentication).
+	// Tokens are parsed by JWTService, which verifies them with the configured algorithm's key
+	// (the public key under RS256), so handlers receive *auth.Claims under the "user" key.
+	// Only access tokens are accepted: refresh tokens are revoked by deleting them from Redis,
+	// which this check never consults. Access tokens blacklisted on logout are then rejected.
+	secured := api.Group("", echojwt.WithConfig(echojwt.Config{
+		TokenLookup: "header:" + echo.HeaderAuthorization + ":Bearer ",
+		ParseTokenFunc: func(c echo.Context, token string) (interface{}, error) {
+			return jwtService.ValidateAccessToken(token)
+		},
+	}), appmiddleware.RejectRevokedTokens(tokenStore))
+
+	secured.POST("/auth/logout-all", authHandler.LogoutAll)
+	secured.POST("/auth/change-password", authHandler.ChangePassword)
+
+	secured.GET("/me", accountHandler.GetMe)
+	secured.GET("/me/wallet", accountHandler.GetWallet)
+	secured.GET("/me/balance", accountHandler.GetMyBalance)
+	secured.GET("/me/payments", paymentHandler.ListMyPayments)
+	secured.GET("/me/transfers", transferHandler.ListMyTransfers)
 
 	// Account routes
+	secured.GET("/accounts/:id", accountHandler.GetAccount)
 	secured.GET("/accounts/:id/balance", accountHandler.GetBalance)
+	secured.POST("/accounts/balances", accountHandler.GetBalances)
+	secured.GET("/accounts/:id/payments", paymentHandler.ListAccountPayments)
+	secured.GET("/accounts/:id/transfers", transferHandler.ListAccountTransfers)
+	secured.GET("/accounts/:id/snapshot", accountHandler.GetSnapshot)
+
+	// Merchant routes
+	secured.GET("/merchants/me/balance", merchantHandler.GetMyBalance)
+	secured.GET("/merchants/me/customers", merchantHandler.ListCustomers)
+	secured.GET("/merchants/me/pending", merchantHandler.GetPending)
+	secured.GET("/merchants/me/balance-alerts", merchantHandler.GetBalanceAlerts)
+	secured.PUT("/merchants/me/balance-alerts", merchantHandler.UpdateBalanceAlerts)
+	secured.GET("/payouts", merchantHandler.ListPayouts)
+	secured.GET("/payouts/:id", merchantHandler.GetPayout)
+
+	// Webhook routes
+	secured.POST("/webhooks/secret/reveal", webhookHandler.RevealSecret)
+	secured.GET("/webhooks/endpoint", webhookHandler.GetEndpoint)
+	secured.PUT("/webhooks/endpoint", webhookHandler.SetEndpoint)
+	secured.POST("/webhooks/test", webhookHandler.SendTestEvent)
+
+	// Card routes
+	secured.GET("/cards", cardHandler.ListMyCards)
+	secured.POST("/cards", cardHandler.CreateCard)
+	secured.GET("/cards/:id/balance-history", cardHandler.GetBalanceHistory)
+	secured.GET("/cards/:id/transfer-stats", transferHandler.GetCardTransferStats)
+	secured.GET("/cards/:id/transfers", transferHandler.ListCardTransfers)
+	secured.GET("/cards/:id/transfers/failed", transferHandler.ListFailedCardTransfers)
+	secured.GET("/cards/:id/payments", paymentHandler.ListCardPayments)
+	secured.POST("/cards/bulk", cardHandler.CreateCardsBulk)
+	secured.PATCH("/cards/:id", cardHandler.UpdateCard)
+	secured.GET("/cards/:id/available-balance", cardHandler.GetAvailableBalance)
+	secured.GET("/cards/:id/holds/:hold_id", cardHandler.GetHold)
+	secured.POST("/cards/:id/holds/:hold_id/release", cardHandler.ReleaseHold)
+
+	// Mutating money-movement routes replay stored responses for repeated Idempotency-Keys
+	idempotent := appmiddleware.Idempotency(cacheClient, cfg.IdempotencyTTL)
+
+	// Card withdrawal routes
+	secured.POST("/cards/:id/withdraw", cardHandler.Withdraw, idempotent)
+
+	// Card hold routes
+	secured.POST("/cards/:id/holds", cardHandler.PlaceHold, idempotent)
+
+	// Payout routes
+	secured.POST("/merchants/me/payouts", merchantHandler.RequestPayout, idempotent)
 
 	// Payment routes
-	secured.POST("/payments/card", paymentHandler.ProcessCardPayment)
+	secured.POST("/payments/card", paymentHandler.ProcessCardPayment, idempotent)
+	secured.POST("/payments/:id/retry", paymentHandler.RetryPayment, idempotent)
+	secured.POST("/payments/:id/cancel", paymentHandler.CancelPayment)
+	secured.POST("/payments/:id/refund", paymentHandler.RefundPayment, idempotent)
+	secured.GET("/payments", paymentHandler.ListMerchantPayments)
+	secured.GET("/payments/:id", paymentHandler.GetPayment)
+	secured.GET("/payments/:id/timeline", paymentHandler.GetPaymentTimeline)
+	secured.GET("/payments/:id/breakdown", paymentHandler.GetPaymentBreakdown)
+	secured.GET("/payments/:id/receipt", paymentHandler.GetPaymentReceipt)
+	secured.GET("/payments/:id/receipt.pdf", paymentHandler.GetPaymentReceiptPDF)
 
 	// Transfer routes
-	secured.POST("/transfers", transferHandler.ProcessTransfer)
+	secured.POST("/transfers", transferHandler.ProcessTransfer, idempotent)
+	secured.POST("/transfers/validate", transferHandler.ValidateTransfer)
 }
 
 // CustomValidator wraps validator for Echo.
@@ -87,3 +259,100 @@ type CustomValidator struct {
 func (cv *CustomValidator) Validate(i interface{}) error {
 	return cv.validator.Struct(i)
 }
+
+// HTTPErrorHandler renders all errors using the standard ErrorResponse JSON shape.
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	status := http.StatusInternalServerError
+	resp := errors.ErrorResponse{
+		Error: "internal server error",
+		Code:  errors.CodeInternalError,
+	}
+
+	switch e := err.(type) {
+	case *echo.HTTPError:
+		status = e.Code
+		switch msg := e.Message.(type) {
+		case errors.ErrorResponse:
+			resp = msg
+		case *errors.ErrorResponse:
+			resp = *msg
+		case string:
+			resp = errors.ErrorResponse{Error: msg, Code: codeForStatus(status)}
+		default:
+			resp = errors.ErrorResponse{Error: http.StatusText(status), Code: codeForStatus(status)}
+		}
+	case *errors.HTTPError:
+		status = e.StatusCode
+		resp = e.ToErrorResponse()
+	}
+
+	var writeErr error
+	if c.Request().Method == http.MethodHead {
+		writeErr = c.NoContent(status)
+	} else {
+		writeErr = c.JSON(status, resp)
+	}
+	if writeErr != nil {
+		c.Logger().Error(writeErr)
+	}
+}
+
+// codeForStatus returns a generic error code for errors that don't carry one.
+func codeForStatus(status int) errors.Code {
+	switch status {
+	case http.StatusBadRequest:
+		return errors.CodeBadRequest
+	case http.StatusUnauthorized:
+		return errors.CodeUnauthorized
+	case http.StatusForbidden:
+		return errors.CodeForbidden
+	case http.StatusNotFound:
+		return errors.CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return errors.CodeMethodNotAllowed
+	case http.StatusConflict:
+		return errors.CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return errors.CodeRequestTooLarge
+	case http.StatusTooManyRequests:
+		return errors.CodeTooManyRequests
+	case http.StatusServiceUnavailable:
+		return errors.CodeServiceUnavailable
+	default:
+		if status >= http.StatusInternalServerError {
+			return errors.CodeInternalError
+		}
+		return errors.CodeRequestError
+	}
+}
+
+// legacyUsersGone answers requests to the removed /users endpoints.
+// NewIPExtractor resolves c.RealIP(). Without trusted proxies it is the connection's address,
+// so a client cannot pick its own IP with X-Forwarded-For. With them, X-Forwarded-For is
+// read right to left, skipping only addresses inside the given CIDR ranges.
+func NewIPExtractor(trustedProxies []string) (echo.IPExtractor, error) {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+	// Echo trusts loopback and private ranges by default; only the configured ones count
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, cidr := range trustedProxies {
+		_, ipRange, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q is not a CIDR range: %w", cidr, err)
+		}
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
+
+func legacyUsersGone(c echo.Context) error {
+	return echo.NewHTTPError(http.StatusGone, errors.ErrorResponse{
+		Error: "the /users endpoints have been removed; use /api/auth/register and /api/me (accounts)",
+		Code:  errors.CodeEndpointRemoved,
+	})
+}
@@ -0,0 +1,361 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"paytabs/internal/auth"
+	"paytabs/internal/cache"
+	"paytabs/internal/config"
+	"paytabs/internal/errors"
+	"paytabs/internal/handler"
+	"paytabs/internal/model"
+	"paytabs/internal/repository"
+	"paytabs/internal/service"
+)
+
+// stubAccountService serves GetAccount from accounts; its other methods are not implemented.
+type stubAccountService struct {
+	service.AccountService
+	accounts map[uuid.UUID]*model.Account
+}
+
+func (s stubAccountService) GetAccount(_ context.Context, id uuid.UUID) (*model.Account, error) {
+	if account, ok := s.accounts[id]; ok {
+		return account, nil
+	}
+	return nil, errors.ErrAccountNotFound
+}
+
+// stubAccountRepository serves FindByID from accounts; its other methods are not implemented.
+type stubAccountRepository struct {
+	repository.AccountRepository
+	accounts map[uuid.UUID]*model.Account
+}
+
+func (r stubAccountRepository) FindByID(_ context.Context, id uuid.UUID) (*model.Account, error) {
+	if account, ok := r.accounts[id]; ok {
+		return account, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// openSession issues an access and a refresh token for account and stores the refresh token,
+// as a login would.
+func openSession(t *testing.T, jwtService *auth.JWTService, tokenStore *auth.TokenStore, account *model.Account) (accessToken, refreshToken string) {
+	t.Helper()
+	accessToken, err := jwtService.GenerateAccessToken(42, account.ID.String(), account.Email)
+	require.NoError(t, err)
+	refreshID, refreshToken, err := jwtService.GenerateRefreshToken(42, account.ID.String(), account.Email)
+	require.NoError(t, err)
+	require.NoError(t, tokenStore.StoreRefreshToken(context.Background(), refreshID, 42, account.ID.String(), account.Email, auth.RefreshTokenExpiry))
+	return accessToken, refreshToken
+}
+
+// serve sends a request with an optional bearer token and JSON body to e.
+func serve(e *echo.Echo, method, path, bearer, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if bearer != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+bearer)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func newTestServer() *echo.Echo {
+	e := echo.New()
+	Register(e, &config.Config{JWTSecret: "test-secret"}, auth.NewJWTService("test-secret", auth.AccessTokenExpiry, auth.RefreshTokenExpiry), auth.NewTokenStore(nil, 0), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	return e
+}
+
+func TestHTTPErrorHandler_JSONResponses(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		expectedCode int
+		expectedErr  errors.Code
+	}{
+		{
+			name:         "unknown path",
+			method:       http.MethodGet,
+			path:         "/does-not-exist",
+			expectedCode: http.StatusNotFound,
+			expectedErr:  errors.CodeNotFound,
+		},
+		{
+			name:         "wrong method",
+			method:       http.MethodPost,
+			path:         "/healthz",
+			expectedCode: http.StatusMethodNotAllowed,
+			expectedErr:  errors.CodeMethodNotAllowed,
+		},
+		{
+			name:         "legacy users route",
+			method:       http.MethodGet,
+			path:         "/api/users/1",
+			expectedCode: http.StatusGone,
+			expectedErr:  errors.CodeEndpointRemoved,
+		},
+		{
+			name:         "missing token",
+			method:       http.MethodGet,
+			path:         "/api/me",
+			expectedCode: http.StatusUnauthorized,
+			expectedErr:  errors.CodeUnauthorized,
+		},
+	}
+
+	e := newTestServer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+			assert.Contains(t, rec.Header().Get(echo.HeaderContentType), echo.MIMEApplicationJSON)
+
+			var body errors.ErrorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			assert.Equal(t, tt.expectedErr, body.Code)
+			assert.NotEmpty(t, body.Error)
+		})
+	}
+}
+
+func TestHTTPErrorHandler_PassesErrorResponseThrough(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.GET("/boom", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.ErrorResponse{
+			Error: "invalid account ID",
+			Code:  errors.CodeInvalidUUID,
+		})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/boom", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	var body errors.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, errors.ErrorResponse{Error: "invalid account ID", Code: errors.CodeInvalidUUID}, body)
+}
+
+func TestRegister_AdminRoutesRejectWrongToken(t *testing.T) {
+	e := echo.New()
+	cfg := &config.Config{JWTSecret: "test-secret", AdminToken: "ops-token"}
+	Register(e, cfg, auth.NewJWTService("test-secret", auth.AccessTokenExpiry, auth.RefreshTokenExpiry), auth.NewTokenStore(nil, 0), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/kill-switches", nil)
+	req.Header.Set("X-Admin-Token", "wrong")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestRegister_MaintenanceModeRefusesWrites(t *testing.T) {
+	e := echo.New()
+	cfg := &config.Config{JWTSecret: "test-secret", MaintenanceMode: true, MaintenanceRetryAfter: 2 * time.Minute}
+	Register(e, cfg, auth.NewJWTService("test-secret", auth.AccessTokenExpiry, auth.RefreshTokenExpiry), auth.NewTokenStore(nil, 0), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/payments/card", strings.NewReader(`{}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "120", rec.Header().Get(echo.HeaderRetryAfter))
+	var body errors.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, errors.CodeMaintenanceMode, body.Code)
+
+	// Health checks stay up, and reads still reach their routes
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/me", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestRegister_LogoutRevokesAccessToken(t *testing.T) {
+	mr := miniredis.RunT(t)
+	cacheClient := cache.New(mr.Addr(), "", 0)
+	jwtService := auth.NewJWTService("test-secret", auth.AccessTokenExpiry, auth.RefreshTokenExpiry)
+	tokenStore := auth.NewTokenStore(cacheClient, 0)
+	authHandler := handler.NewAuthHandler(service.NewAuthService(nil, jwtService, tokenStore, nil, nil, nil))
+	accountID := uuid.MustParse("c56a4180-65aa-42ec-a945-5fd21dec0538")
+	accountHandler := handler.NewAccountHandler(stubAccountService{accounts: map[uuid.UUID]*model.Account{
+		accountID: {ID: accountID, Email: "test@example.com"},
+	}}, nil)
+
+	e := echo.New()
+	Register(e, &config.Config{JWTSecret: "test-secret"}, jwtService, tokenStore, cacheClient, authHandler, accountHandler, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	accessToken, err := jwtService.GenerateAccessToken(42, "c56a4180-65aa-42ec-a945-5fd21dec0538", "test@example.com")
+	require.NoError(t, err)
+	refreshID, refreshToken, err := jwtService.GenerateRefreshToken(42, "c56a4180-65aa-42ec-a945-5fd21dec0538", "test@example.com")
+	require.NoError(t, err)
+	require.NoError(t, tokenStore.StoreRefreshToken(context.Background(), refreshID, 42, "c56a4180-65aa-42ec-a945-5fd21dec0538", "test@example.com", auth.RefreshTokenExpiry))
+
+	getMe := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+accessToken)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	require.Equal(t, http.StatusOK, getMe().Code)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/logout", strings.NewReader(`{"refresh_token":"`+refreshToken+`"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+accessToken)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	// The access token is rejected from now on instead of staying valid until it expires
+	rec = getMe()
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	var body errors.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, errors.CodeInvalidToken, body.Code)
+}
+
+func TestRegister_RejectsRefreshTokenAsBearer(t *testing.T) {
+	jwtService := auth.NewJWTService("test-secret", auth.AccessTokenExpiry, auth.RefreshTokenExpiry)
+	account := &model.Account{ID: uuid.New(), Email: "alice@example.com"}
+	accountHandler := handler.NewAccountHandler(stubAccountService{accounts: map[uuid.UUID]*model.Account{account.ID: account}}, nil)
+
+	e := echo.New()
+	Register(e, &config.Config{JWTSecret: "test-secret"}, jwtService, auth.NewTokenStore(nil, 0), nil, nil, accountHandler, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	_, refreshToken, err := jwtService.GenerateRefreshToken(42, account.ID.String(), account.Email)
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+refreshToken)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestRegister_LogoutAllRevokesEverySession(t *testing.T) {
+	mr := miniredis.RunT(t)
+	cacheClient := cache.New(mr.Addr(), "", 0)
+	jwtService := auth.NewJWTService("test-secret", auth.AccessTokenExpiry, auth.RefreshTokenExpiry)
+	tokenStore := auth.NewTokenStore(cacheClient, 0)
+	account := &model.Account{ID: uuid.New(), Email: "alice@example.com"}
+	accounts := map[uuid.UUID]*model.Account{account.ID: account}
+	authHandler := handler.NewAuthHandler(service.NewAuthService(stubAccountRepository{accounts: accounts}, jwtService, tokenStore, nil, nil, nil))
+	accountHandler := handler.NewAccountHandler(stubAccountService{accounts: accounts}, nil)
+
+	e := echo.New()
+	Register(e, &config.Config{JWTSecret: "test-secret"}, jwtService, tokenStore, cacheClient, authHandler, accountHandler, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	laptopAccess, _ := openSession(t, jwtService, tokenStore, account)
+	_, phoneRefresh := openSession(t, jwtService, tokenStore, account)
+	require.Equal(t, http.StatusOK, serve(e, http.MethodGet, "/api/me", laptopAccess, "").Code)
+
+	require.Equal(t, http.StatusOK, serve(e, http.MethodPost, "/api/auth/logout-all", laptopAccess, "").Code)
+
+	// The other session's refresh token neither authenticates nor refreshes any more
+	assert.Equal(t, http.StatusUnauthorized, serve(e, http.MethodGet, "/api/me", phoneRefresh, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(e, http.MethodPost, "/api/auth/refresh", "", `{"refresh_token":"`+phoneRefresh+`"}`).Code)
+	// And the access token logout-all was called with is blacklisted
+	assert.Equal(t, http.StatusUnauthorized, serve(e, http.MethodGet, "/api/me", laptopAccess, "").Code)
+}
+
+func TestRegister_MeReturnsTokenAccount(t *testing.T) {
+	jwtService := auth.NewJWTService("test-secret", auth.AccessTokenExpiry, auth.RefreshTokenExpiry)
+	account := &model.Account{ID: uuid.New(), Name: "Alice", Email: "alice@example.com", PasswordHash: "secret-hash"}
+	accountHandler := handler.NewAccountHandler(stubAccountService{accounts: map[uuid.UUID]*model.Account{account.ID: account}}, nil)
+
+	// The revocation check needs Redis
+	mr := miniredis.RunT(t)
+	e := echo.New()
+	Register(e, &config.Config{JWTSecret: "test-secret"}, jwtService, auth.NewTokenStore(cache.New(mr.Addr(), "", 0), 0), nil, nil, accountHandler, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	getMe := func(accountID uuid.UUID) *httptest.ResponseRecorder {
+		accessToken, err := jwtService.GenerateAccessToken(42, accountID.String(), "alice@example.com")
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+accessToken)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := getMe(account.ID)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp model.Account
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, account.ID, resp.ID)
+	assert.Equal(t, "Alice", resp.Name)
+	assert.NotContains(t, rec.Body.String(), "secret-hash")
+
+	// A token that outlives its account finds nothing
+	rec = getMe(uuid.New())
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	var body errors.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, errors.CodeAccountNotFound, body.Code)
+}
+
+func TestRegister_ClientIPIgnoresSpoofedForwardedFor(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		remoteAddr     string
+		forwardedFor   string
+		wantIP         string
+	}{
+		{name: "no trusted proxies", remoteAddr: "203.0.113.9:4000", forwardedFor: "198.51.100.1", wantIP: "203.0.113.9"},
+		{name: "private peer is not trusted by default", remoteAddr: "10.0.0.5:4000", forwardedFor: "198.51.100.1", wantIP: "10.0.0.5"},
+		{name: "untrusted peer", trustedProxies: []string{"10.0.0.0/8"}, remoteAddr: "203.0.113.9:4000", forwardedFor: "198.51.100.1", wantIP: "203.0.113.9"},
+		{name: "trusted proxy", trustedProxies: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.5:4000", forwardedFor: "198.51.100.1", wantIP: "198.51.100.1"},
+		{name: "client prepends a spoofed hop", trustedProxies: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.5:4000", forwardedFor: "1.2.3.4, 198.51.100.1", wantIP: "198.51.100.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			Register(e, &config.Config{JWTSecret: "test-secret", TrustedProxies: tt.trustedProxies}, auth.NewJWTService("test-secret", auth.AccessTokenExpiry, auth.RefreshTokenExpiry), auth.NewTokenStore(nil, 0), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+			e.GET("/client-ip", func(c echo.Context) error {
+				return c.String(http.StatusOK, c.RealIP())
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/client-ip", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set(echo.HeaderXForwardedFor, tt.forwardedFor)
+			req.Header.Set(echo.HeaderXRealIP, "1.2.3.4")
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantIP, rec.Body.String())
+		})
+	}
+}
+
+func TestNewIPExtractor_RejectsMalformedRange(t *testing.T) {
+	_, err := NewIPExtractor([]string{"10.0.0.0/8", "10.0.0.300"})
+	assert.Error(t, err)
+}
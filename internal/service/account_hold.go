@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"paytabs/internal/cache"
+	"paytabs/internal/clock"
+	"paytabs/internal/config"
+	"paytabs/internal/errors"
+)
+
+// AccountHold is a temporary block on payments and transfers from an account's cards,
+// placed when activity on the account looks fraudulent.
+type AccountHold struct {
+	AccountID uuid.UUID `json:"account_id"`
+	Reason    string    `json:"reason"`
+	PlacedAt  time.Time `json:"placed_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// accountHoldKey is the redis key holding an account's hold, e.g. account_hold:<uuid>.
+func accountHoldKey(accountID uuid.UUID) string {
+	return "account_hold:" + accountID.String()
+}
+
+// AccountHoldService places, reads, and lifts account holds.
+type AccountHoldService interface {
+	// Place holds the account for duration, replacing any current hold. A zero duration uses
+	// ACCOUNT_HOLD_DURATION.
+	Place(ctx context.Context, accountID uuid.UUID, reason string, duration time.Duration) (*AccountHold, error)
+	// Get returns the account's current hold, or nil when it has none. It returns an error when
+	// redis cannot be read.
+	Get(ctx context.Context, accountID uuid.UUID) (*AccountHold, error)
+	Lift(ctx context.Context, accountID uuid.UUID) error
+}
+
+// accountHolds keeps each hold in redis with a TTL of its duration, so every instance sees it
+// at once and it lifts itself when it expires.
+type accountHolds struct {
+	cache    *cache.Client
+	clock    clock.Clock
+	duration time.Duration
+}
+
+// NewAccountHoldService creates the account hold service.
+func NewAccountHoldService(cache *cache.Client, clk clock.Clock, cfg *config.Config) AccountHoldService {
+	return newAccountHolds(cache, clk, cfg)
+}
+
+func newAccountHolds(cache *cache.Client, clk clock.Clock, cfg *config.Config) *accountHolds {
+	return &accountHolds{cache: cache, clock: clk, duration: cfg.AccountHoldDuration}
+}
+
+func (h *accountHolds) Place(ctx context.Context, accountID uuid.UUID, reason string, duration time.Duration) (*AccountHold, error) {
+	if duration <= 0 {
+		duration = h.duration
+	}
+	now := h.clock.Now().UTC()
+	hold := &AccountHold{AccountID: accountID, Reason: reason, PlacedAt: now, ExpiresAt: now.Add(duration)}
+	data, err := json.Marshal(hold)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.cache.Put(ctx, accountHoldKey(accountID), data, duration); err != nil {
+		return nil, err
+	}
+	log.Printf("account hold: %s held until %s: %s", accountID, hold.ExpiresAt.Format(time.RFC3339), reason)
+	return hold, nil
+}
+
+func (h *accountHolds) Get(ctx context.Context, accountID uuid.UUID) (*AccountHold, error) {
+	data, found, err := h.cache.Lookup(ctx, accountHoldKey(accountID))
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, nil
+	}
+	var hold AccountHold
+	if err := json.Unmarshal(data, &hold); err != nil {
+		return nil, fmt.Errorf("account hold %s: %w", accountID, err)
+	}
+	// The TTL lifts the hold; this covers clock skew between instances and redis
+	if !h.clock.Now().Before(hold.ExpiresAt) {
+		return nil, nil
+	}
+	return &hold, nil
+}
+
+// Lift removes the account's hold, if any. Delete does not report redis errors, so the key is
+// read first to tell the caller when the hold may still be in place.
+func (h *accountHolds) Lift(ctx context.Context, accountID uuid.UUID) error {
+	if _, _, err := h.cache.Lookup(ctx, accountHoldKey(accountID)); err != nil {
+		return err
+	}
+	return h.cache.Delete(ctx, accountHoldKey(accountID))
+}
+
+// Check returns ErrAccountOnHold when the account is held. When the hold cannot be read the
+// payment or transfer proceeds, as it would with no hold.
+func (h *accountHolds) Check(ctx context.Context, accountID uuid.UUID) error {
+	hold, err := h.Get(ctx, accountID)
+	if err != nil {
+		if err != cache.ErrUnavailable {
+			log.Printf("account hold: cannot read hold of %s, proceeding: %v", accountID, err)
+		}
+		return nil
+	}
+	if hold != nil {
+		return errors.ErrAccountOnHold
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"paytabs/internal/cache"
+	"paytabs/internal/clock"
+	"paytabs/internal/config"
+	"paytabs/internal/errors"
+	"paytabs/internal/model"
+)
+
+func TestAccountHolds_PlaceCheckLift(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	holds := newAccountHolds(cache.New(mr.Addr(), "", 0), clock.NewFixed(now), &config.Config{AccountHoldDuration: 24 * time.Hour})
+	accountID := uuid.New()
+
+	assert.NoError(t, holds.Check(ctx, accountID), "no hold by default")
+
+	hold, err := holds.Place(ctx, accountID, "velocity", 0)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(24*time.Hour), hold.ExpiresAt, "zero duration uses ACCOUNT_HOLD_DURATION")
+	assert.Equal(t, 24*time.Hour, mr.TTL(accountHoldKey(accountID)))
+
+	assert.Equal(t, errors.ErrAccountOnHold, holds.Check(ctx, accountID))
+	assert.NoError(t, holds.Check(ctx, uuid.New()), "holds are per account")
+
+	got, err := holds.Get(ctx, accountID)
+	require.NoError(t, err)
+	assert.Equal(t, "velocity", got.Reason)
+
+	require.NoError(t, holds.Lift(ctx, accountID))
+	assert.NoError(t, holds.Check(ctx, accountID))
+}
+
+func TestAccountHolds_Expiry(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	client := cache.New(mr.Addr(), "", 0)
+	accountID := uuid.New()
+
+	_, err := newAccountHolds(client, clock.NewFixed(now), &config.Config{}).Place(ctx, accountID, "velocity", time.Hour)
+	require.NoError(t, err)
+
+	// The key's TTL lifts the hold
+	mr.FastForward(time.Hour)
+	assert.NoError(t, newAccountHolds(client, clock.NewFixed(now), &config.Config{}).Check(ctx, accountID))
+
+	// So does the recorded expiry, when an instance's clock is ahead of redis
+	_, err = newAccountHolds(client, clock.NewFixed(now), &config.Config{}).Place(ctx, accountID, "velocity", time.Hour)
+	require.NoError(t, err)
+	later := newAccountHolds(client, clock.NewFixed(now.Add(time.Hour)), &config.Config{})
+	assert.NoError(t, later.Check(ctx, accountID))
+}
+
+func TestAccountHolds_RedisDown(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := cache.New(mr.Addr(), "", 0)
+	mr.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	holds := newAccountHolds(client, clock.New(), &config.Config{AccountHoldDuration: time.Hour})
+	assert.NoError(t, holds.Check(ctx, uuid.New()), "payments proceed when holds cannot be read")
+	_, err := holds.Place(ctx, uuid.New(), "velocity", 0)
+	assert.Error(t, err)
+	assert.Error(t, holds.Lift(ctx, uuid.New()))
+}
+
+func TestPaymentService_ProcessCardPayment_AccountOnHold(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := cache.New(mr.Addr(), "", 0)
+	merchant := &model.Account{ID: uuid.New(), Active: true, IsMerchant: true}
+	card := &model.Card{ID: uuid.New(), AccountID: uuid.New(), Balance: decimal.RequireFromString("100.00"), Active: true}
+	_, err := NewAccountHoldService(client, clock.New(), &config.Config{}).Place(context.Background(), card.AccountID, "velocity", time.Hour)
+	require.NoError(t, err)
+
+	d := newPaymentTestDeps(merchant, card)
+	svc := NewPaymentService(d.accountRepo, d.cardRepo, d.paymentRepo, d.logRepo, d.payoutRepo, &MockTxManager{}, nil, nil, client, &config.Config{})
+
+	payment, err := svc.ProcessCardPayment(context.Background(), merchant.ID, card.ID, decimal.RequireFromString("10.00"), "", "")
+
+	assert.Equal(t, errors.ErrAccountOnHold, err)
+	assert.Equal(t, model.PaymentStatusFailed, payment.Status)
+	assert.Equal(t, model.PaymentFailureAccountOnHold, payment.FailureReason)
+	d.cardRepo.AssertNotCalled(t, "UpdateBalanceTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestTransferService_ProcessTransfer_AccountOnHold(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := cache.New(mr.Addr(), "", 0)
+	source := &model.Card{ID: uuid.New(), AccountID: uuid.New(), Balance: decimal.RequireFromString("100.00"), Active: true}
+	dest := &model.Card{ID: uuid.New(), AccountID: uuid.New(), Balance: decimal.Zero, Active: true}
+	_, err := NewAccountHoldService(client, clock.New(), &config.Config{}).Place(context.Background(), source.AccountID, "velocity", time.Hour)
+	require.NoError(t, err)
+
+	cardRepo := new(MockCardRepository)
+	transferRepo := new(MockTransferRepository)
+	cardRepo.On("FindByIDForUpdate", mock.Anything, source.ID).Return(source, nil)
+	cardRepo.On("SumActiveHolds", mock.Anything, source.ID, mock.Anything).Return(decimal.Zero, nil)
+	cardRepo.On("FindByID", mock.Anything, source.ID).Return(source, nil)
+	cardRepo.On("FindByID", mock.Anything, dest.ID).Return(dest, nil)
+	transferRepo.On("Create", mock.Anything, mock.AnythingOfType("*model.Transfer")).Return(nil)
+	svc := NewTransferService(cardRepo, transferRepo, client, &config.Config{})
+
+	transfer, err := svc.ProcessTransfer(context.Background(), source.AccountID, source.ID, dest.ID, decimal.RequireFromString("10.00"))
+
+	assert.Equal(t, errors.ErrAccountOnHold, err)
+	assert.Equal(t, model.TransferStatusFailed, transfer.Status)
+	cardRepo.AssertNotCalled(t, "UpdateBalance", mock.Anything, mock.Anything, mock.Anything)
+
+	check, err := svc.ValidateTransfer(context.Background(), source.AccountID, source.ID, dest.ID, decimal.RequireFromString("10.00"))
+	require.NoError(t, err)
+	assert.Equal(t, []TransferRejection{{Code: errors.CodeAccountOnHold, Message: errors.ErrAccountOnHold.Error()}}, check.Rejections)
+}
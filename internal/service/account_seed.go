@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"paytabs/internal/model"
+	"paytabs/internal/repository"
+)
+
+// seedAccountData is the shape of an account in the upstream seed JSON.
+type seedAccountData struct {
+	ID     string `json:"id"`
+	Active bool   `json:"active"`
+	Name   string `json:"name"`
+}
+
+// FetchSeedAccounts downloads the upstream seed JSON from url and converts it to accounts.
+// Entries with invalid IDs are left out and counted in skipped.
+func FetchSeedAccounts(ctx context.Context, url string) (accounts []model.Account, skipped int, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("build seed request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("fetch seed accounts: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("seed source returned status: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("read seed accounts: %w", err)
+	}
+
+	var data []seedAccountData
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, 0, fmt.Errorf("parse seed accounts: %w", err)
+	}
+
+	accounts = make([]model.Account, 0, len(data))
+	for _, item := range data {
+		accountID, err := uuid.Parse(item.ID)
+		if err != nil {
+			skipped++
+			continue
+		}
+		accounts = append(accounts, model.Account{
+			ID:     accountID,
+			Name:   item.Name,
+			Email:  fmt.Sprintf("account-%s@example.com", accountID.String()),
+			Active: item.Active,
+			// Seeded emails are generated and cannot receive a verification token
+			EmailVerified: true,
+		})
+	}
+	return accounts, skipped, nil
+}
+
+// AutoSeedResult summarizes a startup seed.
+type AutoSeedResult struct {
+	// Ran is false when the accounts table already had rows and the seed was not forced.
+	Ran              bool
+	ExistingAccounts int64
+	Seeded           int
+	Skipped          int // Upstream entries with invalid IDs
+}
+
+// AutoSeedAccounts seeds accounts from url through the same idempotent upsert as the seed
+// endpoint, but only when the accounts table is empty, so a restart never rewrites a
+// populated database. force seeds regardless.
+func AutoSeedAccounts(ctx context.Context, repo repository.AccountRepository, accountService AccountService, url string, force bool) (*AutoSeedResult, error) {
+	existing, err := repo.Count(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("count accounts: %w", err)
+	}
+	result := &AutoSeedResult{ExistingAccounts: existing}
+	if existing > 0 && !force {
+		return result, nil
+	}
+
+	accounts, skipped, err := FetchSeedAccounts(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	seeded, err := accountService.SeedAccounts(ctx, accounts)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Ran = true
+	result.Seeded = seeded
+	result.Skipped = skipped
+	return result, nil
+}
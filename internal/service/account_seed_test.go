@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// newSeedSource serves one valid and one invalid seed entry and counts requests.
+func newSeedSource(t *testing.T, id uuid.UUID, requests *int) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		_, _ = w.Write([]byte(`[{"id":"` + id.String() + `","active":true,"name":"Seeded"},{"id":"not-a-uuid","name":"Bad"}]`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestAutoSeedAccounts(t *testing.T) {
+	tests := []struct {
+		name       string
+		existing   int64
+		force      bool
+		expectSeed bool
+	}{
+		{name: "empty table is seeded", existing: 0, expectSeed: true},
+		{name: "populated table is left alone", existing: 3, expectSeed: false},
+		{name: "force seeds a populated table", existing: 3, force: true, expectSeed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := uuid.New()
+			requests := 0
+			srv := newSeedSource(t, id, &requests)
+
+			accountRepo := new(MockAccountRepository)
+			accountRepo.On("Count", mock.Anything).Return(tt.existing, nil)
+			accountRepo.On("FindByID", mock.Anything, id).Return(nil, gorm.ErrRecordNotFound)
+			accountRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+			result, err := AutoSeedAccounts(context.Background(), accountRepo, NewAccountService(accountRepo, nil, nil), srv.URL, tt.force)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectSeed, result.Ran)
+			assert.Equal(t, tt.existing, result.ExistingAccounts)
+			if !tt.expectSeed {
+				assert.Zero(t, requests, "a populated table is not seeded unless forced")
+				accountRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+				return
+			}
+			assert.Equal(t, 1, result.Seeded)
+			assert.Equal(t, 1, result.Skipped)
+			accountRepo.AssertCalled(t, "Create", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestFetchSeedAccounts_BadStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	_, _, err := FetchSeedAccounts(context.Background(), srv.URL)
+
+	assert.ErrorContains(t, err, "503")
+}
@@ -18,36 +18,50 @@ import (
 
 const accountCacheTTL = 5 * time.Minute
 
+// AccountBalance is one account's entry in a batch balance lookup. Balance is zero when
+// the account was not found.
+type AccountBalance struct {
+	AccountID uuid.UUID
+	Found     bool
+	Balance   decimal.Decimal
+}
+
 // AccountService handles account operations.
 type AccountService interface {
 	GetAccount(ctx context.Context, id uuid.UUID) (*model.Account, error)
 	GetBalance(ctx context.Context, id uuid.UUID) (decimal.Decimal, error)
+	GetBalances(ctx context.Context, ids []uuid.UUID) ([]AccountBalance, error)
+	GetWallet(ctx context.Context, id uuid.UUID) (*Wallet, error)
+	ListAccounts(ctx context.Context, filter repository.AccountFilter, sort repository.AccountSort, limit, offset int) ([]model.Account, int64, error)
 	SeedAccounts(ctx context.Context, accounts []model.Account) (int, error)
 }
 
 type accountService struct {
-	repo     repository.AccountRepository
-	cardRepo repository.CardRepository
-	cache    *cache.Client
+	repo      repository.AccountRepository
+	cardRepo  repository.CardRepository
+	cache     *cache.Client
+	validator *CardValidator
 }
 
 // NewAccountService creates a new account service.
 func NewAccountService(repo repository.AccountRepository, cardRepo repository.CardRepository, cache *cache.Client) AccountService {
 	return &accountService{
-		repo:     repo,
-		cardRepo: cardRepo,
-		cache:    cache,
+		repo:      repo,
+		cardRepo:  cardRepo,
+		cache:     cache,
+		validator: NewCardValidator(),
 	}
 }
 
-func (s *accountService) cacheKey(id uuid.UUID) string {
+// accountCacheKey returns the cache key for an account profile read by GetAccount.
+func accountCacheKey(id uuid.UUID) string {
 	return fmt.Sprintf("account:%s", id.String())
 }
 
 // GetAccount retrieves an account by ID with caching.
 func (s *accountService) GetAccount(ctx context.Context, id uuid.UUID) (*model.Account, error) {
 	// Try cache first
-	if data, _ := s.cache.Get(ctx, s.cacheKey(id)); data != nil {
+	if data, _ := s.cache.Get(ctx, accountCacheKey(id)); data != nil {
 		var cached model.Account
 		if err := json.Unmarshal(data, &cached); err == nil {
 			return &cached, nil
@@ -65,7 +79,7 @@ func (s *accountService) GetAccount(ctx context.Context, id uuid.UUID) (*model.A
 
 	// Cache the result
 	if payload, err := json.Marshal(account); err == nil {
-		_ = s.cache.Set(ctx, s.cacheKey(id), payload, accountCacheTTL)
+		_ = s.cache.Set(ctx, accountCacheKey(id), payload, accountCacheTTL)
 	}
 
 	return account, nil
@@ -95,6 +109,67 @@ func (s *accountService) GetBalance(ctx context.Context, id uuid.UUID) (decimal.
 	return total, nil
 }
 
+// GetBalances returns the balance of each account in ids, in order and without duplicates,
+// computed like GetBalance. Balances of accounts whose wallet is cached come from the cache;
+// the rest are read with one query for the accounts and one for their card totals.
+func (s *accountService) GetBalances(ctx context.Context, ids []uuid.UUID) ([]AccountBalance, error) {
+	results := make([]AccountBalance, 0, len(ids))
+	index := make(map[uuid.UUID]int, len(ids))
+	var cold []uuid.UUID
+	for _, id := range ids {
+		if _, seen := index[id]; seen {
+			continue
+		}
+		index[id] = len(results)
+		result := AccountBalance{AccountID: id}
+		if data, _ := s.cache.Get(ctx, walletCacheKey(id)); data != nil {
+			var cached Wallet
+			if err := json.Unmarshal(data, &cached); err == nil {
+				result.Found = true
+				result.Balance = cached.Balance
+			}
+		}
+		if !result.Found {
+			cold = append(cold, id)
+		}
+		results = append(results, result)
+	}
+	if len(cold) == 0 {
+		return results, nil
+	}
+
+	accounts, err := s.repo.FindByIDs(ctx, cold)
+	if err != nil {
+		return nil, fmt.Errorf("get accounts: %w", err)
+	}
+	found := make([]uuid.UUID, 0, len(accounts))
+	for _, account := range accounts {
+		found = append(found, account.ID)
+	}
+	totals, err := s.cardRepo.SumActiveBalancesByAccount(ctx, found)
+	if err != nil {
+		return nil, fmt.Errorf("sum card balances: %w", err)
+	}
+	for _, id := range found {
+		results[index[id]].Found = true
+		results[index[id]].Balance = totals[id]
+	}
+	return results, nil
+}
+
+// ListAccounts lists accounts across all holders for operators, along with the total number
+// matching filter. Password hashes are never returned.
+func (s *accountService) ListAccounts(ctx context.Context, filter repository.AccountFilter, sort repository.AccountSort, limit, offset int) ([]model.Account, int64, error) {
+	accounts, total, err := s.repo.List(ctx, filter, sort, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list accounts: %w", err)
+	}
+	for i := range accounts {
+		accounts[i].PasswordHash = ""
+	}
+	return accounts, total, nil
+}
+
 // SeedAccounts creates or updates accounts from external data.
 func (s *accountService) SeedAccounts(ctx context.Context, accounts []model.Account) (int, error) {
 	count := 0
@@ -120,7 +195,8 @@ func (s *accountService) SeedAccounts(ctx context.Context, accounts []model.Acco
 		}
 
 		// Invalidate cache
-		_ = s.cache.Delete(ctx, s.cacheKey(account.ID))
+		_ = s.cache.Delete(ctx, accountCacheKey(account.ID))
+		invalidateWallets(ctx, s.cache, account.ID)
 		count++
 	}
 	return count, nil
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"paytabs/internal/cache"
+	"paytabs/internal/errors"
+	"paytabs/internal/model"
+	"paytabs/internal/repository"
+)
+
+func TestAccountService_GetBalance_BalanceReadError(t *testing.T) {
+	account := &model.Account{ID: uuid.New(), Active: true}
+	// What the card repository returns when a stored balance fails to scan
+	scanErr := fmt.Errorf("cards of account %s: %w", account.ID, errors.ErrBalanceRead)
+
+	accountRepo := new(MockAccountRepository)
+	cardRepo := new(MockCardRepository)
+	accountRepo.On("FindByID", mock.Anything, account.ID).Return(account, nil)
+	cardRepo.On("FindByAccountID", mock.Anything, account.ID).Return(nil, scanErr)
+
+	_, err := NewAccountService(accountRepo, cardRepo, nil).GetBalance(context.Background(), account.ID)
+
+	assert.ErrorIs(t, err, errors.ErrBalanceRead)
+	httpErr := errors.MapErrorToHTTP(err)
+	assert.Equal(t, http.StatusInternalServerError, httpErr.StatusCode)
+	assert.Equal(t, errors.CodeBalanceReadError, httpErr.Code)
+	assert.NotContains(t, httpErr.Message, account.ID.String(), "entity ids stay in the logs")
+}
+
+func TestAccountService_GetBalances(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := cache.New(mr.Addr(), "", 0)
+
+	warm := uuid.New()
+	cold := uuid.New()
+	noCards := uuid.New()
+	missing := uuid.New()
+	payload, err := json.Marshal(Wallet{Account: &model.Account{ID: warm}, Balance: decimal.RequireFromString("12.50")})
+	require.NoError(t, err)
+	require.NoError(t, client.Set(ctx, walletCacheKey(warm), payload, time.Minute))
+
+	accountRepo := new(MockAccountRepository)
+	cardRepo := new(MockCardRepository)
+	// One query each for the cold accounts, never one per account
+	accountRepo.On("FindByIDs", mock.Anything, []uuid.UUID{cold, noCards, missing}).
+		Return([]model.Account{{ID: noCards}, {ID: cold}}, nil).Once()
+	cardRepo.On("SumActiveBalancesByAccount", mock.Anything, []uuid.UUID{noCards, cold}).
+		Return(map[uuid.UUID]decimal.Decimal{cold: decimal.RequireFromString("40")}, nil).Once()
+
+	results, err := NewAccountService(accountRepo, cardRepo, client).GetBalances(ctx, []uuid.UUID{cold, warm, noCards, cold, missing})
+
+	require.NoError(t, err)
+	require.Len(t, results, 4, "duplicates are reported once")
+	assert.Equal(t, cold, results[0].AccountID)
+	assert.True(t, results[0].Found)
+	assert.True(t, results[0].Balance.Equal(decimal.RequireFromString("40")))
+	assert.Equal(t, warm, results[1].AccountID)
+	assert.True(t, results[1].Found)
+	assert.True(t, results[1].Balance.Equal(decimal.RequireFromString("12.50")), "warm wallets come from the cache")
+	assert.True(t, results[2].Found)
+	assert.True(t, results[2].Balance.IsZero())
+	assert.Equal(t, AccountBalance{AccountID: missing}, results[3])
+	accountRepo.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+}
+
+func TestAccountService_ListAccounts(t *testing.T) {
+	merchant := true
+	filter := repository.AccountFilter{IsMerchant: &merchant, Query: "shop"}
+	sort := repository.AccountSort{Field: repository.AccountSortBalance}
+
+	accountRepo := new(MockAccountRepository)
+	accountRepo.On("List", mock.Anything, filter, sort, 20, 40).
+		Return([]model.Account{{ID: uuid.New(), Name: "Shop", PasswordHash: "$2a$10$hash"}}, int64(41), nil)
+
+	accounts, total, err := NewAccountService(accountRepo, nil, nil).ListAccounts(context.Background(), filter, sort, 20, 40)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(41), total)
+	require.Len(t, accounts, 1)
+	assert.Equal(t, "Shop", accounts[0].Name)
+	assert.Empty(t, accounts[0].PasswordHash)
+}
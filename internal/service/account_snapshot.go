@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"paytabs/internal/clock"
+	"paytabs/internal/errors"
+	"paytabs/internal/model"
+	"paytabs/internal/repository"
+)
+
+// AccountSnapshot is an account's balances, cards, payments and transfers read in one
+// database snapshot, so the activity reconciles exactly against the balances. AsOf is when
+// the snapshot was taken. CardBalance sums the active cards, like GetBalance; Cards lists
+// every card, inactive ones included, with masked numbers.
+type AccountSnapshot struct {
+	AccountID      uuid.UUID
+	AsOf           time.Time
+	AccountBalance decimal.Decimal
+	CardBalance    decimal.Decimal
+	Cards          []model.Card
+	Payments       []model.Payment
+	Transfers      []repository.AccountTransfer
+}
+
+// AccountSnapshotService exports consistent views of an account's activity, e.g. for disputes.
+type AccountSnapshotService interface {
+	Snapshot(ctx context.Context, accountID uuid.UUID) (*AccountSnapshot, error)
+}
+
+type accountSnapshotService struct {
+	accountRepo  repository.AccountRepository
+	cardRepo     repository.CardRepository
+	paymentRepo  repository.PaymentRepository
+	transferRepo repository.TransferRepository
+	txManager    repository.TxManager
+	clock        clock.Clock
+	validator    *CardValidator
+}
+
+// NewAccountSnapshotService creates a new account snapshot service.
+func NewAccountSnapshotService(accountRepo repository.AccountRepository, cardRepo repository.CardRepository, paymentRepo repository.PaymentRepository, transferRepo repository.TransferRepository, txManager repository.TxManager, clk clock.Clock) AccountSnapshotService {
+	return &accountSnapshotService{
+		accountRepo:  accountRepo,
+		cardRepo:     cardRepo,
+		paymentRepo:  paymentRepo,
+		transferRepo: transferRepo,
+		txManager:    txManager,
+		clock:        clk,
+		validator:    NewCardValidator(),
+	}
+}
+
+// Snapshot reads the account and all of its cards, payments (archived ones included) and
+// transfers in one read-only REPEATABLE READ transaction. The account row is read first,
+// which fixes the snapshot, and AsOf is taken right after it: nothing committed later is seen.
+func (s *accountSnapshotService) Snapshot(ctx context.Context, accountID uuid.UUID) (*AccountSnapshot, error) {
+	var snapshot *AccountSnapshot
+	err := s.txManager.WithSnapshot(ctx, func(ctx context.Context, tx interface{}) error {
+		account, err := s.accountRepo.FindByIDTx(ctx, tx, accountID)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return errors.ErrAccountNotFound
+			}
+			return fmt.Errorf("get account: %w", err)
+		}
+		asOf := s.clock.Now().UTC()
+
+		cards, err := s.cardRepo.FindByAccountIDTx(ctx, tx, accountID)
+		if err != nil {
+			return fmt.Errorf("get cards: %w", err)
+		}
+		payments, err := s.paymentRepo.ListAllByAccountTx(ctx, tx, accountID)
+		if err != nil {
+			return fmt.Errorf("list payments: %w", err)
+		}
+		transfers, err := s.transferRepo.ListAllByAccountTx(ctx, tx, accountID)
+		if err != nil {
+			return fmt.Errorf("list transfers: %w", err)
+		}
+
+		snapshot = &AccountSnapshot{
+			AccountID:      accountID,
+			AsOf:           asOf,
+			AccountBalance: account.Balance,
+			CardBalance:    decimal.Zero,
+			Cards:          cards,
+			Payments:       payments,
+			Transfers:      transfers,
+		}
+		for i := range snapshot.Cards {
+			card := &snapshot.Cards[i]
+			if card.Active {
+				snapshot.CardBalance = snapshot.CardBalance.Add(card.Balance)
+			}
+			card.CardNumber = s.validator.MaskCardNumber(card.CardNumber)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"paytabs/internal/clock"
+	"paytabs/internal/errors"
+	"paytabs/internal/model"
+	"paytabs/internal/repository"
+)
+
+func TestAccountSnapshotService_Snapshot(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	account := &model.Account{ID: uuid.New(), Balance: decimal.NewFromInt(100)}
+	cards := []model.Card{
+		{ID: uuid.New(), AccountID: account.ID, CardNumber: "4111111111111111", Balance: decimal.NewFromInt(30), Active: true},
+		{ID: uuid.New(), AccountID: account.ID, CardNumber: "5500000000000004", Balance: decimal.NewFromInt(20), Active: false},
+	}
+	payments := []model.Payment{{ID: uuid.New(), Amount: decimal.NewFromInt(10)}}
+	transfers := []repository.AccountTransfer{{Transfer: model.Transfer{ID: uuid.New()}, Direction: model.TransferDirectionOutbound}}
+
+	accountRepo := new(MockAccountRepository)
+	cardRepo := new(MockCardRepository)
+	paymentRepo := new(MockPaymentRepository)
+	transferRepo := new(MockTransferRepository)
+	accountRepo.On("FindByIDTx", mock.Anything, mock.Anything, account.ID).Return(account, nil)
+	cardRepo.On("FindByAccountIDTx", mock.Anything, mock.Anything, account.ID).Return(cards, nil)
+	paymentRepo.On("ListAllByAccountTx", mock.Anything, mock.Anything, account.ID).Return(payments, nil)
+	transferRepo.On("ListAllByAccountTx", mock.Anything, mock.Anything, account.ID).Return(transfers, nil)
+
+	svc := NewAccountSnapshotService(accountRepo, cardRepo, paymentRepo, transferRepo, &MockTxManager{}, clock.NewFixed(now))
+	snapshot, err := svc.Snapshot(context.Background(), account.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, account.ID, snapshot.AccountID)
+	assert.True(t, now.Equal(snapshot.AsOf))
+	assert.True(t, decimal.NewFromInt(100).Equal(snapshot.AccountBalance))
+	// Like GetBalance, only active cards count towards the card balance
+	assert.True(t, decimal.NewFromInt(30).Equal(snapshot.CardBalance), snapshot.CardBalance.String())
+	require.Len(t, snapshot.Cards, 2)
+	assert.Equal(t, "****1111", snapshot.Cards[0].CardNumber)
+	assert.Equal(t, "****0004", snapshot.Cards[1].CardNumber)
+	assert.Equal(t, payments, snapshot.Payments)
+	assert.Equal(t, transfers, snapshot.Transfers)
+}
+
+func TestAccountSnapshotService_Snapshot_AccountNotFound(t *testing.T) {
+	accountID := uuid.New()
+	accountRepo := new(MockAccountRepository)
+	cardRepo := new(MockCardRepository)
+	accountRepo.On("FindByIDTx", mock.Anything, mock.Anything, accountID).Return(nil, gorm.ErrRecordNotFound)
+
+	svc := NewAccountSnapshotService(accountRepo, cardRepo, new(MockPaymentRepository), new(MockTransferRepository), &MockTxManager{}, clock.New())
+	_, err := svc.Snapshot(context.Background(), accountID)
+
+	assert.Equal(t, errors.ErrAccountNotFound, err)
+	cardRepo.AssertNotCalled(t, "FindByAccountIDTx", mock.Anything, mock.Anything, mock.Anything)
+}
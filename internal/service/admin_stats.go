@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"paytabs/internal/clock"
+	"paytabs/internal/repository"
+)
+
+// QueueGauge reads the depth of one in-memory work queue. Dropped, if set, counts the items
+// the queue discarded because it was full.
+type QueueGauge struct {
+	Name    string
+	Depth   func() (length, capacity int)
+	Dropped func() int64
+}
+
+// QueueDepth is a queue's depth when stats were read.
+type QueueDepth struct {
+	Name     string
+	Length   int
+	Capacity int
+	Dropped  int64 // Since the instance started
+}
+
+// PlatformStats is a single-glance operational view of the platform. Daily counts start at
+// Since, midnight UTC of the current day.
+type PlatformStats struct {
+	repository.PlatformTotals
+	TotalBalance decimal.Decimal // Card balances plus account balances
+	Since        time.Time
+	Queues       []QueueDepth  // Queues of this instance only
+	WorkerPanics []WorkerPanic // Workers of this instance only
+}
+
+// FloatBalance is the money the platform holds in one currency.
+type FloatBalance struct {
+	repository.CurrencyFloat
+	Net decimal.Decimal // Card and account balances, less the pending payouts about to leave
+}
+
+// PlatformFloat is the money held across all cards and accounts, per currency, for
+// reconciling against the bank.
+type PlatformFloat struct {
+	AsOf       time.Time
+	Currencies []FloatBalance // Sorted by currency
+}
+
+// AdminStatsService reports operational figures to operators.
+type AdminStatsService interface {
+	Stats(ctx context.Context) (*PlatformStats, error)
+	Float(ctx context.Context) (*PlatformFloat, error)
+}
+
+type adminStatsService struct {
+	repo            repository.StatsRepository
+	clock           clock.Clock
+	defaultCurrency string
+	queues          []QueueGauge
+}
+
+// NewAdminStatsService creates a stats service that aggregates through repo and reports the
+// depth of each queue. Money stored without a currency is reported in defaultCurrency.
+func NewAdminStatsService(repo repository.StatsRepository, clk clock.Clock, defaultCurrency string, queues ...QueueGauge) AdminStatsService {
+	return &adminStatsService{repo: repo, clock: clk, defaultCurrency: defaultCurrency, queues: queues}
+}
+
+// Stats aggregates platform totals from the database and reads queue depths and worker
+// panics from memory.
+func (s *adminStatsService) Stats(ctx context.Context) (*PlatformStats, error) {
+	now := s.clock.Now().UTC()
+	since := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	totals, err := s.repo.PlatformTotals(ctx, since)
+	if err != nil {
+		return nil, fmt.Errorf("platform totals: %w", err)
+	}
+
+	stats := &PlatformStats{
+		PlatformTotals: *totals,
+		TotalBalance:   totals.CardBalance.Add(totals.AccountBalance),
+		Since:          since,
+		Queues:         make([]QueueDepth, 0, len(s.queues)),
+		WorkerPanics:   WorkerPanics(),
+	}
+	for _, q := range s.queues {
+		length, capacity := q.Depth()
+		depth := QueueDepth{Name: q.Name, Length: length, Capacity: capacity}
+		if q.Dropped != nil {
+			depth.Dropped = q.Dropped()
+		}
+		stats.Queues = append(stats.Queues, depth)
+	}
+	return stats, nil
+}
+
+// Float sums the money held per currency. Cards and accounts stored without a currency hold
+// the default currency and are counted under it.
+func (s *adminStatsService) Float(ctx context.Context) (*PlatformFloat, error) {
+	asOf := s.clock.Now().UTC()
+	stored, err := s.repo.Float(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("platform float: %w", err)
+	}
+
+	byCurrency := make(map[string]*FloatBalance, len(stored))
+	for _, f := range stored {
+		code := f.Currency
+		if code == "" {
+			code = s.defaultCurrency
+		}
+		balance, ok := byCurrency[code]
+		if !ok {
+			balance = &FloatBalance{CurrencyFloat: repository.CurrencyFloat{Currency: code}}
+			byCurrency[code] = balance
+		}
+		balance.CardBalance = balance.CardBalance.Add(f.CardBalance)
+		balance.AccountBalance = balance.AccountBalance.Add(f.AccountBalance)
+		balance.PendingPayouts = balance.PendingPayouts.Add(f.PendingPayouts)
+	}
+
+	float := &PlatformFloat{AsOf: asOf, Currencies: make([]FloatBalance, 0, len(byCurrency))}
+	for _, balance := range byCurrency {
+		balance.Net = balance.CardBalance.Add(balance.AccountBalance).Sub(balance.PendingPayouts)
+		float.Currencies = append(float.Currencies, *balance)
+	}
+	sort.Slice(float.Currencies, func(i, j int) bool {
+		return float.Currencies[i].Currency < float.Currencies[j].Currency
+	})
+	return float, nil
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"paytabs/internal/clock"
+	"paytabs/internal/repository"
+)
+
+func TestAdminStatsService_Stats(t *testing.T) {
+	now := time.Date(2024, 3, 15, 14, 30, 0, 0, time.UTC)
+	midnight := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+
+	repo := new(MockStatsRepository)
+	repo.On("PlatformTotals", mock.Anything, midnight).Return(&repository.PlatformTotals{
+		Accounts:        12,
+		ActiveMerchants: 3,
+		Cards:           20,
+		CardBalance:     decimal.RequireFromString("1500.25"),
+		AccountBalance:  decimal.RequireFromString("499.75"),
+		PaymentsSince:   7,
+		TransfersSince:  4,
+	}, nil)
+
+	logs := make(chan struct{}, 100)
+	logs <- struct{}{}
+	svc := NewAdminStatsService(repo, clock.NewFixed(now), "USD",
+		QueueGauge{Name: "payment_logs", Depth: func() (int, int) { return len(logs), cap(logs) }, Dropped: func() int64 { return 3 }},
+		QueueGauge{Name: "payment_notifications", Depth: (*PaymentNotifier)(nil).QueueDepth},
+	)
+
+	stats, err := svc.Stats(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, midnight, stats.Since)
+	assert.Equal(t, int64(12), stats.Accounts)
+	assert.Equal(t, int64(7), stats.PaymentsSince)
+	assert.Equal(t, "2000.00", stats.TotalBalance.StringFixed(2))
+	assert.Equal(t, []QueueDepth{
+		{Name: "payment_logs", Length: 1, Capacity: 100, Dropped: 3},
+		{Name: "payment_notifications", Length: 0, Capacity: 0},
+	}, stats.Queues)
+}
+
+func TestAdminStatsService_Float(t *testing.T) {
+	now := time.Date(2024, 3, 15, 23, 59, 0, 0, time.UTC)
+	d := decimal.RequireFromString
+
+	repo := new(MockStatsRepository)
+	repo.On("Float", mock.Anything).Return([]repository.CurrencyFloat{
+		// Cards and accounts from before currencies were stored hold the default currency
+		{Currency: "", CardBalance: d("10.00"), AccountBalance: d("5.00")},
+		{Currency: "EUR", CardBalance: d("200.00"), PendingPayouts: d("20.00")},
+		{Currency: "USD", CardBalance: d("1000.50"), AccountBalance: d("300.25"), PendingPayouts: d("100.75")},
+	}, nil)
+
+	float, err := NewAdminStatsService(repo, clock.NewFixed(now), "USD").Float(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, now, float.AsOf)
+	require.Len(t, float.Currencies, 2)
+
+	eur := float.Currencies[0]
+	assert.Equal(t, "EUR", eur.Currency)
+	assert.Equal(t, "0.00", eur.AccountBalance.StringFixed(2))
+	assert.Equal(t, "180.00", eur.Net.StringFixed(2), "pending payouts are about to leave")
+
+	usd := float.Currencies[1]
+	assert.Equal(t, "USD", usd.Currency)
+	assert.Equal(t, "1010.50", usd.CardBalance.StringFixed(2))
+	assert.Equal(t, "305.25", usd.AccountBalance.StringFixed(2))
+	assert.Equal(t, "100.75", usd.PendingPayouts.StringFixed(2))
+	assert.Equal(t, "1215.00", usd.Net.StringFixed(2))
+}
@@ -4,11 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 
 	"paytabs/internal/auth"
+	apperrors "paytabs/internal/errors"
 	"paytabs/internal/model"
 	"paytabs/internal/repository"
 	"github.com/google/uuid"
@@ -23,33 +25,67 @@ var (
 	ErrUserAlreadyExists = errors.New("user already exists")
 	// ErrInvalidRefreshToken is returned when refresh token is invalid or expired.
 	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	// ErrTooManyAttempts is returned when logins are throttled for the email or client IP.
+	ErrTooManyAttempts = errors.New("too many failed login attempts, try again later")
+	// ErrAccountNotVerified is returned when logging in to an account whose email is not verified yet.
+	ErrAccountNotVerified = errors.New("email address not verified")
+	// ErrInvalidVerificationToken is returned when an email verification token is unknown,
+	// expired, or already used.
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+	// ErrInvalidResetToken is returned when a password reset token is unknown, expired, or already used.
+	ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+	// ErrPasswordResetDisabled is returned when no email delivery is configured to send reset tokens.
+	ErrPasswordResetDisabled = errors.New("password reset is not configured")
+	// ErrEmailVerificationDisabled is returned when a verification email is requested but no email
+	// delivery is configured, so new accounts are verified at once.
+	ErrEmailVerificationDisabled = errors.New("email verification is not configured")
 )
 
 // AuthService handles authentication operations.
 type AuthService interface {
 	Register(ctx context.Context, email, password, name string, isMerchant bool) (*model.Account, error)
-	Login(ctx context.Context, email, password string) (accessToken, refreshToken string, account *model.Account, err error)
-	RefreshToken(ctx context.Context, refreshToken string) (accessToken string, err error)
-	Logout(ctx context.Context, refreshToken string) error
+	Login(ctx context.Context, email, password, clientIP string) (accessToken, refreshToken string, account *model.Account, err error)
+	RefreshToken(ctx context.Context, refreshToken string) (accessToken, newRefreshToken string, err error)
+	Logout(ctx context.Context, refreshToken, accessToken string) error
+	LogoutAll(ctx context.Context, accountID uuid.UUID, accessToken string) error
+	IsEmailAvailable(ctx context.Context, email string) (bool, error)
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerification(ctx context.Context, email string) error
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
+	ChangePassword(ctx context.Context, accountID uuid.UUID, currentPassword, newPassword string) error
 }
 
 type authService struct {
 	accountRepo repository.AccountRepository
 	jwtService  *auth.JWTService
 	tokenStore   auth.TokenStoreInterface
+	loginLimiter *auth.LoginLimiter
+	verifier     *auth.EmailVerifier
+	resetter     *auth.PasswordResetter
 }
 
-// NewAuthService creates a new authentication service.
-func NewAuthService(accountRepo repository.AccountRepository, jwtService *auth.JWTService, tokenStore auth.TokenStoreInterface) AuthService {
+// NewAuthService creates a new authentication service. loginLimiter may be nil to disable
+// login throttling, verifier may be nil to let new accounts sign in without verifying their
+// email, and resetter may be nil to disable password resets.
+func NewAuthService(accountRepo repository.AccountRepository, jwtService *auth.JWTService, tokenStore auth.TokenStoreInterface, loginLimiter *auth.LoginLimiter, verifier *auth.EmailVerifier, resetter *auth.PasswordResetter) AuthService {
 	return &authService{
-		accountRepo: accountRepo,
-		jwtService:  jwtService,
-		tokenStore:  tokenStore,
+		accountRepo:  accountRepo,
+		jwtService:   jwtService,
+		tokenStore:   tokenStore,
+		loginLimiter: loginLimiter,
+		verifier:     verifier,
+		resetter:     resetter,
 	}
 }
 
-// Register creates a new account with hashed password.
+// Register creates a new account with hashed password. When email verification is enabled
+// the account is created with EmailVerified false and a verification token is mailed to it;
+// VerifyEmail sets the flag. The token is issued inside the account's transaction, so when
+// it cannot be stored or sent no account is left behind and the email can register again.
 func (s *authService) Register(ctx context.Context, email, password, name string, isMerchant bool) (*model.Account, error) {
+	email = model.NormalizeEmail(email)
+
 	// Check if account already exists
 	existing, err := s.accountRepo.FindByEmail(ctx, email)
 	if err == nil && existing != nil {
@@ -74,91 +110,305 @@ func (s *authService) Register(ctx context.Context, email, password, name string
 		Name:         name,
 		IsMerchant:   isMerchant,
 		Active:       true,
+		// Without a verifier no token can be delivered, so the email counts as verified
+		EmailVerified: s.verifier == nil,
 	}
 
-	if err := s.accountRepo.Create(ctx, account); err != nil {
-		return nil, fmt.Errorf("create account: %w", err)
+	err = s.accountRepo.WithTransaction(ctx, func(ctx context.Context, txRepo repository.AccountRepository) error {
+		if err := txRepo.Create(ctx, account); err != nil {
+			return fmt.Errorf("create account: %w", err)
+		}
+		if s.verifier == nil {
+			return nil
+		}
+		if err := s.verifier.Issue(ctx, account.ID, account.Email); err != nil {
+			return fmt.Errorf("send verification email: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-
 	return account, nil
 }
 
-// Login authenticates an account and returns access and refresh tokens.
-func (s *authService) Login(ctx context.Context, email, password string) (accessToken, refreshToken string, account *model.Account, err error) {
+// ResendVerification mails a new verification token to the account registered with email,
+// if its email is not verified yet. Earlier tokens keep working until they expire. Unknown
+// and already verified emails are not reported, so this cannot be used to find registered
+// addresses.
+func (s *authService) ResendVerification(ctx context.Context, email string) error {
+	if s.verifier == nil {
+		return ErrEmailVerificationDisabled
+	}
+
+	account, err := s.accountRepo.FindByEmail(ctx, model.NormalizeEmail(email))
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("find account: %w", err)
+	}
+	if account.EmailVerified {
+		return nil
+	}
+
+	if err := s.verifier.Issue(ctx, account.ID, account.Email); err != nil {
+		log.Printf("auth: verification email for account %s not sent: %v", account.ID, err)
+	}
+	return nil
+}
+
+// VerifyEmail redeems a token mailed by Register and marks the email of the account it was
+// issued for verified. Each token works once.
+func (s *authService) VerifyEmail(ctx context.Context, token string) error {
+	if s.verifier == nil {
+		return ErrInvalidVerificationToken
+	}
+	accountID, ok := s.verifier.Consume(ctx, token)
+	if !ok {
+		return ErrInvalidVerificationToken
+	}
+	if err := s.accountRepo.UpdateColumns(ctx, accountID, map[string]interface{}{"email_verified": true}); err != nil {
+		return fmt.Errorf("mark email verified: %w", err)
+	}
+	return nil
+}
+
+// Login authenticates an account and returns access and refresh tokens. Failed attempts
+// are throttled per email and per clientIP.
+func (s *authService) Login(ctx context.Context, email, password, clientIP string) (accessToken, refreshToken string, account *model.Account, err error) {
+	email = model.NormalizeEmail(email)
+
+	if s.loginLimiter.Blocked(ctx, email, clientIP) {
+		return "", "", nil, ErrTooManyAttempts
+	}
+
 	// Find account by email
 	account, err = s.accountRepo.FindByEmail(ctx, email)
 	if err != nil {
+		// Unknown emails count too, so throttling does not reveal which accounts exist
+		s.loginLimiter.RecordFailure(ctx, email, clientIP)
 		return "", "", nil, ErrInvalidCredentials
 	}
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(account.PasswordHash), []byte(password)); err != nil {
+		s.loginLimiter.RecordFailure(ctx, email, clientIP)
 		return "", "", nil, ErrInvalidCredentials
 	}
+	s.loginLimiter.Reset(ctx, email)
 
-	// Generate access token (using account ID as uint)
-	accountIDUint := uint(account.ID[0]) + uint(account.ID[1])<<8 + uint(account.ID[2])<<16 + uint(account.ID[3])<<24
-	accessToken, err = s.jwtService.GenerateAccessToken(accountIDUint, account.Email)
-	if err != nil {
-		return "", "", nil, fmt.Errorf("generate access token: %w", err)
+	// With verification on, accounts cannot log in until their email is confirmed
+	if s.verifier != nil && !account.EmailVerified {
+		return "", "", nil, ErrAccountNotVerified
 	}
 
-	// Generate refresh token
-	tokenID, refreshToken, err := s.jwtService.GenerateRefreshToken(accountIDUint, account.Email)
+	// Tokens carry the account UUID; the uint derived from its first bytes can collide and is
+	// only kept for older clients
+	accountIDUint := uint(account.ID[0]) + uint(account.ID[1])<<8 + uint(account.ID[2])<<16 + uint(account.ID[3])<<24
+	accessToken, refreshToken, err = s.issueTokens(ctx, accountIDUint, account.ID.String(), account.Email)
 	if err != nil {
-		return "", "", nil, fmt.Errorf("generate refresh token: %w", err)
-	}
-
-	// Store refresh token in Redis
-	if err := s.tokenStore.StoreRefreshToken(ctx, tokenID, accountIDUint, account.Email, auth.RefreshTokenExpiry); err != nil {
-		return "", "", nil, fmt.Errorf("store refresh token: %w", err)
+		return "", "", nil, err
 	}
 
 	return accessToken, refreshToken, account, nil
 }
 
-// RefreshToken validates a refresh token and returns a new access token.
-func (s *authService) RefreshToken(ctx context.Context, refreshToken string) (accessToken string, err error) {
-	// Validate refresh token
-	claims, err := s.jwtService.ValidateToken(refreshToken)
+// RefreshToken exchanges a refresh token for a new access token and a new refresh token.
+// The presented token is consumed, so it cannot be used again: a token that was already
+// rotated away, even by a concurrent request, or revoked by logout or a password change
+// no longer has its Redis entry and is rejected with ErrInvalidRefreshToken.
+func (s *authService) RefreshToken(ctx context.Context, refreshToken string) (accessToken, newRefreshToken string, err error) {
+	// Validate refresh token; access tokens are refused
+	claims, err := s.jwtService.ValidateRefreshToken(refreshToken)
 	if err != nil {
-		return "", ErrInvalidRefreshToken
+		return "", "", ErrInvalidRefreshToken
 	}
 
 	// Extract token ID
 	tokenID, err := s.jwtService.ExtractTokenID(refreshToken)
 	if err != nil {
-		return "", ErrInvalidRefreshToken
+		return "", "", ErrInvalidRefreshToken
 	}
 
-	// Verify token exists in Redis
-	storedUserID, storedEmail, err := s.tokenStore.GetRefreshToken(ctx, tokenID)
+	// Take the token out of Redis; only one caller can
+	storedUserID, storedEmail, err := s.tokenStore.ConsumeRefreshToken(ctx, tokenID)
 	if err != nil {
-		return "", ErrInvalidRefreshToken
+		return "", "", ErrInvalidRefreshToken
 	}
 
 	// Verify token matches stored data
 	if storedUserID != claims.UserID || storedEmail != claims.Email {
-		return "", ErrInvalidRefreshToken
+		return "", "", ErrInvalidRefreshToken
 	}
 
-	// Generate new access token
-	accessToken, err = s.jwtService.GenerateAccessToken(claims.UserID, claims.Email)
+	return s.issueTokens(ctx, claims.UserID, claims.AccountID, claims.Email)
+}
+
+// issueTokens generates an access token and a refresh token, storing the refresh token
+// in Redis as a new session.
+func (s *authService) issueTokens(ctx context.Context, userID uint, accountID, email string) (accessToken, refreshToken string, err error) {
+	accessToken, err = s.jwtService.GenerateAccessToken(userID, accountID, email)
 	if err != nil {
-		return "", fmt.Errorf("generate access token: %w", err)
+		return "", "", fmt.Errorf("generate access token: %w", err)
+	}
+
+	tokenID, refreshToken, err := s.jwtService.GenerateRefreshToken(userID, accountID, email)
+	if err != nil {
+		return "", "", fmt.Errorf("generate refresh token: %w", err)
+	}
+
+	if err := s.tokenStore.StoreRefreshToken(ctx, tokenID, userID, accountID, email, s.jwtService.RefreshTokenTTL()); err != nil {
+		return "", "", fmt.Errorf("store refresh token: %w", err)
 	}
 
-	return accessToken, nil
+	return accessToken, refreshToken, nil
 }
 
-// Logout invalidates a refresh token.
-func (s *authService) Logout(ctx context.Context, refreshToken string) error {
+// Logout invalidates a refresh token. A non-empty accessToken is blacklisted by its token
+// ID until it would have expired, so it stops working immediately; one that is already
+// invalid or expired, or was issued without a token ID, is ignored.
+func (s *authService) Logout(ctx context.Context, refreshToken, accessToken string) error {
 	// Extract token ID
 	tokenID, err := s.jwtService.ExtractTokenID(refreshToken)
 	if err != nil {
 		return ErrInvalidRefreshToken
 	}
 
+	if err := s.revokeAccessToken(ctx, accessToken); err != nil {
+		return err
+	}
+
 	// Delete refresh token from Redis
 	return s.tokenStore.DeleteRefreshToken(ctx, tokenID)
 }
+
+// LogoutAll ends every session of the account by deleting all of its refresh tokens, and
+// revokes accessToken like Logout. Access tokens issued to the other sessions keep working
+// until they expire.
+func (s *authService) LogoutAll(ctx context.Context, accountID uuid.UUID, accessToken string) error {
+	account, err := s.accountRepo.FindByID(ctx, accountID)
+	if err == gorm.ErrRecordNotFound {
+		return apperrors.ErrAccountNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("find account: %w", err)
+	}
+
+	if err := s.revokeAccessToken(ctx, accessToken); err != nil {
+		return err
+	}
+	if err := s.tokenStore.RevokeAllForUser(ctx, account.ID.String()); err != nil {
+		return fmt.Errorf("revoke sessions: %w", err)
+	}
+	return nil
+}
+
+// revokeAccessToken blacklists accessToken as described for Logout.
+func (s *authService) revokeAccessToken(ctx context.Context, accessToken string) error {
+	if accessToken == "" {
+		return nil
+	}
+	claims, err := s.jwtService.ValidateAccessToken(accessToken)
+	if err != nil || claims.ID == "" {
+		return nil
+	}
+	if ttl := s.jwtService.RemainingLifetime(claims); ttl > 0 {
+		if err := s.tokenStore.BlacklistAccessToken(ctx, claims.ID, ttl); err != nil {
+			return fmt.Errorf("blacklist access token: %w", err)
+		}
+	}
+	return nil
+}
+
+// IsEmailAvailable reports whether no account is registered with the given email.
+func (s *authService) IsEmailAvailable(ctx context.Context, email string) (bool, error) {
+	_, err := s.accountRepo.FindByEmail(ctx, model.NormalizeEmail(email))
+	if err == gorm.ErrRecordNotFound {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("check account existence: %w", err)
+	}
+	return false, nil
+}
+
+// ForgotPassword mails a password reset token to the account registered with email. It
+// succeeds whether or not such an account exists, and a failed delivery is only logged, so
+// callers cannot use it to learn which emails are registered.
+func (s *authService) ForgotPassword(ctx context.Context, email string) error {
+	if s.resetter == nil {
+		return ErrPasswordResetDisabled
+	}
+
+	account, err := s.accountRepo.FindByEmail(ctx, model.NormalizeEmail(email))
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("find account: %w", err)
+	}
+
+	if err := s.resetter.Issue(ctx, account.ID, account.Email); err != nil {
+		log.Printf("auth: password reset for account %s not sent: %v", account.ID, err)
+	}
+	return nil
+}
+
+// ResetPassword redeems a token mailed by ForgotPassword, sets the account's password to
+// newPassword, and ends all of the account's sessions like ChangePassword. Each token works once.
+func (s *authService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	if s.resetter == nil {
+		return ErrInvalidResetToken
+	}
+	accountID, ok := s.resetter.Consume(ctx, token)
+	if !ok {
+		return ErrInvalidResetToken
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcryptCost)
+	if err != nil {
+		return fmt.Errorf("hash password: %w", err)
+	}
+	if err := s.accountRepo.UpdatePassword(ctx, accountID, string(hashedPassword)); err != nil {
+		return fmt.Errorf("update password: %w", err)
+	}
+
+	// Whoever held the old password may still hold a session opened with it
+	if err := s.tokenStore.RevokeAllForUser(ctx, accountID.String()); err != nil {
+		return fmt.Errorf("revoke sessions: %w", err)
+	}
+	return nil
+}
+
+// ChangePassword sets the account's password to newPassword once currentPassword is
+// confirmed, then ends all of the account's sessions by deleting its refresh tokens.
+// A wrong currentPassword returns ErrInvalidCredentials.
+func (s *authService) ChangePassword(ctx context.Context, accountID uuid.UUID, currentPassword, newPassword string) error {
+	account, err := s.accountRepo.FindByID(ctx, accountID)
+	if err == gorm.ErrRecordNotFound {
+		return apperrors.ErrAccountNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("find account: %w", err)
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(account.PasswordHash), []byte(currentPassword)); err != nil {
+		return ErrInvalidCredentials
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcryptCost)
+	if err != nil {
+		return fmt.Errorf("hash password: %w", err)
+	}
+	if err := s.accountRepo.UpdatePassword(ctx, accountID, string(hashedPassword)); err != nil {
+		return fmt.Errorf("update password: %w", err)
+	}
+
+	// Sessions opened with the old password end; access tokens already issued run out on
+	// their own within the access token lifetime.
+	if err := s.tokenStore.RevokeAllForUser(ctx, account.ID.String()); err != nil {
+		return fmt.Errorf("revoke sessions: %w", err)
+	}
+	return nil
+}
@@ -2,16 +2,21 @@ package service
 
 import (
 	"context"
+	"regexp"
 	"testing"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 
 	"paytabs/internal/auth"
+	"paytabs/internal/cache"
 	"paytabs/internal/model"
 	"paytabs/internal/repository"
 )
@@ -26,6 +31,11 @@ func (m *MockAccountRepository) Create(ctx context.Context, account *model.Accou
 	return args.Error(0)
 }
 
+func (m *MockAccountRepository) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	args := m.Called(ctx, id, passwordHash)
+	return args.Error(0)
+}
+
 func (m *MockAccountRepository) Update(ctx context.Context, account *model.Account) error {
 	args := m.Called(ctx, account)
 	return args.Error(0)
@@ -39,6 +49,14 @@ func (m *MockAccountRepository) FindByID(ctx context.Context, id uuid.UUID) (*mo
 	return args.Get(0).(*model.Account), args.Error(1)
 }
 
+func (m *MockAccountRepository) FindByIDs(ctx context.Context, ids []uuid.UUID) ([]model.Account, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Account), args.Error(1)
+}
+
 func (m *MockAccountRepository) FindByIDForUpdate(ctx context.Context, id uuid.UUID) (*model.Account, error) {
 	args := m.Called(ctx, id)
 	if args.Get(0) == nil {
@@ -55,6 +73,11 @@ func (m *MockAccountRepository) FindByEmail(ctx context.Context, email string) (
 	return args.Get(0).(*model.Account), args.Error(1)
 }
 
+func (m *MockAccountRepository) Count(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 func (m *MockAccountRepository) ListActive(ctx context.Context) ([]model.Account, error) {
 	args := m.Called(ctx)
 	if args.Get(0) == nil {
@@ -63,6 +86,14 @@ func (m *MockAccountRepository) ListActive(ctx context.Context) ([]model.Account
 	return args.Get(0).([]model.Account), args.Error(1)
 }
 
+func (m *MockAccountRepository) List(ctx context.Context, filter repository.AccountFilter, sort repository.AccountSort, limit, offset int) ([]model.Account, int64, error) {
+	args := m.Called(ctx, filter, sort, limit, offset)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]model.Account), args.Get(1).(int64), args.Error(2)
+}
+
 func (m *MockAccountRepository) FindByIDOrCreate(ctx context.Context, account *model.Account) (*model.Account, error) {
 	args := m.Called(ctx, account)
 	if args.Get(0) == nil {
@@ -71,11 +102,29 @@ func (m *MockAccountRepository) FindByIDOrCreate(ctx context.Context, account *m
 	return args.Get(0).(*model.Account), args.Error(1)
 }
 
+// WithTransaction runs fn against the mock itself so expectations set on it apply inside the transaction.
 func (m *MockAccountRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context, repo repository.AccountRepository) error) error {
-	args := m.Called(ctx, fn)
+	return fn(ctx, m)
+}
+
+func (m *MockAccountRepository) CreditBalanceTx(ctx context.Context, tx interface{}, id uuid.UUID, amount decimal.Decimal) error {
+	args := m.Called(ctx, tx, id, amount)
 	return args.Error(0)
 }
 
+func (m *MockAccountRepository) UpdateColumns(ctx context.Context, id uuid.UUID, columns map[string]interface{}) error {
+	args := m.Called(ctx, id, columns)
+	return args.Error(0)
+}
+
+func (m *MockAccountRepository) FindByIDTx(ctx context.Context, tx interface{}, id uuid.UUID) (*model.Account, error) {
+	args := m.Called(ctx, tx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Account), args.Error(1)
+}
+
 func (m *MockAccountRepository) FindByIDForUpdateTx(ctx context.Context, tx interface{}, id uuid.UUID) (*model.Account, error) {
 	args := m.Called(ctx, tx, id)
 	if args.Get(0) == nil {
@@ -89,8 +138,8 @@ type MockTokenStore struct {
 	mock.Mock
 }
 
-func (m *MockTokenStore) StoreRefreshToken(ctx context.Context, tokenID string, userID uint, email string, ttl time.Duration) error {
-	args := m.Called(ctx, tokenID, userID, email, ttl)
+func (m *MockTokenStore) StoreRefreshToken(ctx context.Context, tokenID string, userID uint, accountID, email string, ttl time.Duration) error {
+	args := m.Called(ctx, tokenID, userID, accountID, email, ttl)
 	return args.Error(0)
 }
 
@@ -99,11 +148,21 @@ func (m *MockTokenStore) GetRefreshToken(ctx context.Context, tokenID string) (u
 	return args.Get(0).(uint), args.String(1), args.Error(2)
 }
 
+func (m *MockTokenStore) ConsumeRefreshToken(ctx context.Context, tokenID string) (uint, string, error) {
+	args := m.Called(ctx, tokenID)
+	return args.Get(0).(uint), args.String(1), args.Error(2)
+}
+
 func (m *MockTokenStore) DeleteRefreshToken(ctx context.Context, tokenID string) error {
 	args := m.Called(ctx, tokenID)
 	return args.Error(0)
 }
 
+func (m *MockTokenStore) RevokeAllForUser(ctx context.Context, accountID string) error {
+	args := m.Called(ctx, accountID)
+	return args.Error(0)
+}
+
 func (m *MockTokenStore) BlacklistAccessToken(ctx context.Context, tokenID string, ttl time.Duration) error {
 	args := m.Called(ctx, tokenID, ttl)
 	return args.Error(0)
@@ -147,6 +206,17 @@ func TestAuthService_Register(t *testing.T) {
 			},
 			expectedError: ErrUserAlreadyExists,
 		},
+		{
+			name:       "account already exists with different case and whitespace",
+			email:      "  Existing@Example.COM ",
+			password:   "password123",
+			nameField:  "Existing User",
+			isMerchant: false,
+			setupMock: func(m *MockAccountRepository) {
+				m.On("FindByEmail", mock.Anything, "existing@example.com").Return(&model.Account{Email: "existing@example.com"}, nil)
+			},
+			expectedError: ErrUserAlreadyExists,
+		},
 	}
 
 	for _, tt := range tests {
@@ -154,10 +224,10 @@ func TestAuthService_Register(t *testing.T) {
 			mockRepo := new(MockAccountRepository)
 			tt.setupMock(mockRepo)
 
-			jwtService := auth.NewJWTService("test-secret")
+			jwtService := auth.NewJWTService("test-secret", auth.AccessTokenExpiry, auth.RefreshTokenExpiry)
 			mockTokenStore := new(MockTokenStore)
 
-			service := NewAuthService(mockRepo, jwtService, mockTokenStore)
+			service := NewAuthService(mockRepo, jwtService, mockTokenStore, nil, nil, nil)
 			account, err := service.Register(context.Background(), tt.email, tt.password, tt.nameField, tt.isMerchant)
 
 			if tt.expectedError != nil {
@@ -200,7 +270,7 @@ func TestAuthService_Login(t *testing.T) {
 				}, nil)
 				// Convert UUID to uint for token store (using first 4 bytes)
 				accountIDUint := uint(accountID[0]) + uint(accountID[1])<<8 + uint(accountID[2])<<16 + uint(accountID[3])<<24
-				mToken.On("StoreRefreshToken", mock.Anything, mock.Anything, accountIDUint, "test@example.com", mock.Anything).Return(nil)
+				mToken.On("StoreRefreshToken", mock.Anything, mock.Anything, accountIDUint, accountID.String(), "test@example.com", mock.Anything).Return(nil)
 			},
 			expectedError: nil,
 		},
@@ -221,10 +291,10 @@ func TestAuthService_Login(t *testing.T) {
 			mockTokenStore := new(MockTokenStore)
 			tt.setupMock(mockRepo, mockTokenStore)
 
-			jwtService := auth.NewJWTService("test-secret")
-			service := NewAuthService(mockRepo, jwtService, mockTokenStore)
+			jwtService := auth.NewJWTService("test-secret", auth.AccessTokenExpiry, auth.RefreshTokenExpiry)
+			service := NewAuthService(mockRepo, jwtService, mockTokenStore, nil, nil, nil)
 
-			accessToken, refreshToken, account, err := service.Login(context.Background(), tt.email, tt.password)
+			accessToken, refreshToken, account, err := service.Login(context.Background(), tt.email, tt.password, "")
 
 			if tt.expectedError != nil {
 				assert.Error(t, err)
@@ -245,3 +315,554 @@ func TestAuthService_Login(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthService_Login_NormalizesEmail(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), 10)
+	accountID := uuid.New()
+
+	mockRepo := new(MockAccountRepository)
+	mockRepo.On("FindByEmail", mock.Anything, "test@example.com").Return(&model.Account{
+		ID:           accountID,
+		Email:        "test@example.com",
+		PasswordHash: string(hashedPassword),
+	}, nil)
+	mockTokenStore := new(MockTokenStore)
+	mockTokenStore.On("StoreRefreshToken", mock.Anything, mock.Anything, mock.Anything, accountID.String(), "test@example.com", mock.Anything).Return(nil)
+
+	service := NewAuthService(mockRepo, auth.NewJWTService("test-secret", auth.AccessTokenExpiry, auth.RefreshTokenExpiry), mockTokenStore, nil, nil, nil)
+	accessToken, _, account, err := service.Login(context.Background(), " Test@Example.com ", "password123", "")
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, accessToken)
+	assert.Equal(t, accountID, account.ID)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAuthService_Register_StoresNormalizedEmail(t *testing.T) {
+	mockRepo := new(MockAccountRepository)
+	mockRepo.On("FindByEmail", mock.Anything, "new@example.com").Return(nil, gorm.ErrRecordNotFound)
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*model.Account")).Return(nil)
+
+	service := NewAuthService(mockRepo, auth.NewJWTService("test-secret", auth.AccessTokenExpiry, auth.RefreshTokenExpiry), new(MockTokenStore), nil, nil, nil)
+	account, err := service.Register(context.Background(), "New@Example.com ", "password123", "New User", false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "new@example.com", account.Email)
+	assert.True(t, account.EmailVerified, "without a verifier new emails count as verified")
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAuthService_Login_ThrottlesFailures(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), 10)
+	mockRepo := new(MockAccountRepository)
+	mockRepo.On("FindByEmail", mock.Anything, "test@example.com").Return(&model.Account{
+		ID:           uuid.New(),
+		Email:        "test@example.com",
+		PasswordHash: string(hashedPassword),
+	}, nil)
+	mockTokenStore := new(MockTokenStore)
+	mockTokenStore.On("StoreRefreshToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	mr := miniredis.RunT(t)
+	limiter := auth.NewLoginLimiter(cache.New(mr.Addr(), "", 0), 2, 10, time.Minute)
+	service := NewAuthService(mockRepo, auth.NewJWTService("test-secret", auth.AccessTokenExpiry, auth.RefreshTokenExpiry), mockTokenStore, limiter, nil, nil)
+	ctx := context.Background()
+
+	// A success resets the email's failure count
+	_, _, _, err := service.Login(ctx, "test@example.com", "wrong", "10.0.0.1")
+	assert.Equal(t, ErrInvalidCredentials, err)
+	_, _, _, err = service.Login(ctx, "test@example.com", "password123", "10.0.0.1")
+	assert.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, _, _, err = service.Login(ctx, "test@example.com", "wrong", "10.0.0.2")
+		assert.Equal(t, ErrInvalidCredentials, err)
+	}
+
+	// Even the right password is refused while locked, from any IP
+	_, _, _, err = service.Login(ctx, "test@example.com", "password123", "10.0.0.3")
+	assert.Equal(t, ErrTooManyAttempts, err)
+
+	mr.FastForward(2 * time.Minute)
+	_, _, _, err = service.Login(ctx, "test@example.com", "password123", "10.0.0.3")
+	assert.NoError(t, err)
+}
+
+func TestAuthService_Login_EvictsOldestSession(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), 10)
+	mockRepo := new(MockAccountRepository)
+	mockRepo.On("FindByEmail", mock.Anything, "test@example.com").Return(&model.Account{
+		ID:           uuid.New(),
+		Email:        "test@example.com",
+		PasswordHash: string(hashedPassword),
+	}, nil)
+
+	mr := miniredis.RunT(t)
+	tokenStore := auth.NewTokenStore(cache.New(mr.Addr(), "", 0), 2)
+	service := NewAuthService(mockRepo, auth.NewJWTService("test-secret", auth.AccessTokenExpiry, auth.RefreshTokenExpiry), tokenStore, nil, nil, nil)
+	ctx := context.Background()
+
+	var refreshTokens []string
+	for i := 0; i < 3; i++ {
+		_, refreshToken, _, err := service.Login(ctx, "test@example.com", "password123", "10.0.0.1")
+		require.NoError(t, err)
+		refreshTokens = append(refreshTokens, refreshToken)
+	}
+
+	_, _, err := service.RefreshToken(ctx, refreshTokens[0])
+	assert.Equal(t, ErrInvalidRefreshToken, err, "the third login evicts the first session")
+	for _, refreshToken := range refreshTokens[1:] {
+		_, _, err := service.RefreshToken(ctx, refreshToken)
+		assert.NoError(t, err)
+	}
+}
+
+func TestAuthService_Logout_BlacklistsAccessToken(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), 10)
+	mockRepo := new(MockAccountRepository)
+	mockRepo.On("FindByEmail", mock.Anything, "test@example.com").Return(&model.Account{
+		ID:           uuid.New(),
+		Email:        "test@example.com",
+		PasswordHash: string(hashedPassword),
+	}, nil)
+
+	mr := miniredis.RunT(t)
+	jwtService := auth.NewJWTService("test-secret", auth.AccessTokenExpiry, auth.RefreshTokenExpiry)
+	tokenStore := auth.NewTokenStore(cache.New(mr.Addr(), "", 0), 0)
+	service := NewAuthService(mockRepo, jwtService, tokenStore, nil, nil, nil)
+	ctx := context.Background()
+
+	accessToken, refreshToken, _, err := service.Login(ctx, "test@example.com", "password123", "10.0.0.1")
+	require.NoError(t, err)
+	claims, err := jwtService.ValidateToken(accessToken)
+	require.NoError(t, err)
+
+	require.NoError(t, service.Logout(ctx, refreshToken, accessToken))
+
+	revoked, err := tokenStore.IsAccessTokenBlacklisted(ctx, claims.ID)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	// The blacklist entry lives only as long as the token would have
+	ttl := mr.TTL("blacklist:access_token:" + claims.ID)
+	assert.True(t, ttl > 0 && ttl <= auth.AccessTokenExpiry, "ttl %s", ttl)
+
+	_, _, err = service.RefreshToken(ctx, refreshToken)
+	assert.Equal(t, ErrInvalidRefreshToken, err)
+}
+
+func TestAuthService_LogoutAll(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), 10)
+	account := &model.Account{ID: uuid.New(), Email: "test@example.com", PasswordHash: string(hashedPassword)}
+	other := &model.Account{ID: uuid.New(), Email: "other@example.com", PasswordHash: string(hashedPassword)}
+	mockRepo := new(MockAccountRepository)
+	mockRepo.On("FindByEmail", mock.Anything, "test@example.com").Return(account, nil)
+	mockRepo.On("FindByEmail", mock.Anything, "other@example.com").Return(other, nil)
+	mockRepo.On("FindByID", mock.Anything, account.ID).Return(account, nil)
+
+	mr := miniredis.RunT(t)
+	jwtService := auth.NewJWTService("test-secret", auth.AccessTokenExpiry, auth.RefreshTokenExpiry)
+	tokenStore := auth.NewTokenStore(cache.New(mr.Addr(), "", 0), 0)
+	service := NewAuthService(mockRepo, jwtService, tokenStore, nil, nil, nil)
+	ctx := context.Background()
+
+	var accessToken string
+	var refreshTokens []string
+	for i := 0; i < 3; i++ {
+		access, refresh, _, err := service.Login(ctx, "test@example.com", "password123", "10.0.0.1")
+		require.NoError(t, err)
+		accessToken = access
+		refreshTokens = append(refreshTokens, refresh)
+	}
+	_, otherRefresh, _, err := service.Login(ctx, "other@example.com", "password123", "10.0.0.1")
+	require.NoError(t, err)
+
+	require.NoError(t, service.LogoutAll(ctx, account.ID, accessToken))
+
+	for i, refreshToken := range refreshTokens {
+		_, _, err := service.RefreshToken(ctx, refreshToken)
+		assert.Equal(t, ErrInvalidRefreshToken, err, "session %d", i)
+	}
+	claims, err := jwtService.ValidateToken(accessToken)
+	require.NoError(t, err)
+	revoked, err := tokenStore.IsAccessTokenBlacklisted(ctx, claims.ID)
+	require.NoError(t, err)
+	assert.True(t, revoked, "the access token of the request is revoked too")
+
+	_, _, err = service.RefreshToken(ctx, otherRefresh)
+	assert.NoError(t, err, "other accounts keep their sessions")
+}
+
+func TestAuthService_Login_StoresRefreshTokenWithConfiguredTTL(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), 10)
+	mockRepo := new(MockAccountRepository)
+	mockRepo.On("FindByEmail", mock.Anything, "test@example.com").Return(&model.Account{
+		ID:           uuid.New(),
+		Email:        "test@example.com",
+		PasswordHash: string(hashedPassword),
+	}, nil)
+	mockTokenStore := new(MockTokenStore)
+	mockTokenStore.On("StoreRefreshToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything, "test@example.com", 2*time.Hour).Return(nil).Once()
+	service := NewAuthService(mockRepo, auth.NewJWTService("test-secret", time.Minute, 2*time.Hour), mockTokenStore, nil, nil, nil)
+
+	_, _, _, err := service.Login(context.Background(), "test@example.com", "password123", "10.0.0.1")
+
+	require.NoError(t, err)
+	mockTokenStore.AssertExpectations(t)
+}
+
+func TestAuthService_Logout_IgnoresInvalidAccessToken(t *testing.T) {
+	jwtService := auth.NewJWTService("test-secret", auth.AccessTokenExpiry, auth.RefreshTokenExpiry)
+	tokenID, refreshToken, err := jwtService.GenerateRefreshToken(42, uuid.NewString(), "test@example.com")
+	require.NoError(t, err)
+
+	mockStore := new(MockTokenStore)
+	mockStore.On("DeleteRefreshToken", mock.Anything, tokenID).Return(nil)
+	service := NewAuthService(new(MockAccountRepository), jwtService, mockStore, nil, nil, nil)
+
+	// Logging out without an access token, or with one that does not validate, still revokes the session
+	require.NoError(t, service.Logout(context.Background(), refreshToken, ""))
+	require.NoError(t, service.Logout(context.Background(), refreshToken, "not-a-jwt"))
+	mockStore.AssertNotCalled(t, "BlacklistAccessToken", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthService_Login_AttemptsWhileLockedDoNotExtendLock(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), 10)
+	mockRepo := new(MockAccountRepository)
+	mockRepo.On("FindByEmail", mock.Anything, "test@example.com").Return(&model.Account{
+		ID:           uuid.New(),
+		Email:        "test@example.com",
+		PasswordHash: string(hashedPassword),
+	}, nil)
+	mockTokenStore := new(MockTokenStore)
+	mockTokenStore.On("StoreRefreshToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	mr := miniredis.RunT(t)
+	limiter := auth.NewLoginLimiter(cache.New(mr.Addr(), "", 0), 3, 0, 10*time.Minute)
+	service := NewAuthService(mockRepo, auth.NewJWTService("test-secret", auth.AccessTokenExpiry, auth.RefreshTokenExpiry), mockTokenStore, limiter, nil, nil)
+	ctx := context.Background()
+
+	// Consecutive failures cross the threshold
+	for i := 0; i < 3; i++ {
+		_, _, _, err := service.Login(ctx, "test@example.com", "wrong", "10.0.0.1")
+		assert.Equal(t, ErrInvalidCredentials, err)
+	}
+
+	// Guessing on while locked is refused without being counted
+	mr.FastForward(6 * time.Minute)
+	for i := 0; i < 5; i++ {
+		_, _, _, err := service.Login(ctx, "test@example.com", "wrong", "10.0.0.1")
+		assert.Equal(t, ErrTooManyAttempts, err)
+	}
+	count, err := mr.Get("login_fail:email:test@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "3", count)
+
+	// So the lock still lifts one window after the first failure
+	mr.FastForward(5 * time.Minute)
+	_, _, _, err = service.Login(ctx, "test@example.com", "password123", "10.0.0.1")
+	assert.NoError(t, err)
+}
+
+func TestAuthService_RefreshToken_Rotates(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), 10)
+	mockRepo := new(MockAccountRepository)
+	mockRepo.On("FindByEmail", mock.Anything, "test@example.com").Return(&model.Account{
+		ID:           uuid.New(),
+		Email:        "test@example.com",
+		PasswordHash: string(hashedPassword),
+	}, nil)
+
+	mr := miniredis.RunT(t)
+	jwtService := auth.NewJWTService("test-secret", auth.AccessTokenExpiry, auth.RefreshTokenExpiry)
+	service := NewAuthService(mockRepo, jwtService, auth.NewTokenStore(cache.New(mr.Addr(), "", 0), 2), nil, nil, nil)
+	ctx := context.Background()
+
+	_, oldRefreshToken, account, err := service.Login(ctx, "test@example.com", "password123", "10.0.0.1")
+	require.NoError(t, err)
+
+	accessToken, newRefreshToken, err := service.RefreshToken(ctx, oldRefreshToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, oldRefreshToken, newRefreshToken)
+	claims, err := jwtService.ValidateToken(accessToken)
+	require.NoError(t, err)
+	assert.Equal(t, account.ID.String(), claims.AccountID)
+
+	// An access token cannot stand in for a refresh token
+	_, _, err = service.RefreshToken(ctx, accessToken)
+	assert.Equal(t, ErrInvalidRefreshToken, err)
+
+	// The old token stops working once rotated away
+	_, _, err = service.RefreshToken(ctx, oldRefreshToken)
+	assert.Equal(t, ErrInvalidRefreshToken, err)
+
+	// The new one works, once
+	_, newerRefreshToken, err := service.RefreshToken(ctx, newRefreshToken)
+	require.NoError(t, err)
+	_, _, err = service.RefreshToken(ctx, newRefreshToken)
+	assert.Equal(t, ErrInvalidRefreshToken, err)
+
+	// Rotation replaces the session instead of adding one
+	members, err := mr.ZMembers("user_sessions:" + account.ID.String())
+	require.NoError(t, err)
+	assert.Len(t, members, 1)
+	require.NoError(t, service.Logout(ctx, newerRefreshToken, ""))
+}
+
+func TestAuthService_RegisterVerifyLogin(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	email := newFakeEmail(0)
+	verifier := auth.NewEmailVerifier(cache.New(mr.Addr(), "", 0), email, time.Hour)
+
+	var stored *model.Account
+	mockRepo := new(MockAccountRepository)
+	mockRepo.On("FindByEmail", mock.Anything, "new@example.com").Return(nil, gorm.ErrRecordNotFound).Once()
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*model.Account")).
+		Run(func(args mock.Arguments) { stored = args.Get(1).(*model.Account) }).Return(nil).Once()
+	service := NewAuthService(mockRepo, auth.NewJWTService("test-secret", auth.AccessTokenExpiry, auth.RefreshTokenExpiry), auth.NewTokenStore(cache.New(mr.Addr(), "", 0), 0), nil, verifier, nil)
+
+	account, err := service.Register(ctx, "new@example.com", "password123", "New User", false)
+	require.NoError(t, err)
+	assert.False(t, account.EmailVerified)
+	assert.True(t, account.Active, "verification does not touch Active")
+	require.Len(t, email.sent, 1)
+	assert.Equal(t, "new@example.com", email.sent[0].to)
+	token := regexp.MustCompile(`[0-9a-f]{64}`).FindString(email.sent[0].body)
+	require.NotEmpty(t, token)
+
+	// Until the email is verified the right password is not enough
+	mockRepo.On("FindByEmail", mock.Anything, "new@example.com").Return(stored, nil)
+	_, _, _, err = service.Login(ctx, "new@example.com", "password123", "10.0.0.1")
+	assert.Equal(t, ErrAccountNotVerified, err)
+
+	mockRepo.On("UpdateColumns", mock.Anything, account.ID, map[string]interface{}{"email_verified": true}).
+		Run(func(mock.Arguments) { stored.EmailVerified = true }).Return(nil).Once()
+	require.NoError(t, service.VerifyEmail(ctx, token))
+	assert.Equal(t, ErrInvalidVerificationToken, service.VerifyEmail(ctx, token), "tokens are single use")
+
+	accessToken, refreshToken, _, err := service.Login(ctx, "new@example.com", "password123", "10.0.0.1")
+	require.NoError(t, err)
+	assert.NotEmpty(t, accessToken)
+	assert.NotEmpty(t, refreshToken)
+	mockRepo.AssertExpectations(t)
+}
+
+// txRecordingAccountRepo records the outcome of each transaction it runs, since the mock
+// cannot roll anything back itself.
+type txRecordingAccountRepo struct {
+	*MockAccountRepository
+	results []error
+}
+
+func (r *txRecordingAccountRepo) WithTransaction(ctx context.Context, fn func(ctx context.Context, repo repository.AccountRepository) error) error {
+	err := fn(ctx, r.MockAccountRepository)
+	r.results = append(r.results, err)
+	return err
+}
+
+func TestAuthService_Register_VerificationEmailFails(t *testing.T) {
+	mr := miniredis.RunT(t)
+	email := newFakeEmail(1)
+	verifier := auth.NewEmailVerifier(cache.New(mr.Addr(), "", 0), email, time.Hour)
+
+	mockRepo := new(MockAccountRepository)
+	mockRepo.On("FindByEmail", mock.Anything, "new@example.com").Return(nil, gorm.ErrRecordNotFound)
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*model.Account")).Return(nil).Once()
+	repo := &txRecordingAccountRepo{MockAccountRepository: mockRepo}
+	service := NewAuthService(repo, auth.NewJWTService("test-secret", auth.AccessTokenExpiry, auth.RefreshTokenExpiry), new(MockTokenStore), nil, verifier, nil)
+
+	account, err := service.Register(context.Background(), "new@example.com", "password123", "New User", false)
+
+	assert.Error(t, err)
+	assert.Nil(t, account)
+	// The account was created in the transaction the failed email rolled back
+	mockRepo.AssertExpectations(t)
+	require.Len(t, repo.results, 1)
+	assert.Error(t, repo.results[0])
+	assert.Empty(t, email.sent)
+}
+
+func TestAuthService_ResendVerification(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	email := newFakeEmail(0)
+	verifier := auth.NewEmailVerifier(cache.New(mr.Addr(), "", 0), email, time.Hour)
+	unverified := &model.Account{ID: uuid.New(), Email: "new@example.com"}
+	verified := &model.Account{ID: uuid.New(), Email: "old@example.com", EmailVerified: true}
+
+	mockRepo := new(MockAccountRepository)
+	mockRepo.On("FindByEmail", mock.Anything, "new@example.com").Return(unverified, nil)
+	mockRepo.On("FindByEmail", mock.Anything, "old@example.com").Return(verified, nil)
+	mockRepo.On("FindByEmail", mock.Anything, "nobody@example.com").Return(nil, gorm.ErrRecordNotFound)
+	jwtService := auth.NewJWTService("test-secret", auth.AccessTokenExpiry, auth.RefreshTokenExpiry)
+	service := NewAuthService(mockRepo, jwtService, new(MockTokenStore), nil, verifier, nil)
+
+	require.NoError(t, service.ResendVerification(ctx, " New@Example.com"))
+	require.Len(t, email.sent, 1)
+	assert.Equal(t, "new@example.com", email.sent[0].to)
+	token := regexp.MustCompile(`[0-9a-f]{64}`).FindString(email.sent[0].body)
+	mockRepo.On("UpdateColumns", mock.Anything, unverified.ID, map[string]interface{}{"email_verified": true}).Return(nil).Once()
+	require.NoError(t, service.VerifyEmail(ctx, token))
+
+	// Verified and unknown emails answer alike and get nothing
+	require.NoError(t, service.ResendVerification(ctx, "old@example.com"))
+	require.NoError(t, service.ResendVerification(ctx, "nobody@example.com"))
+	assert.Len(t, email.sent, 1)
+
+	disabled := NewAuthService(mockRepo, jwtService, new(MockTokenStore), nil, nil, nil)
+	assert.Equal(t, ErrEmailVerificationDisabled, disabled.ResendVerification(ctx, "new@example.com"))
+}
+
+func TestAuthService_LoginBeforeVerification(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), 10)
+	mockRepo := new(MockAccountRepository)
+	mockRepo.On("FindByEmail", mock.Anything, "new@example.com").Return(&model.Account{
+		ID:           uuid.New(),
+		Email:        "new@example.com",
+		PasswordHash: string(hashedPassword),
+		Active:       true,
+	}, nil)
+	mockTokenStore := new(MockTokenStore)
+	verifier := auth.NewEmailVerifier(nil, newFakeEmail(0), time.Hour)
+	service := NewAuthService(mockRepo, auth.NewJWTService("test-secret", auth.AccessTokenExpiry, auth.RefreshTokenExpiry), mockTokenStore, nil, verifier, nil)
+
+	accessToken, refreshToken, account, err := service.Login(context.Background(), "new@example.com", "password123", "10.0.0.1")
+
+	assert.Equal(t, ErrAccountNotVerified, err)
+	assert.Empty(t, accessToken)
+	assert.Empty(t, refreshToken)
+	assert.Nil(t, account)
+	mockTokenStore.AssertNotCalled(t, "StoreRefreshToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthService_VerifyEmail_InvalidToken(t *testing.T) {
+	mr := miniredis.RunT(t)
+	mockRepo := new(MockAccountRepository)
+	jwtService := auth.NewJWTService("test-secret", auth.AccessTokenExpiry, auth.RefreshTokenExpiry)
+	enabled := NewAuthService(mockRepo, jwtService, new(MockTokenStore), nil, auth.NewEmailVerifier(cache.New(mr.Addr(), "", 0), newFakeEmail(0), time.Hour), nil)
+	disabled := NewAuthService(mockRepo, jwtService, new(MockTokenStore), nil, nil, nil)
+
+	assert.Equal(t, ErrInvalidVerificationToken, enabled.VerifyEmail(context.Background(), "unknown"))
+	assert.Equal(t, ErrInvalidVerificationToken, disabled.VerifyEmail(context.Background(), "unknown"))
+	mockRepo.AssertNotCalled(t, "UpdateColumns", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthService_ResetPassword(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	email := newFakeEmail(0)
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("old-password"), 10)
+	account := &model.Account{ID: uuid.New(), Email: "alice@example.com", PasswordHash: string(hashedPassword), Active: true}
+
+	mockRepo := new(MockAccountRepository)
+	mockRepo.On("FindByEmail", mock.Anything, "alice@example.com").Return(account, nil)
+	mockRepo.On("UpdatePassword", mock.Anything, account.ID, mock.AnythingOfType("string")).
+		Run(func(args mock.Arguments) { account.PasswordHash = args.String(2) }).Return(nil).Once()
+	cacheClient := cache.New(mr.Addr(), "", 0)
+	jwtService := auth.NewJWTService("test-secret", auth.AccessTokenExpiry, auth.RefreshTokenExpiry)
+	resetter := auth.NewPasswordResetter(cacheClient, email, 15*time.Minute)
+	service := NewAuthService(mockRepo, jwtService, auth.NewTokenStore(cacheClient, 0), nil, nil, resetter)
+
+	// A session opened with the old password, e.g. by whoever learned it
+	_, oldRefreshToken, _, err := service.Login(ctx, "alice@example.com", "old-password", "10.0.0.1")
+	require.NoError(t, err)
+
+	require.NoError(t, service.ForgotPassword(ctx, " Alice@Example.com"))
+	require.Len(t, email.sent, 1)
+	assert.Equal(t, "alice@example.com", email.sent[0].to)
+	token := regexp.MustCompile(`[0-9a-f]{64}`).FindString(email.sent[0].body)
+	require.NotEmpty(t, token)
+
+	require.NoError(t, service.ResetPassword(ctx, token, "new-password"))
+
+	// The old session's refresh token can neither be refreshed nor stand in as a bearer token
+	_, _, err = service.RefreshToken(ctx, oldRefreshToken)
+	assert.Equal(t, ErrInvalidRefreshToken, err)
+	_, err = jwtService.ValidateAccessToken(oldRefreshToken)
+	assert.Error(t, err)
+
+	_, _, _, err = service.Login(ctx, "alice@example.com", "old-password", "10.0.0.1")
+	assert.Equal(t, ErrInvalidCredentials, err)
+	_, _, _, err = service.Login(ctx, "alice@example.com", "new-password", "10.0.0.1")
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAuthService_ResetPassword_ReusedToken(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	email := newFakeEmail(0)
+	account := &model.Account{ID: uuid.New(), Email: "alice@example.com"}
+
+	mockRepo := new(MockAccountRepository)
+	mockRepo.On("FindByEmail", mock.Anything, "alice@example.com").Return(account, nil)
+	mockRepo.On("UpdatePassword", mock.Anything, account.ID, mock.AnythingOfType("string")).Return(nil).Once()
+	mockTokenStore := new(MockTokenStore)
+	mockTokenStore.On("RevokeAllForUser", mock.Anything, account.ID.String()).Return(nil).Once()
+	resetter := auth.NewPasswordResetter(cache.New(mr.Addr(), "", 0), email, 15*time.Minute)
+	service := NewAuthService(mockRepo, auth.NewJWTService("test-secret", auth.AccessTokenExpiry, auth.RefreshTokenExpiry), mockTokenStore, nil, nil, resetter)
+
+	require.NoError(t, service.ForgotPassword(ctx, "alice@example.com"))
+	token := regexp.MustCompile(`[0-9a-f]{64}`).FindString(email.sent[0].body)
+	require.NoError(t, service.ResetPassword(ctx, token, "new-password"))
+
+	assert.Equal(t, ErrInvalidResetToken, service.ResetPassword(ctx, token, "attacker-password"))
+	assert.Equal(t, ErrInvalidResetToken, service.ResetPassword(ctx, "unknown", "attacker-password"))
+	mockRepo.AssertNumberOfCalls(t, "UpdatePassword", 1)
+}
+
+func TestAuthService_ForgotPassword_UnknownEmail(t *testing.T) {
+	mr := miniredis.RunT(t)
+	email := newFakeEmail(0)
+	mockRepo := new(MockAccountRepository)
+	mockRepo.On("FindByEmail", mock.Anything, "nobody@example.com").Return(nil, gorm.ErrRecordNotFound)
+	resetter := auth.NewPasswordResetter(cache.New(mr.Addr(), "", 0), email, 15*time.Minute)
+	jwtService := auth.NewJWTService("test-secret", auth.AccessTokenExpiry, auth.RefreshTokenExpiry)
+
+	// Unknown emails succeed like known ones, so the answer does not reveal which are registered
+	err := NewAuthService(mockRepo, jwtService, new(MockTokenStore), nil, nil, resetter).ForgotPassword(context.Background(), "nobody@example.com")
+	assert.NoError(t, err)
+	assert.Empty(t, email.sent)
+
+	err = NewAuthService(mockRepo, jwtService, new(MockTokenStore), nil, nil, nil).ForgotPassword(context.Background(), "nobody@example.com")
+	assert.Equal(t, ErrPasswordResetDisabled, err)
+}
+
+func TestAuthService_ChangePassword(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("old-password"), 10)
+
+	tests := []struct {
+		name            string
+		currentPassword string
+		wantErr         error
+	}{
+		{name: "changed", currentPassword: "old-password"},
+		{name: "wrong current password", currentPassword: "guess", wantErr: ErrInvalidCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account := &model.Account{ID: uuid.New(), Email: "alice@example.com", PasswordHash: string(hashedPassword), Active: true}
+			mockRepo := new(MockAccountRepository)
+			mockRepo.On("FindByID", mock.Anything, account.ID).Return(account, nil)
+			var newHash string
+			mockRepo.On("UpdatePassword", mock.Anything, account.ID, mock.AnythingOfType("string")).
+				Run(func(args mock.Arguments) { newHash = args.String(2) }).Return(nil).Maybe()
+			mockTokenStore := new(MockTokenStore)
+			mockTokenStore.On("RevokeAllForUser", mock.Anything, account.ID.String()).Return(nil).Maybe()
+			service := NewAuthService(mockRepo, auth.NewJWTService("test-secret", auth.AccessTokenExpiry, auth.RefreshTokenExpiry), mockTokenStore, nil, nil, nil)
+
+			err := service.ChangePassword(context.Background(), account.ID, tt.currentPassword, "new-password")
+
+			if tt.wantErr != nil {
+				assert.Equal(t, tt.wantErr, err)
+				mockRepo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+				mockTokenStore.AssertNotCalled(t, "RevokeAllForUser", mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(newHash), []byte("new-password")))
+			mockTokenStore.AssertCalled(t, "RevokeAllForUser", mock.Anything, account.ID.String())
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"paytabs/internal/cache"
+	"paytabs/internal/model"
+	"paytabs/internal/notify"
+)
+
+const (
+	balanceAlertQueueSize = 100
+	balanceAlertKeyPrefix = "balance_alert:"
+
+	// DefaultBalanceAlertDebounce is how long an alert of one kind silences the next when
+	// none is configured.
+	DefaultBalanceAlertDebounce = time.Hour
+)
+
+// BalanceAlertKind is the webhook event type of a balance alert.
+type BalanceAlertKind string
+
+const (
+	// BalanceAlertLow fires when the balance drops below the merchant's LowBalanceThreshold.
+	BalanceAlertLow BalanceAlertKind = "balance.below_threshold"
+	// BalanceAlertHigh fires when the balance reaches the merchant's HighBalanceThreshold.
+	BalanceAlertHigh BalanceAlertKind = "balance.above_threshold"
+)
+
+// BalanceCrossing reports which threshold, if any, a balance change from before to after
+// crossed. Falling from at or above low to below it crosses low; rising from below high to at
+// or above it crosses high. A zero threshold is off. Changes that stay on one side, including
+// those that start or end exactly on the other side's boundary, cross nothing.
+func BalanceCrossing(before, after, low, high decimal.Decimal) (kind BalanceAlertKind, threshold decimal.Decimal, crossed bool) {
+	if low.IsPositive() && before.GreaterThanOrEqual(low) && after.LessThan(low) {
+		return BalanceAlertLow, low, true
+	}
+	if high.IsPositive() && before.LessThan(high) && after.GreaterThanOrEqual(high) {
+		return BalanceAlertHigh, high, true
+	}
+	return "", decimal.Zero, false
+}
+
+// balanceAlert is a queued alert about one merchant's balance.
+type balanceAlert struct {
+	accountID uuid.UUID
+	email     string
+	kind      BalanceAlertKind
+	threshold decimal.Decimal
+	before    decimal.Decimal
+	after     decimal.Decimal
+}
+
+// BalanceAlertData is the data of a balance alert webhook event.
+type BalanceAlertData struct {
+	MerchantAccountID string `json:"merchant_account_id"`
+	Balance           string `json:"balance"`
+	PreviousBalance   string `json:"previous_balance"`
+	Threshold         string `json:"threshold"`
+}
+
+// webhookEventSender delivers signed webhook events; WebhookService implements it.
+type webhookEventSender interface {
+	SendEvent(ctx context.Context, accountID uuid.UUID, eventType string, data interface{}) error
+}
+
+// BalanceAlerter tells merchants, by email and webhook, when their balance crosses one of
+// their thresholds. Alerts are delivered by a background worker so they never slow down the
+// change that caused them. After an alert of one kind the merchant gets no other of that
+// kind for the debounce window, so a balance hovering at a threshold alerts once. A nil
+// *BalanceAlerter is valid and sends nothing.
+type BalanceAlerter struct {
+	email    notify.EmailNotifier
+	webhooks webhookEventSender
+	cache    *cache.Client
+	debounce time.Duration
+	queue    chan balanceAlert
+}
+
+// NewBalanceAlerter creates an alerter. A nil email or webhooks skips that channel. The
+// debounce is kept in redis, so it holds across instances; while redis is unavailable alerts
+// are not debounced. Call Run to start it.
+func NewBalanceAlerter(email notify.EmailNotifier, webhooks WebhookService, cacheClient *cache.Client, debounce time.Duration) *BalanceAlerter {
+	if debounce <= 0 {
+		debounce = DefaultBalanceAlertDebounce
+	}
+	return &BalanceAlerter{
+		email:    email,
+		webhooks: webhooks,
+		cache:    cacheClient,
+		debounce: debounce,
+		queue:    make(chan balanceAlert, balanceAlertQueueSize),
+	}
+}
+
+// QueueDepth reports how many alerts are waiting for delivery.
+func (a *BalanceAlerter) QueueDepth() (length, capacity int) {
+	if a == nil {
+		return 0, 0
+	}
+	return len(a.queue), cap(a.queue)
+}
+
+// Run delivers queued alerts until ctx is cancelled.
+func (a *BalanceAlerter) Run(ctx context.Context) {
+	for {
+		select {
+		case alert := <-a.queue:
+			a.deliver(ctx, alert)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Watches reports whether the merchant has a balance alert to check, so callers can skip
+// reading the balance around a change when it has none.
+func (a *BalanceAlerter) Watches(merchant *model.Account) bool {
+	return a != nil && merchant.IsMerchant &&
+		(merchant.LowBalanceThreshold.IsPositive() || merchant.HighBalanceThreshold.IsPositive())
+}
+
+// BalanceChanged queues an alert if the merchant's balance change from before to after
+// crossed one of its thresholds. It never blocks: when the queue is full the alert is dropped
+// and logged.
+func (a *BalanceAlerter) BalanceChanged(merchant *model.Account, before, after decimal.Decimal) {
+	if !a.Watches(merchant) {
+		return
+	}
+	kind, threshold, crossed := BalanceCrossing(before, after, merchant.LowBalanceThreshold, merchant.HighBalanceThreshold)
+	if !crossed {
+		return
+	}
+
+	alert := balanceAlert{
+		accountID: merchant.ID,
+		email:     merchant.Email,
+		kind:      kind,
+		threshold: threshold,
+		before:    before,
+		after:     after,
+	}
+	select {
+	case a.queue <- alert:
+	default:
+		log.Printf("balance alert dropped (account=%s %s): queue full", merchant.ID, kind)
+	}
+}
+
+// deliver sends one alert unless one of its kind was sent within the debounce window.
+func (a *BalanceAlerter) deliver(ctx context.Context, alert balanceAlert) {
+	key := balanceAlertKeyPrefix + alert.accountID.String() + ":" + string(alert.kind)
+	if first, _ := a.cache.SetNX(ctx, key, []byte(alert.after.String()), a.debounce); !first {
+		return
+	}
+
+	if a.email != nil && alert.email != "" {
+		subject, body := balanceAlertEmail(alert)
+		if err := a.email.Send(ctx, alert.email, subject, body); err != nil {
+			log.Printf("balance alert email to %s failed: %v", alert.email, err)
+		}
+	}
+	if a.webhooks != nil {
+		err := a.webhooks.SendEvent(ctx, alert.accountID, string(alert.kind), BalanceAlertData{
+			MerchantAccountID: alert.accountID.String(),
+			Balance:           alert.after.StringFixed(2),
+			PreviousBalance:   alert.before.StringFixed(2),
+			Threshold:         alert.threshold.StringFixed(2),
+		})
+		// Merchants without an endpoint or secret have opted out of webhooks
+		if err != nil && !stderrors.Is(err, ErrWebhookEndpointNotSet) && !stderrors.Is(err, ErrWebhookSecretNotSet) && !stderrors.Is(err, ErrWebhookSecretsDisabled) {
+			log.Printf("balance alert webhook (account=%s) failed: %v", alert.accountID, err)
+		}
+	}
+}
+
+func balanceAlertEmail(alert balanceAlert) (subject, body string) {
+	direction := "dropped below"
+	if alert.kind == BalanceAlertHigh {
+		direction = "reached"
+	}
+	subject = fmt.Sprintf("Balance alert: your balance %s %s", direction, alert.threshold.StringFixed(2))
+	body = fmt.Sprintf(
+		"Your balance %s your alert threshold.\n\nThreshold: %s\nPrevious balance: %s\nBalance: %s\n",
+		direction,
+		alert.threshold.StringFixed(2),
+		alert.before.StringFixed(2),
+		alert.after.StringFixed(2),
+	)
+	return subject, body
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"paytabs/internal/cache"
+	"paytabs/internal/model"
+)
+
+func TestBalanceCrossing(t *testing.T) {
+	low, high := decimal.NewFromInt(100), decimal.NewFromInt(1000)
+
+	tests := []struct {
+		name          string
+		before, after string
+		low, high     decimal.Decimal
+		wantKind      BalanceAlertKind
+	}{
+		{name: "falls below low", before: "150", after: "99.99", low: low, high: high, wantKind: BalanceAlertLow},
+		{name: "falls from exactly low", before: "100", after: "99.99", low: low, high: high, wantKind: BalanceAlertLow},
+		{name: "falls to exactly low", before: "150", after: "100", low: low, high: high},
+		{name: "already below low", before: "90", after: "80", low: low, high: high},
+		{name: "rises from below low", before: "80", after: "120", low: low, high: high},
+		{name: "rises to exactly high", before: "999.99", after: "1000", low: low, high: high, wantKind: BalanceAlertHigh},
+		{name: "rises past high", before: "500", after: "1500", low: low, high: high, wantKind: BalanceAlertHigh},
+		{name: "already at high", before: "1000", after: "1200", low: low, high: high},
+		{name: "falls from high", before: "1200", after: "900", low: low, high: high},
+		{name: "stays between", before: "200", after: "800", low: low, high: high},
+		{name: "low off", before: "150", after: "0", low: decimal.Zero, high: high},
+		{name: "high off", before: "0", after: "5000", low: low, high: decimal.Zero},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, threshold, crossed := BalanceCrossing(decimal.RequireFromString(tt.before), decimal.RequireFromString(tt.after), tt.low, tt.high)
+
+			assert.Equal(t, tt.wantKind != "", crossed)
+			assert.Equal(t, tt.wantKind, kind)
+			switch tt.wantKind {
+			case BalanceAlertLow:
+				assert.True(t, threshold.Equal(tt.low))
+			case BalanceAlertHigh:
+				assert.True(t, threshold.Equal(tt.high))
+			}
+		})
+	}
+}
+
+// fakeWebhookSender records the events it is asked to send.
+type fakeWebhookSender struct {
+	events []string
+	data   []interface{}
+}
+
+func (f *fakeWebhookSender) SendEvent(ctx context.Context, accountID uuid.UUID, eventType string, data interface{}) error {
+	f.events = append(f.events, eventType)
+	f.data = append(f.data, data)
+	return nil
+}
+
+func TestBalanceAlerter_DebouncesPerKind(t *testing.T) {
+	mr := miniredis.RunT(t)
+	email := newFakeEmail(0)
+	webhooks := &fakeWebhookSender{}
+	a := NewBalanceAlerter(email, nil, cache.New(mr.Addr(), "", 0), 0)
+	a.webhooks = webhooks
+
+	merchant := &model.Account{
+		ID:                   uuid.New(),
+		Email:                "shop@example.com",
+		IsMerchant:           true,
+		LowBalanceThreshold:  decimal.NewFromInt(100),
+		HighBalanceThreshold: decimal.NewFromInt(1000),
+	}
+	drain := func() {
+		for len(a.queue) > 0 {
+			a.deliver(context.Background(), <-a.queue)
+		}
+	}
+
+	// A balance hovering around the low threshold alerts once
+	a.BalanceChanged(merchant, decimal.NewFromInt(120), decimal.NewFromInt(90))
+	a.BalanceChanged(merchant, decimal.NewFromInt(90), decimal.NewFromInt(110))
+	a.BalanceChanged(merchant, decimal.NewFromInt(110), decimal.NewFromInt(95))
+	// Changes that cross nothing are not even queued
+	a.BalanceChanged(merchant, decimal.NewFromInt(300), decimal.NewFromInt(400))
+	assert.Equal(t, 2, len(a.queue))
+	drain()
+
+	// The other kind is debounced separately
+	a.BalanceChanged(merchant, decimal.NewFromInt(900), decimal.NewFromInt(1000))
+	drain()
+
+	require.Equal(t, []string{string(BalanceAlertLow), string(BalanceAlertHigh)}, webhooks.events)
+	assert.Equal(t, BalanceAlertData{
+		MerchantAccountID: merchant.ID.String(),
+		Balance:           "90.00",
+		PreviousBalance:   "120.00",
+		Threshold:         "100.00",
+	}, webhooks.data[0])
+	require.Len(t, email.sent, 2)
+	assert.Equal(t, "shop@example.com", email.sent[0].to)
+	assert.Contains(t, email.sent[0].subject, "below 100.00")
+
+	// After the window the next crossing alerts again
+	mr.FastForward(DefaultBalanceAlertDebounce)
+	a.BalanceChanged(merchant, decimal.NewFromInt(110), decimal.NewFromInt(95))
+	drain()
+	assert.Len(t, webhooks.events, 3)
+}
+
+func TestBalanceAlerter_Watches(t *testing.T) {
+	a := NewBalanceAlerter(nil, nil, nil, 0)
+
+	assert.False(t, a.Watches(&model.Account{IsMerchant: true}))
+	assert.False(t, a.Watches(&model.Account{LowBalanceThreshold: decimal.NewFromInt(10)}), "not a merchant")
+	assert.True(t, a.Watches(&model.Account{IsMerchant: true, HighBalanceThreshold: decimal.NewFromInt(10)}))
+
+	var disabled *BalanceAlerter
+	assert.False(t, disabled.Watches(&model.Account{IsMerchant: true, LowBalanceThreshold: decimal.NewFromInt(10)}))
+	disabled.BalanceChanged(&model.Account{IsMerchant: true, LowBalanceThreshold: decimal.NewFromInt(10)}, decimal.NewFromInt(20), decimal.Zero)
+}
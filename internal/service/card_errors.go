@@ -0,0 +1,25 @@
+package service
+
+import "errors"
+
+// ErrDuplicateCard is returned when the same card number appears more than once in a bulk request.
+var ErrDuplicateCard = errors.New("duplicate card number in request")
+
+// ErrCardInactive is returned when money is moved off a deactivated card.
+var ErrCardInactive = errors.New("card is not active")
+
+// ErrUnsupportedCurrency is returned when a card's requested or inherited currency is not on
+// the configured allow-list.
+var ErrUnsupportedCurrency = errors.New("currency is not supported")
+
+// ErrCardHoldNotFound is returned when a card hold does not exist or belongs to another card.
+var ErrCardHoldNotFound = errors.New("card hold not found")
+
+// ErrCardHoldNotActive is returned when releasing a hold that was already released or has expired.
+var ErrCardHoldNotActive = errors.New("card hold is not active")
+
+// ErrCardHoldNotCustomer is returned when a merchant holds funds on a card that has never paid it.
+var ErrCardHoldNotCustomer = errors.New("merchant can only hold funds on cards that have paid it")
+
+// ErrCardHoldTooLong is returned when a hold is asked to last longer than CARD_HOLD_MAX_DURATION.
+var ErrCardHoldTooLong = errors.New("card hold duration exceeds the maximum")
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"paytabs/internal/errors"
+	"paytabs/internal/model"
+)
+
+// NewCardHold describes funds to hold on a card. A zero Duration lasts CARD_HOLD_MAX_DURATION.
+type NewCardHold struct {
+	Amount    decimal.Decimal
+	Duration  time.Duration
+	Reference string
+}
+
+// CardAvailableBalance is a card's balance split into what is held and what can be spent.
+type CardAvailableBalance struct {
+	Card      *model.Card
+	Balance   decimal.Decimal
+	Held      decimal.Decimal
+	Available decimal.Decimal
+}
+
+// availableBalance is what a card with balance can spend while held is reserved. It is
+// negative when holds outlast a debit made before they were placed, which cannot happen
+// through this service but is reported as it is rather than hidden.
+func availableBalance(balance, held decimal.Decimal) decimal.Decimal {
+	return balance.Sub(held)
+}
+
+// GetAvailableBalance returns the card's balance, the total of its active holds, and the
+// difference that payments and withdrawals may use.
+func (s *cardService) GetAvailableBalance(ctx context.Context, cardID uuid.UUID) (*CardAvailableBalance, error) {
+	card, err := s.GetCard(ctx, cardID)
+	if err != nil {
+		return nil, err
+	}
+	held, err := s.cardRepo.SumActiveHolds(ctx, cardID, s.clock.Now())
+	if err != nil {
+		return nil, fmt.Errorf("sum card holds: %w", err)
+	}
+	return &CardAvailableBalance{
+		Card:      card,
+		Balance:   card.Balance,
+		Held:      held,
+		Available: availableBalance(card.Balance, held),
+	}, nil
+}
+
+// PlaceHold reserves funds on an active card for the card's owner or for a merchant the card
+// has already paid; other callers get ErrNotMerchant or ErrCardHoldNotCustomer. The card
+// row is locked while its holds are summed, as payments lock it before debiting, so the new
+// hold and a concurrent charge cannot both be covered by the same funds.
+func (s *cardService) PlaceHold(ctx context.Context, cardID uuid.UUID, placedBy uuid.UUID, req NewCardHold) (*model.CardHold, error) {
+	if !req.Amount.IsPositive() {
+		return nil, errors.ErrInvalidAmount
+	}
+	duration := req.Duration
+	if duration <= 0 {
+		duration = s.maxHold
+	}
+	if s.maxHold > 0 && duration > s.maxHold {
+		return nil, ErrCardHoldTooLong
+	}
+
+	var hold *model.CardHold
+	err := s.txManager.WithTransaction(ctx, func(ctx context.Context, tx interface{}) error {
+		card, err := s.cardRepo.FindByIDForUpdateTx(ctx, tx, cardID)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return errors.ErrCardNotFound
+			}
+			return fmt.Errorf("lock card: %w", err)
+		}
+		if !card.Active {
+			return ErrCardInactive
+		}
+		if placedBy != card.AccountID {
+			if err := s.checkHoldingMerchant(ctx, placedBy, cardID); err != nil {
+				return err
+			}
+		}
+
+		now := s.clock.Now()
+		held, err := s.cardRepo.SumActiveHoldsTx(ctx, tx, cardID, now)
+		if err != nil {
+			return fmt.Errorf("sum card holds: %w", err)
+		}
+		if availableBalance(card.Balance, held).LessThan(req.Amount) {
+			return errors.ErrInsufficientBalance
+		}
+
+		hold = &model.CardHold{
+			CardID:            cardID,
+			PlacedByAccountID: placedBy,
+			Amount:            req.Amount,
+			Reference:         req.Reference,
+			Status:            model.CardHoldStatusActive,
+			ExpiresAt:         now.Add(duration),
+		}
+		if err := s.cardRepo.CreateHoldTx(ctx, tx, hold); err != nil {
+			return fmt.Errorf("create card hold: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+// checkHoldingMerchant returns an error unless accountID is an active merchant the card has
+// paid before, the only accounts that may hold funds on cards they do not own. Being a
+// merchant alone is not enough, or any merchant could freeze any card's funds.
+func (s *cardService) checkHoldingMerchant(ctx context.Context, accountID, cardID uuid.UUID) error {
+	account, err := s.accountRepo.FindByID(ctx, accountID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return errors.ErrAccountNotFound
+		}
+		return fmt.Errorf("get account: %w", err)
+	}
+	if !account.IsMerchant {
+		return errors.ErrNotMerchant
+	}
+	if !account.Active {
+		return errors.ErrAccountInactive
+	}
+	paid, err := s.cardRepo.HasAcceptedPaymentTo(ctx, cardID, accountID)
+	if err != nil {
+		return fmt.Errorf("check card payments: %w", err)
+	}
+	if !paid {
+		return ErrCardHoldNotCustomer
+	}
+	return nil
+}
+
+// GetHold returns one of the card's holds. Holds on other cards are reported as not found.
+func (s *cardService) GetHold(ctx context.Context, cardID uuid.UUID, holdID uuid.UUID) (*model.CardHold, error) {
+	hold, err := s.cardRepo.FindHoldByID(ctx, holdID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrCardHoldNotFound
+		}
+		return nil, fmt.Errorf("get card hold: %w", err)
+	}
+	if hold.CardID != cardID {
+		return nil, ErrCardHoldNotFound
+	}
+	return hold, nil
+}
+
+// ReleaseHold returns one of the card's active holds to its available balance. Released and
+// expired holds return ErrCardHoldNotActive.
+func (s *cardService) ReleaseHold(ctx context.Context, cardID uuid.UUID, holdID uuid.UUID) (*model.CardHold, error) {
+	hold, err := s.GetHold(ctx, cardID, holdID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock.Now()
+	if hold.StatusAt(now) != model.CardHoldStatusActive {
+		return nil, ErrCardHoldNotActive
+	}
+
+	released, err := s.cardRepo.ReleaseHold(ctx, holdID, now)
+	if err != nil {
+		return nil, fmt.Errorf("release card hold: %w", err)
+	}
+	if !released {
+		// Released by a concurrent request after it was read
+		return nil, ErrCardHoldNotActive
+	}
+
+	hold.Status = model.CardHoldStatusReleased
+	hold.ReleasedAt = &now
+	return hold, nil
+}
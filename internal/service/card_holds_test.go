@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"paytabs/internal/clock"
+	"paytabs/internal/config"
+	"paytabs/internal/errors"
+	"paytabs/internal/model"
+)
+
+var holdTestNow = time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+
+// newCardHoldTestService returns a card service with its clock frozen at holdTestNow and
+// holds capped at a week.
+func newCardHoldTestService(cardRepo *MockCardRepository, accountRepo *MockAccountRepository) *cardService {
+	svc := NewCardService(cardRepo, accountRepo, &MockTxManager{}, nil, nil, &config.Config{CardHoldMaxDuration: 7 * 24 * time.Hour}).(*cardService)
+	svc.clock = clock.NewFixed(holdTestNow)
+	return svc
+}
+
+func TestCardService_GetAvailableBalance(t *testing.T) {
+	card := &model.Card{ID: uuid.New(), AccountID: uuid.New(), Balance: decimal.RequireFromString("100.00"), Active: true}
+	cardRepo := new(MockCardRepository)
+	cardRepo.On("FindByID", mock.Anything, card.ID).Return(card, nil)
+	cardRepo.On("SumActiveHolds", mock.Anything, card.ID, holdTestNow).Return(decimal.RequireFromString("30.25"), nil)
+	svc := newCardHoldTestService(cardRepo, new(MockAccountRepository))
+
+	balance, err := svc.GetAvailableBalance(context.Background(), card.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "100.00", balance.Balance.StringFixed(2))
+	assert.Equal(t, "30.25", balance.Held.StringFixed(2))
+	assert.Equal(t, "69.75", balance.Available.StringFixed(2))
+}
+
+func TestCardService_PlaceHold(t *testing.T) {
+	owner := &model.Account{ID: uuid.New(), Active: true}
+	newCard := func() *model.Card {
+		return &model.Card{ID: uuid.New(), AccountID: owner.ID, Balance: decimal.RequireFromString("100.00"), Active: true}
+	}
+	newRepos := func(card *model.Card, held string) (*MockCardRepository, *MockAccountRepository) {
+		cardRepo := new(MockCardRepository)
+		cardRepo.On("FindByIDForUpdateTx", mock.Anything, mock.Anything, card.ID).Return(card, nil)
+		cardRepo.On("SumActiveHoldsTx", mock.Anything, mock.Anything, card.ID, holdTestNow).Return(decimal.RequireFromString(held), nil).Maybe()
+		cardRepo.On("CreateHoldTx", mock.Anything, mock.Anything, mock.AnythingOfType("*model.CardHold")).Return(nil).Maybe()
+		return cardRepo, new(MockAccountRepository)
+	}
+
+	t.Run("fits the available balance", func(t *testing.T) {
+		card := newCard()
+		cardRepo, accountRepo := newRepos(card, "60.00")
+
+		hold, err := newCardHoldTestService(cardRepo, accountRepo).PlaceHold(context.Background(), card.ID, owner.ID, NewCardHold{
+			Amount:    decimal.RequireFromString("40.00"),
+			Duration:  72 * time.Hour,
+			Reference: "rental-42",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, model.CardHoldStatusActive, hold.Status)
+		assert.Equal(t, owner.ID, hold.PlacedByAccountID)
+		assert.Equal(t, holdTestNow.Add(72*time.Hour), hold.ExpiresAt)
+		cardRepo.AssertCalled(t, "CreateHoldTx", mock.Anything, mock.Anything, hold)
+		accountRepo.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("exceeds the available balance", func(t *testing.T) {
+		card := newCard()
+		cardRepo, accountRepo := newRepos(card, "60.00")
+
+		_, err := newCardHoldTestService(cardRepo, accountRepo).PlaceHold(context.Background(), card.ID, owner.ID, NewCardHold{
+			Amount: decimal.RequireFromString("40.01"),
+		})
+		assert.ErrorIs(t, err, errors.ErrInsufficientBalance)
+		cardRepo.AssertNotCalled(t, "CreateHoldTx", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("defaults to the maximum duration", func(t *testing.T) {
+		card := newCard()
+		cardRepo, accountRepo := newRepos(card, "0")
+
+		hold, err := newCardHoldTestService(cardRepo, accountRepo).PlaceHold(context.Background(), card.ID, owner.ID, NewCardHold{
+			Amount: decimal.RequireFromString("1.00"),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, holdTestNow.Add(7*24*time.Hour), hold.ExpiresAt)
+	})
+
+	t.Run("longer than the maximum", func(t *testing.T) {
+		_, err := newCardHoldTestService(new(MockCardRepository), new(MockAccountRepository)).PlaceHold(context.Background(), uuid.New(), owner.ID, NewCardHold{
+			Amount:   decimal.RequireFromString("1.00"),
+			Duration: 8 * 24 * time.Hour,
+		})
+		assert.ErrorIs(t, err, ErrCardHoldTooLong)
+	})
+
+	t.Run("merchant on a customer's card", func(t *testing.T) {
+		card := newCard()
+		merchant := &model.Account{ID: uuid.New(), Active: true, IsMerchant: true}
+		cardRepo, accountRepo := newRepos(card, "0")
+		accountRepo.On("FindByID", mock.Anything, merchant.ID).Return(merchant, nil)
+		cardRepo.On("HasAcceptedPaymentTo", mock.Anything, card.ID, merchant.ID).Return(true, nil)
+
+		hold, err := newCardHoldTestService(cardRepo, accountRepo).PlaceHold(context.Background(), card.ID, merchant.ID, NewCardHold{
+			Amount: decimal.RequireFromString("100.00"),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, merchant.ID, hold.PlacedByAccountID)
+	})
+
+	t.Run("merchant the card never paid", func(t *testing.T) {
+		card := newCard()
+		merchant := &model.Account{ID: uuid.New(), Active: true, IsMerchant: true}
+		cardRepo, accountRepo := newRepos(card, "0")
+		accountRepo.On("FindByID", mock.Anything, merchant.ID).Return(merchant, nil)
+		cardRepo.On("HasAcceptedPaymentTo", mock.Anything, card.ID, merchant.ID).Return(false, nil)
+
+		_, err := newCardHoldTestService(cardRepo, accountRepo).PlaceHold(context.Background(), card.ID, merchant.ID, NewCardHold{
+			Amount: decimal.RequireFromString("1.00"),
+		})
+		assert.ErrorIs(t, err, ErrCardHoldNotCustomer)
+		cardRepo.AssertNotCalled(t, "CreateHoldTx", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("non-merchant on another account's card", func(t *testing.T) {
+		card := newCard()
+		other := &model.Account{ID: uuid.New(), Active: true}
+		cardRepo, accountRepo := newRepos(card, "0")
+		accountRepo.On("FindByID", mock.Anything, other.ID).Return(other, nil)
+
+		_, err := newCardHoldTestService(cardRepo, accountRepo).PlaceHold(context.Background(), card.ID, other.ID, NewCardHold{
+			Amount: decimal.RequireFromString("1.00"),
+		})
+		assert.ErrorIs(t, err, errors.ErrNotMerchant)
+		cardRepo.AssertNotCalled(t, "CreateHoldTx", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("inactive card", func(t *testing.T) {
+		card := newCard()
+		card.Active = false
+		cardRepo, accountRepo := newRepos(card, "0")
+
+		_, err := newCardHoldTestService(cardRepo, accountRepo).PlaceHold(context.Background(), card.ID, owner.ID, NewCardHold{
+			Amount: decimal.RequireFromString("1.00"),
+		})
+		assert.ErrorIs(t, err, ErrCardInactive)
+	})
+}
+
+func TestCardService_ReleaseHold(t *testing.T) {
+	cardID := uuid.New()
+	newHold := func(expiresIn time.Duration) *model.CardHold {
+		return &model.CardHold{
+			ID:        uuid.New(),
+			CardID:    cardID,
+			Amount:    decimal.RequireFromString("25.00"),
+			Status:    model.CardHoldStatusActive,
+			ExpiresAt: holdTestNow.Add(expiresIn),
+		}
+	}
+
+	t.Run("active", func(t *testing.T) {
+		hold := newHold(time.Hour)
+		cardRepo := new(MockCardRepository)
+		cardRepo.On("FindHoldByID", mock.Anything, hold.ID).Return(hold, nil)
+		cardRepo.On("ReleaseHold", mock.Anything, hold.ID, holdTestNow).Return(true, nil)
+
+		released, err := newCardHoldTestService(cardRepo, new(MockAccountRepository)).ReleaseHold(context.Background(), cardID, hold.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.CardHoldStatusReleased, released.Status)
+		require.NotNil(t, released.ReleasedAt)
+		assert.Equal(t, holdTestNow, *released.ReleasedAt)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		hold := newHold(0)
+		cardRepo := new(MockCardRepository)
+		cardRepo.On("FindHoldByID", mock.Anything, hold.ID).Return(hold, nil)
+
+		_, err := newCardHoldTestService(cardRepo, new(MockAccountRepository)).ReleaseHold(context.Background(), cardID, hold.ID)
+		assert.ErrorIs(t, err, ErrCardHoldNotActive)
+		cardRepo.AssertNotCalled(t, "ReleaseHold", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("released concurrently", func(t *testing.T) {
+		hold := newHold(time.Hour)
+		cardRepo := new(MockCardRepository)
+		cardRepo.On("FindHoldByID", mock.Anything, hold.ID).Return(hold, nil)
+		cardRepo.On("ReleaseHold", mock.Anything, hold.ID, holdTestNow).Return(false, nil)
+
+		_, err := newCardHoldTestService(cardRepo, new(MockAccountRepository)).ReleaseHold(context.Background(), cardID, hold.ID)
+		assert.ErrorIs(t, err, ErrCardHoldNotActive)
+	})
+
+	t.Run("hold of another card", func(t *testing.T) {
+		hold := newHold(time.Hour)
+		cardRepo := new(MockCardRepository)
+		cardRepo.On("FindHoldByID", mock.Anything, hold.ID).Return(hold, nil)
+
+		_, err := newCardHoldTestService(cardRepo, new(MockAccountRepository)).ReleaseHold(context.Background(), uuid.New(), hold.ID)
+		assert.ErrorIs(t, err, ErrCardHoldNotFound)
+	})
+
+	t.Run("unknown hold", func(t *testing.T) {
+		cardRepo := new(MockCardRepository)
+		cardRepo.On("FindHoldByID", mock.Anything, mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+
+		_, err := newCardHoldTestService(cardRepo, new(MockAccountRepository)).ReleaseHold(context.Background(), cardID, uuid.New())
+		assert.ErrorIs(t, err, ErrCardHoldNotFound)
+	})
+}
+
+func TestPaymentService_ProcessCardPayment_HeldFundsUnavailable(t *testing.T) {
+	merchant := &model.Account{ID: uuid.New(), Active: true, IsMerchant: true}
+	newDeps := func(card *model.Card) *paymentTestDeps {
+		d := &paymentTestDeps{
+			accountRepo: new(MockAccountRepository),
+			cardRepo:    new(MockCardRepository),
+			paymentRepo: new(MockPaymentRepository),
+			logRepo:     new(MockPaymentLogRepository),
+		}
+		d.accountRepo.On("FindByID", mock.Anything, merchant.ID).Return(merchant, nil)
+		d.accountRepo.On("CreditBalanceTx", mock.Anything, mock.Anything, merchant.ID, mock.Anything).Return(nil).Maybe()
+		d.cardRepo.On("FindByIDForUpdate", mock.Anything, card.ID).Return(card, nil)
+		d.cardRepo.On("FindByIDForUpdateTx", mock.Anything, mock.Anything, card.ID).Return(card, nil)
+		d.cardRepo.On("SumActiveHoldsTx", mock.Anything, mock.Anything, card.ID, mock.Anything).Return(decimal.RequireFromString("95.00"), nil)
+		d.cardRepo.On("UpdateBalanceTx", mock.Anything, mock.Anything, card.ID, mock.Anything).Return(nil).Maybe()
+		d.cardRepo.On("AddLedgerEntryTx", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+		d.paymentRepo.On("Create", mock.Anything, mock.AnythingOfType("*model.Payment")).Return(nil)
+		d.paymentRepo.On("Update", mock.Anything, mock.AnythingOfType("*model.Payment")).Return(nil)
+		d.paymentRepo.On("TransitionStatusTx", mock.Anything, mock.Anything, mock.Anything, model.PaymentStatusPending, model.PaymentStatusAccepted).Return(true, nil).Maybe()
+		d.logRepo.On("CreateBatch", mock.Anything, mock.Anything).Return(nil).Maybe()
+		d.logRepo.On("Create", mock.Anything, mock.Anything).Return(nil).Maybe()
+		return d
+	}
+
+	t.Run("beyond the available balance", func(t *testing.T) {
+		card := &model.Card{ID: uuid.New(), AccountID: uuid.New(), Balance: decimal.RequireFromString("100.00"), Active: true}
+		d := newDeps(card)
+
+		payment, err := d.service(&config.Config{}).ProcessCardPayment(context.Background(), merchant.ID, card.ID, decimal.RequireFromString("10.00"), "", "")
+		assert.Equal(t, errors.ErrInsufficientBalance, err)
+		assert.Equal(t, model.PaymentFailureInsufficientBalance, payment.FailureReason)
+		d.cardRepo.AssertNotCalled(t, "UpdateBalanceTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("within the available balance", func(t *testing.T) {
+		card := &model.Card{ID: uuid.New(), AccountID: uuid.New(), Balance: decimal.RequireFromString("100.00"), Active: true}
+		d := newDeps(card)
+
+		payment, err := d.service(&config.Config{}).ProcessCardPayment(context.Background(), merchant.ID, card.ID, decimal.RequireFromString("5.00"), "", "")
+		require.NoError(t, err)
+		assert.Equal(t, model.PaymentStatusAccepted, payment.Status)
+		d.cardRepo.AssertCalled(t, "UpdateBalanceTx", mock.Anything, mock.Anything, card.ID, decimalEq("95.00"))
+	})
+}
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"hash/fnv"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// cardLockStripes is the number of mutexes card IDs are spread over.
+const cardLockStripes = 256
+
+// cardLocks serializes work per card with a fixed pool of mutexes, so its memory stays the
+// same however many cards are seen. Cards hashing to the same stripe share a mutex and are
+// serialized with each other too: that only costs parallelism, and since no caller holds two
+// card locks at once it cannot deadlock. The zero value is ready to use.
+type cardLocks struct {
+	stripes [cardLockStripes]sync.Mutex
+}
+
+// get returns the mutex guarding the card.
+func (l *cardLocks) get(cardID uuid.UUID) *sync.Mutex {
+	h := fnv.New32a()
+	_, _ = h.Write(cardID[:])
+	return &l.stripes[h.Sum32()%cardLockStripes]
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCardLocks_BoundedForManyCards(t *testing.T) {
+	var locks cardLocks
+
+	seen := make(map[*sync.Mutex]int)
+	for i := 0; i < 100000; i++ {
+		seen[locks.get(uuid.New())]++
+	}
+
+	assert.LessOrEqual(t, len(seen), cardLockStripes, "every card maps onto the fixed pool")
+	assert.Greater(t, len(seen), cardLockStripes/2, "cards spread over the stripes")
+}
+
+func TestCardLocks_SameCardSameMutex(t *testing.T) {
+	var locks cardLocks
+	cardID := uuid.New()
+
+	assert.Same(t, locks.get(cardID), locks.get(cardID))
+	parsed := uuid.MustParse(cardID.String())
+	assert.Same(t, locks.get(cardID), locks.get(parsed))
+}
+
+func TestCardLocks_SerializesSameCard(t *testing.T) {
+	var locks cardLocks
+	cardID := uuid.New()
+
+	var wg sync.WaitGroup
+	balance := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				// Each goroutine looks the mutex up again, as every payment does
+				mutex := locks.get(cardID)
+				mutex.Lock()
+				current := balance
+				balance = current + 1
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 5000, balance)
+}
@@ -3,29 +3,119 @@ package service
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 
+	"paytabs/internal/cache"
+	"paytabs/internal/clock"
+	"paytabs/internal/config"
+	"paytabs/internal/currency"
 	"paytabs/internal/errors"
+	"paytabs/internal/model"
 	"paytabs/internal/repository"
 )
 
+// NewCard describes a card to be created. The CVV is only used for validation and is never stored.
+// An empty Currency inherits the account's currency, or the server default if the account has none.
+type NewCard struct {
+	CardNumber string
+	CardExpiry string
+	CVV        string
+	Currency   string
+}
+
+// CardUpdate lists the card fields to change. Nil fields are left as they are.
+type CardUpdate struct {
+	Active     *bool
+	CardExpiry *string
+}
+
+// CardWithdrawal is the outcome of moving funds from a card to its owning account.
+type CardWithdrawal struct {
+	ID             uuid.UUID
+	CardID         uuid.UUID
+	AccountID      uuid.UUID
+	Amount         decimal.Decimal
+	CardBalance    decimal.Decimal
+	AccountBalance decimal.Decimal
+}
+
+// CardItemError reports why one card in a bulk request was rejected.
+type CardItemError struct {
+	Index int
+	Err   error
+}
+
+// BulkCardValidationError is returned when one or more cards in a bulk request fail
+// validation. No cards are created in that case.
+type BulkCardValidationError struct {
+	Items []CardItemError
+}
+
+func (e *BulkCardValidationError) Error() string {
+	return fmt.Sprintf("%d card(s) failed validation", len(e.Items))
+}
+
 // CardService handles card operations.
 type CardService interface {
 	GetBalance(ctx context.Context, cardID uuid.UUID) (decimal.Decimal, error)
 	GetAccountTotalBalance(ctx context.Context, accountID uuid.UUID) (decimal.Decimal, error)
+	GetCard(ctx context.Context, cardID uuid.UUID) (*model.Card, error)
+	ListCards(ctx context.Context, filter repository.CardFilter, limit, offset int) ([]model.Card, int64, error)
+	ListAccountCards(ctx context.Context, accountID uuid.UUID) ([]model.Card, error)
+	GetBalanceHistory(ctx context.Context, cardID uuid.UUID, from, to time.Time, limit, offset int) ([]model.LedgerEntry, error)
+	CreateCards(ctx context.Context, accountID uuid.UUID, cards []NewCard) ([]model.Card, error)
+	CreateCard(ctx context.Context, accountID uuid.UUID, card NewCard) (*model.Card, error)
+	UpdateCard(ctx context.Context, cardID uuid.UUID, update CardUpdate) (*model.Card, error)
+	Withdraw(ctx context.Context, cardID uuid.UUID, amount decimal.Decimal) (*CardWithdrawal, error)
+	GetAvailableBalance(ctx context.Context, cardID uuid.UUID) (*CardAvailableBalance, error)
+	PlaceHold(ctx context.Context, cardID uuid.UUID, placedBy uuid.UUID, hold NewCardHold) (*model.CardHold, error)
+	GetHold(ctx context.Context, cardID uuid.UUID, holdID uuid.UUID) (*model.CardHold, error)
+	ReleaseHold(ctx context.Context, cardID uuid.UUID, holdID uuid.UUID) (*model.CardHold, error)
 }
 
 type cardService struct {
-	cardRepo repository.CardRepository
+	cardRepo    repository.CardRepository
+	accountRepo repository.AccountRepository
+	txManager   repository.TxManager
+	alerts      *BalanceAlerter
+	cache       *cache.Client
+	clock       clock.Clock
+	validator   *CardValidator
+	maxCards    int
+	maxHold     time.Duration
+	currencies  []string
+	defaultCur  string
+	dailySpend  *dailySpendTracker
 }
 
 // NewCardService creates a new card service.
-func NewCardService(cardRepo repository.CardRepository) CardService {
+func NewCardService(
+	cardRepo repository.CardRepository,
+	accountRepo repository.AccountRepository,
+	txManager repository.TxManager,
+	alerts *BalanceAlerter,
+	cache *cache.Client,
+	cfg *config.Config,
+) CardService {
+	clk := clock.New()
 	return &cardService{
-		cardRepo: cardRepo,
+		cardRepo:    cardRepo,
+		accountRepo: accountRepo,
+		txManager:   txManager,
+		alerts:      alerts,
+		cache:       cache,
+		clock:       clk,
+		validator:   NewCardValidator().WithMaxExpiryYears(cfg.CardMaxExpiryYears),
+		maxCards:    cfg.MaxCardsPerAccount,
+		maxHold:     cfg.CardHoldMaxDuration,
+		currencies:  cfg.SupportedCurrencies,
+		defaultCur:  cfg.DefaultCurrency,
+		dailySpend:  newDailySpendTracker(cache, cardRepo, clk, cfg.MaxDailyCardSpend),
 	}
 }
 
@@ -57,3 +147,285 @@ func (s *cardService) GetAccountTotalBalance(ctx context.Context, accountID uuid
 
 	return total, nil
 }
+
+// GetCard retrieves a card by ID.
+func (s *cardService) GetCard(ctx context.Context, cardID uuid.UUID) (*model.Card, error) {
+	card, err := s.cardRepo.FindByID(ctx, cardID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.ErrCardNotFound
+		}
+		return nil, fmt.Errorf("get card: %w", err)
+	}
+	return card, nil
+}
+
+// ListCards lists cards across all accounts for operators, newest first, with masked numbers.
+func (s *cardService) ListCards(ctx context.Context, filter repository.CardFilter, limit, offset int) ([]model.Card, int64, error) {
+	cards, total, err := s.cardRepo.List(ctx, filter, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list cards: %w", err)
+	}
+	for i := range cards {
+		cards[i].CardNumber = s.validator.MaskCardNumber(cards[i].CardNumber)
+	}
+	return cards, total, nil
+}
+
+// ListAccountCards lists an account's cards, active or not, with masked numbers.
+func (s *cardService) ListAccountCards(ctx context.Context, accountID uuid.UUID) ([]model.Card, error) {
+	cards, err := s.cardRepo.FindByAccountID(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("list account cards: %w", err)
+	}
+	for i := range cards {
+		cards[i].CardNumber = s.validator.MaskCardNumber(cards[i].CardNumber)
+	}
+	return cards, nil
+}
+
+// UpdateCard changes the fields set in update and returns the updated card. A new expiry
+// must pass the same checks as on creation. Only the changed columns are written, so a
+// concurrent balance change is never overwritten.
+func (s *cardService) UpdateCard(ctx context.Context, cardID uuid.UUID, update CardUpdate) (*model.Card, error) {
+	if update.CardExpiry != nil {
+		if err := s.validator.ValidateExpiry(*update.CardExpiry); err != nil {
+			return nil, err
+		}
+	}
+
+	card, err := s.GetCard(ctx, cardID)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]interface{}, 2)
+	if update.Active != nil {
+		columns["active"] = *update.Active
+		card.Active = *update.Active
+	}
+	if update.CardExpiry != nil {
+		columns["card_expiry"] = *update.CardExpiry
+		card.CardExpiry = *update.CardExpiry
+	}
+	if len(columns) == 0 {
+		return card, nil
+	}
+
+	if err := s.cardRepo.UpdateColumns(ctx, cardID, columns); err != nil {
+		return nil, fmt.Errorf("update card: %w", err)
+	}
+
+	invalidateWallets(ctx, s.cache, card.AccountID)
+	return card, nil
+}
+
+// Withdraw moves amount from a card to its owning account's balance in one transaction.
+// The card and then the account are locked, in the same order payments take them, and
+// the debit is recorded in the card ledger. Funds under an active hold cannot be withdrawn.
+// Like any ledger debit a withdrawal counts toward MAX_DAILY_CARD_SPEND, and is refused with
+// errors.ErrDailyLimitExceeded beyond it.
+func (s *cardService) Withdraw(ctx context.Context, cardID uuid.UUID, amount decimal.Decimal) (*CardWithdrawal, error) {
+	if !amount.IsPositive() {
+		return nil, errors.ErrInvalidAmount
+	}
+	releaseSpend, err := s.dailySpend.Reserve(ctx, cardID, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	withdrawal := &CardWithdrawal{ID: uuid.New(), CardID: cardID, Amount: amount}
+	var owner *model.Account
+	err = s.txManager.WithTransaction(ctx, func(ctx context.Context, tx interface{}) error {
+		card, err := s.cardRepo.FindByIDForUpdateTx(ctx, tx, cardID)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return errors.ErrCardNotFound
+			}
+			return fmt.Errorf("lock card: %w", err)
+		}
+		if !card.Active {
+			return ErrCardInactive
+		}
+
+		held, err := s.cardRepo.SumActiveHoldsTx(ctx, tx, cardID, s.clock.Now())
+		if err != nil {
+			return fmt.Errorf("sum card holds: %w", err)
+		}
+		newCardBalance := card.Balance.Sub(amount)
+		if newCardBalance.LessThan(held) {
+			return errors.ErrInsufficientBalance
+		}
+
+		account, err := s.accountRepo.FindByIDForUpdateTx(ctx, tx, card.AccountID)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return errors.ErrAccountNotFound
+			}
+			return fmt.Errorf("lock account: %w", err)
+		}
+		def, _ := currency.Lookup(s.defaultCur)
+		if err := checkSameCurrency(card.Currency, account.Currency, def); err != nil {
+			return err
+		}
+
+		if err := s.cardRepo.UpdateBalanceTx(ctx, tx, cardID, newCardBalance); err != nil {
+			return fmt.Errorf("update card balance: %w", err)
+		}
+		if err := s.cardRepo.AddLedgerEntryTx(ctx, tx, &model.LedgerEntry{
+			CardID:       cardID,
+			Delta:        amount.Neg(),
+			BalanceAfter: newCardBalance,
+			Reason:       model.LedgerReasonWithdrawal,
+			ReferenceID:  withdrawal.ID,
+		}); err != nil {
+			return fmt.Errorf("record ledger entry: %w", err)
+		}
+		if err := s.accountRepo.CreditBalanceTx(ctx, tx, account.ID, amount); err != nil {
+			return fmt.Errorf("credit account: %w", err)
+		}
+
+		owner = account
+		withdrawal.AccountID = account.ID
+		withdrawal.CardBalance = newCardBalance
+		withdrawal.AccountBalance = account.Balance.Add(amount)
+		return nil
+	})
+	if err != nil {
+		releaseSpend()
+		return nil, err
+	}
+
+	_ = s.cache.Delete(ctx, fmt.Sprintf("card:%s", cardID.String()))
+	invalidateWallets(ctx, s.cache, withdrawal.AccountID)
+	// The account was locked, so its balance before and after the credit are exact
+	s.alerts.BalanceChanged(owner, owner.Balance, withdrawal.AccountBalance)
+	return withdrawal, nil
+}
+
+// GetBalanceHistory lists the card's ledger entries within [from, to], oldest first.
+func (s *cardService) GetBalanceHistory(ctx context.Context, cardID uuid.UUID, from, to time.Time, limit, offset int) ([]model.LedgerEntry, error) {
+	entries, err := s.cardRepo.ListLedgerEntries(ctx, cardID, from, to, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("list ledger entries: %w", err)
+	}
+	return entries, nil
+}
+
+// CreateCards validates and creates a batch of cards for an account in one transaction.
+// If any card is invalid a *BulkCardValidationError listing every rejected item is
+// returned and nothing is created. Card numbers are stored masked. Cards without a currency
+// take the account's, falling back to the server default.
+func (s *cardService) CreateCards(ctx context.Context, accountID uuid.UUID, cards []NewCard) ([]model.Card, error) {
+	var invalid []CardItemError
+	seen := make(map[string]bool, len(cards))
+	for i, c := range cards {
+		number := strings.ReplaceAll(strings.ReplaceAll(c.CardNumber, " ", ""), "-", "")
+		if err := s.validator.ValidateCard(number, c.CardExpiry, c.CVV); err != nil {
+			invalid = append(invalid, CardItemError{Index: i, Err: err})
+			continue
+		}
+		if seen[number] {
+			invalid = append(invalid, CardItemError{Index: i, Err: ErrDuplicateCard})
+			continue
+		}
+		seen[number] = true
+		if c.Currency != "" {
+			if _, ok := s.supportedCurrency(c.Currency); !ok {
+				invalid = append(invalid, CardItemError{Index: i, Err: ErrUnsupportedCurrency})
+			}
+		}
+	}
+	if len(invalid) > 0 {
+		return nil, &BulkCardValidationError{Items: invalid}
+	}
+
+	created := make([]model.Card, 0, len(cards))
+	err := s.txManager.WithTransaction(ctx, func(ctx context.Context, tx interface{}) error {
+		// Lock the account so concurrent requests can't both pass the limit check
+		account, err := s.accountRepo.FindByIDForUpdateTx(ctx, tx, accountID)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return errors.ErrAccountNotFound
+			}
+			return fmt.Errorf("lock account: %w", err)
+		}
+		if !account.Active {
+			return errors.ErrAccountInactive
+		}
+
+		if s.maxCards > 0 {
+			existing, err := s.cardRepo.CountByAccountIDTx(ctx, tx, accountID)
+			if err != nil {
+				return fmt.Errorf("count cards: %w", err)
+			}
+			if existing+int64(len(cards)) > int64(s.maxCards) {
+				return errors.ErrCardLimitExceeded
+			}
+		}
+
+		inherited := account.Currency
+		if inherited == "" {
+			inherited = s.defaultCur
+		}
+
+		for _, c := range cards {
+			requested := c.Currency
+			if requested == "" {
+				requested = inherited
+			}
+			code, ok := s.supportedCurrency(requested)
+			if !ok {
+				return ErrUnsupportedCurrency
+			}
+
+			card := model.Card{
+				AccountID:  accountID,
+				CardNumber: s.validator.MaskCardNumber(c.CardNumber),
+				CardExpiry: c.CardExpiry,
+				Currency:   code,
+				Balance:    decimal.Zero,
+				Active:     true,
+			}
+			if err := s.cardRepo.CreateTx(ctx, tx, &card); err != nil {
+				return fmt.Errorf("create card: %w", err)
+			}
+			created = append(created, card)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	invalidateWallets(ctx, s.cache, accountID)
+	return created, nil
+}
+
+// CreateCard validates and creates a single card for the account, like CreateCards. An
+// invalid number (Luhn), expiry, or CVV is reported as errors.ErrInvalidCard and an
+// unsupported currency as ErrUnsupportedCurrency.
+func (s *cardService) CreateCard(ctx context.Context, accountID uuid.UUID, card NewCard) (*model.Card, error) {
+	created, err := s.CreateCards(ctx, accountID, []NewCard{card})
+	if err != nil {
+		if invalid, ok := err.(*BulkCardValidationError); ok && len(invalid.Items) > 0 {
+			return nil, invalid.Items[0].Err
+		}
+		return nil, err
+	}
+	return &created[0], nil
+}
+
+// supportedCurrency returns the canonical code for code if it is on the allow-list.
+func (s *cardService) supportedCurrency(code string) (string, bool) {
+	cur, ok := currency.Lookup(code)
+	if !ok {
+		return "", false
+	}
+	for _, allowed := range s.currencies {
+		if other, ok := currency.Lookup(allowed); ok && other.Code == cur.Code {
+			return cur.Code, true
+		}
+	}
+	return "", false
+}
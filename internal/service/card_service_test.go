@@ -0,0 +1,431 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"paytabs/internal/cache"
+	"paytabs/internal/config"
+	"paytabs/internal/errors"
+	"paytabs/internal/model"
+	"paytabs/internal/repository"
+)
+
+func TestCardService_CreateCards(t *testing.T) {
+	expiry := time.Now().UTC().AddDate(2, 0, 0).Format("01/06")
+	account := &model.Account{ID: uuid.New(), Active: true}
+
+	newService := func(maxCards int, existing int64) (CardService, *MockCardRepository) {
+		cardRepo := new(MockCardRepository)
+		accountRepo := new(MockAccountRepository)
+		accountRepo.On("FindByIDForUpdateTx", mock.Anything, mock.Anything, account.ID).Return(account, nil).Maybe()
+		cardRepo.On("CountByAccountIDTx", mock.Anything, mock.Anything, account.ID).Return(existing, nil).Maybe()
+		cardRepo.On("CreateTx", mock.Anything, mock.Anything, mock.AnythingOfType("*model.Card")).Return(nil).Maybe()
+		cfg := &config.Config{
+			MaxCardsPerAccount:  maxCards,
+			CardMaxExpiryYears:  DefaultMaxExpiryYears,
+			SupportedCurrencies: []string{"USD", "EUR"},
+			DefaultCurrency:     "USD",
+		}
+		return NewCardService(cardRepo, accountRepo, &MockTxManager{}, nil, nil, cfg), cardRepo
+	}
+
+	t.Run("creates every card masked", func(t *testing.T) {
+		svc, cardRepo := newService(5, 1)
+
+		cards, err := svc.CreateCards(context.Background(), account.ID, []NewCard{
+			{CardNumber: "4111 1111 1111 1111", CardExpiry: expiry, CVV: "123"},
+			{CardNumber: "5555-5555-5555-4444", CardExpiry: expiry, CVV: "1234"},
+		})
+		require.NoError(t, err)
+		require.Len(t, cards, 2)
+		assert.Equal(t, "****1111", cards[0].CardNumber)
+		assert.Equal(t, "****4444", cards[1].CardNumber)
+		assert.Equal(t, account.ID, cards[0].AccountID)
+		assert.True(t, cards[0].Active)
+		assert.Equal(t, "USD", cards[0].Currency, "an account without a currency gets the server default")
+		cardRepo.AssertNumberOfCalls(t, "CreateTx", 2)
+	})
+
+	t.Run("reports every invalid item and creates nothing", func(t *testing.T) {
+		svc, cardRepo := newService(5, 0)
+
+		_, err := svc.CreateCards(context.Background(), account.ID, []NewCard{
+			{CardNumber: "4111111111111111", CardExpiry: expiry, CVV: "123"},
+			{CardNumber: "4111111111111112", CardExpiry: expiry, CVV: "123"},
+			{CardNumber: "4111 1111 1111 1111", CardExpiry: expiry, CVV: "123"},
+			{CardNumber: "5555555555554444", CardExpiry: expiry, CVV: "12"},
+		})
+		var invalid *BulkCardValidationError
+		require.True(t, stderrors.As(err, &invalid))
+		require.Len(t, invalid.Items, 3)
+		assert.Equal(t, CardItemError{Index: 1, Err: errors.ErrInvalidCard}, invalid.Items[0])
+		assert.Equal(t, CardItemError{Index: 2, Err: ErrDuplicateCard}, invalid.Items[1])
+		assert.Equal(t, CardItemError{Index: 3, Err: errors.ErrInvalidCard}, invalid.Items[2])
+		cardRepo.AssertNotCalled(t, "CreateTx", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("limit applies across the whole batch", func(t *testing.T) {
+		svc, cardRepo := newService(3, 2)
+
+		_, err := svc.CreateCards(context.Background(), account.ID, []NewCard{
+			{CardNumber: "4111111111111111", CardExpiry: expiry, CVV: "123"},
+			{CardNumber: "5555555555554444", CardExpiry: expiry, CVV: "123"},
+		})
+		assert.Equal(t, errors.ErrCardLimitExceeded, err)
+		cardRepo.AssertNotCalled(t, "CreateTx", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestCardService_CreateCard(t *testing.T) {
+	expiry := time.Now().UTC().AddDate(2, 0, 0).Format("01/06")
+	past := time.Now().UTC().AddDate(0, -2, 0).Format("01/06")
+	account := &model.Account{ID: uuid.New(), Active: true}
+
+	newService := func() (CardService, *MockCardRepository) {
+		cardRepo := new(MockCardRepository)
+		accountRepo := new(MockAccountRepository)
+		accountRepo.On("FindByIDForUpdateTx", mock.Anything, mock.Anything, account.ID).Return(account, nil).Maybe()
+		cardRepo.On("CreateTx", mock.Anything, mock.Anything, mock.AnythingOfType("*model.Card")).Return(nil).Maybe()
+		cfg := &config.Config{
+			CardMaxExpiryYears:  DefaultMaxExpiryYears,
+			SupportedCurrencies: []string{"USD"},
+			DefaultCurrency:     "USD",
+		}
+		return NewCardService(cardRepo, accountRepo, &MockTxManager{}, nil, nil, cfg), cardRepo
+	}
+
+	t.Run("stores the masked number for the account", func(t *testing.T) {
+		svc, cardRepo := newService()
+
+		card, err := svc.CreateCard(context.Background(), account.ID, NewCard{CardNumber: "4111 1111 1111 1111", CardExpiry: expiry, CVV: "123"})
+		require.NoError(t, err)
+		assert.Equal(t, account.ID, card.AccountID)
+		assert.Equal(t, "****1111", card.CardNumber)
+		cardRepo.AssertCalled(t, "CreateTx", mock.Anything, mock.Anything, mock.MatchedBy(func(c *model.Card) bool {
+			return c.CardNumber == "****1111" && c.AccountID == account.ID
+		}))
+	})
+
+	for name, card := range map[string]NewCard{
+		"failing luhn":   {CardNumber: "4111111111111112", CardExpiry: expiry, CVV: "123"},
+		"expired":        {CardNumber: "4111111111111111", CardExpiry: past, CVV: "123"},
+		"bad expiry":     {CardNumber: "4111111111111111", CardExpiry: "13/30", CVV: "123"},
+		"short cvv":      {CardNumber: "4111111111111111", CardExpiry: expiry, CVV: "12"},
+		"non-digit cvv":  {CardNumber: "4111111111111111", CardExpiry: expiry, CVV: "12a"},
+		"too short card": {CardNumber: "4111", CardExpiry: expiry, CVV: "123"},
+	} {
+		t.Run("rejects "+name, func(t *testing.T) {
+			svc, cardRepo := newService()
+
+			_, err := svc.CreateCard(context.Background(), account.ID, card)
+			assert.Equal(t, errors.ErrInvalidCard, err)
+			cardRepo.AssertNotCalled(t, "CreateTx", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+
+	t.Run("rejects an unsupported currency", func(t *testing.T) {
+		svc, _ := newService()
+
+		_, err := svc.CreateCard(context.Background(), account.ID, NewCard{CardNumber: "4111111111111111", CardExpiry: expiry, CVV: "123", Currency: "JPY"})
+		assert.Equal(t, ErrUnsupportedCurrency, err)
+	})
+}
+
+func TestCardService_CreateCards_Currency(t *testing.T) {
+	expiry := time.Now().UTC().AddDate(2, 0, 0).Format("01/06")
+
+	newService := func(account *model.Account) (CardService, *MockCardRepository) {
+		cardRepo := new(MockCardRepository)
+		accountRepo := new(MockAccountRepository)
+		accountRepo.On("FindByIDForUpdateTx", mock.Anything, mock.Anything, account.ID).Return(account, nil).Maybe()
+		cardRepo.On("CreateTx", mock.Anything, mock.Anything, mock.AnythingOfType("*model.Card")).Return(nil).Maybe()
+		cfg := &config.Config{
+			CardMaxExpiryYears:  DefaultMaxExpiryYears,
+			SupportedCurrencies: []string{"USD", "EUR"},
+			DefaultCurrency:     "USD",
+		}
+		return NewCardService(cardRepo, accountRepo, &MockTxManager{}, nil, nil, cfg), cardRepo
+	}
+
+	t.Run("inherits the account currency", func(t *testing.T) {
+		account := &model.Account{ID: uuid.New(), Active: true, Currency: "EUR"}
+		svc, _ := newService(account)
+
+		cards, err := svc.CreateCards(context.Background(), account.ID, []NewCard{
+			{CardNumber: "4111111111111111", CardExpiry: expiry, CVV: "123"},
+			{CardNumber: "5555555555554444", CardExpiry: expiry, CVV: "123", Currency: "usd"},
+		})
+		require.NoError(t, err)
+		require.Len(t, cards, 2)
+		assert.Equal(t, "EUR", cards[0].Currency)
+		assert.Equal(t, "USD", cards[1].Currency, "an explicit currency wins and is normalized")
+	})
+
+	t.Run("rejects a requested currency off the allow-list", func(t *testing.T) {
+		account := &model.Account{ID: uuid.New(), Active: true, Currency: "EUR"}
+		svc, cardRepo := newService(account)
+
+		_, err := svc.CreateCards(context.Background(), account.ID, []NewCard{
+			{CardNumber: "4111111111111111", CardExpiry: expiry, CVV: "123", Currency: "GBP"},
+		})
+		var invalid *BulkCardValidationError
+		require.True(t, stderrors.As(err, &invalid))
+		assert.Equal(t, []CardItemError{{Index: 0, Err: ErrUnsupportedCurrency}}, invalid.Items)
+		cardRepo.AssertNotCalled(t, "CreateTx", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("rejects an inherited currency off the allow-list", func(t *testing.T) {
+		account := &model.Account{ID: uuid.New(), Active: true, Currency: "JPY"}
+		svc, cardRepo := newService(account)
+
+		_, err := svc.CreateCards(context.Background(), account.ID, []NewCard{
+			{CardNumber: "4111111111111111", CardExpiry: expiry, CVV: "123"},
+		})
+		assert.ErrorIs(t, err, ErrUnsupportedCurrency)
+		cardRepo.AssertNotCalled(t, "CreateTx", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestCardService_UpdateCard(t *testing.T) {
+	validExpiry := time.Now().UTC().AddDate(3, 0, 0).Format("01/06")
+	boolPtr := func(b bool) *bool { return &b }
+	strPtr := func(s string) *string { return &s }
+
+	newCard := func() *model.Card {
+		return &model.Card{ID: uuid.New(), AccountID: uuid.New(), CardNumber: "****1111", CardExpiry: "01/30", Balance: decimal.NewFromInt(50), Active: true}
+	}
+	newService := func(card *model.Card) (CardService, *MockCardRepository) {
+		cardRepo := new(MockCardRepository)
+		cardRepo.On("FindByID", mock.Anything, card.ID).Return(card, nil).Maybe()
+		cfg := &config.Config{CardMaxExpiryYears: DefaultMaxExpiryYears}
+		return NewCardService(cardRepo, new(MockAccountRepository), &MockTxManager{}, nil, nil, cfg), cardRepo
+	}
+
+	t.Run("only active", func(t *testing.T) {
+		card := newCard()
+		svc, cardRepo := newService(card)
+		cardRepo.On("UpdateColumns", mock.Anything, card.ID, map[string]interface{}{"active": false}).Return(nil)
+
+		updated, err := svc.UpdateCard(context.Background(), card.ID, CardUpdate{Active: boolPtr(false)})
+		require.NoError(t, err)
+		assert.False(t, updated.Active)
+		assert.Equal(t, "01/30", updated.CardExpiry, "expiry is untouched")
+		assert.True(t, decimal.NewFromInt(50).Equal(updated.Balance))
+		cardRepo.AssertExpectations(t)
+	})
+
+	t.Run("only expiry", func(t *testing.T) {
+		card := newCard()
+		svc, cardRepo := newService(card)
+		cardRepo.On("UpdateColumns", mock.Anything, card.ID, map[string]interface{}{"card_expiry": validExpiry}).Return(nil)
+
+		updated, err := svc.UpdateCard(context.Background(), card.ID, CardUpdate{CardExpiry: strPtr(validExpiry)})
+		require.NoError(t, err)
+		assert.Equal(t, validExpiry, updated.CardExpiry)
+		assert.True(t, updated.Active, "active is untouched")
+		cardRepo.AssertExpectations(t)
+	})
+
+	t.Run("both fields", func(t *testing.T) {
+		card := newCard()
+		svc, cardRepo := newService(card)
+		cardRepo.On("UpdateColumns", mock.Anything, card.ID, map[string]interface{}{"active": false, "card_expiry": validExpiry}).Return(nil)
+
+		updated, err := svc.UpdateCard(context.Background(), card.ID, CardUpdate{Active: boolPtr(false), CardExpiry: strPtr(validExpiry)})
+		require.NoError(t, err)
+		assert.False(t, updated.Active)
+		assert.Equal(t, validExpiry, updated.CardExpiry)
+	})
+
+	t.Run("invalid expiry changes nothing", func(t *testing.T) {
+		card := newCard()
+		svc, cardRepo := newService(card)
+
+		for _, expiry := range []string{"13/30", "01/20", ""} {
+			_, err := svc.UpdateCard(context.Background(), card.ID, CardUpdate{Active: boolPtr(false), CardExpiry: strPtr(expiry)})
+			assert.ErrorIs(t, err, errors.ErrInvalidCard, expiry)
+		}
+		cardRepo.AssertNotCalled(t, "UpdateColumns", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("unknown card", func(t *testing.T) {
+		cardRepo := new(MockCardRepository)
+		cardRepo.On("FindByID", mock.Anything, mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+		svc := NewCardService(cardRepo, new(MockAccountRepository), &MockTxManager{}, nil, nil, &config.Config{})
+
+		_, err := svc.UpdateCard(context.Background(), uuid.New(), CardUpdate{Active: boolPtr(true)})
+		assert.ErrorIs(t, err, errors.ErrCardNotFound)
+	})
+}
+
+func TestCardService_ListCards_MasksNumbers(t *testing.T) {
+	accountID := uuid.New()
+	active := true
+	filter := repository.CardFilter{AccountID: &accountID, Active: &active}
+	cardRepo := new(MockCardRepository)
+	cardRepo.On("List", mock.Anything, filter, 20, 40).Return([]model.Card{
+		{ID: uuid.New(), AccountID: accountID, CardNumber: "4111111111111111", Active: true},
+	}, int64(41), nil)
+	svc := NewCardService(cardRepo, new(MockAccountRepository), &MockTxManager{}, nil, nil, &config.Config{})
+
+	cards, total, err := svc.ListCards(context.Background(), filter, 20, 40)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(41), total)
+	require.Len(t, cards, 1)
+	assert.Equal(t, "****1111", cards[0].CardNumber)
+}
+
+func TestCardService_ListAccountCards_MasksNumbers(t *testing.T) {
+	accountID := uuid.New()
+	cardRepo := new(MockCardRepository)
+	cardRepo.On("FindByAccountID", mock.Anything, accountID).Return([]model.Card{
+		{ID: uuid.New(), AccountID: accountID, CardNumber: "4111111111111111", Active: true},
+		{ID: uuid.New(), AccountID: accountID, CardNumber: "5500000000000004"},
+	}, nil)
+	svc := NewCardService(cardRepo, new(MockAccountRepository), &MockTxManager{}, nil, nil, &config.Config{})
+
+	cards, err := svc.ListAccountCards(context.Background(), accountID)
+
+	require.NoError(t, err)
+	require.Len(t, cards, 2)
+	assert.Equal(t, "****1111", cards[0].CardNumber)
+	assert.Equal(t, "****0004", cards[1].CardNumber)
+}
+
+func TestCardService_Withdraw(t *testing.T) {
+	newService := func(card *model.Card, account *model.Account) (CardService, *MockCardRepository, *MockAccountRepository) {
+		cardRepo := new(MockCardRepository)
+		accountRepo := new(MockAccountRepository)
+		cardRepo.On("FindByIDForUpdateTx", mock.Anything, mock.Anything, card.ID).Return(card, nil)
+		cardRepo.On("SumActiveHoldsTx", mock.Anything, mock.Anything, card.ID, mock.Anything).Return(decimal.Zero, nil).Maybe()
+		accountRepo.On("FindByIDForUpdateTx", mock.Anything, mock.Anything, account.ID).Return(account, nil).Maybe()
+		return NewCardService(cardRepo, accountRepo, &MockTxManager{}, nil, nil, &config.Config{}), cardRepo, accountRepo
+	}
+
+	t.Run("exact balance", func(t *testing.T) {
+		account := &model.Account{ID: uuid.New(), Active: true, Balance: decimal.RequireFromString("5.00")}
+		card := &model.Card{ID: uuid.New(), AccountID: account.ID, Balance: decimal.RequireFromString("40.25"), Active: true}
+		svc, cardRepo, accountRepo := newService(card, account)
+		cardRepo.On("UpdateBalanceTx", mock.Anything, mock.Anything, card.ID, decimalEq("0")).Return(nil)
+		cardRepo.On("AddLedgerEntryTx", mock.Anything, mock.Anything, mock.MatchedBy(func(e *model.LedgerEntry) bool {
+			return e.CardID == card.ID && e.Reason == model.LedgerReasonWithdrawal &&
+				e.Delta.Equal(decimal.RequireFromString("-40.25")) && e.BalanceAfter.IsZero()
+		})).Return(nil)
+		accountRepo.On("CreditBalanceTx", mock.Anything, mock.Anything, account.ID, decimalEq("40.25")).Return(nil)
+
+		withdrawal, err := svc.Withdraw(context.Background(), card.ID, decimal.RequireFromString("40.25"))
+		require.NoError(t, err)
+		assert.Equal(t, account.ID, withdrawal.AccountID)
+		assert.True(t, withdrawal.CardBalance.IsZero())
+		assert.Equal(t, "45.25", withdrawal.AccountBalance.StringFixed(2))
+		cardRepo.AssertExpectations(t)
+		accountRepo.AssertExpectations(t)
+	})
+
+	t.Run("insufficient balance", func(t *testing.T) {
+		account := &model.Account{ID: uuid.New(), Active: true}
+		card := &model.Card{ID: uuid.New(), AccountID: account.ID, Balance: decimal.RequireFromString("40.25"), Active: true}
+		svc, cardRepo, accountRepo := newService(card, account)
+
+		_, err := svc.Withdraw(context.Background(), card.ID, decimal.RequireFromString("40.26"))
+		assert.ErrorIs(t, err, errors.ErrInsufficientBalance)
+		cardRepo.AssertNotCalled(t, "UpdateBalanceTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		cardRepo.AssertNotCalled(t, "AddLedgerEntryTx", mock.Anything, mock.Anything, mock.Anything)
+		accountRepo.AssertNotCalled(t, "CreditBalanceTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("held funds", func(t *testing.T) {
+		card := &model.Card{ID: uuid.New(), AccountID: uuid.New(), Balance: decimal.RequireFromString("40.25"), Active: true}
+		cardRepo := new(MockCardRepository)
+		cardRepo.On("FindByIDForUpdateTx", mock.Anything, mock.Anything, card.ID).Return(card, nil)
+		cardRepo.On("SumActiveHoldsTx", mock.Anything, mock.Anything, card.ID, mock.Anything).Return(decimal.RequireFromString("40.00"), nil)
+		svc := NewCardService(cardRepo, new(MockAccountRepository), &MockTxManager{}, nil, nil, &config.Config{})
+
+		_, err := svc.Withdraw(context.Background(), card.ID, decimal.RequireFromString("0.26"))
+		assert.ErrorIs(t, err, errors.ErrInsufficientBalance)
+		cardRepo.AssertNotCalled(t, "UpdateBalanceTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("inactive card", func(t *testing.T) {
+		account := &model.Account{ID: uuid.New(), Active: true}
+		card := &model.Card{ID: uuid.New(), AccountID: account.ID, Balance: decimal.RequireFromString("40.25")}
+		svc, _, _ := newService(card, account)
+
+		_, err := svc.Withdraw(context.Background(), card.ID, decimal.RequireFromString("1.00"))
+		assert.ErrorIs(t, err, ErrCardInactive)
+	})
+
+	t.Run("card in another currency", func(t *testing.T) {
+		account := &model.Account{ID: uuid.New(), Active: true, Currency: "USD"}
+		card := &model.Card{ID: uuid.New(), AccountID: account.ID, Balance: decimal.RequireFromString("40.25"), Active: true, Currency: "EUR"}
+		svc, cardRepo, accountRepo := newService(card, account)
+
+		_, err := svc.Withdraw(context.Background(), card.ID, decimal.RequireFromString("1.00"))
+		assert.ErrorIs(t, err, errors.ErrCurrencyMismatch)
+		cardRepo.AssertNotCalled(t, "UpdateBalanceTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		accountRepo.AssertNotCalled(t, "CreditBalanceTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("daily spend limit", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		account := &model.Account{ID: uuid.New(), Active: true}
+		card := &model.Card{ID: uuid.New(), AccountID: account.ID, Balance: decimal.RequireFromString("100.00"), Active: true}
+		cardRepo := new(MockCardRepository)
+		accountRepo := new(MockAccountRepository)
+		cardRepo.On("SumLedgerDebitsSince", mock.Anything, card.ID, mock.Anything).Return(decimal.RequireFromString("45.00"), nil).Once()
+		cardRepo.On("FindByIDForUpdateTx", mock.Anything, mock.Anything, card.ID).Return(card, nil)
+		cardRepo.On("SumActiveHoldsTx", mock.Anything, mock.Anything, card.ID, mock.Anything).Return(decimal.Zero, nil)
+		cardRepo.On("UpdateBalanceTx", mock.Anything, mock.Anything, card.ID, mock.Anything).Return(nil)
+		cardRepo.On("AddLedgerEntryTx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		accountRepo.On("FindByIDForUpdateTx", mock.Anything, mock.Anything, account.ID).Return(account, nil)
+		accountRepo.On("CreditBalanceTx", mock.Anything, mock.Anything, account.ID, mock.Anything).Return(nil)
+		svc := NewCardService(cardRepo, accountRepo, &MockTxManager{}, nil, cache.New(mr.Addr(), "", 0),
+			&config.Config{MaxDailyCardSpend: decimal.RequireFromString("50.00")})
+
+		// The withdrawal counts toward the day, so the next one no longer fits
+		_, err := svc.Withdraw(context.Background(), card.ID, decimal.RequireFromString("5.00"))
+		require.NoError(t, err)
+		_, err = svc.Withdraw(context.Background(), card.ID, decimal.RequireFromString("0.01"))
+		assert.ErrorIs(t, err, errors.ErrDailyLimitExceeded)
+		cardRepo.AssertNumberOfCalls(t, "UpdateBalanceTx", 1)
+	})
+
+	t.Run("failed withdrawal releases its daily spend", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		account := &model.Account{ID: uuid.New(), Active: true}
+		card := &model.Card{ID: uuid.New(), AccountID: account.ID, Balance: decimal.RequireFromString("1.00"), Active: true}
+		cardRepo := new(MockCardRepository)
+		cardRepo.On("SumLedgerDebitsSince", mock.Anything, card.ID, mock.Anything).Return(decimal.Zero, nil)
+		cardRepo.On("FindByIDForUpdateTx", mock.Anything, mock.Anything, card.ID).Return(card, nil)
+		cardRepo.On("SumActiveHoldsTx", mock.Anything, mock.Anything, card.ID, mock.Anything).Return(decimal.Zero, nil)
+		svc := NewCardService(cardRepo, new(MockAccountRepository), &MockTxManager{}, nil, cache.New(mr.Addr(), "", 0),
+			&config.Config{MaxDailyCardSpend: decimal.RequireFromString("50.00")})
+
+		_, err := svc.Withdraw(context.Background(), card.ID, decimal.RequireFromString("40.00"))
+		assert.ErrorIs(t, err, errors.ErrInsufficientBalance)
+
+		value, err := mr.Get(dailySpendKey(card.ID, time.Now()))
+		require.NoError(t, err)
+		assert.Equal(t, "0", value)
+	})
+
+	t.Run("non-positive amount", func(t *testing.T) {
+		svc := NewCardService(new(MockCardRepository), new(MockAccountRepository), &MockTxManager{}, nil, nil, &config.Config{})
+		for _, amount := range []string{"0", "-1.00"} {
+			_, err := svc.Withdraw(context.Background(), uuid.New(), decimal.RequireFromString(amount))
+			assert.ErrorIs(t, err, errors.ErrInvalidAmount, amount)
+		}
+	})
+}
@@ -6,15 +6,37 @@ import (
 	"strings"
 	"time"
 
+	"paytabs/internal/clock"
 	"paytabs/internal/errors"
 )
 
+// DefaultMaxExpiryYears is how far in the future a card expiry may be by default.
+const DefaultMaxExpiryYears = 10
+
 // CardValidator validates card information.
-type CardValidator struct{}
+type CardValidator struct {
+	clock          clock.Clock
+	maxExpiryYears int
+}
 
 // NewCardValidator creates a new card validator.
 func NewCardValidator() *CardValidator {
-	return &CardValidator{}
+	return NewCardValidatorWithClock(clock.New())
+}
+
+// NewCardValidatorWithClock creates a card validator that reads the current time from clk.
+func NewCardValidatorWithClock(clk clock.Clock) *CardValidator {
+	return &CardValidator{
+		clock:          clk,
+		maxExpiryYears: DefaultMaxExpiryYears,
+	}
+}
+
+// WithMaxExpiryYears sets how many years ahead of the current month an expiry may be.
+// A value of zero or less disables the upper bound.
+func (v *CardValidator) WithMaxExpiryYears(years int) *CardValidator {
+	v.maxExpiryYears = years
+	return v
 }
 
 // ValidateCard validates card number, expiry, and CVV.
@@ -27,6 +49,22 @@ func (v *CardValidator) ValidateCard(cardNumber, expiry, cvv string) error {
 		return errors.ErrInvalidCard
 	}
 
+	if err := v.ValidateExpiry(expiry); err != nil {
+		return err
+	}
+
+	// Validate CVV (3-4 digits)
+	cvvRegex := regexp.MustCompile(`^\d{3,4}$`)
+	if !cvvRegex.MatchString(cvv) {
+		return errors.ErrInvalidCard
+	}
+
+	return nil
+}
+
+// ValidateExpiry checks that expiry is MM/YY, not in the past, and within the configured
+// number of years ahead.
+func (v *CardValidator) ValidateExpiry(expiry string) error {
 	// Validate expiry format (MM/YY)
 	expiryRegex := regexp.MustCompile(`^(0[1-9]|1[0-2])/(\d{2})$`)
 	if !expiryRegex.MatchString(expiry) {
@@ -38,12 +76,6 @@ func (v *CardValidator) ValidateCard(cardNumber, expiry, cvv string) error {
 		return errors.ErrInvalidCard
 	}
 
-	// Validate CVV (3-4 digits)
-	cvvRegex := regexp.MustCompile(`^\d{3,4}$`)
-	if !cvvRegex.MatchString(cvv) {
-		return errors.ErrInvalidCard
-	}
-
 	return nil
 }
 
@@ -80,7 +112,8 @@ func (v *CardValidator) validateLuhn(cardNumber string) bool {
 	return sum%10 == 0
 }
 
-// validateExpiry validates that the expiry date is not in the past.
+// validateExpiry validates that the expiry date is not in the past and not
+// unrealistically far in the future.
 func (v *CardValidator) validateExpiry(expiry string) bool {
 	parts := strings.Split(expiry, "/")
 	if len(parts) != 2 {
@@ -102,11 +135,21 @@ func (v *CardValidator) validateExpiry(expiry string) bool {
 		year += 2000
 	}
 
-	now := time.Now()
+	now := v.clock.Now().UTC()
 	expiryDate := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	currentMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
 
 	// Expiry should be at least the current month
-	return expiryDate.After(now.AddDate(0, -1, 0))
+	if expiryDate.Before(currentMonth) {
+		return false
+	}
+
+	// Reject clearly bogus expiries (e.g. typos like 99 for the year)
+	if v.maxExpiryYears > 0 && expiryDate.After(currentMonth.AddDate(v.maxExpiryYears, 0, 0)) {
+		return false
+	}
+
+	return true
 }
 
 // MaskCardNumber masks a card number, showing only last 4 digits.
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"paytabs/internal/clock"
+	"paytabs/internal/errors"
+)
+
+func TestCardValidator_ValidateExpiry(t *testing.T) {
+	tests := []struct {
+		name     string
+		now      time.Time
+		expiry   string
+		expected bool
+	}{
+		{
+			name:     "card expires this month",
+			now:      time.Date(2026, time.March, 31, 23, 59, 59, 0, time.UTC),
+			expiry:   "03/26",
+			expected: true,
+		},
+		{
+			name:     "card expired last month",
+			now:      time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC),
+			expiry:   "02/26",
+			expected: false,
+		},
+		{
+			name:     "card expires 3 years out",
+			now:      time.Date(2026, time.June, 10, 0, 0, 0, 0, time.UTC),
+			expiry:   "06/29",
+			expected: true,
+		},
+		{
+			name:     "card expires 20 years out",
+			now:      time.Date(2026, time.June, 10, 0, 0, 0, 0, time.UTC),
+			expiry:   "06/46",
+			expected: false,
+		},
+		{
+			name:     "card expires exactly at the max window",
+			now:      time.Date(2026, time.June, 10, 0, 0, 0, 0, time.UTC),
+			expiry:   "06/36",
+			expected: true,
+		},
+		{
+			name:     "card expires next month",
+			now:      time.Date(2026, time.December, 15, 12, 0, 0, 0, time.UTC),
+			expiry:   "01/27",
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := NewCardValidatorWithClock(clock.NewFixed(tt.now))
+			assert.Equal(t, tt.expected, validator.validateExpiry(tt.expiry))
+		})
+	}
+}
+
+func TestCardValidator_ValidateExpiryCustomWindow(t *testing.T) {
+	now := time.Date(2026, time.June, 10, 0, 0, 0, 0, time.UTC)
+
+	validator := NewCardValidatorWithClock(clock.NewFixed(now)).WithMaxExpiryYears(2)
+	assert.False(t, validator.validateExpiry("06/29"))
+
+	validator = NewCardValidatorWithClock(clock.NewFixed(now)).WithMaxExpiryYears(0)
+	assert.True(t, validator.validateExpiry("06/99"))
+}
+
+func TestCardValidator_ValidateCard(t *testing.T) {
+	validator := NewCardValidatorWithClock(clock.NewFixed(time.Date(2026, time.June, 10, 0, 0, 0, 0, time.UTC)))
+
+	assert.NoError(t, validator.ValidateCard("4111 1111 1111 1111", "06/26", "123"))
+	assert.Equal(t, errors.ErrInvalidCard, validator.ValidateCard("4111 1111 1111 1112", "06/26", "123"))
+	assert.Equal(t, errors.ErrInvalidCard, validator.ValidateCard("4111 1111 1111 1111", "05/26", "123"))
+	assert.Equal(t, errors.ErrInvalidCard, validator.ValidateCard("4111 1111 1111 1111", "06/26", "12"))
+}
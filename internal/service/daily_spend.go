@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"paytabs/internal/cache"
+	"paytabs/internal/clock"
+	"paytabs/internal/errors"
+	"paytabs/internal/repository"
+)
+
+// dailySpendCounterTTL outlives the UTC day a counter covers so late reads still hit it.
+const dailySpendCounterTTL = 48 * time.Hour
+
+// dailySpendTracker enforces a per-card daily spend limit (payments, outgoing transfers, and
+// withdrawals: every ledger debit). Totals are kept in Redis counters in cents, keyed by card
+// and UTC date; when a counter is missing the day's ledger debits are summed instead and the
+// counter is reseeded from that figure.
+type dailySpendTracker struct {
+	cache    *cache.Client
+	cardRepo repository.CardRepository
+	clock    clock.Clock
+	limit    decimal.Decimal
+}
+
+// newDailySpendTracker creates a tracker. A non-positive limit disables it.
+func newDailySpendTracker(cache *cache.Client, cardRepo repository.CardRepository, clk clock.Clock, limit decimal.Decimal) *dailySpendTracker {
+	return &dailySpendTracker{
+		cache:    cache,
+		cardRepo: cardRepo,
+		clock:    clk,
+		limit:    limit,
+	}
+}
+
+// dailySpendKey returns the counter key for a card on the UTC day containing t.
+func dailySpendKey(cardID uuid.UUID, t time.Time) string {
+	return fmt.Sprintf("daily_spend:%s:%s", cardID.String(), t.UTC().Format("2006-01-02"))
+}
+
+// Check returns errors.ErrDailyLimitExceeded if spending amount would take the card over
+// the daily limit. It counts nothing, so it suits dry runs; debits use Reserve.
+func (t *dailySpendTracker) Check(ctx context.Context, cardID uuid.UUID, amount decimal.Decimal) error {
+	if !t.limit.IsPositive() {
+		return nil
+	}
+	spent, err := t.Spent(ctx, cardID)
+	if err != nil {
+		return err
+	}
+	if spent.Add(amount).GreaterThan(t.limit) {
+		return errors.ErrDailyLimitExceeded
+	}
+	return nil
+}
+
+// Reserve adds amount to today's counter and returns errors.ErrDailyLimitExceeded, taking it
+// back off, if the new total is over the limit. The increment and the comparison use the same
+// atomic INCRBY result, so two concurrent debits cannot both pass on a total that counts
+// neither. The caller must call release if the debit does not go through; a completed debit
+// keeps its reservation, which is its record.
+//
+// Without Redis the day's ledger debits are compared instead, which concurrent debits can
+// still race past until Redis is back.
+func (t *dailySpendTracker) Reserve(ctx context.Context, cardID uuid.UUID, amount decimal.Decimal) (release func(), err error) {
+	release = func() {}
+	if !t.limit.IsPositive() {
+		return release, nil
+	}
+	now := t.clock.Now().UTC()
+	key := dailySpendKey(cardID, now)
+
+	// Seeds a missing counter from the ledger, so the increment counts the whole day
+	spent, err := t.spentOn(ctx, cardID, now)
+	if err != nil {
+		return release, err
+	}
+	cents := amount.Shift(2).IntPart()
+	total, ok := t.cache.IncrBy(ctx, key, cents, dailySpendCounterTTL)
+	if ok && total == cents && spent.IsPositive() {
+		// The counter vanished between seeding and the increment and now only holds this
+		// debit. Drop it so the next debit reseeds the full day, and decide on the ledger.
+		_ = t.cache.Delete(ctx, key)
+		ok = false
+	}
+	if !ok {
+		if spent.Add(amount).GreaterThan(t.limit) {
+			return release, errors.ErrDailyLimitExceeded
+		}
+		return release, nil
+	}
+
+	release = func() {
+		_, _ = t.cache.IncrBy(ctx, key, -cents, dailySpendCounterTTL)
+	}
+	if decimal.New(total, -2).GreaterThan(t.limit) {
+		release()
+		return func() {}, errors.ErrDailyLimitExceeded
+	}
+	return release, nil
+}
+
+// Spent returns how much the card has spent so far today (UTC).
+func (t *dailySpendTracker) Spent(ctx context.Context, cardID uuid.UUID) (decimal.Decimal, error) {
+	return t.spentOn(ctx, cardID, t.clock.Now().UTC())
+}
+
+// spentOn returns how much the card has spent on the UTC day containing now.
+func (t *dailySpendTracker) spentOn(ctx context.Context, cardID uuid.UUID, now time.Time) (decimal.Decimal, error) {
+	key := dailySpendKey(cardID, now)
+	if cents, ok := t.cache.GetInt(ctx, key); ok {
+		return decimal.New(cents, -2), nil
+	}
+
+	// Counter missing (new day, evicted, or redis down): reconcile from the ledger
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	spent, err := t.cardRepo.SumLedgerDebitsSince(ctx, cardID, dayStart)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("sum card debits: %w", err)
+	}
+	_, _ = t.cache.SetNX(ctx, key, []byte(strconv.FormatInt(spent.Shift(2).IntPart(), 10)), dailySpendCounterTTL)
+	return spent, nil
+}
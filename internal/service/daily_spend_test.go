@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"paytabs/internal/cache"
+	"paytabs/internal/clock"
+	"paytabs/internal/errors"
+)
+
+func TestDailySpendTracker(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 3, 10, 15, 30, 0, 0, time.UTC)
+	dayStart := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	cardID := uuid.New()
+	key := dailySpendKey(cardID, now)
+
+	newTracker := func(t *testing.T) (*dailySpendTracker, *MockCardRepository, *miniredis.Miniredis) {
+		mr := miniredis.RunT(t)
+		cardRepo := new(MockCardRepository)
+		tracker := newDailySpendTracker(cache.New(mr.Addr(), "", 0), cardRepo, clock.NewFixed(now), decimal.RequireFromString("100.00"))
+		return tracker, cardRepo, mr
+	}
+
+	t.Run("missing counter reconciles from the ledger once", func(t *testing.T) {
+		tracker, cardRepo, mr := newTracker(t)
+		cardRepo.On("SumLedgerDebitsSince", mock.Anything, cardID, dayStart).Return(decimal.RequireFromString("60.25"), nil).Once()
+
+		spent, err := tracker.Spent(ctx, cardID)
+		require.NoError(t, err)
+		assert.Equal(t, "60.25", spent.StringFixed(2))
+
+		seeded, err := mr.Get(key)
+		require.NoError(t, err)
+		assert.Equal(t, "6025", seeded)
+
+		// The seeded counter answers the next read without touching the database
+		spent, err = tracker.Spent(ctx, cardID)
+		require.NoError(t, err)
+		assert.Equal(t, "60.25", spent.StringFixed(2))
+		cardRepo.AssertExpectations(t)
+	})
+
+	t.Run("limit boundary", func(t *testing.T) {
+		tracker, _, mr := newTracker(t)
+		require.NoError(t, mr.Set(key, "6000"))
+
+		assert.NoError(t, tracker.Check(ctx, cardID, decimal.RequireFromString("40.00")))
+		assert.Equal(t, errors.ErrDailyLimitExceeded, tracker.Check(ctx, cardID, decimal.RequireFromString("40.01")))
+	})
+
+	t.Run("reserve increments an existing counter", func(t *testing.T) {
+		tracker, _, mr := newTracker(t)
+		require.NoError(t, mr.Set(key, "1000"))
+
+		_, err := tracker.Reserve(ctx, cardID, decimal.RequireFromString("12.34"))
+		require.NoError(t, err)
+
+		value, err := mr.Get(key)
+		require.NoError(t, err)
+		assert.Equal(t, "2234", value)
+	})
+
+	t.Run("reserve without a counter seeds it from the ledger first", func(t *testing.T) {
+		tracker, cardRepo, mr := newTracker(t)
+		cardRepo.On("SumLedgerDebitsSince", mock.Anything, cardID, dayStart).Return(decimal.RequireFromString("50.00"), nil).Once()
+
+		_, err := tracker.Reserve(ctx, cardID, decimal.RequireFromString("12.34"))
+		require.NoError(t, err)
+
+		value, err := mr.Get(key)
+		require.NoError(t, err)
+		assert.Equal(t, "6234", value)
+		cardRepo.AssertExpectations(t)
+	})
+
+	t.Run("reserve over the limit rolls the increment back", func(t *testing.T) {
+		tracker, _, mr := newTracker(t)
+		require.NoError(t, mr.Set(key, "6000"))
+
+		_, err := tracker.Reserve(ctx, cardID, decimal.RequireFromString("40.01"))
+		assert.Equal(t, errors.ErrDailyLimitExceeded, err)
+
+		value, err := mr.Get(key)
+		require.NoError(t, err)
+		assert.Equal(t, "6000", value)
+
+		_, err = tracker.Reserve(ctx, cardID, decimal.RequireFromString("40.00"))
+		assert.NoError(t, err)
+	})
+
+	t.Run("release returns a reservation", func(t *testing.T) {
+		tracker, _, mr := newTracker(t)
+		require.NoError(t, mr.Set(key, "1000"))
+
+		release, err := tracker.Reserve(ctx, cardID, decimal.RequireFromString("12.34"))
+		require.NoError(t, err)
+		release()
+
+		value, err := mr.Get(key)
+		require.NoError(t, err)
+		assert.Equal(t, "1000", value)
+	})
+
+	t.Run("concurrent reservations never pass the limit together", func(t *testing.T) {
+		tracker, _, mr := newTracker(t)
+		require.NoError(t, mr.Set(key, "0"))
+
+		var wg sync.WaitGroup
+		var accepted atomic.Int32
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := tracker.Reserve(ctx, cardID, decimal.RequireFromString("30.00")); err == nil {
+					accepted.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(3), accepted.Load())
+		value, err := mr.Get(key)
+		require.NoError(t, err)
+		assert.Equal(t, "9000", value)
+	})
+
+	t.Run("reserve falls back to the ledger when redis is down", func(t *testing.T) {
+		tracker, cardRepo, mr := newTracker(t)
+		mr.Close()
+		cardRepo.On("SumLedgerDebitsSince", mock.Anything, cardID, dayStart).Return(decimal.RequireFromString("90.00"), nil)
+
+		_, err := tracker.Reserve(ctx, cardID, decimal.RequireFromString("10.00"))
+		assert.NoError(t, err)
+		_, err = tracker.Reserve(ctx, cardID, decimal.RequireFromString("10.01"))
+		assert.Equal(t, errors.ErrDailyLimitExceeded, err)
+	})
+
+	t.Run("disabled limit skips counters and the ledger", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		cardRepo := new(MockCardRepository)
+		tracker := newDailySpendTracker(cache.New(mr.Addr(), "", 0), cardRepo, clock.NewFixed(now), decimal.Zero)
+
+		assert.NoError(t, tracker.Check(ctx, cardID, decimal.RequireFromString("1000000")))
+		_, err := tracker.Reserve(ctx, cardID, decimal.RequireFromString("1000000"))
+		assert.NoError(t, err)
+
+		assert.False(t, mr.Exists(key))
+		cardRepo.AssertNotCalled(t, "SumLedgerDebitsSince", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"github.com/shopspring/decimal"
+
+	"paytabs/internal/config"
+	"paytabs/internal/currency"
+	"paytabs/internal/errors"
+)
+
+var hundred = decimal.NewFromInt(100)
+
+// CalculateFee returns the processing fee for amount: percent of the amount plus a fixed
+// fee, rounded to the currency's minor unit with mode. Non-positive inputs contribute
+// nothing. Because the fee is rounded before it is used, amount - fee and amount + fee
+// are exact and fee + net always equals gross.
+func CalculateFee(amount, percent, fixed decimal.Decimal, cur currency.Currency, mode currency.RoundingMode) decimal.Decimal {
+	fee := decimal.Zero
+	if percent.IsPositive() {
+		fee = fee.Add(amount.Mul(percent).Div(hundred))
+	}
+	if fixed.IsPositive() {
+		fee = fee.Add(fixed)
+	}
+	return currency.RoundToCurrency(fee, cur, mode)
+}
+
+// AllocateFee splits the fee charged on a payment across the card debits that fund it, in
+// proportion to each debit, with the largest-remainder method of currency.Money.Allocate.
+// The parts sum to exactly fee, so the merchant credited sum(debits) - fee receives the
+// sum of each debit's net, debit - part, with no cent lost or invented by rounding.
+// It returns nil when there are no debits or they sum to zero.
+func AllocateFee(fee currency.Money, debits []decimal.Decimal) []currency.Money {
+	total := decimal.Zero
+	for _, d := range debits {
+		total = total.Add(d)
+	}
+	if !total.IsPositive() {
+		return nil
+	}
+	return fee.Allocate(debits)
+}
+
+// moneyRounding returns the currency and rounding mode computed amounts are rounded with.
+// Amounts are not stored with a currency, so the configured default applies; an unknown
+// code or mode falls back to USD and half-even (main rejects both at startup).
+func moneyRounding(cfg *config.Config) (currency.Currency, currency.RoundingMode) {
+	cur, ok := currency.Lookup(cfg.DefaultCurrency)
+	if !ok {
+		cur, _ = currency.Lookup("USD")
+	}
+	mode, err := currency.ParseRoundingMode(cfg.RoundingMode)
+	if err != nil {
+		mode = currency.RoundHalfEven
+	}
+	return cur, mode
+}
+
+// heldCurrency returns the currency money stored with code is held in. Cards and accounts
+// stored without a currency, or with one no longer known, hold def.
+func heldCurrency(code string, def currency.Currency) currency.Currency {
+	if cur, ok := currency.Lookup(code); ok {
+		return cur
+	}
+	return def
+}
+
+// checkSameCurrency returns errors.ErrCurrencyMismatch unless money held in codes a and b is
+// in the same currency. Balances are moved 1:1, so there is no conversion to fall back on.
+func checkSameCurrency(a, b string, def currency.Currency) error {
+	if heldCurrency(a, def).Code != heldCurrency(b, def).Code {
+		return errors.ErrCurrencyMismatch
+	}
+	return nil
+}
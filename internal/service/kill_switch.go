@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"paytabs/internal/cache"
+	"paytabs/internal/config"
+	"paytabs/internal/errors"
+)
+
+// MoneyOperation names a class of money movement that operators can switch off.
+type MoneyOperation string
+
+const (
+	OperationPayments  MoneyOperation = "payments"
+	OperationTransfers MoneyOperation = "transfers"
+)
+
+// MoneyOperations lists every operation with a kill switch.
+var MoneyOperations = []MoneyOperation{OperationPayments, OperationTransfers}
+
+// ParseMoneyOperation returns the operation with the given name.
+func ParseMoneyOperation(name string) (MoneyOperation, bool) {
+	for _, op := range MoneyOperations {
+		if string(op) == name {
+			return op, true
+		}
+	}
+	return "", false
+}
+
+// killSwitchKey is the redis key holding an operation's flag, e.g. system:payments_enabled.
+func killSwitchKey(op MoneyOperation) string {
+	return fmt.Sprintf("system:%s_enabled", op)
+}
+
+// KillSwitchService reads and toggles the global money-movement switches.
+type KillSwitchService interface {
+	// Enabled reports whether op is switched on. It returns an error when redis cannot be read.
+	Enabled(ctx context.Context, op MoneyOperation) (bool, error)
+	SetEnabled(ctx context.Context, op MoneyOperation, enabled bool) error
+}
+
+// killSwitch keeps each flag in redis so a toggle reaches every instance at once. A missing
+// key means enabled; any value that does not parse as true means disabled, so an operator
+// writing "0", "false" or "off" with redis-cli halts processing too.
+type killSwitch struct {
+	cache      *cache.Client
+	failClosed bool
+}
+
+// NewKillSwitchService creates the kill switch service.
+func NewKillSwitchService(cache *cache.Client, cfg *config.Config) KillSwitchService {
+	return newKillSwitch(cache, cfg)
+}
+
+func newKillSwitch(cache *cache.Client, cfg *config.Config) *killSwitch {
+	return &killSwitch{cache: cache, failClosed: cfg.KillSwitchFailClosed}
+}
+
+func (k *killSwitch) Enabled(ctx context.Context, op MoneyOperation) (bool, error) {
+	value, found, err := k.cache.Lookup(ctx, killSwitchKey(op))
+	if err != nil {
+		return false, err
+	}
+	if !found {
+		return true, nil
+	}
+	enabled, err := strconv.ParseBool(string(value))
+	return err == nil && enabled, nil
+}
+
+func (k *killSwitch) SetEnabled(ctx context.Context, op MoneyOperation, enabled bool) error {
+	return k.cache.Put(ctx, killSwitchKey(op), []byte(strconv.FormatBool(enabled)), 0)
+}
+
+// Check returns ErrServiceDisabled when op is switched off. When redis cannot be read the
+// operation proceeds, unless KILL_SWITCH_FAIL_CLOSED is set.
+func (k *killSwitch) Check(ctx context.Context, op MoneyOperation) error {
+	enabled, err := k.Enabled(ctx, op)
+	if err != nil {
+		if k.failClosed {
+			log.Printf("kill switch: cannot read %s flag, refusing: %v", op, err)
+			return errors.ErrServiceDisabled
+		}
+		return nil
+	}
+	if !enabled {
+		return errors.ErrServiceDisabled
+	}
+	return nil
+}
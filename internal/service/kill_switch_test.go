@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"paytabs/internal/cache"
+	"paytabs/internal/config"
+	"paytabs/internal/errors"
+)
+
+func TestKillSwitch_Check(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	ks := newKillSwitch(cache.New(mr.Addr(), "", 0), &config.Config{})
+
+	// A missing flag means enabled
+	assert.NoError(t, ks.Check(ctx, OperationPayments))
+
+	require.NoError(t, ks.SetEnabled(ctx, OperationPayments, false))
+	assert.Equal(t, errors.ErrServiceDisabled, ks.Check(ctx, OperationPayments))
+	assert.NoError(t, ks.Check(ctx, OperationTransfers), "switches are independent")
+
+	require.NoError(t, ks.SetEnabled(ctx, OperationPayments, true))
+	assert.NoError(t, ks.Check(ctx, OperationPayments))
+
+	// Values written by hand count as off unless they parse as true
+	require.NoError(t, mr.Set("system:transfers_enabled", "off"))
+	assert.Equal(t, errors.ErrServiceDisabled, ks.Check(ctx, OperationTransfers))
+}
+
+func TestKillSwitch_RedisDown(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := cache.New(mr.Addr(), "", 0)
+	mr.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	open := newKillSwitch(client, &config.Config{})
+	assert.NoError(t, open.Check(ctx, OperationPayments), "fails open by default")
+
+	closed := newKillSwitch(client, &config.Config{KillSwitchFailClosed: true})
+	assert.Equal(t, errors.ErrServiceDisabled, closed.Check(ctx, OperationPayments))
+}
+
+func TestPaymentService_ProcessCardPayment_Disabled(t *testing.T) {
+	mr := miniredis.RunT(t)
+	require.NoError(t, mr.Set("system:payments_enabled", "false"))
+
+	d := &paymentTestDeps{accountRepo: new(MockAccountRepository), cardRepo: new(MockCardRepository), paymentRepo: new(MockPaymentRepository), logRepo: new(MockPaymentLogRepository)}
+	svc := NewPaymentService(d.accountRepo, d.cardRepo, d.paymentRepo, d.logRepo, d.payoutRepo, &MockTxManager{}, nil, nil, cache.New(mr.Addr(), "", 0), &config.Config{})
+
+	payment, err := svc.ProcessCardPayment(context.Background(), uuid.New(), uuid.New(), decimal.RequireFromString("10.00"), "", "")
+
+	assert.Nil(t, payment)
+	assert.Equal(t, errors.ErrServiceDisabled, err)
+	d.accountRepo.AssertNotCalled(t, "FindByID")
+	d.paymentRepo.AssertNotCalled(t, "Create")
+}
+
+func TestTransferService_ProcessTransfer_Disabled(t *testing.T) {
+	mr := miniredis.RunT(t)
+	require.NoError(t, mr.Set("system:transfers_enabled", "false"))
+
+	cardRepo := new(MockCardRepository)
+	transferRepo := new(MockTransferRepository)
+	svc := NewTransferService(cardRepo, transferRepo, cache.New(mr.Addr(), "", 0), &config.Config{})
+
+	transfer, err := svc.ProcessTransfer(context.Background(), uuid.New(), uuid.New(), uuid.New(), decimal.RequireFromString("10.00"))
+
+	assert.Nil(t, transfer)
+	assert.Equal(t, errors.ErrServiceDisabled, err)
+	transferRepo.AssertNotCalled(t, "Create")
+
+	// A dry run reports the same refusal
+	check, err := svc.ValidateTransfer(context.Background(), uuid.New(), uuid.New(), uuid.New(), decimal.RequireFromString("10.00"))
+	assert.Nil(t, check)
+	assert.Equal(t, errors.ErrServiceDisabled, err)
+	cardRepo.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+}
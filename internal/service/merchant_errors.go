@@ -0,0 +1,7 @@
+package service
+
+import "errors"
+
+// ErrInvalidBalanceThresholds is returned for negative balance alert thresholds, or a low
+// threshold that is not below the high one.
+var ErrInvalidBalanceThresholds = errors.New("balance thresholds must not be negative, and low must be below high")
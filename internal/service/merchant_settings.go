@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"paytabs/internal/cache"
+	"paytabs/internal/config"
+	"paytabs/internal/errors"
+	"paytabs/internal/model"
+	"paytabs/internal/repository"
+)
+
+// PaymentCeiling is the largest single payment a merchant may take: the lower of the global
+// and the merchant's own limit, where zero means no limit. Zero is returned when neither is set.
+func PaymentCeiling(global, merchant decimal.Decimal) decimal.Decimal {
+	switch {
+	case !merchant.IsPositive():
+		return decimal.Max(global, decimal.Zero)
+	case !global.IsPositive():
+		return merchant
+	default:
+		return decimal.Min(global, merchant)
+	}
+}
+
+// MerchantSettings are the limits and alerts of one merchant. EffectiveMaxPaymentAmount is
+// what ProcessCardPayment enforces; zero means unlimited. Zero balance thresholds are off.
+type MerchantSettings struct {
+	AccountID                 uuid.UUID
+	MaxPaymentAmount          decimal.Decimal
+	GlobalMaxPaymentAmount    decimal.Decimal
+	EffectiveMaxPaymentAmount decimal.Decimal
+	LowBalanceThreshold       decimal.Decimal
+	HighBalanceThreshold      decimal.Decimal
+}
+
+// MerchantSettingsService reads and changes merchants' settings. The payment ceiling is set
+// by operators; balance alert thresholds by the merchant.
+type MerchantSettingsService interface {
+	Get(ctx context.Context, merchantID uuid.UUID) (*MerchantSettings, error)
+	SetMaxPaymentAmount(ctx context.Context, merchantID uuid.UUID, amount decimal.Decimal) (*MerchantSettings, error)
+	SetBalanceAlerts(ctx context.Context, merchantID uuid.UUID, low, high decimal.Decimal) (*MerchantSettings, error)
+}
+
+type merchantSettingsService struct {
+	accountRepo repository.AccountRepository
+	cache       *cache.Client
+	cfg         *config.Config
+}
+
+// NewMerchantSettingsService creates a new merchant settings service.
+func NewMerchantSettingsService(accountRepo repository.AccountRepository, cacheClient *cache.Client, cfg *config.Config) MerchantSettingsService {
+	return &merchantSettingsService{accountRepo: accountRepo, cache: cacheClient, cfg: cfg}
+}
+
+// Get returns the merchant's settings.
+func (s *merchantSettingsService) Get(ctx context.Context, merchantID uuid.UUID) (*MerchantSettings, error) {
+	merchant, err := s.findMerchant(ctx, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	return s.settings(merchant), nil
+}
+
+// SetMaxPaymentAmount sets the merchant's payment ceiling; zero falls back to the global limit.
+// It applies to the next payment, since payments read the merchant from the database.
+func (s *merchantSettingsService) SetMaxPaymentAmount(ctx context.Context, merchantID uuid.UUID, amount decimal.Decimal) (*MerchantSettings, error) {
+	if amount.IsNegative() {
+		return nil, errors.ErrInvalidAmount
+	}
+	merchant, err := s.findMerchant(ctx, merchantID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.update(ctx, merchantID, map[string]interface{}{"max_payment_amount": amount}); err != nil {
+		return nil, fmt.Errorf("update max payment amount: %w", err)
+	}
+
+	merchant.MaxPaymentAmount = amount
+	return s.settings(merchant), nil
+}
+
+// SetBalanceAlerts sets the balances below and at which the merchant is alerted; zero turns
+// an alert off. When both are set low must be under high, or every change between them
+// would be on the wrong side of one.
+func (s *merchantSettingsService) SetBalanceAlerts(ctx context.Context, merchantID uuid.UUID, low, high decimal.Decimal) (*MerchantSettings, error) {
+	if low.IsNegative() || high.IsNegative() || (low.IsPositive() && high.IsPositive() && !low.LessThan(high)) {
+		return nil, ErrInvalidBalanceThresholds
+	}
+	merchant, err := s.findMerchant(ctx, merchantID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.update(ctx, merchantID, map[string]interface{}{
+		"low_balance_threshold":  low,
+		"high_balance_threshold": high,
+	}); err != nil {
+		return nil, fmt.Errorf("update balance alerts: %w", err)
+	}
+
+	merchant.LowBalanceThreshold = low
+	merchant.HighBalanceThreshold = high
+	return s.settings(merchant), nil
+}
+
+// update writes columns and drops the cached copies of the account.
+func (s *merchantSettingsService) update(ctx context.Context, merchantID uuid.UUID, columns map[string]interface{}) error {
+	if err := s.accountRepo.UpdateColumns(ctx, merchantID, columns); err != nil {
+		return err
+	}
+	_ = s.cache.Delete(ctx, accountCacheKey(merchantID))
+	invalidateWallets(ctx, s.cache, merchantID)
+	return nil
+}
+
+func (s *merchantSettingsService) findMerchant(ctx context.Context, merchantID uuid.UUID) (*model.Account, error) {
+	merchant, err := s.accountRepo.FindByID(ctx, merchantID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.ErrAccountNotFound
+		}
+		return nil, fmt.Errorf("get account: %w", err)
+	}
+	if !merchant.IsMerchant {
+		return nil, errors.ErrNotMerchant
+	}
+	return merchant, nil
+}
+
+func (s *merchantSettingsService) settings(merchant *model.Account) *MerchantSettings {
+	return &MerchantSettings{
+		AccountID:                 merchant.ID,
+		MaxPaymentAmount:          merchant.MaxPaymentAmount,
+		GlobalMaxPaymentAmount:    s.cfg.MaxPaymentAmount,
+		EffectiveMaxPaymentAmount: PaymentCeiling(s.cfg.MaxPaymentAmount, merchant.MaxPaymentAmount),
+		LowBalanceThreshold:       merchant.LowBalanceThreshold,
+		HighBalanceThreshold:      merchant.HighBalanceThreshold,
+	}
+}
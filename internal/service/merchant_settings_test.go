@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"paytabs/internal/config"
+	"paytabs/internal/errors"
+	"paytabs/internal/model"
+)
+
+func TestMerchantSettingsService_SetMaxPaymentAmount(t *testing.T) {
+	merchant := &model.Account{ID: uuid.New(), IsMerchant: true}
+	accountRepo := new(MockAccountRepository)
+	accountRepo.On("FindByID", mock.Anything, merchant.ID).Return(merchant, nil)
+	accountRepo.On("UpdateColumns", mock.Anything, merchant.ID, mock.MatchedBy(func(columns map[string]interface{}) bool {
+		amount, ok := columns["max_payment_amount"].(decimal.Decimal)
+		return len(columns) == 1 && ok && amount.Equal(decimal.NewFromInt(250))
+	})).Return(nil)
+	svc := NewMerchantSettingsService(accountRepo, nil, &config.Config{MaxPaymentAmount: decimal.NewFromInt(1000)})
+
+	settings, err := svc.SetMaxPaymentAmount(context.Background(), merchant.ID, decimal.NewFromInt(250))
+
+	require.NoError(t, err)
+	assert.Equal(t, "250", settings.MaxPaymentAmount.String())
+	assert.Equal(t, "1000", settings.GlobalMaxPaymentAmount.String())
+	assert.Equal(t, "250", settings.EffectiveMaxPaymentAmount.String())
+
+	_, err = svc.SetMaxPaymentAmount(context.Background(), merchant.ID, decimal.NewFromInt(-1))
+	assert.Equal(t, errors.ErrInvalidAmount, err)
+	accountRepo.AssertNumberOfCalls(t, "UpdateColumns", 1)
+}
+
+func TestMerchantSettingsService_NotMerchant(t *testing.T) {
+	account := &model.Account{ID: uuid.New()}
+	accountRepo := new(MockAccountRepository)
+	accountRepo.On("FindByID", mock.Anything, account.ID).Return(account, nil)
+	svc := NewMerchantSettingsService(accountRepo, nil, &config.Config{})
+
+	_, err := svc.Get(context.Background(), account.ID)
+	assert.Equal(t, errors.ErrNotMerchant, err)
+	_, err = svc.SetMaxPaymentAmount(context.Background(), account.ID, decimal.NewFromInt(100))
+	assert.Equal(t, errors.ErrNotMerchant, err)
+	accountRepo.AssertNotCalled(t, "UpdateColumns", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestMerchantSettingsService_SetBalanceAlerts(t *testing.T) {
+	merchant := &model.Account{ID: uuid.New(), IsMerchant: true}
+	accountRepo := new(MockAccountRepository)
+	accountRepo.On("FindByID", mock.Anything, merchant.ID).Return(merchant, nil)
+	accountRepo.On("UpdateColumns", mock.Anything, merchant.ID, mock.Anything).Return(nil)
+	svc := NewMerchantSettingsService(accountRepo, nil, &config.Config{})
+
+	settings, err := svc.SetBalanceAlerts(context.Background(), merchant.ID, decimal.NewFromInt(100), decimal.Zero)
+	require.NoError(t, err)
+	assert.Equal(t, "100", settings.LowBalanceThreshold.String())
+	assert.True(t, settings.HighBalanceThreshold.IsZero())
+
+	invalid := [][2]int64{{-1, 0}, {0, -1}, {500, 500}, {500, 100}}
+	for _, thresholds := range invalid {
+		_, err := svc.SetBalanceAlerts(context.Background(), merchant.ID, decimal.NewFromInt(thresholds[0]), decimal.NewFromInt(thresholds[1]))
+		assert.Equal(t, ErrInvalidBalanceThresholds, err, "thresholds %v", thresholds)
+	}
+	accountRepo.AssertNumberOfCalls(t, "UpdateColumns", 1)
+}
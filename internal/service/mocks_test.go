@@ -0,0 +1,508 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+
+	"paytabs/internal/model"
+	"paytabs/internal/repository"
+)
+
+// MockCardRepository is a mock implementation of CardRepository.
+type MockCardRepository struct {
+	mock.Mock
+}
+
+func (m *MockCardRepository) Create(ctx context.Context, card *model.Card) error {
+	args := m.Called(ctx, card)
+	return args.Error(0)
+}
+
+func (m *MockCardRepository) Update(ctx context.Context, card *model.Card) error {
+	args := m.Called(ctx, card)
+	return args.Error(0)
+}
+
+func (m *MockCardRepository) UpdateColumns(ctx context.Context, id uuid.UUID, columns map[string]interface{}) error {
+	args := m.Called(ctx, id, columns)
+	return args.Error(0)
+}
+
+func (m *MockCardRepository) FindByID(ctx context.Context, id uuid.UUID) (*model.Card, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Card), args.Error(1)
+}
+
+func (m *MockCardRepository) FindByIDForUpdate(ctx context.Context, id uuid.UUID) (*model.Card, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Card), args.Error(1)
+}
+
+func (m *MockCardRepository) List(ctx context.Context, filter repository.CardFilter, limit, offset int) ([]model.Card, int64, error) {
+	args := m.Called(ctx, filter, limit, offset)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]model.Card), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockCardRepository) FindByAccountID(ctx context.Context, accountID uuid.UUID) ([]model.Card, error) {
+	args := m.Called(ctx, accountID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Card), args.Error(1)
+}
+
+func (m *MockCardRepository) SumActiveBalancesByAccount(ctx context.Context, accountIDs []uuid.UUID) (map[uuid.UUID]decimal.Decimal, error) {
+	args := m.Called(ctx, accountIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[uuid.UUID]decimal.Decimal), args.Error(1)
+}
+
+func (m *MockCardRepository) UpdateBalance(ctx context.Context, id uuid.UUID, newBalance interface{}) error {
+	args := m.Called(ctx, id, newBalance)
+	return args.Error(0)
+}
+
+func (m *MockCardRepository) FindByCardNumber(ctx context.Context, cardNumber string) (*model.Card, error) {
+	args := m.Called(ctx, cardNumber)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Card), args.Error(1)
+}
+
+func (m *MockCardRepository) FindOwnerAccount(ctx context.Context, accountID uuid.UUID) (*model.Account, error) {
+	args := m.Called(ctx, accountID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Account), args.Error(1)
+}
+
+// WithTransaction runs fn against the mock itself so expectations set on it apply inside the transaction.
+func (m *MockCardRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context, repo repository.CardRepository) error) error {
+	return fn(ctx, m)
+}
+
+func (m *MockCardRepository) FindByIDForUpdateTx(ctx context.Context, tx interface{}, id uuid.UUID) (*model.Card, error) {
+	args := m.Called(ctx, tx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Card), args.Error(1)
+}
+
+func (m *MockCardRepository) UpdateBalanceTx(ctx context.Context, tx interface{}, id uuid.UUID, newBalance interface{}) error {
+	args := m.Called(ctx, tx, id, newBalance)
+	return args.Error(0)
+}
+
+func (m *MockCardRepository) AddLedgerEntry(ctx context.Context, entry *model.LedgerEntry) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
+
+func (m *MockCardRepository) ListLedgerEntries(ctx context.Context, cardID uuid.UUID, from, to time.Time, limit, offset int) ([]model.LedgerEntry, error) {
+	args := m.Called(ctx, cardID, from, to, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.LedgerEntry), args.Error(1)
+}
+
+func (m *MockCardRepository) AddLedgerEntryTx(ctx context.Context, tx interface{}, entry *model.LedgerEntry) error {
+	args := m.Called(ctx, tx, entry)
+	return args.Error(0)
+}
+
+func (m *MockCardRepository) CreateTx(ctx context.Context, tx interface{}, card *model.Card) error {
+	args := m.Called(ctx, tx, card)
+	return args.Error(0)
+}
+
+func (m *MockCardRepository) FindByAccountIDTx(ctx context.Context, tx interface{}, accountID uuid.UUID) ([]model.Card, error) {
+	args := m.Called(ctx, tx, accountID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Card), args.Error(1)
+}
+
+func (m *MockCardRepository) CountByAccountIDTx(ctx context.Context, tx interface{}, accountID uuid.UUID) (int64, error) {
+	args := m.Called(ctx, tx, accountID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockCardRepository) SumLedgerDebitsSince(ctx context.Context, cardID uuid.UUID, since time.Time) (decimal.Decimal, error) {
+	args := m.Called(ctx, cardID, since)
+	return args.Get(0).(decimal.Decimal), args.Error(1)
+}
+
+func (m *MockCardRepository) FindHoldByID(ctx context.Context, id uuid.UUID) (*model.CardHold, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.CardHold), args.Error(1)
+}
+
+func (m *MockCardRepository) SumActiveHolds(ctx context.Context, cardID uuid.UUID, now time.Time) (decimal.Decimal, error) {
+	args := m.Called(ctx, cardID, now)
+	return args.Get(0).(decimal.Decimal), args.Error(1)
+}
+
+func (m *MockCardRepository) HasAcceptedPaymentTo(ctx context.Context, cardID, merchantAccountID uuid.UUID) (bool, error) {
+	args := m.Called(ctx, cardID, merchantAccountID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockCardRepository) ReleaseHold(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	args := m.Called(ctx, id, at)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockCardRepository) CreateHoldTx(ctx context.Context, tx interface{}, hold *model.CardHold) error {
+	args := m.Called(ctx, tx, hold)
+	return args.Error(0)
+}
+
+func (m *MockCardRepository) SumActiveHoldsTx(ctx context.Context, tx interface{}, cardID uuid.UUID, now time.Time) (decimal.Decimal, error) {
+	args := m.Called(ctx, tx, cardID, now)
+	return args.Get(0).(decimal.Decimal), args.Error(1)
+}
+
+// MockTransferRepository is a mock implementation of TransferRepository.
+type MockTransferRepository struct {
+	mock.Mock
+}
+
+func (m *MockTransferRepository) Create(ctx context.Context, transfer *model.Transfer) error {
+	args := m.Called(ctx, transfer)
+	return args.Error(0)
+}
+
+func (m *MockTransferRepository) FindByID(ctx context.Context, id uuid.UUID) (*model.Transfer, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Transfer), args.Error(1)
+}
+
+func (m *MockTransferRepository) ListByAccount(ctx context.Context, accountID uuid.UUID, limit, offset int) ([]repository.AccountTransfer, int64, error) {
+	args := m.Called(ctx, accountID, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(int64), args.Error(2)
+	}
+	return args.Get(0).([]repository.AccountTransfer), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockTransferRepository) ListAllByAccountTx(ctx context.Context, tx interface{}, accountID uuid.UUID) ([]repository.AccountTransfer, error) {
+	args := m.Called(ctx, tx, accountID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]repository.AccountTransfer), args.Error(1)
+}
+
+func (m *MockTransferRepository) ListByCard(ctx context.Context, cardID uuid.UUID, status model.TransferStatus, limit, offset int) ([]repository.AccountTransfer, int64, error) {
+	args := m.Called(ctx, cardID, status, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(int64), args.Error(2)
+	}
+	return args.Get(0).([]repository.AccountTransfer), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockTransferRepository) SumByCardAndDirection(ctx context.Context, cardID uuid.UUID, from, to time.Time) ([]repository.TransferDirectionTotal, error) {
+	args := m.Called(ctx, cardID, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]repository.TransferDirectionTotal), args.Error(1)
+}
+
+func (m *MockTransferRepository) BusiestDayByCard(ctx context.Context, cardID uuid.UUID, from, to time.Time) (*repository.TransferDayTotal, error) {
+	args := m.Called(ctx, cardID, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repository.TransferDayTotal), args.Error(1)
+}
+
+// MockPaymentRepository is a mock implementation of PaymentRepository.
+type MockPaymentRepository struct {
+	mock.Mock
+}
+
+func (m *MockPaymentRepository) Create(ctx context.Context, payment *model.Payment) error {
+	args := m.Called(ctx, payment)
+	return args.Error(0)
+}
+
+func (m *MockPaymentRepository) CreateTx(ctx context.Context, tx interface{}, payment *model.Payment) error {
+	args := m.Called(ctx, tx, payment)
+	return args.Error(0)
+}
+
+func (m *MockPaymentRepository) Update(ctx context.Context, payment *model.Payment) error {
+	args := m.Called(ctx, payment)
+	return args.Error(0)
+}
+
+func (m *MockPaymentRepository) TransitionStatusTx(ctx context.Context, tx interface{}, id uuid.UUID, from, to model.PaymentStatus) (bool, error) {
+	args := m.Called(ctx, tx, id, from, to)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPaymentRepository) FindByID(ctx context.Context, id uuid.UUID) (*model.Payment, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Payment), args.Error(1)
+}
+
+func (m *MockPaymentRepository) SumAcceptedByMerchantSince(ctx context.Context, merchantAccountID uuid.UUID, since time.Time) (decimal.Decimal, error) {
+	args := m.Called(ctx, merchantAccountID, since)
+	return args.Get(0).(decimal.Decimal), args.Error(1)
+}
+
+func (m *MockPaymentRepository) ListCustomersByMerchant(ctx context.Context, merchantAccountID uuid.UUID, limit, offset int) ([]repository.MerchantCustomer, int64, error) {
+	args := m.Called(ctx, merchantAccountID, limit, offset)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]repository.MerchantCustomer), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockPaymentRepository) SumByMerchantAndStatus(ctx context.Context, merchantAccountID uuid.UUID, statuses []model.PaymentStatus) ([]repository.PaymentStatusTotal, error) {
+	args := m.Called(ctx, merchantAccountID, statuses)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]repository.PaymentStatusTotal), args.Error(1)
+}
+
+func (m *MockPaymentRepository) CountAcceptedRetries(ctx context.Context, originalPaymentID uuid.UUID) (int64, error) {
+	args := m.Called(ctx, originalPaymentID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockPaymentRepository) FindRefundByIdempotencyKeyTx(ctx context.Context, tx interface{}, originalPaymentID uuid.UUID, key string) (*model.Payment, error) {
+	args := m.Called(ctx, tx, originalPaymentID, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Payment), args.Error(1)
+}
+
+func (m *MockPaymentRepository) SumRefundsTx(ctx context.Context, tx interface{}, originalPaymentID uuid.UUID) (decimal.Decimal, error) {
+	args := m.Called(ctx, tx, originalPaymentID)
+	return args.Get(0).(decimal.Decimal), args.Error(1)
+}
+
+func (m *MockPaymentRepository) ExistsByMerchantReference(ctx context.Context, merchantAccountID uuid.UUID, reference string) (bool, error) {
+	args := m.Called(ctx, merchantAccountID, reference)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPaymentRepository) FindByChargeIdempotencyKey(ctx context.Context, merchantAccountID uuid.UUID, key string) (*model.Payment, error) {
+	args := m.Called(ctx, merchantAccountID, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Payment), args.Error(1)
+}
+
+func (m *MockPaymentRepository) SetChargeIdempotencyKeyTx(ctx context.Context, tx interface{}, id uuid.UUID, key string) error {
+	args := m.Called(ctx, tx, id, key)
+	return args.Error(0)
+}
+
+func (m *MockPaymentRepository) ListByAccount(ctx context.Context, accountID uuid.UUID, includeArchived bool, limit, offset int) ([]model.Payment, int64, error) {
+	args := m.Called(ctx, accountID, includeArchived, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(int64), args.Error(2)
+	}
+	return args.Get(0).([]model.Payment), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockPaymentRepository) ListByMerchant(ctx context.Context, merchantAccountID uuid.UUID, filter repository.PaymentFilter, limit, offset int) ([]model.Payment, int64, error) {
+	args := m.Called(ctx, merchantAccountID, filter, limit, offset)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]model.Payment), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockPaymentRepository) ListAllByAccountTx(ctx context.Context, tx interface{}, accountID uuid.UUID) ([]model.Payment, error) {
+	args := m.Called(ctx, tx, accountID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Payment), args.Error(1)
+}
+
+func (m *MockPaymentRepository) ListByCard(ctx context.Context, cardID uuid.UUID, statuses []model.PaymentStatus, limit, offset int) ([]repository.CardPayment, int64, error) {
+	args := m.Called(ctx, cardID, statuses, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(int64), args.Error(2)
+	}
+	return args.Get(0).([]repository.CardPayment), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockPaymentRepository) ArchiveBefore(ctx context.Context, statuses []model.PaymentStatus, cutoff, archivedAt time.Time) (int64, error) {
+	args := m.Called(ctx, statuses, cutoff, archivedAt)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// MockPaymentLogRepository is a mock implementation of PaymentLogRepository.
+type MockPaymentLogRepository struct {
+	mock.Mock
+}
+
+func (m *MockPaymentLogRepository) Create(ctx context.Context, log *model.PaymentLog) error {
+	args := m.Called(ctx, log)
+	return args.Error(0)
+}
+
+func (m *MockPaymentLogRepository) CreateBatch(ctx context.Context, logs []model.PaymentLog) error {
+	args := m.Called(ctx, logs)
+	return args.Error(0)
+}
+
+func (m *MockPaymentLogRepository) ListByPaymentID(ctx context.Context, paymentID uuid.UUID) ([]model.PaymentLog, error) {
+	args := m.Called(ctx, paymentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.PaymentLog), args.Error(1)
+}
+
+// MockTxManager runs the callback directly, handing repositories a nil tx.
+type MockTxManager struct{}
+
+func (m *MockTxManager) WithTransaction(ctx context.Context, fn func(ctx context.Context, tx interface{}) error) error {
+	return fn(ctx, nil)
+}
+
+func (m *MockTxManager) WithSnapshot(ctx context.Context, fn func(ctx context.Context, tx interface{}) error) error {
+	return fn(ctx, nil)
+}
+
+// MockPayoutRepository is a mock implementation of PayoutRepository.
+type MockPayoutRepository struct {
+	mock.Mock
+}
+
+func (m *MockPayoutRepository) Create(ctx context.Context, payout *model.Payout) error {
+	args := m.Called(ctx, payout)
+	return args.Error(0)
+}
+
+func (m *MockPayoutRepository) SumPendingByMerchant(ctx context.Context, merchantAccountID uuid.UUID) (decimal.Decimal, error) {
+	args := m.Called(ctx, merchantAccountID)
+	return args.Get(0).(decimal.Decimal), args.Error(1)
+}
+
+func (m *MockPayoutRepository) CreateTx(ctx context.Context, tx interface{}, payout *model.Payout) error {
+	args := m.Called(ctx, tx, payout)
+	return args.Error(0)
+}
+
+func (m *MockPayoutRepository) SumPendingByMerchantTx(ctx context.Context, tx interface{}, merchantAccountID uuid.UUID) (decimal.Decimal, error) {
+	args := m.Called(ctx, tx, merchantAccountID)
+	return args.Get(0).(decimal.Decimal), args.Error(1)
+}
+
+func (m *MockPayoutRepository) SumPaidByMerchant(ctx context.Context, merchantAccountID uuid.UUID) (decimal.Decimal, error) {
+	args := m.Called(ctx, merchantAccountID)
+	return args.Get(0).(decimal.Decimal), args.Error(1)
+}
+
+func (m *MockPayoutRepository) FindByID(ctx context.Context, id uuid.UUID) (*model.Payout, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Payout), args.Error(1)
+}
+
+func (m *MockPayoutRepository) ListByMerchant(ctx context.Context, merchantAccountID uuid.UUID, status model.PayoutStatus, limit, offset int) ([]model.Payout, int64, error) {
+	args := m.Called(ctx, merchantAccountID, status, limit, offset)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]model.Payout), args.Get(1).(int64), args.Error(2)
+}
+
+// MockWebhookRepository is a mock implementation of WebhookRepository.
+type MockWebhookRepository struct {
+	mock.Mock
+}
+
+func (m *MockWebhookRepository) SaveSecret(ctx context.Context, secret *model.WebhookSecret, audit *model.WebhookSecretAudit) error {
+	args := m.Called(ctx, secret, audit)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) FindSecret(ctx context.Context, accountID uuid.UUID) (*model.WebhookSecret, error) {
+	args := m.Called(ctx, accountID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.WebhookSecret), args.Error(1)
+}
+
+func (m *MockWebhookRepository) CreateAudit(ctx context.Context, audit *model.WebhookSecretAudit) error {
+	args := m.Called(ctx, audit)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) SaveEndpoint(ctx context.Context, endpoint *model.WebhookEndpoint) error {
+	args := m.Called(ctx, endpoint)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) FindEndpoint(ctx context.Context, accountID uuid.UUID) (*model.WebhookEndpoint, error) {
+	args := m.Called(ctx, accountID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.WebhookEndpoint), args.Error(1)
+}
+
+// MockStatsRepository is a mock implementation of StatsRepository.
+type MockStatsRepository struct {
+	mock.Mock
+}
+
+func (m *MockStatsRepository) PlatformTotals(ctx context.Context, since time.Time) (*repository.PlatformTotals, error) {
+	args := m.Called(ctx, since)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repository.PlatformTotals), args.Error(1)
+}
+
+func (m *MockStatsRepository) Float(ctx context.Context) ([]repository.CurrencyFloat, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]repository.CurrencyFloat), args.Error(1)
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"paytabs/internal/clock"
+	"paytabs/internal/model"
+	"paytabs/internal/repository"
+)
+
+// archivableStatuses are the terminal payment statuses eligible for archival.
+var archivableStatuses = []model.PaymentStatus{
+	model.PaymentStatusAccepted,
+	model.PaymentStatusFailed,
+	model.PaymentStatusCancelled,
+}
+
+// PaymentArchiver marks completed payments older than the retention window as archived,
+// keeping the hot set of payments small.
+type PaymentArchiver struct {
+	paymentRepo repository.PaymentRepository
+	clock       clock.Clock
+	retention   time.Duration
+}
+
+// NewPaymentArchiver creates a payment archiver. A non-positive retention disables archival.
+func NewPaymentArchiver(paymentRepo repository.PaymentRepository, clk clock.Clock, retention time.Duration) *PaymentArchiver {
+	return &PaymentArchiver{
+		paymentRepo: paymentRepo,
+		clock:       clk,
+		retention:   retention,
+	}
+}
+
+// ArchiveOnce archives completed payments created before now minus the retention window
+// and returns how many rows were archived.
+func (a *PaymentArchiver) ArchiveOnce(ctx context.Context) (int64, error) {
+	if a.retention <= 0 {
+		return 0, nil
+	}
+	now := a.clock.Now()
+	return a.paymentRepo.ArchiveBefore(ctx, archivableStatuses, now.Add(-a.retention), now)
+}
+
+// Run archives on every interval tick until ctx is cancelled.
+func (a *PaymentArchiver) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			archived, err := a.ArchiveOnce(ctx)
+			if err != nil {
+				log.Printf("payment archival failed: %v", err)
+				continue
+			}
+			if archived > 0 {
+				log.Printf("archived %d payments", archived)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
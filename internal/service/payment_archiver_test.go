@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"paytabs/internal/clock"
+	"paytabs/internal/model"
+)
+
+func TestPaymentArchiver_ArchiveOnce(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	retention := 90 * 24 * time.Hour
+
+	repo := new(MockPaymentRepository)
+	repo.On("ArchiveBefore", mock.Anything,
+		[]model.PaymentStatus{model.PaymentStatusAccepted, model.PaymentStatusFailed, model.PaymentStatusCancelled},
+		now.Add(-retention), now,
+	).Return(int64(3), nil)
+
+	archiver := NewPaymentArchiver(repo, clock.NewFixed(now), retention)
+	archived, err := archiver.ArchiveOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), archived)
+	repo.AssertExpectations(t)
+}
+
+func TestPaymentArchiver_ArchiveOnce_Disabled(t *testing.T) {
+	repo := new(MockPaymentRepository)
+
+	archiver := NewPaymentArchiver(repo, clock.NewFixed(time.Now()), 0)
+	archived, err := archiver.ArchiveOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Zero(t, archived)
+	repo.AssertNotCalled(t, "ArchiveBefore", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"paytabs/internal/model"
+)
+
+// PaymentLineKind names a component of a payment's breakdown.
+type PaymentLineKind string
+
+const (
+	// PaymentLineGross is what the card was charged.
+	PaymentLineGross PaymentLineKind = "gross"
+	// PaymentLineProcessingFee is the processing fee (PAYMENT_FEE_PERCENT and PAYMENT_FEE_FIXED).
+	PaymentLineProcessingFee PaymentLineKind = "processing_fee"
+	// PaymentLineNet is what the merchant was credited.
+	PaymentLineNet PaymentLineKind = "net"
+)
+
+// Who bears a payment's processing fee.
+const (
+	FeePaidByMerchant = "merchant"
+	FeePaidByCustomer = "customer"
+)
+
+// PaymentLineItem is one component of a payment's breakdown.
+type PaymentLineItem struct {
+	Kind   PaymentLineKind
+	Amount decimal.Decimal
+}
+
+// PaymentBreakdown is a payment's amounts as stored when it was processed, as line items:
+// the gross charged to the card, less the fees, is the net credited to the merchant.
+type PaymentBreakdown struct {
+	Payment   *model.Payment
+	FeePaidBy string
+	Lines     []PaymentLineItem
+}
+
+// NewPaymentBreakdown itemizes the payment's stored gross, fee and net amounts. Nothing is
+// recomputed, so later fee configuration changes do not alter past breakdowns. The fee was
+// passed to the customer when the card was charged more than the payment amount.
+func NewPaymentBreakdown(payment *model.Payment) *PaymentBreakdown {
+	feePaidBy := FeePaidByMerchant
+	if payment.GrossAmount.GreaterThan(payment.Amount) {
+		feePaidBy = FeePaidByCustomer
+	}
+	return &PaymentBreakdown{
+		Payment:   payment,
+		FeePaidBy: feePaidBy,
+		Lines: []PaymentLineItem{
+			{Kind: PaymentLineGross, Amount: payment.GrossAmount},
+			{Kind: PaymentLineProcessingFee, Amount: payment.FeeAmount},
+			{Kind: PaymentLineNet, Amount: payment.NetAmount},
+		},
+	}
+}
+
+// GetPaymentBreakdown itemizes one of the merchant's payments. Payments of other merchants
+// are reported as not found.
+func (s *paymentService) GetPaymentBreakdown(ctx context.Context, merchantAccountID uuid.UUID, paymentID uuid.UUID) (*PaymentBreakdown, error) {
+	payment, err := s.GetPayment(ctx, merchantAccountID, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	return NewPaymentBreakdown(payment), nil
+}
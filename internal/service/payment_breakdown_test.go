@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"paytabs/internal/config"
+	"paytabs/internal/errors"
+	"paytabs/internal/model"
+)
+
+func TestPaymentBreakdown_GrossIsFeePlusNet(t *testing.T) {
+	amounts := []string{"0.01", "0.99", "1", "10.05", "33.33", "99.99", "100", "1234.56", "99999.99"}
+	fees := []struct{ percent, fixed string }{
+		{"0", "0"},
+		{"2.9", "0.30"},
+		{"1.75", "0"},
+		{"0", "0.25"},
+		{"3.333", "0.07"},
+	}
+	for _, rounding := range []string{"half_even", "half_up", "down", "up"} {
+		for _, fee := range fees {
+			cfg := &config.Config{
+				PaymentFeePercent: decimal.RequireFromString(fee.percent),
+				PaymentFeeFixed:   decimal.RequireFromString(fee.fixed),
+				RoundingMode:      rounding,
+			}
+			svc := (&paymentTestDeps{}).service(cfg).(*paymentService)
+			for _, passFee := range []bool{false, true} {
+				for _, amount := range amounts {
+					payment := &model.Payment{Amount: decimal.RequireFromString(amount)}
+					svc.applyFee(payment, &model.Account{PassFeeToCustomer: passFee}, svc.currency)
+
+					breakdown := NewPaymentBreakdown(payment)
+					require.Len(t, breakdown.Lines, 3)
+					gross, feeAmount, net := breakdown.Lines[0].Amount, breakdown.Lines[1].Amount, breakdown.Lines[2].Amount
+					assert.True(t, gross.Equal(feeAmount.Add(net)), "%s rounding, fee %+v, pass %v, amount %s: gross %s != fee %s + net %s",
+						rounding, fee, passFee, amount, gross, feeAmount, net)
+					assert.True(t, feeAmount.Equal(feeAmount.Round(2)), "fee %s has sub-cent digits", feeAmount)
+				}
+			}
+		}
+	}
+}
+
+func TestPaymentService_GetPaymentBreakdown(t *testing.T) {
+	merchant := &model.Account{ID: uuid.New(), IsMerchant: true, Active: true}
+	payment := &model.Payment{
+		ID:                uuid.New(),
+		MerchantAccountID: merchant.ID,
+		Amount:            decimal.RequireFromString("100"),
+		GrossAmount:       decimal.RequireFromString("103.20"),
+		FeeAmount:         decimal.RequireFromString("3.20"),
+		NetAmount:         decimal.RequireFromString("100"),
+		Status:            model.PaymentStatusAccepted,
+	}
+	d := newPaymentTestDeps(merchant, &model.Card{ID: uuid.New()})
+	d.paymentRepo.On("FindByID", mock.Anything, payment.ID).Return(payment, nil)
+	// The stored amounts are reported, whatever the fees are configured as today
+	svc := d.service(&config.Config{PaymentFeePercent: decimal.RequireFromString("10")})
+
+	breakdown, err := svc.GetPaymentBreakdown(context.Background(), merchant.ID, payment.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, FeePaidByCustomer, breakdown.FeePaidBy)
+	assert.Equal(t, []PaymentLineKind{PaymentLineGross, PaymentLineProcessingFee, PaymentLineNet},
+		[]PaymentLineKind{breakdown.Lines[0].Kind, breakdown.Lines[1].Kind, breakdown.Lines[2].Kind})
+	assert.Equal(t, "3.2", breakdown.Lines[1].Amount.String())
+
+	_, err = svc.GetPaymentBreakdown(context.Background(), uuid.New(), payment.ID)
+	assert.Equal(t, errors.ErrPaymentNotFound, err, "other merchants' payments are not found")
+}
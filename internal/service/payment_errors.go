@@ -0,0 +1,48 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"paytabs/internal/model"
+)
+
+// ErrPaymentNotFailed is returned when a retry is requested for a payment that did not fail.
+var ErrPaymentNotFailed = errors.New("only failed payments can be retried")
+
+// ErrPaymentAlreadyRetried is returned when a retry of the original payment has already been accepted.
+var ErrPaymentAlreadyRetried = errors.New("payment has already been retried successfully")
+
+// ErrPaymentNotCancellable is returned when a cancel is requested for a payment that has
+// already settled as accepted or failed.
+var ErrPaymentNotCancellable = errors.New("only pending payments can be cancelled")
+
+// ErrPaymentAlreadyCancelled is returned when a cancel is requested for a cancelled payment.
+var ErrPaymentAlreadyCancelled = errors.New("payment is already cancelled")
+
+// ErrPaymentNotRefundable is returned when a refund is requested for a payment that was not accepted.
+var ErrPaymentNotRefundable = errors.New("only accepted payments can be refunded")
+
+// ErrRefundExceedsOriginal is returned when a refund is larger than what is left of the original
+// payment after its earlier refunds.
+var ErrRefundExceedsOriginal = errors.New("refund exceeds the amount of the payment not yet refunded")
+
+// ErrRefundKeyReused is returned when an idempotency key that already refunded the payment
+// is repeated with a different amount.
+var ErrRefundKeyReused = errors.New("idempotency key reused with a different refund amount")
+
+// ErrReceiptsDisabled is returned when no RECEIPT_SIGNING_KEY is configured to sign receipts.
+var ErrReceiptsDisabled = errors.New("payment receipts are not configured")
+
+// PaymentNotRetryableError is returned when a payment failed for a business reason, such as
+// insufficient balance, that a retry would hit again.
+type PaymentNotRetryableError struct {
+	Reason model.PaymentFailureReason
+}
+
+func (e *PaymentNotRetryableError) Error() string {
+	if e.Reason == "" {
+		return "payment failure reason is unknown, so it cannot be retried; submit a new payment"
+	}
+	return fmt.Sprintf("payment failed with %s, which a retry would not fix; submit a new payment once it is resolved", e.Reason)
+}
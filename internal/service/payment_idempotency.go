@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"paytabs/internal/errors"
+	"paytabs/internal/model"
+)
+
+// findKeyedPayment returns the merchant's accepted charge that was requested with the
+// idempotency key, or nil when the key has not charged a card yet. Keys are scoped per
+// merchant, so two merchants using the same key never collide.
+func (s *paymentService) findKeyedPayment(ctx context.Context, merchantAccountID uuid.UUID, key string) (*model.Payment, error) {
+	payment, err := s.paymentRepo.FindByChargeIdempotencyKey(ctx, merchantAccountID, key)
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find payment by idempotency key: %w", err)
+	}
+	return payment, nil
+}
+
+// keyedPayment returns the charge that took the idempotency key from a concurrent one. The
+// unique index only reports a duplicate once that charge has committed, so it must exist.
+func (s *paymentService) keyedPayment(ctx context.Context, merchantAccountID uuid.UUID, key string) (*model.Payment, error) {
+	payment, err := s.findKeyedPayment(ctx, merchantAccountID, key)
+	if err == nil && payment == nil {
+		err = errors.ErrDuplicateIdempotencyKey
+	}
+	return payment, err
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"paytabs/internal/model"
+	"paytabs/internal/notify"
+)
+
+const (
+	paymentNotifyQueueSize   = 100
+	paymentNotifyMaxAttempts = 3
+	paymentNotifyRetryDelay  = 2 * time.Second
+)
+
+// paymentNotification is a queued email about an accepted payment.
+type paymentNotification struct {
+	to      string
+	subject string
+	body    string
+}
+
+// PaymentNotifier emails merchants about accepted payments from a background worker,
+// so slow or failing mail delivery never affects the payment itself. A nil
+// *PaymentNotifier is valid and sends nothing.
+type PaymentNotifier struct {
+	email      notify.EmailNotifier
+	queue      chan paymentNotification
+	retryDelay time.Duration
+	validator  *CardValidator
+}
+
+// NewPaymentNotifier creates a notifier that delivers through email. Call Run to start it.
+func NewPaymentNotifier(email notify.EmailNotifier) *PaymentNotifier {
+	return &PaymentNotifier{
+		email:      email,
+		queue:      make(chan paymentNotification, paymentNotifyQueueSize),
+		retryDelay: paymentNotifyRetryDelay,
+		validator:  NewCardValidator(),
+	}
+}
+
+// QueueDepth reports how many notifications are waiting for delivery.
+func (n *PaymentNotifier) QueueDepth() (length, capacity int) {
+	if n == nil {
+		return 0, 0
+	}
+	return len(n.queue), cap(n.queue)
+}
+
+// Run delivers queued notifications until ctx is cancelled.
+func (n *PaymentNotifier) Run(ctx context.Context) {
+	for {
+		select {
+		case msg := <-n.queue:
+			n.deliver(ctx, msg)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// NotifyAccepted queues an email to the merchant about an accepted payment if the
+// merchant opted in. It never blocks: when the queue is full the email is dropped and logged.
+func (n *PaymentNotifier) NotifyAccepted(merchant *model.Account, card *model.Card, payment *model.Payment) {
+	if n == nil || !merchant.NotifyOnPayment {
+		return
+	}
+
+	msg := paymentNotification{
+		to:      merchant.Email,
+		subject: fmt.Sprintf("Payment received: %s", payment.Amount.StringFixed(2)),
+		body: fmt.Sprintf(
+			"A payment was accepted.\n\nPayment ID: %s\nCard: %s\nAmount: %s\nFee: %s\nNet credited: %s\n",
+			payment.ID,
+			n.validator.MaskCardNumber(card.CardNumber),
+			payment.Amount.StringFixed(2),
+			payment.FeeAmount.StringFixed(2),
+			payment.NetAmount.StringFixed(2),
+		),
+	}
+
+	select {
+	case n.queue <- msg:
+	default:
+		log.Printf("payment notification dropped (payment=%s): queue full", payment.ID)
+	}
+}
+
+// deliver sends one notification, retrying with a growing delay before giving up.
+func (n *PaymentNotifier) deliver(ctx context.Context, msg paymentNotification) {
+	delay := n.retryDelay
+	for attempt := 1; ; attempt++ {
+		err := n.email.Send(ctx, msg.to, msg.subject, msg.body)
+		if err == nil {
+			return
+		}
+		if attempt >= paymentNotifyMaxAttempts {
+			log.Printf("payment notification to %s failed after %d attempts: %v", msg.to, attempt, err)
+			return
+		}
+		log.Printf("payment notification to %s failed (attempt %d), retrying: %v", msg.to, attempt, err)
+
+		select {
+		case <-time.After(delay):
+			delay *= 2
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"paytabs/internal/config"
+	"paytabs/internal/model"
+)
+
+// fakeEmail records sent emails, failing the first `failures` attempts.
+type fakeEmail struct {
+	mu       sync.Mutex
+	failures int
+	attempts int
+	sent     []paymentNotification
+	done     chan struct{}
+}
+
+func newFakeEmail(failures int) *fakeEmail {
+	return &fakeEmail{failures: failures, done: make(chan struct{}, 10)}
+}
+
+func (f *fakeEmail) Send(ctx context.Context, to, subject, body string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.attempts++
+	if f.attempts <= f.failures {
+		return stderrors.New("smtp unavailable")
+	}
+	f.sent = append(f.sent, paymentNotification{to: to, subject: subject, body: body})
+	f.done <- struct{}{}
+	return nil
+}
+
+func startNotifier(t *testing.T, email *fakeEmail) *PaymentNotifier {
+	t.Helper()
+	n := NewPaymentNotifier(email)
+	n.retryDelay = time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go n.Run(ctx)
+	return n
+}
+
+func TestPaymentNotifier_NotifyAccepted(t *testing.T) {
+	email := newFakeEmail(2)
+	n := startNotifier(t, email)
+
+	merchant := &model.Account{Email: "shop@example.com", NotifyOnPayment: true}
+	card := &model.Card{CardNumber: "4111111111111111"}
+	payment := &model.Payment{ID: uuid.New(), Amount: decimal.RequireFromString("25"), FeeAmount: decimal.RequireFromString("1"), NetAmount: decimal.RequireFromString("24")}
+
+	n.NotifyAccepted(merchant, card, payment)
+
+	select {
+	case <-email.done:
+	case <-time.After(time.Second):
+		t.Fatal("notification was not delivered")
+	}
+
+	email.mu.Lock()
+	defer email.mu.Unlock()
+	// Delivered on the third attempt after two failures
+	assert.Equal(t, 3, email.attempts)
+	require.Len(t, email.sent, 1)
+	assert.Equal(t, "shop@example.com", email.sent[0].to)
+	assert.Contains(t, email.sent[0].subject, "25.00")
+	assert.Contains(t, email.sent[0].body, "****1111")
+	assert.NotContains(t, email.sent[0].body, "4111111111111111")
+}
+
+func TestPaymentNotifier_SkipsMerchantsWhoDidNotOptIn(t *testing.T) {
+	n := NewPaymentNotifier(newFakeEmail(0))
+
+	n.NotifyAccepted(&model.Account{Email: "shop@example.com"}, &model.Card{}, &model.Payment{})
+
+	assert.Empty(t, n.queue)
+}
+
+func TestPaymentService_NotificationFailureDoesNotFailPayment(t *testing.T) {
+	merchant := &model.Account{ID: uuid.New(), Email: "shop@example.com", Active: true, IsMerchant: true, NotifyOnPayment: true}
+	card := &model.Card{ID: uuid.New(), AccountID: uuid.New(), Balance: decimal.RequireFromString("100.00"), Active: true}
+
+	d := newPaymentTestDeps(merchant, card)
+	d.cardRepo.On("UpdateBalanceTx", mock.Anything, mock.Anything, card.ID, mock.Anything).Return(nil)
+	d.cardRepo.On("AddLedgerEntryTx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	d.accountRepo.On("CreditBalanceTx", mock.Anything, mock.Anything, merchant.ID, mock.Anything).Return(nil)
+
+	email := newFakeEmail(paymentNotifyMaxAttempts)
+	notifier := startNotifier(t, email)
+	svc := NewPaymentService(d.accountRepo, d.cardRepo, d.paymentRepo, d.logRepo, d.payoutRepo, &MockTxManager{}, notifier, nil, nil, &config.Config{})
+
+	payment, err := svc.ProcessCardPayment(context.Background(), merchant.ID, card.ID, decimal.RequireFromString("10.00"), "", "")
+
+	require.NoError(t, err)
+	assert.Equal(t, model.PaymentStatusAccepted, payment.Status)
+	assert.Eventually(t, func() bool {
+		email.mu.Lock()
+		defer email.mu.Unlock()
+		return email.attempts == paymentNotifyMaxAttempts
+	}, time.Second, 5*time.Millisecond)
+}
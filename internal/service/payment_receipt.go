@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"paytabs/internal/model"
+)
+
+// ReceiptSignatureAlgorithm names how PaymentReceipt.Signature is computed.
+const ReceiptSignatureAlgorithm = "HMAC-SHA256"
+
+// PaymentReceipt is the merchant-facing record of one payment, signed so that a copy handed
+// to a customer can be checked against the platform.
+type PaymentReceipt struct {
+	PaymentID         uuid.UUID
+	MerchantAccountID uuid.UUID
+	MerchantName      string
+	CardNumber        string // Masked as stored; empty if the card no longer exists
+	Currency          string
+	Amount            decimal.Decimal
+	GrossAmount       decimal.Decimal
+	FeeAmount         decimal.Decimal
+	NetAmount         decimal.Decimal
+	Status            model.PaymentStatus
+	TestMode          bool
+	CreatedAt         time.Time
+	Signature         string // Hex HMAC-SHA256 of SignedPayload under RECEIPT_SIGNING_KEY
+}
+
+// SignedPayload is the canonical text the signature covers: one key=value line per field, in
+// this order, with amounts at two decimals and the timestamp in RFC 3339 UTC.
+func (r *PaymentReceipt) SignedPayload() string {
+	lines := []string{
+		"payment_id=" + r.PaymentID.String(),
+		"merchant_account_id=" + r.MerchantAccountID.String(),
+		"merchant_name=" + r.MerchantName,
+		"card_number=" + r.CardNumber,
+		"currency=" + r.Currency,
+		"amount=" + r.Amount.StringFixed(2),
+		"gross_amount=" + r.GrossAmount.StringFixed(2),
+		"fee_amount=" + r.FeeAmount.StringFixed(2),
+		"net_amount=" + r.NetAmount.StringFixed(2),
+		"status=" + string(r.Status),
+		"test_mode=" + strconv.FormatBool(r.TestMode),
+		"created_at=" + r.CreatedAt.UTC().Format(time.RFC3339),
+	}
+	return strings.Join(lines, "\n")
+}
+
+// signReceipt returns the hex HMAC-SHA256 of the receipt's signed payload under key.
+func signReceipt(key string, r *PaymentReceipt) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(r.SignedPayload()))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyReceipt reports whether signature is the receipt's signature under key.
+func VerifyReceipt(key string, r *PaymentReceipt, signature string) bool {
+	return hmac.Equal([]byte(signReceipt(key, r)), []byte(signature))
+}
+
+// GetPaymentReceipt builds the signed receipt of one of the merchant's payments.
+func (s *paymentService) GetPaymentReceipt(ctx context.Context, merchantAccountID uuid.UUID, paymentID uuid.UUID) (*PaymentReceipt, error) {
+	if s.cfg.ReceiptSigningKey == "" {
+		return nil, ErrReceiptsDisabled
+	}
+
+	payment, err := s.GetPayment(ctx, merchantAccountID, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	merchant, err := s.accountRepo.FindByID(ctx, payment.MerchantAccountID)
+	if err != nil {
+		return nil, fmt.Errorf("get merchant: %w", err)
+	}
+
+	receipt := &PaymentReceipt{
+		PaymentID:         payment.ID,
+		MerchantAccountID: payment.MerchantAccountID,
+		MerchantName:      merchant.Name,
+		Currency:          heldCurrency(merchant.Currency, s.currency).Code,
+		Amount:            payment.Amount,
+		GrossAmount:       payment.GrossAmount,
+		FeeAmount:         payment.FeeAmount,
+		NetAmount:         payment.NetAmount,
+		Status:            payment.Status,
+		TestMode:          payment.TestMode,
+		CreatedAt:         payment.CreatedAt,
+	}
+
+	card, err := s.cardRepo.FindByID(ctx, payment.CardID)
+	switch {
+	case err == nil:
+		receipt.CardNumber = card.CardNumber
+		if card.Currency != "" {
+			receipt.Currency = card.Currency
+		}
+	case err != gorm.ErrRecordNotFound:
+		return nil, fmt.Errorf("get card: %w", err)
+	}
+
+	receipt.Signature = signReceipt(s.cfg.ReceiptSigningKey, receipt)
+	return receipt, nil
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"paytabs/internal/config"
+	"paytabs/internal/errors"
+	"paytabs/internal/model"
+)
+
+func TestPaymentService_GetPaymentReceipt(t *testing.T) {
+	merchant := &model.Account{ID: uuid.New(), Name: "Café Nord", IsMerchant: true, Active: true}
+	card := &model.Card{ID: uuid.New(), CardNumber: "**** **** **** 4242", Currency: "EUR"}
+	payment := &model.Payment{
+		ID:                uuid.New(),
+		MerchantAccountID: merchant.ID,
+		CardID:            card.ID,
+		Amount:            decimal.RequireFromString("100"),
+		GrossAmount:       decimal.RequireFromString("100"),
+		FeeAmount:         decimal.RequireFromString("3.2"),
+		NetAmount:         decimal.RequireFromString("96.8"),
+		Status:            model.PaymentStatusAccepted,
+		CreatedAt:         time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+	}
+	cfg := &config.Config{ReceiptSigningKey: "receipt-key"}
+
+	d := newPaymentTestDeps(merchant, card)
+	d.paymentRepo.On("FindByID", mock.Anything, payment.ID).Return(payment, nil)
+	d.cardRepo.On("FindByID", mock.Anything, card.ID).Return(card, nil)
+
+	receipt, err := d.service(cfg).GetPaymentReceipt(context.Background(), merchant.ID, payment.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, "Café Nord", receipt.MerchantName)
+	assert.Equal(t, "**** **** **** 4242", receipt.CardNumber)
+	assert.Equal(t, "EUR", receipt.Currency)
+	assert.Contains(t, receipt.SignedPayload(), "\nnet_amount=96.80\n")
+	assert.True(t, VerifyReceipt("receipt-key", receipt, receipt.Signature))
+	assert.False(t, VerifyReceipt("other-key", receipt, receipt.Signature))
+
+	tampered := *receipt
+	tampered.NetAmount = decimal.RequireFromString("196.80")
+	assert.False(t, VerifyReceipt("receipt-key", &tampered, receipt.Signature), "any changed field breaks the signature")
+}
+
+func TestPaymentService_GetPaymentReceipt_Errors(t *testing.T) {
+	payment := &model.Payment{ID: uuid.New(), MerchantAccountID: uuid.New()}
+
+	t.Run("no signing key", func(t *testing.T) {
+		d := &paymentTestDeps{paymentRepo: new(MockPaymentRepository), logRepo: new(MockPaymentLogRepository)}
+
+		_, err := d.service(&config.Config{}).GetPaymentReceipt(context.Background(), payment.MerchantAccountID, payment.ID)
+
+		assert.Equal(t, ErrReceiptsDisabled, err)
+		d.paymentRepo.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("other merchant", func(t *testing.T) {
+		d := &paymentTestDeps{paymentRepo: new(MockPaymentRepository), logRepo: new(MockPaymentLogRepository)}
+		d.paymentRepo.On("FindByID", mock.Anything, payment.ID).Return(payment, nil)
+
+		_, err := d.service(&config.Config{ReceiptSigningKey: "receipt-key"}).GetPaymentReceipt(context.Background(), uuid.New(), payment.ID)
+
+		assert.Equal(t, errors.ErrPaymentNotFound, err)
+	})
+}
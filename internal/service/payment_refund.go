@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"paytabs/internal/currency"
+	"paytabs/internal/errors"
+	"paytabs/internal/model"
+)
+
+// RefundPayment refunds one of the merchant's accepted payments, in full or in part, as a new
+// payment in status refunded linked to the original. A zero amount refunds whatever is left
+// of the original. Refunds together never exceed the original amount: the card's row lock,
+// taken before the earlier refunds are summed, serializes refunds of the same payment across
+// instances. The card is credited and the merchant debited the refunded amount in one
+// transaction; processing fees are not returned. The merchant must be able to cover the refund
+// from its balance less pending payouts and the payout reserve, checked under the merchant's
+// row lock as RequestPayout does, so a payout and a refund cannot together overdraw it.
+// Refunds of test-mode payments move no money.
+//
+// A non-empty idempotencyKey is stored on the refund. Repeating it for the same payment
+// returns the existing refund instead of refunding again; repeating it with a different
+// non-zero amount returns ErrRefundKeyReused. The lookup runs under the same card lock as the
+// refund, so concurrent retries cannot both refund.
+func (s *paymentService) RefundPayment(ctx context.Context, merchantAccountID uuid.UUID, paymentID uuid.UUID, amount decimal.Decimal, idempotencyKey string) (*model.Payment, error) {
+	if err := s.killSwitch.Check(ctx, OperationPayments); err != nil {
+		return nil, err
+	}
+	if amount.IsNegative() || !amount.Equal(currency.RoundToCurrency(amount, s.currency, currency.RoundDown)) {
+		return nil, errors.ErrInvalidAmount
+	}
+
+	original, err := s.GetPayment(ctx, merchantAccountID, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if original.Status != model.PaymentStatusAccepted {
+		return nil, ErrPaymentNotRefundable
+	}
+
+	var reserve currency.Money
+	if !original.TestMode {
+		reserve, err = merchantReserve(ctx, s.paymentRepo, s.cfg, s.clock, s.currency, merchantAccountID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	mutex := s.getMutex(original.CardID)
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	refund := &model.Payment{
+		ID:                uuid.New(),
+		MerchantAccountID: merchantAccountID,
+		CardID:            original.CardID,
+		Status:            model.PaymentStatusRefunded,
+		RefundOfID:        &original.ID,
+		TestMode:          original.TestMode,
+	}
+	if idempotencyKey != "" {
+		refund.IdempotencyKey = &idempotencyKey
+	}
+	var card *model.Card
+	var merchant *model.Account
+	var existing *model.Payment
+	err = s.txManager.WithTransaction(ctx, func(ctx context.Context, tx interface{}) error {
+		var err error
+		card, err = s.cardRepo.FindByIDForUpdateTx(ctx, tx, original.CardID)
+		if err != nil {
+			return err
+		}
+		if idempotencyKey != "" {
+			existing, err = s.paymentRepo.FindRefundByIdempotencyKeyTx(ctx, tx, original.ID, idempotencyKey)
+			if err == nil {
+				return nil
+			}
+			if err != gorm.ErrRecordNotFound {
+				return fmt.Errorf("find refund by idempotency key: %w", err)
+			}
+			existing = nil
+		}
+		refunded, err := s.paymentRepo.SumRefundsTx(ctx, tx, original.ID)
+		if err != nil {
+			return fmt.Errorf("sum refunds: %w", err)
+		}
+
+		remaining := original.Amount.Sub(refunded)
+		refundAmount := amount
+		if refundAmount.IsZero() {
+			refundAmount = remaining
+		}
+		if !refundAmount.IsPositive() || refundAmount.GreaterThan(remaining) {
+			return ErrRefundExceedsOriginal
+		}
+		refund.Amount = refundAmount
+		refund.GrossAmount = refundAmount
+		refund.NetAmount = refundAmount
+
+		if !refund.TestMode {
+			merchant, err = s.accountRepo.FindByIDForUpdateTx(ctx, tx, merchantAccountID)
+			if err != nil {
+				return err
+			}
+			if err := checkSameCurrency(card.Currency, merchant.Currency, s.currency); err != nil {
+				return err
+			}
+			pending, err := s.payoutRepo.SumPendingByMerchantTx(ctx, tx, merchantAccountID)
+			if err != nil {
+				return fmt.Errorf("sum pending payouts: %w", err)
+			}
+			available := currency.New(merchant.Balance, s.currency).Sub(currency.New(pending, s.currency)).Sub(reserve)
+			if currency.New(refundAmount, s.currency).GreaterThan(available) {
+				return errors.ErrInsufficientBalance
+			}
+			if err := s.accountRepo.CreditBalanceTx(ctx, tx, merchantAccountID, refundAmount.Neg()); err != nil {
+				return err
+			}
+
+			newBalance := card.Balance.Add(refundAmount)
+			if err := s.cardRepo.UpdateBalanceTx(ctx, tx, card.ID, newBalance); err != nil {
+				return err
+			}
+			if err := s.cardRepo.AddLedgerEntryTx(ctx, tx, &model.LedgerEntry{
+				CardID:       card.ID,
+				Delta:        refundAmount,
+				BalanceAfter: newBalance,
+				Reason:       model.LedgerReasonRefund,
+				ReferenceID:  refund.ID,
+			}); err != nil {
+				return err
+			}
+		}
+		return s.paymentRepo.CreateTx(ctx, tx, refund)
+	})
+	if err == ErrRefundExceedsOriginal || err == errors.ErrInsufficientBalance || err == errors.ErrCurrencyMismatch {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("refund payment: %w", err)
+	}
+	if existing != nil {
+		if !amount.IsZero() && !amount.Equal(existing.Amount) {
+			return nil, ErrRefundKeyReused
+		}
+		return existing, nil
+	}
+
+	if !refund.TestMode {
+		_ = s.cache.Delete(ctx, fmt.Sprintf("card:%s", card.ID.String()))
+		invalidateWallets(ctx, s.cache, card.AccountID, merchantAccountID)
+		if s.alerts.Watches(merchant) {
+			s.alerts.BalanceChanged(merchant, merchant.Balance, merchant.Balance.Sub(refund.Amount))
+		}
+	}
+	s.logPayment(ctx, refund.ID, model.PaymentStatusRefunded, fmt.Sprintf("refund of payment %s", original.ID))
+	return refund, nil
+}
@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"paytabs/internal/config"
+	"paytabs/internal/errors"
+	"paytabs/internal/model"
+)
+
+// newRefundTestDeps mocks an accepted payment of 100.00 from card to merchant that has already
+// been refunded prior. The merchant has no pending payouts.
+func newRefundTestDeps(merchant *model.Account, card *model.Card, prior string) (*paymentTestDeps, *model.Payment) {
+	original := &model.Payment{
+		ID:                uuid.New(),
+		MerchantAccountID: merchant.ID,
+		CardID:            card.ID,
+		Amount:            decimal.RequireFromString("100.00"),
+		GrossAmount:       decimal.RequireFromString("100.00"),
+		FeeAmount:         decimal.RequireFromString("3.20"),
+		NetAmount:         decimal.RequireFromString("96.80"),
+		Status:            model.PaymentStatusAccepted,
+	}
+	d := &paymentTestDeps{
+		accountRepo: new(MockAccountRepository),
+		cardRepo:    new(MockCardRepository),
+		paymentRepo: new(MockPaymentRepository),
+		logRepo:     new(MockPaymentLogRepository),
+		payoutRepo:  new(MockPayoutRepository),
+	}
+	d.paymentRepo.On("FindByID", mock.Anything, original.ID).Return(original, nil)
+	d.payoutRepo.On("SumPendingByMerchantTx", mock.Anything, mock.Anything, merchant.ID).Return(decimal.Zero, nil).Maybe()
+	d.paymentRepo.On("SumRefundsTx", mock.Anything, mock.Anything, original.ID).Return(decimal.RequireFromString(prior), nil)
+	d.cardRepo.On("FindByIDForUpdateTx", mock.Anything, mock.Anything, card.ID).Return(card, nil)
+	d.accountRepo.On("FindByIDForUpdateTx", mock.Anything, mock.Anything, merchant.ID).Return(merchant, nil).Maybe()
+	d.logRepo.On("CreateBatch", mock.Anything, mock.Anything).Return(nil).Maybe()
+	d.logRepo.On("Create", mock.Anything, mock.Anything).Return(nil).Maybe()
+	return d, original
+}
+
+func TestPaymentService_RefundPayment(t *testing.T) {
+	tests := []struct {
+		name          string
+		prior         string
+		amount        string
+		wantRefund    string
+		wantCardAfter string
+	}{
+		{name: "full refund", prior: "0", amount: "0", wantRefund: "100.00", wantCardAfter: "500.00"},
+		{name: "full refund of the remainder", prior: "30.00", amount: "0", wantRefund: "70.00", wantCardAfter: "470.00"},
+		{name: "partial refund", prior: "0", amount: "40.00", wantRefund: "40.00", wantCardAfter: "440.00"},
+		{name: "partial refund up to the original", prior: "60.00", amount: "40.00", wantRefund: "40.00", wantCardAfter: "440.00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merchant := &model.Account{ID: uuid.New(), Active: true, IsMerchant: true, Balance: decimal.RequireFromString("1000.00")}
+			card := &model.Card{ID: uuid.New(), AccountID: uuid.New(), Balance: decimal.RequireFromString("400.00"), Active: true}
+			d, original := newRefundTestDeps(merchant, card, tt.prior)
+
+			var ledger *model.LedgerEntry
+			d.cardRepo.On("UpdateBalanceTx", mock.Anything, mock.Anything, card.ID, decimalEq(tt.wantCardAfter)).Return(nil).Once()
+			d.cardRepo.On("AddLedgerEntryTx", mock.Anything, mock.Anything, mock.AnythingOfType("*model.LedgerEntry")).
+				Run(func(args mock.Arguments) { ledger = args.Get(2).(*model.LedgerEntry) }).Return(nil).Once()
+			d.accountRepo.On("CreditBalanceTx", mock.Anything, mock.Anything, merchant.ID, decimalEq("-"+tt.wantRefund)).Return(nil).Once()
+			d.paymentRepo.On("CreateTx", mock.Anything, mock.Anything, mock.AnythingOfType("*model.Payment")).Return(nil).Once()
+			txm := &recordingTxManager{}
+			svc := NewPaymentService(d.accountRepo, d.cardRepo, d.paymentRepo, d.logRepo, d.payoutRepo, txm, nil, nil, nil, &config.Config{})
+
+			refund, err := svc.RefundPayment(context.Background(), merchant.ID, original.ID, decimal.RequireFromString(tt.amount), "")
+
+			require.NoError(t, err)
+			assert.Equal(t, model.PaymentStatusRefunded, refund.Status)
+			assert.Equal(t, tt.wantRefund, refund.Amount.StringFixed(2))
+			assert.Equal(t, tt.wantRefund, refund.NetAmount.StringFixed(2))
+			assert.True(t, refund.FeeAmount.IsZero(), "fees are not refunded")
+			require.NotNil(t, refund.RefundOfID)
+			assert.Equal(t, original.ID, *refund.RefundOfID)
+			assert.Equal(t, card.ID, refund.CardID)
+			require.NotNil(t, ledger)
+			assert.Equal(t, model.LedgerReasonRefund, ledger.Reason)
+			assert.Equal(t, tt.wantRefund, ledger.Delta.StringFixed(2))
+			assert.Equal(t, refund.ID, ledger.ReferenceID)
+			assert.Equal(t, 1, txm.committed)
+			d.cardRepo.AssertExpectations(t)
+			d.accountRepo.AssertExpectations(t)
+			d.paymentRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPaymentService_RefundPayment_RepeatedIdempotencyKey(t *testing.T) {
+	merchant := &model.Account{ID: uuid.New(), Active: true, IsMerchant: true, Balance: decimal.RequireFromString("1000.00")}
+	card := &model.Card{ID: uuid.New(), AccountID: uuid.New(), Balance: decimal.RequireFromString("400.00"), Active: true}
+	d, original := newRefundTestDeps(merchant, card, "0")
+
+	var stored *model.Payment
+	d.paymentRepo.On("FindRefundByIdempotencyKeyTx", mock.Anything, mock.Anything, original.ID, "refund-1").Return(nil, gorm.ErrRecordNotFound).Once()
+	d.paymentRepo.On("CreateTx", mock.Anything, mock.Anything, mock.AnythingOfType("*model.Payment")).
+		Run(func(args mock.Arguments) { stored = args.Get(2).(*model.Payment) }).Return(nil).Once()
+	d.cardRepo.On("UpdateBalanceTx", mock.Anything, mock.Anything, card.ID, decimalEq("440.00")).
+		Run(func(args mock.Arguments) { card.Balance = args.Get(3).(decimal.Decimal) }).Return(nil).Once()
+	d.cardRepo.On("AddLedgerEntryTx", mock.Anything, mock.Anything, mock.AnythingOfType("*model.LedgerEntry")).Return(nil).Once()
+	d.accountRepo.On("CreditBalanceTx", mock.Anything, mock.Anything, merchant.ID, decimalEq("-40.00")).Return(nil).Once()
+	svc := d.service(&config.Config{})
+
+	first, err := svc.RefundPayment(context.Background(), merchant.ID, original.ID, decimal.RequireFromString("40.00"), "refund-1")
+	require.NoError(t, err)
+	assert.Equal(t, "440", card.Balance.String())
+	require.NotNil(t, stored.IdempotencyKey)
+	assert.Equal(t, "refund-1", *stored.IdempotencyKey, "the key is stored on the refund")
+
+	// The retry finds the refund stored with its key
+	d.paymentRepo.On("FindRefundByIdempotencyKeyTx", mock.Anything, mock.Anything, original.ID, "refund-1").Return(stored, nil)
+	second, err := svc.RefundPayment(context.Background(), merchant.ID, original.ID, decimal.RequireFromString("40.00"), "refund-1")
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, second.ID)
+	assert.Equal(t, "440", card.Balance.String(), "the retry did not credit the card again")
+
+	// A retry without an amount asks for the rest of the original, which the key already refunded
+	third, err := svc.RefundPayment(context.Background(), merchant.ID, original.ID, decimal.Zero, "refund-1")
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, third.ID)
+
+	_, err = svc.RefundPayment(context.Background(), merchant.ID, original.ID, decimal.RequireFromString("25.00"), "refund-1")
+	assert.Equal(t, ErrRefundKeyReused, err)
+
+	// The card was credited, and the merchant debited, once
+	d.cardRepo.AssertNumberOfCalls(t, "UpdateBalanceTx", 1)
+	d.accountRepo.AssertNumberOfCalls(t, "CreditBalanceTx", 1)
+	d.paymentRepo.AssertNumberOfCalls(t, "CreateTx", 1)
+}
+
+func TestPaymentService_RefundPayment_ExceedsOriginal(t *testing.T) {
+	tests := []struct {
+		name   string
+		prior  string
+		amount string
+	}{
+		{name: "more than the payment", prior: "0", amount: "100.01"},
+		{name: "more than the remainder", prior: "80.00", amount: "30.00"},
+		{name: "fully refunded already", prior: "100.00", amount: "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merchant := &model.Account{ID: uuid.New(), Active: true, IsMerchant: true, Balance: decimal.RequireFromString("1000.00")}
+			card := &model.Card{ID: uuid.New(), AccountID: uuid.New(), Balance: decimal.RequireFromString("400.00"), Active: true}
+			d, original := newRefundTestDeps(merchant, card, tt.prior)
+			txm := &recordingTxManager{}
+			svc := NewPaymentService(d.accountRepo, d.cardRepo, d.paymentRepo, d.logRepo, d.payoutRepo, txm, nil, nil, nil, &config.Config{})
+
+			_, err := svc.RefundPayment(context.Background(), merchant.ID, original.ID, decimal.RequireFromString(tt.amount), "")
+
+			assert.Equal(t, ErrRefundExceedsOriginal, err)
+			assert.Equal(t, 1, txm.rolledBack)
+			d.cardRepo.AssertNotCalled(t, "UpdateBalanceTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			d.accountRepo.AssertNotCalled(t, "CreditBalanceTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			d.paymentRepo.AssertNotCalled(t, "CreateTx", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestPaymentService_RefundPayment_MerchantBalanceTooLow(t *testing.T) {
+	merchant := &model.Account{ID: uuid.New(), Active: true, IsMerchant: true, Balance: decimal.RequireFromString("20.00")}
+	card := &model.Card{ID: uuid.New(), AccountID: uuid.New(), Balance: decimal.RequireFromString("400.00"), Active: true}
+	d, original := newRefundTestDeps(merchant, card, "0")
+
+	_, err := d.service(&config.Config{}).RefundPayment(context.Background(), merchant.ID, original.ID, decimal.RequireFromString("50.00"), "")
+
+	assert.Equal(t, errors.ErrInsufficientBalance, err)
+	d.cardRepo.AssertNotCalled(t, "UpdateBalanceTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	d.paymentRepo.AssertNotCalled(t, "CreateTx", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPaymentService_RefundPayment_ExceedsAvailableFunds(t *testing.T) {
+	tests := []struct {
+		name    string
+		pending string
+		reserve string
+		wantErr error
+	}{
+		{name: "covered", pending: "900.00", reserve: "0"},
+		{name: "pending payouts", pending: "960.00", reserve: "0", wantErr: errors.ErrInsufficientBalance},
+		{name: "reserve", pending: "900.00", reserve: "10.00", wantErr: errors.ErrInsufficientBalance},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merchant := &model.Account{ID: uuid.New(), Active: true, IsMerchant: true, Balance: decimal.RequireFromString("1000.00")}
+			card := &model.Card{ID: uuid.New(), AccountID: uuid.New(), Balance: decimal.RequireFromString("400.00"), Active: true}
+			d, original := newRefundTestDeps(merchant, card, "0")
+			// A payout requested before the refund still holds its share of the balance
+			d.payoutRepo = new(MockPayoutRepository)
+			d.payoutRepo.On("SumPendingByMerchantTx", mock.Anything, mock.Anything, merchant.ID).Return(decimal.RequireFromString(tt.pending), nil)
+			d.cardRepo.On("UpdateBalanceTx", mock.Anything, mock.Anything, card.ID, mock.Anything).Return(nil).Maybe()
+			d.cardRepo.On("AddLedgerEntryTx", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+			d.accountRepo.On("CreditBalanceTx", mock.Anything, mock.Anything, merchant.ID, mock.Anything).Return(nil).Maybe()
+			d.paymentRepo.On("CreateTx", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+
+			cfg := &config.Config{PayoutReserveFixed: decimal.RequireFromString(tt.reserve)}
+			_, err := d.service(cfg).RefundPayment(context.Background(), merchant.ID, original.ID, decimal.RequireFromString("100.00"), "")
+
+			if tt.wantErr != nil {
+				assert.Equal(t, tt.wantErr, err)
+				d.accountRepo.AssertNotCalled(t, "CreditBalanceTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestPaymentService_RefundPayment_CurrencyMismatch(t *testing.T) {
+	merchant := &model.Account{ID: uuid.New(), Active: true, IsMerchant: true, Balance: decimal.RequireFromString("1000.00"), Currency: "EUR"}
+	card := &model.Card{ID: uuid.New(), AccountID: uuid.New(), Balance: decimal.RequireFromString("400.00"), Active: true, Currency: "USD"}
+	d, original := newRefundTestDeps(merchant, card, "0")
+
+	_, err := d.service(&config.Config{DefaultCurrency: "USD"}).RefundPayment(context.Background(), merchant.ID, original.ID, decimal.Zero, "")
+
+	assert.Equal(t, errors.ErrCurrencyMismatch, err)
+	d.accountRepo.AssertNotCalled(t, "CreditBalanceTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	d.cardRepo.AssertNotCalled(t, "UpdateBalanceTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPaymentService_RefundPayment_TestModeMovesNoMoney(t *testing.T) {
+	merchant := &model.Account{ID: uuid.New(), Active: true, IsMerchant: true, TestMode: true}
+	card := &model.Card{ID: uuid.New(), AccountID: uuid.New(), Balance: decimal.RequireFromString("400.00"), Active: true}
+	d, original := newRefundTestDeps(merchant, card, "0")
+	original.TestMode = true
+	d.paymentRepo.On("CreateTx", mock.Anything, mock.Anything, mock.AnythingOfType("*model.Payment")).Return(nil).Once()
+
+	refund, err := d.service(&config.Config{}).RefundPayment(context.Background(), merchant.ID, original.ID, decimal.Zero, "")
+
+	require.NoError(t, err)
+	assert.True(t, refund.TestMode)
+	assert.Equal(t, "100.00", refund.Amount.StringFixed(2))
+	d.cardRepo.AssertNotCalled(t, "UpdateBalanceTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	d.accountRepo.AssertNotCalled(t, "CreditBalanceTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPaymentService_RefundPayment_Rejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  model.PaymentStatus
+		amount  string
+		wantErr error
+	}{
+		{name: "pending", status: model.PaymentStatusPending, amount: "10.00", wantErr: ErrPaymentNotRefundable},
+		{name: "failed", status: model.PaymentStatusFailed, amount: "10.00", wantErr: ErrPaymentNotRefundable},
+		{name: "cancelled", status: model.PaymentStatusCancelled, amount: "10.00", wantErr: ErrPaymentNotRefundable},
+		{name: "a refund", status: model.PaymentStatusRefunded, amount: "10.00", wantErr: ErrPaymentNotRefundable},
+		{name: "negative amount", status: model.PaymentStatusAccepted, amount: "-10.00", wantErr: errors.ErrInvalidAmount},
+		{name: "fractional cents", status: model.PaymentStatusAccepted, amount: "10.005", wantErr: errors.ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merchantID := uuid.New()
+			payment := &model.Payment{ID: uuid.New(), MerchantAccountID: merchantID, Amount: decimal.NewFromInt(100), Status: tt.status}
+			paymentRepo := new(MockPaymentRepository)
+			paymentRepo.On("FindByID", mock.Anything, payment.ID).Return(payment, nil).Maybe()
+			d := &paymentTestDeps{paymentRepo: paymentRepo, logRepo: new(MockPaymentLogRepository)}
+
+			_, err := d.service(&config.Config{}).RefundPayment(context.Background(), merchantID, payment.ID, decimal.RequireFromString(tt.amount), "")
+
+			assert.Equal(t, tt.wantErr, err)
+			paymentRepo.AssertNotCalled(t, "SumRefundsTx", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestPaymentService_RefundPayment_OtherMerchant(t *testing.T) {
+	payment := &model.Payment{ID: uuid.New(), MerchantAccountID: uuid.New(), Status: model.PaymentStatusAccepted}
+	paymentRepo := new(MockPaymentRepository)
+	paymentRepo.On("FindByID", mock.Anything, payment.ID).Return(payment, nil)
+	d := &paymentTestDeps{paymentRepo: paymentRepo, logRepo: new(MockPaymentLogRepository)}
+
+	_, err := d.service(&config.Config{}).RefundPayment(context.Background(), uuid.New(), payment.ID, decimal.Zero, "")
+
+	assert.Equal(t, errors.ErrPaymentNotFound, err)
+}
@@ -3,7 +3,9 @@ package service
 import (
 	"context"
 	"fmt"
+	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,6 +13,9 @@ import (
 	"gorm.io/gorm"
 
 	"paytabs/internal/cache"
+	"paytabs/internal/clock"
+	"paytabs/internal/config"
+	"paytabs/internal/currency"
 	"paytabs/internal/errors"
 	"paytabs/internal/model"
 	"paytabs/internal/repository"
@@ -18,7 +23,55 @@ import (
 
 // PaymentService handles payment processing operations.
 type PaymentService interface {
-	ProcessCardPayment(ctx context.Context, merchantAccountID uuid.UUID, cardID uuid.UUID, amount decimal.Decimal) (*model.Payment, error)
+	ProcessCardPayment(ctx context.Context, merchantAccountID uuid.UUID, cardID uuid.UUID, amount decimal.Decimal, reference, idempotencyKey string) (*model.Payment, error)
+	GetPayment(ctx context.Context, merchantAccountID uuid.UUID, paymentID uuid.UUID) (*model.Payment, error)
+	GetPaymentTimeline(ctx context.Context, merchantAccountID uuid.UUID, paymentID uuid.UUID) (*PaymentTimeline, error)
+	GetPaymentReceipt(ctx context.Context, merchantAccountID uuid.UUID, paymentID uuid.UUID) (*PaymentReceipt, error)
+	GetPaymentBreakdown(ctx context.Context, merchantAccountID uuid.UUID, paymentID uuid.UUID) (*PaymentBreakdown, error)
+	ListMerchantCustomers(ctx context.Context, merchantAccountID uuid.UUID, limit, offset int) ([]repository.MerchantCustomer, int64, error)
+	ListMerchantPayments(ctx context.Context, merchantAccountID uuid.UUID, filter repository.PaymentFilter, limit, offset int) ([]model.Payment, int64, error)
+	GetPendingSummary(ctx context.Context, merchantAccountID uuid.UUID) (*PendingPaymentsSummary, error)
+	RetryPayment(ctx context.Context, merchantAccountID uuid.UUID, paymentID uuid.UUID) (*model.Payment, error)
+	CancelPayment(ctx context.Context, merchantAccountID uuid.UUID, paymentID uuid.UUID) (*model.Payment, error)
+	RefundPayment(ctx context.Context, merchantAccountID uuid.UUID, paymentID uuid.UUID, amount decimal.Decimal, idempotencyKey string) (*model.Payment, error)
+	LogQueueDepth() (length, capacity int)
+	DroppedLogs() int64
+	ListAccountPayments(ctx context.Context, accountID uuid.UUID, includeArchived bool, limit, offset int) ([]model.Payment, int64, error)
+	ListCardPayments(ctx context.Context, cardID uuid.UUID, limit, offset int) (*CardPaymentPage, error)
+}
+
+// CardPaymentStatuses are the statuses shown in a card's payment history: settled charges
+// and attempts that were refused.
+var CardPaymentStatuses = []model.PaymentStatus{model.PaymentStatusAccepted, model.PaymentStatusFailed}
+
+// CardPaymentPage is a page of the payments made with a card.
+type CardPaymentPage struct {
+	Card     *model.Card
+	Payments []repository.CardPayment
+	Total    int64
+}
+
+// InFlightPaymentStatuses are the statuses of payments that have not settled yet.
+var InFlightPaymentStatuses = []model.PaymentStatus{model.PaymentStatusPending}
+
+// PendingPaymentsSummary is a merchant's in-flight payment volume, overall and per status.
+type PendingPaymentsSummary struct {
+	Count    int64
+	Total    decimal.Decimal
+	ByStatus []repository.PaymentStatusTotal
+}
+
+// PaymentEvent is one status transition in a payment's timeline.
+type PaymentEvent struct {
+	Status  model.PaymentStatus
+	Message string
+	At      time.Time
+}
+
+// PaymentTimeline is a payment with its status transitions, oldest first.
+type PaymentTimeline struct {
+	Payment *model.Payment
+	Events  []PaymentEvent
 }
 
 type paymentService struct {
@@ -26,11 +79,24 @@ type paymentService struct {
 	cardRepo       repository.CardRepository
 	paymentRepo    repository.PaymentRepository
 	paymentLogRepo repository.PaymentLogRepository
+	payoutRepo     repository.PayoutRepository
+	txManager      repository.TxManager
 	cache          *cache.Client
-	// Mutex map for per-card locking
-	cardMutexes sync.Map
+	clock          clock.Clock
+	cfg            *config.Config
+	currency       currency.Currency
+	rounding       currency.RoundingMode
+	dailySpend     *dailySpendTracker
+	killSwitch     *killSwitch
+	holds          *accountHolds
+	notifier       *PaymentNotifier
+	alerts         *BalanceAlerter
+	// Striped mutexes for per-card locking
+	cardLocks cardLocks
 	// Channel for async payment logging
 	logChannel chan model.PaymentLog
+	// droppedLogs counts payment logs discarded by the overflow policy
+	droppedLogs atomic.Int64
 }
 
 // NewPaymentService creates a new payment service.
@@ -39,28 +105,53 @@ func NewPaymentService(
 	cardRepo repository.CardRepository,
 	paymentRepo repository.PaymentRepository,
 	paymentLogRepo repository.PaymentLogRepository,
+	payoutRepo repository.PayoutRepository,
+	txManager repository.TxManager,
+	notifier *PaymentNotifier,
+	alerts *BalanceAlerter,
 	cache *cache.Client,
+	cfg *config.Config,
 ) PaymentService {
+	cur, rounding := moneyRounding(cfg)
 	service := &paymentService{
 		accountRepo:    accountRepo,
 		cardRepo:       cardRepo,
 		paymentRepo:    paymentRepo,
 		paymentLogRepo: paymentLogRepo,
+		payoutRepo:     payoutRepo,
+		txManager:      txManager,
+		notifier:       notifier,
+		alerts:         alerts,
 		cache:          cache,
+		clock:          clock.New(),
+		cfg:            cfg,
+		currency:       cur,
+		rounding:       rounding,
+		dailySpend:     newDailySpendTracker(cache, cardRepo, clock.New(), cfg.MaxDailyCardSpend),
+		killSwitch:     newKillSwitch(cache, cfg),
+		holds:          newAccountHolds(cache, clock.New(), cfg),
 		logChannel:     make(chan model.PaymentLog, 100),
 	}
 
-	// Start async log worker
-	go service.logWorker(context.Background())
+	// Start async log worker, restarted should a log ever make it panic
+	go SuperviseWorker(context.Background(), "payment_logs", cfg.WorkerRestartDelay, service.logWorker)
 
 	return service
 }
 
-// getMutex returns a mutex for a specific card ID.
+// getMutex returns the mutex serializing payments of a specific card ID.
 func (s *paymentService) getMutex(cardID uuid.UUID) *sync.Mutex {
-	key := cardID.String()
-	value, _ := s.cardMutexes.LoadOrStore(key, &sync.Mutex{})
-	return value.(*sync.Mutex)
+	return s.cardLocks.get(cardID)
+}
+
+// LogQueueDepth reports how many payment logs are waiting for the log worker.
+func (s *paymentService) LogQueueDepth() (length, capacity int) {
+	return len(s.logChannel), cap(s.logChannel)
+}
+
+// DroppedLogs reports how many payment logs the overflow policy has discarded since startup.
+func (s *paymentService) DroppedLogs() int64 {
+	return s.droppedLogs.Load()
 }
 
 // logWorker processes payment logs asynchronously.
@@ -96,94 +187,373 @@ func (s *paymentService) logWorker(ctx context.Context) {
 	}
 }
 
-// ProcessCardPayment processes a card payment for a merchant.
-func (s *paymentService) ProcessCardPayment(ctx context.Context, merchantAccountID uuid.UUID, cardID uuid.UUID, amount decimal.Decimal) (*model.Payment, error) {
+// ProcessCardPayment processes a card payment for a merchant. A non-empty reference is the
+// merchant's own (e.g. an order number): the payment is refused with
+// errors.ErrDuplicateReference when the merchant already has one with that reference,
+// whatever its outcome, so an order is never charged twice.
+//
+// A non-empty idempotencyKey is stored, per merchant, on the payment it gets accepted as, in
+// the same transaction that charges the card, and a repeated key returns that payment instead
+// of charging the card again. A charge that is not accepted leaves the key free for a retry.
+func (s *paymentService) ProcessCardPayment(ctx context.Context, merchantAccountID uuid.UUID, cardID uuid.UUID, amount decimal.Decimal, reference, idempotencyKey string) (*model.Payment, error) {
+	if err := s.killSwitch.Check(ctx, OperationPayments); err != nil {
+		return nil, err
+	}
+
 	// Validate amount
 	if amount.LessThanOrEqual(decimal.Zero) {
 		return nil, errors.ErrInvalidAmount
 	}
 
+	var key *string
+	if idempotencyKey != "" {
+		// Replay before the reference check, which the original payment would now fail
+		original, err := s.findKeyedPayment(ctx, merchantAccountID, idempotencyKey)
+		if err != nil || original != nil {
+			return original, err
+		}
+		key = &idempotencyKey
+	}
+	return s.processCardPayment(ctx, merchantAccountID, cardID, amount, reference, key)
+}
+
+// processCardPayment checks the merchant reference and charges the card under its mutex.
+func (s *paymentService) processCardPayment(ctx context.Context, merchantAccountID uuid.UUID, cardID uuid.UUID, amount decimal.Decimal, reference string, idempotencyKey *string) (*model.Payment, error) {
+	// The unique index catches a concurrent payment with the same reference; checking first
+	// refuses the common case before any payment record is written
+	var merchantReference *string
+	if reference != "" {
+		exists, err := s.paymentRepo.ExistsByMerchantReference(ctx, merchantAccountID, reference)
+		if err != nil {
+			return nil, fmt.Errorf("check merchant reference: %w", err)
+		}
+		if exists {
+			return nil, errors.ErrDuplicateReference
+		}
+		merchantReference = &reference
+	}
+
 	// Get mutex for this card
 	mutex := s.getMutex(cardID)
 	mutex.Lock()
 	defer mutex.Unlock()
 
+	return s.chargeCard(ctx, merchantAccountID, cardID, amount, nil, merchantReference, idempotencyKey)
+}
+
+// RetryPayment re-runs a failed payment of the merchant as a new payment linked to the
+// original; the failed payment itself is left untouched. Only transient failures can be
+// retried, and only until one retry of the original is accepted. Retries of retries link to
+// the original payment so that check covers the whole chain.
+func (s *paymentService) RetryPayment(ctx context.Context, merchantAccountID uuid.UUID, paymentID uuid.UUID) (*model.Payment, error) {
+	if err := s.killSwitch.Check(ctx, OperationPayments); err != nil {
+		return nil, err
+	}
+
+	failed, err := s.GetPayment(ctx, merchantAccountID, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if failed.Status != model.PaymentStatusFailed {
+		return nil, ErrPaymentNotFailed
+	}
+	if !failed.FailureReason.Transient() {
+		return nil, &PaymentNotRetryableError{Reason: failed.FailureReason}
+	}
+
+	originalID := failed.ID
+	if failed.RetryOfID != nil {
+		originalID = *failed.RetryOfID
+	}
+
+	mutex := s.getMutex(failed.CardID)
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	accepted, err := s.paymentRepo.CountAcceptedRetries(ctx, originalID)
+	if err != nil {
+		return nil, fmt.Errorf("count retries: %w", err)
+	}
+	if accepted > 0 {
+		return nil, ErrPaymentAlreadyRetried
+	}
+
+	// The reference stays with the original payment; the retry is linked to it by retry_of_id
+	return s.chargeCard(ctx, failed.MerchantAccountID, failed.CardID, failed.Amount, &originalID, nil, nil)
+}
+
+// CancelPayment voids one of the merchant's pending payments. A pending payment holds no funds:
+// the card is debited in the same transaction that accepts it, and that transaction claims the
+// payment with the same conditional status change used here, so exactly one of the cancel and
+// the charge wins and a cancelled payment never moves money.
+func (s *paymentService) CancelPayment(ctx context.Context, merchantAccountID uuid.UUID, paymentID uuid.UUID) (*model.Payment, error) {
+	payment, err := s.GetPayment(ctx, merchantAccountID, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCancellable(payment.Status); err != nil {
+		return nil, err
+	}
+
+	var cancelled bool
+	err = s.txManager.WithTransaction(ctx, func(ctx context.Context, tx interface{}) error {
+		var err error
+		cancelled, err = s.paymentRepo.TransitionStatusTx(ctx, tx, payment.ID, model.PaymentStatusPending, model.PaymentStatusCancelled)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cancel payment: %w", err)
+	}
+	if !cancelled {
+		// It settled or was cancelled after it was read
+		current, err := s.paymentRepo.FindByID(ctx, payment.ID)
+		if err != nil {
+			return nil, fmt.Errorf("get payment: %w", err)
+		}
+		if err := checkCancellable(current.Status); err != nil {
+			return nil, err
+		}
+		return nil, ErrPaymentNotCancellable
+	}
+
+	payment.Status = model.PaymentStatusCancelled
+	s.logPayment(ctx, payment.ID, model.PaymentStatusCancelled, "cancelled by merchant")
+	return payment, nil
+}
+
+// checkCancellable reports why a payment in status cannot be cancelled, if it cannot.
+func checkCancellable(status model.PaymentStatus) error {
+	switch status {
+	case model.PaymentStatusPending:
+		return nil
+	case model.PaymentStatusCancelled:
+		return ErrPaymentAlreadyCancelled
+	default:
+		return ErrPaymentNotCancellable
+	}
+}
+
+// chargeCard runs the payment flow for a card whose mutex the caller holds. retryOf links the
+// new payment to the original failed payment it retries, if any. A non-nil idempotencyKey is
+// stored on the payment once it is accepted; should a concurrent charge of the merchant have
+// taken the key first, the pending payment is cancelled and that charge returned instead.
+func (s *paymentService) chargeCard(ctx context.Context, merchantAccountID uuid.UUID, cardID uuid.UUID, amount decimal.Decimal, retryOf *uuid.UUID, reference, idempotencyKey *string) (*model.Payment, error) {
 	// Validate merchant account exists and is active
 	merchant, err := s.accountRepo.FindByID(ctx, merchantAccountID)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			payment := s.createPaymentRecord(merchantAccountID, cardID, amount, model.PaymentStatusFailed)
+			payment := s.failedPaymentRecord(merchantAccountID, cardID, amount, retryOf, reference, model.PaymentFailureMerchantNotFound)
 			_ = s.paymentRepo.Create(ctx, payment)
 			s.logPayment(ctx, payment.ID, model.PaymentStatusFailed, errors.ErrAccountNotFound.Error())
 			return payment, errors.ErrAccountNotFound
 		}
-		payment := s.createPaymentRecord(merchantAccountID, cardID, amount, model.PaymentStatusFailed)
+		payment := s.failedPaymentRecord(merchantAccountID, cardID, amount, retryOf, reference, model.PaymentFailureProcessingError)
 		_ = s.paymentRepo.Create(ctx, payment)
 		s.logPayment(ctx, payment.ID, model.PaymentStatusFailed, err.Error())
 		return payment, err
 	}
 
 	if !merchant.Active {
-		payment := s.createPaymentRecord(merchantAccountID, cardID, amount, model.PaymentStatusFailed)
+		payment := s.failedMerchantPaymentRecord(merchant, cardID, amount, retryOf, reference, model.PaymentFailureMerchantInactive)
 		_ = s.paymentRepo.Create(ctx, payment)
 		s.logPayment(ctx, payment.ID, model.PaymentStatusFailed, errors.ErrAccountInactive.Error())
 		return payment, errors.ErrAccountInactive
 	}
 
 	if !merchant.IsMerchant {
-		payment := s.createPaymentRecord(merchantAccountID, cardID, amount, model.PaymentStatusFailed)
+		payment := s.failedMerchantPaymentRecord(merchant, cardID, amount, retryOf, reference, model.PaymentFailureNotMerchant)
 		_ = s.paymentRepo.Create(ctx, payment)
 		s.logPayment(ctx, payment.ID, model.PaymentStatusFailed, "account is not a merchant")
 		return payment, fmt.Errorf("account is not a merchant")
 	}
 
+	// Zero means unlimited
+	if ceiling := PaymentCeiling(s.cfg.MaxPaymentAmount, merchant.MaxPaymentAmount); ceiling.IsPositive() && amount.GreaterThan(ceiling) {
+		payment := s.failedMerchantPaymentRecord(merchant, cardID, amount, retryOf, reference, model.PaymentFailureAmountOutOfRange)
+		_ = s.paymentRepo.Create(ctx, payment)
+		s.logPayment(ctx, payment.ID, model.PaymentStatusFailed, errors.ErrAmountOutOfRange.Error())
+		return payment, errors.ErrAmountOutOfRange
+	}
+
 	// Validate card exists and is active
 	card, err := s.cardRepo.FindByIDForUpdate(ctx, cardID)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			payment := s.createPaymentRecord(merchantAccountID, cardID, amount, model.PaymentStatusFailed)
+			payment := s.failedMerchantPaymentRecord(merchant, cardID, amount, retryOf, reference, model.PaymentFailureCardNotFound)
 			_ = s.paymentRepo.Create(ctx, payment)
 			s.logPayment(ctx, payment.ID, model.PaymentStatusFailed, "card not found")
 			return payment, fmt.Errorf("card not found")
 		}
-		payment := s.createPaymentRecord(merchantAccountID, cardID, amount, model.PaymentStatusFailed)
+		payment := s.failedMerchantPaymentRecord(merchant, cardID, amount, retryOf, reference, model.PaymentFailureProcessingError)
 		_ = s.paymentRepo.Create(ctx, payment)
 		s.logPayment(ctx, payment.ID, model.PaymentStatusFailed, err.Error())
 		return payment, err
 	}
 
 	if !card.Active {
-		payment := s.createPaymentRecord(merchantAccountID, cardID, amount, model.PaymentStatusFailed)
+		payment := s.failedMerchantPaymentRecord(merchant, cardID, amount, retryOf, reference, model.PaymentFailureCardInactive)
 		_ = s.paymentRepo.Create(ctx, payment)
 		s.logPayment(ctx, payment.ID, model.PaymentStatusFailed, "card is not active")
 		return payment, fmt.Errorf("card is not active")
 	}
 
-	// Create payment record
+	// A held card owner cannot pay until the hold expires or is lifted
+	if err := s.holds.Check(ctx, card.AccountID); err != nil {
+		payment := s.failedMerchantPaymentRecord(merchant, cardID, amount, retryOf, reference, model.PaymentFailureAccountOnHold)
+		_ = s.paymentRepo.Create(ctx, payment)
+		s.logPayment(ctx, payment.ID, model.PaymentStatusFailed, err.Error())
+		return payment, err
+	}
+
+	// The card is debited and the merchant credited the same amounts, so both must hold
+	// the same currency
+	if err := checkSameCurrency(card.Currency, merchant.Currency, s.currency); err != nil {
+		payment := s.failedMerchantPaymentRecord(merchant, cardID, amount, retryOf, reference, model.PaymentFailureCurrencyMismatch)
+		_ = s.paymentRepo.Create(ctx, payment)
+		s.logPayment(ctx, payment.ID, model.PaymentStatusFailed, err.Error())
+		return payment, err
+	}
+
+	// Create payment record with its fee breakdown
 	payment := s.createPaymentRecord(merchantAccountID, cardID, amount, model.PaymentStatusPending)
+	payment.RetryOfID = retryOf
+	payment.MerchantReference = reference
+	payment.TestMode = merchant.TestMode
+	s.applyFee(payment, merchant, heldCurrency(merchant.Currency, s.currency))
+	if !payment.NetAmount.IsPositive() {
+		payment.Status = model.PaymentStatusFailed
+		payment.FailureReason = model.PaymentFailureAmountBelowFee
+		_ = s.paymentRepo.Create(ctx, payment)
+		s.logPayment(ctx, payment.ID, model.PaymentStatusFailed, "amount does not cover the processing fee")
+		return payment, errors.ErrInvalidAmount
+	}
+	// The day's spend is reserved before charging and given back if the charge does not go
+	// through, so concurrent payments cannot both fit under the limit. Test-mode payments
+	// move no money, so they are only checked.
+	releaseSpend := func() {}
+	if payment.TestMode {
+		err = s.dailySpend.Check(ctx, cardID, payment.GrossAmount)
+	} else {
+		releaseSpend, err = s.dailySpend.Reserve(ctx, cardID, payment.GrossAmount)
+	}
+	if err != nil {
+		payment.Status = model.PaymentStatusFailed
+		payment.FailureReason = model.PaymentFailureDailyLimit
+		if err != errors.ErrDailyLimitExceeded {
+			payment.FailureReason = model.PaymentFailureProcessingError
+		}
+		_ = s.paymentRepo.Create(ctx, payment)
+		s.logPayment(ctx, payment.ID, model.PaymentStatusFailed, err.Error())
+		return payment, err
+	}
+	if payment.TestMode {
+		return s.settleTestPayment(ctx, payment, card, idempotencyKey)
+	}
+	charged := false
+	defer func() {
+		if !charged {
+			releaseSpend()
+		}
+	}()
 	if err := s.paymentRepo.Create(ctx, payment); err != nil {
+		if err == errors.ErrDuplicateReference {
+			return nil, err
+		}
 		s.logPayment(ctx, payment.ID, model.PaymentStatusFailed, err.Error())
 		return payment, fmt.Errorf("create payment: %w", err)
 	}
 
-	// Update card balance atomically (deduct from card)
-	newBalance := card.Balance.Sub(amount)
-	if newBalance.LessThan(decimal.Zero) {
+	// Debit the card by the gross amount, record the ledger entry, and credit the
+	// merchant the net amount in one transaction so no side can commit alone.
+	// The balance check uses only the row locked here, never the cached wallet: a cached
+	// balance can be stale, and the merchant credit is an in-SQL increment for the same reason.
+	// Funds under active card holds are not available to the charge.
+	var merchantBalance *decimal.Decimal
+	err = s.txManager.WithTransaction(ctx, func(ctx context.Context, tx interface{}) error {
+		lockedCard, err := s.cardRepo.FindByIDForUpdateTx(ctx, tx, cardID)
+		if err != nil {
+			return err
+		}
+		held, err := s.cardRepo.SumActiveHoldsTx(ctx, tx, cardID, s.clock.Now())
+		if err != nil {
+			return err
+		}
+
+		newBalance := lockedCard.Balance.Sub(payment.GrossAmount)
+		if newBalance.LessThan(held) {
+			return errors.ErrInsufficientBalance
+		}
+
+		// Claim the pending payment before moving money, so a concurrent cancel and this
+		// charge cannot both take effect.
+		claimed, err := s.paymentRepo.TransitionStatusTx(ctx, tx, payment.ID, model.PaymentStatusPending, model.PaymentStatusAccepted)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			return ErrPaymentAlreadyCancelled
+		}
+		// The unique index settles a race with a concurrent charge under the same key
+		if idempotencyKey != nil {
+			if err := s.paymentRepo.SetChargeIdempotencyKeyTx(ctx, tx, payment.ID, *idempotencyKey); err != nil {
+				return err
+			}
+		}
+
+		if err := s.cardRepo.UpdateBalanceTx(ctx, tx, cardID, newBalance); err != nil {
+			return err
+		}
+		if err := s.cardRepo.AddLedgerEntryTx(ctx, tx, &model.LedgerEntry{
+			CardID:       cardID,
+			Delta:        payment.GrossAmount.Neg(),
+			BalanceAfter: newBalance,
+			Reason:       model.LedgerReasonPayment,
+			ReferenceID:  payment.ID,
+		}); err != nil {
+			return err
+		}
+		if err := s.accountRepo.CreditBalanceTx(ctx, tx, merchantAccountID, payment.NetAmount); err != nil {
+			return err
+		}
+		// The credit holds the merchant's row lock, so this is exactly the balance it produced.
+		// Alerts are best effort: a failed read skips the alert, not the payment.
+		if s.alerts.Watches(merchant) {
+			if credited, err := s.accountRepo.FindByIDTx(ctx, tx, merchantAccountID); err == nil {
+				merchantBalance = &credited.Balance
+			}
+		}
+		return nil
+	})
+	if err == ErrPaymentAlreadyCancelled {
+		payment.Status = model.PaymentStatusCancelled
+		s.logPayment(ctx, payment.ID, model.PaymentStatusCancelled, "cancelled before the card was charged")
+		return payment, nil
+	}
+	if err == errors.ErrDuplicateIdempotencyKey {
+		payment.Status = model.PaymentStatusCancelled
+		_ = s.paymentRepo.Update(ctx, payment)
+		s.logPayment(ctx, payment.ID, model.PaymentStatusCancelled, "superseded by a payment with the same idempotency key")
+		return s.keyedPayment(ctx, merchantAccountID, *idempotencyKey)
+	}
+	if err == errors.ErrInsufficientBalance {
 		payment.Status = model.PaymentStatusFailed
+		payment.FailureReason = model.PaymentFailureInsufficientBalance
 		_ = s.paymentRepo.Update(ctx, payment)
 		s.logPayment(ctx, payment.ID, model.PaymentStatusFailed, errors.ErrInsufficientBalance.Error())
 		return payment, errors.ErrInsufficientBalance
 	}
-
-	if err := s.cardRepo.UpdateBalance(ctx, cardID, newBalance); err != nil {
+	if err != nil {
 		payment.Status = model.PaymentStatusFailed
+		payment.FailureReason = model.PaymentFailureProcessingError
 		_ = s.paymentRepo.Update(ctx, payment)
 		s.logPayment(ctx, payment.ID, model.PaymentStatusFailed, fmt.Sprintf("failed to update balance: %v", err))
 		return payment, fmt.Errorf("update balance: %w", err)
 	}
+	charged = true
 
 	// Mark payment as accepted
 	payment.Status = model.PaymentStatusAccepted
+	payment.ChargeIdempotencyKey = idempotencyKey
 	if err := s.paymentRepo.Update(ctx, payment); err != nil {
 		s.logPayment(ctx, payment.ID, model.PaymentStatusAccepted, "")
 		return payment, nil
@@ -191,13 +561,167 @@ func (s *paymentService) ProcessCardPayment(ctx context.Context, merchantAccount
 
 	// Invalidate cache
 	_ = s.cache.Delete(ctx, fmt.Sprintf("card:%s", cardID.String()))
+	invalidateWallets(ctx, s.cache, card.AccountID, merchantAccountID)
 
 	// Log successful payment
 	s.logPayment(ctx, payment.ID, model.PaymentStatusAccepted, "")
 
+	s.notifier.NotifyAccepted(merchant, card, payment)
+	if merchantBalance != nil {
+		s.alerts.BalanceChanged(merchant, merchantBalance.Sub(payment.NetAmount), *merchantBalance)
+	}
+
+	return payment, nil
+}
+
+// GetPayment returns one of the merchant's payments. Payments of other merchants are
+// reported as not found.
+func (s *paymentService) GetPayment(ctx context.Context, merchantAccountID uuid.UUID, paymentID uuid.UUID) (*model.Payment, error) {
+	payment, err := s.paymentRepo.FindByID(ctx, paymentID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.ErrPaymentNotFound
+		}
+		return nil, fmt.Errorf("get payment: %w", err)
+	}
+	if payment.MerchantAccountID != merchantAccountID {
+		return nil, errors.ErrPaymentNotFound
+	}
 	return payment, nil
 }
 
+// GetPaymentTimeline assembles a payment's status transitions from its logs. Every payment
+// starts with a pending event at creation; each log entry adds the status it recorded. Logs
+// are written asynchronously and may be dropped under load, so if none records the
+// payment's current status a final event is synthesized from the payment itself. Payments
+// of other merchants are reported as not found.
+func (s *paymentService) GetPaymentTimeline(ctx context.Context, merchantAccountID uuid.UUID, paymentID uuid.UUID) (*PaymentTimeline, error) {
+	payment, err := s.GetPayment(ctx, merchantAccountID, paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	logs, err := s.paymentLogRepo.ListByPaymentID(ctx, paymentID)
+	if err != nil {
+		return nil, fmt.Errorf("list payment logs: %w", err)
+	}
+
+	events := []PaymentEvent{{Status: model.PaymentStatusPending, Message: "payment created", At: payment.CreatedAt}}
+	for _, l := range logs {
+		events = append(events, PaymentEvent{Status: l.Status, Message: l.ErrorMessage, At: l.CreatedAt})
+	}
+	if last := events[len(events)-1]; last.Status != payment.Status {
+		events = append(events, PaymentEvent{Status: payment.Status, At: payment.UpdatedAt})
+	}
+
+	return &PaymentTimeline{Payment: payment, Events: events}, nil
+}
+
+// ListAccountPayments lists payments the account received as a merchant or made with one of
+// its cards, newest first. Archived payments are left out unless includeArchived is set.
+func (s *paymentService) ListAccountPayments(ctx context.Context, accountID uuid.UUID, includeArchived bool, limit, offset int) ([]model.Payment, int64, error) {
+	payments, total, err := s.paymentRepo.ListByAccount(ctx, accountID, includeArchived, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list payments: %w", err)
+	}
+	return payments, total, nil
+}
+
+// ListCardPayments lists the accepted and failed payments made with the card, newest first.
+// The card is returned with the page so callers can check its owner.
+func (s *paymentService) ListCardPayments(ctx context.Context, cardID uuid.UUID, limit, offset int) (*CardPaymentPage, error) {
+	card, err := s.cardRepo.FindByID(ctx, cardID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.ErrCardNotFound
+		}
+		return nil, fmt.Errorf("get card: %w", err)
+	}
+
+	payments, total, err := s.paymentRepo.ListByCard(ctx, cardID, CardPaymentStatuses, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("list payments: %w", err)
+	}
+	return &CardPaymentPage{Card: card, Payments: payments, Total: total}, nil
+}
+
+// ListMerchantCustomers lists the accounts whose cards paid the merchant, with their spend.
+func (s *paymentService) ListMerchantCustomers(ctx context.Context, merchantAccountID uuid.UUID, limit, offset int) ([]repository.MerchantCustomer, int64, error) {
+	merchant, err := s.accountRepo.FindByID(ctx, merchantAccountID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, 0, errors.ErrAccountNotFound
+		}
+		return nil, 0, fmt.Errorf("get account: %w", err)
+	}
+	if !merchant.IsMerchant {
+		return nil, 0, errors.ErrNotMerchant
+	}
+
+	customers, total, err := s.paymentRepo.ListCustomersByMerchant(ctx, merchantAccountID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list customers: %w", err)
+	}
+	return customers, total, nil
+}
+
+// ListMerchantPayments lists the payments the merchant received that match filter, newest
+// first. Archived payments are left out unless filter.IncludeArchived is set.
+func (s *paymentService) ListMerchantPayments(ctx context.Context, merchantAccountID uuid.UUID, filter repository.PaymentFilter, limit, offset int) ([]model.Payment, int64, error) {
+	merchant, err := s.accountRepo.FindByID(ctx, merchantAccountID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, 0, errors.ErrAccountNotFound
+		}
+		return nil, 0, fmt.Errorf("get account: %w", err)
+	}
+	if !merchant.IsMerchant {
+		return nil, 0, errors.ErrNotMerchant
+	}
+
+	payments, total, err := s.paymentRepo.ListByMerchant(ctx, merchantAccountID, filter, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list payments: %w", err)
+	}
+	return payments, total, nil
+}
+
+// GetPendingSummary counts and totals the merchant's in-flight payments. Every in-flight
+// status is listed, with zeroes when the merchant has none in it.
+func (s *paymentService) GetPendingSummary(ctx context.Context, merchantAccountID uuid.UUID) (*PendingPaymentsSummary, error) {
+	merchant, err := s.accountRepo.FindByID(ctx, merchantAccountID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.ErrAccountNotFound
+		}
+		return nil, fmt.Errorf("get account: %w", err)
+	}
+	if !merchant.IsMerchant {
+		return nil, errors.ErrNotMerchant
+	}
+
+	totals, err := s.paymentRepo.SumByMerchantAndStatus(ctx, merchantAccountID, InFlightPaymentStatuses)
+	if err != nil {
+		return nil, fmt.Errorf("sum pending payments: %w", err)
+	}
+	byStatus := make(map[model.PaymentStatus]repository.PaymentStatusTotal, len(totals))
+	for _, t := range totals {
+		byStatus[t.Status] = t
+	}
+
+	summary := &PendingPaymentsSummary{Total: decimal.Zero}
+	for _, status := range InFlightPaymentStatuses {
+		t, ok := byStatus[status]
+		if !ok {
+			t = repository.PaymentStatusTotal{Status: status, Total: decimal.Zero}
+		}
+		summary.Count += t.Count
+		summary.Total = summary.Total.Add(t.Total)
+		summary.ByStatus = append(summary.ByStatus, t)
+	}
+	return summary, nil
+}
+
 // createPaymentRecord creates a payment record.
 func (s *paymentService) createPaymentRecord(merchantAccountID uuid.UUID, cardID uuid.UUID, amount decimal.Decimal, status model.PaymentStatus) *model.Payment {
 	return &model.Payment{
@@ -208,6 +732,78 @@ func (s *paymentService) createPaymentRecord(merchantAccountID uuid.UUID, cardID
 	}
 }
 
+// failedPaymentRecord creates a failed payment record with its failure reason.
+func (s *paymentService) failedPaymentRecord(merchantAccountID uuid.UUID, cardID uuid.UUID, amount decimal.Decimal, retryOf *uuid.UUID, reference *string, reason model.PaymentFailureReason) *model.Payment {
+	payment := s.createPaymentRecord(merchantAccountID, cardID, amount, model.PaymentStatusFailed)
+	payment.FailureReason = reason
+	payment.RetryOfID = retryOf
+	payment.MerchantReference = reference
+	return payment
+}
+
+// failedMerchantPaymentRecord creates a failed payment record for a known merchant, so
+// test-mode merchants' rejected payments are marked as test payments too.
+func (s *paymentService) failedMerchantPaymentRecord(merchant *model.Account, cardID uuid.UUID, amount decimal.Decimal, retryOf *uuid.UUID, reference *string, reason model.PaymentFailureReason) *model.Payment {
+	payment := s.failedPaymentRecord(merchant.ID, cardID, amount, retryOf, reference, reason)
+	payment.TestMode = merchant.TestMode
+	return payment
+}
+
+// settleTestPayment decides a test-mode payment that has passed every other check. The
+// outcome follows the card's available balance as a real charge would, but the card is never
+// debited, the merchant never credited, and nothing counts towards the daily limit. A non-nil
+// idempotencyKey is stored on the payment when it is accepted.
+func (s *paymentService) settleTestPayment(ctx context.Context, payment *model.Payment, card *model.Card, idempotencyKey *string) (*model.Payment, error) {
+	held, err := s.cardRepo.SumActiveHolds(ctx, card.ID, s.clock.Now())
+	if err != nil {
+		payment.Status = model.PaymentStatusFailed
+		payment.FailureReason = model.PaymentFailureProcessingError
+		_ = s.paymentRepo.Create(ctx, payment)
+		s.logPayment(ctx, payment.ID, model.PaymentStatusFailed, err.Error())
+		return payment, fmt.Errorf("sum card holds: %w", err)
+	}
+
+	payment.Status = model.PaymentStatusAccepted
+	payment.ChargeIdempotencyKey = idempotencyKey
+	if availableBalance(card.Balance, held).LessThan(payment.GrossAmount) {
+		payment.Status = model.PaymentStatusFailed
+		payment.FailureReason = model.PaymentFailureInsufficientBalance
+		payment.ChargeIdempotencyKey = nil
+	}
+	if err := s.paymentRepo.Create(ctx, payment); err != nil {
+		if err == errors.ErrDuplicateReference {
+			return nil, err
+		}
+		if err == errors.ErrDuplicateIdempotencyKey {
+			return s.keyedPayment(ctx, payment.MerchantAccountID, *idempotencyKey)
+		}
+		s.logPayment(ctx, payment.ID, model.PaymentStatusFailed, err.Error())
+		return payment, fmt.Errorf("create payment: %w", err)
+	}
+	if payment.Status == model.PaymentStatusFailed {
+		s.logPayment(ctx, payment.ID, model.PaymentStatusFailed, errors.ErrInsufficientBalance.Error())
+		return payment, errors.ErrInsufficientBalance
+	}
+	s.logPayment(ctx, payment.ID, model.PaymentStatusAccepted, "test mode")
+	return payment, nil
+}
+
+// applyFee fills in the payment's fee, gross, and net amounts. By default the merchant
+// absorbs the fee (card charged the amount, merchant credited amount - fee); merchants
+// with PassFeeToCustomer have the card charged amount + fee and receive the full amount.
+// The fee is rounded to cur, the currency the payment is made in.
+func (s *paymentService) applyFee(payment *model.Payment, merchant *model.Account, cur currency.Currency) {
+	amount := currency.New(payment.Amount, cur)
+	fee := currency.New(CalculateFee(payment.Amount, s.cfg.PaymentFeePercent, s.cfg.PaymentFeeFixed, cur, s.rounding), cur)
+	gross, net := amount, amount.Sub(fee)
+	if merchant.PassFeeToCustomer {
+		gross, net = amount.Add(fee), amount
+	}
+	payment.FeeAmount = fee.Amount
+	payment.GrossAmount = gross.Amount
+	payment.NetAmount = net.Amount
+}
+
 // logPayment logs a payment attempt asynchronously.
 func (s *paymentService) logPayment(ctx context.Context, paymentID uuid.UUID, status model.PaymentStatus, errorMessage string) {
 	log := model.PaymentLog{
@@ -219,9 +815,31 @@ func (s *paymentService) logPayment(ctx context.Context, paymentID uuid.UUID, st
 	// Send to async log channel (non-blocking)
 	select {
 	case s.logChannel <- log:
+		return
 	default:
-		// Channel full, log synchronously as fallback
+	}
+
+	// Channel full, apply the configured overflow policy
+	switch s.cfg.PaymentLogOverflowPolicy {
+	case config.LogOverflowDrop:
+		s.dropLog(log)
+	case config.LogOverflowBlock:
+		timer := time.NewTimer(s.cfg.PaymentLogOverflowTimeout)
+		defer timer.Stop()
+		select {
+		case s.logChannel <- log:
+		case <-timer.C:
+			s.dropLog(log)
+		}
+	default:
+		// Log synchronously as fallback
 		_ = s.paymentLogRepo.Create(ctx, &log)
 	}
 }
 
+// dropLog discards a payment log that could not be queued and records the drop.
+func (s *paymentService) dropLog(paymentLog model.PaymentLog) {
+	dropped := s.droppedLogs.Add(1)
+	log.Printf("payment log dropped (payment=%s status=%s), %d dropped so far", paymentLog.PaymentID, paymentLog.Status, dropped)
+}
+
@@ -0,0 +1,1060 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"paytabs/internal/cache"
+	"paytabs/internal/config"
+	"paytabs/internal/currency"
+	"paytabs/internal/errors"
+	"paytabs/internal/model"
+	"paytabs/internal/repository"
+)
+
+// decimalEq matches a decimal argument by value rather than representation.
+func decimalEq(expected string) interface{} {
+	want := decimal.RequireFromString(expected)
+	return mock.MatchedBy(func(d decimal.Decimal) bool { return d.Equal(want) })
+}
+
+// paymentTestDeps bundles the mocks behind a payment service under test.
+type paymentTestDeps struct {
+	accountRepo *MockAccountRepository
+	cardRepo    *MockCardRepository
+	paymentRepo *MockPaymentRepository
+	logRepo     *MockPaymentLogRepository
+	payoutRepo  *MockPayoutRepository
+}
+
+func newPaymentTestDeps(merchant *model.Account, card *model.Card) *paymentTestDeps {
+	d := &paymentTestDeps{
+		accountRepo: new(MockAccountRepository),
+		cardRepo:    new(MockCardRepository),
+		paymentRepo: new(MockPaymentRepository),
+		logRepo:     new(MockPaymentLogRepository),
+	}
+	d.accountRepo.On("FindByID", mock.Anything, merchant.ID).Return(merchant, nil)
+	d.cardRepo.On("FindByIDForUpdate", mock.Anything, card.ID).Return(card, nil)
+	d.cardRepo.On("FindByIDForUpdateTx", mock.Anything, mock.Anything, card.ID).Return(card, nil)
+	d.cardRepo.On("SumActiveHoldsTx", mock.Anything, mock.Anything, card.ID, mock.Anything).Return(decimal.Zero, nil).Maybe()
+	d.cardRepo.On("SumActiveHolds", mock.Anything, card.ID, mock.Anything).Return(decimal.Zero, nil).Maybe()
+	d.paymentRepo.On("Create", mock.Anything, mock.AnythingOfType("*model.Payment")).Return(nil)
+	d.paymentRepo.On("Update", mock.Anything, mock.AnythingOfType("*model.Payment")).Return(nil)
+	d.paymentRepo.On("TransitionStatusTx", mock.Anything, mock.Anything, mock.Anything, model.PaymentStatusPending, model.PaymentStatusAccepted).Return(true, nil).Maybe()
+	d.logRepo.On("CreateBatch", mock.Anything, mock.Anything).Return(nil).Maybe()
+	d.logRepo.On("Create", mock.Anything, mock.Anything).Return(nil).Maybe()
+	return d
+}
+
+func (d *paymentTestDeps) service(cfg *config.Config) PaymentService {
+	return NewPaymentService(d.accountRepo, d.cardRepo, d.paymentRepo, d.logRepo, d.payoutRepo, &MockTxManager{}, nil, nil, nil, cfg)
+}
+
+func TestPaymentService_ProcessCardPayment_FeeModes(t *testing.T) {
+	cfg := &config.Config{
+		PaymentFeePercent: decimal.RequireFromString("2.9"),
+		PaymentFeeFixed:   decimal.RequireFromString("0.30"),
+	}
+
+	tests := []struct {
+		name              string
+		passFeeToCustomer bool
+		expectedGross     string
+		expectedNet       string
+		expectedCardAfter string
+	}{
+		{
+			name:              "merchant absorbs fee",
+			passFeeToCustomer: false,
+			expectedGross:     "100.00",
+			expectedNet:       "96.80",
+			expectedCardAfter: "400.00",
+		},
+		{
+			name:              "customer pays fee",
+			passFeeToCustomer: true,
+			expectedGross:     "103.20",
+			expectedNet:       "100.00",
+			expectedCardAfter: "396.80",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merchant := &model.Account{ID: uuid.New(), Active: true, IsMerchant: true, PassFeeToCustomer: tt.passFeeToCustomer}
+			card := &model.Card{ID: uuid.New(), AccountID: uuid.New(), Balance: decimal.RequireFromString("500.00"), Active: true}
+
+			d := newPaymentTestDeps(merchant, card)
+			d.cardRepo.On("UpdateBalanceTx", mock.Anything, mock.Anything, card.ID, decimalEq(tt.expectedCardAfter)).Return(nil)
+			d.cardRepo.On("AddLedgerEntryTx", mock.Anything, mock.Anything, mock.AnythingOfType("*model.LedgerEntry")).Return(nil)
+			d.accountRepo.On("CreditBalanceTx", mock.Anything, mock.Anything, merchant.ID, decimalEq(tt.expectedNet)).Return(nil)
+
+			payment, err := d.service(cfg).ProcessCardPayment(context.Background(), merchant.ID, card.ID, decimal.RequireFromString("100.00"), "", "")
+
+			require.NoError(t, err)
+			assert.Equal(t, model.PaymentStatusAccepted, payment.Status)
+			assert.Equal(t, "100.00", payment.Amount.StringFixed(2))
+			assert.Equal(t, "3.20", payment.FeeAmount.StringFixed(2))
+			assert.Equal(t, tt.expectedGross, payment.GrossAmount.StringFixed(2))
+			assert.Equal(t, tt.expectedNet, payment.NetAmount.StringFixed(2))
+			d.cardRepo.AssertExpectations(t)
+			d.accountRepo.AssertExpectations(t)
+		})
+	}
+}
+
+// recordingTxManager runs transactions like MockTxManager and records whether each one
+// would have committed or rolled back.
+type recordingTxManager struct {
+	MockTxManager
+	committed, rolledBack int
+}
+
+func (m *recordingTxManager) WithTransaction(ctx context.Context, fn func(ctx context.Context, tx interface{}) error) error {
+	err := fn(ctx, nil)
+	if err != nil {
+		m.rolledBack++
+	} else {
+		m.committed++
+	}
+	return err
+}
+
+func TestPaymentService_ProcessCardPayment_CreditsMerchantInCardDebitTransaction(t *testing.T) {
+	merchant := &model.Account{ID: uuid.New(), Active: true, IsMerchant: true}
+
+	t.Run("merchant credited exactly the amount", func(t *testing.T) {
+		card := &model.Card{ID: uuid.New(), AccountID: uuid.New(), Balance: decimal.RequireFromString("500.00"), Active: true}
+		d := newPaymentTestDeps(merchant, card)
+		d.cardRepo.On("UpdateBalanceTx", mock.Anything, mock.Anything, card.ID, decimalEq("400.00")).Return(nil)
+		d.cardRepo.On("AddLedgerEntryTx", mock.Anything, mock.Anything, mock.AnythingOfType("*model.LedgerEntry")).Return(nil)
+		d.accountRepo.On("CreditBalanceTx", mock.Anything, mock.Anything, merchant.ID, decimalEq("100.00")).Return(nil).Once()
+		txm := &recordingTxManager{}
+		svc := NewPaymentService(d.accountRepo, d.cardRepo, d.paymentRepo, d.logRepo, d.payoutRepo, txm, nil, nil, nil, &config.Config{})
+
+		payment, err := svc.ProcessCardPayment(context.Background(), merchant.ID, card.ID, decimal.RequireFromString("100.00"), "", "")
+
+		require.NoError(t, err)
+		assert.Equal(t, model.PaymentStatusAccepted, payment.Status)
+		assert.Equal(t, 1, txm.committed)
+		d.accountRepo.AssertExpectations(t)
+	})
+
+	t.Run("failed credit rolls back the card debit", func(t *testing.T) {
+		card := &model.Card{ID: uuid.New(), AccountID: uuid.New(), Balance: decimal.RequireFromString("500.00"), Active: true}
+		d := newPaymentTestDeps(merchant, card)
+		d.cardRepo.On("UpdateBalanceTx", mock.Anything, mock.Anything, card.ID, decimalEq("400.00")).Return(nil)
+		d.cardRepo.On("AddLedgerEntryTx", mock.Anything, mock.Anything, mock.AnythingOfType("*model.LedgerEntry")).Return(nil)
+		d.accountRepo.On("CreditBalanceTx", mock.Anything, mock.Anything, merchant.ID, mock.Anything).Return(fmt.Errorf("connection reset"))
+		txm := &recordingTxManager{}
+		svc := NewPaymentService(d.accountRepo, d.cardRepo, d.paymentRepo, d.logRepo, d.payoutRepo, txm, nil, nil, nil, &config.Config{})
+
+		payment, err := svc.ProcessCardPayment(context.Background(), merchant.ID, card.ID, decimal.RequireFromString("100.00"), "", "")
+
+		require.Error(t, err)
+		assert.Equal(t, 0, txm.committed)
+		assert.Equal(t, 1, txm.rolledBack, "the debit and the credit commit together or not at all")
+		assert.Equal(t, model.PaymentStatusFailed, payment.Status)
+		assert.Equal(t, model.PaymentFailureProcessingError, payment.FailureReason, "never reported accepted")
+	})
+}
+
+func TestPaymentService_ProcessCardPayment_CustomerFeeNeedsBalanceForTotal(t *testing.T) {
+	cfg := &config.Config{PaymentFeeFixed: decimal.RequireFromString("1.00")}
+	merchant := &model.Account{ID: uuid.New(), Active: true, IsMerchant: true, PassFeeToCustomer: true}
+	// Enough for the amount, one cent short of amount + fee
+	card := &model.Card{ID: uuid.New(), Balance: decimal.RequireFromString("50.99"), Active: true}
+
+	d := newPaymentTestDeps(merchant, card)
+
+	payment, err := d.service(cfg).ProcessCardPayment(context.Background(), merchant.ID, card.ID, decimal.RequireFromString("50.00"), "", "")
+
+	assert.Equal(t, errors.ErrInsufficientBalance, err)
+	assert.Equal(t, model.PaymentStatusFailed, payment.Status)
+	assert.Equal(t, model.PaymentFailureInsufficientBalance, payment.FailureReason)
+	d.cardRepo.AssertNotCalled(t, "UpdateBalanceTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	d.accountRepo.AssertNotCalled(t, "CreditBalanceTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPaymentService_ProcessCardPayment_QueuesBalanceAlert(t *testing.T) {
+	merchant := &model.Account{ID: uuid.New(), Active: true, IsMerchant: true, HighBalanceThreshold: decimal.RequireFromString("1000")}
+	card := &model.Card{ID: uuid.New(), AccountID: uuid.New(), Balance: decimal.RequireFromString("500.00"), Active: true}
+
+	d := newPaymentTestDeps(merchant, card)
+	d.cardRepo.On("UpdateBalanceTx", mock.Anything, mock.Anything, card.ID, mock.Anything).Return(nil)
+	d.cardRepo.On("AddLedgerEntryTx", mock.Anything, mock.Anything, mock.AnythingOfType("*model.LedgerEntry")).Return(nil)
+	d.accountRepo.On("CreditBalanceTx", mock.Anything, mock.Anything, merchant.ID, decimalEq("100")).Return(nil)
+	// The credit took the balance from 950 to 1050
+	d.accountRepo.On("FindByIDTx", mock.Anything, mock.Anything, merchant.ID).Return(&model.Account{ID: merchant.ID, Balance: decimal.RequireFromString("1050")}, nil)
+	alerts := NewBalanceAlerter(nil, nil, nil, 0)
+	svc := NewPaymentService(d.accountRepo, d.cardRepo, d.paymentRepo, d.logRepo, d.payoutRepo, &MockTxManager{}, nil, alerts, nil, &config.Config{})
+
+	_, err := svc.ProcessCardPayment(context.Background(), merchant.ID, card.ID, decimal.RequireFromString("100.00"), "", "")
+
+	require.NoError(t, err)
+	require.Len(t, alerts.queue, 1)
+	alert := <-alerts.queue
+	assert.Equal(t, BalanceAlertHigh, alert.kind)
+	assert.Equal(t, "950", alert.before.String())
+	assert.Equal(t, "1050", alert.after.String())
+}
+
+func TestPaymentService_ProcessCardPayment_DuplicateReference(t *testing.T) {
+	merchant := &model.Account{ID: uuid.New(), Active: true, IsMerchant: true}
+	card := &model.Card{ID: uuid.New(), Balance: decimal.RequireFromString("100.00"), Active: true}
+
+	t.Run("already used", func(t *testing.T) {
+		d := newPaymentTestDeps(merchant, card)
+		d.paymentRepo.On("ExistsByMerchantReference", mock.Anything, merchant.ID, "order-1001").Return(true, nil)
+
+		payment, err := d.service(&config.Config{}).ProcessCardPayment(context.Background(), merchant.ID, card.ID, decimal.RequireFromString("10.00"), "order-1001", "")
+
+		assert.Equal(t, errors.ErrDuplicateReference, err)
+		assert.Nil(t, payment)
+		d.paymentRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		d.cardRepo.AssertNotCalled(t, "FindByIDForUpdate", mock.Anything, mock.Anything)
+	})
+
+	t.Run("taken by a concurrent payment", func(t *testing.T) {
+		d := newPaymentTestDeps(merchant, card)
+		// The unique index refuses the insert after the check passed
+		d.paymentRepo = new(MockPaymentRepository)
+		d.paymentRepo.On("ExistsByMerchantReference", mock.Anything, merchant.ID, "order-1001").Return(false, nil)
+		d.paymentRepo.On("Create", mock.Anything, mock.MatchedBy(func(p *model.Payment) bool {
+			return p.MerchantReference != nil && *p.MerchantReference == "order-1001"
+		})).Return(errors.ErrDuplicateReference)
+
+		payment, err := d.service(&config.Config{}).ProcessCardPayment(context.Background(), merchant.ID, card.ID, decimal.RequireFromString("10.00"), "order-1001", "")
+
+		assert.Equal(t, errors.ErrDuplicateReference, err)
+		assert.Nil(t, payment)
+		d.cardRepo.AssertNotCalled(t, "UpdateBalanceTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("stored on the payment", func(t *testing.T) {
+		d := newPaymentTestDeps(merchant, card)
+		d.paymentRepo.On("ExistsByMerchantReference", mock.Anything, merchant.ID, "order-1002").Return(false, nil)
+		d.cardRepo.On("UpdateBalanceTx", mock.Anything, mock.Anything, card.ID, mock.Anything).Return(nil)
+		d.cardRepo.On("AddLedgerEntryTx", mock.Anything, mock.Anything, mock.AnythingOfType("*model.LedgerEntry")).Return(nil)
+		d.accountRepo.On("CreditBalanceTx", mock.Anything, mock.Anything, merchant.ID, mock.Anything).Return(nil)
+
+		payment, err := d.service(&config.Config{}).ProcessCardPayment(context.Background(), merchant.ID, card.ID, decimal.RequireFromString("10.00"), "order-1002", "")
+
+		require.NoError(t, err)
+		require.NotNil(t, payment.MerchantReference)
+		assert.Equal(t, "order-1002", *payment.MerchantReference)
+	})
+}
+
+func TestPaymentService_ProcessCardPayment_IdempotencyKey(t *testing.T) {
+	merchant := &model.Account{ID: uuid.New(), Active: true, IsMerchant: true}
+	other := &model.Account{ID: uuid.New(), Active: true, IsMerchant: true}
+	card := &model.Card{ID: uuid.New(), AccountID: uuid.New(), Balance: decimal.RequireFromString("500.00"), Active: true}
+	amount := decimal.RequireFromString("100.00")
+
+	d := newPaymentTestDeps(merchant, card)
+	// Payments get their IDs on insert, which the keys are stored against
+	d.paymentRepo = new(MockPaymentRepository)
+	d.paymentRepo.On("Create", mock.Anything, mock.AnythingOfType("*model.Payment")).
+		Run(func(args mock.Arguments) { args.Get(1).(*model.Payment).ID = uuid.New() }).Return(nil)
+	d.paymentRepo.On("Update", mock.Anything, mock.AnythingOfType("*model.Payment")).Return(nil)
+	d.paymentRepo.On("TransitionStatusTx", mock.Anything, mock.Anything, mock.Anything, model.PaymentStatusPending, model.PaymentStatusAccepted).Return(true, nil)
+	d.paymentRepo.On("SetChargeIdempotencyKeyTx", mock.Anything, mock.Anything, mock.Anything, "order-7-attempt").Return(nil)
+	d.paymentRepo.On("FindByChargeIdempotencyKey", mock.Anything, merchant.ID, "order-7-attempt").Return(nil, gorm.ErrRecordNotFound).Once()
+	d.paymentRepo.On("FindByChargeIdempotencyKey", mock.Anything, other.ID, "order-7-attempt").Return(nil, gorm.ErrRecordNotFound)
+	d.accountRepo.On("FindByID", mock.Anything, other.ID).Return(other, nil)
+	d.cardRepo.On("UpdateBalanceTx", mock.Anything, mock.Anything, card.ID, mock.Anything).
+		Run(func(args mock.Arguments) { card.Balance = args.Get(3).(decimal.Decimal) }).Return(nil)
+	d.cardRepo.On("AddLedgerEntryTx", mock.Anything, mock.Anything, mock.AnythingOfType("*model.LedgerEntry")).Return(nil)
+	d.accountRepo.On("CreditBalanceTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	svc := d.service(&config.Config{})
+
+	first, err := svc.ProcessCardPayment(context.Background(), merchant.ID, card.ID, amount, "", "order-7-attempt")
+	require.NoError(t, err)
+	assert.Equal(t, "400", card.Balance.String())
+	require.NotNil(t, first.ChargeIdempotencyKey)
+	assert.Equal(t, "order-7-attempt", *first.ChargeIdempotencyKey)
+	d.paymentRepo.AssertCalled(t, "SetChargeIdempotencyKeyTx", mock.Anything, mock.Anything, first.ID, "order-7-attempt")
+	d.paymentRepo.On("FindByChargeIdempotencyKey", mock.Anything, merchant.ID, "order-7-attempt").Return(first, nil)
+
+	replayed, err := svc.ProcessCardPayment(context.Background(), merchant.ID, card.ID, amount, "", "order-7-attempt")
+
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, replayed.ID)
+	assert.Equal(t, "400", card.Balance.String(), "the retry did not debit the card again")
+	d.cardRepo.AssertNumberOfCalls(t, "UpdateBalanceTx", 1)
+	d.paymentRepo.AssertNumberOfCalls(t, "Create", 1)
+	d.accountRepo.AssertNumberOfCalls(t, "CreditBalanceTx", 1)
+
+	// Keys are scoped per merchant, so another merchant's payment with the same key is its own
+	theirs, err := svc.ProcessCardPayment(context.Background(), other.ID, card.ID, amount, "", "order-7-attempt")
+
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ID, theirs.ID)
+	assert.Equal(t, "300", card.Balance.String())
+}
+
+func TestPaymentService_ProcessCardPayment_IdempotencyKeyReleasedOnFailure(t *testing.T) {
+	merchant := &model.Account{ID: uuid.New(), Active: true, IsMerchant: true}
+	card := &model.Card{ID: uuid.New(), AccountID: uuid.New(), Balance: decimal.RequireFromString("50.00"), Active: true}
+
+	d := newPaymentTestDeps(merchant, card)
+	d.paymentRepo.On("FindByChargeIdempotencyKey", mock.Anything, merchant.ID, "order-8-attempt").Return(nil, gorm.ErrRecordNotFound)
+	d.paymentRepo.On("SetChargeIdempotencyKeyTx", mock.Anything, mock.Anything, mock.Anything, "order-8-attempt").Return(nil)
+	svc := d.service(&config.Config{})
+
+	failed, err := svc.ProcessCardPayment(context.Background(), merchant.ID, card.ID, decimal.RequireFromString("100.00"), "", "order-8-attempt")
+	require.Equal(t, errors.ErrInsufficientBalance, err)
+	assert.Nil(t, failed.ChargeIdempotencyKey, "a failed charge does not hold the key")
+	d.paymentRepo.AssertNotCalled(t, "SetChargeIdempotencyKeyTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	// The card was not debited, so the key is free for a retry once the card is topped up
+	card.Balance = decimal.RequireFromString("150.00")
+	d.cardRepo.On("UpdateBalanceTx", mock.Anything, mock.Anything, card.ID, mock.Anything).Return(nil)
+	d.cardRepo.On("AddLedgerEntryTx", mock.Anything, mock.Anything, mock.AnythingOfType("*model.LedgerEntry")).Return(nil)
+	d.accountRepo.On("CreditBalanceTx", mock.Anything, mock.Anything, merchant.ID, mock.Anything).Return(nil)
+
+	payment, err := svc.ProcessCardPayment(context.Background(), merchant.ID, card.ID, decimal.RequireFromString("100.00"), "", "order-8-attempt")
+
+	require.NoError(t, err)
+	assert.Equal(t, model.PaymentStatusAccepted, payment.Status)
+	d.paymentRepo.AssertNumberOfCalls(t, "SetChargeIdempotencyKeyTx", 1)
+}
+
+func TestPaymentService_ProcessCardPayment_IdempotencyKeyTakenConcurrently(t *testing.T) {
+	merchant := &model.Account{ID: uuid.New(), Active: true, IsMerchant: true}
+	card := &model.Card{ID: uuid.New(), Balance: decimal.RequireFromString("500.00"), Active: true}
+	winner := &model.Payment{ID: uuid.New(), MerchantAccountID: merchant.ID, CardID: card.ID, Status: model.PaymentStatusAccepted}
+
+	// No Redis: the key is only ever checked against the payments table
+	d := newPaymentTestDeps(merchant, card)
+	d.paymentRepo.On("FindByChargeIdempotencyKey", mock.Anything, merchant.ID, "order-9-attempt").Return(nil, gorm.ErrRecordNotFound).Once()
+	d.paymentRepo.On("FindByChargeIdempotencyKey", mock.Anything, merchant.ID, "order-9-attempt").Return(winner, nil)
+	d.paymentRepo.On("SetChargeIdempotencyKeyTx", mock.Anything, mock.Anything, mock.Anything, "order-9-attempt").Return(errors.ErrDuplicateIdempotencyKey)
+
+	payment, err := d.service(&config.Config{}).ProcessCardPayment(context.Background(), merchant.ID, card.ID, decimal.RequireFromString("10.00"), "", "order-9-attempt")
+
+	require.NoError(t, err)
+	assert.Equal(t, winner.ID, payment.ID)
+	d.cardRepo.AssertNotCalled(t, "UpdateBalanceTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	d.paymentRepo.AssertCalled(t, "Update", mock.Anything, mock.MatchedBy(func(p *model.Payment) bool {
+		return p.ID != winner.ID && p.Status == model.PaymentStatusCancelled
+	}))
+}
+
+func TestPaymentService_ProcessCardPayment_MaxPaymentAmount(t *testing.T) {
+	tests := []struct {
+		name        string
+		global      string
+		merchant    string
+		amount      string
+		expectedErr error
+	}{
+		{name: "merchant stricter than global rejects", global: "1000", merchant: "100", amount: "150", expectedErr: errors.ErrAmountOutOfRange},
+		{name: "merchant stricter than global allows up to it", global: "1000", merchant: "100", amount: "100"},
+		{name: "merchant looser than global is capped by global", global: "100", merchant: "1000", amount: "150", expectedErr: errors.ErrAmountOutOfRange},
+		{name: "merchant looser than global allows up to global", global: "100", merchant: "1000", amount: "100"},
+		{name: "zero merchant uses global", global: "100", merchant: "0", amount: "150", expectedErr: errors.ErrAmountOutOfRange},
+		{name: "merchant limit without global", global: "0", merchant: "100", amount: "150", expectedErr: errors.ErrAmountOutOfRange},
+		{name: "no limits", global: "0", merchant: "0", amount: "400"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merchant := &model.Account{ID: uuid.New(), Active: true, IsMerchant: true, MaxPaymentAmount: decimal.RequireFromString(tt.merchant)}
+			card := &model.Card{ID: uuid.New(), AccountID: uuid.New(), Balance: decimal.RequireFromString("500.00"), Active: true}
+
+			d := newPaymentTestDeps(merchant, card)
+			d.cardRepo.On("UpdateBalanceTx", mock.Anything, mock.Anything, card.ID, mock.Anything).Return(nil).Maybe()
+			d.cardRepo.On("AddLedgerEntryTx", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+			d.accountRepo.On("CreditBalanceTx", mock.Anything, mock.Anything, merchant.ID, mock.Anything).Return(nil).Maybe()
+			cfg := &config.Config{MaxPaymentAmount: decimal.RequireFromString(tt.global)}
+
+			payment, err := d.service(cfg).ProcessCardPayment(context.Background(), merchant.ID, card.ID, decimal.RequireFromString(tt.amount), "", "")
+
+			assert.Equal(t, tt.expectedErr, err)
+			if tt.expectedErr != nil {
+				assert.Equal(t, model.PaymentStatusFailed, payment.Status)
+				assert.Equal(t, model.PaymentFailureAmountOutOfRange, payment.FailureReason)
+				d.cardRepo.AssertNotCalled(t, "FindByIDForUpdate", mock.Anything, mock.Anything)
+				d.cardRepo.AssertNotCalled(t, "UpdateBalanceTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			} else {
+				assert.Equal(t, model.PaymentStatusAccepted, payment.Status)
+			}
+		})
+	}
+}
+
+func TestPaymentService_ProcessCardPayment_CurrencyMismatch(t *testing.T) {
+	merchant := &model.Account{ID: uuid.New(), Active: true, IsMerchant: true, Currency: "EUR"}
+	card := &model.Card{ID: uuid.New(), AccountID: uuid.New(), Balance: decimal.RequireFromString("500.00"), Active: true, Currency: "USD"}
+	d := newPaymentTestDeps(merchant, card)
+
+	payment, err := d.service(&config.Config{DefaultCurrency: "USD"}).ProcessCardPayment(context.Background(), merchant.ID, card.ID, decimal.RequireFromString("10.00"), "", "")
+
+	assert.Equal(t, errors.ErrCurrencyMismatch, err)
+	assert.Equal(t, model.PaymentStatusFailed, payment.Status)
+	assert.Equal(t, model.PaymentFailureCurrencyMismatch, payment.FailureReason)
+	d.cardRepo.AssertNotCalled(t, "UpdateBalanceTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	d.accountRepo.AssertNotCalled(t, "CreditBalanceTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPaymentService_ProcessCardPayment_FeeInPaymentCurrency(t *testing.T) {
+	// The server default has two decimals; the fee must still round to whole yen
+	merchant := &model.Account{ID: uuid.New(), Active: true, IsMerchant: true, Currency: "JPY"}
+	card := &model.Card{ID: uuid.New(), AccountID: uuid.New(), Balance: decimal.RequireFromString("5000"), Active: true, Currency: "JPY"}
+	d := newPaymentTestDeps(merchant, card)
+	d.cardRepo.On("UpdateBalanceTx", mock.Anything, mock.Anything, card.ID, mock.Anything).Return(nil)
+	d.cardRepo.On("AddLedgerEntryTx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	d.accountRepo.On("CreditBalanceTx", mock.Anything, mock.Anything, merchant.ID, mock.Anything).Return(nil)
+	cfg := &config.Config{
+		DefaultCurrency:   "USD",
+		PaymentFeePercent: decimal.RequireFromString("2.9"),
+		PaymentFeeFixed:   decimal.RequireFromString("0.30"),
+	}
+
+	payment, err := d.service(cfg).ProcessCardPayment(context.Background(), merchant.ID, card.ID, decimal.RequireFromString("1000"), "", "")
+
+	require.NoError(t, err)
+	assert.Equal(t, "29", payment.FeeAmount.String())
+	assert.Equal(t, "971", payment.NetAmount.String())
+}
+
+func TestPaymentService_ProcessCardPayment_TestModeMovesNoMoney(t *testing.T) {
+	tests := []struct {
+		name           string
+		cardBalance    string
+		expectedStatus model.PaymentStatus
+		expectedErr    error
+	}{
+		{name: "accepted", cardBalance: "500.00", expectedStatus: model.PaymentStatusAccepted},
+		{name: "insufficient balance", cardBalance: "20.00", expectedStatus: model.PaymentStatusFailed, expectedErr: errors.ErrInsufficientBalance},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merchant := &model.Account{ID: uuid.New(), Active: true, IsMerchant: true, TestMode: true}
+			card := &model.Card{ID: uuid.New(), AccountID: uuid.New(), Balance: decimal.RequireFromString(tt.cardBalance), Active: true}
+
+			d := newPaymentTestDeps(merchant, card)
+
+			payment, err := d.service(&config.Config{}).ProcessCardPayment(context.Background(), merchant.ID, card.ID, decimal.RequireFromString("100.00"), "", "")
+
+			assert.Equal(t, tt.expectedErr, err)
+			assert.Equal(t, tt.expectedStatus, payment.Status)
+			assert.True(t, payment.TestMode)
+			assert.Equal(t, tt.cardBalance, card.Balance.StringFixed(2))
+			d.paymentRepo.AssertCalled(t, "Create", mock.Anything, mock.MatchedBy(func(p *model.Payment) bool { return p.TestMode }))
+			d.cardRepo.AssertNotCalled(t, "UpdateBalanceTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			d.cardRepo.AssertNotCalled(t, "AddLedgerEntryTx", mock.Anything, mock.Anything, mock.Anything)
+			d.accountRepo.AssertNotCalled(t, "CreditBalanceTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestPaymentService_ProcessCardPayment_TestModeRejectionsAreMarked(t *testing.T) {
+	merchant := &model.Account{ID: uuid.New(), Active: true, IsMerchant: true, TestMode: true}
+	card := &model.Card{ID: uuid.New(), Balance: decimal.RequireFromString("500.00"), Active: false}
+
+	d := newPaymentTestDeps(merchant, card)
+
+	payment, err := d.service(&config.Config{}).ProcessCardPayment(context.Background(), merchant.ID, card.ID, decimal.RequireFromString("100.00"), "", "")
+
+	require.Error(t, err)
+	assert.Equal(t, model.PaymentFailureCardInactive, payment.FailureReason)
+	assert.True(t, payment.TestMode)
+}
+
+func TestCalculateFee(t *testing.T) {
+	usd, _ := currency.Lookup("USD")
+	tests := []struct {
+		amount, percent, fixed, expected string
+		mode                             currency.RoundingMode
+	}{
+		{amount: "100.00", percent: "0", fixed: "0", expected: "0.00"},
+		{amount: "100.00", percent: "2.9", fixed: "0.30", expected: "3.20"},
+		{amount: "10.55", percent: "2.9", fixed: "0", expected: "0.31"},
+		{amount: "10.00", percent: "0", fixed: "0.25", expected: "0.25"},
+		// 1% of 100.50 is exactly 1.005: half-even keeps the even cent, half-up rounds away
+		{amount: "100.50", percent: "1", fixed: "0", expected: "1.00", mode: currency.RoundHalfEven},
+		{amount: "100.50", percent: "1", fixed: "0", expected: "1.01", mode: currency.RoundHalfUp},
+	}
+
+	for _, tt := range tests {
+		mode := tt.mode
+		if mode == "" {
+			mode = currency.RoundHalfEven
+		}
+		fee := CalculateFee(
+			decimal.RequireFromString(tt.amount),
+			decimal.RequireFromString(tt.percent),
+			decimal.RequireFromString(tt.fixed),
+			usd,
+			mode,
+		)
+		assert.Equal(t, tt.expected, fee.StringFixed(2), "amount=%s percent=%s fixed=%s", tt.amount, tt.percent, tt.fixed)
+		assert.Equal(t, int32(-2), fee.Exponent(), "fee must be stored at cent precision")
+	}
+}
+
+func TestPaymentService_ApplyFee_NoLostCent(t *testing.T) {
+	cfg := &config.Config{
+		PaymentFeePercent: decimal.RequireFromString("2.9"),
+		PaymentFeeFixed:   decimal.RequireFromString("0.30"),
+	}
+	svc := (&paymentTestDeps{}).service(cfg).(*paymentService)
+
+	// Every cent amount from 0.01 to 20.00 in both fee modes: fee + net == gross exactly
+	for cents := int64(1); cents <= 2000; cents++ {
+		for _, passFee := range []bool{false, true} {
+			payment := &model.Payment{Amount: decimal.New(cents, -2)}
+			svc.applyFee(payment, &model.Account{PassFeeToCustomer: passFee}, svc.currency)
+
+			require.True(t, payment.FeeAmount.Add(payment.NetAmount).Equal(payment.GrossAmount),
+				"amount=%s passFee=%v fee=%s net=%s gross=%s", payment.Amount, passFee, payment.FeeAmount, payment.NetAmount, payment.GrossAmount)
+			require.True(t, payment.FeeAmount.Equal(payment.FeeAmount.Round(2)), "fee %s has sub-cent digits", payment.FeeAmount)
+		}
+	}
+}
+
+func TestAllocateFee_DebitsEqualCreditsPlusFees(t *testing.T) {
+	usd, _ := currency.Lookup("USD")
+	rng := rand.New(rand.NewSource(1))
+
+	// Random splits of random amounts under random fees: however the fee's cents are shared
+	// out, what the cards pay equals what the merchant is credited plus the fee
+	for i := 0; i < 5000; i++ {
+		debits := make([]decimal.Decimal, 1+rng.Intn(6))
+		totalDebits := decimal.Zero
+		for j := range debits {
+			debits[j] = decimal.New(1+rng.Int63n(100000), -2)
+			totalDebits = totalDebits.Add(debits[j])
+		}
+		percent := decimal.New(rng.Int63n(1000), -2)
+		fixed := decimal.New(rng.Int63n(100), -2)
+		fee := currency.New(CalculateFee(totalDebits, percent, fixed, usd, currency.RoundHalfEven), usd)
+
+		parts := AllocateFee(fee, debits)
+		require.Len(t, parts, len(debits))
+
+		totalFees, totalCredits := decimal.Zero, decimal.Zero
+		for j, part := range parts {
+			require.True(t, part.Amount.Equal(part.Amount.Round(2)), "fee part %s has sub-cent digits", part.Amount)
+			totalFees = totalFees.Add(part.Amount)
+			totalCredits = totalCredits.Add(debits[j].Sub(part.Amount))
+		}
+		require.True(t, totalFees.Equal(fee.Amount), "fee parts %v sum to %s, want %s", parts, totalFees, fee.Amount)
+		require.True(t, totalDebits.Equal(totalCredits.Add(totalFees)),
+			"debits %v: total %s != credits %s + fees %s", debits, totalDebits, totalCredits, totalFees)
+	}
+}
+
+func TestAllocateFee_NoDebits(t *testing.T) {
+	usd, _ := currency.Lookup("USD")
+	fee := currency.New(decimal.RequireFromString("1.00"), usd)
+
+	assert.Nil(t, AllocateFee(fee, nil))
+	assert.Nil(t, AllocateFee(fee, []decimal.Decimal{decimal.Zero}))
+}
+
+func TestPaymentService_DroppedLogs(t *testing.T) {
+	// No worker reads this queue, so every log finds it full
+	svc := &paymentService{
+		cfg:        &config.Config{PaymentLogOverflowPolicy: config.LogOverflowDrop},
+		logChannel: make(chan model.PaymentLog),
+	}
+
+	svc.logPayment(context.Background(), uuid.New(), model.PaymentStatusFailed, "declined")
+	svc.logPayment(context.Background(), uuid.New(), model.PaymentStatusAccepted, "")
+
+	assert.Equal(t, int64(2), svc.DroppedLogs())
+}
+
+func TestPaymentService_GetPaymentTimeline(t *testing.T) {
+	merchantID := uuid.New()
+	created := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		status   model.PaymentStatus
+		logs     []model.PaymentLog
+		expected []model.PaymentStatus
+		reason   string
+	}{
+		{
+			name:     "accepted",
+			status:   model.PaymentStatusAccepted,
+			logs:     []model.PaymentLog{{Status: model.PaymentStatusAccepted, CreatedAt: created.Add(time.Second)}},
+			expected: []model.PaymentStatus{model.PaymentStatusPending, model.PaymentStatusAccepted},
+		},
+		{
+			name:     "failed with reason",
+			status:   model.PaymentStatusFailed,
+			logs:     []model.PaymentLog{{Status: model.PaymentStatusFailed, ErrorMessage: "insufficient balance", CreatedAt: created.Add(time.Second)}},
+			expected: []model.PaymentStatus{model.PaymentStatusPending, model.PaymentStatusFailed},
+			reason:   "insufficient balance",
+		},
+		{
+			name:     "dropped log is synthesized from the payment",
+			status:   model.PaymentStatusAccepted,
+			expected: []model.PaymentStatus{model.PaymentStatusPending, model.PaymentStatusAccepted},
+		},
+		{
+			name:     "still pending",
+			status:   model.PaymentStatusPending,
+			expected: []model.PaymentStatus{model.PaymentStatusPending},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payment := &model.Payment{ID: uuid.New(), MerchantAccountID: merchantID, Status: tt.status, CreatedAt: created, UpdatedAt: created.Add(2 * time.Second)}
+			d := &paymentTestDeps{paymentRepo: new(MockPaymentRepository), logRepo: new(MockPaymentLogRepository)}
+			d.paymentRepo.On("FindByID", mock.Anything, payment.ID).Return(payment, nil)
+			d.logRepo.On("ListByPaymentID", mock.Anything, payment.ID).Return(tt.logs, nil)
+
+			timeline, err := d.service(&config.Config{}).GetPaymentTimeline(context.Background(), merchantID, payment.ID)
+
+			require.NoError(t, err)
+			var statuses []model.PaymentStatus
+			for _, e := range timeline.Events {
+				statuses = append(statuses, e.Status)
+			}
+			assert.Equal(t, tt.expected, statuses)
+			assert.Equal(t, created, timeline.Events[0].At)
+			if tt.reason != "" {
+				assert.Equal(t, tt.reason, timeline.Events[len(timeline.Events)-1].Message)
+			}
+		})
+	}
+}
+
+func TestPaymentService_GetPaymentTimeline_OtherMerchant(t *testing.T) {
+	payment := &model.Payment{ID: uuid.New(), MerchantAccountID: uuid.New()}
+	d := &paymentTestDeps{paymentRepo: new(MockPaymentRepository), logRepo: new(MockPaymentLogRepository)}
+	d.paymentRepo.On("FindByID", mock.Anything, payment.ID).Return(payment, nil)
+
+	_, err := d.service(&config.Config{}).GetPaymentTimeline(context.Background(), uuid.New(), payment.ID)
+
+	assert.Equal(t, errors.ErrPaymentNotFound, err)
+	d.logRepo.AssertNotCalled(t, "ListByPaymentID", mock.Anything, mock.Anything)
+}
+
+func TestPaymentService_ListMerchantCustomers(t *testing.T) {
+	merchant := &model.Account{ID: uuid.New(), IsMerchant: true}
+	d := &paymentTestDeps{accountRepo: new(MockAccountRepository), paymentRepo: new(MockPaymentRepository), logRepo: new(MockPaymentLogRepository)}
+	d.accountRepo.On("FindByID", mock.Anything, merchant.ID).Return(merchant, nil)
+	d.paymentRepo.On("ListCustomersByMerchant", mock.Anything, merchant.ID, 20, 0).
+		Return([]repository.MerchantCustomer{{Name: "Alice"}}, int64(1), nil)
+
+	customers, total, err := d.service(&config.Config{}).ListMerchantCustomers(context.Background(), merchant.ID, 20, 0)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, "Alice", customers[0].Name)
+}
+
+func TestPaymentService_ListMerchantCustomers_NotMerchant(t *testing.T) {
+	account := &model.Account{ID: uuid.New()}
+	d := &paymentTestDeps{accountRepo: new(MockAccountRepository), paymentRepo: new(MockPaymentRepository), logRepo: new(MockPaymentLogRepository)}
+	d.accountRepo.On("FindByID", mock.Anything, account.ID).Return(account, nil)
+
+	_, _, err := d.service(&config.Config{}).ListMerchantCustomers(context.Background(), account.ID, 20, 0)
+
+	assert.Equal(t, errors.ErrNotMerchant, err)
+	d.paymentRepo.AssertNotCalled(t, "ListCustomersByMerchant", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPaymentService_ListMerchantPayments(t *testing.T) {
+	merchant := &model.Account{ID: uuid.New(), IsMerchant: true}
+	failed := model.PaymentStatusFailed
+	filter := repository.PaymentFilter{Status: &failed, From: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)}
+	d := &paymentTestDeps{accountRepo: new(MockAccountRepository), paymentRepo: new(MockPaymentRepository), logRepo: new(MockPaymentLogRepository)}
+	d.accountRepo.On("FindByID", mock.Anything, merchant.ID).Return(merchant, nil)
+	d.paymentRepo.On("ListByMerchant", mock.Anything, merchant.ID, filter, 20, 40).
+		Return([]model.Payment{{ID: uuid.New(), Status: model.PaymentStatusFailed}}, int64(41), nil)
+
+	payments, total, err := d.service(&config.Config{}).ListMerchantPayments(context.Background(), merchant.ID, filter, 20, 40)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(41), total)
+	require.Len(t, payments, 1)
+	d.paymentRepo.AssertExpectations(t)
+}
+
+func TestPaymentService_ListMerchantPayments_NotMerchant(t *testing.T) {
+	account := &model.Account{ID: uuid.New()}
+	d := &paymentTestDeps{accountRepo: new(MockAccountRepository), paymentRepo: new(MockPaymentRepository), logRepo: new(MockPaymentLogRepository)}
+	d.accountRepo.On("FindByID", mock.Anything, account.ID).Return(account, nil)
+
+	_, _, err := d.service(&config.Config{}).ListMerchantPayments(context.Background(), account.ID, repository.PaymentFilter{}, 20, 0)
+
+	assert.Equal(t, errors.ErrNotMerchant, err)
+	d.paymentRepo.AssertNotCalled(t, "ListByMerchant", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPaymentService_GetPendingSummary(t *testing.T) {
+	merchant := &model.Account{ID: uuid.New(), IsMerchant: true}
+	d := &paymentTestDeps{accountRepo: new(MockAccountRepository), paymentRepo: new(MockPaymentRepository), logRepo: new(MockPaymentLogRepository)}
+	d.accountRepo.On("FindByID", mock.Anything, merchant.ID).Return(merchant, nil)
+	d.paymentRepo.On("SumByMerchantAndStatus", mock.Anything, merchant.ID, InFlightPaymentStatuses).
+		Return([]repository.PaymentStatusTotal{
+			{Status: model.PaymentStatusPending, Count: 3, Total: decimal.RequireFromString("125.50")},
+		}, nil)
+
+	summary, err := d.service(&config.Config{}).GetPendingSummary(context.Background(), merchant.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), summary.Count)
+	assert.True(t, summary.Total.Equal(decimal.RequireFromString("125.50")))
+	require.Len(t, summary.ByStatus, 1)
+	assert.Equal(t, model.PaymentStatusPending, summary.ByStatus[0].Status)
+}
+
+func TestPaymentService_GetPendingSummary_NoneInFlight(t *testing.T) {
+	merchant := &model.Account{ID: uuid.New(), IsMerchant: true}
+	d := &paymentTestDeps{accountRepo: new(MockAccountRepository), paymentRepo: new(MockPaymentRepository), logRepo: new(MockPaymentLogRepository)}
+	d.accountRepo.On("FindByID", mock.Anything, merchant.ID).Return(merchant, nil)
+	d.paymentRepo.On("SumByMerchantAndStatus", mock.Anything, merchant.ID, InFlightPaymentStatuses).
+		Return([]repository.PaymentStatusTotal{}, nil)
+
+	summary, err := d.service(&config.Config{}).GetPendingSummary(context.Background(), merchant.ID)
+
+	require.NoError(t, err)
+	assert.Zero(t, summary.Count)
+	assert.True(t, summary.Total.IsZero())
+	require.Len(t, summary.ByStatus, len(InFlightPaymentStatuses))
+	assert.Zero(t, summary.ByStatus[0].Count)
+	assert.True(t, summary.ByStatus[0].Total.IsZero())
+}
+
+func TestPaymentService_GetPendingSummary_NotMerchant(t *testing.T) {
+	account := &model.Account{ID: uuid.New()}
+	d := &paymentTestDeps{accountRepo: new(MockAccountRepository), paymentRepo: new(MockPaymentRepository), logRepo: new(MockPaymentLogRepository)}
+	d.accountRepo.On("FindByID", mock.Anything, account.ID).Return(account, nil)
+
+	_, err := d.service(&config.Config{}).GetPendingSummary(context.Background(), account.ID)
+
+	assert.Equal(t, errors.ErrNotMerchant, err)
+	d.paymentRepo.AssertNotCalled(t, "SumByMerchantAndStatus", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPaymentService_CancelPayment(t *testing.T) {
+	merchantID := uuid.New()
+	payment := &model.Payment{ID: uuid.New(), MerchantAccountID: merchantID, CardID: uuid.New(), Status: model.PaymentStatusPending}
+
+	paymentRepo := new(MockPaymentRepository)
+	paymentRepo.On("FindByID", mock.Anything, payment.ID).Return(payment, nil)
+	paymentRepo.On("TransitionStatusTx", mock.Anything, mock.Anything, payment.ID, model.PaymentStatusPending, model.PaymentStatusCancelled).Return(true, nil)
+	d := &paymentTestDeps{accountRepo: new(MockAccountRepository), cardRepo: new(MockCardRepository), paymentRepo: paymentRepo, logRepo: new(MockPaymentLogRepository)}
+	d.logRepo.On("CreateBatch", mock.Anything, mock.Anything).Return(nil).Maybe()
+	d.logRepo.On("Create", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	cancelled, err := d.service(&config.Config{}).CancelPayment(context.Background(), merchantID, payment.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, model.PaymentStatusCancelled, cancelled.Status)
+	paymentRepo.AssertExpectations(t)
+	d.cardRepo.AssertNotCalled(t, "UpdateBalanceTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPaymentService_CancelPayment_Rejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  model.PaymentStatus
+		wantErr error
+	}{
+		{name: "accepted", status: model.PaymentStatusAccepted, wantErr: ErrPaymentNotCancellable},
+		{name: "failed", status: model.PaymentStatusFailed, wantErr: ErrPaymentNotCancellable},
+		{name: "already cancelled", status: model.PaymentStatusCancelled, wantErr: ErrPaymentAlreadyCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merchantID := uuid.New()
+			payment := &model.Payment{ID: uuid.New(), MerchantAccountID: merchantID, Status: tt.status}
+			paymentRepo := new(MockPaymentRepository)
+			paymentRepo.On("FindByID", mock.Anything, payment.ID).Return(payment, nil)
+			d := &paymentTestDeps{paymentRepo: paymentRepo, logRepo: new(MockPaymentLogRepository)}
+
+			_, err := d.service(&config.Config{}).CancelPayment(context.Background(), merchantID, payment.ID)
+
+			assert.Equal(t, tt.wantErr, err)
+			paymentRepo.AssertNotCalled(t, "TransitionStatusTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestPaymentService_CancelPayment_SettledConcurrently(t *testing.T) {
+	merchantID := uuid.New()
+	pending := &model.Payment{ID: uuid.New(), MerchantAccountID: merchantID, Status: model.PaymentStatusPending}
+	accepted := &model.Payment{ID: pending.ID, MerchantAccountID: merchantID, Status: model.PaymentStatusAccepted}
+
+	paymentRepo := new(MockPaymentRepository)
+	paymentRepo.On("FindByID", mock.Anything, pending.ID).Return(pending, nil).Once()
+	paymentRepo.On("FindByID", mock.Anything, pending.ID).Return(accepted, nil).Once()
+	paymentRepo.On("TransitionStatusTx", mock.Anything, mock.Anything, pending.ID, model.PaymentStatusPending, model.PaymentStatusCancelled).Return(false, nil)
+	d := &paymentTestDeps{paymentRepo: paymentRepo, logRepo: new(MockPaymentLogRepository)}
+
+	_, err := d.service(&config.Config{}).CancelPayment(context.Background(), merchantID, pending.ID)
+
+	assert.Equal(t, ErrPaymentNotCancellable, err)
+}
+
+func TestPaymentService_CancelPayment_OtherMerchant(t *testing.T) {
+	payment := &model.Payment{ID: uuid.New(), MerchantAccountID: uuid.New(), Status: model.PaymentStatusPending}
+	paymentRepo := new(MockPaymentRepository)
+	paymentRepo.On("FindByID", mock.Anything, payment.ID).Return(payment, nil)
+	d := &paymentTestDeps{paymentRepo: paymentRepo, logRepo: new(MockPaymentLogRepository)}
+
+	_, err := d.service(&config.Config{}).CancelPayment(context.Background(), uuid.New(), payment.ID)
+
+	assert.Equal(t, errors.ErrPaymentNotFound, err)
+	paymentRepo.AssertNotCalled(t, "TransitionStatusTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPaymentService_ProcessCardPayment_CancelledBeforeCharge(t *testing.T) {
+	merchant := &model.Account{ID: uuid.New(), Active: true, IsMerchant: true}
+	card := &model.Card{ID: uuid.New(), Balance: decimal.RequireFromString("500.00"), Active: true}
+
+	d := newPaymentTestDeps(merchant, card)
+	d.paymentRepo = new(MockPaymentRepository)
+	d.paymentRepo.On("Create", mock.Anything, mock.AnythingOfType("*model.Payment")).Return(nil)
+	d.paymentRepo.On("TransitionStatusTx", mock.Anything, mock.Anything, mock.Anything, model.PaymentStatusPending, model.PaymentStatusAccepted).Return(false, nil)
+
+	payment, err := d.service(&config.Config{}).ProcessCardPayment(context.Background(), merchant.ID, card.ID, decimal.RequireFromString("100.00"), "", "")
+
+	require.NoError(t, err)
+	assert.Equal(t, model.PaymentStatusCancelled, payment.Status)
+	d.cardRepo.AssertNotCalled(t, "UpdateBalanceTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	d.accountRepo.AssertNotCalled(t, "CreditBalanceTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// retryTestDeps sets up a chargeable merchant and card plus the failed payment to retry.
+func retryTestDeps(failed *model.Payment) (*paymentTestDeps, *model.Account, *model.Card) {
+	merchant := &model.Account{ID: failed.MerchantAccountID, Active: true, IsMerchant: true}
+	card := &model.Card{ID: failed.CardID, AccountID: uuid.New(), Balance: decimal.RequireFromString("500.00"), Active: true}
+	d := newPaymentTestDeps(merchant, card)
+	d.paymentRepo.On("FindByID", mock.Anything, failed.ID).Return(failed, nil)
+	return d, merchant, card
+}
+
+func TestPaymentService_RetryPayment_TransientFailure(t *testing.T) {
+	failed := &model.Payment{
+		ID: uuid.New(), MerchantAccountID: uuid.New(), CardID: uuid.New(), Amount: decimal.RequireFromString("100.00"),
+		Status: model.PaymentStatusFailed, FailureReason: model.PaymentFailureProcessingError,
+	}
+	d, merchant, card := retryTestDeps(failed)
+	d.paymentRepo.On("CountAcceptedRetries", mock.Anything, failed.ID).Return(int64(0), nil)
+	d.cardRepo.On("UpdateBalanceTx", mock.Anything, mock.Anything, card.ID, decimalEq("400.00")).Return(nil)
+	d.cardRepo.On("AddLedgerEntryTx", mock.Anything, mock.Anything, mock.AnythingOfType("*model.LedgerEntry")).Return(nil)
+	d.accountRepo.On("CreditBalanceTx", mock.Anything, mock.Anything, merchant.ID, decimalEq("100.00")).Return(nil)
+
+	payment, err := d.service(&config.Config{}).RetryPayment(context.Background(), merchant.ID, failed.ID)
+
+	require.NoError(t, err)
+	assert.NotEqual(t, failed.ID, payment.ID)
+	assert.Equal(t, model.PaymentStatusAccepted, payment.Status)
+	require.NotNil(t, payment.RetryOfID)
+	assert.Equal(t, failed.ID, *payment.RetryOfID)
+	assert.Equal(t, model.PaymentStatusFailed, failed.Status, "the original payment is not modified")
+	d.paymentRepo.AssertNotCalled(t, "Update", mock.Anything, failed)
+}
+
+func TestPaymentService_RetryPayment_RetryOfRetryLinksToOriginal(t *testing.T) {
+	originalID := uuid.New()
+	failed := &model.Payment{
+		ID: uuid.New(), MerchantAccountID: uuid.New(), CardID: uuid.New(), Amount: decimal.RequireFromString("100.00"),
+		Status: model.PaymentStatusFailed, FailureReason: model.PaymentFailureProcessingError, RetryOfID: &originalID,
+	}
+	d, merchant, _ := retryTestDeps(failed)
+	d.paymentRepo.On("CountAcceptedRetries", mock.Anything, originalID).Return(int64(1), nil)
+
+	_, err := d.service(&config.Config{}).RetryPayment(context.Background(), merchant.ID, failed.ID)
+
+	assert.Equal(t, ErrPaymentAlreadyRetried, err)
+	d.paymentRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestPaymentService_RetryPayment_Rejected(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        model.PaymentStatus
+		reason        model.PaymentFailureReason
+		otherMerchant bool
+		check         func(t *testing.T, err error)
+	}{
+		{
+			name:   "business failure",
+			status: model.PaymentStatusFailed,
+			reason: model.PaymentFailureInsufficientBalance,
+			check: func(t *testing.T, err error) {
+				var notRetryable *PaymentNotRetryableError
+				require.ErrorAs(t, err, &notRetryable)
+				assert.Equal(t, model.PaymentFailureInsufficientBalance, notRetryable.Reason)
+				assert.Contains(t, err.Error(), "insufficient_balance")
+			},
+		},
+		{
+			name:   "failure recorded before reasons were tracked",
+			status: model.PaymentStatusFailed,
+			check: func(t *testing.T, err error) {
+				var notRetryable *PaymentNotRetryableError
+				assert.ErrorAs(t, err, &notRetryable)
+			},
+		},
+		{
+			name:   "accepted payment",
+			status: model.PaymentStatusAccepted,
+			check:  func(t *testing.T, err error) { assert.Equal(t, ErrPaymentNotFailed, err) },
+		},
+		{
+			name:          "another merchant's payment",
+			status:        model.PaymentStatusFailed,
+			reason:        model.PaymentFailureProcessingError,
+			otherMerchant: true,
+			check:         func(t *testing.T, err error) { assert.Equal(t, errors.ErrPaymentNotFound, err) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failed := &model.Payment{
+				ID: uuid.New(), MerchantAccountID: uuid.New(), CardID: uuid.New(), Amount: decimal.RequireFromString("10.00"),
+				Status: tt.status, FailureReason: tt.reason,
+			}
+			d, merchant, _ := retryTestDeps(failed)
+			callerID := merchant.ID
+			if tt.otherMerchant {
+				callerID = uuid.New()
+			}
+
+			payment, err := d.service(&config.Config{}).RetryPayment(context.Background(), callerID, failed.ID)
+
+			assert.Nil(t, payment)
+			tt.check(t, err)
+			d.paymentRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+// newStaleBalanceCache returns a cache holding a wallet and card entry that claim balance
+// for card, as a read path might have left them before a concurrent charge.
+func newStaleBalanceCache(t *testing.T, card *model.Card, balance string) (*cache.Client, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := cache.New(mr.Addr(), "", 0)
+	stale := *card
+	stale.Balance = decimal.RequireFromString(balance)
+	payload, err := json.Marshal(Wallet{Balance: stale.Balance, Cards: []model.Card{stale}})
+	require.NoError(t, err)
+	require.NoError(t, mr.Set(walletCacheKey(card.AccountID), string(payload)))
+	require.NoError(t, mr.Set("card:"+card.ID.String(), balance))
+	return client, mr
+}
+
+func TestPaymentService_ProcessCardPayment_IgnoresCachedBalance(t *testing.T) {
+	merchant := &model.Account{ID: uuid.New(), Active: true, IsMerchant: true}
+
+	t.Run("stale high cache does not authorize", func(t *testing.T) {
+		card := &model.Card{ID: uuid.New(), AccountID: uuid.New(), Balance: decimal.RequireFromString("10.00"), Active: true}
+		cacheClient, _ := newStaleBalanceCache(t, card, "1000.00")
+
+		d := newPaymentTestDeps(merchant, card)
+		svc := NewPaymentService(d.accountRepo, d.cardRepo, d.paymentRepo, d.logRepo, d.payoutRepo, &MockTxManager{}, nil, nil, cacheClient, &config.Config{})
+
+		payment, err := svc.ProcessCardPayment(context.Background(), merchant.ID, card.ID, decimal.RequireFromString("50.00"), "", "")
+
+		assert.Equal(t, errors.ErrInsufficientBalance, err)
+		assert.Equal(t, model.PaymentFailureInsufficientBalance, payment.FailureReason)
+		d.cardRepo.AssertNotCalled(t, "UpdateBalanceTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("stale low cache does not refuse", func(t *testing.T) {
+		card := &model.Card{ID: uuid.New(), AccountID: uuid.New(), Balance: decimal.RequireFromString("500.00"), Active: true}
+		cacheClient, mr := newStaleBalanceCache(t, card, "0.00")
+
+		d := newPaymentTestDeps(merchant, card)
+		d.cardRepo.On("UpdateBalanceTx", mock.Anything, mock.Anything, card.ID, decimalEq("450.00")).Return(nil)
+		d.cardRepo.On("AddLedgerEntryTx", mock.Anything, mock.Anything, mock.AnythingOfType("*model.LedgerEntry")).Return(nil)
+		d.accountRepo.On("CreditBalanceTx", mock.Anything, mock.Anything, merchant.ID, decimalEq("50.00")).Return(nil)
+		svc := NewPaymentService(d.accountRepo, d.cardRepo, d.paymentRepo, d.logRepo, d.payoutRepo, &MockTxManager{}, nil, nil, cacheClient, &config.Config{})
+
+		payment, err := svc.ProcessCardPayment(context.Background(), merchant.ID, card.ID, decimal.RequireFromString("50.00"), "", "")
+
+		require.NoError(t, err)
+		assert.Equal(t, model.PaymentStatusAccepted, payment.Status)
+		assert.False(t, mr.Exists(walletCacheKey(card.AccountID)), "the stale wallet is dropped after the charge")
+		assert.False(t, mr.Exists("card:"+card.ID.String()))
+	})
+}
+
+func TestPaymentService_ListCardPayments(t *testing.T) {
+	card := &model.Card{ID: uuid.New(), AccountID: uuid.New()}
+	payments := []repository.CardPayment{{Payment: model.Payment{ID: uuid.New(), CardID: card.ID}, MerchantName: "Corner Shop"}}
+
+	d := &paymentTestDeps{cardRepo: new(MockCardRepository), paymentRepo: new(MockPaymentRepository), logRepo: new(MockPaymentLogRepository)}
+	d.cardRepo.On("FindByID", mock.Anything, card.ID).Return(card, nil)
+	d.paymentRepo.On("ListByCard", mock.Anything, card.ID, CardPaymentStatuses, 20, 0).Return(payments, int64(1), nil)
+
+	page, err := d.service(&config.Config{}).ListCardPayments(context.Background(), card.ID, 20, 0)
+
+	require.NoError(t, err)
+	assert.Equal(t, card, page.Card)
+	assert.Equal(t, payments, page.Payments)
+	assert.Equal(t, int64(1), page.Total)
+}
+
+func TestPaymentService_ListCardPayments_CardNotFound(t *testing.T) {
+	cardID := uuid.New()
+	d := &paymentTestDeps{cardRepo: new(MockCardRepository), paymentRepo: new(MockPaymentRepository), logRepo: new(MockPaymentLogRepository)}
+	d.cardRepo.On("FindByID", mock.Anything, cardID).Return(nil, gorm.ErrRecordNotFound)
+
+	_, err := d.service(&config.Config{}).ListCardPayments(context.Background(), cardID, 20, 0)
+
+	assert.Equal(t, errors.ErrCardNotFound, err)
+	d.paymentRepo.AssertNotCalled(t, "ListByCard", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPaymentService_ProcessCardPayment_LockTimeout(t *testing.T) {
+	merchant := &model.Account{ID: uuid.New(), Active: true, IsMerchant: true}
+	card := &model.Card{ID: uuid.New(), AccountID: uuid.New(), Balance: decimal.RequireFromString("100.00"), Active: true}
+	lockErr := fmt.Errorf("card %s: %w", card.ID, errors.ErrLockTimeout)
+
+	d := &paymentTestDeps{
+		accountRepo: new(MockAccountRepository),
+		cardRepo:    new(MockCardRepository),
+		paymentRepo: new(MockPaymentRepository),
+		logRepo:     new(MockPaymentLogRepository),
+	}
+	d.accountRepo.On("FindByID", mock.Anything, merchant.ID).Return(merchant, nil)
+	d.cardRepo.On("FindByIDForUpdate", mock.Anything, card.ID).Return(card, nil)
+	d.cardRepo.On("FindByIDForUpdateTx", mock.Anything, mock.Anything, card.ID).Return(nil, lockErr)
+	d.paymentRepo.On("Create", mock.Anything, mock.AnythingOfType("*model.Payment")).Return(nil)
+	d.paymentRepo.On("Update", mock.Anything, mock.AnythingOfType("*model.Payment")).Return(nil)
+	d.logRepo.On("CreateBatch", mock.Anything, mock.Anything).Return(nil).Maybe()
+	d.logRepo.On("Create", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	payment, err := d.service(&config.Config{}).ProcessCardPayment(context.Background(), merchant.ID, card.ID, decimal.RequireFromString("10.00"), "", "")
+
+	assert.ErrorIs(t, err, errors.ErrLockTimeout)
+	assert.Equal(t, model.PaymentFailureProcessingError, payment.FailureReason, "a lock timeout can be retried")
+	httpErr := errors.MapErrorToHTTP(err)
+	assert.Equal(t, http.StatusServiceUnavailable, httpErr.StatusCode)
+	assert.Equal(t, errors.CodeLockTimeout, httpErr.Code)
+	d.cardRepo.AssertNotCalled(t, "UpdateBalanceTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
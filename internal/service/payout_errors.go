@@ -0,0 +1,23 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// PayoutReserveError is returned when a payout would leave the merchant's available balance
+// below the required reserve. MaxPayable is the largest payout that would be accepted.
+type PayoutReserveError struct {
+	Reserve    decimal.Decimal
+	MaxPayable decimal.Decimal
+}
+
+func (e *PayoutReserveError) Error() string {
+	return fmt.Sprintf("payout would breach the %s reserve; maximum payable is %s",
+		e.Reserve.StringFixed(2), e.MaxPayable.StringFixed(2))
+}
+
+// ErrPayoutNotFound is returned when a payout does not exist or belongs to another merchant.
+var ErrPayoutNotFound = errors.New("payout not found")
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"paytabs/internal/clock"
+	"paytabs/internal/config"
+	"paytabs/internal/currency"
+	"paytabs/internal/errors"
+	"paytabs/internal/model"
+	"paytabs/internal/repository"
+)
+
+// MerchantBalance splits a merchant's account balance into what is held by in-flight
+// payouts and what is available to spend or pay out.
+type MerchantBalance struct {
+	Total     decimal.Decimal
+	Held      decimal.Decimal
+	Available decimal.Decimal
+}
+
+// PayoutService handles merchant payouts and payout-aware balances.
+type PayoutService interface {
+	GetMerchantBalance(ctx context.Context, merchantAccountID uuid.UUID) (*MerchantBalance, error)
+	RequestPayout(ctx context.Context, merchantAccountID uuid.UUID, amount decimal.Decimal) (*model.Payout, error)
+	ListPayouts(ctx context.Context, merchantAccountID uuid.UUID, status model.PayoutStatus, limit, offset int) (*PayoutPage, error)
+	GetPayout(ctx context.Context, merchantAccountID uuid.UUID, payoutID uuid.UUID) (*model.Payout, error)
+}
+
+// PayoutPage is a page of a merchant's payout history.
+type PayoutPage struct {
+	Payouts []model.Payout
+	Total   int64
+	// TotalPaidOut is the sum of every payout the merchant has been paid, whatever the page
+	// or status filter.
+	TotalPaidOut decimal.Decimal
+}
+
+type payoutService struct {
+	accountRepo repository.AccountRepository
+	payoutRepo  repository.PayoutRepository
+	paymentRepo repository.PaymentRepository
+	txManager   repository.TxManager
+	clock       clock.Clock
+	cfg         *config.Config
+	currency    currency.Currency
+	rounding    currency.RoundingMode
+}
+
+// NewPayoutService creates a new payout service.
+func NewPayoutService(
+	accountRepo repository.AccountRepository,
+	payoutRepo repository.PayoutRepository,
+	paymentRepo repository.PaymentRepository,
+	txManager repository.TxManager,
+	clk clock.Clock,
+	cfg *config.Config,
+) PayoutService {
+	cur, rounding := moneyRounding(cfg)
+	return &payoutService{
+		accountRepo: accountRepo,
+		payoutRepo:  payoutRepo,
+		paymentRepo: paymentRepo,
+		txManager:   txManager,
+		clock:       clk,
+		cfg:         cfg,
+		currency:    cur,
+		rounding:    rounding,
+	}
+}
+
+// GetMerchantBalance returns the merchant's total account balance, the amount held by
+// pending payouts, and the difference as available.
+func (s *payoutService) GetMerchantBalance(ctx context.Context, merchantAccountID uuid.UUID) (*MerchantBalance, error) {
+	account, err := s.getMerchant(ctx, merchantAccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	held, err := s.payoutRepo.SumPendingByMerchant(ctx, merchantAccountID)
+	if err != nil {
+		return nil, fmt.Errorf("sum pending payouts: %w", err)
+	}
+
+	return &MerchantBalance{
+		Total:     account.Balance,
+		Held:      held,
+		Available: account.Balance.Sub(held),
+	}, nil
+}
+
+// RequestPayout creates a pending payout. The merchant's available balance after the payout
+// must stay at or above the reserve; otherwise a *PayoutReserveError reports the maximum
+// payable. The account row is locked while checking so concurrent requests cannot both
+// pass against the same balance.
+func (s *payoutService) RequestPayout(ctx context.Context, merchantAccountID uuid.UUID, amount decimal.Decimal) (*model.Payout, error) {
+	if !amount.IsPositive() || !amount.Equal(currency.RoundToCurrency(amount, s.currency, currency.RoundDown)) {
+		return nil, errors.ErrInvalidAmount
+	}
+	if _, err := s.getMerchant(ctx, merchantAccountID); err != nil {
+		return nil, err
+	}
+
+	reserve, err := merchantReserve(ctx, s.paymentRepo, s.cfg, s.clock, s.currency, merchantAccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	payout := &model.Payout{
+		MerchantAccountID: merchantAccountID,
+		Amount:            amount,
+		Status:            model.PayoutStatusPending,
+	}
+	err = s.txManager.WithTransaction(ctx, func(ctx context.Context, tx interface{}) error {
+		account, err := s.accountRepo.FindByIDForUpdateTx(ctx, tx, merchantAccountID)
+		if err != nil {
+			return err
+		}
+		held, err := s.payoutRepo.SumPendingByMerchantTx(ctx, tx, merchantAccountID)
+		if err != nil {
+			return fmt.Errorf("sum pending payouts: %w", err)
+		}
+
+		available := currency.New(account.Balance, s.currency).Sub(currency.New(held, s.currency))
+		maxPayable := available.Sub(reserve).Max(currency.Zero(s.currency))
+		if currency.New(amount, s.currency).GreaterThan(maxPayable) {
+			return &PayoutReserveError{Reserve: reserve.Amount, MaxPayable: maxPayable.Amount}
+		}
+		return s.payoutRepo.CreateTx(ctx, tx, payout)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payout, nil
+}
+
+// ListPayouts lists the merchant's payouts, newest first, optionally only those in status.
+func (s *payoutService) ListPayouts(ctx context.Context, merchantAccountID uuid.UUID, status model.PayoutStatus, limit, offset int) (*PayoutPage, error) {
+	if _, err := s.getMerchant(ctx, merchantAccountID); err != nil {
+		return nil, err
+	}
+
+	payouts, total, err := s.payoutRepo.ListByMerchant(ctx, merchantAccountID, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("list payouts: %w", err)
+	}
+	paid, err := s.payoutRepo.SumPaidByMerchant(ctx, merchantAccountID)
+	if err != nil {
+		return nil, fmt.Errorf("sum paid payouts: %w", err)
+	}
+	return &PayoutPage{Payouts: payouts, Total: total, TotalPaidOut: paid}, nil
+}
+
+// GetPayout returns one of the merchant's payouts. Payouts of other merchants are reported
+// as not found.
+func (s *payoutService) GetPayout(ctx context.Context, merchantAccountID uuid.UUID, payoutID uuid.UUID) (*model.Payout, error) {
+	payout, err := s.payoutRepo.FindByID(ctx, payoutID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrPayoutNotFound
+		}
+		return nil, fmt.Errorf("get payout: %w", err)
+	}
+	if payout.MerchantAccountID != merchantAccountID {
+		return nil, ErrPayoutNotFound
+	}
+	return payout, nil
+}
+
+// merchantReserve returns what the merchant must keep after a payout or refund: the larger of
+// the flat reserve and the configured percentage of accepted payment volume over the reserve
+// window.
+func merchantReserve(ctx context.Context, paymentRepo repository.PaymentRepository, cfg *config.Config, clk clock.Clock, cur currency.Currency, merchantAccountID uuid.UUID) (currency.Money, error) {
+	reserve := currency.New(cfg.PayoutReserveFixed, cur).Max(currency.Zero(cur))
+	if !cfg.PayoutReservePercent.IsPositive() {
+		return reserve, nil
+	}
+
+	volume, err := paymentRepo.SumAcceptedByMerchantSince(ctx, merchantAccountID, clk.Now().Add(-cfg.PayoutReserveWindow))
+	if err != nil {
+		return currency.Money{}, fmt.Errorf("sum recent payment volume: %w", err)
+	}
+	// Round up so the reserve never falls a fraction of a cent short
+	pct := currency.New(volume, cur).Mul(cfg.PayoutReservePercent.Div(hundred), currency.RoundUp)
+	return reserve.Max(pct), nil
+}
+
+// getMerchant loads the account and rejects non-merchants.
+func (s *payoutService) getMerchant(ctx context.Context, merchantAccountID uuid.UUID) (*model.Account, error) {
+	account, err := s.accountRepo.FindByID(ctx, merchantAccountID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.ErrAccountNotFound
+		}
+		return nil, fmt.Errorf("get account: %w", err)
+	}
+	if !account.IsMerchant {
+		return nil, errors.ErrNotMerchant
+	}
+	return account, nil
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"paytabs/internal/clock"
+	"paytabs/internal/config"
+	"paytabs/internal/errors"
+	"paytabs/internal/model"
+)
+
+func TestPayoutService_GetMerchantBalance(t *testing.T) {
+	merchant := &model.Account{ID: uuid.New(), IsMerchant: true, Balance: decimal.RequireFromString("250.00")}
+
+	accountRepo := new(MockAccountRepository)
+	payoutRepo := new(MockPayoutRepository)
+	accountRepo.On("FindByID", mock.Anything, merchant.ID).Return(merchant, nil)
+	payoutRepo.On("SumPendingByMerchant", mock.Anything, merchant.ID).Return(decimal.RequireFromString("100.50"), nil)
+
+	balance, err := NewPayoutService(accountRepo, payoutRepo, nil, &MockTxManager{}, nil, &config.Config{}).GetMerchantBalance(context.Background(), merchant.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, "250.00", balance.Total.StringFixed(2))
+	assert.Equal(t, "100.50", balance.Held.StringFixed(2))
+	assert.Equal(t, "149.50", balance.Available.StringFixed(2))
+}
+
+func TestPayoutService_GetMerchantBalance_NotMerchant(t *testing.T) {
+	account := &model.Account{ID: uuid.New()}
+
+	accountRepo := new(MockAccountRepository)
+	payoutRepo := new(MockPayoutRepository)
+	accountRepo.On("FindByID", mock.Anything, account.ID).Return(account, nil)
+
+	_, err := NewPayoutService(accountRepo, payoutRepo, nil, &MockTxManager{}, nil, &config.Config{}).GetMerchantBalance(context.Background(), account.ID)
+
+	assert.Equal(t, errors.ErrNotMerchant, err)
+	payoutRepo.AssertNotCalled(t, "SumPendingByMerchant", mock.Anything, mock.Anything)
+}
+
+func TestPayoutService_RequestPayout_ReserveBoundary(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	cfg := &config.Config{
+		PayoutReserveFixed:   decimal.RequireFromString("50.00"),
+		PayoutReservePercent: decimal.RequireFromString("10"),
+		PayoutReserveWindow:  30 * 24 * time.Hour,
+	}
+
+	tests := []struct {
+		name       string
+		volume     string
+		amount     string
+		wantErr    bool
+		maxPayable string
+	}{
+		// balance 1000, held 100 => available 900
+		{name: "flat reserve, exactly at max", volume: "200.00", amount: "850.00"},
+		{name: "flat reserve, one cent over", volume: "200.00", amount: "850.01", wantErr: true, maxPayable: "850.00"},
+		{name: "percentage reserve wins, exactly at max", volume: "3000.00", amount: "600.00"},
+		{name: "percentage reserve wins, one cent over", volume: "3000.00", amount: "600.01", wantErr: true, maxPayable: "600.00"},
+		// 10% of 3000.05 is 300.005, rounded up so the reserve is never a fraction short
+		{name: "percentage reserve rounds up", volume: "3000.05", amount: "600.00", wantErr: true, maxPayable: "599.99"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merchant := &model.Account{ID: uuid.New(), IsMerchant: true, Balance: decimal.RequireFromString("1000.00")}
+
+			accountRepo := new(MockAccountRepository)
+			payoutRepo := new(MockPayoutRepository)
+			paymentRepo := new(MockPaymentRepository)
+			accountRepo.On("FindByID", mock.Anything, merchant.ID).Return(merchant, nil)
+			accountRepo.On("FindByIDForUpdateTx", mock.Anything, mock.Anything, merchant.ID).Return(merchant, nil)
+			payoutRepo.On("SumPendingByMerchantTx", mock.Anything, mock.Anything, merchant.ID).Return(decimal.RequireFromString("100.00"), nil)
+			payoutRepo.On("CreateTx", mock.Anything, mock.Anything, mock.AnythingOfType("*model.Payout")).Return(nil)
+			paymentRepo.On("SumAcceptedByMerchantSince", mock.Anything, merchant.ID, now.Add(-cfg.PayoutReserveWindow)).
+				Return(decimal.RequireFromString(tt.volume), nil)
+
+			svc := NewPayoutService(accountRepo, payoutRepo, paymentRepo, &MockTxManager{}, clock.NewFixed(now), cfg)
+			payout, err := svc.RequestPayout(context.Background(), merchant.ID, decimal.RequireFromString(tt.amount))
+
+			if !tt.wantErr {
+				require.NoError(t, err)
+				assert.Equal(t, model.PayoutStatusPending, payout.Status)
+				assert.Equal(t, tt.amount, payout.Amount.StringFixed(2))
+				return
+			}
+			var reserveErr *PayoutReserveError
+			require.ErrorAs(t, err, &reserveErr)
+			assert.Equal(t, tt.maxPayable, reserveErr.MaxPayable.StringFixed(2))
+			assert.Contains(t, err.Error(), "maximum payable is "+tt.maxPayable)
+			payoutRepo.AssertNotCalled(t, "CreateTx", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestPayoutService_RequestPayout_ReserveAboveAvailable(t *testing.T) {
+	merchant := &model.Account{ID: uuid.New(), IsMerchant: true, Balance: decimal.RequireFromString("40.00")}
+	accountRepo := new(MockAccountRepository)
+	payoutRepo := new(MockPayoutRepository)
+	accountRepo.On("FindByID", mock.Anything, merchant.ID).Return(merchant, nil)
+	accountRepo.On("FindByIDForUpdateTx", mock.Anything, mock.Anything, merchant.ID).Return(merchant, nil)
+	payoutRepo.On("SumPendingByMerchantTx", mock.Anything, mock.Anything, merchant.ID).Return(decimal.Zero, nil)
+
+	cfg := &config.Config{PayoutReserveFixed: decimal.RequireFromString("50.00")}
+	_, err := NewPayoutService(accountRepo, payoutRepo, nil, &MockTxManager{}, nil, cfg).
+		RequestPayout(context.Background(), merchant.ID, decimal.RequireFromString("0.01"))
+
+	var reserveErr *PayoutReserveError
+	require.ErrorAs(t, err, &reserveErr)
+	assert.True(t, reserveErr.MaxPayable.IsZero())
+}
+
+func TestPayoutService_RequestPayout_InvalidAmount(t *testing.T) {
+	svc := NewPayoutService(new(MockAccountRepository), new(MockPayoutRepository), nil, &MockTxManager{}, nil, &config.Config{})
+
+	for _, amount := range []string{"0", "-5.00", "10.001"} {
+		_, err := svc.RequestPayout(context.Background(), uuid.New(), decimal.RequireFromString(amount))
+		assert.Equal(t, errors.ErrInvalidAmount, err, amount)
+	}
+}
+
+func TestPayoutService_ListPayouts(t *testing.T) {
+	merchant := &model.Account{ID: uuid.New(), IsMerchant: true}
+	payouts := []model.Payout{
+		{ID: uuid.New(), MerchantAccountID: merchant.ID, Amount: decimal.RequireFromString("40.00"), Status: model.PayoutStatusPaid},
+		{ID: uuid.New(), MerchantAccountID: merchant.ID, Amount: decimal.RequireFromString("60.00"), Status: model.PayoutStatusPaid},
+	}
+
+	accountRepo := new(MockAccountRepository)
+	payoutRepo := new(MockPayoutRepository)
+	accountRepo.On("FindByID", mock.Anything, merchant.ID).Return(merchant, nil)
+	payoutRepo.On("ListByMerchant", mock.Anything, merchant.ID, model.PayoutStatusPaid, 2, 0).Return(payouts, int64(5), nil)
+	payoutRepo.On("SumPaidByMerchant", mock.Anything, merchant.ID).Return(decimal.RequireFromString("310.00"), nil)
+
+	page, err := NewPayoutService(accountRepo, payoutRepo, nil, &MockTxManager{}, nil, &config.Config{}).ListPayouts(context.Background(), merchant.ID, model.PayoutStatusPaid, 2, 0)
+
+	require.NoError(t, err)
+	assert.Equal(t, payouts, page.Payouts)
+	assert.Equal(t, int64(5), page.Total)
+	assert.Equal(t, "310.00", page.TotalPaidOut.StringFixed(2), "the paid total covers every paid payout, not just the page")
+}
+
+func TestPayoutService_ListPayouts_NotMerchant(t *testing.T) {
+	account := &model.Account{ID: uuid.New()}
+
+	accountRepo := new(MockAccountRepository)
+	payoutRepo := new(MockPayoutRepository)
+	accountRepo.On("FindByID", mock.Anything, account.ID).Return(account, nil)
+
+	_, err := NewPayoutService(accountRepo, payoutRepo, nil, &MockTxManager{}, nil, &config.Config{}).ListPayouts(context.Background(), account.ID, "", 20, 0)
+
+	assert.Equal(t, errors.ErrNotMerchant, err)
+	payoutRepo.AssertNotCalled(t, "ListByMerchant", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPayoutService_GetPayout(t *testing.T) {
+	merchantID := uuid.New()
+	payout := &model.Payout{ID: uuid.New(), MerchantAccountID: merchantID, Amount: decimal.RequireFromString("40.00"), Status: model.PayoutStatusPending}
+
+	payoutRepo := new(MockPayoutRepository)
+	payoutRepo.On("FindByID", mock.Anything, payout.ID).Return(payout, nil)
+	payoutRepo.On("FindByID", mock.Anything, mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+	svc := NewPayoutService(new(MockAccountRepository), payoutRepo, nil, &MockTxManager{}, nil, &config.Config{})
+
+	got, err := svc.GetPayout(context.Background(), merchantID, payout.ID)
+	require.NoError(t, err)
+	assert.Equal(t, payout, got)
+
+	_, err = svc.GetPayout(context.Background(), uuid.New(), payout.ID)
+	assert.Equal(t, ErrPayoutNotFound, err, "another merchant's payout")
+
+	_, err = svc.GetPayout(context.Background(), merchantID, uuid.New())
+	assert.Equal(t, ErrPayoutNotFound, err)
+}
@@ -0,0 +1,18 @@
+package service
+
+import "errors"
+
+var (
+	// ErrSameCard is returned when a transfer's source and destination are the same card.
+	ErrSameCard = errors.New("cannot transfer to the same card")
+	// ErrSourceCardNotFound is returned when a transfer's source card does not exist.
+	ErrSourceCardNotFound = errors.New("source card not found")
+	// ErrDestinationCardNotFound is returned when a transfer's destination card does not exist.
+	ErrDestinationCardNotFound = errors.New("destination card not found")
+	// ErrSourceCardInactive is returned when a transfer's source card is not active.
+	ErrSourceCardInactive = errors.New("source card is not active")
+	// ErrDestinationCardInactive is returned when a transfer's destination card is not active.
+	ErrDestinationCardInactive = errors.New("destination card is not active")
+	// ErrTransferNotCardOwner is returned when the caller does not own a transfer's source card.
+	ErrTransferNotCardOwner = errors.New("source card belongs to another account")
+)
@@ -3,12 +3,15 @@ package service
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 
 	"paytabs/internal/cache"
+	"paytabs/internal/clock"
+	"paytabs/internal/config"
 	"paytabs/internal/errors"
 	"paytabs/internal/model"
 	"paytabs/internal/repository"
@@ -16,13 +19,68 @@ import (
 
 // TransferService handles card-to-card transfer operations.
 type TransferService interface {
-	ProcessTransfer(ctx context.Context, sourceCardID, destinationCardID uuid.UUID, amount decimal.Decimal) (*model.Transfer, error)
+	ProcessTransfer(ctx context.Context, accountID, sourceCardID, destinationCardID uuid.UUID, amount decimal.Decimal) (*model.Transfer, error)
+	ValidateTransfer(ctx context.Context, accountID, sourceCardID, destinationCardID uuid.UUID, amount decimal.Decimal) (*TransferCheck, error)
+	ListAccountTransfers(ctx context.Context, accountID uuid.UUID, limit, offset int) ([]repository.AccountTransfer, int64, error)
+	GetCardTransferStats(ctx context.Context, cardID uuid.UUID, from, to time.Time) (*CardTransferStats, error)
+	ListCardTransfers(ctx context.Context, cardID uuid.UUID, status model.TransferStatus, limit, offset int) (*CardTransferPage, error)
+}
+
+// TransferRejection is a machine-readable reason a transfer would fail.
+type TransferRejection struct {
+	Code    errors.Code `json:"code"`
+	Message string      `json:"message"`
+}
+
+// TransferCheck is the outcome of validating a transfer without moving money.
+// Cards are nil when they could not be found.
+type TransferCheck struct {
+	SourceCard                  *model.Card
+	DestinationCard             *model.Card
+	Fee                         decimal.Decimal
+	ProjectedSourceBalance      decimal.Decimal
+	ProjectedDestinationBalance decimal.Decimal
+	Rejections                  []TransferRejection
+}
+
+// TransferDirectionStats summarizes a card's completed transfers in one direction.
+type TransferDirectionStats struct {
+	Count   int64
+	Total   decimal.Decimal
+	Average decimal.Decimal // Zero when Count is zero
+}
+
+// CardTransferStats summarizes a card's completed transfers over a period. BusiestDay is
+// nil when the card had no transfers in the period.
+type CardTransferStats struct {
+	Card       *model.Card
+	Inbound    TransferDirectionStats
+	Outbound   TransferDirectionStats
+	BusiestDay *repository.TransferDayTotal
+}
+
+// CardTransferPage is a page of a card's transfers, each annotated with its direction
+// relative to the card.
+type CardTransferPage struct {
+	Card      *model.Card
+	Transfers []repository.AccountTransfer
+	Total     int64
+}
+
+// OK reports whether the transfer would succeed.
+func (c *TransferCheck) OK() bool {
+	return len(c.Rejections) == 0
 }
 
 type transferService struct {
 	cardRepo     repository.CardRepository
 	transferRepo repository.TransferRepository
 	cache        *cache.Client
+	cfg          *config.Config
+	clock        clock.Clock
+	dailySpend   *dailySpendTracker
+	killSwitch   *killSwitch
+	holds        *accountHolds
 }
 
 // NewTransferService creates a new transfer service.
@@ -30,70 +88,106 @@ func NewTransferService(
 	cardRepo repository.CardRepository,
 	transferRepo repository.TransferRepository,
 	cache *cache.Client,
+	cfg *config.Config,
 ) TransferService {
+	clk := clock.New()
 	return &transferService{
 		cardRepo:     cardRepo,
 		transferRepo: transferRepo,
 		cache:        cache,
+		cfg:          cfg,
+		clock:        clk,
+		dailySpend:   newDailySpendTracker(cache, cardRepo, clk, cfg.MaxDailyCardSpend),
+		killSwitch:   newKillSwitch(cache, cfg),
+		holds:        newAccountHolds(cache, clk, cfg),
 	}
 }
 
-// ProcessTransfer processes a card-to-card transfer with atomic balance updates.
-func (s *transferService) ProcessTransfer(ctx context.Context, sourceCardID, destinationCardID uuid.UUID, amount decimal.Decimal) (*model.Transfer, error) {
-	// Validate amount
-	if amount.LessThanOrEqual(decimal.Zero) {
-		return nil, errors.ErrInvalidAmount
+// ProcessTransfer processes a card-to-card transfer with atomic balance updates on behalf of
+// accountID, which must own the source card.
+func (s *transferService) ProcessTransfer(ctx context.Context, accountID, sourceCardID, destinationCardID uuid.UUID, amount decimal.Decimal) (*model.Transfer, error) {
+	if _, err := s.checkTransferAllowed(ctx, accountID, sourceCardID); err != nil {
+		return nil, err
+	}
+	if err := s.checkAmount(amount); err != nil {
+		return nil, err
 	}
-
-	// Prevent self-transfer
 	if sourceCardID == destinationCardID {
-		return nil, fmt.Errorf("cannot transfer to the same card")
+		return nil, ErrSameCard
+	}
+	// Reserved up front and given back unless the transfer completes, so concurrent transfers
+	// cannot both fit under the limit
+	releaseSpend, err := s.dailySpend.Reserve(ctx, sourceCardID, amount)
+	if err != nil {
+		return nil, err
 	}
+	transferred := false
+	defer func() {
+		if !transferred {
+			releaseSpend()
+		}
+	}()
 
-	// Create transfer record
+	// Create transfer record; the ID is assigned up front so ledger entries can reference it
 	transfer := &model.Transfer{
+		ID:                uuid.New(),
 		SourceCardID:      sourceCardID,
 		DestinationCardID: destinationCardID,
 		Amount:            amount,
 		Status:            model.TransferStatusPending,
 	}
 
+	// Owning accounts, captured for wallet cache invalidation
+	var sourceAccountID, destAccountID uuid.UUID
+
 	// Use transaction for atomic balance updates
-	err := s.cardRepo.WithTransaction(ctx, func(ctx context.Context, txRepo repository.CardRepository) error {
-		// Lock and fetch source card
+	err = s.cardRepo.WithTransaction(ctx, func(ctx context.Context, txRepo repository.CardRepository) error {
+		// Lock and fetch source card. Its balance comes from this locked row only, never
+		// from the cached wallet, which can be stale.
 		sourceCard, err := txRepo.FindByIDForUpdate(ctx, sourceCardID)
 		if err != nil {
 			if err == gorm.ErrRecordNotFound {
-				transfer.Status = model.TransferStatusFailed
-				transfer.ErrorMessage = "source card not found"
-				return fmt.Errorf("source card not found")
+				err = ErrSourceCardNotFound
 			}
 			transfer.Status = model.TransferStatusFailed
 			transfer.ErrorMessage = err.Error()
 			return err
 		}
 
-		// Validate source card is active
-		if !sourceCard.Active {
+		// Holds are summed under the card lock, as PlaceHold takes it too, so a hold placed
+		// concurrently is either counted here or sees the debited balance
+		held, err := txRepo.SumActiveHolds(ctx, sourceCardID, s.clock.Now())
+		if err != nil {
 			transfer.Status = model.TransferStatusFailed
-			transfer.ErrorMessage = "source card is not active"
-			return fmt.Errorf("source card is not active")
+			transfer.ErrorMessage = fmt.Sprintf("failed to sum source holds: %v", err)
+			return err
 		}
 
-		// Check sufficient balance
-		if sourceCard.Balance.LessThan(amount) {
+		// Validate source card is active and can cover the amount outside its holds
+		if err := checkSourceCard(sourceCard, held, amount); err != nil {
 			transfer.Status = model.TransferStatusFailed
-			transfer.ErrorMessage = errors.ErrInsufficientBalance.Error()
-			return errors.ErrInsufficientBalance
+			transfer.ErrorMessage = err.Error()
+			return err
+		}
+
+		if err := s.checkOwnerActive(ctx, txRepo, sourceCard); err != nil {
+			transfer.Status = model.TransferStatusFailed
+			transfer.ErrorMessage = err.Error()
+			return err
+		}
+
+		// A held source owner cannot send money until the hold expires or is lifted
+		if err := s.holds.Check(ctx, sourceCard.AccountID); err != nil {
+			transfer.Status = model.TransferStatusFailed
+			transfer.ErrorMessage = err.Error()
+			return err
 		}
 
 		// Lock and fetch destination card
 		destCard, err := txRepo.FindByIDForUpdate(ctx, destinationCardID)
 		if err != nil {
 			if err == gorm.ErrRecordNotFound {
-				transfer.Status = model.TransferStatusFailed
-				transfer.ErrorMessage = "destination card not found"
-				return fmt.Errorf("destination card not found")
+				err = ErrDestinationCardNotFound
 			}
 			transfer.Status = model.TransferStatusFailed
 			transfer.ErrorMessage = err.Error()
@@ -101,10 +195,23 @@ func (s *transferService) ProcessTransfer(ctx context.Context, sourceCardID, des
 		}
 
 		// Validate destination card is active
-		if !destCard.Active {
+		if err := checkDestinationCard(destCard); err != nil {
 			transfer.Status = model.TransferStatusFailed
-			transfer.ErrorMessage = "destination card is not active"
-			return fmt.Errorf("destination card is not active")
+			transfer.ErrorMessage = err.Error()
+			return err
+		}
+
+		if err := s.checkOwnerActive(ctx, txRepo, destCard); err != nil {
+			transfer.Status = model.TransferStatusFailed
+			transfer.ErrorMessage = err.Error()
+			return err
+		}
+
+		// Balances move 1:1, so both cards must hold the same currency
+		if err := s.checkCurrencies(sourceCard, destCard); err != nil {
+			transfer.Status = model.TransferStatusFailed
+			transfer.ErrorMessage = err.Error()
+			return err
 		}
 
 		// Update balances atomically
@@ -123,10 +230,38 @@ func (s *transferService) ProcessTransfer(ctx context.Context, sourceCardID, des
 			return err
 		}
 
+		// Record both sides in the card ledger
+		if err := txRepo.AddLedgerEntry(ctx, &model.LedgerEntry{
+			CardID:       sourceCardID,
+			Delta:        amount.Neg(),
+			BalanceAfter: newSourceBalance,
+			Reason:       model.LedgerReasonTransferOut,
+			ReferenceID:  transfer.ID,
+		}); err != nil {
+			transfer.Status = model.TransferStatusFailed
+			transfer.ErrorMessage = fmt.Sprintf("failed to record ledger entry: %v", err)
+			return err
+		}
+
+		if err := txRepo.AddLedgerEntry(ctx, &model.LedgerEntry{
+			CardID:       destinationCardID,
+			Delta:        amount,
+			BalanceAfter: newDestBalance,
+			Reason:       model.LedgerReasonTransferIn,
+			ReferenceID:  transfer.ID,
+		}); err != nil {
+			transfer.Status = model.TransferStatusFailed
+			transfer.ErrorMessage = fmt.Sprintf("failed to record ledger entry: %v", err)
+			return err
+		}
+
 		// Mark transfer as completed
 		transfer.Status = model.TransferStatusCompleted
+		sourceAccountID = sourceCard.AccountID
+		destAccountID = destCard.AccountID
 		return nil
 	})
+	transferred = err == nil
 
 	// Create transfer record (regardless of success/failure)
 	if err := s.transferRepo.Create(ctx, transfer); err != nil {
@@ -141,7 +276,257 @@ func (s *transferService) ProcessTransfer(ctx context.Context, sourceCardID, des
 	// Invalidate cache for both cards
 	_ = s.cache.Delete(ctx, fmt.Sprintf("card:%s", sourceCardID.String()))
 	_ = s.cache.Delete(ctx, fmt.Sprintf("card:%s", destinationCardID.String()))
+	invalidateWallets(ctx, s.cache, sourceAccountID, destAccountID)
 
 	return transfer, nil
 }
 
+
+// ListAccountTransfers lists transfers involving any of the account's cards, newest first.
+func (s *transferService) ListAccountTransfers(ctx context.Context, accountID uuid.UUID, limit, offset int) ([]repository.AccountTransfer, int64, error) {
+	transfers, total, err := s.transferRepo.ListByAccount(ctx, accountID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list transfers: %w", err)
+	}
+	return transfers, total, nil
+}
+
+// ListCardTransfers lists transfers into or out of the card in the given status (any status
+// when empty), newest first. The card is returned with the page so callers can check its owner.
+func (s *transferService) ListCardTransfers(ctx context.Context, cardID uuid.UUID, status model.TransferStatus, limit, offset int) (*CardTransferPage, error) {
+	card, err := s.cardRepo.FindByID(ctx, cardID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.ErrCardNotFound
+		}
+		return nil, fmt.Errorf("get card: %w", err)
+	}
+
+	transfers, total, err := s.transferRepo.ListByCard(ctx, cardID, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("list transfers: %w", err)
+	}
+	return &CardTransferPage{Card: card, Transfers: transfers, Total: total}, nil
+}
+
+// GetCardTransferStats aggregates the card's completed inbound and outbound transfers
+// created within [from, to]. The card is returned with the stats so callers can check
+// its owner.
+func (s *transferService) GetCardTransferStats(ctx context.Context, cardID uuid.UUID, from, to time.Time) (*CardTransferStats, error) {
+	card, err := s.cardRepo.FindByID(ctx, cardID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.ErrCardNotFound
+		}
+		return nil, fmt.Errorf("get card: %w", err)
+	}
+
+	totals, err := s.transferRepo.SumByCardAndDirection(ctx, cardID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("sum transfers: %w", err)
+	}
+	busiest, err := s.transferRepo.BusiestDayByCard(ctx, cardID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("busiest transfer day: %w", err)
+	}
+
+	stats := &CardTransferStats{Card: card, BusiestDay: busiest}
+	for _, total := range totals {
+		direction := TransferDirectionStats{Count: total.Count, Total: total.Total, Average: decimal.Zero}
+		if total.Count > 0 {
+			direction.Average = total.Total.Div(decimal.NewFromInt(total.Count))
+		}
+		switch total.Direction {
+		case model.TransferDirectionInbound:
+			stats.Inbound = direction
+		case model.TransferDirectionOutbound:
+			stats.Outbound = direction
+		}
+	}
+	return stats, nil
+}
+
+// ValidateTransfer runs the same checks as ProcessTransfer against current card state
+// without moving money, collecting every reason the transfer would fail. Transfers carry
+// no fee, so Fee is always zero. The preconditions ProcessTransfer refuses outright (transfers
+// disabled, a source card accountID does not own) are returned as errors, not rejections.
+func (s *transferService) ValidateTransfer(ctx context.Context, accountID, sourceCardID, destinationCardID uuid.UUID, amount decimal.Decimal) (*TransferCheck, error) {
+	sourceCard, err := s.checkTransferAllowed(ctx, accountID, sourceCardID)
+	if err != nil {
+		return nil, err
+	}
+
+	check := &TransferCheck{Fee: decimal.Zero}
+	reject := func(err error) {
+		check.Rejections = append(check.Rejections, transferRejectionFor(err))
+	}
+
+	if err := s.checkAmount(amount); err != nil {
+		reject(err)
+	}
+	if sourceCardID == destinationCardID {
+		reject(ErrSameCard)
+	}
+	if err := s.dailySpend.Check(ctx, sourceCardID, amount); err == errors.ErrDailyLimitExceeded {
+		reject(err)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if sourceCard == nil {
+		reject(ErrSourceCardNotFound)
+	} else {
+		check.SourceCard = sourceCard
+		check.ProjectedSourceBalance = sourceCard.Balance.Sub(amount)
+		held, err := s.cardRepo.SumActiveHolds(ctx, sourceCardID, s.clock.Now())
+		if err != nil {
+			return nil, fmt.Errorf("sum source holds: %w", err)
+		}
+		if err := checkSourceCard(sourceCard, held, amount); err != nil {
+			reject(err)
+		}
+		if err := s.checkOwnerActive(ctx, s.cardRepo, sourceCard); err == errors.ErrAccountInactive {
+			reject(err)
+		} else if err != nil {
+			return nil, err
+		}
+		if err := s.holds.Check(ctx, sourceCard.AccountID); err != nil {
+			reject(err)
+		}
+	}
+
+	destCard, err := s.cardRepo.FindByID(ctx, destinationCardID)
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		reject(ErrDestinationCardNotFound)
+	case err != nil:
+		return nil, fmt.Errorf("get destination card: %w", err)
+	default:
+		check.DestinationCard = destCard
+		check.ProjectedDestinationBalance = destCard.Balance.Add(amount)
+		if err := checkDestinationCard(destCard); err != nil {
+			reject(err)
+		}
+		if err := s.checkOwnerActive(ctx, s.cardRepo, destCard); err == errors.ErrAccountInactive {
+			reject(err)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	if check.SourceCard != nil && check.DestinationCard != nil {
+		if err := s.checkCurrencies(check.SourceCard, check.DestinationCard); err != nil {
+			reject(err)
+		}
+	}
+
+	return check, nil
+}
+
+// checkTransferAllowed runs the preconditions shared by ProcessTransfer and ValidateTransfer:
+// transfers must be enabled and accountID must own the source card. It returns the source
+// card, or nil if it does not exist so each caller can report that its own way.
+func (s *transferService) checkTransferAllowed(ctx context.Context, accountID, sourceCardID uuid.UUID) (*model.Card, error) {
+	if err := s.killSwitch.Check(ctx, OperationTransfers); err != nil {
+		return nil, err
+	}
+	card, err := s.cardRepo.FindByID(ctx, sourceCardID)
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get source card: %w", err)
+	}
+	if card.AccountID != accountID {
+		return nil, ErrTransferNotCardOwner
+	}
+	return card, nil
+}
+
+// checkAmount validates the transfer amount against zero and the configured ceiling.
+func (s *transferService) checkAmount(amount decimal.Decimal) error {
+	if amount.LessThanOrEqual(decimal.Zero) {
+		return errors.ErrInvalidAmount
+	}
+	// Zero means unlimited
+	if s.cfg.MaxTransferAmount.IsPositive() && amount.GreaterThan(s.cfg.MaxTransferAmount) {
+		return errors.ErrAmountOutOfRange
+	}
+	return nil
+}
+
+// checkOwnerActive verifies the card's owning account is active when
+// RequireActiveCardOwner is set. A missing owner counts as inactive.
+func (s *transferService) checkOwnerActive(ctx context.Context, repo repository.CardRepository, card *model.Card) error {
+	if !s.cfg.RequireActiveCardOwner {
+		return nil
+	}
+	owner, err := repo.FindOwnerAccount(ctx, card.AccountID)
+	if err == gorm.ErrRecordNotFound {
+		return errors.ErrAccountInactive
+	}
+	if err != nil {
+		return fmt.Errorf("get card owner: %w", err)
+	}
+	if !owner.Active {
+		return errors.ErrAccountInactive
+	}
+	return nil
+}
+
+// checkCurrencies verifies the source and destination cards hold the same currency.
+func (s *transferService) checkCurrencies(source, dest *model.Card) error {
+	def, _ := moneyRounding(s.cfg)
+	return checkSameCurrency(source.Currency, dest.Currency, def)
+}
+
+// checkSourceCard validates that the source card can send amount while held stays reserved.
+func checkSourceCard(card *model.Card, held, amount decimal.Decimal) error {
+	if !card.Active {
+		return ErrSourceCardInactive
+	}
+	if availableBalance(card.Balance, held).LessThan(amount) {
+		return errors.ErrInsufficientBalance
+	}
+	return nil
+}
+
+// checkDestinationCard validates that the destination card can receive funds.
+func checkDestinationCard(card *model.Card) error {
+	if !card.Active {
+		return ErrDestinationCardInactive
+	}
+	return nil
+}
+
+// transferRejectionFor converts a transfer validation error into a structured reason.
+func transferRejectionFor(err error) TransferRejection {
+	code := errors.CodeTransferRejected
+	switch err {
+	case errors.ErrInvalidAmount:
+		code = errors.CodeInvalidAmount
+	case errors.ErrAmountOutOfRange:
+		code = errors.CodeAmountOutOfRange
+	case errors.ErrDailyLimitExceeded:
+		code = errors.CodeDailyLimitExceeded
+	case errors.ErrAccountInactive:
+		code = errors.CodeAccountInactive
+	case errors.ErrAccountOnHold:
+		code = errors.CodeAccountOnHold
+	case errors.ErrInsufficientBalance:
+		code = errors.CodeInsufficientBalance
+	case ErrSameCard:
+		code = errors.CodeSameCard
+	case ErrSourceCardNotFound:
+		code = errors.CodeSourceCardNotFound
+	case ErrDestinationCardNotFound:
+		code = errors.CodeDestinationCardNotFound
+	case ErrSourceCardInactive:
+		code = errors.CodeSourceCardInactive
+	case ErrDestinationCardInactive:
+		code = errors.CodeDestinationCardInactive
+	case errors.ErrCurrencyMismatch:
+		code = errors.CodeCurrencyMismatch
+	}
+	return TransferRejection{Code: code, Message: err.Error()}
+}
@@ -0,0 +1,477 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+
+	"paytabs/internal/config"
+	"paytabs/internal/errors"
+	"paytabs/internal/model"
+	"paytabs/internal/repository"
+)
+
+func TestTransferService_MaxTransferAmount(t *testing.T) {
+	maxAmount := decimal.RequireFromString("500.00")
+
+	tests := []struct {
+		name          string
+		amount        string
+		expectedError error
+	}{
+		{
+			name:          "exactly at the max",
+			amount:        "500.00",
+			expectedError: nil,
+		},
+		{
+			name:          "one cent over the max",
+			amount:        "500.01",
+			expectedError: errors.ErrAmountOutOfRange,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ownerID := uuid.New()
+			sourceID := uuid.New()
+			destID := uuid.New()
+			amount := decimal.RequireFromString(tt.amount)
+
+			cardRepo := new(MockCardRepository)
+			transferRepo := new(MockTransferRepository)
+			cardRepo.On("FindByID", mock.Anything, sourceID).Return(&model.Card{ID: sourceID, AccountID: ownerID}, nil)
+			if tt.expectedError == nil {
+				cardRepo.On("FindByIDForUpdate", mock.Anything, sourceID).Return(&model.Card{
+					ID: sourceID, AccountID: ownerID, Balance: decimal.RequireFromString("1000.00"), Active: true,
+				}, nil)
+				cardRepo.On("SumActiveHolds", mock.Anything, sourceID, mock.Anything).Return(decimal.Zero, nil)
+				cardRepo.On("FindByIDForUpdate", mock.Anything, destID).Return(&model.Card{
+					ID: destID, Balance: decimal.Zero, Active: true,
+				}, nil)
+				cardRepo.On("UpdateBalance", mock.Anything, sourceID, mock.Anything).Return(nil)
+				cardRepo.On("UpdateBalance", mock.Anything, destID, mock.Anything).Return(nil)
+				cardRepo.On("AddLedgerEntry", mock.Anything, mock.AnythingOfType("*model.LedgerEntry")).Return(nil)
+				transferRepo.On("Create", mock.Anything, mock.AnythingOfType("*model.Transfer")).Return(nil)
+			}
+
+			service := NewTransferService(cardRepo, transferRepo, nil, &config.Config{MaxTransferAmount: maxAmount})
+			transfer, err := service.ProcessTransfer(context.Background(), ownerID, sourceID, destID, amount)
+
+			if tt.expectedError != nil {
+				assert.Equal(t, tt.expectedError, err)
+				assert.Nil(t, transfer)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, model.TransferStatusCompleted, transfer.Status)
+			}
+
+			cardRepo.AssertExpectations(t)
+			transferRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestTransferService_ZeroMaxTransferAmountIsUnlimited(t *testing.T) {
+	ownerID := uuid.New()
+	sourceID := uuid.New()
+	destID := uuid.New()
+	amount := decimal.RequireFromString("1000000.00")
+
+	cardRepo := new(MockCardRepository)
+	transferRepo := new(MockTransferRepository)
+	source := &model.Card{ID: sourceID, AccountID: ownerID, Balance: amount, Active: true}
+	cardRepo.On("FindByID", mock.Anything, sourceID).Return(source, nil)
+	cardRepo.On("FindByIDForUpdate", mock.Anything, sourceID).Return(source, nil)
+	cardRepo.On("SumActiveHolds", mock.Anything, sourceID, mock.Anything).Return(decimal.Zero, nil)
+	cardRepo.On("FindByIDForUpdate", mock.Anything, destID).Return(&model.Card{
+		ID: destID, Balance: decimal.Zero, Active: true,
+	}, nil)
+	cardRepo.On("UpdateBalance", mock.Anything, sourceID, mock.Anything).Return(nil)
+	cardRepo.On("UpdateBalance", mock.Anything, destID, mock.Anything).Return(nil)
+	cardRepo.On("AddLedgerEntry", mock.Anything, mock.AnythingOfType("*model.LedgerEntry")).Return(nil)
+	transferRepo.On("Create", mock.Anything, mock.AnythingOfType("*model.Transfer")).Return(nil)
+
+	service := NewTransferService(cardRepo, transferRepo, nil, &config.Config{})
+	_, err := service.ProcessTransfer(context.Background(), ownerID, sourceID, destID, amount)
+
+	assert.NoError(t, err)
+}
+
+func TestTransferService_RecordsLedgerEntries(t *testing.T) {
+	ownerID := uuid.New()
+	sourceID := uuid.New()
+	destID := uuid.New()
+
+	cardRepo := new(MockCardRepository)
+	transferRepo := new(MockTransferRepository)
+	source := &model.Card{ID: sourceID, AccountID: ownerID, Balance: decimal.RequireFromString("100.00"), Active: true}
+	cardRepo.On("FindByID", mock.Anything, sourceID).Return(source, nil)
+	cardRepo.On("FindByIDForUpdate", mock.Anything, sourceID).Return(source, nil)
+	cardRepo.On("SumActiveHolds", mock.Anything, sourceID, mock.Anything).Return(decimal.Zero, nil)
+	cardRepo.On("FindByIDForUpdate", mock.Anything, destID).Return(&model.Card{
+		ID: destID, Balance: decimal.RequireFromString("5.00"), Active: true,
+	}, nil)
+	cardRepo.On("UpdateBalance", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	var entries []*model.LedgerEntry
+	cardRepo.On("AddLedgerEntry", mock.Anything, mock.AnythingOfType("*model.LedgerEntry")).
+		Run(func(args mock.Arguments) {
+			entries = append(entries, args.Get(1).(*model.LedgerEntry))
+		}).Return(nil)
+	transferRepo.On("Create", mock.Anything, mock.AnythingOfType("*model.Transfer")).Return(nil)
+
+	service := NewTransferService(cardRepo, transferRepo, nil, &config.Config{})
+	transfer, err := service.ProcessTransfer(context.Background(), ownerID, sourceID, destID, decimal.RequireFromString("30.00"))
+
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, sourceID, entries[0].CardID)
+		assert.Equal(t, model.LedgerReasonTransferOut, entries[0].Reason)
+		assert.True(t, entries[0].Delta.Equal(decimal.RequireFromString("-30.00")))
+		assert.True(t, entries[0].BalanceAfter.Equal(decimal.RequireFromString("70.00")))
+		assert.Equal(t, transfer.ID, entries[0].ReferenceID)
+
+		assert.Equal(t, destID, entries[1].CardID)
+		assert.Equal(t, model.LedgerReasonTransferIn, entries[1].Reason)
+		assert.True(t, entries[1].Delta.Equal(decimal.RequireFromString("30.00")))
+		assert.True(t, entries[1].BalanceAfter.Equal(decimal.RequireFromString("35.00")))
+		assert.Equal(t, transfer.ID, entries[1].ReferenceID)
+	}
+}
+
+func TestTransferService_ValidateTransfer(t *testing.T) {
+	ownerID := uuid.New()
+	sourceID := uuid.New()
+	destID := uuid.New()
+
+	t.Run("would succeed with projected balances", func(t *testing.T) {
+		cardRepo := new(MockCardRepository)
+		cardRepo.On("FindByID", mock.Anything, sourceID).Return(&model.Card{
+			ID: sourceID, AccountID: ownerID, Balance: decimal.RequireFromString("100.00"), Active: true,
+		}, nil)
+		cardRepo.On("SumActiveHolds", mock.Anything, sourceID, mock.Anything).Return(decimal.Zero, nil)
+		cardRepo.On("FindByID", mock.Anything, destID).Return(&model.Card{
+			ID: destID, Balance: decimal.RequireFromString("5.00"), Active: true,
+		}, nil)
+
+		service := NewTransferService(cardRepo, new(MockTransferRepository), nil, &config.Config{})
+		check, err := service.ValidateTransfer(context.Background(), ownerID, sourceID, destID, decimal.RequireFromString("40.00"))
+
+		assert.NoError(t, err)
+		assert.True(t, check.OK())
+		assert.Equal(t, "60.00", check.ProjectedSourceBalance.StringFixed(2))
+		assert.Equal(t, "45.00", check.ProjectedDestinationBalance.StringFixed(2))
+		assert.True(t, check.Fee.IsZero())
+		cardRepo.AssertNotCalled(t, "UpdateBalance", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("collects every rejection", func(t *testing.T) {
+		cardRepo := new(MockCardRepository)
+		cardRepo.On("FindByID", mock.Anything, sourceID).Return(&model.Card{
+			ID: sourceID, AccountID: ownerID, Balance: decimal.RequireFromString("10.00"), Active: true,
+		}, nil)
+		cardRepo.On("SumActiveHolds", mock.Anything, sourceID, mock.Anything).Return(decimal.Zero, nil)
+		cardRepo.On("FindByID", mock.Anything, destID).Return(&model.Card{
+			ID: destID, Balance: decimal.Zero, Active: false,
+		}, nil)
+
+		service := NewTransferService(cardRepo, new(MockTransferRepository), nil, &config.Config{
+			MaxTransferAmount: decimal.RequireFromString("20.00"),
+		})
+		check, err := service.ValidateTransfer(context.Background(), ownerID, sourceID, destID, decimal.RequireFromString("25.00"))
+
+		assert.NoError(t, err)
+		assert.False(t, check.OK())
+		codes := make([]errors.Code, 0, len(check.Rejections))
+		for _, r := range check.Rejections {
+			codes = append(codes, r.Code)
+		}
+		assert.Equal(t, []errors.Code{errors.CodeAmountOutOfRange, errors.CodeInsufficientBalance, errors.CodeDestinationCardInactive}, codes)
+	})
+
+	t.Run("missing cards", func(t *testing.T) {
+		cardRepo := new(MockCardRepository)
+		cardRepo.On("FindByID", mock.Anything, mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+
+		service := NewTransferService(cardRepo, new(MockTransferRepository), nil, &config.Config{})
+		check, err := service.ValidateTransfer(context.Background(), ownerID, sourceID, destID, decimal.RequireFromString("1.00"))
+
+		assert.NoError(t, err)
+		assert.Nil(t, check.SourceCard)
+		assert.Nil(t, check.DestinationCard)
+		assert.Len(t, check.Rejections, 2)
+		assert.Equal(t, errors.CodeSourceCardNotFound, check.Rejections[0].Code)
+		assert.Equal(t, errors.CodeDestinationCardNotFound, check.Rejections[1].Code)
+	})
+
+	t.Run("cards in different currencies", func(t *testing.T) {
+		cardRepo := new(MockCardRepository)
+		cardRepo.On("FindByID", mock.Anything, sourceID).Return(&model.Card{
+			ID: sourceID, AccountID: ownerID, Balance: decimal.RequireFromString("100.00"), Active: true, Currency: "EUR",
+		}, nil)
+		cardRepo.On("SumActiveHolds", mock.Anything, sourceID, mock.Anything).Return(decimal.Zero, nil)
+		cardRepo.On("FindByID", mock.Anything, destID).Return(&model.Card{
+			ID: destID, Balance: decimal.Zero, Active: true,
+		}, nil)
+
+		service := NewTransferService(cardRepo, new(MockTransferRepository), nil, &config.Config{DefaultCurrency: "USD"})
+		check, err := service.ValidateTransfer(context.Background(), ownerID, sourceID, destID, decimal.RequireFromString("1.00"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []TransferRejection{{Code: errors.CodeCurrencyMismatch, Message: errors.ErrCurrencyMismatch.Error()}}, check.Rejections)
+	})
+}
+
+func TestTransferService_CurrencyMismatch(t *testing.T) {
+	ownerID := uuid.New()
+	sourceID := uuid.New()
+	destID := uuid.New()
+
+	tests := []struct {
+		name        string
+		sourceCur   string
+		destCur     string
+		expectedErr error
+	}{
+		{name: "same currency", sourceCur: "EUR", destCur: "eur"},
+		{name: "unset currency is the default", sourceCur: "", destCur: "USD"},
+		{name: "different currencies", sourceCur: "EUR", destCur: "USD", expectedErr: errors.ErrCurrencyMismatch},
+		{name: "unset currency against another", sourceCur: "", destCur: "EUR", expectedErr: errors.ErrCurrencyMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cardRepo := new(MockCardRepository)
+			transferRepo := new(MockTransferRepository)
+			source := &model.Card{ID: sourceID, AccountID: ownerID, Balance: decimal.RequireFromString("100.00"), Active: true, Currency: tt.sourceCur}
+			cardRepo.On("FindByID", mock.Anything, sourceID).Return(source, nil)
+			cardRepo.On("FindByIDForUpdate", mock.Anything, sourceID).Return(source, nil)
+			cardRepo.On("SumActiveHolds", mock.Anything, sourceID, mock.Anything).Return(decimal.Zero, nil)
+			cardRepo.On("FindByIDForUpdate", mock.Anything, destID).Return(&model.Card{
+				ID: destID, Balance: decimal.Zero, Active: true, Currency: tt.destCur,
+			}, nil)
+			cardRepo.On("UpdateBalance", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+			cardRepo.On("AddLedgerEntry", mock.Anything, mock.Anything).Return(nil).Maybe()
+			transferRepo.On("Create", mock.Anything, mock.AnythingOfType("*model.Transfer")).Return(nil)
+
+			service := NewTransferService(cardRepo, transferRepo, nil, &config.Config{DefaultCurrency: "USD"})
+			transfer, err := service.ProcessTransfer(context.Background(), ownerID, sourceID, destID, decimal.RequireFromString("10.00"))
+
+			if tt.expectedErr != nil {
+				assert.Equal(t, tt.expectedErr, err)
+				assert.Equal(t, model.TransferStatusFailed, transfer.Status)
+				cardRepo.AssertNotCalled(t, "UpdateBalance", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, model.TransferStatusCompleted, transfer.Status)
+		})
+	}
+}
+
+func TestTransferService_SourceCardOwnedByAnotherAccount(t *testing.T) {
+	source := &model.Card{ID: uuid.New(), AccountID: uuid.New(), Balance: decimal.RequireFromString("100.00"), Active: true}
+	destID := uuid.New()
+	callerID := uuid.New()
+
+	cardRepo := new(MockCardRepository)
+	cardRepo.On("FindByID", mock.Anything, source.ID).Return(source, nil)
+	transferRepo := new(MockTransferRepository)
+	service := NewTransferService(cardRepo, transferRepo, nil, &config.Config{})
+
+	transfer, err := service.ProcessTransfer(context.Background(), callerID, source.ID, destID, decimal.RequireFromString("10.00"))
+	assert.Nil(t, transfer)
+	assert.Equal(t, ErrTransferNotCardOwner, err)
+	cardRepo.AssertNotCalled(t, "FindByIDForUpdate", mock.Anything, mock.Anything)
+	transferRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+
+	check, err := service.ValidateTransfer(context.Background(), callerID, source.ID, destID, decimal.RequireFromString("10.00"))
+	assert.Nil(t, check)
+	assert.Equal(t, ErrTransferNotCardOwner, err)
+}
+
+func TestTransferService_RequireActiveCardOwner(t *testing.T) {
+	sourceOwner := &model.Account{ID: uuid.New(), Active: false}
+	destOwner := &model.Account{ID: uuid.New(), Active: true}
+	source := &model.Card{ID: uuid.New(), AccountID: sourceOwner.ID, Balance: decimal.RequireFromString("100.00"), Active: true}
+	dest := &model.Card{ID: uuid.New(), AccountID: destOwner.ID, Balance: decimal.Zero, Active: true}
+
+	newRepos := func() (*MockCardRepository, *MockTransferRepository) {
+		cardRepo := new(MockCardRepository)
+		cardRepo.On("FindByIDForUpdate", mock.Anything, source.ID).Return(source, nil)
+		cardRepo.On("SumActiveHolds", mock.Anything, source.ID, mock.Anything).Return(decimal.Zero, nil)
+		cardRepo.On("FindByIDForUpdate", mock.Anything, dest.ID).Return(dest, nil)
+		cardRepo.On("FindByID", mock.Anything, source.ID).Return(source, nil)
+		cardRepo.On("FindByID", mock.Anything, dest.ID).Return(dest, nil)
+		cardRepo.On("FindOwnerAccount", mock.Anything, sourceOwner.ID).Return(sourceOwner, nil)
+		cardRepo.On("FindOwnerAccount", mock.Anything, destOwner.ID).Return(destOwner, nil)
+		cardRepo.On("UpdateBalance", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		cardRepo.On("AddLedgerEntry", mock.Anything, mock.Anything).Return(nil)
+		transferRepo := new(MockTransferRepository)
+		transferRepo.On("Create", mock.Anything, mock.AnythingOfType("*model.Transfer")).Return(nil)
+		return cardRepo, transferRepo
+	}
+
+	t.Run("inactive source account blocks an active card", func(t *testing.T) {
+		cardRepo, transferRepo := newRepos()
+		service := NewTransferService(cardRepo, transferRepo, nil, &config.Config{RequireActiveCardOwner: true})
+
+		transfer, err := service.ProcessTransfer(context.Background(), source.AccountID, source.ID, dest.ID, decimal.RequireFromString("10.00"))
+
+		assert.Equal(t, errors.ErrAccountInactive, err)
+		assert.Equal(t, model.TransferStatusFailed, transfer.Status)
+		cardRepo.AssertNotCalled(t, "UpdateBalance", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("validate reports the inactive owner", func(t *testing.T) {
+		cardRepo, transferRepo := newRepos()
+		service := NewTransferService(cardRepo, transferRepo, nil, &config.Config{RequireActiveCardOwner: true})
+
+		check, err := service.ValidateTransfer(context.Background(), source.AccountID, source.ID, dest.ID, decimal.RequireFromString("10.00"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []TransferRejection{{Code: errors.CodeAccountInactive, Message: errors.ErrAccountInactive.Error()}}, check.Rejections)
+	})
+
+	t.Run("check disabled", func(t *testing.T) {
+		cardRepo, transferRepo := newRepos()
+		service := NewTransferService(cardRepo, transferRepo, nil, &config.Config{})
+
+		transfer, err := service.ProcessTransfer(context.Background(), source.AccountID, source.ID, dest.ID, decimal.RequireFromString("10.00"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, model.TransferStatusCompleted, transfer.Status)
+		cardRepo.AssertNotCalled(t, "FindOwnerAccount", mock.Anything, mock.Anything)
+	})
+}
+
+func TestTransferService_IgnoresCachedBalance(t *testing.T) {
+	source := &model.Card{ID: uuid.New(), AccountID: uuid.New(), Balance: decimal.RequireFromString("10.00"), Active: true}
+	destID := uuid.New()
+	cacheClient, _ := newStaleBalanceCache(t, source, "1000.00")
+
+	cardRepo := new(MockCardRepository)
+	transferRepo := new(MockTransferRepository)
+	cardRepo.On("FindByID", mock.Anything, source.ID).Return(source, nil)
+	cardRepo.On("FindByIDForUpdate", mock.Anything, source.ID).Return(source, nil)
+	cardRepo.On("SumActiveHolds", mock.Anything, source.ID, mock.Anything).Return(decimal.Zero, nil)
+	transferRepo.On("Create", mock.Anything, mock.AnythingOfType("*model.Transfer")).Return(nil)
+
+	service := NewTransferService(cardRepo, transferRepo, cacheClient, &config.Config{})
+	transfer, err := service.ProcessTransfer(context.Background(), source.AccountID, source.ID, destID, decimal.RequireFromString("50.00"))
+
+	assert.Equal(t, errors.ErrInsufficientBalance, err)
+	assert.Equal(t, model.TransferStatusFailed, transfer.Status)
+	cardRepo.AssertNotCalled(t, "UpdateBalance", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestTransferService_RespectsCardHolds(t *testing.T) {
+	source := &model.Card{ID: uuid.New(), AccountID: uuid.New(), Balance: decimal.RequireFromString("100.00"), Active: true}
+	dest := &model.Card{ID: uuid.New(), AccountID: uuid.New(), Balance: decimal.Zero, Active: true}
+
+	newRepos := func() (*MockCardRepository, *MockTransferRepository) {
+		cardRepo := new(MockCardRepository)
+		cardRepo.On("FindByIDForUpdate", mock.Anything, source.ID).Return(source, nil)
+		cardRepo.On("FindByID", mock.Anything, source.ID).Return(source, nil)
+		cardRepo.On("FindByID", mock.Anything, dest.ID).Return(dest, nil)
+		cardRepo.On("SumActiveHolds", mock.Anything, source.ID, mock.Anything).Return(decimal.RequireFromString("70.00"), nil)
+		transferRepo := new(MockTransferRepository)
+		transferRepo.On("Create", mock.Anything, mock.AnythingOfType("*model.Transfer")).Return(nil)
+		return cardRepo, transferRepo
+	}
+
+	t.Run("transfer into held funds is rejected", func(t *testing.T) {
+		cardRepo, transferRepo := newRepos()
+		service := NewTransferService(cardRepo, transferRepo, nil, &config.Config{})
+
+		transfer, err := service.ProcessTransfer(context.Background(), source.AccountID, source.ID, dest.ID, decimal.RequireFromString("40.00"))
+
+		assert.Equal(t, errors.ErrInsufficientBalance, err)
+		assert.Equal(t, model.TransferStatusFailed, transfer.Status)
+		cardRepo.AssertNotCalled(t, "FindByIDForUpdate", mock.Anything, dest.ID)
+		cardRepo.AssertNotCalled(t, "UpdateBalance", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("validate reports the shortfall", func(t *testing.T) {
+		cardRepo, transferRepo := newRepos()
+		service := NewTransferService(cardRepo, transferRepo, nil, &config.Config{})
+
+		check, err := service.ValidateTransfer(context.Background(), source.AccountID, source.ID, dest.ID, decimal.RequireFromString("40.00"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []TransferRejection{{Code: errors.CodeInsufficientBalance, Message: errors.ErrInsufficientBalance.Error()}}, check.Rejections)
+	})
+
+	t.Run("available funds can still move", func(t *testing.T) {
+		cardRepo, transferRepo := newRepos()
+		cardRepo.On("FindByIDForUpdate", mock.Anything, dest.ID).Return(dest, nil)
+		cardRepo.On("UpdateBalance", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		cardRepo.On("AddLedgerEntry", mock.Anything, mock.Anything).Return(nil)
+		service := NewTransferService(cardRepo, transferRepo, nil, &config.Config{})
+
+		transfer, err := service.ProcessTransfer(context.Background(), source.AccountID, source.ID, dest.ID, decimal.RequireFromString("30.00"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, model.TransferStatusCompleted, transfer.Status)
+		cardRepo.AssertCalled(t, "UpdateBalance", mock.Anything, source.ID, decimal.RequireFromString("70.00"))
+	})
+}
+
+func TestTransferService_GetCardTransferStats(t *testing.T) {
+	cardID := uuid.New()
+	to := time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(0, 0, -30)
+	busiest := &repository.TransferDayTotal{Day: time.Date(2026, 3, 14, 0, 0, 0, 0, time.UTC), Count: 2, Total: decimal.RequireFromString("75")}
+
+	cardRepo := new(MockCardRepository)
+	cardRepo.On("FindByID", mock.Anything, cardID).Return(&model.Card{ID: cardID}, nil)
+	transferRepo := new(MockTransferRepository)
+	transferRepo.On("SumByCardAndDirection", mock.Anything, cardID, from, to).Return([]repository.TransferDirectionTotal{
+		{Direction: model.TransferDirectionInbound, Count: 3, Total: decimal.RequireFromString("100")},
+	}, nil)
+	transferRepo.On("BusiestDayByCard", mock.Anything, cardID, from, to).Return(busiest, nil)
+
+	service := NewTransferService(cardRepo, transferRepo, nil, &config.Config{})
+	stats, err := service.GetCardTransferStats(context.Background(), cardID, from, to)
+
+	assert.NoError(t, err)
+	assert.Equal(t, cardID, stats.Card.ID)
+	assert.Equal(t, int64(3), stats.Inbound.Count)
+	assert.Equal(t, "100.00", stats.Inbound.Total.StringFixed(2))
+	assert.Equal(t, "33.33", stats.Inbound.Average.StringFixed(2))
+	assert.Equal(t, int64(0), stats.Outbound.Count)
+	assert.True(t, stats.Outbound.Average.IsZero())
+	assert.Equal(t, busiest, stats.BusiestDay)
+}
+
+func TestTransferService_GetCardTransferStats_CardNotFound(t *testing.T) {
+	cardID := uuid.New()
+	cardRepo := new(MockCardRepository)
+	cardRepo.On("FindByID", mock.Anything, cardID).Return(nil, gorm.ErrRecordNotFound)
+	transferRepo := new(MockTransferRepository)
+
+	service := NewTransferService(cardRepo, transferRepo, nil, &config.Config{})
+	_, err := service.GetCardTransferStats(context.Background(), cardID, time.Now().Add(-time.Hour), time.Now())
+
+	assert.Equal(t, errors.ErrCardNotFound, err)
+	transferRepo.AssertNotCalled(t, "SumByCardAndDirection", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestTransferService_ListCardTransfers_CardNotFound(t *testing.T) {
+	cardID := uuid.New()
+	cardRepo := new(MockCardRepository)
+	cardRepo.On("FindByID", mock.Anything, cardID).Return(nil, gorm.ErrRecordNotFound)
+	transferRepo := new(MockTransferRepository)
+
+	service := NewTransferService(cardRepo, transferRepo, nil, &config.Config{})
+	_, err := service.ListCardTransfers(context.Background(), cardID, model.TransferStatusFailed, 20, 0)
+
+	assert.Equal(t, errors.ErrCardNotFound, err)
+	transferRepo.AssertNotCalled(t, "ListByCard", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"paytabs/internal/cache"
+	"paytabs/internal/model"
+)
+
+const walletCacheTTL = 30 * time.Second
+
+// Wallet aggregates an account profile with its balance and active cards.
+type Wallet struct {
+	Account *model.Account  `json:"account"`
+	Balance decimal.Decimal `json:"balance"`
+	Cards   []model.Card    `json:"cards"`
+}
+
+// GetWallet assembles the account profile, total balance, and active cards (with masked
+// numbers) in a single payload. The result is cached briefly and invalidated whenever a
+// payment or transfer changes one of the account's card balances.
+func (s *accountService) GetWallet(ctx context.Context, id uuid.UUID) (*Wallet, error) {
+	if data, _ := s.cache.Get(ctx, walletCacheKey(id)); data != nil {
+		var cached Wallet
+		if err := json.Unmarshal(data, &cached); err == nil {
+			return &cached, nil
+		}
+	}
+
+	account, err := s.GetAccount(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	cards, err := s.cardRepo.FindByAccountID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get cards: %w", err)
+	}
+
+	wallet := &Wallet{
+		Account: account,
+		Balance: decimal.Zero,
+		Cards:   make([]model.Card, 0, len(cards)),
+	}
+	for _, card := range cards {
+		if !card.Active {
+			continue
+		}
+		card.CardNumber = s.validator.MaskCardNumber(card.CardNumber)
+		wallet.Cards = append(wallet.Cards, card)
+		wallet.Balance = wallet.Balance.Add(card.Balance)
+	}
+
+	if payload, err := json.Marshal(wallet); err == nil {
+		_ = s.cache.Set(ctx, walletCacheKey(id), payload, walletCacheTTL)
+	}
+
+	return wallet, nil
+}
+
+// walletCacheKey returns the cache key for an account's assembled wallet. The cached balance
+// is for display only and may lag a concurrent payment; money movement must never read it.
+func walletCacheKey(accountID uuid.UUID) string {
+	return fmt.Sprintf("wallet:%s", accountID.String())
+}
+
+// invalidateWallets drops the cached wallets of the accounts, for callers that just changed
+// one of their balances or cards. It is the only place outside GetWallet that touches them.
+func invalidateWallets(ctx context.Context, c *cache.Client, accountIDs ...uuid.UUID) {
+	for _, id := range accountIDs {
+		_ = c.Delete(ctx, walletCacheKey(id))
+	}
+}
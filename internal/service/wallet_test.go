@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"paytabs/internal/cache"
+	"paytabs/internal/model"
+)
+
+func TestAccountService_GetWallet_CachedUntilInvalidated(t *testing.T) {
+	ctx := context.Background()
+	client := cache.New(miniredis.RunT(t).Addr(), "", 0)
+	account := &model.Account{ID: uuid.New(), Active: true}
+	other := uuid.New()
+	card := model.Card{ID: uuid.New(), AccountID: account.ID, CardNumber: "4111111111111111", Balance: decimal.RequireFromString("40.00"), Active: true}
+	closed := model.Card{ID: uuid.New(), AccountID: account.ID, CardNumber: "5555555555554444", Balance: decimal.RequireFromString("9.00")}
+
+	accountRepo := new(MockAccountRepository)
+	cardRepo := new(MockCardRepository)
+	accountRepo.On("FindByID", mock.Anything, account.ID).Return(account, nil)
+	cardRepo.On("FindByAccountID", mock.Anything, account.ID).Return([]model.Card{card, closed}, nil).Once()
+	svc := NewAccountService(accountRepo, cardRepo, client)
+
+	wallet, err := svc.GetWallet(ctx, account.ID)
+	require.NoError(t, err)
+	require.Len(t, wallet.Cards, 1, "inactive cards are left out")
+	assert.Equal(t, "40", wallet.Balance.String())
+	assert.NotEqual(t, card.CardNumber, wallet.Cards[0].CardNumber, "numbers are masked")
+
+	_, err = svc.GetWallet(ctx, account.ID)
+	require.NoError(t, err)
+	cardRepo.AssertNumberOfCalls(t, "FindByAccountID", 1)
+
+	// Another account's balance change leaves this wallet cached
+	invalidateWallets(ctx, client, other)
+	_, err = svc.GetWallet(ctx, account.ID)
+	require.NoError(t, err)
+	cardRepo.AssertNumberOfCalls(t, "FindByAccountID", 1)
+
+	card.Balance = decimal.RequireFromString("15.00")
+	cardRepo.On("FindByAccountID", mock.Anything, account.ID).Return([]model.Card{card}, nil).Once()
+	invalidateWallets(ctx, client, account.ID, other)
+
+	wallet, err = svc.GetWallet(ctx, account.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, "15", wallet.Balance.String())
+	cardRepo.AssertNumberOfCalls(t, "FindByAccountID", 2)
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"paytabs/internal/model"
+	"paytabs/internal/notify"
+)
+
+const (
+	// WebhookSignatureHeader carries the signature of every webhook request, as
+	// "t=<unix seconds>,v1=<hex HMAC-SHA256>".
+	WebhookSignatureHeader = "Webhook-Signature"
+
+	// WebhookEventTest is the type of the sample event sent by SendTestEvent.
+	WebhookEventTest = "webhook.test"
+
+	maxWebhookURLLength = 2048
+)
+
+// SignWebhook returns the WebhookSignatureHeader value for body sent at ts: the HMAC-SHA256,
+// keyed with the merchant's secret, of the unix timestamp, a dot, and the raw body. Receivers
+// recompute it over the bytes they received and compare in constant time; the timestamp lets
+// them reject replays of old requests.
+func SignWebhook(secret string, ts time.Time, body []byte) string {
+	unix := strconv.FormatInt(ts.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unix))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + unix + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookEvent is the JSON body of every webhook request.
+type WebhookEvent struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookTestResult reports how a merchant's endpoint answered a test event. StatusCode is
+// zero and Error says why when no response was received.
+type WebhookTestResult struct {
+	EventID    string
+	URL        string
+	Delivered  bool
+	StatusCode int
+	Latency    time.Duration
+	Error      string
+}
+
+// SetEndpoint validates and stores the merchant's webhook URL. Whether it resolves to a public
+// address is checked on delivery, since DNS can change after it is saved.
+func (s *webhookService) SetEndpoint(ctx context.Context, accountID uuid.UUID, rawURL string) (*model.WebhookEndpoint, error) {
+	if len(rawURL) > maxWebhookURLLength {
+		return nil, ErrInvalidWebhookURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, ErrInvalidWebhookURL
+	}
+	if err := s.requireMerchant(ctx, accountID); err != nil {
+		return nil, err
+	}
+
+	endpoint := &model.WebhookEndpoint{AccountID: accountID, URL: u.String()}
+	if err := s.webhookRepo.SaveEndpoint(ctx, endpoint); err != nil {
+		return nil, fmt.Errorf("save webhook endpoint: %w", err)
+	}
+	return endpoint, nil
+}
+
+// GetEndpoint returns the merchant's webhook endpoint, or ErrWebhookEndpointNotSet.
+func (s *webhookService) GetEndpoint(ctx context.Context, accountID uuid.UUID) (*model.WebhookEndpoint, error) {
+	if err := s.requireMerchant(ctx, accountID); err != nil {
+		return nil, err
+	}
+	return s.findEndpoint(ctx, accountID)
+}
+
+func (s *webhookService) findEndpoint(ctx context.Context, accountID uuid.UUID) (*model.WebhookEndpoint, error) {
+	endpoint, err := s.webhookRepo.FindEndpoint(ctx, accountID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrWebhookEndpointNotSet
+		}
+		return nil, fmt.Errorf("find webhook endpoint: %w", err)
+	}
+	return endpoint, nil
+}
+
+// SendTestEvent delivers a signed WebhookEventTest to the merchant's endpoint once, through
+// the same client and signature as every other webhook. A delivery that fails is a result,
+// not an error: the result says what went wrong. Only sends that reach the endpoint count
+// towards the hourly limit.
+func (s *webhookService) SendTestEvent(ctx context.Context, accountID uuid.UUID) (*WebhookTestResult, error) {
+	if err := s.requireMerchant(ctx, accountID); err != nil {
+		return nil, err
+	}
+	endpoint, secret, err := s.deliveryTarget(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Like reveals, tests are not limited while Redis is unavailable
+	if s.testsPerHour > 0 {
+		n, ok := s.cache.IncrBy(ctx, webhookTestKeyPrefix+accountID.String(), 1, webhookTestWindow)
+		if ok && n > int64(s.testsPerHour) {
+			return nil, ErrTooManyWebhookTests
+		}
+	}
+
+	event := s.newEvent(WebhookEventTest, map[string]string{"merchant_account_id": accountID.String()})
+	start := time.Now()
+	resp, err := s.post(ctx, endpoint.URL, secret, event)
+	result := &WebhookTestResult{
+		EventID:   event.ID,
+		URL:       endpoint.URL,
+		Delivered: err == nil,
+		Latency:   time.Since(start),
+	}
+	if resp != nil {
+		result.StatusCode = resp.StatusCode
+	}
+	if err != nil {
+		var deliveryErr *notify.WebhookDeliveryError
+		if stderrors.As(err, &deliveryErr) && deliveryErr.StatusCode == 0 && deliveryErr.Err != nil {
+			result.Error = deliveryErr.Err.Error()
+		} else {
+			result.Error = err.Error()
+		}
+	}
+	return result, nil
+}
+
+// SendEvent delivers an event of eventType carrying data to the merchant's endpoint once.
+func (s *webhookService) SendEvent(ctx context.Context, accountID uuid.UUID, eventType string, data interface{}) error {
+	endpoint, secret, err := s.deliveryTarget(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	_, err = s.post(ctx, endpoint.URL, secret, s.newEvent(eventType, data))
+	return err
+}
+
+// deliveryTarget returns the merchant's endpoint and signing secret, or the error saying
+// which one is missing.
+func (s *webhookService) deliveryTarget(ctx context.Context, accountID uuid.UUID) (*model.WebhookEndpoint, string, error) {
+	if s.box == nil || s.client == nil {
+		return nil, "", ErrWebhookSecretsDisabled
+	}
+	endpoint, err := s.findEndpoint(ctx, accountID)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := s.SigningSecret(ctx, accountID)
+	if err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", ErrWebhookSecretNotSet
+		}
+		return nil, "", err
+	}
+	return endpoint, secret, nil
+}
+
+func (s *webhookService) newEvent(eventType string, data interface{}) WebhookEvent {
+	return WebhookEvent{
+		ID:        "evt_" + uuid.New().String(),
+		Type:      eventType,
+		CreatedAt: s.clock.Now().UTC(),
+		Data:      data,
+	}
+}
+
+// post signs event with secret and sends it to endpointURL.
+func (s *webhookService) post(ctx context.Context, endpointURL, secret string, event WebhookEvent) (*notify.WebhookResponse, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("encode webhook event: %w", err)
+	}
+	return s.client.Post(ctx, endpointURL, body, map[string]string{
+		WebhookSignatureHeader: SignWebhook(secret, event.CreatedAt, body),
+	})
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"paytabs/internal/auth"
+	"paytabs/internal/cache"
+	"paytabs/internal/clock"
+	"paytabs/internal/errors"
+	"paytabs/internal/model"
+	"paytabs/internal/notify"
+)
+
+func TestSignWebhook(t *testing.T) {
+	sig := SignWebhook("whsec_test", time.Unix(1700000000, 0), []byte(`{"id":"evt_1"}`))
+
+	assert.Equal(t, "t=1700000000,v1=c89214b5b5da833daed6f0b8c5bb6bd58cea9022bd80ccc78230f3942d632925", sig)
+}
+
+// webhookTestDeps is a merchant with an endpoint at url and a stored secret.
+type webhookTestDeps struct {
+	merchant    *model.Account
+	secret      string
+	accountRepo *MockAccountRepository
+	webhookRepo *MockWebhookRepository
+	box         *auth.SecretBox
+}
+
+func newWebhookTestDeps(t *testing.T, url string) *webhookTestDeps {
+	t.Helper()
+	d := &webhookTestDeps{
+		merchant:    &model.Account{ID: uuid.New(), IsMerchant: true},
+		secret:      "whsec_test",
+		accountRepo: new(MockAccountRepository),
+		webhookRepo: new(MockWebhookRepository),
+	}
+	var err error
+	d.box, err = auth.NewSecretBox("test-key")
+	require.NoError(t, err)
+	sealed, err := d.box.Seal(d.secret, []byte(d.merchant.ID.String()))
+	require.NoError(t, err)
+
+	d.accountRepo.On("FindByID", mock.Anything, d.merchant.ID).Return(d.merchant, nil)
+	d.webhookRepo.On("FindEndpoint", mock.Anything, d.merchant.ID).Return(&model.WebhookEndpoint{AccountID: d.merchant.ID, URL: url}, nil)
+	d.webhookRepo.On("FindSecret", mock.Anything, d.merchant.ID).Return(&model.WebhookSecret{AccountID: d.merchant.ID, Ciphertext: sealed}, nil)
+	return d
+}
+
+func (d *webhookTestDeps) service(cacheClient *cache.Client, testsPerHour int) *webhookService {
+	client := notify.NewWebhookClient(5*time.Second, 1024, true)
+	return NewWebhookService(d.accountRepo, d.webhookRepo, d.box, client, cacheClient, 3, testsPerHour).(*webhookService)
+}
+
+func TestWebhookService_SendTestEvent_DeliversSignedEvent(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	var gotSignature string
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSignature = r.Header.Get(WebhookSignatureHeader)
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	d := newWebhookTestDeps(t, srv.URL)
+	svc := d.service(nil, 10)
+	svc.clock = clock.NewFixed(now)
+
+	result, err := svc.SendTestEvent(context.Background(), d.merchant.ID)
+
+	require.NoError(t, err)
+	assert.True(t, result.Delivered)
+	assert.Equal(t, http.StatusNoContent, result.StatusCode)
+	assert.Equal(t, srv.URL, result.URL)
+	assert.Empty(t, result.Error)
+
+	// The endpoint can verify the request with the merchant's secret
+	assert.Equal(t, SignWebhook(d.secret, now, gotBody), gotSignature)
+	var event WebhookEvent
+	require.NoError(t, json.Unmarshal(gotBody, &event))
+	assert.Equal(t, result.EventID, event.ID)
+	assert.Equal(t, WebhookEventTest, event.Type)
+	assert.True(t, now.Equal(event.CreatedAt))
+}
+
+func TestWebhookService_SendTestEvent_ReportsFailedDelivery(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	d := newWebhookTestDeps(t, srv.URL)
+	result, err := d.service(nil, 10).SendTestEvent(context.Background(), d.merchant.ID)
+
+	require.NoError(t, err, "a failed delivery is a result, not an error")
+	assert.False(t, result.Delivered)
+	assert.Equal(t, http.StatusUnauthorized, result.StatusCode)
+	assert.NotEmpty(t, result.Error)
+
+	// Nothing listening: no status code, and the error says why
+	srv.Close()
+	result, err = d.service(nil, 10).SendTestEvent(context.Background(), d.merchant.ID)
+	require.NoError(t, err)
+	assert.False(t, result.Delivered)
+	assert.Zero(t, result.StatusCode)
+	assert.NotEmpty(t, result.Error)
+}
+
+func TestWebhookService_SendTestEvent_RateLimited(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	mr := miniredis.RunT(t)
+
+	d := newWebhookTestDeps(t, srv.URL)
+	svc := d.service(cache.New(mr.Addr(), "", 0), 1)
+
+	_, err := svc.SendTestEvent(context.Background(), d.merchant.ID)
+	require.NoError(t, err)
+	_, err = svc.SendTestEvent(context.Background(), d.merchant.ID)
+	assert.Equal(t, ErrTooManyWebhookTests, err)
+}
+
+func TestWebhookService_SendTestEvent_Preconditions(t *testing.T) {
+	t.Run("no endpoint", func(t *testing.T) {
+		d := newWebhookTestDeps(t, "")
+		d.webhookRepo.ExpectedCalls = nil
+		d.webhookRepo.On("FindEndpoint", mock.Anything, d.merchant.ID).Return(nil, gorm.ErrRecordNotFound)
+
+		_, err := d.service(nil, 10).SendTestEvent(context.Background(), d.merchant.ID)
+		assert.Equal(t, ErrWebhookEndpointNotSet, err)
+	})
+
+	t.Run("no secret", func(t *testing.T) {
+		d := newWebhookTestDeps(t, "https://example.com/hooks")
+		d.webhookRepo.ExpectedCalls = nil
+		d.webhookRepo.On("FindEndpoint", mock.Anything, d.merchant.ID).Return(&model.WebhookEndpoint{URL: "https://example.com/hooks"}, nil)
+		d.webhookRepo.On("FindSecret", mock.Anything, d.merchant.ID).Return(nil, gorm.ErrRecordNotFound)
+
+		_, err := d.service(nil, 10).SendTestEvent(context.Background(), d.merchant.ID)
+		assert.Equal(t, ErrWebhookSecretNotSet, err)
+	})
+
+	t.Run("not a merchant", func(t *testing.T) {
+		d := newWebhookTestDeps(t, "https://example.com/hooks")
+		d.merchant.IsMerchant = false
+
+		_, err := d.service(nil, 10).SendTestEvent(context.Background(), d.merchant.ID)
+		assert.Equal(t, errors.ErrNotMerchant, err)
+	})
+
+	t.Run("secrets disabled", func(t *testing.T) {
+		d := newWebhookTestDeps(t, "https://example.com/hooks")
+		d.box = nil
+
+		_, err := d.service(nil, 10).SendTestEvent(context.Background(), d.merchant.ID)
+		assert.Equal(t, ErrWebhookSecretsDisabled, err)
+	})
+}
+
+func TestWebhookService_SetEndpoint(t *testing.T) {
+	d := newWebhookTestDeps(t, "")
+	d.webhookRepo.On("SaveEndpoint", mock.Anything, mock.MatchedBy(func(e *model.WebhookEndpoint) bool {
+		return e.AccountID == d.merchant.ID && e.URL == "https://shop.example.com/hooks"
+	})).Return(nil)
+	svc := d.service(nil, 10)
+
+	endpoint, err := svc.SetEndpoint(context.Background(), d.merchant.ID, "https://shop.example.com/hooks")
+	require.NoError(t, err)
+	assert.Equal(t, "https://shop.example.com/hooks", endpoint.URL)
+
+	for _, url := range []string{"", "shop.example.com/hooks", "ftp://shop.example.com", "https://", "http://" + string(make([]byte, 2048))} {
+		_, err := svc.SetEndpoint(context.Background(), d.merchant.ID, url)
+		assert.Equal(t, ErrInvalidWebhookURL, err, "url %.40q", url)
+	}
+	d.webhookRepo.AssertNumberOfCalls(t, "SaveEndpoint", 1)
+}
@@ -0,0 +1,21 @@
+package service
+
+import "errors"
+
+// ErrWebhookSecretsDisabled is returned when no WEBHOOK_SECRET_KEY is configured to encrypt secrets.
+var ErrWebhookSecretsDisabled = errors.New("webhook secrets are not configured")
+
+// ErrTooManySecretReveals is returned when a merchant exceeds the hourly webhook secret reveal limit.
+var ErrTooManySecretReveals = errors.New("too many webhook secret reveals, try again later")
+
+// ErrTooManyWebhookTests is returned when a merchant exceeds the hourly webhook test event limit.
+var ErrTooManyWebhookTests = errors.New("too many webhook test events, try again later")
+
+// ErrInvalidWebhookURL is returned for a webhook endpoint that is not an absolute http or https URL.
+var ErrInvalidWebhookURL = errors.New("webhook URL must be an absolute http or https URL of at most 2048 characters")
+
+// ErrWebhookEndpointNotSet is returned when a merchant has not configured a webhook endpoint.
+var ErrWebhookEndpointNotSet = errors.New("no webhook endpoint is configured")
+
+// ErrWebhookSecretNotSet is returned when a merchant has not generated a webhook signing secret.
+var ErrWebhookSecretNotSet = errors.New("no webhook signing secret has been generated")
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"paytabs/internal/auth"
+	"paytabs/internal/cache"
+	"paytabs/internal/clock"
+	"paytabs/internal/errors"
+	"paytabs/internal/model"
+	"paytabs/internal/notify"
+	"paytabs/internal/repository"
+)
+
+const (
+	webhookSecretPrefix          = "whsec_"
+	webhookSecretRevealKeyPrefix = "webhook_secret_reveal:"
+	webhookSecretRevealWindow    = time.Hour
+	webhookTestKeyPrefix         = "webhook_test:"
+	webhookTestWindow            = time.Hour
+)
+
+// WebhookService manages merchants' webhook signing secrets and endpoints.
+type WebhookService interface {
+	// RegenerateSecret replaces the merchant's secret and returns the new plaintext. It is the
+	// only time the plaintext leaves the service.
+	RegenerateSecret(ctx context.Context, accountID uuid.UUID, clientIP string) (string, error)
+	// SigningSecret decrypts the merchant's current secret for signing outgoing webhooks.
+	SigningSecret(ctx context.Context, accountID uuid.UUID) (string, error)
+	// SetEndpoint sets the URL the merchant's webhook events are sent to.
+	SetEndpoint(ctx context.Context, accountID uuid.UUID, url string) (*model.WebhookEndpoint, error)
+	GetEndpoint(ctx context.Context, accountID uuid.UUID) (*model.WebhookEndpoint, error)
+	// SendTestEvent signs and delivers a sample event to the merchant's endpoint and reports
+	// how the endpoint answered.
+	SendTestEvent(ctx context.Context, accountID uuid.UUID) (*WebhookTestResult, error)
+	// SendEvent signs and delivers an event to the merchant's endpoint once. Failing
+	// deliveries are returned as *notify.WebhookDeliveryError.
+	SendEvent(ctx context.Context, accountID uuid.UUID, eventType string, data interface{}) error
+}
+
+type webhookService struct {
+	accountRepo    repository.AccountRepository
+	webhookRepo    repository.WebhookRepository
+	box            *auth.SecretBox
+	client         *notify.WebhookClient
+	cache          *cache.Client
+	clock          clock.Clock
+	revealsPerHour int
+	testsPerHour   int
+}
+
+// NewWebhookService creates a new webhook service. A nil box disables secrets and a nil client
+// disables delivery; revealsPerHour and testsPerHour of zero or less disable their rate limits.
+func NewWebhookService(accountRepo repository.AccountRepository, webhookRepo repository.WebhookRepository, box *auth.SecretBox, client *notify.WebhookClient, cacheClient *cache.Client, revealsPerHour, testsPerHour int) WebhookService {
+	return &webhookService{
+		accountRepo:    accountRepo,
+		webhookRepo:    webhookRepo,
+		box:            box,
+		client:         client,
+		cache:          cacheClient,
+		clock:          clock.New(),
+		revealsPerHour: revealsPerHour,
+		testsPerHour:   testsPerHour,
+	}
+}
+
+// RegenerateSecret generates a new secret, stores it encrypted and returns it. Every call is
+// audited, including those refused by the rate limit.
+func (s *webhookService) RegenerateSecret(ctx context.Context, accountID uuid.UUID, clientIP string) (string, error) {
+	if s.box == nil {
+		return "", ErrWebhookSecretsDisabled
+	}
+	if err := s.requireMerchant(ctx, accountID); err != nil {
+		return "", err
+	}
+
+	// The counter lives in Redis; if it is unavailable reveals are not limited but are still audited
+	if s.revealsPerHour > 0 {
+		n, ok := s.cache.IncrBy(ctx, webhookSecretRevealKeyPrefix+accountID.String(), 1, webhookSecretRevealWindow)
+		if ok && n > int64(s.revealsPerHour) {
+			s.audit(ctx, accountID, model.WebhookSecretActionDenied, clientIP)
+			return "", ErrTooManySecretReveals
+		}
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("generate secret: %w", err)
+	}
+	secret := webhookSecretPrefix + hex.EncodeToString(raw)
+
+	sealed, err := s.box.Seal(secret, []byte(accountID.String()))
+	if err != nil {
+		return "", fmt.Errorf("encrypt secret: %w", err)
+	}
+
+	if err := s.webhookRepo.SaveSecret(ctx,
+		&model.WebhookSecret{AccountID: accountID, Ciphertext: sealed},
+		&model.WebhookSecretAudit{AccountID: accountID, Action: model.WebhookSecretActionRegenerated, ClientIP: clientIP},
+	); err != nil {
+		return "", fmt.Errorf("save secret: %w", err)
+	}
+
+	return secret, nil
+}
+
+// SigningSecret returns the merchant's current secret in plaintext.
+func (s *webhookService) SigningSecret(ctx context.Context, accountID uuid.UUID) (string, error) {
+	if s.box == nil {
+		return "", ErrWebhookSecretsDisabled
+	}
+	stored, err := s.webhookRepo.FindSecret(ctx, accountID)
+	if err != nil {
+		return "", fmt.Errorf("find secret: %w", err)
+	}
+	return s.box.Open(stored.Ciphertext, []byte(accountID.String()))
+}
+
+func (s *webhookService) requireMerchant(ctx context.Context, accountID uuid.UUID) error {
+	account, err := s.accountRepo.FindByID(ctx, accountID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return errors.ErrAccountNotFound
+		}
+		return fmt.Errorf("get account: %w", err)
+	}
+	if !account.IsMerchant {
+		return errors.ErrNotMerchant
+	}
+	return nil
+}
+
+// audit records an attempt that did not save a secret. Failures are logged rather than
+// returned so the caller still sees the original outcome.
+func (s *webhookService) audit(ctx context.Context, accountID uuid.UUID, action model.WebhookSecretAction, clientIP string) {
+	if err := s.webhookRepo.CreateAudit(ctx, &model.WebhookSecretAudit{
+		AccountID: accountID,
+		Action:    action,
+		ClientIP:  clientIP,
+	}); err != nil {
+		log.Printf("webhook secret audit for %s failed: %v", accountID, err)
+	}
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"paytabs/internal/auth"
+	"paytabs/internal/cache"
+	"paytabs/internal/errors"
+	"paytabs/internal/model"
+)
+
+func TestWebhookService_RegenerateSecret_StoresOnlyCiphertext(t *testing.T) {
+	merchant := &model.Account{ID: uuid.New(), IsMerchant: true}
+	box, err := auth.NewSecretBox("test-key")
+	require.NoError(t, err)
+
+	accountRepo := new(MockAccountRepository)
+	webhookRepo := new(MockWebhookRepository)
+	accountRepo.On("FindByID", mock.Anything, merchant.ID).Return(merchant, nil)
+
+	var saved *model.WebhookSecret
+	webhookRepo.On("SaveSecret", mock.Anything, mock.Anything, mock.MatchedBy(func(a *model.WebhookSecretAudit) bool {
+		return a.AccountID == merchant.ID && a.Action == model.WebhookSecretActionRegenerated && a.ClientIP == "10.0.0.1"
+	})).Run(func(args mock.Arguments) {
+		saved = args.Get(1).(*model.WebhookSecret)
+	}).Return(nil)
+
+	svc := NewWebhookService(accountRepo, webhookRepo, box, nil, nil, 3, 0)
+	secret, err := svc.RegenerateSecret(context.Background(), merchant.ID, "10.0.0.1")
+
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(secret, "whsec_"))
+	require.NotNil(t, saved)
+	assert.NotContains(t, saved.Ciphertext, secret)
+
+	// The stored value decrypts back to what was revealed
+	webhookRepo.On("FindSecret", mock.Anything, merchant.ID).Return(saved, nil)
+	signing, err := svc.SigningSecret(context.Background(), merchant.ID)
+	require.NoError(t, err)
+	assert.Equal(t, secret, signing)
+}
+
+func TestWebhookService_RegenerateSecret_RateLimitedAndAudited(t *testing.T) {
+	merchant := &model.Account{ID: uuid.New(), IsMerchant: true}
+	box, _ := auth.NewSecretBox("test-key")
+	mr := miniredis.RunT(t)
+
+	accountRepo := new(MockAccountRepository)
+	webhookRepo := new(MockWebhookRepository)
+	accountRepo.On("FindByID", mock.Anything, merchant.ID).Return(merchant, nil)
+	webhookRepo.On("SaveSecret", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	webhookRepo.On("CreateAudit", mock.Anything, mock.MatchedBy(func(a *model.WebhookSecretAudit) bool {
+		return a.Action == model.WebhookSecretActionDenied
+	})).Return(nil).Once()
+
+	svc := NewWebhookService(accountRepo, webhookRepo, box, nil, cache.New(mr.Addr(), "", 0), 2, 0)
+	for i := 0; i < 2; i++ {
+		_, err := svc.RegenerateSecret(context.Background(), merchant.ID, "10.0.0.1")
+		require.NoError(t, err)
+	}
+
+	_, err := svc.RegenerateSecret(context.Background(), merchant.ID, "10.0.0.1")
+	assert.Equal(t, ErrTooManySecretReveals, err)
+	webhookRepo.AssertNumberOfCalls(t, "SaveSecret", 2)
+	webhookRepo.AssertExpectations(t)
+}
+
+func TestWebhookService_RegenerateSecret_Rejections(t *testing.T) {
+	account := &model.Account{ID: uuid.New()}
+	accountRepo := new(MockAccountRepository)
+	webhookRepo := new(MockWebhookRepository)
+	accountRepo.On("FindByID", mock.Anything, account.ID).Return(account, nil)
+	box, _ := auth.NewSecretBox("test-key")
+
+	_, err := NewWebhookService(accountRepo, webhookRepo, box, nil, nil, 3, 0).RegenerateSecret(context.Background(), account.ID, "")
+	assert.Equal(t, errors.ErrNotMerchant, err)
+
+	_, err = NewWebhookService(accountRepo, webhookRepo, nil, nil, nil, 3, 0).RegenerateSecret(context.Background(), account.ID, "")
+	assert.Equal(t, ErrWebhookSecretsDisabled, err)
+
+	webhookRepo.AssertNotCalled(t, "SaveSecret", mock.Anything, mock.Anything, mock.Anything)
+}
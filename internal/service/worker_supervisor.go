@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"log"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// workerPanics counts the panics recovered in each background worker of this instance,
+// keyed by worker name.
+var workerPanics sync.Map
+
+// WorkerPanic is how many times a background worker has panicked and been restarted.
+type WorkerPanic struct {
+	Name  string
+	Count int64
+}
+
+// WorkerPanics reports the recovered panics of every worker that has panicked, by name.
+func WorkerPanics() []WorkerPanic {
+	var panics []WorkerPanic
+	workerPanics.Range(func(name, count any) bool {
+		panics = append(panics, WorkerPanic{Name: name.(string), Count: count.(*atomic.Int64).Load()})
+		return true
+	})
+	sort.Slice(panics, func(i, j int) bool { return panics[i].Name < panics[j].Name })
+	return panics
+}
+
+// SuperviseWorker runs loop until it returns or ctx is cancelled. A panic in loop is logged
+// with its stack and counted, and loop is restarted after restartDelay, so one bad item
+// cannot stop a worker for the rest of the process lifetime. Whatever the loop held in
+// memory when it panicked is lost.
+func SuperviseWorker(ctx context.Context, name string, restartDelay time.Duration, loop func(ctx context.Context)) {
+	for {
+		if !runRecovered(ctx, name, loop) {
+			return
+		}
+		timer := time.NewTimer(restartDelay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+		log.Printf("worker %s: restarting", name)
+	}
+}
+
+// runRecovered runs loop once and reports whether it panicked.
+func runRecovered(ctx context.Context, name string, loop func(ctx context.Context)) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicked = true
+			count, _ := workerPanics.LoadOrStore(name, new(atomic.Int64))
+			total := count.(*atomic.Int64).Add(1)
+			log.Printf("worker %s: panic (%d so far): %v\n%s", name, total, r, debug.Stack())
+		}
+	}()
+	loop(ctx)
+	return false
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"paytabs/internal/config"
+	"paytabs/internal/model"
+)
+
+// workerPanicCount reports the recovered panics of the named worker.
+func workerPanicCount(name string) int64 {
+	for _, p := range WorkerPanics() {
+		if p.Name == name {
+			return p.Count
+		}
+	}
+	return 0
+}
+
+func TestSuperviseWorker_RestartsAfterPanic(t *testing.T) {
+	name := "test_worker_" + uuid.NewString()
+	runs := 0
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		SuperviseWorker(context.Background(), name, 0, func(ctx context.Context) {
+			runs++
+			if runs < 3 {
+				panic("bad item")
+			}
+		})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("worker was not restarted")
+	}
+	assert.Equal(t, 3, runs, "the loop returning normally ends supervision")
+	assert.Equal(t, int64(2), workerPanicCount(name))
+}
+
+func TestSuperviseWorker_StopsWhenCancelledBeforeRestart(t *testing.T) {
+	name := "test_worker_" + uuid.NewString()
+	ctx, cancel := context.WithCancel(context.Background())
+	runs := 0
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		SuperviseWorker(ctx, name, time.Hour, func(ctx context.Context) {
+			runs++
+			panic("bad item")
+		})
+	}()
+
+	require.Eventually(t, func() bool { return workerPanicCount(name) == 1 }, time.Second, time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("supervision did not stop")
+	}
+	assert.Equal(t, 1, runs)
+}
+
+func TestPaymentService_LogWorkerRecoversFromPanic(t *testing.T) {
+	logRepo := new(MockPaymentLogRepository)
+	written := make(chan []model.PaymentLog, 1)
+	// The first batch makes the repository panic, as a malformed log would
+	logRepo.On("CreateBatch", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		panic("nil batch element")
+	}).Once()
+	logRepo.On("CreateBatch", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		written <- args.Get(1).([]model.PaymentLog)
+	}).Return(nil)
+
+	panicsBefore := workerPanicCount("payment_logs")
+	svc := NewPaymentService(nil, nil, nil, logRepo, nil, nil, nil, nil, nil, &config.Config{}).(*paymentService)
+
+	// A full batch is flushed at once, so each batch of ten reaches the repository on its own
+	for i := 0; i < 20; i++ {
+		svc.logChannel <- model.PaymentLog{PaymentID: uuid.New(), Status: model.PaymentStatusAccepted}
+	}
+
+	select {
+	case batch := <-written:
+		assert.Len(t, batch, 10)
+	case <-time.After(3 * time.Second):
+		t.Fatal("log worker stopped after a panic")
+	}
+	assert.Equal(t, panicsBefore+1, workerPanicCount("payment_logs"))
+}